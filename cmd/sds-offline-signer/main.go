@@ -0,0 +1,190 @@
+// Command sds-offline-signer signs the RAV signing requests queued by a consumer
+// sidecar running with --offline-signing-dir, on a machine holding the payer
+// signer key in an air-gapped environment.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/graphprotocol/substreams-data-service/horizon/devenv"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/streamingfast/cli"
+	. "github.com/streamingfast/cli"
+	"github.com/streamingfast/cli/sflags"
+	"github.com/streamingfast/eth-go"
+)
+
+var version = "dev"
+
+func main() {
+	Run(
+		"sds-offline-signer <requests-dir>",
+		"Sign queued RAV signing requests with an offline key",
+		ConfigureVersion(version),
+		Execute(runSign),
+		ExactArgs(1),
+		Description(`
+			Signs every '<id>.request.json' file of <requests-dir> not answered yet
+			and writes the '<id>.response.json' file the consumer sidecar waits
+			for, into --responses-dir (<requests-dir> when empty). Copy the request
+			files from the consumer sidecar --offline-signing-dir to this machine
+			and the response files back.
+
+			Each request is printed before being signed. Requests whose RAV value
+			aggregate exceeds --max-value are refused and left unanswered, their
+			signing call on the consumer side eventually times out.
+		`),
+		Flags(func(flags *pflag.FlagSet) {
+			flags.String("private-key", "", "Signer private key (hex), read from the SDS_OFFLINE_SIGNER_PRIVATE_KEY environment variable when empty")
+			flags.String("responses-dir", "", "Directory response files are written to, <requests-dir> when empty")
+			flags.String("max-value", "", "Maximum RAV value aggregate signed, in GRT (unlimited when empty)")
+			flags.Bool("dry-run", false, "Only print the pending requests, nothing is signed")
+		}),
+	)
+}
+
+func runSign(cmd *cobra.Command, args []string) error {
+	requestsDir := args[0]
+	privateKeyHex := sflags.MustGetString(cmd, "private-key")
+	responsesDir := sflags.MustGetString(cmd, "responses-dir")
+	maxValueGRT := sflags.MustGetString(cmd, "max-value")
+	dryRun := sflags.MustGetBool(cmd, "dry-run")
+
+	if privateKeyHex == "" {
+		privateKeyHex = os.Getenv("SDS_OFFLINE_SIGNER_PRIVATE_KEY")
+	}
+	cli.Ensure(privateKeyHex != "" || dryRun, "<private-key> is required")
+
+	var key *eth.PrivateKey
+	var err error
+	if privateKeyHex != "" {
+		key, err = eth.NewPrivateKey(privateKeyHex)
+		cli.NoError(err, "invalid <private-key>")
+	}
+
+	if responsesDir == "" {
+		responsesDir = requestsDir
+	}
+
+	var maxValue *big.Int
+	if maxValueGRT != "" {
+		maxValue, err = devenv.ParseGRT(maxValueGRT)
+		cli.NoError(err, "invalid <max-value> %q", maxValueGRT)
+	}
+
+	requests, err := pendingRequests(requestsDir, responsesDir)
+	if err != nil {
+		return err
+	}
+	if len(requests) == 0 {
+		fmt.Println("No pending signing request")
+		return nil
+	}
+
+	if key != nil {
+		fmt.Printf("Signer: %s\n", key.PublicKey().Address().Pretty())
+	}
+
+	signed, refused := 0, 0
+	for _, request := range requests {
+		rav := request.RAV
+		fmt.Printf("%s  collector=%s\n%s\n", request.ID, request.Domain.VerifyingContract.Pretty(), indent(rav.Pretty()))
+
+		if maxValue != nil && rav.ValueAggregate.Cmp(maxValue) > 0 {
+			fmt.Printf("  refused: value exceeds --max-value %s GRT\n", maxValueGRT)
+			refused++
+			continue
+		}
+		if dryRun {
+			continue
+		}
+
+		response, err := request.Sign(key)
+		if err != nil {
+			return fmt.Errorf("signing request %s: %w", request.ID, err)
+		}
+		if err := writeJSON(filepath.Join(responsesDir, request.ResponseFileName()), response); err != nil {
+			return fmt.Errorf("writing response to request %s: %w", request.ID, err)
+		}
+		signed++
+	}
+
+	if dryRun {
+		fmt.Printf("\n%d pending request(s), dry run, nothing signed\n", len(requests))
+		return nil
+	}
+	fmt.Printf("\nSigned %d request(s), refused %d\n", signed, refused)
+	return nil
+}
+
+// pendingRequests returns the requests of requestsDir without a response in
+// responsesDir, oldest first
+func pendingRequests(requestsDir, responsesDir string) ([]*horizon.OfflineSigningRequest, error) {
+	entries, err := os.ReadDir(requestsDir)
+	if err != nil {
+		return nil, fmt.Errorf("listing requests: %w", err)
+	}
+
+	var requests []*horizon.OfflineSigningRequest
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), horizon.OfflineRequestSuffix) {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(requestsDir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("reading request %s: %w", entry.Name(), err)
+		}
+
+		var request horizon.OfflineSigningRequest
+		if err := json.Unmarshal(data, &request); err != nil {
+			return nil, fmt.Errorf("decoding request %s: %w", entry.Name(), err)
+		}
+		if request.Domain == nil || request.RAV == nil || request.RAV.ValueAggregate == nil {
+			return nil, fmt.Errorf("request %s is missing its domain or RAV", entry.Name())
+		}
+
+		if _, err := os.Stat(filepath.Join(responsesDir, request.ResponseFileName())); err == nil {
+			continue
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("checking response to request %s: %w", request.ID, err)
+		}
+
+		requests = append(requests, &request)
+	}
+
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].CreatedAt.Before(requests[j].CreatedAt)
+	})
+	return requests, nil
+}
+
+// writeJSON writes v to path through a temporary file so the consumer sidecar
+// never reads a partial response
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// indent indents every line of s by two spaces
+func indent(s string) string {
+	return "  " + strings.ReplaceAll(s, "\n", "\n  ")
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/streamingfast/cli"
+	. "github.com/streamingfast/cli"
+	"github.com/streamingfast/cli/sflags"
+	"github.com/streamingfast/eth-go"
+)
+
+// ABI payloads handled by 'sds abi encode' and 'sds abi decode'
+const (
+	abiKindSignedRAV   = "signed-rav"
+	abiKindCollectData = "collect-data"
+)
+
+var abiEncodeCmd = Command(
+	runABIEncode,
+	"encode <signed-rav|collect-data> <rav>",
+	"ABI encode a SignedRAV tuple or SubstreamsDataService.collect data to hex",
+	ExactArgs(2),
+	Description(`
+		Encodes with the same encoders the sidecars use, for debugging interop
+		issues with contract calls:
+		- signed-rav: abi.encode(SignedRAV)
+		- collect-data: abi.encode(SignedRAV, uint256 dataServiceCut), the data
+		  parameter of SubstreamsDataService.collect, or with --call the complete
+		  collect(serviceProvider, paymentType, data) call
+
+		<rav> is the RAV as JSON, inline or the path of a file, in the format of
+		the 'rav' field of offline signing requests (metadata in base64).
+		--signature is the RAV signature in the V+R+S layout produced by the
+		sidecars or in the 64-byte EIP-2098 compact form, it is encoded in the
+		65-byte R+S+V order expected by the contracts.
+	`),
+	Flags(func(flags *pflag.FlagSet) {
+		flags.String("signature", "", "RAV signature (hex, 65 bytes V+R+S or 64 bytes EIP-2098 compact, required)")
+		flags.String("data-service-cut", "0", "Data service cut of collect-data, in PPM (e.g. \"100000\") or as a percentage (e.g. \"10%\")")
+		flags.Bool("call", false, "Encode the complete SubstreamsDataService.collect call instead of its data parameter (collect-data only)")
+	}),
+)
+
+var abiDecodeCmd = Command(
+	runABIDecode,
+	"decode <signed-rav|collect-data> <hex>",
+	"Decode a hex ABI encoded SignedRAV tuple or SubstreamsDataService.collect data",
+	ExactArgs(2),
+	Description(`
+		Decodes the payloads produced by 'sds abi encode', or read from a
+		transaction input. collect-data accepts either the data parameter of
+		SubstreamsDataService.collect or the complete call, recognized by its
+		method selector.
+
+		With --chain-id and --collector-address, the RAV signer is recovered under
+		the collector's EIP-712 domain.
+	`),
+	Flags(func(flags *pflag.FlagSet) {
+		flags.Uint64("chain-id", 0, "Chain ID of the collector, to recover the RAV signer")
+		flags.String("collector-address", "", "GraphTallyCollector contract address, to recover the RAV signer")
+		flags.String("domain-name", horizon.DefaultDomainName, "EIP-712 domain name of the collector contract")
+		flags.String("domain-version", horizon.DefaultDomainVersion, "EIP-712 domain version of the collector contract")
+	}),
+)
+
+func runABIEncode(cmd *cobra.Command, args []string) error {
+	kind, ravInput := args[0], args[1]
+	signatureHex := sflags.MustGetString(cmd, "signature")
+	dataServiceCutValue := sflags.MustGetString(cmd, "data-service-cut")
+	call := sflags.MustGetBool(cmd, "call")
+
+	cli.Ensure(kind == abiKindSignedRAV || kind == abiKindCollectData, "unknown payload %q, expected %q or %q", kind, abiKindSignedRAV, abiKindCollectData)
+	cli.Ensure(!call || kind == abiKindCollectData, "<call> is only supported with %q", abiKindCollectData)
+
+	rav, err := readRAVInput(ravInput)
+	cli.NoError(err, "invalid <rav>")
+
+	cli.Ensure(signatureHex != "", "<signature> is required")
+	signatureBytes, err := eth.NewHex(signatureHex)
+	cli.NoError(err, "invalid <signature> %q", signatureHex)
+	signature, err := horizon.ParseSignature(signatureBytes)
+	cli.NoError(err, "invalid <signature> %q", signatureHex)
+
+	signedRAV := &horizon.SignedRAV{Message: rav, Signature: signature}
+
+	var data []byte
+	switch {
+	case kind == abiKindSignedRAV:
+		data, err = horizon.EncodeSignedRAV(signedRAV)
+	default:
+		dataServiceCut, cutErr := horizon.ParsePPM(dataServiceCutValue)
+		cli.NoError(cutErr, "invalid <data-service-cut> %q", dataServiceCutValue)
+
+		if call {
+			data, err = horizon.EncodeDataServiceCollect(signedRAV, dataServiceCut)
+		} else {
+			data, err = horizon.EncodeCollectData(signedRAV, dataServiceCut)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(eth.Hex(data).Pretty())
+	return nil
+}
+
+func runABIDecode(cmd *cobra.Command, args []string) error {
+	kind, input := args[0], args[1]
+	chainID := sflags.MustGetUint64(cmd, "chain-id")
+	collectorHex := sflags.MustGetString(cmd, "collector-address")
+	domainName := sflags.MustGetString(cmd, "domain-name")
+	domainVersion := sflags.MustGetString(cmd, "domain-version")
+
+	cli.Ensure(kind == abiKindSignedRAV || kind == abiKindCollectData, "unknown payload %q, expected %q or %q", kind, abiKindSignedRAV, abiKindCollectData)
+	cli.Ensure((chainID == 0) == (collectorHex == ""), "<chain-id> and <collector-address> must be given together")
+
+	data, err := eth.NewHex(strings.TrimSpace(input))
+	cli.NoError(err, "invalid <hex>")
+
+	var signedRAV *horizon.SignedRAV
+	switch {
+	case kind == abiKindSignedRAV:
+		signedRAV, err = horizon.DecodeSignedRAV(data)
+		if err != nil {
+			return err
+		}
+		fmt.Println(signedRAV.Pretty())
+
+	case len(data) >= 4 && string(data[:4]) == string(horizon.DataServiceCollectMethod.MethodID()):
+		collect, err := horizon.DecodeDataServiceCollect(data)
+		if err != nil {
+			return err
+		}
+		signedRAV = collect.SignedRAV
+
+		fmt.Printf("Service provider: %s\n", collect.ServiceProvider.Pretty())
+		fmt.Printf("Payment type:     %d\n", collect.PaymentType)
+		fmt.Printf("Data service cut: %s\n", collect.DataServiceCut)
+		fmt.Println(signedRAV.Pretty())
+
+	default:
+		var dataServiceCut horizon.PPM
+		signedRAV, dataServiceCut, err = horizon.DecodeCollectData(data)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Data service cut: %s\n", dataServiceCut)
+		fmt.Println(signedRAV.Pretty())
+	}
+
+	if collectorHex != "" {
+		collector, err := resolveAddress(cmd, collectorHex)
+		cli.NoError(err, "invalid <collector-address> %q", collectorHex)
+
+		signer, err := signedRAV.RecoverSigner(horizon.NewDomainWithNameVersion(domainName, domainVersion, chainID, collector))
+		if err != nil {
+			return fmt.Errorf("recovering RAV signer: %w", err)
+		}
+		fmt.Printf("Signer:           %s\n", signer.Pretty())
+	}
+	return nil
+}
+
+// readRAVInput parses input as RAV JSON, read from the file it names unless it
+// is inline JSON
+func readRAVInput(input string) (*horizon.RAV, error) {
+	content := []byte(input)
+	if !strings.HasPrefix(strings.TrimSpace(input), "{") {
+		var err error
+		if content, err = os.ReadFile(input); err != nil {
+			return nil, err
+		}
+	}
+
+	var rav horizon.RAV
+	if err := json.Unmarshal(content, &rav); err != nil {
+		return nil, fmt.Errorf("parsing RAV JSON: %w", err)
+	}
+	if rav.ValueAggregate == nil {
+		return nil, fmt.Errorf("RAV JSON has no valueAggregate")
+	}
+	return &rav, nil
+}
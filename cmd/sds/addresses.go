@@ -0,0 +1,52 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/streamingfast/cli"
+	"github.com/streamingfast/cli/sflags"
+	"github.com/streamingfast/eth-go"
+)
+
+func addressFlags(flags *pflag.FlagSet) {
+	flags.String("address-book", "", "YAML file mapping names to addresses, names can then be used wherever an address is expected")
+	flags.String("ens-rpc-endpoint", "", "RPC endpoint of a chain with ENS (mainnet, Sepolia or Holesky) used to resolve '.eth' names given in place of addresses")
+}
+
+var (
+	addressResolverOnce sync.Once
+	addressResolver     *sidecar.AddressResolver
+)
+
+// cmdAddressResolver returns the resolver configured by the root --address-book
+// and --ens-rpc-endpoint flags
+func cmdAddressResolver(cmd *cobra.Command) *sidecar.AddressResolver {
+	addressResolverOnce.Do(func() {
+		addressBookPath := sflags.MustGetString(cmd, "address-book")
+		ensRPCEndpoint := sflags.MustGetString(cmd, "ens-rpc-endpoint")
+
+		var book sidecar.AddressBook
+		if addressBookPath != "" {
+			var err error
+			book, err = sidecar.LoadAddressBook(addressBookPath)
+			cli.NoError(err, "invalid <address-book> %q", addressBookPath)
+		}
+
+		var ens *sidecar.ENSResolver
+		if ensRPCEndpoint != "" {
+			ens = sidecar.NewENSResolver(ensRPCEndpoint)
+		}
+
+		addressResolver = sidecar.NewAddressResolver(book, ens)
+	})
+	return addressResolver
+}
+
+// resolveAddress parses an address given on the command line, either in hex
+// or as a name of the address book or ENS
+func resolveAddress(cmd *cobra.Command, value string) (eth.Address, error) {
+	return cmdAddressResolver(cmd).Resolve(cmd.Context(), value)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/streamingfast/cli"
+	. "github.com/streamingfast/cli"
+	"github.com/streamingfast/cli/sflags"
+	"github.com/streamingfast/eth-go"
+	"github.com/streamingfast/logging"
+	"github.com/streamingfast/shutter"
+	"go.uber.org/zap"
+)
+
+var aggregatorLog, _ = logging.PackageLogger("aggregator", "github.com/graphprotocol/substreams-data-service/cmd/sds@aggregator")
+
+var aggregatorServeCmd = Command(
+	runAggregatorServe,
+	"serve",
+	"Serve the receipt aggregator over the JSON-RPC API of the tap-rs aggregator",
+	Description(`
+		Aggregates receipts into RAVs signed with --private-key, served over
+		JSON-RPC with the request and response schema of the tap-rs aggregator
+		(tap-aggregator) so existing indexer-service deployments can point at it
+		unchanged, as can 'sds provider sidecar --aggregator-url'.
+
+		Two methods are served on POST requests to the listen address:
+		- aggregate_receipts(api_version, receipts, previous_rav), the API version
+		  being "0.0", returning {"data": <signed RAV>}
+		- api_versions(), returning {"data": {"versions_supported": ["0.0"], ...}}
+
+		Receipts and previous RAVs must be signed by one of --accepted-signers,
+		the payer signers, or by --private-key itself. Aggregation errors are
+		returned with code -32002 and unsupported API versions with -32001, as
+		tap-aggregator does.
+	`),
+	Flags(func(flags *pflag.FlagSet) {
+		flags.String("listen-addr", ":7610", "HTTP listen address of the JSON-RPC server")
+		flags.String("private-key", "", "Private key (hex) signing the aggregated RAVs (required)")
+		flags.StringSlice("accepted-signers", nil, "Addresses whose receipts and RAVs are aggregated, the payer signers (required)")
+		networkFlag(flags)
+		flags.Uint64("chain-id", 1337, "Chain ID for EIP-712 domain")
+		flags.String("collector-address", "", "Collector contract address for EIP-712 domain (required unless preset by --network)")
+		flags.String("domain-name", horizon.DefaultDomainName, "EIP-712 domain name of the collector contract")
+		flags.String("domain-version", horizon.DefaultDomainVersion, "EIP-712 domain version of the collector contract")
+		flags.String("auth-token", "", "Bearer token clients must send, no authentication when empty")
+		flags.Int64("max-request-body-size", horizon.DefaultAggregatorMaxRequestSize, "Largest JSON-RPC request accepted, in bytes")
+	}),
+	NoArgs(),
+)
+
+func runAggregatorServe(cmd *cobra.Command, args []string) error {
+	listenAddr := sflags.MustGetString(cmd, "listen-addr")
+	privateKeyHex := sflags.MustGetString(cmd, "private-key")
+	acceptedSignersHex := sflags.MustGetStringSlice(cmd, "accepted-signers")
+	chainID := chainIDWithPreset(cmd)
+	collectorHex := addressWithPreset(cmd, "collector-address", presetCollector)
+	domainName := sflags.MustGetString(cmd, "domain-name")
+	domainVersion := sflags.MustGetString(cmd, "domain-version")
+	authToken := sflags.MustGetString(cmd, "auth-token")
+	maxRequestSize := sflags.MustGetInt64(cmd, "max-request-body-size")
+
+	cli.Ensure(privateKeyHex != "", "<private-key> is required")
+	privateKey, err := eth.NewPrivateKey(privateKeyHex)
+	cli.NoError(err, "invalid <private-key>")
+
+	cli.Ensure(len(acceptedSignersHex) > 0, "<accepted-signers> is required")
+	acceptedSigners := make([]eth.Address, 0, len(acceptedSignersHex))
+	for _, signerHex := range acceptedSignersHex {
+		signer, err := resolveAddress(cmd, signerHex)
+		cli.NoError(err, "invalid <accepted-signers> address %q", signerHex)
+		acceptedSigners = append(acceptedSigners, signer)
+	}
+	acceptedSigners = append(acceptedSigners, privateKey.PublicKey().Address())
+
+	cli.Ensure(collectorHex != "", "<collector-address> is required")
+	collectorAddr, err := resolveAddress(cmd, collectorHex)
+	cli.NoError(err, "invalid <collector-address> %q", collectorHex)
+
+	cli.Ensure(maxRequestSize > 0, "<max-request-body-size> must be greater than 0")
+
+	domain := horizon.NewDomainWithNameVersion(domainName, domainVersion, chainID, collectorAddr)
+	aggregator := horizon.NewAggregator(domain, privateKey, acceptedSigners)
+
+	server := &http.Server{
+		Addr:              listenAddr,
+		Handler:           horizon.NewAggregatorServer(aggregator, authToken, maxRequestSize),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	app := NewApplication(cmd.Context())
+
+	serverShutter := shutter.New()
+	serverShutter.OnTerminating(func(_ error) {
+		server.Close()
+	})
+	app.SuperviseAndStartUsing(serverShutter, func() error {
+		aggregatorLog.Info("serving aggregator",
+			zap.String("listen_addr", listenAddr),
+			zap.Stringer("signer", privateKey.PublicKey().Address()),
+			zap.Int("accepted_signers", len(acceptedSignersHex)),
+		)
+
+		err := server.ListenAndServe()
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	})
+
+	return app.WaitForTermination(aggregatorLog, 0*time.Second, 10*time.Second)
+}
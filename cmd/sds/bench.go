@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/streamingfast/cli"
+	. "github.com/streamingfast/cli"
+	"github.com/streamingfast/cli/sflags"
+	"github.com/streamingfast/eth-go"
+)
+
+// EIP-712 operations measured by 'sds bench eip712'
+var benchEIP712Operations = []string{"hash", "sign", "recover"}
+
+var benchEIP712Cmd = Command(
+	runBenchEIP712,
+	"eip712",
+	"Measure EIP-712 hash, sign and recover throughput of RAVs on this machine",
+	NoArgs(),
+	Description(`
+		Runs each operation of --operations on RAVs for --duration, from
+		--concurrency goroutines, and prints its throughput and average latency,
+		to size sidecars before a production rollout:
+		- hash: EIP-712 digest of a RAV, paid on every signature and recovery
+		- sign: signing a RAV with the configured signer, what the consumer
+		  sidecar does for every RAV request
+		- recover: recovering the signer of a RAV, what the provider sidecar and
+		  the aggregator do for every RAV and receipt
+
+		Signs with --signer-private-key, a random key when empty, or through the
+		eth_signTypedData_v4 endpoint of --remote-signer-url (e.g. a KMS backed
+		Web3Signer) for --signer-address, as the consumer sidecar does. Remote
+		signers are usually bound by latency rather than CPU, raise --concurrency
+		to find their throughput.
+	`),
+	Flags(func(flags *pflag.FlagSet) {
+		flags.Duration("duration", 5*time.Second, "How long each operation runs")
+		flags.Int("concurrency", runtime.GOMAXPROCS(0), "Goroutines running each operation concurrently")
+		flags.StringSlice("operations", benchEIP712Operations, "Operations measured, among "+strings.Join(benchEIP712Operations, ", "))
+		flags.String("signer-private-key", "", "Private key signing RAVs (hex, a random key when empty and --remote-signer-url is not set)")
+		flags.String("remote-signer-url", "", "JSON-RPC endpoint signing RAVs with eth_signTypedData_v4, instead of signing with --signer-private-key")
+		flags.String("signer-address", "", "Address of the remote signer key, required with --remote-signer-url")
+		flags.Duration("remote-signer-timeout", horizon.DefaultRemoteSignerTimeout, "Maximum time waited for each signature of --remote-signer-url")
+		flags.Uint64("chain-id", 1337, "Chain ID of the EIP-712 domain")
+		flags.String("collector-address", "0x0000000000000000000000000000000000000000", "GraphTallyCollector address of the EIP-712 domain")
+	}),
+)
+
+func runBenchEIP712(cmd *cobra.Command, args []string) error {
+	duration := sflags.MustGetDuration(cmd, "duration")
+	concurrency := sflags.MustGetInt(cmd, "concurrency")
+	operations := sflags.MustGetStringSlice(cmd, "operations")
+	signerKeyHex := sflags.MustGetString(cmd, "signer-private-key")
+	remoteSignerURL := sflags.MustGetString(cmd, "remote-signer-url")
+	signerAddressHex := sflags.MustGetString(cmd, "signer-address")
+	remoteSignerTimeout := sflags.MustGetDuration(cmd, "remote-signer-timeout")
+	chainID := sflags.MustGetUint64(cmd, "chain-id")
+	collectorHex := sflags.MustGetString(cmd, "collector-address")
+
+	cli.Ensure(duration > 0, "<duration> must be greater than 0")
+	cli.Ensure(concurrency > 0, "<concurrency> must be greater than 0")
+	for _, operation := range operations {
+		cli.Ensure(slices.Contains(benchEIP712Operations, operation), "unknown operation %q in <operations>, expected one of %s", operation, strings.Join(benchEIP712Operations, ", "))
+	}
+
+	collector, err := resolveAddress(cmd, collectorHex)
+	cli.NoError(err, "invalid <collector-address> %q", collectorHex)
+	domain := horizon.NewDomain(chainID, collector)
+
+	var signer horizon.Signer
+	backend := "local key"
+	if remoteSignerURL != "" {
+		cli.Ensure(signerKeyHex == "", "<remote-signer-url> and <signer-private-key> are mutually exclusive")
+		cli.Ensure(signerAddressHex != "", "<signer-address> is required with <remote-signer-url>")
+		cli.Ensure(remoteSignerTimeout > 0, "<remote-signer-timeout> must be greater than 0")
+		signerAddress, err := resolveAddress(cmd, signerAddressHex)
+		cli.NoError(err, "invalid <signer-address> %q", signerAddressHex)
+		signer = horizon.NewRemoteSigner(remoteSignerURL, signerAddress, remoteSignerTimeout)
+		backend = "remote signer " + remoteSignerURL
+	} else {
+		var key *eth.PrivateKey
+		if signerKeyHex != "" {
+			key, err = eth.NewPrivateKey(signerKeyHex)
+			cli.NoError(err, "invalid <signer-private-key>")
+		} else {
+			key, err = eth.NewRandomPrivateKey()
+			cli.NoError(err, "generating signer key")
+			backend = "random local key"
+		}
+		signer = horizon.NewLocalSigner(key)
+	}
+
+	newRAV := func(i uint64) *horizon.RAV {
+		return &horizon.RAV{
+			CollectionID:    horizon.CollectionID{0x01},
+			Payer:           signer.Address(),
+			ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+			DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+			TimestampNs:     uint64(time.Now().UnixNano()) + i,
+			ValueAggregate:  new(big.Int).SetUint64(1_000_000_000_000 + i),
+			Metadata:        []byte{},
+		}
+	}
+
+	// The RAV recovered is signed once up front, which also checks the signer
+	// backend answers with signatures of its address
+	signedRAV, err := horizon.SignWith(domain, newRAV(0), signer)
+	if err != nil {
+		return fmt.Errorf("signing with %s: %w", backend, err)
+	}
+	recovered, err := signedRAV.RecoverSigner(domain)
+	if err != nil || !strings.EqualFold(recovered.Pretty(), signer.Address().Pretty()) {
+		return fmt.Errorf("signature of %s does not recover to %s", backend, signer.Address().Pretty())
+	}
+
+	fmt.Printf("Signer:      %s (%s)\n", signer.Address().Pretty(), backend)
+	fmt.Printf("Concurrency: %d goroutine(s), GOMAXPROCS %d, %s per operation\n", concurrency, runtime.GOMAXPROCS(0), duration)
+
+	for _, operation := range operations {
+		var run func(i uint64) error
+		switch operation {
+		case "hash":
+			rav := newRAV(0)
+			run = func(i uint64) error {
+				_, err := horizon.HashTypedData(domain, rav)
+				return err
+			}
+		case "sign":
+			run = func(i uint64) error {
+				_, err := horizon.SignWith(domain, newRAV(i), signer)
+				return err
+			}
+		case "recover":
+			run = func(i uint64) error {
+				_, err := signedRAV.RecoverSigner(domain)
+				return err
+			}
+		}
+
+		result := runBenchOperation(duration, concurrency, run)
+		fmt.Printf("  %-8s %12.0f ops/s  avg %-12s ops=%d", operation, result.throughput(), result.averageLatency(concurrency), result.ops)
+		if result.errors > 0 {
+			fmt.Printf("  errors=%d (last: %v)", result.errors, result.lastErr)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// benchResult is what an operation achieved during a benchmark run, failed
+// calls are counted apart from ops
+type benchResult struct {
+	ops     uint64
+	errors  uint64
+	lastErr error
+	elapsed time.Duration
+}
+
+func (r *benchResult) throughput() float64 {
+	return float64(r.ops) / r.elapsed.Seconds()
+}
+
+// averageLatency is the average time of one call, each of the concurrency
+// goroutines having run calls back to back
+func (r *benchResult) averageLatency(concurrency int) time.Duration {
+	calls := r.ops + r.errors
+	if calls == 0 {
+		return 0
+	}
+	return time.Duration(uint64(r.elapsed) * uint64(concurrency) / calls)
+}
+
+// runBenchOperation calls run back to back from concurrency goroutines for
+// duration, each call with a distinct index
+func runBenchOperation(duration time.Duration, concurrency int, run func(i uint64) error) *benchResult {
+	var next, ops, errors atomic.Uint64
+	var lastErr atomic.Value
+
+	start := time.Now()
+	deadline := start.Add(duration)
+
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				if err := run(next.Add(1)); err != nil {
+					errors.Add(1)
+					lastErr.Store(err)
+					continue
+				}
+				ops.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	result := &benchResult{ops: ops.Load(), errors: errors.Load(), elapsed: time.Since(start)}
+	if err, ok := lastErr.Load().(error); ok {
+		result.lastErr = err
+	}
+	return result
+}
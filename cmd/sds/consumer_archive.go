@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/consumer/sidecar"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/streamingfast/cli"
+	. "github.com/streamingfast/cli"
+	"github.com/streamingfast/cli/sflags"
+	"github.com/streamingfast/eth-go"
+)
+
+var consumerArchiveCmd = Group(
+	"archive",
+	"Read the RAV archive kept by the consumer sidecar (--archive-dir)",
+	consumerArchiveExportCmd,
+)
+
+var consumerArchiveExportCmd = Command(
+	runConsumerArchiveExport,
+	"export <archive-dir>",
+	"Export the RAVs archived by the consumer sidecar, as JSON lines or CSV",
+	Description(`
+		Reads the archive directory of a consumer sidecar (its --archive-dir), no
+		running sidecar is needed, and prints the archived RAVs in signing order.
+
+		The 'csv' format lists one RAV per row along with the signer recovered
+		from its signature under the domain it was signed with. The 'jsonl'
+		format prints the archive entries as they are stored.
+	`),
+	ExactArgs(1),
+	Flags(func(flags *pflag.FlagSet) {
+		flags.String("since", "", "Only export RAVs signed at or after this time, RFC3339 or YYYY-MM-DD (UTC)")
+		flags.String("until", "", "Only export RAVs signed before this time, RFC3339 or YYYY-MM-DD (UTC)")
+		flags.String("service-provider", "", "Only export RAVs paying this service provider")
+		flags.String("format", "jsonl", "Output format, 'jsonl' or 'csv'")
+	}),
+)
+
+func runConsumerArchiveExport(cmd *cobra.Command, args []string) error {
+	dir := args[0]
+	sinceValue := sflags.MustGetString(cmd, "since")
+	untilValue := sflags.MustGetString(cmd, "until")
+	serviceProviderHex := sflags.MustGetString(cmd, "service-provider")
+	format := sflags.MustGetString(cmd, "format")
+
+	cli.Ensure(format == "jsonl" || format == "csv", "invalid <format> %q, expected 'jsonl' or 'csv'", format)
+
+	since, err := parseArchiveTime(sinceValue)
+	cli.NoError(err, "invalid <since> %q", sinceValue)
+	until, err := parseArchiveTime(untilValue)
+	cli.NoError(err, "invalid <until> %q", untilValue)
+
+	var serviceProvider eth.Address
+	if serviceProviderHex != "" {
+		serviceProvider, err = resolveAddress(cmd, serviceProviderHex)
+		cli.NoError(err, "invalid <service-provider> %q", serviceProviderHex)
+	}
+
+	entries, err := sidecar.ReadRAVArchive(dir, since, until)
+	if err != nil {
+		return err
+	}
+
+	if format == "jsonl" {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, entry := range entries {
+			if serviceProvider != nil && entry.RAV.ServiceProvider.Pretty() != serviceProvider.Pretty() {
+				continue
+			}
+			if err := encoder.Encode(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	writer := csv.NewWriter(os.Stdout)
+	writer.Write([]string{"signed_at", "final", "collector", "payer", "service_provider", "data_service", "collection_id", "timestamp_ns", "value_aggregate", "signer", "signature"})
+	for _, entry := range entries {
+		rav := entry.RAV
+		if serviceProvider != nil && rav.ServiceProvider.Pretty() != serviceProvider.Pretty() {
+			continue
+		}
+
+		collector, signer := "", ""
+		if entry.Domain != nil {
+			collector = entry.Domain.VerifyingContract.Pretty()
+			if recovered, err := entry.SignedRAV().RecoverSigner(entry.Domain); err == nil {
+				signer = recovered.Pretty()
+			}
+		}
+
+		writer.Write([]string{
+			entry.SignedAt.UTC().Format(time.RFC3339Nano),
+			strconv.FormatBool(entry.Final),
+			collector,
+			rav.Payer.Pretty(),
+			rav.ServiceProvider.Pretty(),
+			rav.DataService.Pretty(),
+			eth.Hash(rav.CollectionID[:]).Pretty(),
+			strconv.FormatUint(rav.TimestampNs, 10),
+			rav.ValueAggregate.String(),
+			signer,
+			entry.Signature.Pretty(),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// parseArchiveTime parses an RFC3339 time or a YYYY-MM-DD UTC day, zero when empty
+func parseArchiveTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if day, err := time.Parse(time.DateOnly, value); err == nil {
+		return day, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC3339 or YYYY-MM-DD: %w", err)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/consumer/sidecar"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/streamingfast/cli"
+	. "github.com/streamingfast/cli"
+	"github.com/streamingfast/cli/sflags"
+)
+
+var consumerBlacklistCmd = Group(
+	"blacklist",
+	"Review and manage the service providers refused new sessions by the consumer sidecar",
+	consumerBlacklistListCmd,
+	consumerBlacklistAddCmd,
+	consumerBlacklistClearCmd,
+
+	PersistentFlags(func(flags *pflag.FlagSet) {
+		flags.String("admin-addr", "", "Consumer sidecar admin server address, its --admin-listen-addr (required)")
+	}),
+)
+
+var consumerBlacklistListCmd = Command(
+	runConsumerBlacklistList,
+	"list",
+	"List the blacklisted service providers along with why they were blacklisted",
+	NoArgs(),
+)
+
+var consumerBlacklistAddCmd = Command(
+	runConsumerBlacklistAdd,
+	"add <provider>",
+	"Blacklist a service provider, refusing it new sessions until cleared",
+	ExactArgs(1),
+	Flags(func(flags *pflag.FlagSet) {
+		flags.String("reason", "", "Reason reported to refused sessions")
+	}),
+)
+
+var consumerBlacklistClearCmd = Command(
+	runConsumerBlacklistClear,
+	"clear <provider>",
+	"Accept new sessions toward a blacklisted service provider again",
+	Description(`
+		The disputed sessions counted toward --blacklist-after-divergences are
+		forgotten too, the service provider starts over with a clean record.
+		Disputes stay in its price book track record.
+	`),
+	ExactArgs(1),
+)
+
+func runConsumerBlacklistList(cmd *cobra.Command, args []string) error {
+	var out struct {
+		Blacklisted []*sidecar.BlacklistEntryResponse `json:"blacklisted"`
+	}
+	if err := adminRequest(cmd.Context(), http.MethodGet, consumerBlacklistAdminAddr(cmd)+"/v1/providers/blacklist", nil, &out); err != nil {
+		return fmt.Errorf("listing blacklisted service providers: %w", err)
+	}
+
+	if len(out.Blacklisted) == 0 {
+		fmt.Println("No blacklisted service provider")
+		return nil
+	}
+
+	fmt.Printf("%d blacklisted service provider(s):\n", len(out.Blacklisted))
+	for _, entry := range out.Blacklisted {
+		printBlacklistEntry(entry)
+	}
+	return nil
+}
+
+func runConsumerBlacklistAdd(cmd *cobra.Command, args []string) error {
+	provider, err := resolveAddress(cmd, args[0])
+	cli.NoError(err, "invalid <provider> %q", args[0])
+	reason := sflags.MustGetString(cmd, "reason")
+
+	var out sidecar.BlacklistEntryResponse
+	if err := adminRequest(cmd.Context(), http.MethodPut, consumerBlacklistAdminAddr(cmd)+"/v1/providers/"+provider.Pretty()+"/blacklist", &sidecar.BlacklistRequest{Reason: reason}, &out); err != nil {
+		return fmt.Errorf("blacklisting %s: %w", provider.Pretty(), err)
+	}
+
+	fmt.Println("Service provider blacklisted:")
+	printBlacklistEntry(&out)
+	return nil
+}
+
+func runConsumerBlacklistClear(cmd *cobra.Command, args []string) error {
+	provider, err := resolveAddress(cmd, args[0])
+	cli.NoError(err, "invalid <provider> %q", args[0])
+
+	var out map[string]string
+	if err := adminRequest(cmd.Context(), http.MethodDelete, consumerBlacklistAdminAddr(cmd)+"/v1/providers/"+provider.Pretty()+"/blacklist", nil, &out); err != nil {
+		return fmt.Errorf("clearing %s: %w", provider.Pretty(), err)
+	}
+
+	fmt.Printf("Service provider %s cleared, new sessions are accepted again\n", provider.Pretty())
+	return nil
+}
+
+func consumerBlacklistAdminAddr(cmd *cobra.Command) string {
+	adminAddr := sflags.MustGetString(cmd, "admin-addr")
+	cli.Ensure(adminAddr != "", "<admin-addr> is required")
+	return adminBaseURL(adminAddr)
+}
+
+func printBlacklistEntry(entry *sidecar.BlacklistEntryResponse) {
+	origin := "operator"
+	if entry.Automatic {
+		origin = "automatic"
+	}
+
+	fmt.Printf("  %s  %s  at=%s\n", entry.ServiceProvider, origin, entry.BlacklistedAt.UTC().Format(time.RFC3339))
+	if entry.Reason != "" {
+		fmt.Printf("    %s\n", entry.Reason)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/consumer/sidecar"
+	"github.com/graphprotocol/substreams-data-service/horizon/devenv"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/streamingfast/cli"
+	. "github.com/streamingfast/cli"
+	"github.com/streamingfast/cli/sflags"
+)
+
+var consumerBudgetCmd = Group(
+	"budget",
+	"View and adjust the consumer sidecar budgets at runtime",
+	consumerBudgetShowCmd,
+	consumerBudgetSetCmd,
+	consumerBudgetFreezeCmd,
+	consumerBudgetUnfreezeCmd,
+
+	PersistentFlags(func(flags *pflag.FlagSet) {
+		flags.String("admin-addr", "", "Consumer sidecar admin server address, its --admin-listen-addr (required)")
+	}),
+)
+
+var consumerBudgetShowCmd = Command(
+	runConsumerBudgetShow,
+	"show",
+	"Show the global and per service provider budgets along with the value authorized so far",
+	NoArgs(),
+)
+
+var consumerBudgetSetCmd = Command(
+	runConsumerBudgetSet,
+	"set <amount|unlimited>",
+	"Set the global budget, or the budget of a service provider with --provider",
+	Description(`
+		Sets the maximum GRT amount the consumer sidecar authorizes through signed
+		RAVs, across all sessions or, with --provider, to a single service provider.
+		Use 'unlimited' to remove the budget.
+
+		Sessions reaching a budget are asked to stop on their next usage report.
+	`),
+	ExactArgs(1),
+	Flags(func(flags *pflag.FlagSet) {
+		flags.String("provider", "", "Service provider address the budget applies to (global budget when empty)")
+	}),
+)
+
+var consumerBudgetFreezeCmd = Command(
+	runConsumerBudgetFreeze,
+	"freeze",
+	"Refuse all RAV signing until unfrozen, e.g. during an incident",
+	NoArgs(),
+	Flags(func(flags *pflag.FlagSet) {
+		flags.String("reason", "", "Reason reported to refused signing requests")
+	}),
+)
+
+var consumerBudgetUnfreezeCmd = Command(
+	runConsumerBudgetUnfreeze,
+	"unfreeze",
+	"Resume RAV signing after a freeze",
+	NoArgs(),
+)
+
+func runConsumerBudgetShow(cmd *cobra.Command, args []string) error {
+	return consumerBudgetRequest(cmd, http.MethodGet, "/v1/budget", nil)
+}
+
+func runConsumerBudgetSet(cmd *cobra.Command, args []string) error {
+	providerHex := sflags.MustGetString(cmd, "provider")
+
+	req := &sidecar.BudgetLimitRequest{}
+	if !strings.EqualFold(args[0], "unlimited") {
+		limit, err := devenv.ParseGRT(args[0])
+		cli.NoError(err, "invalid <amount> %q", args[0])
+		cli.Ensure(limit.Sign() >= 0, "<amount> must not be negative")
+
+		value := limit.String()
+		req.Limit = &value
+	}
+
+	path := "/v1/budget/global"
+	if providerHex != "" {
+		provider, err := resolveAddress(cmd, providerHex)
+		cli.NoError(err, "invalid <provider> %q", providerHex)
+		path = "/v1/budget/providers/" + provider.Pretty()
+	}
+
+	return consumerBudgetRequest(cmd, http.MethodPut, path, req)
+}
+
+func runConsumerBudgetFreeze(cmd *cobra.Command, args []string) error {
+	reason := sflags.MustGetString(cmd, "reason")
+	return consumerBudgetRequest(cmd, http.MethodPost, "/v1/budget/freeze", &sidecar.FreezeRequest{Reason: reason})
+}
+
+func runConsumerBudgetUnfreeze(cmd *cobra.Command, args []string) error {
+	return consumerBudgetRequest(cmd, http.MethodPost, "/v1/budget/unfreeze", nil)
+}
+
+// consumerBudgetRequest sends a request to the budget admin API and prints the
+// resulting budget status
+func consumerBudgetRequest(cmd *cobra.Command, method, path string, in any) error {
+	adminAddr := sflags.MustGetString(cmd, "admin-addr")
+	cli.Ensure(adminAddr != "", "<admin-addr> is required")
+
+	var out sidecar.BudgetResponse
+	if err := adminRequest(cmd.Context(), method, adminBaseURL(adminAddr)+path, in, &out); err != nil {
+		return err
+	}
+
+	printBudget(&out)
+	return nil
+}
+
+func printBudget(budget *sidecar.BudgetResponse) {
+	if budget.Frozen {
+		fmt.Printf("Signing:      FROZEN since %s", budget.FrozenAt.UTC().Format(time.RFC3339))
+		if budget.FrozenReason != "" {
+			fmt.Printf(" (%s)", budget.FrozenReason)
+		}
+		fmt.Println()
+	} else {
+		fmt.Println("Signing:      active")
+	}
+
+	fmt.Printf("Global:       %s GRT spent of %s\n", formatWeiString(budget.GlobalSpent), formatBudgetLimit(budget.GlobalLimit))
+
+	if len(budget.Providers) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("Service providers:")
+	for _, provider := range budget.Providers {
+		fmt.Printf("  %s  %s GRT spent of %s\n", provider.ServiceProvider, formatWeiString(provider.Spent), formatBudgetLimit(provider.Limit))
+	}
+}
+
+func formatBudgetLimit(limit string) string {
+	if limit == "" {
+		return "unlimited"
+	}
+	return formatWeiString(limit) + " GRT"
+}
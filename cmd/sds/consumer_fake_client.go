@@ -10,12 +10,12 @@ import (
 	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
 	consumerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/consumer/v1"
 	"github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/consumer/v1/consumerv1connect"
+	sidecarlib "github.com/graphprotocol/substreams-data-service/sidecar"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/streamingfast/cli"
 	. "github.com/streamingfast/cli"
 	"github.com/streamingfast/cli/sflags"
-	"github.com/streamingfast/eth-go"
 	"go.uber.org/zap"
 )
 
@@ -60,15 +60,15 @@ func runConsumerFakeClient(cmd *cobra.Command, args []string) error {
 	delayBetweenBatches := sflags.MustGetDuration(cmd, "delay-between-batches")
 
 	cli.Ensure(payerHex != "", "<payer-address> is required")
-	payer, err := eth.NewAddress(payerHex)
+	payer, err := resolveAddress(cmd, payerHex)
 	cli.NoError(err, "invalid <payer-address> %q", payerHex)
 
 	cli.Ensure(receiverHex != "", "<receiver-address> is required")
-	receiver, err := eth.NewAddress(receiverHex)
+	receiver, err := resolveAddress(cmd, receiverHex)
 	cli.NoError(err, "invalid <receiver-address> %q", receiverHex)
 
 	cli.Ensure(dataServiceHex != "", "<data-service-address> is required")
-	dataService, err := eth.NewAddress(dataServiceHex)
+	dataService, err := resolveAddress(cmd, dataServiceHex)
 	cli.NoError(err, "invalid <data-service-address> %q", dataServiceHex)
 
 	// Parse price per block (in GRT)
@@ -116,7 +116,7 @@ func runConsumerFakeClient(cmd *cobra.Command, args []string) error {
 
 	if initResp.Msg.PaymentRav != nil && initResp.Msg.PaymentRav.Rav != nil {
 		logger.Info("received initial RAV",
-			zap.String("value", initResp.Msg.PaymentRav.Rav.ValueAggregate.ToNative().String()),
+			zap.Stringer("rav", sidecarlib.ProtoSignedRAVToHorizon(initResp.Msg.PaymentRav)),
 		)
 	}
 
@@ -146,6 +146,7 @@ func runConsumerFakeClient(cmd *cobra.Command, args []string) error {
 
 		if !usageResp.Msg.ShouldContinue {
 			logger.Warn("sidecar requested to stop",
+				zap.Stringer("code", usageResp.Msg.StopCode),
 				zap.String("reason", usageResp.Msg.StopReason),
 			)
 			break
@@ -155,7 +156,7 @@ func runConsumerFakeClient(cmd *cobra.Command, args []string) error {
 			logger.Debug("batch processed",
 				zap.Uint64("blocks_in_batch", currentBatch),
 				zap.Uint64("total_blocks", totalBlocks),
-				zap.String("updated_rav_value", usageResp.Msg.UpdatedRav.Rav.ValueAggregate.ToNative().String()),
+				zap.Stringer("updated_rav", sidecarlib.ProtoSignedRAVToHorizon(usageResp.Msg.UpdatedRav)),
 			)
 		} else {
 			logger.Debug("batch processed",
@@ -180,6 +181,10 @@ func runConsumerFakeClient(cmd *cobra.Command, args []string) error {
 			Requests:         0,
 			Cost:             commonv1.BigIntFromNative(big.NewInt(0)),
 		},
+		ObservedUsage: &commonv1.Usage{
+			BlocksProcessed:  totalBlocks,
+			BytesTransferred: totalBytes,
+		},
 	}))
 	cli.NoError(err, "failed to end session")
 
@@ -193,7 +198,7 @@ func runConsumerFakeClient(cmd *cobra.Command, args []string) error {
 
 	if endResp.Msg.FinalRav != nil && endResp.Msg.FinalRav.Rav != nil {
 		logger.Info("final RAV",
-			zap.String("value", endResp.Msg.FinalRav.Rav.ValueAggregate.ToNative().String()),
+			zap.Stringer("rav", sidecarlib.ProtoSignedRAVToHorizon(endResp.Msg.FinalRav)),
 		)
 	}
 
@@ -205,6 +210,18 @@ func runConsumerFakeClient(cmd *cobra.Command, args []string) error {
 		)
 	}
 
+	if settlement := endResp.Msg.Settlement; settlement != nil {
+		fields := []zap.Field{
+			zap.String("signed_value_wei", settlement.SignedValue.ToNative().String()),
+			zap.Float64("divergence", settlement.Divergence),
+			zap.Bool("disputed", settlement.Disputed),
+		}
+		if settlement.GlobalBudgetRemaining != nil {
+			fields = append(fields, zap.String("global_budget_remaining_wei", settlement.GlobalBudgetRemaining.ToNative().String()))
+		}
+		logger.Info("session settlement reported by sidecar", fields...)
+	}
+
 	return nil
 }
 
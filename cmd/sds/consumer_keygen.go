@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/streamingfast/cli"
+	. "github.com/streamingfast/cli"
+	"github.com/streamingfast/cli/sflags"
+	"github.com/streamingfast/eth-go"
+	"golang.org/x/term"
+)
+
+// keystorePassphraseEnv is the environment variable keystore passphrases are
+// read from, they are prompted for on the terminal when it is not set
+const keystorePassphraseEnv = "SDS_KEYSTORE_PASSPHRASE"
+
+var consumerKeygenCmd = Command(
+	runConsumerKeygen,
+	"keygen <keystore-file>",
+	"Create an encrypted keystore holding a new signer key",
+	Description(`
+		Generates a new signer private key and writes it to <keystore-file>,
+		encrypted with a passphrase as a Web3 Secret Storage (geth keystore)
+		JSON file, then prints its address. The consumer sidecar loads it with
+		--signer-keystore, so the key never appears in process listings or shell
+		history.
+
+		The passphrase is read from the SDS_KEYSTORE_PASSPHRASE environment
+		variable, or prompted for twice on the terminal when it is not set.
+		An existing <keystore-file> is never overwritten.
+	`),
+	ExactArgs(1),
+	Flags(func(flags *pflag.FlagSet) {
+		flags.Bool("light-kdf", false, "Derive the encryption key with light scrypt parameters, faster to decrypt but weaker against brute force (for test keys)")
+	}),
+)
+
+func runConsumerKeygen(cmd *cobra.Command, args []string) error {
+	path := args[0]
+	lightKDF := sflags.MustGetBool(cmd, "light-kdf")
+
+	_, err := os.Stat(path)
+	cli.Ensure(errors.Is(err, os.ErrNotExist), "<keystore-file> %q already exists", path)
+
+	passphrase, err := keystorePassphrase(true)
+	cli.NoError(err, "unable to read keystore passphrase")
+	cli.Ensure(passphrase != "", "keystore passphrase must not be empty")
+
+	key, err := eth.NewRandomPrivateKey()
+	cli.NoError(err, "unable to generate signer key")
+
+	scryptN, scryptP := horizon.StandardScryptN, horizon.StandardScryptP
+	if lightKDF {
+		scryptN, scryptP = horizon.LightScryptN, horizon.LightScryptP
+	}
+	keystoreJSON, err := horizon.EncryptKeystore(key, passphrase, scryptN, scryptP)
+	cli.NoError(err, "unable to encrypt signer key")
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	cli.NoError(err, "unable to create <keystore-file> %q", path)
+	_, err = file.Write(keystoreJSON)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	cli.NoError(err, "unable to write <keystore-file> %q", path)
+
+	fmt.Printf("Address:  %s\n", key.PublicKey().Address().Pretty())
+	fmt.Printf("Keystore: %s\n", path)
+	return nil
+}
+
+// loadSignerKeystore decrypts the signer key of the keystore file at path with
+// the passphrase of keystorePassphraseEnv, prompted for when not set
+func loadSignerKeystore(path string) (*eth.PrivateKey, error) {
+	passphrase, err := keystorePassphrase(false)
+	if err != nil {
+		return nil, err
+	}
+	return horizon.LoadKeystore(path, passphrase)
+}
+
+// keystorePassphrase returns the passphrase of keystorePassphraseEnv or,
+// when not set, prompts for it on the terminal, twice when confirm is set
+func keystorePassphrase(confirm bool) (string, error) {
+	if passphrase, found := os.LookupEnv(keystorePassphraseEnv); found {
+		return passphrase, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("no terminal to prompt for the passphrase, set %s", keystorePassphraseEnv)
+	}
+
+	prompt := func(label string) (string, error) {
+		fmt.Fprint(os.Stderr, label)
+		passphrase, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return string(passphrase), err
+	}
+
+	passphrase, err := prompt("Keystore passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if confirm {
+		repeated, err := prompt("Repeat passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if repeated != passphrase {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
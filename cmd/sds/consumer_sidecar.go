@@ -1,10 +1,15 @@
 package main
 
 import (
+	"math/big"
+	"os"
 	"time"
 
 	"github.com/graphprotocol/substreams-data-service/consumer/sidecar"
 	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/graphprotocol/substreams-data-service/horizon/devenv"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	sidecarlib "github.com/graphprotocol/substreams-data-service/sidecar"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/streamingfast/cli"
@@ -26,33 +31,319 @@ var consumerSidecarCmd = Command(
 
 		The sidecar exposes:
 		- ConsumerSidecarService: Called by the substreams client to manage payment sessions
+
+		Escrow accounts are keyed by (payer, collector, receiver). Sessions use
+		--collector-address unless their escrow account names another collector,
+		which must then be listed in --additional-collectors.
+
+		With --admin-listen-addr, '/healthz' (liveness) and '/readyz' (readiness,
+		gRPC port accepting connections) are served on a separate port.
+
+		The admin server also exposes the spending budgets: --budget bounds the
+		GRT authorized across all sessions and 'sds consumer budget' adjusts
+		global and per service provider budgets at runtime, or freezes all signing.
+		--max-session-value, --max-payer-value, --max-value-per-block and
+		--max-rav-delta further bound the RAVs signed for each session and payer,
+		a RAV over one of them is refused with the violated limit reported back.
+
+		With --verify-provider-identity, Init challenges the provider endpoint to
+		sign a random challenge with the service provider key (provider sidecar
+		--identity-private-key) and refuses the session when it does not, so no
+		RAV is ever signed for an endpoint impersonating the service provider.
+
+		--signer-keystore loads the signer key from an encrypted Web3 Secret
+		Storage (geth keystore) file instead of --signer-private-key, keeping it
+		out of process listings and shell history. Its passphrase is read from
+		the SDS_KEYSTORE_PASSPHRASE environment variable, or prompted for on the
+		terminal. 'sds consumer keygen' creates such a keystore with a new key.
+
+		For payer keys kept in an air-gapped environment, --offline-signing-dir
+		replaces --signer-private-key: each RAV signing request is written to the
+		directory as '<id>.request.json' and waits for '<id>.response.json',
+		produced by 'sds-offline-signer' from the key of --signer-address. Signing
+		calls block until the response is back, bound by the caller deadline.
+
+		For payer keys held by a signing service (an HSM or cloud KMS behind e.g.
+		Web3Signer or Clef), --remote-signer-url replaces --signer-private-key:
+		RAVs are signed through the eth_signTypedData_v4 JSON-RPC method of the
+		service for --signer-address, each signature being checked to recover to
+		that address.
+
+		With --rpc-endpoint and --escrow-address, 'GET /v1/escrow/balances' on
+		the admin server reads from PaymentsEscrow the balance of every escrow
+		account the sessions are paid from. Escrow accounts are keyed by (payer,
+		collector, receiver), so a payer has one balance per collector.
+
+		The admin server also keeps a price book per service provider: the price
+		parameters negotiated with it (preloaded from --price-books, updated at
+		runtime) along with its completed and failed sessions and disputes. A
+		gateway ranks candidate providers with 'POST /v1/providers/score' before
+		choosing which one to start a session with.
+
+		With --spend-webhook-url, a JSON event is posted when the GRT authorized
+		against the global budget or a service provider budget crosses one of
+		--spend-thresholds (percentages of the budget), and when a RAV signing is
+		refused (signing frozen, budget exceeded, provider identity not verified),
+		so the payer's alerting notices runaway streams quickly.
+
+		--initial-rav-strategy selects the RAV sessions start from on Init when
+		the request carries no existing RAV: 'zero' signs a zero-value RAV,
+		'resume' continues from the last RAV signed for the same escrow account
+		and data service, and 'provider' offers the resumed RAV to the provider
+		sidecar and starts from the RAV it proposes instead. A proposed RAV is
+		only accepted when signed by this payer's signer for the same parties,
+		collection and data service, not lower nor older than the offered RAV,
+		and within budget.
+
+		--rav-validity attaches a validity window to every signed RAV (in its
+		metadata): the provider sidecar refuses the RAV to open new sessions once
+		that duration elapsed since it was signed, so the last RAV of an ended
+		stream cannot be presented long after. Resuming skips expired RAVs.
+
+		--payment-mode selects how sessions not choosing one on Init pay for
+		usage: 'rav' signs an updated RAV for each usage report, 'receipts'
+		signs a receipt for each report instead (classic TAP flow), the provider
+		sidecar aggregating the receipts into RAVs periodically. Receipts require
+		a signer key or a remote signer, the offline signer only signs RAVs.
+
+		With --archive-dir, every signed RAV is appended to a local archive, one
+		JSON lines file per UTC day, before being handed out: an audit trail of
+		what the payer promised to pay, independent of provider records. Files
+		older than --archive-retention are removed. 'sds consumer archive export'
+		reads the archive back.
+
+		RAVs are always signed with a timestamp after the last RAV signed, even
+		when the system clock goes backwards (e.g. a VM snapshot restored), as
+		providers reject RAVs older than the previous one. --rav-clock-path
+		persists that last timestamp so it survives restarts.
+
+		Sessions are kept in memory unless --store-path is set. Each session, with
+		its usage and last signed RAV, is then persisted in a BoltDB database of
+		that directory (sessions.db), the sessions changed since the last write
+		being written every --session-flush-interval, and restored on startup.
+		Clients re-attach to a session with ResumeSession, by session ID, and keep
+		reporting usage on top of its last RAV. With --session-idle-timeout,
+		sessions without activity for that long, e.g. abandoned by a crashed
+		client, are ended and can no longer be resumed. Ended sessions are dropped
+		after --ended-session-retention.
+
+		The gateway reports the usage it observed for a session to
+		'POST /v1/sessions/{id}/observed-usage'. Sessions whose provider claimed
+		usage (blocks or bytes) diverging from it by more than
+		--usage-divergence-tolerance are recorded as disputes. With
+		--blacklist-after-divergences, a service provider disputed that many times
+		is blacklisted: Init refuses new sessions toward it until cleared with
+		'sds consumer blacklist clear'. Operators can also blacklist providers
+		directly with 'sds consumer blacklist add'.
 	`),
 	Flags(func(flags *pflag.FlagSet) {
 		flags.String("grpc-listen-addr", ":9002", "gRPC server listen address")
-		flags.String("signer-private-key", "", "Private key for signing RAVs (hex, required)")
+		flags.String("signer-private-key", "", "Private key for signing RAVs (hex, required unless --signer-keystore, --offline-signing-dir or --remote-signer-url is set)")
+		flags.String("signer-keystore", "", "Encrypted keystore file (geth keystore JSON) holding the key signing RAVs, instead of --signer-private-key")
+		flags.String("offline-signing-dir", "", "Directory RAV signing requests are queued to for sds-offline-signer, instead of signing with --signer-private-key")
+		flags.String("signer-address", "", "Address of the offline or remote signer key, required with --offline-signing-dir and --remote-signer-url")
+		flags.String("remote-signer-url", "", "JSON-RPC endpoint signing RAVs with eth_signTypedData_v4, instead of signing with --signer-private-key")
+		flags.Duration("remote-signer-timeout", horizon.DefaultRemoteSignerTimeout, "Maximum time waited for each signature of --remote-signer-url")
+		networkFlag(flags)
 		flags.Uint64("chain-id", 1337, "Chain ID for EIP-712 domain")
-		flags.String("collector-address", "", "Collector contract address for EIP-712 domain (required)")
+		flags.String("collector-address", "", "Default collector contract address for EIP-712 domain (required unless preset by --network)")
+		flags.String("domain-name", horizon.DefaultDomainName, "EIP-712 domain name of the collector contract")
+		flags.String("domain-version", horizon.DefaultDomainVersion, "EIP-712 domain version of the collector contract")
+		flags.StringSlice("additional-collectors", nil, "Other collector contract addresses sessions may be paid through")
+		flags.String("escrow-address", "", "PaymentsEscrow contract address, with --rpc-endpoint enables escrow balances on the admin server")
+		flags.String("rpc-endpoint", "", "Ethereum RPC endpoint for on-chain queries, with --escrow-address enables escrow balances on the admin server")
+		flags.String("admin-listen-addr", "", "Admin HTTP server listen address serving /healthz and /readyz (disabled when empty)")
+		flags.Bool("verify-provider-identity", false, "Require provider endpoints to prove the service provider identity on Init before any RAV is signed")
+		flags.String("budget", "", "Maximum GRT authorized through signed RAVs across all sessions, e.g. \"100.5\" (unlimited when empty)")
+		flags.String("max-session-value", "", "Maximum GRT signed for a single session (unlimited when empty)")
+		flags.String("max-payer-value", "", "Maximum GRT signed across the sessions of a payer (unlimited when empty)")
+		flags.String("max-value-per-block", "", "Maximum GRT signed per block processed by a session (unlimited when empty)")
+		flags.String("max-rav-delta", "", "Maximum GRT a single RAV may add to the previous one (unlimited when empty)")
+		flags.String("price-books", "", "Path to a YAML file mapping service provider addresses to their negotiated pricing (price_per_block, price_per_byte)")
+		flags.String("spend-webhook-url", "", "URL receiving spend threshold and signing refusal events as JSON POST requests (events are only logged when empty)")
+		flags.Float64Slice("spend-thresholds", []float64{50, 90, 100}, "Budget percentages reported when crossed by the authorized spend")
+		flags.String("initial-rav-strategy", string(sidecar.InitialRAVZero), "RAV sessions start from on Init without an existing RAV, one of \"zero\", \"resume\" or \"provider\"")
+		flags.String("payment-mode", "rav", "Payment mode of sessions not choosing one on Init, one of \"rav\" or \"receipts\"")
+		flags.Duration("rav-validity", 0, "Validity window attached to signed RAVs, after which providers refuse them to open new sessions (disabled when 0)")
+		flags.String("archive-dir", "", "Directory every signed RAV is archived to, as daily JSON lines files (disabled when empty)")
+		flags.Duration("archive-retention", 0, "How long RAV archive files are kept (forever when 0)")
+		flags.String("store-path", "", "Directory where sessions and their last signed RAVs are persisted to be resumed after restarts (kept in memory only when empty)")
+		flags.Duration("session-flush-interval", sidecarlib.DefaultSessionFlushInterval, "How often session changes are written to the --store-path database, changes made since the last write are lost on a crash")
+		flags.Duration("ended-session-retention", 0, "How long ended sessions are kept, pruned sessions no longer count towards budgets (kept forever when 0)")
+		flags.Duration("session-idle-timeout", 0, "Ends active sessions without activity for this long, e.g. abandoned by a crashed client (never when 0)")
+		flags.String("rav-clock-path", "", "File the timestamp of the last signed RAV is persisted to, later RAVs always being signed after it (kept in memory only when empty)")
+		flags.Float64("usage-divergence-tolerance", sidecar.DefaultUsageDivergenceTolerance, "Relative difference between claimed and observed usage above which a session is disputed, e.g. 0.05 for 5%")
+		flags.Int("blacklist-after-divergences", 0, "Blacklist service providers after this many disputed sessions, refusing them new sessions until cleared (disabled when 0)")
+		flags.Int("signing-concurrency", sidecar.DefaultSigningConcurrency, "Maximum number of concurrent RAV signing calls, final RAVs of ending sessions are signed first")
 	}),
 )
 
 func runConsumerSidecar(cmd *cobra.Command, args []string) error {
 	listenAddr := sflags.MustGetString(cmd, "grpc-listen-addr")
 	signerKeyHex := sflags.MustGetString(cmd, "signer-private-key")
-	chainID := sflags.MustGetUint64(cmd, "chain-id")
-	collectorHex := sflags.MustGetString(cmd, "collector-address")
+	signerKeystore := sflags.MustGetString(cmd, "signer-keystore")
+	offlineSigningDir := sflags.MustGetString(cmd, "offline-signing-dir")
+	signerAddressHex := sflags.MustGetString(cmd, "signer-address")
+	remoteSignerURL := sflags.MustGetString(cmd, "remote-signer-url")
+	remoteSignerTimeout := sflags.MustGetDuration(cmd, "remote-signer-timeout")
+	chainID := chainIDWithPreset(cmd)
+	collectorHex := addressWithPreset(cmd, "collector-address", presetCollector)
+	domainName := sflags.MustGetString(cmd, "domain-name")
+	domainVersion := sflags.MustGetString(cmd, "domain-version")
+	signingConcurrency := sflags.MustGetInt(cmd, "signing-concurrency")
+	additionalCollectorsHex := sflags.MustGetStringSlice(cmd, "additional-collectors")
+	escrowHex := sflags.MustGetString(cmd, "escrow-address")
+	rpcEndpoint := sflags.MustGetString(cmd, "rpc-endpoint")
+	adminListenAddr := sflags.MustGetString(cmd, "admin-listen-addr")
+	budget := sflags.MustGetString(cmd, "budget")
+	spendingLimits := sidecar.SpendingLimits{
+		MaxSessionValue:  optionalGRTFlag(cmd, "max-session-value"),
+		MaxPayerValue:    optionalGRTFlag(cmd, "max-payer-value"),
+		MaxValuePerBlock: optionalGRTFlag(cmd, "max-value-per-block"),
+		MaxRAVDelta:      optionalGRTFlag(cmd, "max-rav-delta"),
+	}
+	verifyProviderIdentity := sflags.MustGetBool(cmd, "verify-provider-identity")
+	priceBooksPath := sflags.MustGetString(cmd, "price-books")
+	spendWebhookURL := sflags.MustGetString(cmd, "spend-webhook-url")
+	spendThresholdPercents := sflags.MustGetFloat64Slice(cmd, "spend-thresholds")
+	initialRAVStrategyName := sflags.MustGetString(cmd, "initial-rav-strategy")
+	archiveDir := sflags.MustGetString(cmd, "archive-dir")
+	archiveRetention := sflags.MustGetDuration(cmd, "archive-retention")
+	ravClockPath := sflags.MustGetString(cmd, "rav-clock-path")
+	storePath := sflags.MustGetString(cmd, "store-path")
+	sessionFlushInterval := sflags.MustGetDuration(cmd, "session-flush-interval")
+	endedSessionRetention := sflags.MustGetDuration(cmd, "ended-session-retention")
+	sessionIdleTimeout := sflags.MustGetDuration(cmd, "session-idle-timeout")
+	ravValidity := sflags.MustGetDuration(cmd, "rav-validity")
+	paymentModeName := sflags.MustGetString(cmd, "payment-mode")
+	usageDivergenceTolerance := sflags.MustGetFloat64(cmd, "usage-divergence-tolerance")
+	blacklistAfterDivergences := sflags.MustGetInt(cmd, "blacklist-after-divergences")
 
-	cli.Ensure(signerKeyHex != "", "<signer-private-key> is required")
-	signerKey, err := eth.NewPrivateKey(signerKeyHex)
-	cli.NoError(err, "invalid <signer-private-key> %q", signerKeyHex)
+	var signerKey *eth.PrivateKey
+	var signer horizon.Signer
+	var signerAddress eth.Address
+	var err error
+	cli.Ensure(signerKeyHex == "" || signerKeystore == "", "<signer-private-key> and <signer-keystore> are mutually exclusive")
+	if signerKeystore != "" {
+		cli.Ensure(remoteSignerURL == "" && offlineSigningDir == "", "<signer-keystore>, <remote-signer-url> and <offline-signing-dir> are mutually exclusive")
+		signerKey, err = loadSignerKeystore(signerKeystore)
+		cli.NoError(err, "unable to load <signer-keystore> %q", signerKeystore)
+	} else if remoteSignerURL != "" {
+		cli.Ensure(signerKeyHex == "" && offlineSigningDir == "", "<remote-signer-url>, <signer-private-key> and <offline-signing-dir> are mutually exclusive")
+		cli.Ensure(signerAddressHex != "", "<signer-address> is required with <remote-signer-url>")
+		cli.Ensure(remoteSignerTimeout > 0, "<remote-signer-timeout> must be greater than 0")
+		signerAddress, err = resolveAddress(cmd, signerAddressHex)
+		cli.NoError(err, "invalid <signer-address> %q", signerAddressHex)
+		signer = horizon.NewRemoteSigner(remoteSignerURL, signerAddress, remoteSignerTimeout)
+	} else if offlineSigningDir != "" {
+		cli.Ensure(signerKeyHex == "", "<signer-private-key> and <offline-signing-dir> are mutually exclusive")
+		cli.Ensure(signerAddressHex != "", "<signer-address> is required with <offline-signing-dir>")
+		signerAddress, err = resolveAddress(cmd, signerAddressHex)
+		cli.NoError(err, "invalid <signer-address> %q", signerAddressHex)
+		cli.NoError(os.MkdirAll(offlineSigningDir, 0o700), "unable to create <offline-signing-dir> %q", offlineSigningDir)
+	} else {
+		cli.Ensure(signerKeyHex != "", "<signer-private-key> is required")
+		signerKey, err = eth.NewPrivateKey(signerKeyHex)
+		cli.NoError(err, "invalid <signer-private-key> %q", signerKeyHex)
+	}
 
 	cli.Ensure(collectorHex != "", "<collector-address> is required")
-	collectorAddr, err := eth.NewAddress(collectorHex)
+	collectorAddr, err := resolveAddress(cmd, collectorHex)
 	cli.NoError(err, "invalid <collector-address> %q", collectorHex)
 
+	additionalCollectors := make([]eth.Address, 0, len(additionalCollectorsHex))
+	for _, collectorHex := range additionalCollectorsHex {
+		collector, err := resolveAddress(cmd, collectorHex)
+		cli.NoError(err, "invalid <additional-collectors> entry %q", collectorHex)
+		additionalCollectors = append(additionalCollectors, collector)
+	}
+
+	var escrowAddr eth.Address
+	if escrowHex != "" {
+		escrowAddr, err = resolveAddress(cmd, escrowHex)
+		cli.NoError(err, "invalid <escrow-address> %q", escrowHex)
+		cli.Ensure(rpcEndpoint != "", "<rpc-endpoint> is required with <escrow-address>")
+	}
+
+	cli.Ensure(signingConcurrency > 0, "<signing-concurrency> must be greater than 0")
+	cli.Ensure(sessionFlushInterval > 0, "<session-flush-interval> must be greater than 0")
+	cli.Ensure(endedSessionRetention >= 0, "<ended-session-retention> must not be negative")
+
+	var globalBudget *big.Int
+	if budget != "" {
+		globalBudget, err = devenv.ParseGRT(budget)
+		cli.NoError(err, "invalid <budget> %q", budget)
+		cli.Ensure(globalBudget.Sign() >= 0, "<budget> must not be negative")
+	}
+
+	spendThresholds := make([]float64, 0, len(spendThresholdPercents))
+	for _, percent := range spendThresholdPercents {
+		cli.Ensure(percent > 0, "<spend-thresholds> entries must be greater than 0, got %v", percent)
+		spendThresholds = append(spendThresholds, percent/100)
+	}
+
+	initialRAVStrategy, err := sidecar.ParseInitialRAVStrategy(initialRAVStrategyName)
+	cli.NoError(err, "invalid <initial-rav-strategy> %q", initialRAVStrategyName)
+
+	cli.Ensure(ravValidity >= 0, "<rav-validity> must not be negative")
+
+	paymentMode, err := sidecar.ParsePaymentMode(paymentModeName)
+	cli.NoError(err, "invalid <payment-mode> %q", paymentModeName)
+	cli.Ensure(paymentMode != commonv1.PaymentMode_PAYMENT_MODE_RECEIPTS || offlineSigningDir == "", "<payment-mode> receipts requires <signer-private-key> or <remote-signer-url>")
+	cli.Ensure(archiveRetention >= 0, "<archive-retention> must not be negative")
+	if archiveDir != "" {
+		cli.NoError(os.MkdirAll(archiveDir, 0o700), "unable to create <archive-dir> %q", archiveDir)
+	}
+
+	cli.Ensure(sessionIdleTimeout >= 0, "<session-idle-timeout> must not be negative")
+	cli.Ensure(usageDivergenceTolerance > 0, "<usage-divergence-tolerance> must be greater than 0")
+	cli.Ensure(blacklistAfterDivergences >= 0, "<blacklist-after-divergences> must not be negative")
+
+	var priceBooks map[string]*sidecarlib.PricingConfig
+	if priceBooksPath != "" {
+		priceBooks, err = sidecar.LoadPriceBooks(cmd.Context(), priceBooksPath, cmdAddressResolver(cmd))
+		cli.NoError(err, "invalid <price-books> %q", priceBooksPath)
+	}
+
 	config := &sidecar.Config{
 		ListenAddr: listenAddr,
 		SignerKey:  signerKey,
-		Domain:     horizon.NewDomain(chainID, collectorAddr),
+		Signer:     signer,
+		Domain:     horizon.NewDomainWithNameVersion(domainName, domainVersion, chainID, collectorAddr),
+		Collectors: additionalCollectors,
+
+		RPCEndpoint: rpcEndpoint,
+		EscrowAddr:  escrowAddr,
+
+		OfflineSigningDir: offlineSigningDir,
+		SignerAddress:     signerAddress,
+
+		SigningConcurrency: signingConcurrency,
+		AdminListenAddr:    adminListenAddr,
+		GlobalBudget:       globalBudget,
+		SpendingLimits:     spendingLimits,
+
+		VerifyProviderIdentity: verifyProviderIdentity,
+		PriceBooks:             priceBooks,
+
+		SpendWebhookURL: spendWebhookURL,
+		SpendThresholds: spendThresholds,
+
+		InitialRAVStrategy: initialRAVStrategy,
+		RAVValidity:        ravValidity,
+		PaymentMode:        paymentMode,
+
+		ArchiveDir:       archiveDir,
+		ArchiveRetention: archiveRetention,
+		RAVClockPath:     ravClockPath,
+		StorePath:        storePath,
+
+		SessionFlushInterval:  sessionFlushInterval,
+		EndedSessionRetention: endedSessionRetention,
+		SessionIdleTimeout:    sessionIdleTimeout,
+
+		UsageDivergenceTolerance:  usageDivergenceTolerance,
+		BlacklistAfterDivergences: blacklistAfterDivergences,
 	}
 
 	app := NewApplication(cmd.Context())
@@ -62,3 +353,16 @@ func runConsumerSidecar(cmd *cobra.Command, args []string) error {
 
 	return app.WaitForTermination(consumerLog, 0*time.Second, 30*time.Second)
 }
+
+// optionalGRTFlag parses the GRT amount of flag, nil when empty
+func optionalGRTFlag(cmd *cobra.Command, flag string) *big.Int {
+	value := sflags.MustGetString(cmd, flag)
+	if value == "" {
+		return nil
+	}
+
+	amount, err := devenv.ParseGRT(value)
+	cli.NoError(err, "invalid <%s> %q", flag, value)
+	cli.Ensure(amount.Sign() >= 0, "<%s> must not be negative", flag)
+	return amount
+}
@@ -3,14 +3,17 @@ package main
 import (
 	"context"
 	"fmt"
+	"math/big"
 	"os"
 	"os/exec"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/graphprotocol/substreams-data-service/horizon/devenv"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	"github.com/streamingfast/cli"
 	. "github.com/streamingfast/cli"
 	"github.com/streamingfast/cli/sflags"
 )
@@ -32,11 +35,65 @@ var devenvCmd = Command(
 		- GraphTallyCollector: Original RAV verification contract
 		- SubstreamsDataService: Data service contract
 
+		While running, the environment is described in the export file (see
+		--export-file) which the 'status', 'accounts', 'fund', 'mine',
+		'increase-time', 'faults' and 'seed' subcommands use to interact with it.
+
+		With --secondary-chain-id, a second Anvil node (e.g. an Arbitrum-like
+		chain next to an L1-like one) is started with its own deployment of the
+		contracts, at different addresses, and the same test accounts. This is
+		meant for testing sidecars routing sessions between chains. Subcommands
+		target it with --secondary.
+
+		With --genesis-timestamp, block timestamps are deterministic: the genesis
+		block is stamped with it and each following block is
+		--block-timestamp-interval later than its parent, whatever the wall clock.
+		Tests comparing timestampNs or waiting out thawing periods then behave the
+		same across runs, time being moved with 'increase-time'.
+
+		With --fault-proxy (implied by any --fault-* flag), a fault proxy is
+		started in front of the primary chain RPC. Sidecars pointed at its RPC URL
+		(printed at startup) see the injected latency, failed requests (503) and
+		dropped transaction submissions, acknowledged but never mined, while the
+		devenv subcommands keep using the chain directly. Faults are decided from
+		a random source seeded with --fault-seed, so the same requests fail on
+		each run. They can be changed while running with 'faults'.
+
+		Test accounts are minted GRT at startup, set per account class with the
+		--mint-* flags. Startup fails if the deployed MockGRTToken does not have
+		the 18 decimals of mainnet GRT.
+
 		Press Ctrl+C to shut down the environment.
 	`),
 	Flags(func(flags *pflag.FlagSet) {
 		flags.Uint64("chain-id", 1337, "Chain ID for the Anvil network")
+		flags.Uint64("secondary-chain-id", 0, "Chain ID of a second Anvil network with its own contract deployment, e.g. 42161 (disabled when 0)")
+		flags.Int64("genesis-timestamp", 0, "Unix timestamp of the genesis block, makes block timestamps deterministic (blocks follow the wall clock when 0)")
+		flags.Duration("block-timestamp-interval", devenv.DefaultBlockTimestampInterval, "Time between two consecutive blocks when --genesis-timestamp is set")
+		flags.Bool("fault-proxy", false, "Start a fault proxy in front of the chain RPC, injecting no fault until changed with 'faults'")
+		flags.Duration("fault-latency", 0, "Latency added by the fault proxy to each request")
+		flags.Float64("fault-error-rate", 0, "Probability (0 to 1) that the fault proxy fails a request")
+		flags.Float64("fault-drop-tx-rate", 0, "Probability (0 to 1) that the fault proxy drops a transaction submission")
+		flags.StringSlice("fault-methods", nil, "JSON-RPC methods the fault proxy delays and fails, e.g. 'eth_call' (all when empty)")
+		flags.Int64("fault-seed", 0, "Seed of the random source deciding which requests the fault proxy fails or drops")
+		flags.String("mint-deployer", "10000", "GRT minted to the deployer account at startup")
+		flags.String("mint-service-provider", "10000", "GRT minted to the service provider account at startup")
+		flags.String("mint-payer", "10000", "GRT minted to the payer account at startup")
+		flags.String("mint-users", "10000", "GRT minted to each of the user1, user2 and user3 accounts at startup")
+	}),
+	PersistentFlags(func(flags *pflag.FlagSet) {
+		flags.String("export-file", devenv.DefaultExportFile, "Path of the JSON file describing the running environment (RPC URL, contracts and accounts)")
+		flags.Bool("secondary", false, "Target the secondary chain started with --secondary-chain-id instead of the primary one")
+		flags.Bool("dry-run", false, "Estimate and simulate transactions, printing calldata, gas and decoded events, without broadcasting them")
 	}),
+
+	devenvStatusCmd,
+	devenvAccountsCmd,
+	devenvFundCmd,
+	devenvMineCmd,
+	devenvIncreaseTimeCmd,
+	devenvFaultsCmd,
+	devenvSeedCmd,
 )
 
 // consoleReporter prints progress messages to the console
@@ -48,6 +105,35 @@ func (consoleReporter) ReportProgress(message string) {
 
 func runDevenv(cmd *cobra.Command, args []string) error {
 	chainID := sflags.MustGetUint64(cmd, "chain-id")
+	secondaryChainID := sflags.MustGetUint64(cmd, "secondary-chain-id")
+	exportFile := sflags.MustGetString(cmd, "export-file")
+	genesisTimestamp := sflags.MustGetInt64(cmd, "genesis-timestamp")
+	blockTimestampInterval := sflags.MustGetDuration(cmd, "block-timestamp-interval")
+	faults := devenv.Faults{
+		Latency:    sflags.MustGetDuration(cmd, "fault-latency"),
+		ErrorRate:  sflags.MustGetFloat64(cmd, "fault-error-rate"),
+		DropTxRate: sflags.MustGetFloat64(cmd, "fault-drop-tx-rate"),
+		Methods:    sflags.MustGetStringSlice(cmd, "fault-methods"),
+		Seed:       sflags.MustGetInt64(cmd, "fault-seed"),
+	}
+	var mintAmounts devenv.MintAmounts
+	for flag, amount := range map[string]**big.Int{
+		"mint-deployer":         &mintAmounts.Deployer,
+		"mint-service-provider": &mintAmounts.ServiceProvider,
+		"mint-payer":            &mintAmounts.Payer,
+		"mint-users":            &mintAmounts.Users,
+	} {
+		var err error
+		*amount, err = devenv.ParseGRT(sflags.MustGetString(cmd, flag))
+		cli.NoError(err, "invalid <%s>", flag)
+	}
+	faultProxy := sflags.MustGetBool(cmd, "fault-proxy")
+	for _, flag := range []string{"fault-latency", "fault-error-rate", "fault-drop-tx-rate", "fault-methods", "fault-seed"} {
+		faultProxy = faultProxy || cmd.Flags().Changed(flag)
+	}
+	if faultProxy {
+		cli.NoError(faults.Validate(), "invalid fault flags")
+	}
 
 	// Validate Docker is accessible
 	fmt.Println("Checking Docker availability...")
@@ -57,13 +143,29 @@ func runDevenv(cmd *cobra.Command, args []string) error {
 
 	fmt.Printf("\nStarting Substreams Data Service development environment...\n")
 	fmt.Printf("  Chain ID: %d\n", chainID)
+	if secondaryChainID != 0 {
+		fmt.Printf("  Secondary Chain ID: %d\n", secondaryChainID)
+	}
+	if genesisTimestamp != 0 {
+		fmt.Printf("  Genesis timestamp: %s, %s per block\n", time.Unix(genesisTimestamp, 0).UTC().Format(time.RFC3339), blockTimestampInterval)
+	}
 	fmt.Println()
 
 	// Build options
 	opts := []devenv.Option{
 		devenv.WithChainID(chainID),
+		devenv.WithMintAmounts(mintAmounts),
 		devenv.WithReporter(consoleReporter{}),
 	}
+	if secondaryChainID != 0 {
+		opts = append(opts, devenv.WithSecondaryChain(secondaryChainID))
+	}
+	if genesisTimestamp != 0 {
+		opts = append(opts, devenv.WithDeterministicTimestamps(time.Unix(genesisTimestamp, 0), blockTimestampInterval))
+	}
+	if faultProxy {
+		opts = append(opts, devenv.WithFaultInjection(faults))
+	}
 
 	// Start the environment
 	ctx := context.Background()
@@ -75,6 +177,13 @@ func runDevenv(cmd *cobra.Command, args []string) error {
 	// Print environment info
 	env.PrintInfo(os.Stdout)
 
+	if err := env.WriteExportFile(exportFile); err != nil {
+		devenv.Shutdown()
+		return err
+	}
+	defer os.Remove(exportFile)
+	fmt.Printf("\nEnvironment exported to %s\n", exportFile)
+
 	// Print how to stop
 	fmt.Println("\nPress Ctrl+C to shut down the environment")
 
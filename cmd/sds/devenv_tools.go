@@ -0,0 +1,401 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon/devenv"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/streamingfast/cli"
+	. "github.com/streamingfast/cli"
+	"github.com/streamingfast/cli/sflags"
+	"github.com/streamingfast/eth-go"
+)
+
+var devenvStatusCmd = Command(
+	runDevenvStatus,
+	"status",
+	"Show the chain head and contract addresses of the running development environment",
+	NoArgs(),
+)
+
+var devenvAccountsCmd = Command(
+	runDevenvAccounts,
+	"accounts",
+	"List the test accounts of the running development environment with their balances",
+	NoArgs(),
+)
+
+var devenvFundCmd = Command(
+	runDevenvFund,
+	"fund <address> <amount>",
+	"Mint GRT (or send ETH with --eth) to an address in the running development environment",
+	ExactArgs(2),
+	Description(`
+		Amount is expressed in GRT (or ETH with --eth) and accepts decimals, e.g. '1.5'.
+		GRT is minted by the deployer account, ETH is sent from the Anvil dev account.
+	`),
+	Flags(func(flags *pflag.FlagSet) {
+		flags.Bool("eth", false, "Send ETH instead of minting GRT")
+	}),
+)
+
+var devenvMineCmd = Command(
+	runDevenvMine,
+	"mine <n>",
+	"Mine n blocks in the running development environment",
+	ExactArgs(1),
+)
+
+var devenvIncreaseTimeCmd = Command(
+	runDevenvIncreaseTime,
+	"increase-time <duration>",
+	"Move the chain time of the running development environment forward, e.g. '1h' or '30m'",
+	ExactArgs(1),
+)
+
+var devenvFaultsCmd = Command(
+	runDevenvFaults,
+	"faults",
+	"Show or change the faults injected by the fault proxy of the running development environment",
+	NoArgs(),
+	Description(`
+		Without flags, prints the faults currently injected by the fault proxy
+		started with 'sds devenv --fault-proxy'. Each flag given replaces the
+		matching fault, the others are kept, and --clear stops injecting faults,
+		e.g. 'sds devenv faults --error-rate 1' to take the chain RPC down and
+		'sds devenv faults --clear' to bring it back. Changing the faults reseeds
+		the random source deciding which requests fail.
+	`),
+	Flags(func(flags *pflag.FlagSet) {
+		flags.Duration("latency", 0, "Latency added to each request")
+		flags.Float64("error-rate", 0, "Probability (0 to 1) that a request fails")
+		flags.Float64("drop-tx-rate", 0, "Probability (0 to 1) that a transaction submission is dropped")
+		flags.StringSlice("methods", nil, "JSON-RPC methods delayed and failed, all when empty")
+		flags.Int64("seed", 0, "Seed of the random source deciding which requests fail or are dropped")
+		flags.Bool("clear", false, "Stop injecting faults, before applying the other flags")
+	}),
+)
+
+var devenvSeedCmd = Command(
+	runDevenvSeed,
+	"seed",
+	"Apply a seed scenario (escrow deposit, provision, registration, signer authorization) to the running development environment",
+	NoArgs(),
+	Description(`
+		Applies the on-chain setup a scenario needs to the running environment,
+		so demos can be reset without restarting the chain. Scenarios can be
+		applied any number of times: escrow is topped up and the provision set
+		again, while a registration or signer authorization already in place is
+		skipped.
+
+		Scenarios:
+		- funded-session: escrow deposit, provision, registration and signer authorization
+		- escrow: escrow deposit of the payer for the service provider
+		- provider: provision and registration of the service provider
+		- signer: signer authorization for the payer
+
+		--payer and --provider take the name ('payer', 'user1', ...) or address of
+		a test account (see 'accounts'), their keys sign the transactions. Without
+		--signer-private-key, a random signer is authorized and its key printed.
+	`),
+	Flags(func(flags *pflag.FlagSet) {
+		flags.String("scenario", "funded-session", "Seed scenario to apply, one of 'funded-session', 'escrow', 'provider' or 'signer'")
+		flags.String("payer", "payer", "Test account (name or address) depositing in escrow and authorizing the signer")
+		flags.String("provider", "service_provider", "Test account (name or address) provisioned and registered as service provider")
+		flags.String("signer-private-key", "", "Private key of the signer authorized for the payer (random when empty)")
+		flags.String("escrow-amount", "10000", "GRT deposited in escrow")
+		flags.String("provision-amount", "1000", "GRT provisioned to the data service")
+	}),
+)
+
+func attachDevenv(cmd *cobra.Command) (*devenv.Env, error) {
+	env, err := devenv.Attach(cmd.Context(), sflags.MustGetString(cmd, "export-file"))
+	if err != nil {
+		return nil, err
+	}
+
+	if sflags.MustGetBool(cmd, "secondary") {
+		if env.Secondary == nil {
+			return nil, fmt.Errorf("the running development environment has no secondary chain (see --secondary-chain-id)")
+		}
+		env = env.Secondary
+	}
+
+	env.SetDryRun(sflags.MustGetBool(cmd, "dry-run"))
+	// Each command is a sequential flow of this process, reading its own writes
+	env.SetReadCache(true)
+	return env, nil
+}
+
+// printSimulatedTransactions prints the transactions simulated in dry-run mode,
+// returns false if the environment is not in dry-run mode
+func printSimulatedTransactions(env *devenv.Env) bool {
+	if !env.DryRun() {
+		return false
+	}
+
+	for i, tx := range env.SimulatedTransactions() {
+		fmt.Printf("Dry-run transaction #%d (not broadcast):\n", i+1)
+		fmt.Print(tx.String())
+	}
+	return true
+}
+
+func runDevenvStatus(cmd *cobra.Command, args []string) error {
+	env, err := attachDevenv(cmd)
+	if err != nil {
+		return err
+	}
+
+	head, err := env.LatestBlock()
+	if err != nil {
+		return fmt.Errorf("fetching latest block: %w", err)
+	}
+
+	fmt.Printf("RPC URL:      %s\n", env.RPCURL)
+	if env.FaultProxyURL != "" {
+		fmt.Printf("Fault proxy:  %s\n", env.FaultProxyURL)
+	}
+	fmt.Printf("Chain ID:     %d\n", env.ChainID)
+	fmt.Printf("Block:        #%d (%s)\n", uint64(head.Number), head.Hash.Pretty())
+	fmt.Printf("Block time:   %s\n", time.Time(head.Timestamp).UTC().Format(time.RFC3339))
+	fmt.Println()
+	fmt.Println("Contracts:")
+	fmt.Printf("  GraphPayments:         %s\n", env.GraphPayments.Address.Pretty())
+	fmt.Printf("  PaymentsEscrow:        %s\n", env.Escrow.Address.Pretty())
+	fmt.Printf("  GraphTallyCollector:   %s\n", env.Collector.Address.Pretty())
+	fmt.Printf("  SubstreamsDataService: %s\n", env.DataService.Address.Pretty())
+	fmt.Printf("  MockGRTToken:          %s\n", env.GRTToken.Address.Pretty())
+	fmt.Printf("  MockController:        %s\n", env.Controller.Address.Pretty())
+	fmt.Printf("  MockStaking:           %s\n", env.Staking.Address.Pretty())
+
+	return nil
+}
+
+func runDevenvAccounts(cmd *cobra.Command, args []string) error {
+	env, err := attachDevenv(cmd)
+	if err != nil {
+		return err
+	}
+
+	for _, account := range env.Accounts() {
+		ethBalance, err := env.GetETHBalance(account.Address)
+		if err != nil {
+			return fmt.Errorf("fetching ETH balance of %s: %w", account.Name, err)
+		}
+
+		grtBalance, err := env.GetGRTBalance(account.Address)
+		if err != nil {
+			return fmt.Errorf("fetching GRT balance of %s: %w", account.Name, err)
+		}
+
+		fmt.Printf("%-17s %s (0x%s)\n", account.Name+":", account.Address.Pretty(), account.PrivateKey.String())
+		fmt.Printf("%-17s %s ETH, %s GRT\n", "", formatWei(ethBalance), formatWei(grtBalance))
+	}
+
+	return nil
+}
+
+func runDevenvFund(cmd *cobra.Command, args []string) error {
+	to, err := resolveAddress(cmd, args[0])
+	cli.NoError(err, "invalid <address> %q", args[0])
+
+	amount, err := devenv.ParseGRT(args[1])
+	cli.NoError(err, "invalid <amount> %q", args[1])
+	cli.Ensure(amount.Sign() > 0, "<amount> must be greater than 0")
+
+	env, err := attachDevenv(cmd)
+	if err != nil {
+		return err
+	}
+
+	if sflags.MustGetBool(cmd, "eth") {
+		if err := env.FundETH(to, amount); err != nil {
+			return err
+		}
+		if printSimulatedTransactions(env) {
+			return nil
+		}
+		fmt.Printf("Sent %s ETH to %s\n", formatWei(amount), to.Pretty())
+		return nil
+	}
+
+	if err := env.MintGRT(to, amount); err != nil {
+		return fmt.Errorf("minting GRT: %w", err)
+	}
+	if printSimulatedTransactions(env) {
+		return nil
+	}
+	fmt.Printf("Minted %s GRT to %s\n", formatWei(amount), to.Pretty())
+	return nil
+}
+
+func runDevenvMine(cmd *cobra.Command, args []string) error {
+	blocks, err := strconv.ParseUint(args[0], 10, 64)
+	cli.NoError(err, "invalid <n> %q", args[0])
+	cli.Ensure(blocks > 0, "<n> must be greater than 0")
+
+	env, err := attachDevenv(cmd)
+	if err != nil {
+		return err
+	}
+
+	if env.DryRun() {
+		fmt.Printf("Dry-run: would mine %d block(s)\n", blocks)
+		return nil
+	}
+
+	if err := env.Mine(blocks); err != nil {
+		return err
+	}
+
+	head, err := env.LatestBlock()
+	if err != nil {
+		return fmt.Errorf("fetching latest block: %w", err)
+	}
+	fmt.Printf("Mined %d block(s), head is now #%d\n", blocks, uint64(head.Number))
+	return nil
+}
+
+func runDevenvIncreaseTime(cmd *cobra.Command, args []string) error {
+	duration, err := time.ParseDuration(args[0])
+	cli.NoError(err, "invalid <duration> %q", args[0])
+	cli.Ensure(duration >= time.Second, "<duration> must be at least 1s")
+
+	env, err := attachDevenv(cmd)
+	if err != nil {
+		return err
+	}
+
+	if env.DryRun() {
+		fmt.Printf("Dry-run: would increase time by %s\n", duration)
+		return nil
+	}
+
+	if err := env.IncreaseTime(duration); err != nil {
+		return err
+	}
+
+	head, err := env.LatestBlock()
+	if err != nil {
+		return fmt.Errorf("fetching latest block: %w", err)
+	}
+	fmt.Printf("Increased time by %s, block #%d time is now %s\n", duration, uint64(head.Number), time.Time(head.Timestamp).UTC().Format(time.RFC3339))
+	return nil
+}
+
+func runDevenvFaults(cmd *cobra.Command, args []string) error {
+	env, err := attachDevenv(cmd)
+	if err != nil {
+		return err
+	}
+
+	faults, err := env.Faults(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	if sflags.MustGetBool(cmd, "clear") {
+		faults, changed = devenv.Faults{}, true
+	}
+	if cmd.Flags().Changed("latency") {
+		faults.Latency, changed = sflags.MustGetDuration(cmd, "latency"), true
+	}
+	if cmd.Flags().Changed("error-rate") {
+		faults.ErrorRate, changed = sflags.MustGetFloat64(cmd, "error-rate"), true
+	}
+	if cmd.Flags().Changed("drop-tx-rate") {
+		faults.DropTxRate, changed = sflags.MustGetFloat64(cmd, "drop-tx-rate"), true
+	}
+	if cmd.Flags().Changed("methods") {
+		faults.Methods, changed = sflags.MustGetStringSlice(cmd, "methods"), true
+	}
+	if cmd.Flags().Changed("seed") {
+		faults.Seed, changed = sflags.MustGetInt64(cmd, "seed"), true
+	}
+
+	if changed {
+		cli.NoError(faults.Validate(), "invalid faults")
+		if err := env.SetFaults(cmd.Context(), faults); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Fault proxy: %s\n", env.FaultProxyURL)
+	fmt.Printf("Faults:      %s\n", faults)
+	return nil
+}
+
+func runDevenvSeed(cmd *cobra.Command, args []string) error {
+	scenario := sflags.MustGetString(cmd, "scenario")
+	cli.Ensure(devenv.LookupSeedScenario(scenario) != nil, "unknown --scenario %q", scenario)
+
+	escrowAmount, err := devenv.ParseGRT(sflags.MustGetString(cmd, "escrow-amount"))
+	cli.NoError(err, "invalid --escrow-amount")
+	provisionAmount, err := devenv.ParseGRT(sflags.MustGetString(cmd, "provision-amount"))
+	cli.NoError(err, "invalid --provision-amount")
+
+	config := &devenv.SeedConfig{EscrowAmount: escrowAmount, ProvisionAmount: provisionAmount}
+	if signerKeyHex := sflags.MustGetString(cmd, "signer-private-key"); signerKeyHex != "" {
+		config.SignerKey, err = eth.NewPrivateKey(signerKeyHex)
+		cli.NoError(err, "invalid --signer-private-key")
+	}
+
+	env, err := attachDevenv(cmd)
+	if err != nil {
+		return err
+	}
+
+	config.Payer, err = lookupDevenvAccount(env, sflags.MustGetString(cmd, "payer"))
+	cli.NoError(err, "invalid --payer")
+	config.ServiceProvider, err = lookupDevenvAccount(env, sflags.MustGetString(cmd, "provider"))
+	cli.NoError(err, "invalid --provider")
+
+	result, err := env.Seed(scenario, config)
+	if err != nil {
+		return fmt.Errorf("seeding %q: %w", scenario, err)
+	}
+	if printSimulatedTransactions(env) {
+		return nil
+	}
+
+	fmt.Printf("Seeded %q (payer %s, service provider %s)\n", scenario, result.Payer.Pretty(), result.ServiceProvider.Pretty())
+	for _, step := range result.Applied {
+		fmt.Printf("  applied: %s\n", step)
+	}
+	for _, step := range result.Skipped {
+		fmt.Printf("  skipped: %s (already in place)\n", step)
+	}
+	if result.SignerKey != nil {
+		fmt.Printf("Signer:         %s (0x%s)\n", result.SignerKey.PublicKey().Address().Pretty(), result.SignerKey.String())
+	}
+
+	balance, err := env.GetEscrowBalance(result.Payer, result.ServiceProvider)
+	if err != nil {
+		return fmt.Errorf("fetching escrow balance: %w", err)
+	}
+	fmt.Printf("Escrow balance: %s GRT\n", formatWei(balance))
+	return nil
+}
+
+// lookupDevenvAccount returns the test account of env named or at value, only
+// test accounts can be seeded as their keys sign the transactions
+func lookupDevenvAccount(env *devenv.Env, value string) (devenv.Account, error) {
+	for _, account := range env.Accounts() {
+		if strings.EqualFold(account.Name, value) || strings.EqualFold(account.Address.Pretty(), value) {
+			return account.Account, nil
+		}
+	}
+	return devenv.Account{}, fmt.Errorf("%q is not a test account of the development environment (see 'sds devenv accounts')", value)
+}
+
+// formatWei formats an 18 decimals token amount (ETH or GRT) as a decimal string
+func formatWei(wei *big.Int) string {
+	return devenv.FormatGRT(wei)
+}
@@ -19,14 +19,22 @@ func main() {
 		"Substreams Data Service CLI",
 		ConfigureVersion(version),
 		OnCommandErrorLogAndExit(zlog),
+		PersistentFlags(addressFlags),
 
 		devenvCmd,
+		statusCmd,
 
 		Group(
 			"provider",
 			"Provider-side commands",
 			providerSidecarCmd,
 			providerFakeOperatorCmd,
+			providerCollectPendingCmd,
+			providerExportSessionCmd,
+			providerImportSessionCmd,
+			providerQuarantineCmd,
+			providerDeadLetterCmd,
+			providerPayerStatsCmd,
 		),
 
 		Group(
@@ -34,6 +42,43 @@ func main() {
 			"Consumer-side commands",
 			consumerSidecarCmd,
 			consumerFakeClientCmd,
+			consumerBudgetCmd,
+			consumerArchiveCmd,
+			consumerBlacklistCmd,
+			consumerKeygenCmd,
+		),
+
+		Group(
+			"aggregator",
+			"Receipt aggregator commands",
+			aggregatorServeCmd,
+		),
+
+		Group(
+			"verify",
+			"On-chain verification commands",
+			verifyEscrowCmd,
+			verifyEIP712Cmd,
+		),
+
+		Group(
+			"proof",
+			"Signer authorization proof commands",
+			proofCreateCmd,
+			proofVerifyCmd,
+		),
+
+		Group(
+			"bench",
+			"Benchmarking commands",
+			benchEIP712Cmd,
+		),
+
+		Group(
+			"abi",
+			"ABI encoding debugging commands",
+			abiEncodeCmd,
+			abiDecodeCmd,
 		),
 	)
 }
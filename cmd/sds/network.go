@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/streamingfast/cli"
+	"github.com/streamingfast/cli/sflags"
+	"github.com/streamingfast/eth-go"
+)
+
+func networkFlag(flags *pflag.FlagSet) {
+	flags.String("network", "", "Known network presetting --chain-id and the contract addresses not set explicitly, one of "+strings.Join(horizon.KnownNetworkNames(), ", "))
+}
+
+// networkPreset returns the preset of --network, nil when not set
+func networkPreset(cmd *cobra.Command) *horizon.Network {
+	name := sflags.MustGetString(cmd, "network")
+	if name == "" {
+		return nil
+	}
+	network, err := horizon.LookupNetwork(name)
+	cli.NoError(err, "invalid <network>")
+	return network
+}
+
+// chainIDWithPreset returns --chain-id, or the chain ID of the --network preset
+// when not set explicitly
+func chainIDWithPreset(cmd *cobra.Command) uint64 {
+	chainID, provided := sflags.MustGetUint64Provided(cmd, "chain-id")
+	if network := networkPreset(cmd); network != nil && !provided {
+		return network.ChainID
+	}
+	return chainID
+}
+
+// addressWithPreset returns the address flag name, or the address preset picks
+// from the --network preset when not set explicitly and known for the network
+func addressWithPreset(cmd *cobra.Command, name string, preset func(network *horizon.Network) eth.Address) string {
+	value, provided := sflags.MustGetStringProvided(cmd, name)
+	if network := networkPreset(cmd); network != nil && !provided {
+		if addr := preset(network); addr != nil {
+			return addr.Pretty()
+		}
+	}
+	return value
+}
+
+func presetCollector(network *horizon.Network) eth.Address {
+	return network.GraphTallyCollector
+}
+
+func presetDataService(network *horizon.Network) eth.Address {
+	return network.SubstreamsDataService
+}
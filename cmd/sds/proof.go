@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/streamingfast/cli"
+	. "github.com/streamingfast/cli"
+	"github.com/streamingfast/cli/sflags"
+	"github.com/streamingfast/eth-go"
+)
+
+var proofCreateCmd = Command(
+	runProofCreate,
+	"create",
+	"Create the signer proof required by GraphTallyCollector.authorizeSigner",
+	NoArgs(),
+	Description(`
+		Signs, with the signer key, the proof that the signer agrees to sign RAVs
+		on behalf of --authorizer (the payer sending the authorizeSigner
+		transaction). The signer key holder runs this command and hands the proof
+		over, the authorizer then calls:
+
+		  authorizeSigner(<signer>, <deadline>, <proof>)
+
+		on the GraphTallyCollector before the deadline.
+
+		--deadline is a duration from now (e.g. '24h'), a Unix timestamp in
+		seconds or an RFC 3339 date. The resolved Unix timestamp is printed, it
+		must be passed as is to authorizeSigner.
+	`),
+	Flags(func(flags *pflag.FlagSet) {
+		flags.String("signer-key", "", "Private key of the signer being authorized (hex, required)")
+		flags.String("authorizer", "", "Address sending the authorizeSigner transaction, usually the payer (required)")
+		flags.String("deadline", "24h", "Proof deadline: duration from now, Unix timestamp (seconds) or RFC 3339 date")
+		flags.Uint64("chain-id", 0, "Chain ID the GraphTallyCollector is deployed on (required)")
+		flags.String("collector-address", "", "GraphTallyCollector contract address (required)")
+	}),
+)
+
+var proofVerifyCmd = Command(
+	runProofVerify,
+	"verify <proof>",
+	"Verify a signer proof before sending the authorizeSigner transaction",
+	ExactArgs(1),
+	Description(`
+		Checks the proof (hex) recovers to --signer for the given authorizer,
+		deadline, chain and collector, and that the deadline is not past, i.e.
+		that GraphTallyCollector.authorizeSigner would accept it.
+
+		--deadline must be the exact Unix timestamp (or RFC 3339 date) the proof
+		was created for.
+	`),
+	Flags(func(flags *pflag.FlagSet) {
+		flags.String("signer", "", "Address of the signer being authorized (required)")
+		flags.String("authorizer", "", "Address sending the authorizeSigner transaction, usually the payer (required)")
+		flags.String("deadline", "", "Proof deadline: Unix timestamp (seconds) or RFC 3339 date (required)")
+		flags.Uint64("chain-id", 0, "Chain ID the GraphTallyCollector is deployed on (required)")
+		flags.String("collector-address", "", "GraphTallyCollector contract address (required)")
+	}),
+)
+
+func runProofCreate(cmd *cobra.Command, args []string) error {
+	signerKeyHex := sflags.MustGetString(cmd, "signer-key")
+	chainID, collector, authorizer := mustProofTarget(cmd)
+
+	cli.Ensure(signerKeyHex != "", "<signer-key> is required")
+	signerKey, err := eth.NewPrivateKey(signerKeyHex)
+	cli.NoError(err, "invalid <signer-key>")
+
+	deadlineValue := sflags.MustGetString(cmd, "deadline")
+	deadline, err := parseProofDeadline(deadlineValue, true, time.Now())
+	cli.NoError(err, "invalid <deadline> %q", deadlineValue)
+
+	proof, err := horizon.NewSignerProof(chainID, collector, deadline, authorizer, signerKey)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Signer:     %s\n", signerKey.PublicKey().Address().Pretty())
+	fmt.Printf("Authorizer: %s\n", authorizer.Pretty())
+	fmt.Printf("Collector:  %s (chain %d)\n", collector.Pretty(), chainID)
+	fmt.Printf("Deadline:   %d (%s)\n", deadline, time.Unix(int64(deadline), 0).UTC().Format(time.RFC3339))
+	fmt.Printf("Proof:      %s\n", eth.Hex(proof).Pretty())
+	return nil
+}
+
+func runProofVerify(cmd *cobra.Command, args []string) error {
+	signerHex := sflags.MustGetString(cmd, "signer")
+	chainID, collector, authorizer := mustProofTarget(cmd)
+
+	cli.Ensure(signerHex != "", "<signer> is required")
+	signer, err := resolveAddress(cmd, signerHex)
+	cli.NoError(err, "invalid <signer> %q", signerHex)
+
+	deadlineValue := sflags.MustGetString(cmd, "deadline")
+	cli.Ensure(deadlineValue != "", "<deadline> is required")
+	deadline, err := parseProofDeadline(deadlineValue, false, time.Now())
+	cli.NoError(err, "invalid <deadline> %q", deadlineValue)
+
+	proof, err := eth.NewHex(args[0])
+	cli.NoError(err, "invalid <proof> %q", args[0])
+
+	if err := horizon.VerifySignerProof(chainID, collector, deadline, authorizer, signer, proof); err != nil {
+		return err
+	}
+
+	deadlineAt := time.Unix(int64(deadline), 0).UTC()
+	if !deadlineAt.After(time.Now()) {
+		return fmt.Errorf("proof signed by %s but its deadline %s is past, authorizeSigner would revert", signer.Pretty(), deadlineAt.Format(time.RFC3339))
+	}
+
+	fmt.Printf("Proof valid: signed by %s for authorizer %s, expires %s\n", signer.Pretty(), authorizer.Pretty(), deadlineAt.Format(time.RFC3339))
+	return nil
+}
+
+// mustProofTarget reads the flags shared by proof commands
+func mustProofTarget(cmd *cobra.Command) (chainID uint64, collector, authorizer eth.Address) {
+	chainID = sflags.MustGetUint64(cmd, "chain-id")
+	collectorHex := sflags.MustGetString(cmd, "collector-address")
+	authorizerHex := sflags.MustGetString(cmd, "authorizer")
+
+	cli.Ensure(chainID != 0, "<chain-id> is required")
+
+	cli.Ensure(collectorHex != "", "<collector-address> is required")
+	collector, err := resolveAddress(cmd, collectorHex)
+	cli.NoError(err, "invalid <collector-address> %q", collectorHex)
+
+	cli.Ensure(authorizerHex != "", "<authorizer> is required")
+	authorizer, err = resolveAddress(cmd, authorizerHex)
+	cli.NoError(err, "invalid <authorizer> %q", authorizerHex)
+
+	return chainID, collector, authorizer
+}
+
+// parseProofDeadline parses a Unix timestamp in seconds, an RFC 3339 date or,
+// when allowDuration is set, a duration from now
+func parseProofDeadline(value string, allowDuration bool, now time.Time) (uint64, error) {
+	value = strings.TrimSpace(value)
+
+	if seconds, err := strconv.ParseUint(value, 10, 64); err == nil {
+		return seconds, nil
+	}
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		if at.Unix() < 0 {
+			return 0, fmt.Errorf("date before the Unix epoch")
+		}
+		return uint64(at.Unix()), nil
+	}
+	if allowDuration {
+		if duration, err := time.ParseDuration(value); err == nil {
+			if duration <= 0 {
+				return 0, fmt.Errorf("duration must be positive")
+			}
+			return uint64(now.Add(duration).Unix()), nil
+		}
+		return 0, fmt.Errorf("expected a duration, a Unix timestamp or an RFC 3339 date")
+	}
+	return 0, fmt.Errorf("expected a Unix timestamp or an RFC 3339 date")
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/provider/sidecar"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/streamingfast/cli"
+	. "github.com/streamingfast/cli"
+	"github.com/streamingfast/cli/sflags"
+)
+
+var providerCollectPendingCmd = Command(
+	runProviderCollectPending,
+	"collect-pending",
+	"List final RAVs awaiting on-chain collection and collect them",
+	Description(`
+		Connects to the provider sidecar admin server (--admin-listen-addr of
+		'sds provider sidecar') and lists the final RAVs of ended sessions that
+		were not collected on-chain yet.
+
+		With --session (repeatable) or --all, the selected RAVs are collected by
+		the sidecar through SubstreamsDataService.collect. Adding --dry-run only
+		estimates the gas of each collect transaction and the tokens it would
+		transfer, nothing is sent.
+	`),
+	Flags(func(flags *pflag.FlagSet) {
+		flags.String("admin-addr", "", "Provider sidecar admin server address, its --admin-listen-addr (required)")
+		flags.StringSlice("session", nil, "Session ID whose final RAV should be collected, can be repeated")
+		flags.Bool("all", false, "Collect all pending final RAVs")
+		flags.Bool("dry-run", false, "Only estimate gas and token deltas, no transaction is sent")
+		flags.Duration("timeout", 5*time.Minute, "Maximum time to wait for the sidecar to answer, collect transactions are awaited until mined")
+	}),
+)
+
+func runProviderCollectPending(cmd *cobra.Command, args []string) error {
+	adminAddr := sflags.MustGetString(cmd, "admin-addr")
+	sessionIDs := sflags.MustGetStringSlice(cmd, "session")
+	all := sflags.MustGetBool(cmd, "all")
+	dryRun := sflags.MustGetBool(cmd, "dry-run")
+	timeout := sflags.MustGetDuration(cmd, "timeout")
+
+	cli.Ensure(adminAddr != "", "<admin-addr> is required")
+	cli.Ensure(!all || len(sessionIDs) == 0, "<all> and <session> are mutually exclusive")
+	adminAddr = adminBaseURL(adminAddr)
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+	defer cancel()
+
+	var pending struct {
+		AmountUnit string                `json:"amount_unit"`
+		Pending    []*sidecar.PendingRAV `json:"pending"`
+	}
+	if err := adminRequest(ctx, http.MethodGet, adminAddr+"/v1/collections/pending", nil, &pending); err != nil {
+		return fmt.Errorf("listing pending RAVs: %w", err)
+	}
+
+	if len(pending.Pending) == 0 {
+		fmt.Println("No final RAV awaiting collection")
+		return nil
+	}
+
+	fmt.Printf("%d final RAV(s) awaiting collection:\n", len(pending.Pending))
+	for _, rav := range pending.Pending {
+		fmt.Printf("  %s  payer=%s  value=%s  ended=%s\n", rav.SessionID, rav.Payer, formatAmount(rav.ValueAggregate, pending.AmountUnit), rav.EndedAt.UTC().Format(time.RFC3339))
+	}
+
+	if !all && len(sessionIDs) == 0 {
+		fmt.Println("\nUse --session <id> or --all to collect them (add --dry-run to only estimate)")
+		return nil
+	}
+
+	var out struct {
+		AmountUnit string                   `json:"amount_unit"`
+		Results    []*sidecar.CollectResult `json:"results"`
+	}
+	req := &sidecar.CollectRequest{SessionIDs: sessionIDs, All: all, DryRun: dryRun}
+	if err := adminRequest(ctx, http.MethodPost, adminAddr+"/v1/collections/collect", req, &out); err != nil {
+		return fmt.Errorf("collecting RAVs: %w", err)
+	}
+
+	if dryRun {
+		fmt.Println("\nDry run, no transaction sent:")
+	} else {
+		fmt.Println("\nCollection results:")
+	}
+
+	failed := 0
+	for _, result := range out.Results {
+		fmt.Printf("  %s\n", result.SessionID)
+		if result.TokensDelta != "" {
+			fmt.Printf("    tokens delta:      %s (RAV value %s, already collected %s)\n", formatAmount(result.TokensDelta, out.AmountUnit), formatAmount(result.ValueAggregate, out.AmountUnit), formatAmount(result.AlreadyCollected, out.AmountUnit))
+			fmt.Printf("    gas:               %d at %s wei (fee %s ETH)\n", result.Gas, result.GasPrice, formatWeiString(result.Fee))
+		}
+		if result.TxHash != "" {
+			fmt.Printf("    transaction:       %s\n", result.TxHash)
+		}
+		if result.FeePaid != "" {
+			fmt.Printf("    gas used:          %d (fee paid %s ETH)\n", result.GasUsed, formatWeiString(result.FeePaid))
+		}
+		if result.Error != "" {
+			failed++
+			fmt.Printf("    error:             %s\n", result.Error)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d RAV(s) could not be collected", failed, len(out.Results))
+	}
+	return nil
+}
+
+// formatAmount renders an amount received from a sidecar in GRT, wei amounts
+// (the default unit) are converted while already formatted ones are kept
+func formatAmount(value, unit string) string {
+	if unit == "" || unit == "wei" {
+		return formatWeiString(value) + " GRT"
+	}
+	return value + " " + unit
+}
+
+// formatWeiString formats a decimal wei string as GRT, invalid values are returned as-is
+func formatWeiString(wei string) string {
+	value, ok := new(big.Int).SetString(wei, 10)
+	if !ok {
+		return wei
+	}
+	return formatWei(value)
+}
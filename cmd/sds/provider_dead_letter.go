@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/provider/sidecar"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/streamingfast/cli"
+	. "github.com/streamingfast/cli"
+	"github.com/streamingfast/cli/sflags"
+)
+
+var providerDeadLetterCmd = Group(
+	"dead-letter",
+	"Review the collections that kept failing on the provider sidecar (--collect-retry)",
+	providerDeadLetterListCmd,
+	providerDeadLetterRetryCmd,
+
+	PersistentFlags(func(flags *pflag.FlagSet) {
+		flags.String("admin-addr", "", "Provider sidecar admin server address, its --admin-listen-addr (required)")
+	}),
+)
+
+var providerDeadLetterListCmd = Command(
+	runProviderDeadLetterList,
+	"list",
+	"List the final RAVs moved to the dead-letter queue along with their last error",
+	NoArgs(),
+)
+
+var providerDeadLetterRetryCmd = Command(
+	runProviderDeadLetterRetry,
+	"retry <session-id>",
+	"Requeue a dead-lettered collection",
+	Description(`
+		The final RAV is pending collection again and retried right away, with its
+		attempts reset. Fix what made it fail first (e.g. re-authorize the signer
+		or top up the escrow), it is otherwise dead-lettered again.
+	`),
+	ExactArgs(1),
+)
+
+func runProviderDeadLetterList(cmd *cobra.Command, args []string) error {
+	adminAddr := providerDeadLetterAdminAddr(cmd)
+
+	var out struct {
+		AmountUnit   string                            `json:"amount_unit"`
+		DeadLettered []*sidecar.DeadLetteredCollection `json:"dead_lettered"`
+	}
+	if err := adminRequest(cmd.Context(), http.MethodGet, adminAddr+"/v1/collections/dead-letter", nil, &out); err != nil {
+		return fmt.Errorf("listing dead-lettered collections: %w", err)
+	}
+
+	if len(out.DeadLettered) == 0 {
+		fmt.Println("No dead-lettered collection")
+		return nil
+	}
+
+	fmt.Printf("%d dead-lettered collection(s):\n", len(out.DeadLettered))
+	for _, collection := range out.DeadLettered {
+		printDeadLetteredCollection(collection, out.AmountUnit)
+	}
+	return nil
+}
+
+func runProviderDeadLetterRetry(cmd *cobra.Command, args []string) error {
+	adminAddr := providerDeadLetterAdminAddr(cmd)
+
+	var out struct {
+		AmountUnit string                          `json:"amount_unit"`
+		Requeued   *sidecar.DeadLetteredCollection `json:"requeued"`
+	}
+	if err := adminRequest(cmd.Context(), http.MethodPost, adminAddr+"/v1/collections/dead-letter/"+url.PathEscape(args[0])+"/retry", nil, &out); err != nil {
+		return fmt.Errorf("requeuing collection of session %s: %w", args[0], err)
+	}
+
+	fmt.Println("Collection requeued:")
+	printDeadLetteredCollection(out.Requeued, out.AmountUnit)
+	return nil
+}
+
+func providerDeadLetterAdminAddr(cmd *cobra.Command) string {
+	adminAddr := sflags.MustGetString(cmd, "admin-addr")
+	cli.Ensure(adminAddr != "", "<admin-addr> is required")
+	return adminBaseURL(adminAddr)
+}
+
+func printDeadLetteredCollection(collection *sidecar.DeadLetteredCollection, unit string) {
+	fmt.Printf("  %s  payer=%s  value=%s  attempts=%d  at=%s\n", collection.SessionID, collection.Payer, formatAmount(collection.ValueAggregate, unit), collection.Attempts, collection.DeadLetteredAt.UTC().Format(time.RFC3339))
+	if collection.Reason != "" {
+		fmt.Printf("    %s: %s\n", collection.Reason, collection.LastError)
+	} else {
+		fmt.Printf("    %s\n", collection.LastError)
+	}
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"io"
 	"math/big"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"connectrpc.com/connect"
@@ -30,6 +34,14 @@ var providerFakeOperatorCmd = Command(
 		2. Reports usage as data is streamed
 		3. Ends the session
 
+		With --stream-usage, usage is reported on a single StreamUsage stream
+		instead of one ReportUsage call per batch, RAV updates and stop signals
+		are received on the same stream.
+
+		With --per-block-entries, each batch is reported as one usage_batch
+		entry per block, stamped with the time it was streamed, which the
+		sidecar sums into a single report.
+
 		This is useful for testing the provider sidecar without running actual provider services.
 	`),
 	Flags(func(flags *pflag.FlagSet) {
@@ -37,6 +49,8 @@ var providerFakeOperatorCmd = Command(
 		flags.String("signer-private-key", "", "Private key for signing test RAVs (hex, required)")
 		flags.Uint64("chain-id", 1337, "Chain ID for EIP-712 domain")
 		flags.String("collector-address", "", "Collector contract address for EIP-712 domain (required)")
+		flags.String("domain-name", horizon.DefaultDomainName, "EIP-712 domain name of the collector contract")
+		flags.String("domain-version", horizon.DefaultDomainVersion, "EIP-712 domain version of the collector contract")
 		flags.String("payer-address", "", "Payer address (required)")
 		flags.String("service-provider-address", "", "Service provider address (required)")
 		flags.String("data-service-address", "", "Data service contract address (required)")
@@ -45,6 +59,9 @@ var providerFakeOperatorCmd = Command(
 		flags.Uint64("batch-size", 10, "Number of blocks per usage report")
 		flags.String("price-per-block", "0.001", "Price per block in GRT for cost calculation")
 		flags.Duration("delay-between-batches", 500*time.Millisecond, "Delay between batch reports")
+		flags.StringSlice("instance-ids", nil, "Provider instance IDs usage reports are spread across in turn, simulating a load-balanced tier2 fleet")
+		flags.Bool("stream-usage", false, "Report usage on a single StreamUsage stream (HTTP/2) instead of one ReportUsage call per batch")
+		flags.Bool("per-block-entries", false, "Report each batch as one usage_batch entry per block instead of a single usage")
 	}),
 )
 
@@ -55,6 +72,8 @@ func runProviderFakeOperator(cmd *cobra.Command, args []string) error {
 	signerKeyHex := sflags.MustGetString(cmd, "signer-private-key")
 	chainID := sflags.MustGetUint64(cmd, "chain-id")
 	collectorHex := sflags.MustGetString(cmd, "collector-address")
+	domainName := sflags.MustGetString(cmd, "domain-name")
+	domainVersion := sflags.MustGetString(cmd, "domain-version")
 	payerHex := sflags.MustGetString(cmd, "payer-address")
 	serviceProviderHex := sflags.MustGetString(cmd, "service-provider-address")
 	dataServiceHex := sflags.MustGetString(cmd, "data-service-address")
@@ -63,25 +82,28 @@ func runProviderFakeOperator(cmd *cobra.Command, args []string) error {
 	batchSize := sflags.MustGetUint64(cmd, "batch-size")
 	pricePerBlockStr := sflags.MustGetString(cmd, "price-per-block")
 	delayBetweenBatches := sflags.MustGetDuration(cmd, "delay-between-batches")
+	instanceIDs := sflags.MustGetStringSlice(cmd, "instance-ids")
+	streamUsage := sflags.MustGetBool(cmd, "stream-usage")
+	perBlockEntries := sflags.MustGetBool(cmd, "per-block-entries")
 
 	cli.Ensure(signerKeyHex != "", "<signer-private-key> is required")
 	signerKey, err := eth.NewPrivateKey(signerKeyHex)
 	cli.NoError(err, "invalid <signer-private-key> %q", signerKeyHex)
 
 	cli.Ensure(collectorHex != "", "<collector-address> is required")
-	collectorAddr, err := eth.NewAddress(collectorHex)
+	collectorAddr, err := resolveAddress(cmd, collectorHex)
 	cli.NoError(err, "invalid <collector-address> %q", collectorHex)
 
 	cli.Ensure(payerHex != "", "<payer-address> is required")
-	payer, err := eth.NewAddress(payerHex)
+	payer, err := resolveAddress(cmd, payerHex)
 	cli.NoError(err, "invalid <payer-address> %q", payerHex)
 
 	cli.Ensure(serviceProviderHex != "", "<service-provider-address> is required")
-	serviceProvider, err := eth.NewAddress(serviceProviderHex)
+	serviceProvider, err := resolveAddress(cmd, serviceProviderHex)
 	cli.NoError(err, "invalid <service-provider-address> %q", serviceProviderHex)
 
 	cli.Ensure(dataServiceHex != "", "<data-service-address> is required")
-	dataService, err := eth.NewAddress(dataServiceHex)
+	dataService, err := resolveAddress(cmd, dataServiceHex)
 	cli.NoError(err, "invalid <data-service-address> %q", dataServiceHex)
 
 	// Parse price per block (in GRT)
@@ -92,7 +114,7 @@ func runProviderFakeOperator(cmd *cobra.Command, args []string) error {
 	weiMultiplier := new(big.Float).SetInt(big.NewInt(1e18))
 	priceWei, _ := new(big.Float).Mul(pricePerBlock, weiMultiplier).Int(nil)
 
-	domain := horizon.NewDomain(chainID, collectorAddr)
+	domain := horizon.NewDomainWithNameVersion(domainName, domainVersion, chainID, collectorAddr)
 
 	logger := providerLog
 	logger.Info("starting fake provider client",
@@ -105,26 +127,27 @@ func runProviderFakeOperator(cmd *cobra.Command, args []string) error {
 		zap.String("price_per_block", pricePerBlockStr),
 	)
 
-	// Create client
+	// Create client, StreamUsage is a bidirectional stream requiring HTTP/2
+	httpClient := http.DefaultClient
+	if streamUsage {
+		var protocols http.Protocols
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+		httpClient = &http.Client{Transport: &http.Transport{Protocols: &protocols}}
+	}
 	client := providerv1connect.NewProviderSidecarServiceClient(
-		http.DefaultClient,
+		httpClient,
 		sidecarAddr,
 	)
 
 	// Step 1: Create an initial RAV and validate payment
 	logger.Info("Step 1: Creating initial RAV and validating payment")
 
-	initialRAV, err := signRAV(
-		domain,
-		signerKey,
-		[32]byte{}, // Zero collection ID for new session
-		payer,
-		dataService,
-		serviceProvider,
-		uint64(time.Now().UnixNano()),
-		big.NewInt(0), // Zero initial value
-		nil,
-	)
+	initialRAV, err := horizon.SignBootstrapRAV(domain, horizon.RAVParties{
+		Payer:           payer,
+		ServiceProvider: serviceProvider,
+		DataService:     dataService,
+	}, horizon.CollectionID{}, nil, horizon.NewLocalSigner(signerKey))
 	cli.NoError(err, "failed to sign initial RAV")
 
 	validateResp, err := client.ValidatePayment(ctx, connect.NewRequest(&providerv1.ValidatePaymentRequest{
@@ -134,6 +157,7 @@ func runProviderFakeOperator(cmd *cobra.Command, args []string) error {
 
 	if !validateResp.Msg.Valid {
 		logger.Error("payment validation failed",
+			zap.Stringer("code", validateResp.Msg.RejectionCode),
 			zap.String("reason", validateResp.Msg.RejectionReason),
 		)
 		cli.Quit("payment validation failed: %s", validateResp.Msg.RejectionReason)
@@ -153,6 +177,19 @@ func runProviderFakeOperator(cmd *cobra.Command, args []string) error {
 
 	// Step 2: Simulate streaming data and reporting usage
 	logger.Info("Step 2: Simulating data streaming")
+	reportUsage := func(req *providerv1.ReportUsageRequest) (*providerv1.ReportUsageResponse, error) {
+		resp, err := client.ReportUsage(ctx, connect.NewRequest(req))
+		if err != nil {
+			return nil, err
+		}
+		return resp.Msg, nil
+	}
+	var stream *usageStream
+	if streamUsage {
+		stream = openUsageStream(ctx, client, logger)
+		reportUsage = stream.report
+	}
+
 	var totalBlocks, totalBytes, totalRequests uint64
 	totalCost := big.NewInt(0)
 
@@ -167,15 +204,38 @@ func runProviderFakeOperator(cmd *cobra.Command, args []string) error {
 		requests := uint64(1)
 		cost := new(big.Int).Mul(priceWei, big.NewInt(int64(currentBatch)))
 
-		usageResp, err := client.ReportUsage(ctx, connect.NewRequest(&providerv1.ReportUsageRequest{
-			SessionId: sessionID,
-			Usage: &commonv1.Usage{
+		var instanceID string
+		if len(instanceIDs) > 0 {
+			instanceID = instanceIDs[(blocksStreamed/batchSize)%uint64(len(instanceIDs))]
+		}
+
+		req := &providerv1.ReportUsageRequest{
+			SessionId:  sessionID,
+			InstanceId: instanceID,
+		}
+		if perBlockEntries {
+			for block := uint64(0); block < currentBatch; block++ {
+				entry := &commonv1.Usage{
+					BlocksProcessed:  1,
+					BytesTransferred: bytesPerBlock,
+					Cost:             commonv1.BigIntFromNative(priceWei),
+					TimestampNs:      uint64(time.Now().UnixNano()),
+				}
+				if block == 0 {
+					entry.Requests = requests
+				}
+				req.UsageBatch = append(req.UsageBatch, entry)
+			}
+		} else {
+			req.Usage = &commonv1.Usage{
 				BlocksProcessed:  currentBatch,
 				BytesTransferred: bytes,
 				Requests:         requests,
 				Cost:             commonv1.BigIntFromNative(cost),
-			},
-		}))
+			}
+		}
+
+		usageResp, err := reportUsage(req)
 		cli.NoError(err, "failed to report usage")
 
 		totalBlocks += currentBatch
@@ -183,9 +243,10 @@ func runProviderFakeOperator(cmd *cobra.Command, args []string) error {
 		totalRequests += requests
 		totalCost.Add(totalCost, cost)
 
-		if !usageResp.Msg.ShouldContinue {
+		if !usageResp.ShouldContinue {
 			logger.Warn("sidecar requested to stop",
-				zap.String("reason", usageResp.Msg.StopReason),
+				zap.Stringer("code", usageResp.StopCode),
+				zap.String("reason", usageResp.StopReason),
 			)
 			break
 		}
@@ -193,7 +254,7 @@ func runProviderFakeOperator(cmd *cobra.Command, args []string) error {
 		logger.Debug("batch streamed",
 			zap.Uint64("blocks_in_batch", currentBatch),
 			zap.Uint64("total_blocks", totalBlocks),
-			zap.Bool("rav_updated", usageResp.Msg.RavUpdated),
+			zap.Bool("rav_updated", usageResp.RavUpdated),
 		)
 
 		// Delay between batches to simulate real streaming
@@ -202,6 +263,10 @@ func runProviderFakeOperator(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if stream != nil {
+		cli.NoError(stream.close(), "failed to close usage stream")
+	}
+
 	// Step 3: Check session status
 	logger.Info("Step 3: Checking session status")
 	statusResp, err := client.GetSessionStatus(ctx, connect.NewRequest(&providerv1.GetSessionStatusRequest{
@@ -221,6 +286,14 @@ func runProviderFakeOperator(cmd *cobra.Command, args []string) error {
 				zap.Uint64("estimated_blocks_remaining", statusResp.Msg.PaymentStatus.EstimatedBlocksRemaining),
 			)
 		}
+		for _, instance := range statusResp.Msg.InstanceUsage {
+			logger.Info("instance usage",
+				zap.String("instance_id", instance.InstanceId),
+				zap.Uint64("blocks", instance.Usage.BlocksProcessed),
+				zap.Uint64("reports", instance.Reports),
+				zap.String("cost", instance.Usage.Cost.ToNative().String()),
+			)
+		}
 	}
 
 	// Step 4: End session
@@ -247,21 +320,19 @@ func runProviderFakeOperator(cmd *cobra.Command, args []string) error {
 
 	if endResp.Msg.FinalRav != nil && endResp.Msg.FinalRav.Rav != nil {
 		logger.Info("final RAV",
-			zap.String("value", endResp.Msg.FinalRav.Rav.ValueAggregate.ToNative().String()),
+			zap.Stringer("rav", sidecar.ProtoSignedRAVToHorizon(endResp.Msg.FinalRav)),
 		)
 	}
 
-	if endResp.Msg.TotalUsage != nil {
-		logger.Info("total usage reported by sidecar",
-			zap.Uint64("blocks", endResp.Msg.TotalUsage.BlocksProcessed),
-			zap.Uint64("bytes", endResp.Msg.TotalUsage.BytesTransferred),
-			zap.Uint64("requests", endResp.Msg.TotalUsage.Requests),
-		)
-	}
-
-	if endResp.Msg.TotalValue != nil {
-		logger.Info("total value",
-			zap.String("wei", endResp.Msg.TotalValue.ToNative().String()),
+	if settlement := endResp.Msg.Settlement; settlement != nil {
+		logger.Info("session settlement reported by sidecar",
+			zap.Uint64("blocks", settlement.GetTotalUsage().GetBlocksProcessed()),
+			zap.Uint64("bytes", settlement.GetTotalUsage().GetBytesTransferred()),
+			zap.Uint64("requests", settlement.GetTotalUsage().GetRequests()),
+			zap.String("signed_value_wei", settlement.SignedValue.ToNative().String()),
+			zap.String("previously_collected_wei", settlement.PreviouslyCollected.ToNative().String()),
+			zap.String("pending_delta_wei", settlement.PendingDelta.ToNative().String()),
+			zap.String("projected_payout_wei", settlement.ProjectedPayout.ToNative().String()),
 		)
 	}
 
@@ -289,3 +360,60 @@ func signRAV(
 	}
 	return horizon.Sign(domain, rav, key)
 }
+
+// usageStream reports usage on a StreamUsage stream. The sidecar only answers
+// on RAV updates and stop signals, outcomes are received in the background and
+// the first stop signal is returned by the reports following it.
+type usageStream struct {
+	stream *connect.BidiStreamForClient[providerv1.ReportUsageRequest, providerv1.StreamUsageResponse]
+	stop   atomic.Pointer[providerv1.ReportUsageResponse]
+	done   chan struct{}
+	err    error
+}
+
+func openUsageStream(ctx context.Context, client providerv1connect.ProviderSidecarServiceClient, logger *zap.Logger) *usageStream {
+	u := &usageStream{stream: client.StreamUsage(ctx), done: make(chan struct{})}
+
+	go func() {
+		defer close(u.done)
+		for {
+			resp, err := u.stream.Receive()
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					u.err = err
+				}
+				return
+			}
+
+			if !resp.Outcome.GetShouldContinue() {
+				u.stop.CompareAndSwap(nil, resp.Outcome)
+				continue
+			}
+			logger.Debug("RAV updated", zap.String("session_id", resp.SessionId))
+		}
+	}()
+
+	return u
+}
+
+func (u *usageStream) report(req *providerv1.ReportUsageRequest) (*providerv1.ReportUsageResponse, error) {
+	if err := u.stream.Send(req); err != nil {
+		return nil, err
+	}
+	if stop := u.stop.Load(); stop != nil {
+		return stop, nil
+	}
+	return &providerv1.ReportUsageResponse{ShouldContinue: true}, nil
+}
+
+// close ends the stream once the outcomes of the reports sent are received
+func (u *usageStream) close() error {
+	if err := u.stream.CloseRequest(); err != nil {
+		return err
+	}
+	<-u.done
+	if err := u.stream.CloseResponse(); err != nil {
+		return err
+	}
+	return u.err
+}
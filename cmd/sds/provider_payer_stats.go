@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/provider/sidecar"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/streamingfast/cli"
+	. "github.com/streamingfast/cli"
+	"github.com/streamingfast/cli/sflags"
+)
+
+var providerPayerStatsCmd = Command(
+	runProviderPayerStats,
+	"payer-stats",
+	"Show per payer statistics of the provider sidecar sessions",
+	Description(`
+		Connects to the provider sidecar admin server (--admin-listen-addr of
+		'sds provider sidecar') and lists, for each payer, its sessions, the value
+		signed over them, the value collected on-chain, the value disputed (RAVs
+		in quarantine and dead-lettered collections) and the average session
+		length, highest value signed first.
+
+		Without --store-path on the sidecar, statistics only cover the sessions
+		served since its last restart.
+	`),
+	Flags(func(flags *pflag.FlagSet) {
+		flags.String("admin-addr", "", "Provider sidecar admin server address, its --admin-listen-addr (required)")
+	}),
+	NoArgs(),
+)
+
+func runProviderPayerStats(cmd *cobra.Command, args []string) error {
+	adminAddr := sflags.MustGetString(cmd, "admin-addr")
+	cli.Ensure(adminAddr != "", "<admin-addr> is required")
+
+	var out struct {
+		AmountUnit string                `json:"amount_unit"`
+		Payers     []*sidecar.PayerStats `json:"payers"`
+	}
+	if err := adminRequest(cmd.Context(), http.MethodGet, adminBaseURL(adminAddr)+"/v1/payers/stats", nil, &out); err != nil {
+		return fmt.Errorf("getting payer statistics: %w", err)
+	}
+
+	if len(out.Payers) == 0 {
+		fmt.Println("No session served yet")
+		return nil
+	}
+
+	fmt.Printf("%d payer(s):\n", len(out.Payers))
+	for _, payer := range out.Payers {
+		averageLength := payer.AverageSessionLength
+		if averageLength == "" {
+			averageLength = "-"
+		}
+
+		fmt.Printf("  %s  sessions=%d (%d active)  last=%s\n", payer.Payer, payer.Sessions, payer.ActiveSessions, payer.LastSessionAt.UTC().Format(time.RFC3339))
+		fmt.Printf("    signed:            %s\n", formatAmount(payer.ValueSigned, out.AmountUnit))
+		fmt.Printf("    collected:         %s\n", formatAmount(payer.ValueCollected, out.AmountUnit))
+		fmt.Printf("    disputed:          %s\n", formatAmount(payer.ValueDisputed, out.AmountUnit))
+		fmt.Printf("    average session:   %s\n", averageLength)
+	}
+	return nil
+}
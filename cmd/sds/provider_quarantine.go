@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/provider/sidecar"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/streamingfast/cli"
+	. "github.com/streamingfast/cli"
+	"github.com/streamingfast/cli/sflags"
+)
+
+var providerQuarantineCmd = Group(
+	"quarantine",
+	"Review the RAVs quarantined by the provider sidecar (--quarantine)",
+	providerQuarantineListCmd,
+	providerQuarantineApproveCmd,
+	providerQuarantineRejectCmd,
+
+	PersistentFlags(func(flags *pflag.FlagSet) {
+		flags.String("admin-addr", "", "Provider sidecar admin server address, its --admin-listen-addr (required)")
+	}),
+)
+
+var providerQuarantineListCmd = Command(
+	runProviderQuarantineList,
+	"list",
+	"List the quarantined RAVs along with why they were quarantined",
+	NoArgs(),
+)
+
+var providerQuarantineApproveCmd = Command(
+	runProviderQuarantineDecide("approve"),
+	"approve <id>",
+	"Apply a quarantined RAV to its session",
+	Description(`
+		The RAV becomes the current RAV of its session, as if it had been accepted
+		when submitted. It can no longer be approved once its session is gone or a
+		RAV of higher value was accepted since.
+	`),
+	ExactArgs(1),
+)
+
+var providerQuarantineRejectCmd = Command(
+	runProviderQuarantineDecide("reject"),
+	"reject <id>",
+	"Drop a quarantined RAV",
+	ExactArgs(1),
+)
+
+func runProviderQuarantineList(cmd *cobra.Command, args []string) error {
+	adminAddr := providerQuarantineAdminAddr(cmd)
+
+	var out struct {
+		AmountUnit  string                    `json:"amount_unit"`
+		Quarantined []*sidecar.QuarantinedRAV `json:"quarantined"`
+	}
+	if err := adminRequest(cmd.Context(), http.MethodGet, adminAddr+"/v1/ravs/quarantine", nil, &out); err != nil {
+		return fmt.Errorf("listing quarantined RAVs: %w", err)
+	}
+
+	if len(out.Quarantined) == 0 {
+		fmt.Println("No quarantined RAV")
+		return nil
+	}
+
+	fmt.Printf("%d quarantined RAV(s):\n", len(out.Quarantined))
+	for _, rav := range out.Quarantined {
+		printQuarantinedRAV(rav, out.AmountUnit)
+	}
+	return nil
+}
+
+func runProviderQuarantineDecide(decision string) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		adminAddr := providerQuarantineAdminAddr(cmd)
+
+		var out struct {
+			AmountUnit string                  `json:"amount_unit"`
+			Decided    *sidecar.QuarantinedRAV `json:"decided"`
+		}
+		if err := adminRequest(cmd.Context(), http.MethodPost, adminAddr+"/v1/ravs/quarantine/"+url.PathEscape(args[0])+"/"+decision, nil, &out); err != nil {
+			return fmt.Errorf("%s quarantined RAV %s: %w", decision, args[0], err)
+		}
+
+		fmt.Printf("Quarantined RAV %sd:\n", decision)
+		printQuarantinedRAV(out.Decided, out.AmountUnit)
+		return nil
+	}
+}
+
+func providerQuarantineAdminAddr(cmd *cobra.Command) string {
+	adminAddr := sflags.MustGetString(cmd, "admin-addr")
+	cli.Ensure(adminAddr != "", "<admin-addr> is required")
+	return adminBaseURL(adminAddr)
+}
+
+func printQuarantinedRAV(rav *sidecar.QuarantinedRAV, unit string) {
+	fmt.Printf("  %s  session=%s  payer=%s  value=%s", rav.ID, rav.SessionID, rav.Payer, formatAmount(rav.ValueAggregate, unit))
+	if rav.CurrentValue != "" {
+		fmt.Printf("  current=%s", formatAmount(rav.CurrentValue, unit))
+	}
+	fmt.Printf("  at=%s\n", rav.QuarantinedAt.UTC().Format(time.RFC3339))
+	for _, reason := range rav.Reasons {
+		fmt.Printf("    - %s\n", reason)
+	}
+}
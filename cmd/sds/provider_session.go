@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	sidecarlib "github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/streamingfast/cli"
+	. "github.com/streamingfast/cli"
+	"github.com/streamingfast/cli/sflags"
+)
+
+var providerExportSessionCmd = Command(
+	runProviderExportSession,
+	"export-session <session-id>",
+	"Export a session from a provider sidecar to a signed file",
+	ExactArgs(1),
+	Description(`
+		Connects to the provider sidecar admin server (--admin-listen-addr of
+		'sds provider sidecar') and exports the session state, usage and current
+		RAV to a file signed with the service provider identity key
+		(--identity-private-key of the sidecar).
+
+		For incident recovery when sidecar instances share no storage: the file
+		is imported on another instance of the same service provider with
+		'sds provider import-session'.
+	`),
+	Flags(func(flags *pflag.FlagSet) {
+		flags.String("admin-addr", "", "Provider sidecar admin server address, its --admin-listen-addr (required)")
+		flags.String("output", "", "File the signed session export is written to, <session-id>.session.json when empty")
+		flags.Duration("timeout", 30*time.Second, "Maximum time to wait for the sidecar to answer")
+	}),
+)
+
+var providerImportSessionCmd = Command(
+	runProviderImportSession,
+	"import-session <file>",
+	"Import a session exported by another provider sidecar instance",
+	ExactArgs(1),
+	Description(`
+		Connects to the provider sidecar admin server (--admin-listen-addr of
+		'sds provider sidecar') and imports a session exported with
+		'sds provider export-session'.
+
+		The sidecar refuses exports not signed by its service provider, sessions
+		it already knows, and sessions whose current RAV is not signed by one of
+		its accepted signers.
+	`),
+	Flags(func(flags *pflag.FlagSet) {
+		flags.String("admin-addr", "", "Provider sidecar admin server address, its --admin-listen-addr (required)")
+		flags.Duration("timeout", 30*time.Second, "Maximum time to wait for the sidecar to answer")
+	}),
+)
+
+func runProviderExportSession(cmd *cobra.Command, args []string) error {
+	sessionID := args[0]
+	adminAddr := sflags.MustGetString(cmd, "admin-addr")
+	output := sflags.MustGetString(cmd, "output")
+	timeout := sflags.MustGetDuration(cmd, "timeout")
+
+	cli.Ensure(adminAddr != "", "<admin-addr> is required")
+	if output == "" {
+		output = sessionID + ".session.json"
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+	defer cancel()
+
+	var signed sidecarlib.SignedSessionExport
+	if err := adminRequest(ctx, http.MethodGet, adminBaseURL(adminAddr)+"/v1/sessions/"+url.PathEscape(sessionID)+"/export", nil, &signed); err != nil {
+		return fmt.Errorf("exporting session: %w", err)
+	}
+
+	content, err := json.MarshalIndent(&signed, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding session export: %w", err)
+	}
+	if err := os.WriteFile(output, content, 0o600); err != nil {
+		return fmt.Errorf("writing session export: %w", err)
+	}
+
+	fmt.Printf("Session %s exported to %s\n", sessionID, output)
+	return nil
+}
+
+func runProviderImportSession(cmd *cobra.Command, args []string) error {
+	path := args[0]
+	adminAddr := sflags.MustGetString(cmd, "admin-addr")
+	timeout := sflags.MustGetDuration(cmd, "timeout")
+
+	cli.Ensure(adminAddr != "", "<admin-addr> is required")
+
+	content, err := os.ReadFile(path)
+	cli.NoError(err, "unable to read session export %q", path)
+
+	var signed sidecarlib.SignedSessionExport
+	cli.NoError(json.Unmarshal(content, &signed), "invalid session export %q", path)
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+	defer cancel()
+
+	var out struct {
+		SessionID string `json:"session_id"`
+		State     string `json:"state"`
+	}
+	if err := adminRequest(ctx, http.MethodPost, adminBaseURL(adminAddr)+"/v1/sessions/import", &signed, &out); err != nil {
+		return fmt.Errorf("importing session: %w", err)
+	}
+
+	fmt.Printf("Session %s imported (%s)\n", out.SessionID, out.State)
+	return nil
+}
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"math/big"
+	"net/url"
 	"time"
 
 	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/graphprotocol/substreams-data-service/horizon/devenv"
 	"github.com/graphprotocol/substreams-data-service/provider/sidecar"
 	sidecarlib "github.com/graphprotocol/substreams-data-service/sidecar"
 	"github.com/spf13/cobra"
@@ -13,6 +18,7 @@ import (
 	"github.com/streamingfast/cli/sflags"
 	"github.com/streamingfast/eth-go"
 	"github.com/streamingfast/logging"
+	tracing "github.com/streamingfast/sf-tracing"
 )
 
 var providerLog, _ = logging.PackageLogger("provider", "github.com/graphprotocol/substreams-data-service/cmd/sds@provider")
@@ -29,6 +35,164 @@ var providerSidecarCmd = Command(
 		- ProviderSidecarService: Called by the data provider to validate payments and report usage
 		- PaymentGatewayService: Called by consumer sidecars for session management and RAV exchange
 
+		Sessions are also served read-only as JSON on the same address through
+		'GET /v1/sessions' and 'GET /v1/sessions/{id}' for dashboards.
+
+		When --aggregator-url is set, consumers may submit signed receipts instead
+		of RAVs through SubmitRAV, the receipts are forwarded to the external
+		aggregator (JSON-RPC 'aggregate_receipts') and the resulting RAV goes
+		through the same validation as a directly submitted one.
+
+		With --aggregator-private-key, receipts are aggregated in process instead,
+		RAVs being signed with that key: the payer must authorize its address as
+		a signer for them to be collectable, trusting this service provider to
+		aggregate only the receipts it received. Sessions started in receipts
+		payment mode (consumer --payment-mode receipts) require either aggregator,
+		their receipts being buffered and aggregated into the session RAV every
+		--receipt-aggregation-interval and when the session ends.
+
+		A RAV is requested from consumers (rav_requested on ReportUsage) as soon as
+		usage not covered by their last RAV is reported, then not again until one
+		is returned. With --rav-request-value, --rav-request-interval or
+		--rav-request-blocks, it is only requested once the usage left uncovered
+		exceeds that value, or that long or that many blocks after the last RAV.
+		Usage left uncovered when the session ends is requested on EndSession, the
+		final RAV then being accepted after the session ended.
+
+		On shutdown, new sessions are rejected (SHUTTING_DOWN) and a final RAV is
+		requested for each active session, pushed on StreamUsage and answered to
+		its next usage report. They are awaited up to --shutdown-rav-timeout
+		(disabled when negative) before active sessions are persisted and, with
+		--collect-on-shutdown, their RAVs collected on-chain.
+
+		How long consumers take to return a signed RAV once requested is tracked
+		per payer, exported on the admin server ('/metrics', '/v1/payers/stats').
+		With --max-rav-turnaround, streaming stops (RAV_OVERDUE) for sessions whose
+		RAV is overdue by more than that.
+
+		With --admin-listen-addr, '/healthz' (liveness) and '/readyz' (readiness)
+		are served on a separate port. Readiness checks that the gRPC port accepts
+		connections, that the chain RPC answers and, with --data-service-address,
+		that the service provider is registered with the data service.
+
+		The admin server also lists final RAVs of ended sessions awaiting on-chain
+		collection and collects them on request, see 'sds provider collect-pending'.
+		Collection requires --data-service-address, transactions are signed with
+		--collect-private-key (only dry runs are possible without it). RAVs are
+		collected as query fees unless --payment-type, or --collection-payment-types
+		for specific collections, selects another GraphPayments payment type. Payment
+		types are checked against the data service rules at startup:
+		SubstreamsDataService only collects query fees today.
+
+		With --collect-retry, collections that failed are retried automatically
+		every --collect-retry-backoff, doubled after each failure. Those reverting
+		--collect-max-attempts times (e.g. revoked signer) are moved to a
+		dead-letter queue instead of being retried forever, counted by the
+		'sds_provider_collections_dead_lettered' metric. Review and requeue them
+		with 'sds provider dead-letter'.
+
+		With --auto-collect, the final RAV of each session is collected as soon as
+		the session ends, one transaction at a time, without going through the
+		admin server. RAVs left pending before a restart are collected at startup.
+		Chain RPC calls failing transiently are retried with exponential backoff,
+		other failures are retried according to --collect-retry when enabled and
+		left pending otherwise.
+
+		With --identity-private-key (the service provider key), the sidecar signs
+		identity challenges from consumer sidecars verifying they pay the service
+		provider operating this endpoint (consumer --verify-provider-identity).
+
+		With the admin server and --data-service-address, collecting the current
+		RAV of each active collection is simulated every
+		--redeemability-check-interval. The outcome is exported as the
+		'sds_provider_collection_redeemable' Prometheus metric on '/metrics' and
+		listed on '/v1/collections/redeemability' with the decoded revert reason,
+		surfacing authorization or escrow problems before sessions end.
+
+		With --auto-accept-provision, the provision of the service provider to the
+		data service (read from --staking-address) is checked every
+		--provision-check-interval. Pending parameters staged for it are accepted
+		on-chain (acceptProvisionPendingParameters, signed by --collect-private-key)
+		when the verifier cut is at most --provision-max-verifier-cut and the
+		thawing period at most --provision-max-thawing-period, otherwise a warning
+		is logged and they are left for the operator to review.
+
+		With --escrow-cap, RAVs whose value aggregate exceeds the payer's escrow
+		balance plus --escrow-cap-tolerance are rejected up front and the stream is
+		told to stop. Balances are refreshed from chain every --escrow-cap-refresh.
+
+		With --max-uncollected-value, a collection whose accepted RAVs promise
+		that much value not collected on-chain yet has its current RAV collected
+		(signed by --collect-private-key) before its sessions are allowed more
+		usage. The stream is told to stop when the collection fails or no collect
+		key is set, bounding what is lost if the payer's escrow is drained later.
+
+		With --watch-escrow, the payer's escrow balance is read from chain when a
+		session starts and again once older than --escrow-balance-ttl while usage
+		is reported. The stream is told to stop as soon as the session owes more
+		than the balance, e.g. after the payer withdrew from its escrow.
+
+		When the chain RPC becomes unreachable, the sidecar enters a degraded mode
+		for --degraded-grace: existing sessions are served from the escrow balances
+		last read, session status and '/readyz' report 'degraded' and readiness is
+		kept. Past the grace window, chain failures surface as before.
+
+		A consumer re-initializing (StartSession) with the last RAV of a session
+		still active, or ended by a client disconnect or an error, less than
+		--session-resume-grace ago resumes that session: the usage accumulated
+		since that RAV is kept instead of being lost with a new session.
+
+		Sessions are kept in memory unless --store-path is set. Each session, with
+		its usage and current RAV, is then persisted in a BoltDB database of that
+		directory (sessions.db), the sessions changed since the last write being
+		written every --session-flush-interval, and restored on startup. Unbilled
+		usage and final RAVs awaiting collection thus survive a restart, and
+		interrupted sessions can still be resumed. Ended sessions whose final RAV
+		is collected, or has no value, are dropped after --ended-session-retention.
+
+		Sessions may start without an initial RAV (RAV0) unless
+		--require-initial-rav is set. With a trust window (--trust-window-blocks
+		and/or --trust-window-value), such sessions are served up to the window
+		and ended as soon as their usage goes beyond it before a signed RAV was
+		received. Zero-value initial RAVs older than --bootstrap-rav-max-age are
+		rejected. The first RAV accepted binds the session to its collection,
+		payer, service provider and data service, later RAVs changing any of
+		them are rejected.
+
+		With --quarantine, RAVs submitted during a session that pass the hard
+		checks but look suspicious are held for review instead of being applied
+		or dropped: value aggregate increasing by more than
+		--quarantine-max-value-increase, timestamp more than
+		--quarantine-max-clock-skew in the future or going backwards. Review them
+		with 'sds provider quarantine'.
+
+		The payment policy flags bound the payments accepted beyond the accepted
+		signers and the validity of their RAVs, evaluated on ValidatePayment and
+		on every ReportUsage: escrow balance left over what the payer's active
+		sessions owe (--policy-min-escrow-headroom), usage served to a payer
+		beyond its RAVs (--policy-max-payer-debt), price per block of the service
+		parameters or of the reported usage (--policy-min-price-per-block),
+		collections RAVs may be for (--policy-accepted-collections) and session
+		length (--policy-max-session-duration). Payments violating them are
+		rejected and streams told to stop with REJECTION_CODE_POLICY_VIOLATION,
+		counted per rule as 'sds_provider_policy_rejections_total' on the admin
+		server.
+
+		RAV metadata received from consumers is bounded to --max-rav-metadata-size
+		bytes. With --strict-rav-metadata, only the layouts the horizon package
+		produces are accepted: empty, collection ID, or collection ID and receipts
+		merkle root.
+
+		With the admin server, the handling time of ValidatePayment and ReportUsage
+		is exported as 'sds_provider_request_duration_seconds'. When tracing is
+		enabled through the SF_TRACING environment variable (e.g.
+		'http://otel-collector:4318'), observations of sampled requests carry their
+		trace ID as an OpenMetrics exemplar.
+
+		GRT amounts in the REST and admin responses and in logs are rendered in
+		--amount-unit: exact integer 'wei' (default) or decimal 'grt' rounded to
+		--amount-decimals places. JSON responses report the unit in 'amount_unit'.
+
 		Pricing configuration should be provided via a YAML file with the following format:
 		  price_per_block: "0.000001"   # Price per processed block in GRT
 		  price_per_byte: "0.0000000001" # Price per byte transferred in GRT
@@ -36,37 +200,324 @@ var providerSidecarCmd = Command(
 	Flags(func(flags *pflag.FlagSet) {
 		flags.String("grpc-listen-addr", ":9001", "gRPC server listen address")
 		flags.String("service-provider", "", "Service provider address (required)")
+		networkFlag(flags)
 		flags.Uint64("chain-id", 1337, "Chain ID for EIP-712 domain")
-		flags.String("collector-address", "", "Collector contract address for EIP-712 domain (required)")
+		flags.String("collector-address", "", "Collector contract address for EIP-712 domain (required unless preset by --network)")
+		flags.String("domain-name", horizon.DefaultDomainName, "EIP-712 domain name of the collector contract")
+		flags.String("domain-version", horizon.DefaultDomainVersion, "EIP-712 domain version of the collector contract")
 		flags.String("escrow-address", "", "PaymentsEscrow contract address for balance queries (required)")
 		flags.String("rpc-endpoint", "", "Ethereum RPC endpoint for on-chain queries (required)")
 		flags.String("pricing-config", "", "Path to pricing configuration YAML file (uses defaults if not provided)")
+		flags.String("admin-listen-addr", "", "Admin HTTP server listen address serving /healthz and /readyz (disabled when empty)")
+		flags.String("data-service-address", "", "SubstreamsDataService contract address, when set readiness requires the service provider to be registered")
+		flags.String("collect-private-key", "", "Private key (hex) of the service provider or one of its operators, signs collect transactions triggered through the admin server and provision acceptance transactions")
+		flags.String("identity-private-key", "", "Private key (hex) of the service provider, signs identity challenges from consumer sidecars (identity proofs disabled when empty)")
+		flags.Duration("redeemability-check-interval", sidecar.DefaultRedeemabilityCheckInterval, "How often collecting the current RAV of each active collection is simulated, requires --admin-listen-addr and --data-service-address (disabled when 0)")
+		flags.Bool("auto-accept-provision", false, "Accept pending provision parameters on-chain when within --provision-max-verifier-cut and --provision-max-thawing-period, signed by --collect-private-key")
+		flags.String("staking-address", "", "HorizonStaking contract address holding the provision, required by --auto-accept-provision")
+		flags.String("provision-max-verifier-cut", "0", "Highest verifier cut automatically accepted, in PPM (e.g. \"100000\") or as a percentage (e.g. \"10%\")")
+		flags.Duration("provision-max-thawing-period", 0, "Longest thawing period automatically accepted, required by --auto-accept-provision")
+		flags.Duration("provision-check-interval", sidecar.DefaultProvisionCheckInterval, "How often the provision is checked for pending parameters")
+		flags.String("data-service-cut", "0", "Share of collected tokens requested for the data service when collecting RAVs, in PPM (e.g. \"100000\") or as a percentage (e.g. \"10%\")")
+		flags.String("protocol-payment-cut", "0", "GraphPayments protocol payment cut the payout in session settlements is projected after, in PPM (e.g. \"10000\") or as a percentage (e.g. \"1%\")")
+		flags.String("delegation-fee-cut", "0", "Delegation fee cut of the service provider the payout in session settlements is projected after, in PPM (e.g. \"100000\") or as a percentage (e.g. \"10%\")")
+		flags.String("payment-type", "query-fee", "Payment type RAVs are collected under, \"query-fee\", \"indexing-fee\" or \"indexing-rewards\", must be supported by the data service")
+		flags.StringSlice("collection-payment-types", nil, "Payment type of specific collections, overriding --payment-type, as <collection-id>=<payment-type>")
+		flags.Bool("collect-retry", false, "Retry failed collections of final RAVs automatically, moving those failing --collect-max-attempts times to a dead-letter queue, requires --admin-listen-addr and --collect-private-key")
+		flags.Bool("auto-collect", false, "Collect the final RAV of each session on-chain as soon as it ends, requires --collect-private-key")
+		flags.Int("collect-max-attempts", sidecar.DefaultCollectMaxAttempts, "Failed collect attempts (reverts, nothing left to collect) after which a final RAV is moved to the dead-letter queue")
+		flags.Duration("collect-retry-backoff", sidecar.DefaultCollectRetryBackoff, "Delay before retrying a failed collection, doubled after each failed attempt up to an hour")
+		flags.String("store-path", "", "Directory where sessions and their current RAVs are persisted to survive restarts (kept in memory only when empty)")
+		flags.Duration("session-flush-interval", sidecarlib.DefaultSessionFlushInterval, "How often session changes are written to the --store-path database, changes made since the last write are lost on a crash")
+		flags.Duration("ended-session-retention", 0, "How long ended sessions are kept once their final RAV is collected or has no value (kept forever when 0)")
+		flags.Duration("session-resume-grace", sidecar.DefaultSessionResumeGrace, "How long a session interrupted by a consumer crash can be resumed by re-initializing with its last RAV, keeping unbilled usage (disabled when negative)")
+		flags.Bool("require-initial-rav", false, "Reject sessions started without an initial RAV, unless a trust window (--trust-window-blocks, --trust-window-value) is set")
+		flags.Uint64("trust-window-blocks", 0, "Blocks served to a session started without an initial RAV before a signed RAV is required (unbounded when 0)")
+		flags.String("trust-window-value", "", "GRT of usage served to a session started without an initial RAV before a signed RAV is required, e.g. \"0.01\" (unbounded when empty)")
+		flags.Duration("bootstrap-rav-max-age", 0, "Reject zero-value initial RAVs (RAV0) timestamped longer ago than this (any age accepted when 0)")
+		flags.Duration("replay-window", sidecarlib.DefaultReplayWindow, "How long a session-initiating RAV is remembered, re-sending it attaches to its session (or is rejected once that session ended)")
+		flags.Bool("escrow-cap", false, "Reject RAVs whose value aggregate exceeds the payer's escrow balance plus --escrow-cap-tolerance")
+		flags.String("escrow-cap-tolerance", "0", "GRT a RAV value aggregate may exceed the payer's escrow balance snapshot by, e.g. \"0.5\"")
+		flags.Duration("escrow-cap-refresh", sidecar.DefaultEscrowCapRefresh, "How long an escrow balance snapshot bounds RAVs before being refreshed from chain")
+		flags.String("max-uncollected-value", "", "GRT of accepted RAVs a collection may hold uncollected before its current RAV is collected on-chain, e.g. \"100\" (unbounded when empty)")
+		flags.Bool("watch-escrow", false, "Stop streams as soon as the session owes more than the payer's escrow balance, read from chain on session start and during streaming")
+		flags.Duration("escrow-balance-ttl", sidecar.DefaultEscrowBalanceTTL, "How long an escrow balance read by --watch-escrow is trusted before being read again from chain")
+		flags.Duration("degraded-grace", sidecar.DefaultDegradedGrace, "How long existing sessions keep being served from cached escrow balances while the chain RPC is unreachable")
+		flags.Int("max-rav-metadata-size", sidecar.DefaultMaxMetadataSize, "Largest RAV metadata accepted from consumers, in bytes")
+		flags.Bool("strict-rav-metadata", false, "Reject RAVs whose metadata is not of a known layout (empty, collection ID, collection ID and receipts merkle root)")
+		flags.Bool("quarantine", false, "Hold submitted RAVs failing the soft checks (--quarantine-max-value-increase, --quarantine-max-clock-skew, timestamp going backwards) for review on the admin server, requires --admin-listen-addr")
+		flags.String("quarantine-max-value-increase", "", "GRT a submitted RAV value aggregate may increase by over the session's current RAV before being quarantined, e.g. \"10\" (unchecked when empty)")
+		flags.Duration("quarantine-max-clock-skew", time.Minute, "How far in the future a submitted RAV timestamp may be before being quarantined (unchecked when 0)")
+		flags.Int("quarantine-capacity", sidecar.DefaultQuarantineCapacity, "Maximum number of quarantined RAVs, RAVs failing the soft checks are rejected beyond it")
+		flags.String("policy-min-escrow-headroom", "", "GRT of escrow balance the payer must keep over what its active sessions owe, e.g. \"10\" (not enforced when empty)")
+		flags.String("policy-max-payer-debt", "", "GRT of usage a payer's active sessions may be served beyond their RAVs, e.g. \"1\" (not enforced when empty)")
+		flags.String("policy-min-price-per-block", "", "Lowest GRT price per block payments are accepted for, e.g. \"0.000001\" (not enforced when empty)")
+		flags.StringSlice("policy-accepted-collections", nil, "Collection IDs (32 bytes hex) RAVs may be for (any when empty)")
+		flags.Duration("policy-max-session-duration", 0, "Longest a session is served from its start (not enforced when 0)")
+		flags.String("amount-unit", "wei", "Unit GRT amounts are displayed in by REST and admin responses and logs, 'wei' (exact) or 'grt'")
+		flags.Int("amount-decimals", sidecarlib.DefaultDisplayDecimals, "Decimal places GRT amounts are rounded to when --amount-unit is 'grt'")
+		flags.String("aggregator-url", "", "JSON-RPC endpoint of an external aggregator service turning submitted receipts into RAVs")
+		flags.String("aggregator-auth-token", "", "Bearer token sent to the external aggregator service")
+		flags.String("aggregator-private-key", "", "Private key of an embedded aggregator signing RAVs from submitted receipts, in place of --aggregator-url, the payer must authorize its address as a signer")
+		flags.Duration("receipt-aggregation-interval", sidecar.DefaultReceiptAggregationInterval, "How often the receipts of sessions in receipts payment mode are aggregated into the session RAV")
+		flags.Duration("max-rav-turnaround", 0, "Stop streaming for sessions whose consumer has not returned a signed RAV that long after one was requested (not enforced when 0)")
+		flags.String("rav-request-value", "", "GRT of usage a session's RAV may leave uncovered before a RAV is requested, e.g. \"0.1\" (requested on any uncovered usage when no threshold is set)")
+		flags.Duration("rav-request-interval", 0, "Request a RAV for the usage left uncovered that long after the session's last RAV (unused when 0)")
+		flags.Duration("shutdown-rav-timeout", sidecar.DefaultShutdownRAVTimeout, "How long shutdown waits for the final RAVs requested from active sessions (none requested when negative)")
+		flags.Bool("collect-on-shutdown", false, "Collect the current RAV of each active session on-chain on shutdown, once their final RAVs were received, requires --collect-private-key")
+		flags.Uint64("rav-request-blocks", 0, "Request a RAV for the usage left uncovered once that many blocks were streamed after the session's last RAV (unused when 0)")
 	}),
 )
 
 func runProviderSidecar(cmd *cobra.Command, args []string) error {
 	listenAddr := sflags.MustGetString(cmd, "grpc-listen-addr")
 	serviceProviderHex := sflags.MustGetString(cmd, "service-provider")
-	chainID := sflags.MustGetUint64(cmd, "chain-id")
-	collectorHex := sflags.MustGetString(cmd, "collector-address")
+	chainID := chainIDWithPreset(cmd)
+	collectorHex := addressWithPreset(cmd, "collector-address", presetCollector)
+	domainName := sflags.MustGetString(cmd, "domain-name")
+	domainVersion := sflags.MustGetString(cmd, "domain-version")
 	escrowHex := sflags.MustGetString(cmd, "escrow-address")
 	rpcEndpoint := sflags.MustGetString(cmd, "rpc-endpoint")
 	pricingConfigPath := sflags.MustGetString(cmd, "pricing-config")
+	adminListenAddr := sflags.MustGetString(cmd, "admin-listen-addr")
+	dataServiceHex := addressWithPreset(cmd, "data-service-address", presetDataService)
+	replayWindow := sflags.MustGetDuration(cmd, "replay-window")
+	sessionResumeGrace := sflags.MustGetDuration(cmd, "session-resume-grace")
+	storePath := sflags.MustGetString(cmd, "store-path")
+	sessionFlushInterval := sflags.MustGetDuration(cmd, "session-flush-interval")
+	endedSessionRetention := sflags.MustGetDuration(cmd, "ended-session-retention")
+	requireInitialRAV := sflags.MustGetBool(cmd, "require-initial-rav")
+	trustWindowBlocks := sflags.MustGetUint64(cmd, "trust-window-blocks")
+	trustWindowValueGRT := sflags.MustGetString(cmd, "trust-window-value")
+	bootstrapRAVMaxAge := sflags.MustGetDuration(cmd, "bootstrap-rav-max-age")
+	collectKeyHex := sflags.MustGetString(cmd, "collect-private-key")
+	identityKeyHex := sflags.MustGetString(cmd, "identity-private-key")
+	dataServiceCutValue := sflags.MustGetString(cmd, "data-service-cut")
+	protocolPaymentCutValue := sflags.MustGetString(cmd, "protocol-payment-cut")
+	delegationFeeCutValue := sflags.MustGetString(cmd, "delegation-fee-cut")
+	paymentType := sflags.MustGetString(cmd, "payment-type")
+	collectionPaymentTypes := sflags.MustGetStringSlice(cmd, "collection-payment-types")
+	collectRetry := sflags.MustGetBool(cmd, "collect-retry")
+	autoCollect := sflags.MustGetBool(cmd, "auto-collect")
+	collectMaxAttempts := sflags.MustGetInt(cmd, "collect-max-attempts")
+	collectRetryBackoff := sflags.MustGetDuration(cmd, "collect-retry-backoff")
+	redeemabilityCheckInterval := sflags.MustGetDuration(cmd, "redeemability-check-interval")
+	autoAcceptProvision := sflags.MustGetBool(cmd, "auto-accept-provision")
+	stakingHex := sflags.MustGetString(cmd, "staking-address")
+	provisionMaxVerifierCutValue := sflags.MustGetString(cmd, "provision-max-verifier-cut")
+	provisionMaxThawingPeriod := sflags.MustGetDuration(cmd, "provision-max-thawing-period")
+	provisionCheckInterval := sflags.MustGetDuration(cmd, "provision-check-interval")
+	escrowCap := sflags.MustGetBool(cmd, "escrow-cap")
+	escrowCapToleranceGRT := sflags.MustGetString(cmd, "escrow-cap-tolerance")
+	escrowCapRefresh := sflags.MustGetDuration(cmd, "escrow-cap-refresh")
+	maxUncollectedValueGRT := sflags.MustGetString(cmd, "max-uncollected-value")
+	watchEscrow := sflags.MustGetBool(cmd, "watch-escrow")
+	escrowBalanceTTL := sflags.MustGetDuration(cmd, "escrow-balance-ttl")
+	degradedGrace := sflags.MustGetDuration(cmd, "degraded-grace")
+	maxMetadataSize := sflags.MustGetInt(cmd, "max-rav-metadata-size")
+	strictMetadata := sflags.MustGetBool(cmd, "strict-rav-metadata")
+	quarantine := sflags.MustGetBool(cmd, "quarantine")
+	quarantineMaxValueIncreaseGRT := sflags.MustGetString(cmd, "quarantine-max-value-increase")
+	quarantineMaxClockSkew := sflags.MustGetDuration(cmd, "quarantine-max-clock-skew")
+	quarantineCapacity := sflags.MustGetInt(cmd, "quarantine-capacity")
+	policyMinEscrowHeadroomGRT := sflags.MustGetString(cmd, "policy-min-escrow-headroom")
+	policyMaxPayerDebtGRT := sflags.MustGetString(cmd, "policy-max-payer-debt")
+	policyMinPricePerBlockGRT := sflags.MustGetString(cmd, "policy-min-price-per-block")
+	policyAcceptedCollectionsHex := sflags.MustGetStringSlice(cmd, "policy-accepted-collections")
+	policyMaxSessionDuration := sflags.MustGetDuration(cmd, "policy-max-session-duration")
+	amountUnitName := sflags.MustGetString(cmd, "amount-unit")
+	amountDecimals := sflags.MustGetInt(cmd, "amount-decimals")
+	aggregatorURL := sflags.MustGetString(cmd, "aggregator-url")
+	aggregatorAuthToken := sflags.MustGetString(cmd, "aggregator-auth-token")
+	aggregatorKeyHex := sflags.MustGetString(cmd, "aggregator-private-key")
+	receiptAggregationInterval := sflags.MustGetDuration(cmd, "receipt-aggregation-interval")
+	maxRAVTurnaround := sflags.MustGetDuration(cmd, "max-rav-turnaround")
+	ravRequestValueGRT := sflags.MustGetString(cmd, "rav-request-value")
+	ravRequestInterval := sflags.MustGetDuration(cmd, "rav-request-interval")
+	ravRequestBlocks := sflags.MustGetUint64(cmd, "rav-request-blocks")
+	shutdownRAVTimeout := sflags.MustGetDuration(cmd, "shutdown-rav-timeout")
+	collectOnShutdown := sflags.MustGetBool(cmd, "collect-on-shutdown")
 
 	cli.Ensure(serviceProviderHex != "", "<service-provider> is required")
-	serviceProviderAddr, err := eth.NewAddress(serviceProviderHex)
+	serviceProviderAddr, err := resolveAddress(cmd, serviceProviderHex)
 	cli.NoError(err, "invalid <service-provider> %q", serviceProviderHex)
 
 	cli.Ensure(collectorHex != "", "<collector-address> is required")
-	collectorAddr, err := eth.NewAddress(collectorHex)
+	collectorAddr, err := resolveAddress(cmd, collectorHex)
 	cli.NoError(err, "invalid <collector-address> %q", collectorHex)
 
 	cli.Ensure(escrowHex != "", "<escrow-address> is required")
-	escrowAddr, err := eth.NewAddress(escrowHex)
+	escrowAddr, err := resolveAddress(cmd, escrowHex)
 	cli.NoError(err, "invalid <escrow-address> %q", escrowHex)
 
 	cli.Ensure(rpcEndpoint != "", "<rpc-endpoint> is required")
 
+	cli.Ensure(replayWindow > 0, "<replay-window> must be greater than 0")
+	cli.Ensure(bootstrapRAVMaxAge >= 0, "<bootstrap-rav-max-age> must not be negative")
+	cli.Ensure(sessionFlushInterval > 0, "<session-flush-interval> must be greater than 0")
+	cli.Ensure(endedSessionRetention >= 0, "<ended-session-retention> must not be negative")
+
+	var dataServiceAddr eth.Address
+	if dataServiceHex != "" {
+		dataServiceAddr, err = resolveAddress(cmd, dataServiceHex)
+		cli.NoError(err, "invalid <data-service-address> %q", dataServiceHex)
+	}
+
+	var collectKey *eth.PrivateKey
+	if collectKeyHex != "" {
+		cli.Ensure((adminListenAddr != "" || autoAcceptProvision || autoCollect || collectOnShutdown) && dataServiceAddr != nil, "<collect-private-key> requires <data-service-address> and either <admin-listen-addr>, <auto-accept-provision>, <auto-collect> or <collect-on-shutdown>")
+		collectKey, err = eth.NewPrivateKey(collectKeyHex)
+		cli.NoError(err, "invalid <collect-private-key>")
+	}
+
+	var identityKey *eth.PrivateKey
+	if identityKeyHex != "" {
+		identityKey, err = eth.NewPrivateKey(identityKeyHex)
+		cli.NoError(err, "invalid <identity-private-key>")
+		cli.Ensure(bytes.Equal(identityKey.PublicKey().Address(), serviceProviderAddr), "<identity-private-key> must be the <service-provider> key, got key of %s", identityKey.PublicKey().Address().Pretty())
+	}
+
+	var provisionBounds *sidecarlib.ProvisionBounds
+	var stakingAddr eth.Address
+	if autoAcceptProvision {
+		cli.Ensure(collectKey != nil, "<auto-accept-provision> requires <collect-private-key>")
+		cli.Ensure(stakingHex != "", "<auto-accept-provision> requires <staking-address>")
+		stakingAddr, err = resolveAddress(cmd, stakingHex)
+		cli.NoError(err, "invalid <staking-address> %q", stakingHex)
+		provisionMaxVerifierCut, err := horizon.ParsePPM(provisionMaxVerifierCutValue)
+		cli.NoError(err, "invalid <provision-max-verifier-cut> %q", provisionMaxVerifierCutValue)
+		cli.Ensure(provisionMaxThawingPeriod > 0, "<auto-accept-provision> requires <provision-max-thawing-period>")
+		cli.Ensure(provisionCheckInterval > 0, "<provision-check-interval> must be greater than 0")
+
+		provisionBounds = &sidecarlib.ProvisionBounds{
+			MaxVerifierCut:   provisionMaxVerifierCut,
+			MaxThawingPeriod: provisionMaxThawingPeriod,
+		}
+	}
+
+	dataServiceCut, err := horizon.ParsePPM(dataServiceCutValue)
+	cli.NoError(err, "invalid <data-service-cut> %q", dataServiceCutValue)
+	protocolPaymentCut, err := horizon.ParsePPM(protocolPaymentCutValue)
+	cli.NoError(err, "invalid <protocol-payment-cut> %q", protocolPaymentCutValue)
+	delegationFeeCut, err := horizon.ParsePPM(delegationFeeCutValue)
+	cli.NoError(err, "invalid <delegation-fee-cut> %q", delegationFeeCutValue)
+
+	paymentTypes, err := sidecarlib.ParsePaymentTypes(paymentType, collectionPaymentTypes)
+	cli.NoError(err, "invalid <payment-type> or <collection-payment-types>")
+
+	cli.Ensure(!autoCollect || collectKey != nil, "<auto-collect> requires <collect-private-key>")
+	cli.Ensure(!collectOnShutdown || collectKey != nil, "<collect-on-shutdown> requires <collect-private-key>")
+
+	var collectRetryPolicy *sidecar.CollectRetryPolicy
+	if collectRetry {
+		cli.Ensure(adminListenAddr != "" && collectKey != nil, "<collect-retry> requires <admin-listen-addr> and <collect-private-key>")
+		cli.Ensure(collectMaxAttempts > 0, "<collect-max-attempts> must be greater than 0")
+		cli.Ensure(collectRetryBackoff > 0, "<collect-retry-backoff> must be greater than 0")
+
+		collectRetryPolicy = &sidecar.CollectRetryPolicy{MaxAttempts: collectMaxAttempts, Backoff: collectRetryBackoff}
+	}
+
+	if aggregatorURL != "" {
+		parsed, err := url.Parse(aggregatorURL)
+		cli.NoError(err, "invalid <aggregator-url> %q", aggregatorURL)
+		cli.Ensure(parsed.Scheme == "http" || parsed.Scheme == "https", "<aggregator-url> must be an http(s) URL, got %q", aggregatorURL)
+	}
+	cli.Ensure(aggregatorAuthToken == "" || aggregatorURL != "", "<aggregator-auth-token> requires <aggregator-url>")
+
+	var aggregatorKey *eth.PrivateKey
+	if aggregatorKeyHex != "" {
+		cli.Ensure(aggregatorURL == "", "<aggregator-private-key> and <aggregator-url> are mutually exclusive")
+		aggregatorKey, err = eth.NewPrivateKey(aggregatorKeyHex)
+		cli.NoError(err, "invalid <aggregator-private-key>")
+	}
+	cli.Ensure(receiptAggregationInterval > 0, "<receipt-aggregation-interval> must be greater than 0")
+	cli.Ensure(maxRAVTurnaround >= 0, "<max-rav-turnaround> must not be negative")
+	cli.Ensure(ravRequestInterval >= 0, "<rav-request-interval> must not be negative")
+
+	var ravRequestThresholds *sidecar.RAVRequestThresholds
+	if ravRequestValueGRT != "" || ravRequestInterval > 0 || ravRequestBlocks > 0 {
+		ravRequestThresholds = &sidecar.RAVRequestThresholds{Interval: ravRequestInterval, Blocks: ravRequestBlocks}
+		if ravRequestValueGRT != "" {
+			ravRequestThresholds.Value, err = devenv.ParseGRT(ravRequestValueGRT)
+			cli.NoError(err, "invalid <rav-request-value> %q", ravRequestValueGRT)
+			cli.Ensure(ravRequestThresholds.Value.Sign() >= 0, "<rav-request-value> must not be negative")
+		}
+	}
+
+	escrowCapTolerance, err := devenv.ParseGRT(escrowCapToleranceGRT)
+	cli.NoError(err, "invalid <escrow-cap-tolerance> %q", escrowCapToleranceGRT)
+	cli.Ensure(escrowCapTolerance.Sign() >= 0, "<escrow-cap-tolerance> must not be negative")
+	cli.Ensure(escrowCapRefresh > 0, "<escrow-cap-refresh> must be greater than 0")
+
+	var maxUncollectedValue *big.Int
+	if maxUncollectedValueGRT != "" {
+		maxUncollectedValue, err = devenv.ParseGRT(maxUncollectedValueGRT)
+		cli.NoError(err, "invalid <max-uncollected-value> %q", maxUncollectedValueGRT)
+		cli.Ensure(maxUncollectedValue.Sign() > 0, "<max-uncollected-value> must be greater than 0")
+	}
+	cli.Ensure(escrowBalanceTTL > 0, "<escrow-balance-ttl> must be greater than 0")
+	cli.Ensure(degradedGrace > 0, "<degraded-grace> must be greater than 0")
+	cli.Ensure(maxMetadataSize > 0, "<max-rav-metadata-size> must be greater than 0")
+
+	var trustWindow *sidecar.TrustWindow
+	if trustWindowBlocks > 0 || trustWindowValueGRT != "" {
+		trustWindow = &sidecar.TrustWindow{Blocks: trustWindowBlocks}
+		if trustWindowValueGRT != "" {
+			trustWindow.Value, err = devenv.ParseGRT(trustWindowValueGRT)
+			cli.NoError(err, "invalid <trust-window-value> %q", trustWindowValueGRT)
+			cli.Ensure(trustWindow.Value.Sign() >= 0, "<trust-window-value> must not be negative")
+		}
+	}
+
+	var quarantinePolicy *sidecar.QuarantinePolicy
+	if quarantine {
+		cli.Ensure(adminListenAddr != "", "<quarantine> requires <admin-listen-addr>")
+		cli.Ensure(quarantineMaxClockSkew >= 0, "<quarantine-max-clock-skew> must not be negative")
+		cli.Ensure(quarantineCapacity > 0, "<quarantine-capacity> must be greater than 0")
+
+		quarantinePolicy = &sidecar.QuarantinePolicy{MaxClockSkew: quarantineMaxClockSkew, Capacity: quarantineCapacity}
+		if quarantineMaxValueIncreaseGRT != "" {
+			quarantinePolicy.MaxValueIncrease, err = devenv.ParseGRT(quarantineMaxValueIncreaseGRT)
+			cli.NoError(err, "invalid <quarantine-max-value-increase> %q", quarantineMaxValueIncreaseGRT)
+			cli.Ensure(quarantinePolicy.MaxValueIncrease.Sign() >= 0, "<quarantine-max-value-increase> must not be negative")
+		}
+	}
+
+	cli.Ensure(policyMaxSessionDuration >= 0, "<policy-max-session-duration> must not be negative")
+	// Policy amounts are not enforced when empty
+	parsePolicyGRT := func(flag, value string) *big.Int {
+		if value == "" {
+			return nil
+		}
+		amount, err := devenv.ParseGRT(value)
+		cli.NoError(err, "invalid <%s> %q", flag, value)
+		cli.Ensure(amount.Sign() >= 0, "<%s> must not be negative", flag)
+		return amount
+	}
+	paymentPolicy := &sidecar.PaymentPolicy{
+		MinEscrowHeadroom:  parsePolicyGRT("policy-min-escrow-headroom", policyMinEscrowHeadroomGRT),
+		MaxPayerDebt:       parsePolicyGRT("policy-max-payer-debt", policyMaxPayerDebtGRT),
+		MinPricePerBlock:   parsePolicyGRT("policy-min-price-per-block", policyMinPricePerBlockGRT),
+		MaxSessionDuration: policyMaxSessionDuration,
+	}
+	for _, collectionIDHex := range policyAcceptedCollectionsHex {
+		collectionHash, err := eth.NewHash(collectionIDHex)
+		cli.NoError(err, "invalid <policy-accepted-collections> entry %q", collectionIDHex)
+
+		var collectionID horizon.CollectionID
+		cli.Ensure(len(collectionHash) == len(collectionID), "<policy-accepted-collections> entry %q must be %d bytes", collectionIDHex, len(collectionID))
+		copy(collectionID[:], collectionHash)
+		paymentPolicy.AcceptedCollections = append(paymentPolicy.AcceptedCollections, collectionID)
+	}
+	if policyMinEscrowHeadroomGRT == "" && policyMaxPayerDebtGRT == "" && policyMinPricePerBlockGRT == "" && len(policyAcceptedCollectionsHex) == 0 && policyMaxSessionDuration == 0 {
+		paymentPolicy = nil
+	}
+
+	amountUnit, err := sidecarlib.ParseAmountUnit(amountUnitName)
+	cli.NoError(err, "invalid <amount-unit> %q", amountUnitName)
+	amountDisplay, err := sidecarlib.NewAmountDisplay(amountUnit, amountDecimals)
+	cli.NoError(err, "invalid <amount-decimals> %d", amountDecimals)
+
 	// Load pricing configuration
 	var pricingConfig *sidecarlib.PricingConfig
 	if pricingConfigPath != "" {
@@ -79,12 +530,75 @@ func runProviderSidecar(cmd *cobra.Command, args []string) error {
 	config := &sidecar.Config{
 		ListenAddr:      listenAddr,
 		ServiceProvider: serviceProviderAddr,
-		Domain:          horizon.NewDomain(chainID, collectorAddr),
+		Domain:          horizon.NewDomainWithNameVersion(domainName, domainVersion, chainID, collectorAddr),
 		CollectorAddr:   collectorAddr,
 		EscrowAddr:      escrowAddr,
 		RPCEndpoint:     rpcEndpoint,
 		PricingConfig:   pricingConfig,
 		AcceptedSigners: nil, // Will be configured dynamically
+
+		AggregatorURL:       aggregatorURL,
+		AggregatorAuthToken: aggregatorAuthToken,
+		AggregatorKey:       aggregatorKey,
+
+		ReceiptAggregationInterval: receiptAggregationInterval,
+		MaxRAVTurnaround:           maxRAVTurnaround,
+		RAVRequestThresholds:       ravRequestThresholds,
+		ShutdownRAVTimeout:         shutdownRAVTimeout,
+		CollectOnShutdown:          collectOnShutdown,
+
+		AdminListenAddr: adminListenAddr,
+		DataServiceAddr: dataServiceAddr,
+		ReplayWindow:    replayWindow,
+
+		SessionResumeGrace:    sessionResumeGrace,
+		StorePath:             storePath,
+		SessionFlushInterval:  sessionFlushInterval,
+		EndedSessionRetention: endedSessionRetention,
+		RequireInitialRAV:     requireInitialRAV,
+		TrustWindow:           trustWindow,
+		BootstrapRAVMaxAge:    bootstrapRAVMaxAge,
+
+		CollectKey:         collectKey,
+		DataServiceCut:     dataServiceCut,
+		ProtocolPaymentCut: protocolPaymentCut,
+		DelegationFeeCut:   delegationFeeCut,
+		PaymentTypes:       paymentTypes,
+		CollectRetry:       collectRetryPolicy,
+		AutoCollect:        autoCollect,
+		IdentityKey:        identityKey,
+
+		RedeemabilityCheckInterval: redeemabilityCheckInterval,
+
+		ProvisionBounds:        provisionBounds,
+		StakingAddr:            stakingAddr,
+		ProvisionCheckInterval: provisionCheckInterval,
+
+		EnforceEscrowCap:   escrowCap,
+		EscrowCapTolerance: escrowCapTolerance,
+		EscrowCapRefresh:   escrowCapRefresh,
+
+		MaxUncollectedValue: maxUncollectedValue,
+
+		WatchEscrow:      watchEscrow,
+		EscrowBalanceTTL: escrowBalanceTTL,
+
+		DegradedGrace: degradedGrace,
+
+		AmountDisplay: amountDisplay,
+
+		MaxMetadataSize: maxMetadataSize,
+		StrictMetadata:  strictMetadata,
+
+		Quarantine: quarantinePolicy,
+
+		PaymentPolicy: paymentPolicy,
+	}
+
+	tracerProvider, err := tracing.SetupOpenTelemetry(cmd.Context(), "sds-provider-sidecar")
+	cli.NoError(err, "invalid SF_TRACING")
+	if tracerProvider != nil {
+		defer tracerProvider.Shutdown(context.Background())
 	}
 
 	app := NewApplication(cmd.Context())
@@ -92,5 +606,14 @@ func runProviderSidecar(cmd *cobra.Command, args []string) error {
 	sidecarServer := sidecar.New(config, providerLog)
 	app.SuperviseAndStart(sidecarServer)
 
-	return app.WaitForTermination(providerLog, 0*time.Second, 30*time.Second)
+	// Shutdown awaits the final RAVs of active sessions, then collects them
+	gracefulShutdownDelay := 30 * time.Second
+	if shutdownRAVTimeout > 0 {
+		gracefulShutdownDelay += shutdownRAVTimeout
+	}
+	if collectOnShutdown {
+		gracefulShutdownDelay += sidecar.ShutdownCollectTimeout
+	}
+
+	return app.WaitForTermination(providerLog, 0*time.Second, gracefulShutdownDelay)
 }
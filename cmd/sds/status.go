@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon/devenv"
+	"github.com/graphprotocol/substreams-data-service/provider/sidecar"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	. "github.com/streamingfast/cli"
+	"github.com/streamingfast/cli/sflags"
+	"github.com/streamingfast/eth-go"
+	"github.com/streamingfast/eth-go/rpc"
+)
+
+var statusCmd = Command(
+	runStatus,
+	"status",
+	"Show a readiness overview of the local stack: devenv, chain, both sidecars and pending collections",
+	Description(`
+		Queries every component of a local development stack and prints a single
+		overview:
+
+		- the development environment, when its --export-file exists
+		- the chain, --rpc-endpoint or the development environment RPC: head
+		  block and sync state (eth_syncing)
+		- the provider and consumer sidecars '/readyz' endpoints, on their
+		  --admin-listen-addr, with the outcome of each readiness check
+		- the final RAVs awaiting on-chain collection on the provider sidecar
+
+		Components that are not configured are skipped, use an empty address to
+		skip a sidecar. The command fails when any queried component is not ready.
+	`),
+	NoArgs(),
+	Flags(func(flags *pflag.FlagSet) {
+		flags.String("export-file", devenv.DefaultExportFile, "Path of the JSON file describing the running development environment")
+		flags.String("rpc-endpoint", "", "Chain RPC endpoint, the development environment RPC when empty")
+		flags.String("provider-admin-addr", "localhost:9101", "Provider sidecar admin server address, its --admin-listen-addr (skipped when empty)")
+		flags.String("consumer-admin-addr", "localhost:9102", "Consumer sidecar admin server address, its --admin-listen-addr (skipped when empty)")
+		flags.Duration("timeout", 5*time.Second, "Maximum time to wait for each component to answer")
+	}),
+)
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	exportFile := sflags.MustGetString(cmd, "export-file")
+	rpcEndpoint := sflags.MustGetString(cmd, "rpc-endpoint")
+	providerAdminAddr := sflags.MustGetString(cmd, "provider-admin-addr")
+	consumerAdminAddr := sflags.MustGetString(cmd, "consumer-admin-addr")
+	timeout := sflags.MustGetDuration(cmd, "timeout")
+
+	var notReady []string
+
+	// Development environment
+	export, err := readDevenvExport(exportFile)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		fmt.Printf("Devenv:            not running (no %s)\n", exportFile)
+	case err != nil:
+		fmt.Printf("Devenv:            NOT READY, %s\n", err)
+		notReady = append(notReady, "devenv")
+	default:
+		fmt.Printf("Devenv:            running, chain %d at %s\n", export.ChainID, export.RPCURL)
+		if export.Secondary != nil {
+			fmt.Printf("                   secondary chain %d at %s\n", export.Secondary.ChainID, export.Secondary.RPCURL)
+		}
+		if rpcEndpoint == "" {
+			rpcEndpoint = export.RPCURL
+		}
+	}
+
+	// Chain
+	if rpcEndpoint == "" {
+		fmt.Println("Chain:             skipped (no --rpc-endpoint)")
+	} else {
+		ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+		status, err := chainStatus(ctx, rpcEndpoint)
+		cancel()
+		if err != nil {
+			fmt.Printf("Chain:             NOT READY, %s\n", err)
+			notReady = append(notReady, "chain")
+		} else {
+			fmt.Printf("Chain:             %s\n", status)
+		}
+	}
+
+	// Sidecars
+	for _, component := range []struct{ name, addr string }{
+		{"provider", providerAdminAddr},
+		{"consumer", consumerAdminAddr},
+	} {
+		label := fmt.Sprintf("%-19s", strings.ToUpper(component.name[:1])+component.name[1:]+" sidecar:")
+		if component.addr == "" {
+			fmt.Println(label + "skipped")
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+		readiness, err := sidecarReadiness(ctx, adminBaseURL(component.addr))
+		cancel()
+		if err != nil {
+			fmt.Printf("%sNOT READY, %s\n", label, err)
+			notReady = append(notReady, component.name+" sidecar")
+			continue
+		}
+
+		state := "ready"
+		switch {
+		case !readiness.Ready:
+			state = "NOT READY"
+			notReady = append(notReady, component.name+" sidecar")
+		case readiness.Degraded:
+			state = "ready (degraded)"
+		}
+		fmt.Printf("%s%s (%s)\n", label, state, component.addr)
+
+		names := make([]string, 0, len(readiness.Checks))
+		for name := range readiness.Checks {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			fmt.Printf("                     %s: %s\n", name, readiness.Checks[name])
+		}
+	}
+
+	// Pending collections
+	if providerAdminAddr != "" && !slices.Contains(notReady, "provider sidecar") {
+		var pending struct {
+			AmountUnit string                `json:"amount_unit"`
+			Pending    []*sidecar.PendingRAV `json:"pending"`
+		}
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+		err := adminRequest(ctx, http.MethodGet, adminBaseURL(providerAdminAddr)+"/v1/collections/pending", nil, &pending)
+		cancel()
+		if err != nil {
+			fmt.Printf("Collections:       unknown, %s\n", err)
+		} else {
+			fmt.Printf("Collections:       %s\n", pendingCollectionsSummary(pending.Pending, pending.AmountUnit))
+		}
+	}
+
+	fmt.Println()
+	if len(notReady) > 0 {
+		return fmt.Errorf("not ready: %s", strings.Join(notReady, ", "))
+	}
+	fmt.Println("Stack is ready")
+	return nil
+}
+
+func readDevenvExport(path string) (*devenv.Export, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var export devenv.Export
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("parsing export file %q: %w", path, err)
+	}
+	return &export, nil
+}
+
+// chainStatus describes the head block of the chain and whether its node is
+// still syncing
+func chainStatus(ctx context.Context, rpcEndpoint string) (string, error) {
+	client := rpc.NewClient(rpcEndpoint)
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return "", fmt.Errorf("querying chain ID: %w", err)
+	}
+
+	head, err := client.GetBlockByNumber(ctx, rpc.LatestBlock)
+	if err != nil {
+		return "", fmt.Errorf("fetching latest block: %w", err)
+	}
+
+	// eth_syncing returns false once synced, the sync progress otherwise
+	syncing, err := rpc.Do[json.RawMessage](client, ctx, "eth_syncing", nil)
+	if err != nil {
+		return "", fmt.Errorf("querying sync state: %w", err)
+	}
+	sync := "synced"
+	if string(syncing) != "false" {
+		var progress struct {
+			CurrentBlock eth.Uint64 `json:"currentBlock"`
+			HighestBlock eth.Uint64 `json:"highestBlock"`
+		}
+		if err := json.Unmarshal(syncing, &progress); err != nil {
+			return "", fmt.Errorf("decoding sync state: %w", err)
+		}
+		sync = fmt.Sprintf("SYNCING, at #%d of #%d", uint64(progress.CurrentBlock), uint64(progress.HighestBlock))
+	}
+
+	age := time.Since(time.Time(head.Timestamp)).Round(time.Second)
+	return fmt.Sprintf("chain %s, block #%d (%s ago), %s", chainID, uint64(head.Number), age, sync), nil
+}
+
+// sidecarReadinessResponse is the body of a sidecar admin server '/readyz'
+type sidecarReadinessResponse struct {
+	Ready    bool              `json:"ready"`
+	Degraded bool              `json:"degraded,omitempty"`
+	Checks   map[string]string `json:"checks"`
+}
+
+// sidecarReadiness queries the '/readyz' endpoint of a sidecar admin server,
+// which answers 503 along with the failed checks when not ready
+func sidecarReadiness(ctx context.Context, adminAddr string) (*sidecarReadinessResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, adminAddr+"/readyz", nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var out sidecarReadinessResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decoding readiness: %w", err)
+	}
+	return &out, nil
+}
+
+// pendingCollectionsSummary counts the final RAVs awaiting collection, with
+// their total value when amounts are exact wei
+func pendingCollectionsSummary(pending []*sidecar.PendingRAV, unit string) string {
+	if len(pending) == 0 {
+		return "no final RAV awaiting collection"
+	}
+
+	summary := fmt.Sprintf("%d final RAV(s) awaiting collection", len(pending))
+	if unit == "" || unit == "wei" {
+		total := new(big.Int)
+		for _, rav := range pending {
+			if value, ok := new(big.Int).SetString(rav.ValueAggregate, 10); ok {
+				total.Add(total, value)
+			}
+		}
+		summary += ", " + formatAmount(total.String(), unit)
+	}
+	return summary + " (sds provider collect-pending)"
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/graphprotocol/substreams-data-service/horizon/compat"
+	sidecarlib "github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/streamingfast/cli"
+	. "github.com/streamingfast/cli"
+	"github.com/streamingfast/cli/sflags"
+	"github.com/streamingfast/eth-go"
+	"github.com/streamingfast/eth-go/rpc"
+)
+
+var verifyEscrowCmd = Command(
+	runVerifyEscrow,
+	"escrow",
+	"Query the escrow balance and tokens collected for a payer, optionally at a past block",
+	Description(`
+		Queries PaymentsEscrow.getBalance(payer, collector, receiver) and, when
+		--data-service and --collection-id are set,
+		GraphTallyCollector.tokensCollected(dataService, collectionId, receiver, payer).
+
+		With --block, both are read at that block instead of the latest one, e.g.
+		to establish what the payer had deposited and what was already collected
+		when a disputed RAV was issued. Blocks outside the node's pruning window
+		require an archive RPC endpoint.
+	`),
+	Flags(func(flags *pflag.FlagSet) {
+		flags.String("rpc-endpoint", "", "Ethereum RPC endpoint, an archive node for old blocks (required)")
+		flags.String("escrow-address", "", "PaymentsEscrow contract address (required)")
+		flags.String("collector-address", "", "GraphTallyCollector contract address (required)")
+		flags.String("payer", "", "Payer address (required)")
+		flags.String("receiver", "", "Receiver (service provider) address (required)")
+		flags.String("data-service", "", "Data service contract address, required to query tokens collected")
+		flags.String("collection-id", "", "Collection ID (32 bytes hex), required to query tokens collected")
+		flags.Uint64("block", 0, "Block number to query at (latest when 0)")
+	}),
+)
+
+func runVerifyEscrow(cmd *cobra.Command, args []string) error {
+	rpcEndpoint := sflags.MustGetString(cmd, "rpc-endpoint")
+	escrowHex := sflags.MustGetString(cmd, "escrow-address")
+	collectorHex := sflags.MustGetString(cmd, "collector-address")
+	payerHex := sflags.MustGetString(cmd, "payer")
+	receiverHex := sflags.MustGetString(cmd, "receiver")
+	dataServiceHex := sflags.MustGetString(cmd, "data-service")
+	collectionIDHex := sflags.MustGetString(cmd, "collection-id")
+	block := sflags.MustGetUint64(cmd, "block")
+
+	cli.Ensure(rpcEndpoint != "", "<rpc-endpoint> is required")
+
+	cli.Ensure(escrowHex != "", "<escrow-address> is required")
+	escrowAddr, err := resolveAddress(cmd, escrowHex)
+	cli.NoError(err, "invalid <escrow-address> %q", escrowHex)
+
+	cli.Ensure(collectorHex != "", "<collector-address> is required")
+	collectorAddr, err := resolveAddress(cmd, collectorHex)
+	cli.NoError(err, "invalid <collector-address> %q", collectorHex)
+
+	cli.Ensure(payerHex != "", "<payer> is required")
+	payer, err := resolveAddress(cmd, payerHex)
+	cli.NoError(err, "invalid <payer> %q", payerHex)
+
+	cli.Ensure(receiverHex != "", "<receiver> is required")
+	receiver, err := resolveAddress(cmd, receiverHex)
+	cli.NoError(err, "invalid <receiver> %q", receiverHex)
+
+	cli.Ensure((dataServiceHex == "") == (collectionIDHex == ""), "<data-service> and <collection-id> must be set together")
+
+	var dataService eth.Address
+	var collectionID horizon.CollectionID
+	if dataServiceHex != "" {
+		dataService, err = resolveAddress(cmd, dataServiceHex)
+		cli.NoError(err, "invalid <data-service> %q", dataServiceHex)
+
+		collectionHash, err := eth.NewHash(collectionIDHex)
+		cli.NoError(err, "invalid <collection-id> %q", collectionIDHex)
+		cli.Ensure(len(collectionHash) == len(collectionID), "<collection-id> must be %d bytes, got %d", len(collectionID), len(collectionHash))
+		copy(collectionID[:], collectionHash)
+	}
+
+	var at *rpc.BlockRef
+	blockLabel := "latest"
+	if block > 0 {
+		at = rpc.BlockNumber(block)
+		blockLabel = strconv.FormatUint(block, 10)
+	}
+
+	querier := sidecarlib.NewEscrowQuerier(rpcEndpoint, escrowAddr)
+
+	balance, err := querier.GetBalanceAt(cmd.Context(), payer, collectorAddr, receiver, at)
+	if err != nil {
+		return fmt.Errorf("querying escrow balance at block %s: %w", blockLabel, err)
+	}
+
+	fmt.Printf("Block:            %s\n", blockLabel)
+	fmt.Printf("Payer:            %s\n", payer.Pretty())
+	fmt.Printf("Receiver:         %s\n", receiver.Pretty())
+	fmt.Printf("Escrow balance:   %s GRT\n", formatWei(balance))
+
+	if dataService != nil {
+		collected, err := querier.TokensCollectedAt(cmd.Context(), collectorAddr, dataService, collectionID, receiver, payer, at)
+		if err != nil {
+			return fmt.Errorf("querying tokens collected at block %s: %w", blockLabel, err)
+		}
+		fmt.Printf("Tokens collected: %s GRT\n", formatWei(collected))
+	}
+
+	return nil
+}
+
+var verifyEIP712Cmd = Command(
+	runVerifyEIP712,
+	"eip712",
+	"Check that Go EIP-712 hashing and signing of RAVs match the GraphTallyCollector deployed on a network",
+	NoArgs(),
+	Description(`
+		Draws --samples random RAVs, covering the edges of each field, signs each
+		with a random key and compares the EIP-712 hash and the recovered signer
+		computed in Go with the ones of the collector's encodeRAV and
+		recoverRAVSigner, through eth_call. Run it against a network before
+		pointing sidecars to it, or after a collector upgrade: any mismatch means
+		RAVs signed by the sidecars would not be collectable there.
+
+		The chain ID of the EIP-712 domain is queried from --rpc-endpoint unless
+		--chain-id is set. A failing run prints its seed, pass it to --seed to
+		reproduce the same RAVs.
+	`),
+	Flags(func(flags *pflag.FlagSet) {
+		flags.String("rpc-endpoint", "", "Ethereum RPC endpoint (required)")
+		flags.String("collector-address", "", "GraphTallyCollector contract address (required)")
+		flags.Uint64("chain-id", 0, "Chain ID of the EIP-712 domain, queried from --rpc-endpoint when 0")
+		flags.Int("samples", 100, "Number of random RAVs checked")
+		flags.Int64("seed", 0, "Seed of the random RAVs, the current time when 0")
+	}),
+)
+
+func runVerifyEIP712(cmd *cobra.Command, args []string) error {
+	rpcEndpoint := sflags.MustGetString(cmd, "rpc-endpoint")
+	collectorHex := sflags.MustGetString(cmd, "collector-address")
+	chainID := sflags.MustGetUint64(cmd, "chain-id")
+	samples := sflags.MustGetInt(cmd, "samples")
+	seed := sflags.MustGetInt64(cmd, "seed")
+
+	cli.Ensure(rpcEndpoint != "", "<rpc-endpoint> is required")
+	cli.Ensure(samples > 0, "<samples> must be greater than 0")
+
+	cli.Ensure(collectorHex != "", "<collector-address> is required")
+	collector, err := resolveAddress(cmd, collectorHex)
+	cli.NoError(err, "invalid <collector-address> %q", collectorHex)
+
+	if chainID == 0 {
+		id, err := rpc.NewClient(rpcEndpoint).ChainID(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("querying chain ID: %w", err)
+		}
+		chainID = id.Uint64()
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	fmt.Printf("Collector: %s (chain %d)\n", collector.Pretty(), chainID)
+	fmt.Printf("Seed:      %d\n", seed)
+
+	report, err := compat.NewChecker(rpcEndpoint, chainID, collector).Run(cmd.Context(), samples, rand.New(rand.NewSource(seed)))
+	if err != nil {
+		return fmt.Errorf("checking after %d RAV(s): %w", report.Checked, err)
+	}
+
+	for _, mismatch := range report.Mismatches {
+		rav := mismatch.RAV
+		fmt.Printf("\nMismatch: %s\n", mismatch.Err)
+		fmt.Printf("  collectionId:    %s\n", eth.Hash(rav.CollectionID[:]).Pretty())
+		fmt.Printf("  payer:           %s\n", rav.Payer.Pretty())
+		fmt.Printf("  serviceProvider: %s\n", rav.ServiceProvider.Pretty())
+		fmt.Printf("  dataService:     %s\n", rav.DataService.Pretty())
+		fmt.Printf("  timestampNs:     %d\n", rav.TimestampNs)
+		fmt.Printf("  valueAggregate:  %s\n", rav.ValueAggregate)
+		fmt.Printf("  metadata:        0x%x\n", rav.Metadata)
+		fmt.Printf("  signer:          %s\n", mismatch.Signer.Pretty())
+	}
+
+	fmt.Printf("\nChecked %d RAV(s), %d mismatch(es)\n", report.Checked, len(report.Mismatches))
+	if len(report.Mismatches) > 0 {
+		return fmt.Errorf("EIP-712 encoding of RAVs in Go differs from the collector at %s, reproduce with --seed %d", collector.Pretty(), seed)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+// Package client helps Substreams clients pay providers through the consumer
+// sidecar: it opens the payment session, attaches the current RAV to outgoing
+// Blocks() requests and keeps it up to date as usage is reported.
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"connectrpc.com/connect"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	consumerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/consumer/v1"
+	"github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/consumer/v1/consumerv1connect"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// IsBlocksMethod reports whether the full gRPC method name is the Substreams
+// Blocks() stream, of any version of the sf.substreams.rpc Stream service
+func IsBlocksMethod(method string) bool {
+	return strings.HasPrefix(method, "/sf.substreams.rpc.") && strings.HasSuffix(method, ".Stream/Blocks")
+}
+
+// PaymentInterceptor attaches the payment header to the outgoing Substreams
+// requests of a gRPC client (step 5 of the payment flow, 'ss->p: Blocks()').
+//
+// The payment session is initialized with the consumer sidecar (Init) on the
+// first paid request, its payment RAV is then sent on every paid request,
+// reconnections included. ReportUsage forwards the usage received from the
+// provider to the consumer sidecar and keeps the most recent RAV it signed,
+// so a reconnection presents it and resumes the session.
+type PaymentInterceptor struct {
+	sidecar consumerv1connect.ConsumerSidecarServiceClient
+	init    *consumerv1.InitRequest
+	methods func(method string) bool
+	logger  *zap.Logger
+
+	mu        sync.Mutex
+	sessionID string
+	rav       *commonv1.SignedRAV
+}
+
+// NewPaymentInterceptor returns an interceptor paying the Blocks() requests
+// through the consumer sidecar client, init describes the escrow account and
+// provider endpoint of the payment session
+func NewPaymentInterceptor(sidecar consumerv1connect.ConsumerSidecarServiceClient, init *consumerv1.InitRequest, logger *zap.Logger) *PaymentInterceptor {
+	return &PaymentInterceptor{
+		sidecar: sidecar,
+		init:    init,
+		methods: IsBlocksMethod,
+		logger:  logger,
+	}
+}
+
+// WithMethods replaces the predicate selecting the gRPC methods the payment
+// header is attached to, IsBlocksMethod by default
+func (i *PaymentInterceptor) WithMethods(methods func(method string) bool) *PaymentInterceptor {
+	i.methods = methods
+	return i
+}
+
+// UnaryClientInterceptor attaches the payment header to the selected unary calls
+func (i *PaymentInterceptor) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, err := i.attach(ctx, method)
+		if err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor attaches the payment header to the selected streams,
+// Blocks() by default
+func (i *PaymentInterceptor) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, err := i.attach(ctx, method)
+		if err != nil {
+			return nil, err
+		}
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+// attach adds the payment header to the outgoing metadata of ctx when method
+// is paid for, unless the caller already set one
+func (i *PaymentInterceptor) attach(ctx context.Context, method string) (context.Context, error) {
+	if !i.methods(method) {
+		return ctx, nil
+	}
+	if md, found := metadata.FromOutgoingContext(ctx); found && len(md.Get(sidecar.PaymentHeader)) > 0 {
+		return ctx, nil
+	}
+
+	rav, err := i.PaymentRAV(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := sidecar.EncodePaymentHeader(rav)
+	if err != nil {
+		return nil, err
+	}
+	return metadata.AppendToOutgoingContext(ctx, sidecar.PaymentHeader, header), nil
+}
+
+// PaymentRAV returns the RAV to present to the provider, initializing the
+// payment session with the consumer sidecar when not done yet
+func (i *PaymentInterceptor) PaymentRAV(ctx context.Context) (*commonv1.SignedRAV, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.rav != nil {
+		return i.rav, nil
+	}
+
+	resp, err := i.sidecar.Init(ctx, connect.NewRequest(i.init))
+	if err != nil {
+		return nil, fmt.Errorf("initializing payment session with consumer sidecar: %w", err)
+	}
+	if resp.Msg.PaymentRav == nil || resp.Msg.PaymentRav.Rav == nil {
+		return nil, fmt.Errorf("initializing payment session with consumer sidecar: no payment RAV returned")
+	}
+
+	if resp.Msg.Session != nil {
+		i.sessionID = resp.Msg.Session.SessionId
+	}
+	i.rav = resp.Msg.PaymentRav
+
+	i.logger.Info("payment session initialized",
+		zap.String("session_id", i.sessionID),
+		zap.Stringer("rav", sidecar.ProtoSignedRAVToHorizon(i.rav)),
+	)
+	return i.rav, nil
+}
+
+// SessionID returns the ID of the payment session, empty until initialized
+func (i *PaymentInterceptor) SessionID() string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.sessionID
+}
+
+// ReportUsage reports usage received from the provider to the consumer sidecar
+// and keeps the RAV it returns as the payment RAV of the next requests
+func (i *PaymentInterceptor) ReportUsage(ctx context.Context, usage *commonv1.Usage) (*consumerv1.ReportUsageResponse, error) {
+	sessionID := i.SessionID()
+	if sessionID == "" {
+		return nil, fmt.Errorf("reporting usage: payment session not initialized")
+	}
+
+	resp, err := i.sidecar.ReportUsage(ctx, connect.NewRequest(&consumerv1.ReportUsageRequest{SessionId: sessionID, Usage: usage}))
+	if err != nil {
+		return nil, fmt.Errorf("reporting usage to consumer sidecar: %w", err)
+	}
+
+	if resp.Msg.UpdatedRav != nil && resp.Msg.UpdatedRav.Rav != nil {
+		i.mu.Lock()
+		i.rav = resp.Msg.UpdatedRav
+		i.mu.Unlock()
+	}
+	return resp.Msg, nil
+}
+
+// EndSession ends the payment session with the consumer sidecar, the next paid
+// request initializes a new one
+func (i *PaymentInterceptor) EndSession(ctx context.Context, finalUsage *commonv1.Usage) (*consumerv1.EndSessionResponse, error) {
+	sessionID := i.SessionID()
+	if sessionID == "" {
+		return nil, fmt.Errorf("ending session: payment session not initialized")
+	}
+
+	resp, err := i.sidecar.EndSession(ctx, connect.NewRequest(&consumerv1.EndSessionRequest{SessionId: sessionID, FinalUsage: finalUsage}))
+	if err != nil {
+		return nil, fmt.Errorf("ending session with consumer sidecar: %w", err)
+	}
+
+	i.mu.Lock()
+	i.sessionID = ""
+	i.rav = nil
+	i.mu.Unlock()
+	return resp.Msg, nil
+}
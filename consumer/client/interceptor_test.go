@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	consumerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/consumer/v1"
+	"github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/consumer/v1/consumerv1connect"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type fakeConsumerSidecar struct {
+	consumerv1connect.UnimplementedConsumerSidecarServiceHandler
+
+	inits int
+}
+
+func fakeRAV(value int64) *commonv1.SignedRAV {
+	return &commonv1.SignedRAV{
+		Rav: &commonv1.RAV{
+			Payer:           commonv1.AddressFromEth(eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+			DataService:     commonv1.AddressFromEth(eth.MustNewAddress("0x2222222222222222222222222222222222222222")),
+			ServiceProvider: commonv1.AddressFromEth(eth.MustNewAddress("0x3333333333333333333333333333333333333333")),
+			ValueAggregate:  commonv1.BigIntFromNative(big.NewInt(value)),
+		},
+		Signature: make([]byte, 65),
+	}
+}
+
+func (f *fakeConsumerSidecar) Init(ctx context.Context, req *connect.Request[consumerv1.InitRequest]) (*connect.Response[consumerv1.InitResponse], error) {
+	f.inits++
+	return connect.NewResponse(&consumerv1.InitResponse{
+		Session:    &commonv1.SessionInfo{SessionId: "session-1"},
+		PaymentRav: fakeRAV(0),
+	}), nil
+}
+
+func (f *fakeConsumerSidecar) ReportUsage(ctx context.Context, req *connect.Request[consumerv1.ReportUsageRequest]) (*connect.Response[consumerv1.ReportUsageResponse], error) {
+	return connect.NewResponse(&consumerv1.ReportUsageResponse{
+		UpdatedRav:     fakeRAV(req.Msg.Usage.Cost.ToNative().Int64()),
+		ShouldContinue: true,
+	}), nil
+}
+
+func TestPaymentInterceptor(t *testing.T) {
+	fake := &fakeConsumerSidecar{}
+	server := httptest.NewServer(http.NewServeMux())
+	defer server.Close()
+	path, handler := consumerv1connect.NewConsumerSidecarServiceHandler(fake)
+	server.Config.Handler.(*http.ServeMux).Handle(path, handler)
+
+	interceptor := NewPaymentInterceptor(consumerv1connect.NewConsumerSidecarServiceClient(http.DefaultClient, server.URL), &consumerv1.InitRequest{ProviderEndpoint: "localhost:9000"}, zap.NewNop())
+	stream := interceptor.StreamClientInterceptor()
+
+	sentRAV := func(method string) *commonv1.SignedRAV {
+		var headers []string
+		_, err := stream(context.Background(), &grpc.StreamDesc{ServerStreams: true}, nil, method, func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			headers = md.Get(sidecar.PaymentHeader)
+			return nil, nil
+		})
+		require.NoError(t, err)
+		if len(headers) == 0 {
+			return nil
+		}
+
+		require.Len(t, headers, 1)
+		rav, err := sidecar.DecodePaymentHeader(headers[0])
+		require.NoError(t, err)
+		return rav
+	}
+
+	// Other methods are not paid for
+	assert.Nil(t, sentRAV("/sf.substreams.rpc.v2.EndpointInfo/Info"))
+	assert.Equal(t, 0, fake.inits)
+
+	rav := sentRAV("/sf.substreams.rpc.v2.Stream/Blocks")
+	require.NotNil(t, rav)
+	assert.Equal(t, int64(0), rav.Rav.ValueAggregate.ToNative().Int64())
+	assert.Equal(t, "session-1", interceptor.SessionID())
+
+	// Reconnections present the latest RAV of the same session
+	_, err := interceptor.ReportUsage(context.Background(), &commonv1.Usage{Cost: commonv1.BigIntFromNative(big.NewInt(500))})
+	require.NoError(t, err)
+
+	rav = sentRAV("/sf.substreams.rpc.v3.Stream/Blocks")
+	require.NotNil(t, rav)
+	assert.Equal(t, int64(500), rav.Rav.ValueAggregate.ToNative().Int64())
+	assert.Equal(t, 1, fake.inits)
+}
@@ -0,0 +1,447 @@
+package sidecar
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"go.uber.org/zap"
+)
+
+// BudgetResponse is the JSON representation of BudgetStatus served by the admin
+// server, GRT values are decimal wei strings and an empty limit means unlimited
+type BudgetResponse struct {
+	GlobalLimit  string                    `json:"global_limit,omitempty"`
+	GlobalSpent  string                    `json:"global_spent"`
+	Providers    []*ProviderBudgetResponse `json:"providers"`
+	Frozen       bool                      `json:"frozen"`
+	FrozenReason string                    `json:"frozen_reason,omitempty"`
+	FrozenAt     *time.Time                `json:"frozen_at,omitempty"`
+}
+
+// ProviderBudgetResponse is the JSON representation of ProviderBudgetStatus
+type ProviderBudgetResponse struct {
+	ServiceProvider string `json:"service_provider"`
+	Limit           string `json:"limit,omitempty"`
+	Spent           string `json:"spent"`
+}
+
+// BudgetLimitRequest sets a budget limit in wei, a null limit removes it
+type BudgetLimitRequest struct {
+	Limit *string `json:"limit"`
+}
+
+// FreezeRequest freezes all RAV signing, the reason is reported to refused requests
+type FreezeRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// ProviderRecordResponse is the JSON representation of ProviderRecord, prices
+// are decimal GRT strings and are omitted when no price book was recorded
+type ProviderRecordResponse struct {
+	ServiceProvider   string     `json:"service_provider"`
+	PricePerBlock     string     `json:"price_per_block,omitempty"`
+	PricePerByte      string     `json:"price_per_byte,omitempty"`
+	PricingUpdatedAt  *time.Time `json:"pricing_updated_at,omitempty"`
+	SessionsCompleted uint64     `json:"sessions_completed"`
+	SessionsFailed    uint64     `json:"sessions_failed"`
+	Disputes          uint64     `json:"disputes"`
+	Score             *float64   `json:"score,omitempty"`
+}
+
+// ProviderPricingRequest records the price parameters negotiated with a
+// service provider, as decimal GRT strings
+type ProviderPricingRequest struct {
+	PricePerBlock string `json:"price_per_block"`
+	PricePerByte  string `json:"price_per_byte"`
+}
+
+// ScoreProvidersRequest lists the candidate service providers to rank
+type ScoreProvidersRequest struct {
+	ServiceProviders []string `json:"service_providers"`
+}
+
+// BlacklistRequest blacklists a service provider, the reason is reported to
+// refused sessions
+type BlacklistRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// BlacklistEntryResponse is the JSON representation of BlacklistEntry
+type BlacklistEntryResponse struct {
+	ServiceProvider string    `json:"service_provider"`
+	Reason          string    `json:"reason,omitempty"`
+	BlacklistedAt   time.Time `json:"blacklisted_at"`
+	Automatic       bool      `json:"automatic"`
+}
+
+// UsageCheckResponse is the JSON representation of UsageCheck
+type UsageCheckResponse struct {
+	SessionID       string       `json:"session_id"`
+	ServiceProvider string       `json:"service_provider"`
+	Claimed         MeteredUsage `json:"claimed"`
+	Observed        MeteredUsage `json:"observed"`
+	Divergence      float64      `json:"divergence"`
+	Disputed        bool         `json:"disputed"`
+	Divergences     int          `json:"divergences"`
+	Blacklisted     bool         `json:"blacklisted"`
+}
+
+// adminHandlers registers the budget endpoints on the admin server:
+//   - GET /v1/budget: current budgets, spend and freeze state
+//   - PUT /v1/budget/global: sets (or with a null limit removes) the global budget
+//   - PUT /v1/budget/providers/{address}: sets (or removes) a service provider budget
+//   - POST /v1/budget/freeze and POST /v1/budget/unfreeze: stop and resume all signing
+//
+// Every budget endpoint answers with the resulting budget status.
+// GET /v1/spend/categories reports the usage and cost per usage category and
+// GET /v1/spend/settlements the settlements of the ended sessions, both
+// optionally of one service_provider. GET /v1/escrow/balances reads the
+// PaymentsEscrow balance of every escrow account the sessions are paid from,
+// one per (payer, collector, receiver).
+//
+// And the price book endpoints:
+//   - GET /v1/providers: price books and track record of every service provider
+//   - PUT /v1/providers/{address}/pricing: records negotiated price parameters
+//   - POST /v1/providers/{address}/failures: records a failed session
+//   - POST /v1/providers/{address}/disputes: records a dispute
+//   - POST /v1/providers/score: ranks candidate service providers best first
+//
+// And the blacklist endpoints:
+//   - POST /v1/sessions/{id}/observed-usage: compares the usage observed by the
+//     gateway with the usage the provider claimed, disputing diverging sessions
+//   - GET /v1/providers/blacklist: blacklisted service providers
+//   - PUT /v1/providers/{address}/blacklist: blacklists a service provider
+//   - DELETE /v1/providers/{address}/blacklist: clears a blacklisted service
+//     provider along with its divergences
+func (s *Sidecar) adminHandlers(admin *sidecar.AdminServer) {
+	admin.Handle("GET /v1/budget", http.HandlerFunc(s.handleAdminGetBudget))
+	admin.Handle("PUT /v1/budget/global", http.HandlerFunc(s.handleAdminSetGlobalBudget))
+	admin.Handle("PUT /v1/budget/providers/{address}", http.HandlerFunc(s.handleAdminSetProviderBudget))
+	admin.Handle("POST /v1/budget/freeze", http.HandlerFunc(s.handleAdminFreeze))
+	admin.Handle("POST /v1/budget/unfreeze", http.HandlerFunc(s.handleAdminUnfreeze))
+	admin.Handle("GET /v1/spend/categories", http.HandlerFunc(s.handleAdminGetCategorySpend))
+	admin.Handle("GET /v1/spend/settlements", http.HandlerFunc(s.handleAdminGetSettlements))
+	admin.Handle("GET /v1/escrow/balances", http.HandlerFunc(s.handleAdminGetEscrowBalances))
+
+	admin.Handle("GET /v1/providers", http.HandlerFunc(s.handleAdminListProviders))
+	admin.Handle("PUT /v1/providers/{address}/pricing", http.HandlerFunc(s.handleAdminSetProviderPricing))
+	admin.Handle("POST /v1/providers/{address}/failures", http.HandlerFunc(s.handleAdminRecordProviderFailure))
+	admin.Handle("POST /v1/providers/{address}/disputes", http.HandlerFunc(s.handleAdminRecordProviderDispute))
+	admin.Handle("POST /v1/providers/score", http.HandlerFunc(s.handleAdminScoreProviders))
+
+	admin.Handle("POST /v1/sessions/{id}/observed-usage", http.HandlerFunc(s.handleAdminCheckObservedUsage))
+	admin.Handle("GET /v1/providers/blacklist", http.HandlerFunc(s.handleAdminListBlacklist))
+	admin.Handle("PUT /v1/providers/{address}/blacklist", http.HandlerFunc(s.handleAdminBlacklistProvider))
+	admin.Handle("DELETE /v1/providers/{address}/blacklist", http.HandlerFunc(s.handleAdminClearBlacklistedProvider))
+}
+
+func (s *Sidecar) handleAdminGetBudget(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, newBudgetResponse(s.BudgetStatus()))
+}
+
+func (s *Sidecar) handleAdminSetGlobalBudget(w http.ResponseWriter, r *http.Request) {
+	limit, err := decodeBudgetLimit(r)
+	if err != nil {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.SetGlobalBudget(limit)
+	s.logger.Info("global budget updated", zap.Stringer("limit", limit))
+	s.writeJSON(w, http.StatusOK, newBudgetResponse(s.BudgetStatus()))
+}
+
+func (s *Sidecar) handleAdminSetProviderBudget(w http.ResponseWriter, r *http.Request) {
+	serviceProvider, err := eth.NewAddress(r.PathValue("address"))
+	if err != nil {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid service provider address: %s", err)})
+		return
+	}
+
+	limit, err := decodeBudgetLimit(r)
+	if err != nil {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.SetProviderBudget(serviceProvider, limit)
+	s.logger.Info("service provider budget updated", zap.Stringer("service_provider", serviceProvider), zap.Stringer("limit", limit))
+	s.writeJSON(w, http.StatusOK, newBudgetResponse(s.BudgetStatus()))
+}
+
+func (s *Sidecar) handleAdminFreeze(w http.ResponseWriter, r *http.Request) {
+	var req FreezeRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body: " + err.Error()})
+			return
+		}
+	}
+
+	s.FreezeSigning(req.Reason)
+	s.logger.Warn("RAV signing frozen", zap.String("reason", req.Reason))
+	s.writeJSON(w, http.StatusOK, newBudgetResponse(s.BudgetStatus()))
+}
+
+func (s *Sidecar) handleAdminUnfreeze(w http.ResponseWriter, r *http.Request) {
+	s.UnfreezeSigning()
+	s.logger.Info("RAV signing unfrozen")
+	s.writeJSON(w, http.StatusOK, newBudgetResponse(s.BudgetStatus()))
+}
+
+func (s *Sidecar) handleAdminListProviders(w http.ResponseWriter, r *http.Request) {
+	records := s.ProviderRecords()
+
+	out := make([]*ProviderRecordResponse, 0, len(records))
+	for _, record := range records {
+		out = append(out, newProviderRecordResponse(record, nil))
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"providers": out})
+}
+
+func (s *Sidecar) handleAdminSetProviderPricing(w http.ResponseWriter, r *http.Request) {
+	serviceProvider, ok := s.adminProviderAddress(w, r)
+	if !ok {
+		return
+	}
+
+	var req ProviderPricingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body: " + err.Error()})
+		return
+	}
+
+	pricing, err := newPricingConfig(req.PricePerBlock, req.PricePerByte)
+	if err != nil {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.SetProviderPricing(serviceProvider, pricing)
+	s.logger.Info("service provider price book updated", zap.Stringer("service_provider", serviceProvider), zap.String("price_per_block", req.PricePerBlock), zap.String("price_per_byte", req.PricePerByte))
+	s.writeJSON(w, http.StatusOK, newProviderRecordResponse(s.priceBooks.get(serviceProvider), nil))
+}
+
+func (s *Sidecar) handleAdminRecordProviderFailure(w http.ResponseWriter, r *http.Request) {
+	serviceProvider, ok := s.adminProviderAddress(w, r)
+	if !ok {
+		return
+	}
+
+	s.RecordProviderFailure(serviceProvider)
+	s.writeJSON(w, http.StatusOK, newProviderRecordResponse(s.priceBooks.get(serviceProvider), nil))
+}
+
+func (s *Sidecar) handleAdminRecordProviderDispute(w http.ResponseWriter, r *http.Request) {
+	serviceProvider, ok := s.adminProviderAddress(w, r)
+	if !ok {
+		return
+	}
+
+	s.RecordProviderDispute(serviceProvider)
+	s.logger.Info("dispute recorded against service provider", zap.Stringer("service_provider", serviceProvider))
+	s.writeJSON(w, http.StatusOK, newProviderRecordResponse(s.priceBooks.get(serviceProvider), nil))
+}
+
+func (s *Sidecar) handleAdminScoreProviders(w http.ResponseWriter, r *http.Request) {
+	var req ScoreProvidersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body: " + err.Error()})
+		return
+	}
+
+	candidates := make([]eth.Address, 0, len(req.ServiceProviders))
+	for _, addressHex := range req.ServiceProviders {
+		address, err := eth.NewAddress(addressHex)
+		if err != nil {
+			s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid service provider address %q: %s", addressHex, err)})
+			return
+		}
+		candidates = append(candidates, address)
+	}
+
+	scores := s.ScoreProviders(candidates)
+	out := make([]*ProviderRecordResponse, 0, len(scores))
+	for _, score := range scores {
+		out = append(out, newProviderRecordResponse(score.Record, &score.Score))
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"providers": out})
+}
+
+func (s *Sidecar) handleAdminCheckObservedUsage(w http.ResponseWriter, r *http.Request) {
+	var observed MeteredUsage
+	if err := json.NewDecoder(r.Body).Decode(&observed); err != nil {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body: " + err.Error()})
+		return
+	}
+
+	check, err := s.CheckObservedUsage(r.PathValue("id"), observed)
+	if err != nil {
+		s.writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, &UsageCheckResponse{
+		SessionID:       check.SessionID,
+		ServiceProvider: check.ServiceProvider.Pretty(),
+		Claimed:         check.Claimed,
+		Observed:        check.Observed,
+		Divergence:      check.Divergence,
+		Disputed:        check.Disputed,
+		Divergences:     check.Divergences,
+		Blacklisted:     check.Blacklisted,
+	})
+}
+
+func (s *Sidecar) handleAdminListBlacklist(w http.ResponseWriter, r *http.Request) {
+	entries := s.BlacklistedProviders()
+
+	out := make([]*BlacklistEntryResponse, 0, len(entries))
+	for _, entry := range entries {
+		out = append(out, newBlacklistEntryResponse(entry))
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"blacklisted": out})
+}
+
+func (s *Sidecar) handleAdminBlacklistProvider(w http.ResponseWriter, r *http.Request) {
+	serviceProvider, ok := s.adminProviderAddress(w, r)
+	if !ok {
+		return
+	}
+
+	var req BlacklistRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body: " + err.Error()})
+			return
+		}
+	}
+
+	entry := s.BlacklistProvider(serviceProvider, req.Reason)
+	s.logger.Warn("service provider blacklisted", zap.Stringer("service_provider", serviceProvider), zap.String("reason", req.Reason))
+	s.writeJSON(w, http.StatusOK, newBlacklistEntryResponse(entry))
+}
+
+func (s *Sidecar) handleAdminClearBlacklistedProvider(w http.ResponseWriter, r *http.Request) {
+	serviceProvider, ok := s.adminProviderAddress(w, r)
+	if !ok {
+		return
+	}
+
+	if !s.ClearBlacklistedProvider(serviceProvider) {
+		s.writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("service provider %s is not blacklisted", serviceProvider.Pretty())})
+		return
+	}
+
+	s.logger.Info("blacklisted service provider cleared", zap.Stringer("service_provider", serviceProvider))
+	s.writeJSON(w, http.StatusOK, map[string]string{"cleared": serviceProvider.Pretty()})
+}
+
+func (s *Sidecar) adminProviderAddress(w http.ResponseWriter, r *http.Request) (eth.Address, bool) {
+	serviceProvider, err := eth.NewAddress(r.PathValue("address"))
+	if err != nil {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid service provider address: %s", err)})
+		return nil, false
+	}
+	return serviceProvider, true
+}
+
+func (s *Sidecar) writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		s.logger.Warn("failed to write JSON response", zap.Error(err))
+	}
+}
+
+func decodeBudgetLimit(r *http.Request) (*big.Int, error) {
+	var req BudgetLimitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, fmt.Errorf("invalid request body: %w", err)
+	}
+	if req.Limit == nil {
+		return nil, nil
+	}
+
+	limit, ok := new(big.Int).SetString(*req.Limit, 10)
+	if !ok || limit.Sign() < 0 {
+		return nil, fmt.Errorf("invalid limit %q, expected a non-negative wei amount", *req.Limit)
+	}
+	return limit, nil
+}
+
+func newPricingConfig(pricePerBlock, pricePerByte string) (*sidecar.PricingConfig, error) {
+	blockPrice, err := sidecar.NewPriceFromDecimal(pricePerBlock)
+	if err != nil {
+		return nil, fmt.Errorf("invalid price_per_block: %w", err)
+	}
+	bytePrice, err := sidecar.NewPriceFromDecimal(pricePerByte)
+	if err != nil {
+		return nil, fmt.Errorf("invalid price_per_byte: %w", err)
+	}
+
+	return &sidecar.PricingConfig{
+		PricePerBlock:    blockPrice,
+		PricePerByte:     bytePrice,
+		PricePerBlockStr: pricePerBlock,
+		PricePerByteStr:  pricePerByte,
+	}, nil
+}
+
+func newProviderRecordResponse(record *ProviderRecord, score *float64) *ProviderRecordResponse {
+	out := &ProviderRecordResponse{
+		ServiceProvider:   record.ServiceProvider.Pretty(),
+		SessionsCompleted: record.SessionsCompleted,
+		SessionsFailed:    record.SessionsFailed,
+		Disputes:          record.Disputes,
+		Score:             score,
+	}
+	if record.Pricing != nil {
+		out.PricePerBlock = record.Pricing.PricePerBlock.ToDecimalString()
+		out.PricePerByte = record.Pricing.PricePerByte.ToDecimalString()
+		out.PricingUpdatedAt = &record.PricingUpdatedAt
+	}
+	return out
+}
+
+func newBlacklistEntryResponse(entry *BlacklistEntry) *BlacklistEntryResponse {
+	return &BlacklistEntryResponse{
+		ServiceProvider: entry.ServiceProvider.Pretty(),
+		Reason:          entry.Reason,
+		BlacklistedAt:   entry.BlacklistedAt,
+		Automatic:       entry.Automatic,
+	}
+}
+
+func newBudgetResponse(status *BudgetStatus) *BudgetResponse {
+	out := &BudgetResponse{
+		GlobalSpent:  status.GlobalSpent.String(),
+		Providers:    make([]*ProviderBudgetResponse, 0, len(status.Providers)),
+		Frozen:       status.Frozen,
+		FrozenReason: status.FrozenReason,
+	}
+	if status.GlobalLimit != nil {
+		out.GlobalLimit = status.GlobalLimit.String()
+	}
+	if status.Frozen {
+		out.FrozenAt = &status.FrozenAt
+	}
+
+	for _, provider := range status.Providers {
+		entry := &ProviderBudgetResponse{
+			ServiceProvider: provider.ServiceProvider.Pretty(),
+			Spent:           provider.Spent.String(),
+		}
+		if provider.Limit != nil {
+			entry.Limit = provider.Limit.String()
+		}
+		out.Providers = append(out.Providers, entry)
+	}
+
+	return out
+}
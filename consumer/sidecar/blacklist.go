@@ -0,0 +1,288 @@
+package sidecar
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"go.uber.org/zap"
+)
+
+// DefaultUsageDivergenceTolerance is the relative difference between the usage
+// claimed by a provider and the usage observed by the consumer above which a
+// session is disputed
+const DefaultUsageDivergenceTolerance = 0.05
+
+// ErrProviderBlacklisted is returned by Init for sessions toward a blacklisted
+// service provider
+var ErrProviderBlacklisted = errors.New("service provider is blacklisted")
+
+// BlacklistEntry is a blacklisted service provider
+type BlacklistEntry struct {
+	ServiceProvider eth.Address
+	Reason          string
+	BlacklistedAt   time.Time
+	// Automatic is set when the service provider was blacklisted for its
+	// divergences rather than by an operator
+	Automatic bool
+}
+
+// MeteredUsage is the usage of a session as metered by one side, the provider
+// claiming it or the consumer observing it
+type MeteredUsage struct {
+	BlocksProcessed  uint64 `json:"blocks_processed"`
+	BytesTransferred uint64 `json:"bytes_transferred"`
+}
+
+// UsageCheck is the comparison of the usage a provider claimed for a session
+// with the usage the consumer observed
+type UsageCheck struct {
+	SessionID       string
+	ServiceProvider eth.Address
+	Claimed         MeteredUsage
+	Observed        MeteredUsage
+	// Divergence is the largest relative difference between claimed and
+	// observed blocks or bytes
+	Divergence float64
+	// Disputed is set when Divergence exceeds the tolerance, a dispute is then
+	// recorded against the service provider, once per session
+	Disputed bool
+	// Divergences counts the disputed sessions of the service provider since
+	// it was last cleared
+	Divergences int
+	// Blacklisted is set when the service provider is blacklisted
+	Blacklisted bool
+}
+
+// providerBlacklist holds the blacklisted service providers, counts the
+// disputed sessions of the others and keeps the last usage check of sessions
+type providerBlacklist struct {
+	tolerance float64
+	// maxDivergences is the number of disputed sessions after which a service
+	// provider is blacklisted automatically, only operators blacklist when zero
+	maxDivergences int
+
+	mu          sync.Mutex
+	entries     map[string]*BlacklistEntry
+	divergences map[string]int
+	disputed    map[string]bool        // session ID -> already counted
+	checks      map[string]*UsageCheck // session ID -> last usage check
+}
+
+func newProviderBlacklist(tolerance float64, maxDivergences int) *providerBlacklist {
+	if tolerance <= 0 {
+		tolerance = DefaultUsageDivergenceTolerance
+	}
+
+	return &providerBlacklist{
+		tolerance:      tolerance,
+		maxDivergences: maxDivergences,
+		entries:        make(map[string]*BlacklistEntry),
+		divergences:    make(map[string]int),
+		disputed:       make(map[string]bool),
+		checks:         make(map[string]*UsageCheck),
+	}
+}
+
+// check returns the entry of serviceProvider, nil when not blacklisted
+func (b *providerBlacklist) check(serviceProvider eth.Address) *BlacklistEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entry, found := b.entries[serviceProvider.Pretty()]
+	if !found {
+		return nil
+	}
+	copied := *entry
+	return &copied
+}
+
+// add blacklists serviceProvider, keeping the entry it may already have
+func (b *providerBlacklist) add(serviceProvider eth.Address, reason string, automatic bool, now time.Time) *BlacklistEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entry, found := b.entries[serviceProvider.Pretty()]
+	if !found {
+		entry = &BlacklistEntry{ServiceProvider: serviceProvider, Reason: reason, BlacklistedAt: now, Automatic: automatic}
+		b.entries[serviceProvider.Pretty()] = entry
+	}
+	copied := *entry
+	return &copied
+}
+
+// clear removes serviceProvider from the blacklist and forgets its
+// divergences, false when it was not blacklisted
+func (b *providerBlacklist) clear(serviceProvider eth.Address) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, found := b.entries[serviceProvider.Pretty()]
+	delete(b.entries, serviceProvider.Pretty())
+	delete(b.divergences, serviceProvider.Pretty())
+	return found
+}
+
+// list returns a copy of every entry, ordered by service provider address
+func (b *providerBlacklist) list() []*BlacklistEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := make([]*BlacklistEntry, 0, len(b.entries))
+	for _, entry := range b.entries {
+		copied := *entry
+		out = append(out, &copied)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ServiceProvider.Pretty() < out[j].ServiceProvider.Pretty()
+	})
+	return out
+}
+
+// recordCheck keeps check as the last usage check of its session
+func (b *providerBlacklist) recordCheck(check *UsageCheck) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	copied := *check
+	b.checks[check.SessionID] = &copied
+}
+
+// lastCheck returns the last usage check of sessionID, nil when its usage was
+// never checked
+func (b *providerBlacklist) lastCheck(sessionID string) *UsageCheck {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	check, found := b.checks[sessionID]
+	if !found {
+		return nil
+	}
+	copied := *check
+	return &copied
+}
+
+// recordDivergence counts the disputed session sessionID of serviceProvider
+// and, when automatic blacklisting is enabled, blacklists the provider once it
+// reached the maximum divergences. It returns the divergences counted, whether the provider
+// is blacklisted and whether the session was not counted before.
+func (b *providerBlacklist) recordDivergence(sessionID string, serviceProvider eth.Address, now time.Time) (int, bool, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := serviceProvider.Pretty()
+	counted := !b.disputed[sessionID]
+	if counted {
+		b.disputed[sessionID] = true
+		b.divergences[key]++
+	}
+	count := b.divergences[key]
+
+	if _, found := b.entries[key]; found {
+		return count, true, counted
+	}
+	if b.maxDivergences <= 0 || count < b.maxDivergences {
+		return count, false, counted
+	}
+
+	b.entries[key] = &BlacklistEntry{
+		ServiceProvider: serviceProvider,
+		Reason:          fmt.Sprintf("claimed usage diverged from observed usage in %d sessions", count),
+		BlacklistedAt:   now,
+		Automatic:       true,
+	}
+	return count, true, counted
+}
+
+// usageDivergence returns the largest relative difference between claimed and
+// observed blocks or bytes, relative to the observed usage
+func usageDivergence(claimed, observed MeteredUsage) float64 {
+	relative := func(claimed, observed uint64) float64 {
+		diff := math.Abs(float64(claimed) - float64(observed))
+		if diff == 0 {
+			return 0
+		}
+		return diff / math.Max(float64(observed), 1)
+	}
+	return math.Max(relative(claimed.BlocksProcessed, observed.BlocksProcessed), relative(claimed.BytesTransferred, observed.BytesTransferred))
+}
+
+// CheckObservedUsage compares the usage the provider of session sessionID
+// claimed, as reported through ReportUsage and EndSession, with the usage the
+// consumer observed. A session diverging beyond the tolerance is recorded as a
+// dispute against the service provider, which is blacklisted after repeated
+// disputes when Config.BlacklistAfterDivergences is set. The settlement of a
+// session checked once ended acknowledges the observed usage.
+func (s *Sidecar) CheckObservedUsage(sessionID string, observed MeteredUsage) (*UsageCheck, error) {
+	session, err := s.sessions.Get(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	check := s.checkObservedUsage(session, observed)
+	s.blacklist.recordCheck(check)
+
+	if settlement := session.GetConsumerSettlement(); settlement != nil {
+		updated := *settlement
+		applyUsageCheck(&updated, check)
+		session.SetConsumerSettlement(&updated)
+		s.persistSession(session)
+	}
+	return check, nil
+}
+
+// checkObservedUsage compares the usage claimed for session with observed,
+// recording a dispute the first time it diverges beyond the tolerance
+func (s *Sidecar) checkObservedUsage(session *sidecar.Session, observed MeteredUsage) *UsageCheck {
+	claimedUsage := session.GetUsage()
+	check := &UsageCheck{
+		SessionID:       session.ID,
+		ServiceProvider: session.Receiver,
+		Claimed:         MeteredUsage{BlocksProcessed: claimedUsage.BlocksProcessed, BytesTransferred: claimedUsage.BytesTransferred},
+		Observed:        observed,
+	}
+	check.Divergence = usageDivergence(check.Claimed, observed)
+
+	if check.Divergence <= s.blacklist.tolerance {
+		check.Blacklisted = s.blacklist.check(session.Receiver) != nil
+		return check
+	}
+
+	check.Disputed = true
+	var counted bool
+	check.Divergences, check.Blacklisted, counted = s.blacklist.recordDivergence(session.ID, session.Receiver, time.Now())
+	if !counted {
+		return check // Checked before, the dispute is already recorded
+	}
+	s.RecordProviderDispute(session.Receiver)
+
+	s.logger.Warn("provider claimed usage diverges from observed usage",
+		zap.String("session_id", session.ID),
+		zap.Stringer("service_provider", session.Receiver),
+		zap.Float64("divergence", check.Divergence),
+		zap.Int("divergences", check.Divergences),
+		zap.Bool("blacklisted", check.Blacklisted),
+	)
+	return check
+}
+
+// BlacklistProvider refuses new sessions toward serviceProvider until cleared
+func (s *Sidecar) BlacklistProvider(serviceProvider eth.Address, reason string) *BlacklistEntry {
+	return s.blacklist.add(serviceProvider, reason, false, time.Now())
+}
+
+// ClearBlacklistedProvider accepts new sessions toward serviceProvider again and
+// forgets its divergences, false when it was not blacklisted
+func (s *Sidecar) ClearBlacklistedProvider(serviceProvider eth.Address) bool {
+	return s.blacklist.clear(serviceProvider)
+}
+
+// BlacklistedProviders returns the blacklisted service providers
+func (s *Sidecar) BlacklistedProviders() []*BlacklistEntry {
+	return s.blacklist.list()
+}
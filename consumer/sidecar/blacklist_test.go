@@ -0,0 +1,126 @@
+package sidecar
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	consumerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/consumer/v1"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlacklist_DivergingUsage(t *testing.T) {
+	provider := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+
+	s := newBudgetTestSidecar(t, nil)
+	s.blacklist = newProviderBlacklist(0.1, 2)
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.admin.Handler().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	sessionWithClaimedUsage := func(blocks uint64) string {
+		sessionID := initBudgetTestSession(t, s, provider)
+		_, err := s.ReportUsage(context.Background(), connect.NewRequest(&consumerv1.ReportUsageRequest{
+			SessionId: sessionID,
+			Usage:     &commonv1.Usage{BlocksProcessed: blocks, BytesTransferred: 1000, Cost: commonv1.BigIntFromNative(big.NewInt(10))},
+		}))
+		require.NoError(t, err)
+		return sessionID
+	}
+
+	observe := func(sessionID string) *UsageCheckResponse {
+		rec := serve(http.MethodPost, "/v1/sessions/"+sessionID+"/observed-usage", `{"blocks_processed":100,"bytes_transferred":1000}`)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var out UsageCheckResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+		return &out
+	}
+
+	// Within tolerance, nothing is disputed
+	check := observe(sessionWithClaimedUsage(105))
+	assert.False(t, check.Disputed)
+	assert.InDelta(t, 0.05, check.Divergence, 1e-9)
+
+	// Diverging sessions are disputed once each
+	diverging := sessionWithClaimedUsage(150)
+	check = observe(diverging)
+	assert.True(t, check.Disputed)
+	assert.Equal(t, 1, check.Divergences)
+	assert.False(t, check.Blacklisted)
+	assert.Equal(t, 1, observe(diverging).Divergences)
+	assert.Equal(t, uint64(1), s.priceBooks.get(provider).Disputes)
+
+	check = observe(sessionWithClaimedUsage(200))
+	assert.Equal(t, 2, check.Divergences)
+	assert.True(t, check.Blacklisted)
+
+	// New sessions toward the provider are refused until cleared
+	_, err := s.Init(context.Background(), connect.NewRequest(&consumerv1.InitRequest{
+		EscrowAccount: &commonv1.EscrowAccount{
+			Payer:       commonv1.AddressFromEth(eth.MustNewAddress("0x2222222222222222222222222222222222222222")),
+			Receiver:    commonv1.AddressFromEth(provider),
+			DataService: commonv1.AddressFromEth(eth.MustNewAddress("0x3333333333333333333333333333333333333333")),
+		},
+	}))
+	require.Error(t, err)
+	assert.Equal(t, connect.CodePermissionDenied, connect.CodeOf(err))
+	assert.ErrorIs(t, err, ErrProviderBlacklisted)
+
+	rec := serve(http.MethodGet, "/v1/providers/blacklist", "")
+	var list struct {
+		Blacklisted []*BlacklistEntryResponse `json:"blacklisted"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+	require.Len(t, list.Blacklisted, 1)
+	assert.Equal(t, provider.Pretty(), list.Blacklisted[0].ServiceProvider)
+	assert.True(t, list.Blacklisted[0].Automatic)
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodDelete, "/v1/providers/"+provider.Pretty()+"/blacklist", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodDelete, "/v1/providers/"+provider.Pretty()+"/blacklist", "").Code)
+	initBudgetTestSession(t, s, provider)
+}
+
+func TestBlacklist_Manual(t *testing.T) {
+	provider := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+	s := newBudgetTestSidecar(t, nil)
+
+	rec := httptest.NewRecorder()
+	s.admin.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/v1/providers/"+provider.Pretty()+"/blacklist", strings.NewReader(`{"reason":"overbilling"}`)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var entry BlacklistEntryResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&entry))
+	assert.Equal(t, "overbilling", entry.Reason)
+	assert.False(t, entry.Automatic)
+
+	_, err := s.Init(context.Background(), connect.NewRequest(&consumerv1.InitRequest{
+		EscrowAccount: &commonv1.EscrowAccount{
+			Payer:       commonv1.AddressFromEth(eth.MustNewAddress("0x2222222222222222222222222222222222222222")),
+			Receiver:    commonv1.AddressFromEth(provider),
+			DataService: commonv1.AddressFromEth(eth.MustNewAddress("0x3333333333333333333333333333333333333333")),
+		},
+	}))
+	assert.ErrorContains(t, err, "overbilling")
+
+	// Without automatic blacklisting, diverging sessions are disputed only
+	s.ClearBlacklistedProvider(provider)
+	for range 5 {
+		sessionID := initBudgetTestSession(t, s, provider)
+		check, err := s.CheckObservedUsage(sessionID, MeteredUsage{BlocksProcessed: 100})
+		require.NoError(t, err)
+		assert.True(t, check.Disputed)
+		assert.False(t, check.Blacklisted)
+	}
+}
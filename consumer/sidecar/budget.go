@@ -0,0 +1,363 @@
+package sidecar
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	consumerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/consumer/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+)
+
+var (
+	// ErrSigningFrozen is returned for every signing request while signing is frozen
+	ErrSigningFrozen = errors.New("RAV signing is frozen")
+	// ErrBudgetExceeded is returned when signing a RAV would authorize more than a budget allows
+	ErrBudgetExceeded = errors.New("budget exceeded")
+)
+
+// budgets bounds the value the sidecar authorizes through signed RAVs, globally
+// and per service provider, and can freeze all signing during an incident. Spend
+// is the sum of the value aggregates of the latest RAV of every known session.
+type budgets struct {
+	mu           sync.RWMutex
+	global       *big.Int // nil when unlimited
+	perProvider  map[string]*big.Int
+	frozen       bool
+	frozenReason string
+	frozenAt     time.Time
+}
+
+func newBudgets(global *big.Int) *budgets {
+	return &budgets{
+		global:      global,
+		perProvider: make(map[string]*big.Int),
+	}
+}
+
+// checkFrozen returns ErrSigningFrozen while signing is frozen
+func (b *budgets) checkFrozen() error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.frozen {
+		if b.frozenReason != "" {
+			return fmt.Errorf("%w: %s", ErrSigningFrozen, b.frozenReason)
+		}
+		return ErrSigningFrozen
+	}
+	return nil
+}
+
+// check verifies that authorizing increase more for serviceProvider keeps
+// globalSpent and providerSpent within their budgets
+func (b *budgets) check(serviceProvider eth.Address, globalSpent, providerSpent, increase *big.Int) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.global != nil {
+		if next := new(big.Int).Add(globalSpent, increase); next.Cmp(b.global) > 0 {
+			return &BudgetViolation{Limit: consumerv1.BudgetLimit_BUDGET_LIMIT_GLOBAL, Max: b.global, Requested: next}
+		}
+	}
+
+	if limit, found := b.perProvider[serviceProvider.Pretty()]; found {
+		if next := new(big.Int).Add(providerSpent, increase); next.Cmp(limit) > 0 {
+			return &BudgetViolation{Limit: consumerv1.BudgetLimit_BUDGET_LIMIT_PROVIDER, Max: limit, Requested: next, Party: serviceProvider}
+		}
+	}
+	return nil
+}
+
+// SpendingLimits bounds the RAVs signed for each session and each payer, on
+// top of the global and per service provider budgets, so a misbehaving
+// provider cannot get ever larger RAVs signed. Nil limits are unlimited.
+type SpendingLimits struct {
+	// MaxSessionValue bounds the value aggregate of the RAVs of a session
+	MaxSessionValue *big.Int
+	// MaxPayerValue bounds the value authorized across the sessions of a payer
+	MaxPayerValue *big.Int
+	// MaxValuePerBlock bounds the value aggregate of a session divided by the
+	// blocks it processed, checked once blocks were reported
+	MaxValuePerBlock *big.Int
+	// MaxRAVDelta bounds the value a single RAV adds to the previous one
+	MaxRAVDelta *big.Int
+}
+
+// check verifies that raising the value aggregate of session from previous to
+// next, by increase, stays within the limits, payerSpent being the value
+// authorized across the sessions of its payer
+func (l *SpendingLimits) check(session *sidecar.Session, payerSpent, increase, next *big.Int) error {
+	if l.MaxRAVDelta != nil && increase.Cmp(l.MaxRAVDelta) > 0 {
+		return &BudgetViolation{Limit: consumerv1.BudgetLimit_BUDGET_LIMIT_RAV_DELTA, Max: l.MaxRAVDelta, Requested: increase}
+	}
+
+	if l.MaxSessionValue != nil && next.Cmp(l.MaxSessionValue) > 0 {
+		return &BudgetViolation{Limit: consumerv1.BudgetLimit_BUDGET_LIMIT_SESSION, Max: l.MaxSessionValue, Requested: next}
+	}
+
+	if l.MaxValuePerBlock != nil {
+		if blocks := session.GetUsage().BlocksProcessed; blocks > 0 {
+			divisor := new(big.Int).SetUint64(blocks)
+			if new(big.Int).Mul(l.MaxValuePerBlock, divisor).Cmp(next) < 0 {
+				// Rounded up so the requested value per block exceeds the limit
+				perBlock := new(big.Int).Add(next, divisor)
+				perBlock.Sub(perBlock, big.NewInt(1)).Div(perBlock, divisor)
+				return &BudgetViolation{Limit: consumerv1.BudgetLimit_BUDGET_LIMIT_VALUE_PER_BLOCK, Max: l.MaxValuePerBlock, Requested: perBlock}
+			}
+		}
+	}
+
+	if l.MaxPayerValue != nil {
+		if payerNext := new(big.Int).Add(payerSpent, increase); payerNext.Cmp(l.MaxPayerValue) > 0 {
+			return &BudgetViolation{Limit: consumerv1.BudgetLimit_BUDGET_LIMIT_PAYER, Max: l.MaxPayerValue, Requested: payerNext, Party: session.Payer}
+		}
+	}
+	return nil
+}
+
+// BudgetViolation is the ErrBudgetExceeded error of a RAV refused for
+// exceeding a budget or spending limit, it is returned to clients as a
+// consumerv1.BudgetViolation
+type BudgetViolation struct {
+	Limit consumerv1.BudgetLimit
+	// Max is the configured limit and Requested the value the RAV would have
+	// reached against it
+	Max       *big.Int
+	Requested *big.Int
+	// Party is the service provider or payer the limit applies to, if any
+	Party eth.Address
+}
+
+func (v *BudgetViolation) Error() string {
+	switch v.Limit {
+	case consumerv1.BudgetLimit_BUDGET_LIMIT_GLOBAL:
+		return fmt.Sprintf("%s: global budget %s would reach %s", ErrBudgetExceeded, v.Max, v.Requested)
+	case consumerv1.BudgetLimit_BUDGET_LIMIT_PROVIDER:
+		return fmt.Sprintf("%s: budget %s of service provider %s would reach %s", ErrBudgetExceeded, v.Max, v.Party.Pretty(), v.Requested)
+	case consumerv1.BudgetLimit_BUDGET_LIMIT_SESSION:
+		return fmt.Sprintf("%s: session limit %s would reach %s", ErrBudgetExceeded, v.Max, v.Requested)
+	case consumerv1.BudgetLimit_BUDGET_LIMIT_PAYER:
+		return fmt.Sprintf("%s: limit %s of payer %s would reach %s", ErrBudgetExceeded, v.Max, v.Party.Pretty(), v.Requested)
+	case consumerv1.BudgetLimit_BUDGET_LIMIT_VALUE_PER_BLOCK:
+		return fmt.Sprintf("%s: value per block limit %s would reach %s", ErrBudgetExceeded, v.Max, v.Requested)
+	case consumerv1.BudgetLimit_BUDGET_LIMIT_RAV_DELTA:
+		return fmt.Sprintf("%s: RAV delta limit %s would reach %s", ErrBudgetExceeded, v.Max, v.Requested)
+	}
+	return fmt.Sprintf("%s: limit %s would reach %s", ErrBudgetExceeded, v.Max, v.Requested)
+}
+
+func (v *BudgetViolation) Unwrap() error {
+	return ErrBudgetExceeded
+}
+
+// Proto returns the violation as sent to clients
+func (v *BudgetViolation) Proto() *consumerv1.BudgetViolation {
+	return &consumerv1.BudgetViolation{
+		Limit:     v.Limit,
+		Max:       commonv1.BigIntFromNative(v.Max),
+		Requested: commonv1.BigIntFromNative(v.Requested),
+	}
+}
+
+func (b *budgets) setGlobal(limit *big.Int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.global = limit
+}
+
+func (b *budgets) setProvider(serviceProvider eth.Address, limit *big.Int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if limit == nil {
+		delete(b.perProvider, serviceProvider.Pretty())
+		return
+	}
+	b.perProvider[serviceProvider.Pretty()] = limit
+}
+
+func (b *budgets) setFrozen(frozen bool, reason string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.frozen = frozen
+	b.frozenReason = ""
+	b.frozenAt = time.Time{}
+	if frozen {
+		b.frozenReason = reason
+		b.frozenAt = time.Now()
+	}
+}
+
+// BudgetStatus is a snapshot of the sidecar budgets and spend
+type BudgetStatus struct {
+	// GlobalLimit is the maximum value authorized across all sessions, nil when unlimited
+	GlobalLimit *big.Int
+	// GlobalSpent is the value authorized across all sessions
+	GlobalSpent *big.Int
+	// Providers holds the spend of every service provider with a session or a budget
+	Providers []*ProviderBudgetStatus
+
+	Frozen       bool
+	FrozenReason string
+	FrozenAt     time.Time
+}
+
+// ProviderBudgetStatus is the budget and spend of one service provider
+type ProviderBudgetStatus struct {
+	ServiceProvider eth.Address
+	// Limit is the maximum value authorized to the service provider, nil when unlimited
+	Limit *big.Int
+	Spent *big.Int
+}
+
+// spend returns the value authorized across sessions, globally, per service
+// provider and per payer, receipts signed in receipts payment mode included
+func spend(sessions []*sidecar.Session) (*big.Int, map[string]*big.Int, map[string]*big.Int) {
+	global := big.NewInt(0)
+	perProvider := make(map[string]*big.Int)
+	perPayer := make(map[string]*big.Int)
+	for _, session := range sessions {
+		rav := session.GetRAV()
+		if (rav == nil || rav.Message == nil || rav.Message.ValueAggregate == nil) && !session.PaysWithReceipts() {
+			continue
+		}
+
+		value := authorizedValue(session)
+		global.Add(global, value)
+		addSpend(perProvider, session.Receiver.Pretty(), value)
+		addSpend(perPayer, session.Payer.Pretty(), value)
+	}
+	return global, perProvider, perPayer
+}
+
+func addSpend(spent map[string]*big.Int, key string, value *big.Int) {
+	if spent[key] == nil {
+		spent[key] = big.NewInt(0)
+	}
+	spent[key].Add(spent[key], value)
+}
+
+// authorizeSpend verifies signing is not frozen and that raising the value
+// aggregate of session from previous to next stays within the budgets and
+// spending limits
+func (s *Sidecar) authorizeSpend(session *sidecar.Session, previous, next *big.Int) error {
+	if err := s.budgets.checkFrozen(); err != nil {
+		return err
+	}
+
+	increase := new(big.Int).Set(next)
+	if previous != nil {
+		increase.Sub(increase, previous)
+	}
+	if increase.Sign() <= 0 {
+		return nil
+	}
+
+	globalSpent, perProvider, perPayer := spend(s.sessions.List())
+	if err := s.spendingLimits.check(session, spentOf(perPayer, session.Payer), increase, next); err != nil {
+		return err
+	}
+	return s.budgets.check(session.Receiver, globalSpent, spentOf(perProvider, session.Receiver), increase)
+}
+
+func spentOf(spent map[string]*big.Int, address eth.Address) *big.Int {
+	if value := spent[address.Pretty()]; value != nil {
+		return value
+	}
+	return big.NewInt(0)
+}
+
+// BudgetStatus returns the current budgets along with the value authorized so far
+func (s *Sidecar) BudgetStatus() *BudgetStatus {
+	globalSpent, perProvider, _ := spend(s.sessions.List())
+
+	s.budgets.mu.RLock()
+	defer s.budgets.mu.RUnlock()
+
+	out := &BudgetStatus{
+		GlobalSpent:  globalSpent,
+		Frozen:       s.budgets.frozen,
+		FrozenReason: s.budgets.frozenReason,
+		FrozenAt:     s.budgets.frozenAt,
+	}
+	if s.budgets.global != nil {
+		out.GlobalLimit = new(big.Int).Set(s.budgets.global)
+	}
+
+	providers := make(map[string]*ProviderBudgetStatus)
+	for key, spent := range perProvider {
+		providers[key] = &ProviderBudgetStatus{ServiceProvider: eth.MustNewAddress(key), Spent: spent}
+	}
+	for key, limit := range s.budgets.perProvider {
+		if providers[key] == nil {
+			providers[key] = &ProviderBudgetStatus{ServiceProvider: eth.MustNewAddress(key), Spent: big.NewInt(0)}
+		}
+		providers[key].Limit = new(big.Int).Set(limit)
+	}
+
+	for _, provider := range providers {
+		out.Providers = append(out.Providers, provider)
+	}
+	sort.Slice(out.Providers, func(i, j int) bool {
+		return out.Providers[i].ServiceProvider.Pretty() < out.Providers[j].ServiceProvider.Pretty()
+	})
+
+	return out
+}
+
+// SetGlobalBudget sets the maximum value authorized across all sessions, nil removes the limit
+func (s *Sidecar) SetGlobalBudget(limit *big.Int) {
+	s.budgets.setGlobal(limit)
+}
+
+// SetProviderBudget sets the maximum value authorized to serviceProvider, nil removes the limit
+func (s *Sidecar) SetProviderBudget(serviceProvider eth.Address, limit *big.Int) {
+	s.budgets.setProvider(serviceProvider, limit)
+}
+
+// FreezeSigning refuses every RAV signing request until UnfreezeSigning is called
+func (s *Sidecar) FreezeSigning(reason string) {
+	s.budgets.setFrozen(true, reason)
+}
+
+// UnfreezeSigning resumes RAV signing after FreezeSigning
+func (s *Sidecar) UnfreezeSigning() {
+	s.budgets.setFrozen(false, "")
+}
+
+// signingError returns the Connect error of a refused RAV signing, carrying the
+// budget violation as error detail
+func signingError(err error) *connect.Error {
+	connectErr := connect.NewError(signingErrorCode(err), err)
+
+	var violation *BudgetViolation
+	if errors.As(err, &violation) {
+		if detail, detailErr := connect.NewErrorDetail(violation.Proto()); detailErr == nil {
+			connectErr.AddDetail(detail)
+		}
+	}
+	return connectErr
+}
+
+// signingErrorCode maps RAV signing errors to the Connect code returned to clients
+func signingErrorCode(err error) connect.Code {
+	switch {
+	case errors.Is(err, ErrSigningFrozen):
+		return connect.CodeUnavailable
+	case errors.Is(err, ErrBudgetExceeded):
+		return connect.CodeResourceExhausted
+	case errors.Is(err, ErrProviderNotVerified):
+		return connect.CodePermissionDenied
+	default:
+		return connect.CodeInternal
+	}
+}
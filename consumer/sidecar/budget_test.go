@@ -0,0 +1,242 @@
+package sidecar
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	consumerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/consumer/v1"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newBudgetTestSidecar(t *testing.T, globalBudget *big.Int) *Sidecar {
+	t.Helper()
+
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	return New(&Config{
+		ListenAddr:      ":0",
+		SignerKey:       signerKey,
+		Domain:          horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+		AdminListenAddr: ":0",
+		GlobalBudget:    globalBudget,
+	}, zap.NewNop())
+}
+
+func initBudgetTestSession(t *testing.T, s *Sidecar, receiver eth.Address) string {
+	t.Helper()
+
+	resp, err := s.Init(context.Background(), connect.NewRequest(&consumerv1.InitRequest{
+		EscrowAccount: &commonv1.EscrowAccount{
+			Payer:       commonv1.AddressFromEth(eth.MustNewAddress("0x2222222222222222222222222222222222222222")),
+			Receiver:    commonv1.AddressFromEth(receiver),
+			DataService: commonv1.AddressFromEth(eth.MustNewAddress("0x3333333333333333333333333333333333333333")),
+		},
+	}))
+	require.NoError(t, err)
+	return resp.Msg.Session.SessionId
+}
+
+func reportCost(s *Sidecar, sessionID string, cost int64) (*consumerv1.ReportUsageResponse, error) {
+	resp, err := s.ReportUsage(context.Background(), connect.NewRequest(&consumerv1.ReportUsageRequest{
+		SessionId: sessionID,
+		Usage:     &commonv1.Usage{BlocksProcessed: 1, Cost: commonv1.BigIntFromNative(big.NewInt(cost))},
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Msg, nil
+}
+
+func TestBudget_Global(t *testing.T) {
+	s := newBudgetTestSidecar(t, big.NewInt(100))
+	provider := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+
+	first := initBudgetTestSession(t, s, provider)
+	second := initBudgetTestSession(t, s, provider)
+
+	resp, err := reportCost(s, first, 60)
+	require.NoError(t, err)
+	assert.True(t, resp.ShouldContinue)
+
+	resp, err = reportCost(s, second, 40)
+	require.NoError(t, err)
+	assert.True(t, resp.ShouldContinue)
+
+	resp, err = reportCost(s, second, 1)
+	require.NoError(t, err)
+	assert.False(t, resp.ShouldContinue)
+	assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_BUDGET_EXCEEDED, resp.StopCode)
+	assert.Nil(t, resp.UpdatedRav)
+
+	s.SetGlobalBudget(nil)
+	resp, err = reportCost(s, second, 1)
+	require.NoError(t, err)
+	assert.True(t, resp.ShouldContinue)
+	assert.Equal(t, "101", s.BudgetStatus().GlobalSpent.String())
+}
+
+func TestBudget_PerProvider(t *testing.T) {
+	s := newBudgetTestSidecar(t, nil)
+	limited := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+	other := eth.MustNewAddress("0x5555555555555555555555555555555555555555")
+
+	s.SetProviderBudget(limited, big.NewInt(10))
+
+	resp, err := reportCost(s, initBudgetTestSession(t, s, limited), 11)
+	require.NoError(t, err)
+	assert.False(t, resp.ShouldContinue)
+
+	resp, err = reportCost(s, initBudgetTestSession(t, s, other), 11)
+	require.NoError(t, err)
+	assert.True(t, resp.ShouldContinue)
+}
+
+func TestBudget_Freeze(t *testing.T) {
+	s := newBudgetTestSidecar(t, nil)
+	provider := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+	sessionID := initBudgetTestSession(t, s, provider)
+
+	s.FreezeSigning("incident")
+
+	_, err := reportCost(s, sessionID, 1)
+	assert.Equal(t, connect.CodeUnavailable, connect.CodeOf(err))
+	assert.ErrorIs(t, err, ErrSigningFrozen)
+
+	_, err = s.Init(context.Background(), connect.NewRequest(&consumerv1.InitRequest{
+		EscrowAccount: &commonv1.EscrowAccount{
+			Payer:       commonv1.AddressFromEth(eth.MustNewAddress("0x2222222222222222222222222222222222222222")),
+			Receiver:    commonv1.AddressFromEth(provider),
+			DataService: commonv1.AddressFromEth(eth.MustNewAddress("0x3333333333333333333333333333333333333333")),
+		},
+	}))
+	assert.Equal(t, connect.CodeUnavailable, connect.CodeOf(err))
+
+	s.UnfreezeSigning()
+	resp, err := reportCost(s, sessionID, 1)
+	require.NoError(t, err)
+	assert.True(t, resp.ShouldContinue)
+}
+
+func TestBudget_AdminAPI(t *testing.T) {
+	s := newBudgetTestSidecar(t, nil)
+
+	serve := func(method, path, body string) (int, *BudgetResponse) {
+		rec := httptest.NewRecorder()
+		s.admin.Handler().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+
+		var out BudgetResponse
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+		}
+		return rec.Code, &out
+	}
+
+	code, out := serve(http.MethodPut, "/v1/budget/global", `{"limit":"1000"}`)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "1000", out.GlobalLimit)
+
+	code, out = serve(http.MethodPut, "/v1/budget/providers/0x4444444444444444444444444444444444444444", `{"limit":"50"}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, out.Providers, 1)
+	assert.Equal(t, "50", out.Providers[0].Limit)
+	assert.Equal(t, "0", out.Providers[0].Spent)
+
+	code, out = serve(http.MethodPost, "/v1/budget/freeze", `{"reason":"incident"}`)
+	require.Equal(t, http.StatusOK, code)
+	assert.True(t, out.Frozen)
+	assert.Equal(t, "incident", out.FrozenReason)
+
+	code, out = serve(http.MethodPost, "/v1/budget/unfreeze", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.False(t, out.Frozen)
+
+	code, out = serve(http.MethodPut, "/v1/budget/global", `{"limit":null}`)
+	require.Equal(t, http.StatusOK, code)
+	assert.Empty(t, out.GlobalLimit)
+
+	code, _ = serve(http.MethodPut, "/v1/budget/global", `{"limit":"-1"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = serve(http.MethodPut, "/v1/budget/providers/not-an-address", `{"limit":"1"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestSpendingLimits(t *testing.T) {
+	provider := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+
+	tests := []struct {
+		name      string
+		limits    SpendingLimits
+		sessions  int
+		costs     []int64
+		limit     consumerv1.BudgetLimit
+		max       int64
+		requested int64
+	}{
+		{"rav delta", SpendingLimits{MaxRAVDelta: big.NewInt(50)}, 1, []int64{50, 51}, consumerv1.BudgetLimit_BUDGET_LIMIT_RAV_DELTA, 50, 51},
+		{"session", SpendingLimits{MaxSessionValue: big.NewInt(100)}, 1, []int64{60, 40, 1}, consumerv1.BudgetLimit_BUDGET_LIMIT_SESSION, 100, 101},
+		{"value per block", SpendingLimits{MaxValuePerBlock: big.NewInt(10)}, 1, []int64{10, 11}, consumerv1.BudgetLimit_BUDGET_LIMIT_VALUE_PER_BLOCK, 10, 11},
+		{"payer", SpendingLimits{MaxPayerValue: big.NewInt(100)}, 2, []int64{60, 41}, consumerv1.BudgetLimit_BUDGET_LIMIT_PAYER, 100, 101},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newBudgetTestSidecar(t, nil)
+			s.spendingLimits = tt.limits
+
+			sessionIDs := make([]string, tt.sessions)
+			for i := range sessionIDs {
+				sessionIDs[i] = initBudgetTestSession(t, s, provider)
+			}
+
+			// Costs are reported round robin across the sessions, the last one
+			// crossing the limit
+			for i, cost := range tt.costs {
+				resp, err := reportCost(s, sessionIDs[i%tt.sessions], cost)
+				require.NoError(t, err)
+
+				if i < len(tt.costs)-1 {
+					assert.True(t, resp.ShouldContinue)
+					continue
+				}
+
+				assert.False(t, resp.ShouldContinue)
+				assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_BUDGET_EXCEEDED, resp.StopCode)
+				require.NotNil(t, resp.BudgetViolation)
+				assert.Equal(t, tt.limit, resp.BudgetViolation.Limit)
+				assert.Equal(t, big.NewInt(tt.max), resp.BudgetViolation.Max.ToNative())
+				assert.Equal(t, big.NewInt(tt.requested), resp.BudgetViolation.Requested.ToNative())
+			}
+		})
+	}
+}
+
+func TestSigningError_BudgetViolationDetail(t *testing.T) {
+	violation := &BudgetViolation{
+		Limit:     consumerv1.BudgetLimit_BUDGET_LIMIT_SESSION,
+		Max:       big.NewInt(100),
+		Requested: big.NewInt(101),
+	}
+
+	connectErr := signingError(violation)
+	assert.Equal(t, connect.CodeResourceExhausted, connectErr.Code())
+	assert.ErrorIs(t, connectErr, ErrBudgetExceeded)
+
+	require.Len(t, connectErr.Details(), 1)
+	detail, err := connectErr.Details()[0].Value()
+	require.NoError(t, err)
+	require.IsType(t, &consumerv1.BudgetViolation{}, detail)
+	assert.Equal(t, consumerv1.BudgetLimit_BUDGET_LIMIT_SESSION, detail.(*consumerv1.BudgetViolation).Limit)
+}
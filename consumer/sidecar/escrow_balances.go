@@ -0,0 +1,67 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/streamingfast/eth-go"
+)
+
+// ErrEscrowQueriesDisabled is returned by EscrowBalances when no RPC endpoint or
+// PaymentsEscrow address is configured
+var ErrEscrowQueriesDisabled = errors.New("escrow balance queries are not configured, an RPC endpoint and the PaymentsEscrow address are required")
+
+// escrowBalanceFunc reads the balance of the (payer, collector, receiver) escrow
+// account, sidecar.EscrowQuerier.GetBalance in production
+type escrowBalanceFunc func(ctx context.Context, payer, collector, receiver eth.Address) (*big.Int, error)
+
+// EscrowBalance is the balance of an escrow account in the PaymentsEscrow
+// contract as served by the admin API
+type EscrowBalance struct {
+	Payer     string `json:"payer"`
+	Collector string `json:"collector"`
+	Receiver  string `json:"receiver"`
+	Balance   string `json:"balance"`
+}
+
+// EscrowBalances reads from the PaymentsEscrow contract the balance of every
+// escrow account the known sessions are paid from. Funds are deposited per
+// (payer, collector, receiver), so a payer paying through several collectors
+// has one balance per collector.
+func (s *Sidecar) EscrowBalances(ctx context.Context) ([]*EscrowBalance, error) {
+	if s.escrowBalance == nil {
+		return nil, ErrEscrowQueriesDisabled
+	}
+
+	accounts := s.sessions.EscrowAccounts(s.domain.VerifyingContract)
+	out := make([]*EscrowBalance, 0, len(accounts))
+	for _, account := range accounts {
+		balance, err := s.escrowBalance(ctx, account.Payer, account.Collector, account.Receiver)
+		if err != nil {
+			return nil, fmt.Errorf("reading escrow balance of %s: %w", account, err)
+		}
+
+		out = append(out, &EscrowBalance{
+			Payer:     account.Payer.Pretty(),
+			Collector: account.Collector.Pretty(),
+			Receiver:  account.Receiver.Pretty(),
+			Balance:   balance.String(),
+		})
+	}
+	return out, nil
+}
+
+func (s *Sidecar) handleAdminGetEscrowBalances(w http.ResponseWriter, r *http.Request) {
+	balances, err := s.EscrowBalances(r.Context())
+	switch {
+	case errors.Is(err, ErrEscrowQueriesDisabled):
+		s.writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+	case err != nil:
+		s.writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+	default:
+		s.writeJSON(w, http.StatusOK, balances)
+	}
+}
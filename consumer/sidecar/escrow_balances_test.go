@@ -0,0 +1,68 @@
+package sidecar
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestEscrowBalances(t *testing.T) {
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	defaultCollector := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
+	otherCollector := eth.MustNewAddress("0x5555555555555555555555555555555555555555")
+	s := New(&Config{
+		ListenAddr:      ":0",
+		SignerKey:       signerKey,
+		Domain:          horizon.NewDomain(1337, defaultCollector),
+		Collectors:      []eth.Address{otherCollector},
+		AdminListenAddr: ":0",
+	}, zap.NewNop())
+
+	get := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.admin.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/escrow/balances", nil))
+		return rec
+	}
+
+	_, err = s.EscrowBalances(context.Background())
+	assert.ErrorIs(t, err, ErrEscrowQueriesDisabled)
+	assert.Equal(t, http.StatusServiceUnavailable, get().Code)
+
+	payer := signerKey.PublicKey().Address()
+	receiver := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+	dataService := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	s.sessions.CreateWithCollector(payer, receiver, dataService, defaultCollector)
+	s.sessions.CreateWithCollector(payer, receiver, dataService, defaultCollector)
+	s.sessions.CreateWithCollector(payer, receiver, dataService, otherCollector)
+
+	// The same payer holds a distinct balance for each collector
+	onChain := map[string]*big.Int{
+		defaultCollector.Pretty(): big.NewInt(1000),
+		otherCollector.Pretty():   big.NewInt(250),
+	}
+	s.escrowBalance = func(ctx context.Context, p, collector, r eth.Address) (*big.Int, error) {
+		assert.Equal(t, payer, p)
+		assert.Equal(t, receiver, r)
+		return onChain[collector.Pretty()], nil
+	}
+
+	rec := get()
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var balances []*EscrowBalance
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &balances))
+	require.Len(t, balances, 2)
+	assert.Equal(t, &EscrowBalance{Payer: payer.Pretty(), Collector: defaultCollector.Pretty(), Receiver: receiver.Pretty(), Balance: "1000"}, balances[0])
+	assert.Equal(t, &EscrowBalance{Payer: payer.Pretty(), Collector: otherCollector.Pretty(), Receiver: receiver.Pretty(), Balance: "250"}, balances[1])
+}
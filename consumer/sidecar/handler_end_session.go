@@ -37,6 +37,20 @@ func (s *Sidecar) EndSession(
 		session.AddUsage(finalUsage.BlocksProcessed, finalUsage.BytesTransferred, finalUsage.Requests, finalUsage.Cost.ToNative())
 	}
 
+	// Compare the usage the client observed with the usage the provider
+	// claimed, acknowledged in the settlement
+	if observed := req.Msg.ObservedUsage; observed != nil {
+		if _, err := s.CheckObservedUsage(sessionID, MeteredUsage{BlocksProcessed: observed.BlocksProcessed, BytesTransferred: observed.BytesTransferred}); err != nil {
+			s.logger.Warn("checking observed usage failed", zap.String("session_id", sessionID), zap.Error(err))
+		}
+	}
+
+	// Pay for the final usage with a receipt instead, the provider sidecar
+	// aggregating the session's receipts into its final RAV
+	if session.PaysWithReceipts() {
+		return s.endSessionWithReceipt(ctx, session, finalUsage)
+	}
+
 	// Get current RAV
 	currentRAV := session.GetRAV()
 
@@ -62,7 +76,25 @@ func (s *Sidecar) EndSession(
 		collectionID = currentRAV.Message.CollectionID
 	}
 
+	var previousValue *big.Int
+	if currentRAV != nil && currentRAV.Message != nil {
+		previousValue = currentRAV.Message.ValueAggregate
+	}
+	if err := s.identities.authorize(session.ID, finalValue); err != nil {
+		s.logger.Warn("refusing to sign final RAV", zap.String("session_id", sessionID), zap.Error(err))
+		s.notifySigningRefused(session, err)
+		return nil, signingError(err)
+	}
+	if err := s.authorizeSpend(session, previousValue, finalValue); err != nil {
+		s.logger.Warn("refusing to sign final RAV", zap.String("session_id", sessionID), zap.Error(err))
+		s.notifySigningRefused(session, err)
+		return nil, signingError(err)
+	}
+
 	finalRAV, err := s.signRAV(
+		ctx,
+		SigningPriorityFinal,
+		session.Collector,
 		collectionID,
 		session.Payer,
 		session.DataService,
@@ -73,13 +105,19 @@ func (s *Sidecar) EndSession(
 	)
 	if err != nil {
 		s.logger.Error("failed to sign final RAV", zap.Error(err))
-		return nil, connect.NewError(connect.CodeInternal, err)
+		s.notifySigningRefused(session, err)
+		return nil, signingError(err)
 	}
 
 	session.SetRAV(finalRAV)
+	s.observeSpend(session)
 
 	// End the session
 	session.End(commonv1.EndReason_END_REASON_COMPLETE)
+	s.identities.forget(session.ID)
+	s.priceBooks.recordSession(session.Receiver, true)
+	s.settle(session)
+	s.persistSession(session)
 
 	// Get total usage
 	totalUsage := session.GetUsage()
@@ -87,6 +125,7 @@ func (s *Sidecar) EndSession(
 	response := &consumerv1.EndSessionResponse{
 		FinalRav:   sidecar.HorizonSignedRAVToProto(finalRAV),
 		TotalUsage: totalUsage,
+		Settlement: settlementToProto(session),
 	}
 
 	s.logger.Info("EndSession completed",
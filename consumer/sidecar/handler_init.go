@@ -2,11 +2,11 @@ package sidecar
 
 import (
 	"context"
-	"math/big"
-	"time"
+	"fmt"
 
 	"connectrpc.com/connect"
 	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
 	consumerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/consumer/v1"
 	"github.com/graphprotocol/substreams-data-service/sidecar"
 	"go.uber.org/zap"
@@ -27,59 +27,133 @@ func (s *Sidecar) Init(
 	ea := req.Msg.EscrowAccount
 	payer, receiver, dataService := ea.Payer.ToEth(), ea.Receiver.ToEth(), ea.DataService.ToEth()
 
+	// Resolve the collector the escrow account is keyed by
+	collector, err := s.resolveCollector(ea.Collector.GetBytes())
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	// Resolve how usage of the session is paid for
+	paymentMode, err := s.resolvePaymentMode(req.Msg.PaymentMode)
+	if err != nil {
+		return nil, err
+	}
+
+	// Refuse service providers blacklisted for their dispute history
+	if entry := s.blacklist.check(receiver); entry != nil {
+		s.logger.Warn("refusing session toward blacklisted service provider",
+			zap.Stringer("receiver", receiver),
+			zap.String("reason", entry.Reason),
+		)
+		return nil, connect.NewError(connect.CodePermissionDenied, fmt.Errorf("%w: %s", ErrProviderBlacklisted, entry.Reason))
+	}
+
+	// Make sure the provider endpoint is operated by the service provider we pay
+	if s.identities.enabled {
+		if err := s.verifyProviderIdentity(ctx, req.Msg.ProviderEndpoint, receiver); err != nil {
+			s.logger.Warn("provider identity verification failed",
+				zap.String("provider_endpoint", req.Msg.ProviderEndpoint),
+				zap.Stringer("receiver", receiver),
+				zap.Error(err),
+			)
+			return nil, connect.NewError(connect.CodePermissionDenied, err)
+		}
+	}
+
 	// Create a new session
-	session := s.sessions.Create(payer, receiver, dataService)
+	session := s.sessions.CreateWithCollector(payer, receiver, dataService, collector)
+	session.ProviderEndpoint = req.Msg.ProviderEndpoint
+	session.PaymentMode = paymentMode
+	if s.identities.enabled {
+		s.identities.markVerified(session.ID)
+	}
 
 	s.logger.Debug("created session",
 		zap.String("session_id", session.ID),
 		zap.Stringer("payer", payer),
 		zap.Stringer("receiver", receiver),
 		zap.Stringer("data_service", dataService),
+		zap.Stringer("collector", collector),
+		zap.Stringer("payment_mode", paymentMode),
 	)
 
-	// Check if we have an existing RAV to continue from
-	var existingRAV *horizon.SignedRAV
-	if req.Msg.ExistingRav != nil {
-		existingRAV = sidecar.ProtoSignedRAVToHorizon(req.Msg.ExistingRav)
-		session.SetRAV(existingRAV)
+	// Pick the RAV to continue from: the existing RAV of the request or, when
+	// resuming, the last RAV signed for this escrow account and data service
+	initialRAV := sidecar.ProtoSignedRAVToHorizon(req.Msg.ExistingRav)
+	if initialRAV == nil && s.initialRAVStrategy != InitialRAVZero {
+		initialRAV = s.lastKnownRAV(session.ID, payer, receiver, dataService, collector)
 	}
 
-	// Create initial RAV (can be zero-value for new sessions)
-	var initialRAV *horizon.SignedRAV
-	var err error
-
-	if existingRAV != nil {
-		// Use the existing RAV
-		initialRAV = existingRAV
-	} else {
-		// Create a zero-value RAV for new sessions
-		// This establishes the session parameters without committing to any value
-		var collectionID horizon.CollectionID
-		// Collection ID can be derived from session or left empty for now
+	if initialRAV == nil {
+		// Start new sessions from a zero-value RAV, the collection ID being
+		// left empty for now
+		rav0 := horizon.NewBootstrapRAV(horizon.RAVParties{
+			Payer:           payer,
+			ServiceProvider: receiver,
+			DataService:     dataService,
+		}, horizon.CollectionID{}, nil)
 
 		initialRAV, err = s.signRAV(
-			collectionID,
-			payer,
-			dataService,
-			receiver,
-			uint64(time.Now().UnixNano()),
-			big.NewInt(0), // Zero value
-			nil,           // No metadata yet
+			ctx,
+			SigningPriorityNormal,
+			collector,
+			rav0.CollectionID,
+			rav0.Payer,
+			rav0.DataService,
+			rav0.ServiceProvider,
+			rav0.TimestampNs,
+			rav0.ValueAggregate,
+			rav0.Metadata,
 		)
 		if err != nil {
 			s.logger.Error("failed to sign initial RAV", zap.Error(err))
-			return nil, connect.NewError(connect.CodeInternal, err)
+			s.notifySigningRefused(session, err)
+			return nil, signingError(err)
 		}
-
-		session.SetRAV(initialRAV)
 	}
+	session.SetRAV(initialRAV)
 
-	// In a full implementation, we would call the provider's PaymentGateway.StartSession
-	// to register this session. For now, we return the signed RAV for the client to use.
+	// Let the provider propose the RAV to start from instead (useThis(RAVx)),
+	// accepted only when it passes validateProposedRAV and fits the budgets
+	if s.initialRAVStrategy == InitialRAVProvider {
+		proposed, err := s.proposeInitialRAV(ctx, req.Msg.ProviderEndpoint, ea, initialRAV, paymentMode)
+		if err != nil {
+			s.logger.Warn("starting session on provider failed", zap.String("provider_endpoint", req.Msg.ProviderEndpoint), zap.Error(err))
+			session.End(commonv1.EndReason_END_REASON_ERROR)
+			s.identities.forget(session.ID)
+			return nil, connect.NewError(connect.CodeFailedPrecondition, err)
+		}
+
+		if proposed != nil {
+			if err := s.validateProposedRAV(collector, initialRAV, proposed); err != nil {
+				s.logger.Warn("refusing provider proposed RAV", zap.String("session_id", session.ID), zap.Error(err))
+				session.End(commonv1.EndReason_END_REASON_PAYMENT_ISSUE)
+				s.identities.forget(session.ID)
+				return nil, connect.NewError(connect.CodeFailedPrecondition, err)
+			}
+			if err := s.authorizeSpend(session, valueOf(initialRAV), valueOf(proposed)); err != nil {
+				s.logger.Warn("refusing provider proposed RAV", zap.String("session_id", session.ID), zap.Error(err))
+				s.notifySigningRefused(session, err)
+				session.End(commonv1.EndReason_END_REASON_PAYMENT_ISSUE)
+				s.identities.forget(session.ID)
+				return nil, signingError(err)
+			}
+
+			s.logger.Info("using provider proposed RAV",
+				zap.String("session_id", session.ID),
+				zap.Stringer("value_aggregate", valueOf(proposed)),
+			)
+			initialRAV = proposed
+			session.SetRAV(initialRAV)
+		}
+	}
+	s.observeSpend(session)
+	s.persistSession(session)
 
 	response := &consumerv1.InitResponse{
-		Session:    session.ToSessionInfo(),
-		PaymentRav: sidecar.HorizonSignedRAVToProto(initialRAV),
+		Session:     session.ToSessionInfo(),
+		PaymentRav:  sidecar.HorizonSignedRAVToProto(initialRAV),
+		PaymentMode: paymentMode,
 	}
 
 	s.logger.Info("Init completed",
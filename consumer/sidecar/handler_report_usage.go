@@ -37,10 +37,20 @@ func (s *Sidecar) ReportUsage(
 			connect.NewError(connect.CodeFailedPrecondition, nil))
 	}
 
-	// Add usage to session
+	// Add usage to session, with its category breakdown
 	usage := req.Msg.Usage
 	if usage != nil {
 		session.AddUsage(usage.BlocksProcessed, usage.BytesTransferred, usage.Requests, usage.Cost.ToNative())
+		session.AddUsageCategories(usage.Categories)
+	}
+
+	// Pay with a receipt instead, the provider sidecar aggregating them
+	if session.PaysWithReceipts() {
+		cost := big.NewInt(0)
+		if usage.GetCost() != nil {
+			cost = usage.Cost.ToNative()
+		}
+		return s.reportUsageWithReceipt(ctx, session, cost)
 	}
 
 	// Get current RAV for value calculation
@@ -60,7 +70,23 @@ func (s *Sidecar) ReportUsage(
 		collectionID = currentRAV.Message.CollectionID
 	}
 
+	var previousValue *big.Int
+	if currentRAV != nil && currentRAV.Message != nil {
+		previousValue = currentRAV.Message.ValueAggregate
+	}
+	if err := s.identities.authorize(session.ID, newValue); err != nil {
+		s.logger.Warn("refusing to sign RAV", zap.String("session_id", sessionID), zap.Error(err))
+		s.notifySigningRefused(session, err)
+		return nil, signingError(err)
+	}
+	if err := s.authorizeSpend(session, previousValue, newValue); err != nil {
+		return s.refuseSpend(session, err)
+	}
+
 	updatedRAV, err := s.signRAV(
+		ctx,
+		SigningPriorityNormal,
+		session.Collector,
 		collectionID,
 		session.Payer,
 		session.DataService,
@@ -71,10 +97,13 @@ func (s *Sidecar) ReportUsage(
 	)
 	if err != nil {
 		s.logger.Error("failed to sign updated RAV", zap.Error(err))
-		return nil, connect.NewError(connect.CodeInternal, err)
+		s.notifySigningRefused(session, err)
+		return nil, signingError(err)
 	}
 
 	session.SetRAV(updatedRAV)
+	s.observeSpend(session)
+	s.persistSession(session)
 
 	response := &consumerv1.ReportUsageResponse{
 		UpdatedRav:     sidecar.HorizonSignedRAVToProto(updatedRAV),
@@ -0,0 +1,59 @@
+package sidecar
+
+import (
+	"context"
+	"fmt"
+
+	"connectrpc.com/connect"
+	consumerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/consumer/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"go.uber.org/zap"
+)
+
+// ResumeSession re-attaches a client to an active session by ID, e.g. after
+// the client or the sidecar restarted, returning the last RAV signed for it so
+// the stream continues from its cumulative usage and value
+func (s *Sidecar) ResumeSession(
+	ctx context.Context,
+	req *connect.Request[consumerv1.ResumeSessionRequest],
+) (*connect.Response[consumerv1.ResumeSessionResponse], error) {
+	sessionID := req.Msg.SessionId
+
+	s.logger.Info("ResumeSession called",
+		zap.String("session_id", sessionID),
+	)
+
+	session, err := s.sessions.Get(sessionID)
+	if err != nil {
+		s.logger.Warn("session not found", zap.String("session_id", sessionID))
+		return nil, connect.NewError(connect.CodeNotFound, err)
+	}
+	if !session.IsActive() {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("session %s is not active", sessionID))
+	}
+
+	// Sessions restored from the store prove the provider identity again,
+	// verifications are not persisted
+	if !s.identities.isVerified(session.ID) {
+		if err := s.verifyProviderIdentity(ctx, session.ProviderEndpoint, session.Receiver); err != nil {
+			s.logger.Warn("provider identity verification failed",
+				zap.String("session_id", session.ID),
+				zap.String("provider_endpoint", session.ProviderEndpoint),
+				zap.Stringer("receiver", session.Receiver),
+				zap.Error(err),
+			)
+			return nil, connect.NewError(connect.CodePermissionDenied, err)
+		}
+		s.identities.markVerified(session.ID)
+	}
+
+	s.logger.Info("ResumeSession completed",
+		zap.String("session_id", session.ID),
+		zap.Uint64("blocks_processed", session.GetUsage().BlocksProcessed),
+	)
+
+	return connect.NewResponse(&consumerv1.ResumeSessionResponse{
+		Session:    sessionToProto(session),
+		PaymentRav: sidecar.HorizonSignedRAVToProto(session.GetRAV()),
+	}), nil
+}
@@ -0,0 +1,78 @@
+package sidecar
+
+import (
+	"context"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	consumerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/consumer/v1"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestResumeSession_AfterRestart(t *testing.T) {
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	storePath := filepath.Join(t.TempDir(), "sessions")
+
+	// Each sidecar closes the store of the previous one, as on a restart
+	var current *Sidecar
+	newSidecar := func(globalBudget *big.Int) *Sidecar {
+		if current != nil {
+			current.closeSessionStore()
+		}
+		s := New(&Config{
+			ListenAddr:   ":0",
+			SignerKey:    signerKey,
+			Domain:       horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+			GlobalBudget: globalBudget,
+			StorePath:    storePath,
+		}, zap.NewNop())
+		require.NoError(t, s.restoreSessions())
+		t.Cleanup(s.closeSessionStore)
+		current = s
+		return s
+	}
+
+	s := newSidecar(nil)
+	sessionID := initBudgetTestSession(t, s, eth.MustNewAddress("0x4444444444444444444444444444444444444444"))
+	_, err = reportCost(s, sessionID, 10)
+	require.NoError(t, err)
+	_, err = reportCost(s, sessionID, 5)
+	require.NoError(t, err)
+
+	// The restarted sidecar only knows the session from its store
+	s = newSidecar(big.NewInt(25))
+
+	resp, err := s.ResumeSession(context.Background(), connect.NewRequest(&consumerv1.ResumeSessionRequest{SessionId: sessionID}))
+	require.NoError(t, err)
+	assert.True(t, resp.Msg.Session.Active)
+	assert.Equal(t, sessionID, resp.Msg.Session.Info.SessionId)
+	assert.Equal(t, uint64(2), resp.Msg.Session.Info.AccumulatedUsage.BlocksProcessed)
+	assert.Equal(t, "15", resp.Msg.PaymentRav.Rav.ValueAggregate.ToNative().String())
+
+	// Usage keeps being paid on top of the last RAV, within the budget the
+	// restored session already spent from
+	report, err := reportCost(s, sessionID, 7)
+	require.NoError(t, err)
+	assert.Equal(t, "22", report.UpdatedRav.Rav.ValueAggregate.ToNative().String())
+
+	report, err = reportCost(s, sessionID, 5)
+	require.NoError(t, err)
+	assert.False(t, report.ShouldContinue)
+
+	_, err = s.EndSession(context.Background(), connect.NewRequest(&consumerv1.EndSessionRequest{SessionId: sessionID}))
+	require.NoError(t, err)
+
+	s = newSidecar(nil)
+	_, err = s.ResumeSession(context.Background(), connect.NewRequest(&consumerv1.ResumeSessionRequest{SessionId: sessionID}))
+	assert.Equal(t, connect.CodeFailedPrecondition, connect.CodeOf(err))
+
+	_, err = s.ResumeSession(context.Background(), connect.NewRequest(&consumerv1.ResumeSessionRequest{SessionId: "unknown"}))
+	assert.Equal(t, connect.CodeNotFound, connect.CodeOf(err))
+}
@@ -0,0 +1,90 @@
+package sidecar
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"connectrpc.com/connect"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	consumerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/consumer/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"go.uber.org/zap"
+)
+
+// ListSessions lists the sessions of the sidecar, ordered by creation time,
+// filtered by service provider, collection and activity when requested
+func (s *Sidecar) ListSessions(
+	ctx context.Context,
+	req *connect.Request[consumerv1.ListSessionsRequest],
+) (*connect.Response[consumerv1.ListSessionsResponse], error) {
+	s.logger.Debug("ListSessions called")
+
+	serviceProvider := eth.Address(req.Msg.ServiceProvider.GetBytes())
+	collectionID := req.Msg.CollectionId
+	if len(collectionID) != 0 && len(collectionID) != 32 {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("collection ID must be 32 bytes, got %d", len(collectionID)))
+	}
+
+	response := &consumerv1.ListSessionsResponse{}
+	for _, session := range s.sessions.List() {
+		if req.Msg.ActiveOnly && !session.IsActive() {
+			continue
+		}
+		if len(serviceProvider) != 0 && !sidecar.AddressesEqual(session.Receiver, serviceProvider) {
+			continue
+		}
+		if len(collectionID) != 0 && !sessionHasCollection(session, collectionID) {
+			continue
+		}
+		response.Sessions = append(response.Sessions, sessionToProto(session))
+	}
+
+	return connect.NewResponse(response), nil
+}
+
+// GetSession gets a session by ID
+func (s *Sidecar) GetSession(
+	ctx context.Context,
+	req *connect.Request[consumerv1.GetSessionRequest],
+) (*connect.Response[consumerv1.GetSessionResponse], error) {
+	sessionID := req.Msg.SessionId
+
+	s.logger.Debug("GetSession called",
+		zap.String("session_id", sessionID),
+	)
+
+	session, err := s.sessions.Get(sessionID)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, err)
+	}
+
+	return connect.NewResponse(&consumerv1.GetSessionResponse{
+		Session: sessionToProto(session),
+	}), nil
+}
+
+func sessionHasCollection(session *sidecar.Session, collectionID []byte) bool {
+	rav := session.GetRAV()
+	return rav != nil && rav.Message != nil && bytes.Equal(rav.Message.CollectionID[:], collectionID)
+}
+
+func sessionToProto(session *sidecar.Session) *consumerv1.Session {
+	snapshot := session.Snapshot()
+	out := &consumerv1.Session{
+		Info:             session.ToSessionInfo(),
+		Active:           snapshot.State == sidecar.SessionStateActive,
+		ProviderEndpoint: session.ProviderEndpoint,
+		CreatedAtNs:      uint64(snapshot.CreatedAt.UnixNano()),
+		EndReason:        snapshot.EndReason,
+		PaymentMode:      session.PaymentMode,
+	}
+	if session.PaysWithReceipts() {
+		out.ReceiptsValue = commonv1.BigIntFromNative(session.GetReceiptsValue())
+	}
+	if snapshot.EndedAt != nil {
+		out.EndedAtNs = uint64(snapshot.EndedAt.UnixNano())
+	}
+	return out
+}
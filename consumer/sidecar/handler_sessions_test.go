@@ -0,0 +1,88 @@
+package sidecar
+
+import (
+	"context"
+	"testing"
+
+	"connectrpc.com/connect"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	consumerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/consumer/v1"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListSessions(t *testing.T) {
+	s := newBudgetTestSidecar(t, nil)
+	first := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+	second := eth.MustNewAddress("0x5555555555555555555555555555555555555555")
+
+	initSession := func(receiver eth.Address, endpoint string) string {
+		resp, err := s.Init(context.Background(), connect.NewRequest(&consumerv1.InitRequest{
+			EscrowAccount: &commonv1.EscrowAccount{
+				Payer:       commonv1.AddressFromEth(eth.MustNewAddress("0x2222222222222222222222222222222222222222")),
+				Receiver:    commonv1.AddressFromEth(receiver),
+				DataService: commonv1.AddressFromEth(eth.MustNewAddress("0x3333333333333333333333333333333333333333")),
+			},
+			ProviderEndpoint: endpoint,
+		}))
+		require.NoError(t, err)
+		return resp.Msg.Session.SessionId
+	}
+
+	list := func(req *consumerv1.ListSessionsRequest) []string {
+		resp, err := s.ListSessions(context.Background(), connect.NewRequest(req))
+		require.NoError(t, err)
+
+		var ids []string
+		for _, session := range resp.Msg.Sessions {
+			ids = append(ids, session.Info.SessionId)
+		}
+		return ids
+	}
+
+	a := initSession(first, "tier1-a:443")
+	b := initSession(second, "tier1-b:443")
+	c := initSession(first, "tier1-c:443")
+
+	assert.Equal(t, []string{a, b, c}, list(&consumerv1.ListSessionsRequest{}))
+	assert.Equal(t, []string{a, c}, list(&consumerv1.ListSessionsRequest{ServiceProvider: commonv1.AddressFromEth(first)}))
+	assert.Equal(t, []string{a, b, c}, list(&consumerv1.ListSessionsRequest{CollectionId: make([]byte, 32)}))
+	assert.Empty(t, list(&consumerv1.ListSessionsRequest{CollectionId: append([]byte{0x01}, make([]byte, 31)...)}))
+
+	_, err := s.EndSession(context.Background(), connect.NewRequest(&consumerv1.EndSessionRequest{SessionId: a}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{b, c}, list(&consumerv1.ListSessionsRequest{ActiveOnly: true}))
+
+	_, err = s.ListSessions(context.Background(), connect.NewRequest(&consumerv1.ListSessionsRequest{CollectionId: []byte{0x01}}))
+	assert.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
+}
+
+func TestGetSession(t *testing.T) {
+	s := newBudgetTestSidecar(t, nil)
+	sessionID := initBudgetTestSession(t, s, eth.MustNewAddress("0x4444444444444444444444444444444444444444"))
+
+	_, err := reportCost(s, sessionID, 10)
+	require.NoError(t, err)
+
+	resp, err := s.GetSession(context.Background(), connect.NewRequest(&consumerv1.GetSessionRequest{SessionId: sessionID}))
+	require.NoError(t, err)
+	session := resp.Msg.Session
+	assert.True(t, session.Active)
+	assert.Equal(t, sessionID, session.Info.SessionId)
+	assert.Equal(t, "10", session.Info.CurrentRav.Rav.ValueAggregate.ToNative().String())
+	assert.NotZero(t, session.CreatedAtNs)
+	assert.Zero(t, session.EndedAtNs)
+
+	_, err = s.EndSession(context.Background(), connect.NewRequest(&consumerv1.EndSessionRequest{SessionId: sessionID}))
+	require.NoError(t, err)
+
+	resp, err = s.GetSession(context.Background(), connect.NewRequest(&consumerv1.GetSessionRequest{SessionId: sessionID}))
+	require.NoError(t, err)
+	assert.False(t, resp.Msg.Session.Active)
+	assert.NotZero(t, resp.Msg.Session.EndedAtNs)
+	assert.Equal(t, commonv1.EndReason_END_REASON_COMPLETE, resp.Msg.Session.EndReason)
+
+	_, err = s.GetSession(context.Background(), connect.NewRequest(&consumerv1.GetSessionRequest{SessionId: "unknown"}))
+	assert.Equal(t, connect.CodeNotFound, connect.CodeOf(err))
+}
@@ -0,0 +1,132 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1/providerv1connect"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+)
+
+// ErrProviderNotVerified is returned when a non-zero RAV would be signed for a
+// session whose provider endpoint did not prove the service provider identity
+var ErrProviderNotVerified = errors.New("provider identity not verified")
+
+// providerIdentityTimeout bounds identity challenges sent to provider endpoints
+const providerIdentityTimeout = 10 * time.Second
+
+// providerIdentities tracks the sessions whose provider endpoint proved, by
+// signing a challenge with the service provider key, that it is operated by the
+// service provider the session pays, until the session ends. When disabled
+// every session is trusted.
+type providerIdentities struct {
+	enabled bool
+
+	mu       sync.RWMutex
+	verified map[string]bool // by session ID
+}
+
+func newProviderIdentities(enabled bool) *providerIdentities {
+	return &providerIdentities{
+		enabled:  enabled,
+		verified: make(map[string]bool),
+	}
+}
+
+func (p *providerIdentities) markVerified(sessionID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.verified[sessionID] = true
+}
+
+// forget drops the verification of sessionID, which ended
+func (p *providerIdentities) forget(sessionID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.verified, sessionID)
+}
+
+// isVerified returns whether the provider identity of sessionID was verified,
+// always true when verification is disabled
+func (p *providerIdentities) isVerified(sessionID string) bool {
+	if !p.enabled {
+		return true
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.verified[sessionID]
+}
+
+// authorize returns ErrProviderNotVerified if value is non-zero and the
+// session's provider identity was not verified
+func (p *providerIdentities) authorize(sessionID string, value *big.Int) error {
+	if !p.enabled || value == nil || value.Sign() <= 0 {
+		return nil
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if !p.verified[sessionID] {
+		return fmt.Errorf("%w for session %s", ErrProviderNotVerified, sessionID)
+	}
+	return nil
+}
+
+// verifyProviderIdentity challenges the provider endpoint to sign a random
+// challenge with the serviceProvider key through PaymentGatewayService.ProveIdentity
+func (s *Sidecar) verifyProviderIdentity(ctx context.Context, endpoint string, serviceProvider eth.Address) error {
+	if endpoint == "" {
+		return fmt.Errorf("%w: no provider endpoint to challenge", ErrProviderNotVerified)
+	}
+
+	challenge, err := sidecar.NewIdentityChallenge()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, providerIdentityTimeout)
+	defer cancel()
+
+	client := providerv1connect.NewPaymentGatewayServiceClient(http.DefaultClient, providerEndpointURL(endpoint))
+	resp, err := client.ProveIdentity(ctx, connect.NewRequest(&providerv1.ProveIdentityRequest{
+		Challenge:       challenge,
+		ServiceProvider: commonv1.AddressFromEth(serviceProvider),
+	}))
+	if err != nil {
+		return fmt.Errorf("%w: challenging %s: %w", ErrProviderNotVerified, endpoint, err)
+	}
+
+	if len(resp.Msg.Signature) != len(eth.Signature{}) {
+		return fmt.Errorf("%w: invalid signature length %d", ErrProviderNotVerified, len(resp.Msg.Signature))
+	}
+	var signature eth.Signature
+	copy(signature[:], resp.Msg.Signature)
+
+	if err := sidecar.VerifyIdentityChallenge(challenge, serviceProvider, signature); err != nil {
+		return fmt.Errorf("%w: %w", ErrProviderNotVerified, err)
+	}
+	return nil
+}
+
+// providerEndpointURL defaults provider endpoints given as host:port to plain HTTP
+func providerEndpointURL(endpoint string) string {
+	if strings.Contains(endpoint, "://") {
+		return endpoint
+	}
+	return "http://" + endpoint
+}
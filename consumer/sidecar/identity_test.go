@@ -0,0 +1,141 @@
+package sidecar
+
+import (
+	"context"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	consumerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/consumer/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1/providerv1connect"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// identityGateway answers identity challenges with key
+type identityGateway struct {
+	providerv1connect.UnimplementedPaymentGatewayServiceHandler
+	key *eth.PrivateKey
+}
+
+func (g *identityGateway) ProveIdentity(ctx context.Context, req *connect.Request[providerv1.ProveIdentityRequest]) (*connect.Response[providerv1.ProveIdentityResponse], error) {
+	serviceProvider := g.key.PublicKey().Address()
+	signature, err := sidecar.SignIdentityChallenge(g.key, req.Msg.Challenge, serviceProvider)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	return connect.NewResponse(&providerv1.ProveIdentityResponse{
+		ServiceProvider: commonv1.AddressFromEth(serviceProvider),
+		Signature:       signature[:],
+	}), nil
+}
+
+func newIdentityGateway(t *testing.T, key *eth.PrivateKey) string {
+	t.Helper()
+
+	_, handler := providerv1connect.NewPaymentGatewayServiceHandler(&identityGateway{key: key})
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return server.URL
+}
+
+func TestInit_VerifyProviderIdentity(t *testing.T) {
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	providerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	impostorKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	s := New(&Config{
+		ListenAddr:             ":0",
+		SignerKey:              signerKey,
+		Domain:                 horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+		VerifyProviderIdentity: true,
+	}, zap.NewNop())
+
+	initSession := func(endpoint string) (*consumerv1.InitResponse, error) {
+		resp, err := s.Init(context.Background(), connect.NewRequest(&consumerv1.InitRequest{
+			EscrowAccount: &commonv1.EscrowAccount{
+				Payer:       commonv1.AddressFromEth(eth.MustNewAddress("0x2222222222222222222222222222222222222222")),
+				Receiver:    commonv1.AddressFromEth(providerKey.PublicKey().Address()),
+				DataService: commonv1.AddressFromEth(eth.MustNewAddress("0x3333333333333333333333333333333333333333")),
+			},
+			ProviderEndpoint: endpoint,
+		}))
+		if err != nil {
+			return nil, err
+		}
+		return resp.Msg, nil
+	}
+
+	resp, err := initSession(newIdentityGateway(t, providerKey))
+	require.NoError(t, err)
+
+	usage, err := reportCost(s, resp.Session.SessionId, 10)
+	require.NoError(t, err)
+	assert.True(t, usage.ShouldContinue)
+	assert.NotNil(t, usage.UpdatedRav)
+
+	_, err = s.EndSession(context.Background(), connect.NewRequest(&consumerv1.EndSessionRequest{SessionId: resp.Session.SessionId}))
+	require.NoError(t, err)
+	assert.False(t, s.identities.isVerified(resp.Session.SessionId))
+	assert.Empty(t, s.identities.verified)
+
+	_, err = initSession(newIdentityGateway(t, impostorKey))
+	assert.Equal(t, connect.CodePermissionDenied, connect.CodeOf(err))
+	assert.ErrorIs(t, err, ErrProviderNotVerified)
+	assert.ErrorIs(t, err, sidecar.ErrIdentityMismatch)
+
+	_, err = initSession("")
+	assert.Equal(t, connect.CodePermissionDenied, connect.CodeOf(err))
+}
+
+func TestProviderIdentities_Authorize(t *testing.T) {
+	identities := newProviderIdentities(true)
+
+	assert.NoError(t, identities.authorize("session", big.NewInt(0)))
+	assert.ErrorIs(t, identities.authorize("session", big.NewInt(1)), ErrProviderNotVerified)
+
+	identities.markVerified("session")
+	assert.NoError(t, identities.authorize("session", big.NewInt(1)))
+
+	identities.forget("session")
+	assert.ErrorIs(t, identities.authorize("session", big.NewInt(1)), ErrProviderNotVerified)
+
+	assert.NoError(t, newProviderIdentities(false).authorize("session", big.NewInt(1)))
+}
+
+func TestExpireIdleSessions(t *testing.T) {
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	s := New(&Config{
+		ListenAddr:         ":0",
+		SignerKey:          signerKey,
+		Domain:             horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+		SessionIdleTimeout: time.Minute,
+	}, zap.NewNop())
+	s.identities.enabled = true
+
+	idle := s.sessions.Create(eth.MustNewAddress("0x2222222222222222222222222222222222222222"), eth.MustNewAddress("0x4444444444444444444444444444444444444444"), eth.MustNewAddress("0x3333333333333333333333333333333333333333"))
+	s.identities.markVerified(idle.ID)
+
+	assert.Equal(t, 0, s.expireIdleSessions(time.Now()))
+	assert.True(t, idle.IsActive())
+
+	assert.Equal(t, 1, s.expireIdleSessions(time.Now().Add(2*time.Minute)))
+	assert.False(t, idle.IsActive())
+	assert.Equal(t, commonv1.EndReason_END_REASON_CLIENT_DISCONNECT, idle.EndReason)
+	assert.False(t, s.identities.isVerified(idle.ID))
+	assert.Empty(t, s.identities.verified)
+}
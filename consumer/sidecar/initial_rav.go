@@ -0,0 +1,147 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1/providerv1connect"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+)
+
+// InitialRAVStrategy selects the RAV a session starts from on Init
+type InitialRAVStrategy string
+
+const (
+	// InitialRAVZero starts from a freshly signed zero-value RAV, unless the
+	// Init request carries an existing RAV to continue from
+	InitialRAVZero InitialRAVStrategy = "zero"
+	// InitialRAVResume continues from the existing RAV of the Init request or,
+	// without one, from the last RAV signed for the same escrow account and
+	// data service, falling back to a zero-value RAV
+	InitialRAVResume InitialRAVStrategy = "resume"
+	// InitialRAVProvider offers the resumed (or zero-value) RAV to the provider
+	// through PaymentGatewayService.StartSession and starts from the RAV the
+	// provider answers with, once validated by validateProposedRAV
+	InitialRAVProvider InitialRAVStrategy = "provider"
+)
+
+// ErrInvalidProposedRAV is returned when the starting RAV proposed by a
+// provider is refused
+var ErrInvalidProposedRAV = errors.New("invalid provider proposed RAV")
+
+// providerStartSessionTimeout bounds StartSession calls sent to provider endpoints
+const providerStartSessionTimeout = 10 * time.Second
+
+// ParseInitialRAVStrategy parses one of "zero", "resume" or "provider"
+func ParseInitialRAVStrategy(in string) (InitialRAVStrategy, error) {
+	switch strategy := InitialRAVStrategy(in); strategy {
+	case InitialRAVZero, InitialRAVResume, InitialRAVProvider:
+		return strategy, nil
+	}
+	return "", fmt.Errorf("unknown initial RAV strategy %q, expected one of %q, %q or %q", in, InitialRAVZero, InitialRAVResume, InitialRAVProvider)
+}
+
+// lastKnownRAV returns the highest RAV of the sessions other than exclude paying
+// receiver for dataService from payer's escrow through collector, nil when none.
+// RAVs past their validity window are skipped.
+func (s *Sidecar) lastKnownRAV(exclude string, payer, receiver, dataService, collector eth.Address) *horizon.SignedRAV {
+	var last *horizon.SignedRAV
+	for _, session := range s.sessions.List() {
+		if session.ID == exclude ||
+			!sidecar.AddressesEqual(session.Payer, payer) ||
+			!sidecar.AddressesEqual(session.Receiver, receiver) ||
+			!sidecar.AddressesEqual(session.DataService, dataService) ||
+			!sidecar.AddressesEqual(session.Collector, collector) {
+			continue
+		}
+
+		rav := session.GetRAV()
+		if rav == nil || rav.Message == nil || rav.Message.ValueAggregate == nil {
+			continue
+		}
+		// Providers honoring the validity window would refuse it
+		if horizon.CheckRAVValidity(rav.Message, time.Now()) != nil {
+			continue
+		}
+		if last == nil || rav.Message.ValueAggregate.Cmp(last.Message.ValueAggregate) > 0 ||
+			(rav.Message.ValueAggregate.Cmp(last.Message.ValueAggregate) == 0 && rav.Message.TimestampNs > last.Message.TimestampNs) {
+			last = rav
+		}
+	}
+	return last
+}
+
+// proposeInitialRAV starts a session paid for in paymentMode on the provider
+// endpoint offering the offered RAV and returns the RAV the provider wants the
+// session to use, nil when it keeps the offered one
+func (s *Sidecar) proposeInitialRAV(ctx context.Context, endpoint string, escrowAccount *commonv1.EscrowAccount, offered *horizon.SignedRAV, paymentMode commonv1.PaymentMode) (*horizon.SignedRAV, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("%w: no provider endpoint to start the session with", ErrInvalidProposedRAV)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, providerStartSessionTimeout)
+	defer cancel()
+
+	client := providerv1connect.NewPaymentGatewayServiceClient(http.DefaultClient, providerEndpointURL(endpoint))
+	resp, err := client.StartSession(ctx, connect.NewRequest(&providerv1.StartSessionRequest{
+		EscrowAccount: escrowAccount,
+		InitialRav:    sidecar.HorizonSignedRAVToProto(offered),
+		PaymentMode:   paymentMode,
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("starting session on %s: %w", endpoint, err)
+	}
+	if !resp.Msg.Accepted {
+		return nil, fmt.Errorf("provider rejected the session (%s): %s", resp.Msg.RejectionCode, resp.Msg.RejectionReason)
+	}
+
+	proposed := sidecar.ProtoSignedRAVToHorizon(resp.Msg.UseRav)
+	if proposed == nil || proposed.Message == nil || proposed.Signature == offered.Signature {
+		return nil, nil
+	}
+	return proposed, nil
+}
+
+// validateProposedRAV checks a starting RAV proposed by the provider in place
+// of offered: it must be signed by this sidecar's signer under the collector's
+// domain, for the same payer, service provider and data service, for the same
+// collection when offered carries one, and must neither lower the value
+// aggregate nor predate offered when offered carries value. Budgets are
+// checked separately by authorizeSpend.
+func (s *Sidecar) validateProposedRAV(collector eth.Address, offered, proposed *horizon.SignedRAV) error {
+	validator := horizon.NewValidator(s.collectorDomains[collector.Pretty()], []eth.Address{s.signerAddress})
+
+	want := offered.Message
+	policy := horizon.Policy{
+		Parties: &horizon.RAVParties{Payer: want.Payer, ServiceProvider: want.ServiceProvider, DataService: want.DataService},
+	}
+
+	// A zero-value offered RAV commits to no collection, the provider may
+	// propose one of another collection, the value cannot decrease anyway
+	var previous *horizon.SignedRAV
+	if valueOf(offered).Sign() > 0 {
+		previous = offered
+	}
+
+	if err := validator.Validate(previous, proposed, policy); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidProposedRAV, err)
+	}
+	return nil
+}
+
+// valueOf returns rav's value aggregate, zero when rav holds none
+func valueOf(rav *horizon.SignedRAV) *big.Int {
+	if rav == nil || rav.Message == nil || rav.Message.ValueAggregate == nil {
+		return big.NewInt(0)
+	}
+	return rav.Message.ValueAggregate
+}
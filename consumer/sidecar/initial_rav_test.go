@@ -0,0 +1,246 @@
+package sidecar
+
+import (
+	"context"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	consumerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/consumer/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1/providerv1connect"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// proposingGateway starts sessions proposing proposed as the RAV to use, or
+// the offered one when nil
+type proposingGateway struct {
+	providerv1connect.UnimplementedPaymentGatewayServiceHandler
+	proposed *horizon.SignedRAV
+}
+
+func (g *proposingGateway) StartSession(ctx context.Context, req *connect.Request[providerv1.StartSessionRequest]) (*connect.Response[providerv1.StartSessionResponse], error) {
+	useRAV := req.Msg.InitialRav
+	if g.proposed != nil {
+		useRAV = sidecar.HorizonSignedRAVToProto(g.proposed)
+	}
+	return connect.NewResponse(&providerv1.StartSessionResponse{
+		SessionId: "provider-session",
+		UseRav:    useRAV,
+		Accepted:  true,
+	}), nil
+}
+
+func TestInit_InitialRAVStrategy(t *testing.T) {
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	otherKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	domain := horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111"))
+	payer := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	dataService := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	serviceProvider := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+
+	newSidecar := func(strategy InitialRAVStrategy, budget *big.Int) *Sidecar {
+		return New(&Config{
+			ListenAddr:         ":0",
+			SignerKey:          signerKey,
+			Domain:             domain,
+			GlobalBudget:       budget,
+			InitialRAVStrategy: strategy,
+		}, zap.NewNop())
+	}
+
+	// Collection IDs travel in the metadata through the proto conversions
+	collection := func(id byte) (horizon.CollectionID, []byte) {
+		collectionID := horizon.CollectionID{id}
+		return collectionID, collectionID[:]
+	}
+
+	signed := func(key *eth.PrivateKey, value int64, mutate func(rav *horizon.RAV)) *horizon.SignedRAV {
+		collectionID, metadata := collection(1)
+		rav := &horizon.RAV{
+			CollectionID:    collectionID,
+			Metadata:        metadata,
+			Payer:           payer,
+			DataService:     dataService,
+			ServiceProvider: serviceProvider,
+			TimestampNs:     uint64(time.Now().UnixNano()),
+			ValueAggregate:  big.NewInt(value),
+		}
+		if mutate != nil {
+			mutate(rav)
+		}
+		out, err := (&keySigner{signer: horizon.NewLocalSigner(key)}).SignRAV(context.Background(), domain, rav)
+		require.NoError(t, err)
+		return out
+	}
+
+	initSession := func(s *Sidecar, endpoint string, existing *horizon.SignedRAV) (*consumerv1.InitResponse, error) {
+		resp, err := s.Init(context.Background(), connect.NewRequest(&consumerv1.InitRequest{
+			EscrowAccount: &commonv1.EscrowAccount{
+				Payer:       commonv1.AddressFromEth(payer),
+				Receiver:    commonv1.AddressFromEth(serviceProvider),
+				DataService: commonv1.AddressFromEth(dataService),
+			},
+			ProviderEndpoint: endpoint,
+			ExistingRav:      sidecar.HorizonSignedRAVToProto(existing),
+		}))
+		if err != nil {
+			return nil, err
+		}
+		return resp.Msg, nil
+	}
+
+	gateway := func(proposed *horizon.SignedRAV) string {
+		_, handler := providerv1connect.NewPaymentGatewayServiceHandler(&proposingGateway{proposed: proposed})
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+		return server.URL
+	}
+
+	paymentValue := func(resp *consumerv1.InitResponse) *big.Int {
+		return valueOf(sidecar.ProtoSignedRAVToHorizon(resp.PaymentRav))
+	}
+
+	t.Run("zero", func(t *testing.T) {
+		s := newSidecar(InitialRAVZero, nil)
+
+		resp, err := initSession(s, "", signed(signerKey, 100, nil))
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(100), paymentValue(resp))
+
+		resp, err = initSession(s, "", nil)
+		require.NoError(t, err)
+		assert.Equal(t, 0, paymentValue(resp).Sign())
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		s := newSidecar(InitialRAVResume, nil)
+
+		resp, err := initSession(s, "", nil)
+		require.NoError(t, err)
+		assert.Equal(t, 0, paymentValue(resp).Sign())
+
+		_, err = initSession(s, "", signed(signerKey, 100, nil))
+		require.NoError(t, err)
+
+		resp, err = initSession(s, "", nil)
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(100), paymentValue(resp))
+	})
+
+	t.Run("resume skips expired", func(t *testing.T) {
+		s := newSidecar(InitialRAVResume, nil)
+
+		_, err := initSession(s, "", signed(signerKey, 100, func(rav *horizon.RAV) {
+			rav.Metadata = horizon.EncodeRAVValidityMetadata(rav.CollectionID, time.Now().Add(-time.Minute))
+		}))
+		require.NoError(t, err)
+
+		resp, err := initSession(s, "", nil)
+		require.NoError(t, err)
+		assert.Equal(t, 0, paymentValue(resp).Sign())
+	})
+
+	t.Run("provider keeps offered RAV", func(t *testing.T) {
+		s := newSidecar(InitialRAVProvider, nil)
+
+		resp, err := initSession(s, gateway(nil), nil)
+		require.NoError(t, err)
+		assert.Equal(t, 0, paymentValue(resp).Sign())
+	})
+
+	t.Run("provider proposal accepted", func(t *testing.T) {
+		s := newSidecar(InitialRAVProvider, nil)
+
+		resp, err := initSession(s, gateway(signed(signerKey, 250, nil)), nil)
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(250), paymentValue(resp))
+	})
+
+	t.Run("provider proposal refused", func(t *testing.T) {
+		existing := signed(signerKey, 100, nil)
+
+		for name, proposed := range map[string]*horizon.SignedRAV{
+			"other signer": signed(otherKey, 250, nil),
+			"other payer":  signed(signerKey, 250, func(rav *horizon.RAV) { rav.Payer = eth.MustNewAddress("0x5555555555555555555555555555555555555555") }),
+			"other data service": signed(signerKey, 250, func(rav *horizon.RAV) {
+				rav.DataService = eth.MustNewAddress("0x5555555555555555555555555555555555555555")
+			}),
+			"other collection": signed(signerKey, 250, func(rav *horizon.RAV) { rav.CollectionID, rav.Metadata = collection(2) }),
+			"lower value":      signed(signerKey, 50, nil),
+			"older":            signed(signerKey, 250, func(rav *horizon.RAV) { rav.TimestampNs = existing.Message.TimestampNs - 1 }),
+		} {
+			t.Run(name, func(t *testing.T) {
+				s := newSidecar(InitialRAVProvider, nil)
+
+				_, err := initSession(s, gateway(proposed), existing)
+				assert.Equal(t, connect.CodeFailedPrecondition, connect.CodeOf(err))
+				assert.ErrorIs(t, err, ErrInvalidProposedRAV)
+			})
+		}
+	})
+
+	t.Run("provider proposal over budget", func(t *testing.T) {
+		s := newSidecar(InitialRAVProvider, big.NewInt(200))
+
+		_, err := initSession(s, gateway(signed(signerKey, 250, nil)), nil)
+		assert.ErrorIs(t, err, ErrBudgetExceeded)
+	})
+
+	t.Run("provider unreachable", func(t *testing.T) {
+		s := newSidecar(InitialRAVProvider, nil)
+
+		_, err := initSession(s, "", nil)
+		assert.Equal(t, connect.CodeFailedPrecondition, connect.CodeOf(err))
+	})
+}
+
+func TestParseInitialRAVStrategy(t *testing.T) {
+	strategy, err := ParseInitialRAVStrategy("provider")
+	require.NoError(t, err)
+	assert.Equal(t, InitialRAVProvider, strategy)
+
+	_, err = ParseInitialRAVStrategy("latest")
+	assert.Error(t, err)
+}
+
+func TestInit_RAVValidity(t *testing.T) {
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	s := New(&Config{
+		ListenAddr:  ":0",
+		SignerKey:   signerKey,
+		Domain:      horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+		RAVValidity: time.Hour,
+	}, zap.NewNop())
+
+	resp, err := s.Init(context.Background(), connect.NewRequest(&consumerv1.InitRequest{
+		EscrowAccount: &commonv1.EscrowAccount{
+			Payer:       commonv1.AddressFromEth(eth.MustNewAddress("0x2222222222222222222222222222222222222222")),
+			Receiver:    commonv1.AddressFromEth(eth.MustNewAddress("0x4444444444444444444444444444444444444444")),
+			DataService: commonv1.AddressFromEth(eth.MustNewAddress("0x3333333333333333333333333333333333333333")),
+		},
+	}))
+	require.NoError(t, err)
+
+	rav := sidecar.ProtoSignedRAVToHorizon(resp.Msg.PaymentRav)
+	validUntil, found := horizon.DecodeRAVValidityMetadata(rav.Message.Metadata)
+	require.True(t, found)
+	assert.Equal(t, time.Unix(0, int64(rav.Message.TimestampNs)).Add(time.Hour), validUntil)
+
+	recovered, err := rav.RecoverSigner(s.domain)
+	require.NoError(t, err)
+	assert.Equal(t, signerKey.PublicKey().Address(), recovered)
+}
@@ -0,0 +1,113 @@
+package sidecar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+)
+
+// offlineResponsePollInterval is how often the offline signing directory is
+// checked for the response to a pending request
+const offlineResponsePollInterval = 500 * time.Millisecond
+
+// ravSigner signs RAVs under an EIP-712 domain
+type ravSigner interface {
+	SignRAV(ctx context.Context, domain *horizon.Domain, rav *horizon.RAV) (*horizon.SignedRAV, error)
+}
+
+// keySigner signs RAVs right away with a horizon.Signer, the sidecar's own
+// private key or a remote signing backend
+type keySigner struct {
+	signer horizon.Signer
+}
+
+func (s *keySigner) SignRAV(ctx context.Context, domain *horizon.Domain, rav *horizon.RAV) (*horizon.SignedRAV, error) {
+	return horizon.SignWith(domain, rav, s.signer)
+}
+
+// receiptSigner signs receipts under an EIP-712 domain. Only signers holding
+// the key implement it, a receipt per usage report is not workable through the
+// offline signer.
+type receiptSigner interface {
+	SignReceipt(ctx context.Context, domain *horizon.Domain, receipt *horizon.Receipt) (*horizon.SignedReceipt, error)
+}
+
+func (s *keySigner) SignReceipt(ctx context.Context, domain *horizon.Domain, receipt *horizon.Receipt) (*horizon.SignedReceipt, error) {
+	return horizon.SignWith(domain, receipt, s.signer)
+}
+
+// offlineSigner queues RAV signing requests as files in a directory, signed by
+// the offline signer (sds-offline-signer) in an air-gapped environment. Each
+// request waits until the response file shows up or its context is done, in
+// which case the request file is withdrawn.
+type offlineSigner struct {
+	dir    string
+	signer eth.Address
+}
+
+func newOfflineSigner(dir string, signer eth.Address) *offlineSigner {
+	return &offlineSigner{dir: dir, signer: signer}
+}
+
+func (s *offlineSigner) SignRAV(ctx context.Context, domain *horizon.Domain, rav *horizon.RAV) (*horizon.SignedRAV, error) {
+	request, err := horizon.NewOfflineSigningRequest(domain, rav)
+	if err != nil {
+		return nil, err
+	}
+
+	requestPath := filepath.Join(s.dir, request.RequestFileName())
+	responsePath := filepath.Join(s.dir, request.ResponseFileName())
+	if err := writeFileAtomic(requestPath, request); err != nil {
+		return nil, fmt.Errorf("queuing offline signing request: %w", err)
+	}
+	defer os.Remove(requestPath)
+
+	ticker := time.NewTicker(offlineResponsePollInterval)
+	defer ticker.Stop()
+
+	for {
+		data, err := os.ReadFile(responsePath)
+		switch {
+		case err == nil:
+			os.Remove(responsePath)
+
+			var response horizon.OfflineSigningResponse
+			if err := json.Unmarshal(data, &response); err != nil {
+				return nil, fmt.Errorf("decoding offline signing response %s: %w", responsePath, err)
+			}
+			return request.Complete(&response, s.signer)
+
+		case !errors.Is(err, fs.ErrNotExist):
+			return nil, fmt.Errorf("reading offline signing response: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for offline signing response %s: %w", request.ID, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// writeFileAtomic writes v as JSON to path through a temporary file so readers
+// never see a partial file
+func writeFileAtomic(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
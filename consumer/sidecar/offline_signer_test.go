@@ -0,0 +1,97 @@
+package sidecar
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOfflineSigner_SignRAV(t *testing.T) {
+	dir := t.TempDir()
+	domain := horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111"))
+
+	key, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	rav := &horizon.RAV{
+		Payer:           key.PublicKey().Address(),
+		DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+		ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		TimestampNs:     1234567890,
+		ValueAggregate:  big.NewInt(1000),
+	}
+
+	// Stands for sds-offline-signer answering the queued request
+	go func() {
+		for {
+			entries, _ := os.ReadDir(dir)
+			for _, entry := range entries {
+				if !strings.HasSuffix(entry.Name(), horizon.OfflineRequestSuffix) {
+					continue
+				}
+
+				data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+				if err != nil {
+					continue
+				}
+				var request horizon.OfflineSigningRequest
+				if json.Unmarshal(data, &request) != nil {
+					continue
+				}
+				response, err := request.Sign(key)
+				if err != nil {
+					continue
+				}
+				data, _ = json.Marshal(response)
+				os.WriteFile(filepath.Join(dir, request.ResponseFileName()), data, 0o600)
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	signer := newOfflineSigner(dir, key.PublicKey().Address())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	signed, err := signer.SignRAV(ctx, domain, rav)
+	require.NoError(t, err)
+
+	recovered, err := signed.RecoverSigner(domain)
+	require.NoError(t, err)
+	assert.Equal(t, key.PublicKey().Address(), recovered)
+
+	// Request and response files are cleaned up
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestOfflineSigner_SignRAV_Cancelled(t *testing.T) {
+	dir := t.TempDir()
+	domain := horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111"))
+
+	signer := newOfflineSigner(dir, eth.MustNewAddress("0x4444444444444444444444444444444444444444"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := signer.SignRAV(ctx, domain, &horizon.RAV{ValueAggregate: big.NewInt(1)})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The withdrawn request is not left for the offline signer
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
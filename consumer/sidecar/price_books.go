@@ -0,0 +1,261 @@
+package sidecar
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"gopkg.in/yaml.v3"
+)
+
+// Reference usage the default scorer prices providers at: 1M blocks and 10GB
+// transferred, i.e. 2 GRT at the default provider pricing
+const (
+	ScoreReferenceBlocks = 1_000_000
+	ScoreReferenceBytes  = 10_000_000_000
+)
+
+// ProviderRecord is what the consumer sidecar knows about a service provider:
+// the price parameters negotiated with it and its track record
+type ProviderRecord struct {
+	ServiceProvider eth.Address
+	// Pricing holds the negotiated price parameters, nil when none were recorded
+	Pricing *sidecar.PricingConfig
+	// PricingUpdatedAt is when Pricing was last recorded
+	PricingUpdatedAt time.Time
+
+	// SessionsCompleted counts the sessions ended with a final RAV
+	SessionsCompleted uint64
+	// SessionsFailed counts the sessions reported as failed by the gateway
+	SessionsFailed uint64
+	// Disputes counts the disputes raised against the service provider
+	Disputes uint64
+}
+
+// ProviderScorer scores a service provider for session selection, the higher
+// the better. It is the hook a gateway customizes to weigh price, reliability
+// and past disputes its own way.
+type ProviderScorer func(record *ProviderRecord) float64
+
+// DefaultProviderScorer multiplies three factors in (0, 1]:
+//   - price: 1 / (1 + cost in GRT of the reference usage), providers without
+//     price book score 0 as they cannot be compared
+//   - reliability: (completed + 1) / (completed + failed + 2), so unknown
+//     providers start at 0.5
+//   - disputes: 1 / (1 + disputes)
+func DefaultProviderScorer(record *ProviderRecord) float64 {
+	if record.Pricing == nil {
+		return 0
+	}
+
+	cost, _ := new(big.Float).Quo(
+		new(big.Float).SetInt(record.Pricing.CalculateUsageCost(ScoreReferenceBlocks, ScoreReferenceBytes)),
+		new(big.Float).SetInt(weiPerGRT),
+	).Float64()
+
+	price := 1 / (1 + cost)
+	reliability := float64(record.SessionsCompleted+1) / float64(record.SessionsCompleted+record.SessionsFailed+2)
+	disputes := 1 / float64(1+record.Disputes)
+
+	return price * reliability * disputes
+}
+
+var weiPerGRT = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+
+// ProviderScore is the score of a candidate service provider
+type ProviderScore struct {
+	Record *ProviderRecord
+	Score  float64
+}
+
+// priceBooks holds a ProviderRecord per service provider
+type priceBooks struct {
+	mu        sync.RWMutex
+	providers map[string]*ProviderRecord
+	scorer    ProviderScorer
+}
+
+func newPriceBooks(scorer ProviderScorer) *priceBooks {
+	if scorer == nil {
+		scorer = DefaultProviderScorer
+	}
+
+	return &priceBooks{
+		providers: make(map[string]*ProviderRecord),
+		scorer:    scorer,
+	}
+}
+
+// recordLocked returns the record of serviceProvider, creating it when unknown
+func (b *priceBooks) recordLocked(serviceProvider eth.Address) *ProviderRecord {
+	record, found := b.providers[serviceProvider.Pretty()]
+	if !found {
+		record = &ProviderRecord{ServiceProvider: serviceProvider}
+		b.providers[serviceProvider.Pretty()] = record
+	}
+	return record
+}
+
+func (b *priceBooks) setPricing(serviceProvider eth.Address, pricing *sidecar.PricingConfig) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	record := b.recordLocked(serviceProvider)
+	record.Pricing = pricing
+	record.PricingUpdatedAt = time.Now()
+}
+
+func (b *priceBooks) recordSession(serviceProvider eth.Address, completed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	record := b.recordLocked(serviceProvider)
+	if completed {
+		record.SessionsCompleted++
+	} else {
+		record.SessionsFailed++
+	}
+}
+
+func (b *priceBooks) recordDispute(serviceProvider eth.Address) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.recordLocked(serviceProvider).Disputes++
+}
+
+// get returns a copy of the record of serviceProvider, an empty record when unknown
+func (b *priceBooks) get(serviceProvider eth.Address) *ProviderRecord {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	record, found := b.providers[serviceProvider.Pretty()]
+	if !found {
+		return &ProviderRecord{ServiceProvider: serviceProvider}
+	}
+	copied := *record
+	return &copied
+}
+
+// list returns a copy of every record, ordered by service provider address
+func (b *priceBooks) list() []*ProviderRecord {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	out := make([]*ProviderRecord, 0, len(b.providers))
+	for _, record := range b.providers {
+		copied := *record
+		out = append(out, &copied)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ServiceProvider.Pretty() < out[j].ServiceProvider.Pretty()
+	})
+	return out
+}
+
+// score ranks candidates best first, ties keep the candidates order
+func (b *priceBooks) score(candidates []eth.Address) []*ProviderScore {
+	out := make([]*ProviderScore, 0, len(candidates))
+	for _, candidate := range candidates {
+		record := b.get(candidate)
+
+		score := b.scorer(record)
+		if math.IsNaN(score) {
+			score = 0
+		}
+		out = append(out, &ProviderScore{Record: record, Score: score})
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Score > out[j].Score
+	})
+	return out
+}
+
+// SetProviderPricing records the price parameters negotiated with serviceProvider
+func (s *Sidecar) SetProviderPricing(serviceProvider eth.Address, pricing *sidecar.PricingConfig) {
+	s.priceBooks.setPricing(serviceProvider, pricing)
+}
+
+// RecordProviderFailure records a session with serviceProvider that failed,
+// e.g. a stream the gateway had to move to another provider
+func (s *Sidecar) RecordProviderFailure(serviceProvider eth.Address) {
+	s.priceBooks.recordSession(serviceProvider, false)
+}
+
+// RecordProviderDispute records a dispute raised against serviceProvider
+func (s *Sidecar) RecordProviderDispute(serviceProvider eth.Address) {
+	s.priceBooks.recordDispute(serviceProvider)
+}
+
+// ProviderRecords returns what is known about every service provider
+func (s *Sidecar) ProviderRecords() []*ProviderRecord {
+	return s.priceBooks.list()
+}
+
+// ScoreProviders ranks candidate service providers best first with the
+// configured ProviderScorer
+func (s *Sidecar) ScoreProviders(candidates []eth.Address) []*ProviderScore {
+	return s.priceBooks.score(candidates)
+}
+
+// LoadPriceBooks loads per service provider price parameters from a YAML file
+// mapping service provider addresses to pricing, in the provider sidecar
+// pricing configuration format:
+//
+//	"0xa6f1845e54b1d6a95319251f1ca775b4ad406cdf":
+//	  price_per_block: "0.000001"
+//	  price_per_byte: "0.0000000001"
+//
+// Service providers can also be referenced by any name resolver resolves,
+// which may be nil to only accept addresses.
+func LoadPriceBooks(ctx context.Context, path string, resolver *sidecar.AddressResolver) (map[string]*sidecar.PricingConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading price books: %w", err)
+	}
+
+	return ParsePriceBooksWithResolver(ctx, data, resolver)
+}
+
+// ParsePriceBooks parses per service provider price parameters from YAML
+// bytes, see LoadPriceBooks, the result is keyed by normalized address
+func ParsePriceBooks(data []byte) (map[string]*sidecar.PricingConfig, error) {
+	return ParsePriceBooksWithResolver(context.Background(), data, nil)
+}
+
+// ParsePriceBooksWithResolver is ParsePriceBooks with service providers
+// resolved by resolver
+func ParsePriceBooksWithResolver(ctx context.Context, data []byte, resolver *sidecar.AddressResolver) (map[string]*sidecar.PricingConfig, error) {
+	var raw map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing price books: %w", err)
+	}
+
+	out := make(map[string]*sidecar.PricingConfig, len(raw))
+	for addressHex, node := range raw {
+		address, err := resolver.Resolve(ctx, addressHex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid service provider address %q: %w", addressHex, err)
+		}
+
+		content, err := yaml.Marshal(&node)
+		if err != nil {
+			return nil, fmt.Errorf("price book of %s: %w", addressHex, err)
+		}
+
+		pricing, err := sidecar.ParsePricingConfig(content)
+		if err != nil {
+			return nil, fmt.Errorf("price book of %s: %w", addressHex, err)
+		}
+		out[address.Pretty()] = pricing
+	}
+	return out, nil
+}
@@ -0,0 +1,152 @@
+package sidecar
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	consumerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/consumer/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestParsePriceBooks(t *testing.T) {
+	books, err := ParsePriceBooks([]byte(`
+"0xA6F1845E54B1D6A95319251F1CA775B4AD406CDF":
+  price_per_block: "0.000001"
+  price_per_byte: "0.0000000001"
+`))
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+
+	pricing := books["0xa6f1845e54b1d6a95319251f1ca775b4ad406cdf"]
+	require.NotNil(t, pricing)
+	assert.Equal(t, "0.000001", pricing.PricePerBlock.ToDecimalString())
+	assert.Equal(t, "0.0000000001", pricing.PricePerByte.ToDecimalString())
+
+	_, err = ParsePriceBooks([]byte(`"not-an-address": {price_per_block: "1"}`))
+	assert.ErrorContains(t, err, "invalid service provider address")
+
+	_, err = ParsePriceBooks([]byte(`"0xa6f1845e54b1d6a95319251f1ca775b4ad406cdf": {price_per_block: "abc"}`))
+	assert.ErrorContains(t, err, "invalid price_per_block")
+}
+
+func TestScoreProviders(t *testing.T) {
+	cheap := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+	expensive := eth.MustNewAddress("0x5555555555555555555555555555555555555555")
+	unpriced := eth.MustNewAddress("0x6666666666666666666666666666666666666666")
+
+	s := newBudgetTestSidecar(t, nil)
+	s.SetProviderPricing(cheap, sidecar.DefaultPricingConfig())
+	expensivePricing, err := newPricingConfig("0.00001", "0.000000001")
+	require.NoError(t, err)
+	s.SetProviderPricing(expensive, expensivePricing)
+
+	ranked := func() []string {
+		var out []string
+		for _, score := range s.ScoreProviders([]eth.Address{unpriced, expensive, cheap}) {
+			out = append(out, score.Record.ServiceProvider.Pretty())
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{cheap.Pretty(), expensive.Pretty(), unpriced.Pretty()}, ranked())
+
+	// Completed sessions are recorded on EndSession
+	sessionID := initBudgetTestSession(t, s, expensive)
+	_, err = s.EndSession(context.Background(), connect.NewRequest(&consumerv1.EndSessionRequest{SessionId: sessionID}))
+	require.NoError(t, err)
+
+	records := s.ProviderRecords()
+	require.Len(t, records, 2)
+	assert.Equal(t, cheap.Pretty(), records[0].ServiceProvider.Pretty())
+	assert.Equal(t, uint64(1), records[1].SessionsCompleted)
+
+	// Failures and disputes push the cheap provider behind
+	for range 4 {
+		s.RecordProviderFailure(cheap)
+	}
+	s.RecordProviderDispute(cheap)
+	assert.Equal(t, []string{expensive.Pretty(), cheap.Pretty(), unpriced.Pretty()}, ranked())
+}
+
+func TestScoreProviders_CustomScorer(t *testing.T) {
+	first := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+	second := eth.MustNewAddress("0x5555555555555555555555555555555555555555")
+
+	s := New(&Config{
+		ListenAddr: ":0",
+		Domain:     horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+		ProviderScorer: func(record *ProviderRecord) float64 {
+			return float64(record.SessionsCompleted)
+		},
+		PriceBooks: map[string]*sidecar.PricingConfig{first.Pretty(): sidecar.DefaultPricingConfig()},
+	}, zap.NewNop())
+
+	s.priceBooks.recordSession(second, true)
+
+	scores := s.ScoreProviders([]eth.Address{first, second})
+	require.Len(t, scores, 2)
+	assert.Equal(t, second.Pretty(), scores[0].Record.ServiceProvider.Pretty())
+	assert.Equal(t, 1.0, scores[0].Score)
+	assert.NotNil(t, scores[1].Record.Pricing)
+}
+
+func TestPriceBooks_AdminAPI(t *testing.T) {
+	s := newBudgetTestSidecar(t, nil)
+
+	serve := func(method, path, body string) (int, string) {
+		rec := httptest.NewRecorder()
+		s.admin.Handler().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec.Code, rec.Body.String()
+	}
+	decode := func(body string) []*ProviderRecordResponse {
+		var out struct {
+			Providers []*ProviderRecordResponse `json:"providers"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(body), &out))
+		return out.Providers
+	}
+
+	code, body := serve(http.MethodPut, "/v1/providers/0x4444444444444444444444444444444444444444/pricing", `{"price_per_block":"0.000001","price_per_byte":"0.0000000001"}`)
+	require.Equal(t, http.StatusOK, code, body)
+	var record ProviderRecordResponse
+	require.NoError(t, json.Unmarshal([]byte(body), &record))
+	assert.Equal(t, "0.000001", record.PricePerBlock)
+	assert.NotNil(t, record.PricingUpdatedAt)
+
+	code, _ = serve(http.MethodPost, "/v1/providers/0x5555555555555555555555555555555555555555/failures", "")
+	require.Equal(t, http.StatusOK, code)
+	code, _ = serve(http.MethodPost, "/v1/providers/0x5555555555555555555555555555555555555555/disputes", "")
+	require.Equal(t, http.StatusOK, code)
+
+	code, body = serve(http.MethodGet, "/v1/providers", "")
+	require.Equal(t, http.StatusOK, code)
+	providers := decode(body)
+	require.Len(t, providers, 2)
+	assert.Equal(t, uint64(1), providers[1].SessionsFailed)
+	assert.Equal(t, uint64(1), providers[1].Disputes)
+	assert.Empty(t, providers[1].PricePerBlock)
+
+	code, body = serve(http.MethodPost, "/v1/providers/score", `{"service_providers":["0x5555555555555555555555555555555555555555","0x4444444444444444444444444444444444444444"]}`)
+	require.Equal(t, http.StatusOK, code)
+	providers = decode(body)
+	require.Len(t, providers, 2)
+	assert.Equal(t, "0x4444444444444444444444444444444444444444", providers[0].ServiceProvider)
+	require.NotNil(t, providers[0].Score)
+	assert.Greater(t, *providers[0].Score, 0.0)
+
+	code, _ = serve(http.MethodPut, "/v1/providers/0x4444444444444444444444444444444444444444/pricing", `{"price_per_block":"abc"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = serve(http.MethodPost, "/v1/providers/score", `{"service_providers":["not-an-address"]}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
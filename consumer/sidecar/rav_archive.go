@@ -0,0 +1,242 @@
+package sidecar
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+)
+
+// Archive files hold the RAVs signed during one UTC day as JSON lines, named
+// rav-archive-YYYY-MM-DD.jsonl
+const (
+	archiveFilePrefix = "rav-archive-"
+	archiveFileSuffix = ".jsonl"
+	archiveDayLayout  = "2006-01-02"
+)
+
+// ArchivedRAVSchema versions the JSON encoding of archive entries, their RAV
+// being encoded canonically since version 2
+var ArchivedRAVSchema = horizon.NewSchema("archived RAV", "schemaVersion", 2)
+
+func init() {
+	ArchivedRAVSchema.RegisterMigration(1, horizon.CanonicalJSONMigration)
+}
+
+// ArchivedRAV is a RAV signed by the consumer sidecar as kept in the archive,
+// along with the domain it was signed under so it can be verified later
+type ArchivedRAV struct {
+	// SchemaVersion is the ArchivedRAVSchema version of the entry, set when recorded
+	SchemaVersion int `json:"schemaVersion"`
+
+	SignedAt  time.Time       `json:"signedAt"`
+	Final     bool            `json:"final"`
+	Domain    *horizon.Domain `json:"domain"`
+	RAV       *horizon.RAV    `json:"rav"`
+	Signature eth.Hex         `json:"signature"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, entries of older schema versions
+// are migrated to the current one
+func (a *ArchivedRAV) UnmarshalJSON(data []byte) error {
+	type plain ArchivedRAV
+	return ArchivedRAVSchema.Unmarshal(data, (*plain)(a))
+}
+
+// SignedRAV returns the archived RAV along with its signature
+func (a *ArchivedRAV) SignedRAV() *horizon.SignedRAV {
+	signedRAV := &horizon.SignedRAV{Message: a.RAV}
+	copy(signedRAV.Signature[:], a.Signature)
+	return signedRAV
+}
+
+// ravArchive appends every signed RAV to daily files of dir, giving payers an
+// audit trail of what they promised to pay independent of provider records.
+// Files of days older than retention are removed when a new day starts, they
+// are kept forever when retention is zero.
+type ravArchive struct {
+	dir       string
+	retention time.Duration
+
+	mu   sync.Mutex
+	day  string
+	file *os.File
+}
+
+func newRAVArchive(dir string, retention time.Duration) *ravArchive {
+	return &ravArchive{dir: dir, retention: retention}
+}
+
+// record appends entry to the file of the day it was signed, synced to disk
+// before returning
+func (a *ravArchive) record(entry *ArchivedRAV) error {
+	entry.SchemaVersion = ArchivedRAVSchema.Version()
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encoding archived RAV: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	day := entry.SignedAt.UTC().Format(archiveDayLayout)
+	if a.file == nil || day != a.day {
+		if err := a.rotate(day, entry.SignedAt); err != nil {
+			return err
+		}
+	}
+
+	if _, err := a.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("writing RAV archive: %w", err)
+	}
+	return a.file.Sync()
+}
+
+// rotate switches to the file of day and removes expired files, a.mu is held
+func (a *ravArchive) rotate(day string, now time.Time) error {
+	if err := os.MkdirAll(a.dir, 0o700); err != nil {
+		return fmt.Errorf("creating RAV archive directory: %w", err)
+	}
+
+	file, err := os.OpenFile(filepath.Join(a.dir, archiveFilePrefix+day+archiveFileSuffix), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening RAV archive: %w", err)
+	}
+	if a.file != nil {
+		a.file.Close()
+	}
+	a.file, a.day = file, day
+
+	return a.prune(now)
+}
+
+// prune removes the files of days entirely older than the retention
+func (a *ravArchive) prune(now time.Time) error {
+	if a.retention <= 0 {
+		return nil
+	}
+
+	days, err := archiveDays(a.dir)
+	if err != nil {
+		return err
+	}
+
+	cutoff := now.Add(-a.retention)
+	for day, path := range days {
+		if day.AddDate(0, 0, 1).Before(cutoff) {
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("removing expired RAV archive: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (a *ravArchive) close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.file != nil {
+		a.file.Close()
+		a.file = nil
+	}
+}
+
+// ReadRAVArchive returns the RAVs archived in dir signed within [since, until),
+// in signing order. Zero bounds are open.
+func ReadRAVArchive(dir string, since, until time.Time) ([]*ArchivedRAV, error) {
+	days, err := archiveDays(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ordered := make([]time.Time, 0, len(days))
+	for day := range days {
+		ordered = append(ordered, day)
+	}
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].Before(ordered[j]) })
+
+	var out []*ArchivedRAV
+	for _, day := range ordered {
+		if (!since.IsZero() && day.AddDate(0, 0, 1).Before(since)) || (!until.IsZero() && !day.Before(until)) {
+			continue
+		}
+
+		entries, err := readArchiveFile(days[day])
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if (since.IsZero() || !entry.SignedAt.Before(since)) && (until.IsZero() || entry.SignedAt.Before(until)) {
+				out = append(out, entry)
+			}
+		}
+	}
+	return out, nil
+}
+
+func readArchiveFile(path string) ([]*ArchivedRAV, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening RAV archive: %w", err)
+	}
+	defer file.Close()
+
+	var out []*ArchivedRAV
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry ArchivedRAV
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("decoding %s line %d: %w", filepath.Base(path), line, err)
+		}
+		out = append(out, &entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", filepath.Base(path), err)
+	}
+	return out, nil
+}
+
+// archiveDays lists the archive files of dir by the UTC day they cover
+func archiveDays(dir string) (map[time.Time]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("listing RAV archive: %w", err)
+	}
+
+	days := make(map[time.Time]string)
+	for _, entry := range entries {
+		name, found := strings.CutPrefix(entry.Name(), archiveFilePrefix)
+		if !found || entry.IsDir() {
+			continue
+		}
+		name, found = strings.CutSuffix(name, archiveFileSuffix)
+		if !found {
+			continue
+		}
+
+		day, err := time.Parse(archiveDayLayout, name)
+		if err != nil {
+			continue
+		}
+		days[day] = filepath.Join(dir, entry.Name())
+	}
+	return days, nil
+}
@@ -0,0 +1,104 @@
+package sidecar
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRAVArchive(t *testing.T) {
+	dir := t.TempDir()
+	archive := newRAVArchive(dir, 48*time.Hour)
+	defer archive.close()
+
+	day := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	entry := func(at time.Time, value int64) *ArchivedRAV {
+		return &ArchivedRAV{
+			SignedAt:  at,
+			Domain:    horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+			RAV:       &horizon.RAV{ValueAggregate: big.NewInt(value)},
+			Signature: make(eth.Hex, 65),
+		}
+	}
+
+	require.NoError(t, archive.record(entry(day, 1)))
+	require.NoError(t, archive.record(entry(day.Add(time.Hour), 2)))
+	require.NoError(t, archive.record(entry(day.AddDate(0, 0, 1), 3)))
+
+	values := func(entries []*ArchivedRAV) (out []int64) {
+		for _, entry := range entries {
+			out = append(out, entry.RAV.ValueAggregate.Int64())
+		}
+		return out
+	}
+
+	entries, err := ReadRAVArchive(dir, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, values(entries))
+
+	entries, err = ReadRAVArchive(dir, day.Add(time.Minute), day.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2}, values(entries))
+
+	// Three days later, the first day is entirely past the retention
+	require.NoError(t, archive.record(entry(day.AddDate(0, 0, 3), 4)))
+
+	files, err := filepath.Glob(filepath.Join(dir, "rav-archive-*.jsonl"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "rav-archive-2025-03-11.jsonl"),
+		filepath.Join(dir, "rav-archive-2025-03-13.jsonl"),
+	}, files)
+
+	entries, err = ReadRAVArchive(filepath.Join(dir, "missing"), time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestSignRAV_Archived(t *testing.T) {
+	key, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	domain := horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111"))
+	s := New(&Config{ListenAddr: ":0", SignerKey: key, Domain: domain, ArchiveDir: dir}, zap.NewNop())
+	defer s.archive.close()
+
+	signedRAV, err := s.signRAV(context.Background(), SigningPriorityFinal, nil, horizon.CollectionID{0xaa},
+		eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+		eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		eth.MustNewAddress("0x4444444444444444444444444444444444444444"),
+		1, big.NewInt(100), nil)
+	require.NoError(t, err)
+
+	entries, err := ReadRAVArchive(dir, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.True(t, entries[0].Final)
+	assert.Equal(t, signedRAV, entries[0].SignedRAV())
+
+	signer, err := entries[0].SignedRAV().RecoverSigner(entries[0].Domain)
+	require.NoError(t, err)
+	assert.Equal(t, key.PublicKey().Address(), signer)
+
+	// A RAV that cannot be archived is not handed out
+	s.archive.close()
+	require.NoError(t, os.RemoveAll(dir))
+	require.NoError(t, os.WriteFile(dir, nil, 0o600))
+
+	_, err = s.signRAV(context.Background(), SigningPriorityNormal, nil, horizon.CollectionID{0xaa},
+		eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+		eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		eth.MustNewAddress("0x4444444444444444444444444444444444444444"),
+		1, big.NewInt(200), nil)
+	assert.ErrorContains(t, err, "archiving signed RAV")
+}
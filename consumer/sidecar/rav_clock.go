@@ -0,0 +1,105 @@
+package sidecar
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ravClock hands out strictly increasing RAV timestamps. A RAV is stamped
+// with max(requested, last+1), last being the latest timestamp handed out, so
+// a system clock going backwards (e.g. a VM snapshot restored) never yields a
+// RAV older than one already signed, which providers would reject. With a
+// path, the last timestamp is persisted to it and survives restarts, it is
+// tracked in memory only otherwise.
+type ravClock struct {
+	path string
+
+	mu     sync.Mutex
+	loaded bool
+	last   uint64
+}
+
+func newRAVClock(path string) *ravClock {
+	return &ravClock{path: path}
+}
+
+// stamp returns the timestamp to sign a RAV requested at timestampNs with,
+// recording it as the last one handed out. repaired is true when timestampNs
+// was not after the last timestamp.
+func (c *ravClock) stamp(timestampNs uint64) (stamped uint64, repaired bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.loaded {
+		if c.last, err = c.load(); err != nil {
+			return 0, false, err
+		}
+		c.loaded = true
+	}
+
+	stamped = timestampNs
+	if stamped <= c.last {
+		stamped, repaired = c.last+1, true
+	}
+
+	if err := c.save(stamped); err != nil {
+		return 0, false, err
+	}
+	c.last = stamped
+	return stamped, repaired, nil
+}
+
+// load reads the last timestamp persisted, 0 when none was
+func (c *ravClock) load() (uint64, error) {
+	if c.path == "" {
+		return 0, nil
+	}
+
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("reading last RAV timestamp: %w", err)
+	}
+
+	last, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing last RAV timestamp of %s: %w", c.path, err)
+	}
+	return last, nil
+}
+
+// save persists timestampNs through a temporary file renamed over the
+// previous one, a crash never leaves a partially written timestamp
+func (c *ravClock) save(timestampNs uint64) error {
+	if c.path == "" {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("persisting last RAV timestamp: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.WriteString(strconv.FormatUint(timestampNs, 10) + "\n")
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path)
+	}
+	if err != nil {
+		return fmt.Errorf("persisting last RAV timestamp: %w", err)
+	}
+	return nil
+}
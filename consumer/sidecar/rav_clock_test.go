@@ -0,0 +1,87 @@
+package sidecar
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRAVClock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rav-clock")
+	clock := newRAVClock(path)
+
+	stamped, repaired, err := clock.stamp(1000)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1000), stamped)
+	assert.False(t, repaired)
+
+	// The system clock went backwards, or did not move
+	stamped, repaired, err = clock.stamp(500)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1001), stamped)
+	assert.True(t, repaired)
+
+	stamped, _, err = clock.stamp(1001)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1002), stamped)
+
+	// The last timestamp survives a restart
+	stamped, repaired, err = newRAVClock(path).stamp(900)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1003), stamped)
+	assert.True(t, repaired)
+
+	stamped, repaired, err = newRAVClock(path).stamp(2000)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2000), stamped)
+	assert.False(t, repaired)
+
+	// Without a path, the last timestamp is only kept in memory
+	memory := newRAVClock("")
+	_, _, err = memory.stamp(1000)
+	require.NoError(t, err)
+	stamped, _, err = memory.stamp(1000)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1001), stamped)
+
+	require.NoError(t, os.WriteFile(path, []byte("garbage"), 0o600))
+	_, _, err = newRAVClock(path).stamp(1000)
+	assert.Error(t, err)
+}
+
+func TestSignRAV_MonotonicTimestamps(t *testing.T) {
+	key, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	domain := horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111"))
+	s := New(&Config{ListenAddr: ":0", SignerKey: key, Domain: domain, RAVClockPath: filepath.Join(t.TempDir(), "rav-clock")}, zap.NewNop())
+
+	sign := func(at time.Time) *horizon.SignedRAV {
+		signedRAV, err := s.signRAV(context.Background(), SigningPriorityNormal, nil, horizon.CollectionID{0xaa},
+			key.PublicKey().Address(), eth.MustNewAddress("0x2222222222222222222222222222222222222222"), eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+			uint64(at.UnixNano()), big.NewInt(100), nil)
+		require.NoError(t, err)
+		return signedRAV
+	}
+
+	now := time.Now()
+	first := sign(now)
+	assert.Equal(t, uint64(now.UnixNano()), first.Message.TimestampNs)
+
+	// A VM snapshot restored an hour back
+	second := sign(now.Add(-time.Hour))
+	assert.Equal(t, first.Message.TimestampNs+1, second.Message.TimestampNs)
+
+	signer, err := second.RecoverSigner(domain)
+	require.NoError(t, err)
+	assert.Equal(t, key.PublicKey().Address(), signer)
+}
@@ -0,0 +1,206 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	consumerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/consumer/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"go.uber.org/zap"
+)
+
+var ErrReceiptsUnsupported = errors.New("receipts payment mode requires an online signer (signer key or remote signer)")
+
+// ParsePaymentMode parses one of "rav" or "receipts"
+func ParsePaymentMode(in string) (commonv1.PaymentMode, error) {
+	switch in {
+	case "rav":
+		return commonv1.PaymentMode_PAYMENT_MODE_RAV, nil
+	case "receipts":
+		return commonv1.PaymentMode_PAYMENT_MODE_RECEIPTS, nil
+	}
+	return commonv1.PaymentMode_PAYMENT_MODE_UNSPECIFIED, fmt.Errorf("unknown payment mode %q, expected one of \"rav\" or \"receipts\"", in)
+}
+
+// resolvePaymentMode returns the payment mode of a session initialized with
+// the requested mode, the sidecar's default when unspecified
+func (s *Sidecar) resolvePaymentMode(requested commonv1.PaymentMode) (commonv1.PaymentMode, error) {
+	mode := requested
+	if mode == commonv1.PaymentMode_PAYMENT_MODE_UNSPECIFIED {
+		mode = s.paymentMode
+	}
+
+	switch mode {
+	case commonv1.PaymentMode_PAYMENT_MODE_UNSPECIFIED, commonv1.PaymentMode_PAYMENT_MODE_RAV:
+		return commonv1.PaymentMode_PAYMENT_MODE_RAV, nil
+	case commonv1.PaymentMode_PAYMENT_MODE_RECEIPTS:
+		if _, ok := s.signer.(receiptSigner); !ok {
+			return mode, connect.NewError(connect.CodeFailedPrecondition, ErrReceiptsUnsupported)
+		}
+		return mode, nil
+	default:
+		return mode, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("unknown payment mode %s", mode))
+	}
+}
+
+// authorizedValue returns the value authorized to the service provider for
+// session: the value aggregate of its current RAV plus, in receipts payment
+// mode, the value of the receipts signed on top of it
+func authorizedValue(session *sidecar.Session) *big.Int {
+	value := big.NewInt(0)
+	if rav := session.GetRAV(); rav != nil && rav.Message != nil && rav.Message.ValueAggregate != nil {
+		value.Set(rav.Message.ValueAggregate)
+	}
+	if session.PaysWithReceipts() {
+		value.Add(value, session.GetReceiptsValue())
+	}
+	return value
+}
+
+// signReceipt signs a receipt of value for the usage of session, under the
+// collection of the session's current RAV. The receipt is accounted for in the
+// session once signed, callers authorize the spend beforehand.
+func (s *Sidecar) signReceipt(ctx context.Context, priority SigningPriority, session *sidecar.Session, value *big.Int) (*horizon.SignedReceipt, error) {
+	signer, ok := s.signer.(receiptSigner)
+	if !ok {
+		return nil, ErrReceiptsUnsupported
+	}
+	if err := s.budgets.checkFrozen(); err != nil {
+		return nil, err
+	}
+
+	collector, err := s.resolveCollector(session.Collector)
+	if err != nil {
+		return nil, err
+	}
+	domain := s.collectorDomains[collector.Pretty()]
+
+	var collectionID horizon.CollectionID
+	if rav := session.GetRAV(); rav != nil && rav.Message != nil {
+		collectionID = rav.Message.CollectionID
+	}
+	receipt := horizon.NewReceipt(collectionID, session.Payer, session.DataService, session.Receiver, value)
+
+	var signedReceipt *horizon.SignedReceipt
+	err = s.signingQueue.Do(ctx, session.Receiver.Pretty(), priority, func() (err error) {
+		signedReceipt, err = signer.SignReceipt(ctx, domain, receipt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	session.AddReceiptValue(value)
+	return signedReceipt, nil
+}
+
+// reportUsageWithReceipt pays for usage of cost reported for a session in
+// receipts payment mode with a receipt of that value, no receipt is signed for
+// usage free of charge
+func (s *Sidecar) reportUsageWithReceipt(ctx context.Context, session *sidecar.Session, cost *big.Int) (*connect.Response[consumerv1.ReportUsageResponse], error) {
+	response := &consumerv1.ReportUsageResponse{ShouldContinue: true}
+	if cost.Sign() <= 0 {
+		s.persistSession(session)
+		return connect.NewResponse(response), nil
+	}
+
+	previousValue := authorizedValue(session)
+	newValue := new(big.Int).Add(previousValue, cost)
+	if err := s.identities.authorize(session.ID, newValue); err != nil {
+		s.logger.Warn("refusing to sign receipt", zap.String("session_id", session.ID), zap.Error(err))
+		s.notifySigningRefused(session, err)
+		return nil, signingError(err)
+	}
+	if err := s.authorizeSpend(session, previousValue, newValue); err != nil {
+		return s.refuseSpend(session, err)
+	}
+
+	receipt, err := s.signReceipt(ctx, SigningPriorityNormal, session, cost)
+	if err != nil {
+		s.logger.Error("failed to sign receipt", zap.Error(err))
+		s.notifySigningRefused(session, err)
+		return nil, signingError(err)
+	}
+	s.observeSpend(session)
+	s.persistSession(session)
+
+	response.Receipt = sidecar.HorizonSignedReceiptToProto(receipt)
+	return connect.NewResponse(response), nil
+}
+
+// endSessionWithReceipt ends a session in receipts payment mode, paying for
+// its final usage with a receipt
+func (s *Sidecar) endSessionWithReceipt(ctx context.Context, session *sidecar.Session, finalUsage *commonv1.Usage) (*connect.Response[consumerv1.EndSessionResponse], error) {
+	var finalReceipt *horizon.SignedReceipt
+	if finalUsage.GetCost() != nil && finalUsage.Cost.ToNative().Sign() > 0 {
+		cost := finalUsage.Cost.ToNative()
+		previousValue := authorizedValue(session)
+		newValue := new(big.Int).Add(previousValue, cost)
+		if err := s.identities.authorize(session.ID, newValue); err != nil {
+			s.logger.Warn("refusing to sign final receipt", zap.String("session_id", session.ID), zap.Error(err))
+			s.notifySigningRefused(session, err)
+			return nil, signingError(err)
+		}
+		if err := s.authorizeSpend(session, previousValue, newValue); err != nil {
+			s.logger.Warn("refusing to sign final receipt", zap.String("session_id", session.ID), zap.Error(err))
+			s.notifySigningRefused(session, err)
+			return nil, signingError(err)
+		}
+
+		var err error
+		finalReceipt, err = s.signReceipt(ctx, SigningPriorityFinal, session, cost)
+		if err != nil {
+			s.logger.Error("failed to sign final receipt", zap.Error(err))
+			s.notifySigningRefused(session, err)
+			return nil, signingError(err)
+		}
+		s.observeSpend(session)
+	}
+
+	session.End(commonv1.EndReason_END_REASON_COMPLETE)
+	s.identities.forget(session.ID)
+	s.priceBooks.recordSession(session.Receiver, true)
+	s.settle(session)
+	s.persistSession(session)
+
+	totalUsage := session.GetUsage()
+	s.logger.Info("EndSession completed",
+		zap.String("session_id", session.ID),
+		zap.Uint64("total_blocks", totalUsage.BlocksProcessed),
+		zap.Uint64("total_bytes", totalUsage.BytesTransferred),
+		zap.Stringer("receipts_value", session.GetReceiptsValue()),
+	)
+
+	return connect.NewResponse(&consumerv1.EndSessionResponse{
+		FinalRav:     sidecar.HorizonSignedRAVToProto(session.GetRAV()),
+		TotalUsage:   totalUsage,
+		FinalReceipt: sidecar.HorizonSignedReceiptToProto(finalReceipt),
+		Settlement:   settlementToProto(session),
+	}), nil
+}
+
+// refuseSpend answers a usage report whose payment authorizeSpend refused with
+// err, telling the stream to stop when a budget is exceeded
+func (s *Sidecar) refuseSpend(session *sidecar.Session, err error) (*connect.Response[consumerv1.ReportUsageResponse], error) {
+	s.notifySigningRefused(session, err)
+	if !errors.Is(err, ErrBudgetExceeded) {
+		return nil, signingError(err)
+	}
+
+	s.logger.Warn("budget exceeded, stopping session", zap.String("session_id", session.ID), zap.Error(err))
+	response := &consumerv1.ReportUsageResponse{
+		ShouldContinue: false,
+		StopReason:     err.Error(),
+		StopCode:       commonv1.RejectionCode_REJECTION_CODE_BUDGET_EXCEEDED,
+	}
+	var violation *BudgetViolation
+	if errors.As(err, &violation) {
+		response.BudgetViolation = violation.Proto()
+	}
+	return connect.NewResponse(response), nil
+}
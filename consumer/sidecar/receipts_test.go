@@ -0,0 +1,129 @@
+package sidecar
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	consumerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/consumer/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func receiptsTestEscrowAccount() *commonv1.EscrowAccount {
+	return &commonv1.EscrowAccount{
+		Payer:       commonv1.AddressFromEth(eth.MustNewAddress("0x2222222222222222222222222222222222222222")),
+		Receiver:    commonv1.AddressFromEth(eth.MustNewAddress("0x4444444444444444444444444444444444444444")),
+		DataService: commonv1.AddressFromEth(eth.MustNewAddress("0x3333333333333333333333333333333333333333")),
+	}
+}
+
+func initReceiptsTestSession(t *testing.T, s *Sidecar) *consumerv1.InitResponse {
+	t.Helper()
+
+	resp, err := s.Init(context.Background(), connect.NewRequest(&consumerv1.InitRequest{
+		EscrowAccount: receiptsTestEscrowAccount(),
+		PaymentMode:   commonv1.PaymentMode_PAYMENT_MODE_RECEIPTS,
+	}))
+	require.NoError(t, err)
+	return resp.Msg
+}
+
+func TestReportUsage_Receipts(t *testing.T) {
+	s := newBudgetTestSidecar(t, big.NewInt(100))
+	initResp := initReceiptsTestSession(t, s)
+	sessionID := initResp.Session.SessionId
+	assert.Equal(t, commonv1.PaymentMode_PAYMENT_MODE_RECEIPTS, initResp.PaymentMode)
+
+	resp, err := reportCost(s, sessionID, 60)
+	require.NoError(t, err)
+	assert.True(t, resp.ShouldContinue)
+	assert.Nil(t, resp.UpdatedRav)
+	require.NotNil(t, resp.Receipt)
+
+	receipt := sidecar.ProtoSignedReceiptToHorizon(resp.Receipt)
+	require.NotNil(t, receipt)
+	assert.Equal(t, "60", receipt.Message.Value.String())
+	signer, err := receipt.RecoverSigner(s.domain)
+	require.NoError(t, err)
+	assert.Equal(t, s.signerAddress.Pretty(), signer.Pretty())
+
+	// Usage free of charge is not paid with a receipt
+	resp, err = reportCost(s, sessionID, 0)
+	require.NoError(t, err)
+	assert.True(t, resp.ShouldContinue)
+	assert.Nil(t, resp.Receipt)
+
+	// Receipts count against the budgets as RAVs do
+	resp, err = reportCost(s, sessionID, 41)
+	require.NoError(t, err)
+	assert.False(t, resp.ShouldContinue)
+	assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_BUDGET_EXCEEDED, resp.StopCode)
+	assert.Nil(t, resp.Receipt)
+	assert.Equal(t, "60", s.BudgetStatus().GlobalSpent.String())
+
+	// The session RAV is left as initialized, the provider aggregates receipts
+	end, err := s.EndSession(context.Background(), connect.NewRequest(&consumerv1.EndSessionRequest{
+		SessionId:  sessionID,
+		FinalUsage: &commonv1.Usage{BlocksProcessed: 1, Cost: commonv1.BigIntFromNative(big.NewInt(40))},
+	}))
+	require.NoError(t, err)
+	require.NotNil(t, end.Msg.FinalReceipt)
+	assert.Equal(t, "40", end.Msg.FinalReceipt.Receipt.Value.ToNative().String())
+	assert.Equal(t, initResp.PaymentRav.Signature, end.Msg.FinalRav.Signature)
+
+	session, err := s.GetSession(context.Background(), connect.NewRequest(&consumerv1.GetSessionRequest{SessionId: sessionID}))
+	require.NoError(t, err)
+	assert.False(t, session.Msg.Session.Active)
+	assert.Equal(t, "100", session.Msg.Session.ReceiptsValue.ToNative().String())
+}
+
+func TestInit_PaymentMode(t *testing.T) {
+	s := newBudgetTestSidecar(t, nil)
+	s.paymentMode = commonv1.PaymentMode_PAYMENT_MODE_RECEIPTS
+
+	resp, err := s.Init(context.Background(), connect.NewRequest(&consumerv1.InitRequest{
+		EscrowAccount: receiptsTestEscrowAccount(),
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, commonv1.PaymentMode_PAYMENT_MODE_RECEIPTS, resp.Msg.PaymentMode)
+
+	_, err = s.Init(context.Background(), connect.NewRequest(&consumerv1.InitRequest{
+		EscrowAccount: receiptsTestEscrowAccount(),
+		PaymentMode:   commonv1.PaymentMode(42),
+	}))
+	assert.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
+
+	// The offline signer cannot sign a receipt per usage report
+	offline := New(&Config{
+		ListenAddr:        ":0",
+		Domain:            horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+		OfflineSigningDir: t.TempDir(),
+		SignerAddress:     eth.MustNewAddress("0x5555555555555555555555555555555555555555"),
+		PaymentMode:       commonv1.PaymentMode_PAYMENT_MODE_RECEIPTS,
+	}, zap.NewNop())
+	_, err = offline.Init(context.Background(), connect.NewRequest(&consumerv1.InitRequest{
+		EscrowAccount: receiptsTestEscrowAccount(),
+	}))
+	assert.Equal(t, connect.CodeFailedPrecondition, connect.CodeOf(err))
+	assert.ErrorIs(t, err, ErrReceiptsUnsupported)
+}
+
+func TestParsePaymentMode(t *testing.T) {
+	mode, err := ParsePaymentMode("rav")
+	require.NoError(t, err)
+	assert.Equal(t, commonv1.PaymentMode_PAYMENT_MODE_RAV, mode)
+
+	mode, err = ParsePaymentMode("receipts")
+	require.NoError(t, err)
+	assert.Equal(t, commonv1.PaymentMode_PAYMENT_MODE_RECEIPTS, mode)
+
+	_, err = ParsePaymentMode("vouchers")
+	assert.Error(t, err)
+}
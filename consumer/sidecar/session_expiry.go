@@ -0,0 +1,59 @@
+package sidecar
+
+import (
+	"context"
+	"time"
+
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	"go.uber.org/zap"
+)
+
+// maxSessionExpiryInterval bounds how often idle sessions are looked for
+const maxSessionExpiryInterval = time.Minute
+
+// expireIdleSessions ends the active sessions without activity for
+// s.sessionIdleTimeout as of now, e.g. abandoned by a client that crashed
+// without calling EndSession. No final RAV is signed for them, the provider
+// holds the last one signed. Returns the number of sessions expired.
+func (s *Sidecar) expireIdleSessions(now time.Time) int {
+	expired := 0
+	for _, session := range s.sessions.GetActive() {
+		idle := now.Sub(session.LastActivity())
+		if idle < s.sessionIdleTimeout {
+			continue
+		}
+
+		session.End(commonv1.EndReason_END_REASON_CLIENT_DISCONNECT)
+		s.identities.forget(session.ID)
+		s.persistSession(session)
+		expired++
+
+		s.logger.Info("idle session expired",
+			zap.String("session_id", session.ID),
+			zap.Duration("idle", idle),
+		)
+	}
+	return expired
+}
+
+// watchSessionExpiry expires idle sessions until the sidecar terminates
+func (s *Sidecar) watchSessionExpiry() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.OnTerminating(func(_ error) {
+		cancel()
+	})
+
+	interval := min(s.sessionIdleTimeout/2, maxSessionExpiryInterval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.expireIdleSessions(now)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package sidecar
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"go.uber.org/zap"
+)
+
+// sessionPruneInterval is how often ended sessions past their retention are
+// looked for
+const sessionPruneInterval = time.Minute
+
+// persistSession schedules saving the current state of session, its usage and
+// last signed RAV, to the session store, if any, on the next flush so requests
+// never wait on disk. Failures are logged, the session keeps being served from
+// memory.
+func (s *Sidecar) persistSession(session *sidecar.Session) {
+	if s.storeWriter == nil {
+		return
+	}
+
+	s.storeWriter.MarkDirty(session.ID, func() *sidecar.SessionExport {
+		return sidecar.NewSessionExport(session)
+	})
+}
+
+// flushSessions writes the sessions changed since the last flush to the
+// session store, if any
+func (s *Sidecar) flushSessions() {
+	if s.storeWriter == nil {
+		return
+	}
+
+	if err := s.storeWriter.Flush(); err != nil {
+		s.logger.Error("persisting sessions failed, retrying on next flush", zap.Int("sessions", s.storeWriter.Pending()), zap.Error(err))
+	}
+}
+
+// closeSessionStore flushes the pending session changes and closes the
+// session store, if any
+func (s *Sidecar) closeSessionStore() {
+	if s.store == nil {
+		return
+	}
+
+	s.flushSessions()
+	if err := s.store.Close(); err != nil {
+		s.logger.Warn("closing session store", zap.Error(err))
+	}
+}
+
+// pruneSessions drops the sessions ended for longer than the session retention
+// as of now, from memory and from the session store. Returns the number of
+// sessions pruned.
+func (s *Sidecar) pruneSessions(now time.Time) int {
+	if s.sessionRetention <= 0 {
+		return 0
+	}
+
+	var pruned []string
+	for _, session := range s.sessions.EndedBefore(now.Add(-s.sessionRetention)) {
+		s.sessions.Delete(session.ID)
+		pruned = append(pruned, session.ID)
+	}
+	if len(pruned) == 0 {
+		return 0
+	}
+
+	if s.storeWriter != nil {
+		if err := s.storeWriter.Delete(pruned...); err != nil {
+			s.logger.Warn("deleting pruned sessions from store failed, they are restored on restart", zap.Int("sessions", len(pruned)), zap.Error(err))
+		}
+	}
+
+	s.logger.Info("pruned ended sessions", zap.Int("sessions", len(pruned)), zap.Duration("retention", s.sessionRetention))
+	return len(pruned)
+}
+
+// watchSessionStore flushes session changes every interval and prunes ended
+// sessions past their retention, until the sidecar terminates
+func (s *Sidecar) watchSessionStore(interval time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.OnTerminating(func(_ error) {
+		cancel()
+	})
+
+	flush := time.NewTicker(interval)
+	defer flush.Stop()
+	prune := time.NewTicker(sessionPruneInterval)
+	defer prune.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-flush.C:
+			s.flushSessions()
+		case now := <-prune.C:
+			s.pruneSessions(now)
+		}
+	}
+}
+
+// restoreSessions loads the sessions of the session store, if any, so clients
+// can re-attach to them with ResumeSession after a restart. Budgets are
+// derived from the sessions, restoring them restores the spend accounted for.
+func (s *Sidecar) restoreSessions() error {
+	if s.store == nil {
+		return nil
+	}
+
+	exports, err := s.store.List()
+	if err != nil {
+		return fmt.Errorf("loading stored sessions: %w", err)
+	}
+
+	for _, export := range exports {
+		session, err := export.Session()
+		if err != nil {
+			return fmt.Errorf("restoring session %s: %w", export.SessionID, err)
+		}
+		if err := s.sessions.Import(session); err != nil {
+			return fmt.Errorf("restoring session %s: %w", export.SessionID, err)
+		}
+	}
+
+	s.logger.Info("restored stored sessions", zap.Int("sessions", len(exports)))
+	return nil
+}
@@ -0,0 +1,59 @@
+package sidecar
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPruneSessions(t *testing.T) {
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	storePath := filepath.Join(t.TempDir(), "sessions")
+
+	s := New(&Config{
+		ListenAddr:            ":0",
+		SignerKey:             signerKey,
+		Domain:                horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+		StorePath:             storePath,
+		EndedSessionRetention: time.Hour,
+	}, zap.NewNop())
+
+	newSession := func() *sidecar.Session {
+		session := s.sessions.Create(
+			eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+			eth.MustNewAddress("0x4444444444444444444444444444444444444444"),
+			eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		)
+		s.persistSession(session)
+		return session
+	}
+
+	active := newSession()
+	ended := newSession()
+	ended.End(commonv1.EndReason_END_REASON_COMPLETE)
+	s.flushSessions()
+
+	assert.Equal(t, 0, s.pruneSessions(time.Now()))
+	assert.Equal(t, 1, s.pruneSessions(time.Now().Add(2*time.Hour)))
+	_, err = s.sessions.Get(ended.ID)
+	assert.Error(t, err)
+	_, err = s.sessions.Get(active.ID)
+	assert.NoError(t, err)
+
+	s.closeSessionStore()
+	store := sidecar.NewBoltSessionStore(storePath)
+	defer store.Close()
+	exports, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, exports, 1)
+	assert.Equal(t, active.ID, exports[0].SessionID)
+}
@@ -0,0 +1,159 @@
+package sidecar
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	consumerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/consumer/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"go.uber.org/zap"
+)
+
+// SettlementResponse is the consumer settlement of an ended session, GRT
+// values are decimal wei strings, budgets left are empty when unlimited
+type SettlementResponse struct {
+	SessionID               string        `json:"session_id"`
+	ServiceProvider         string        `json:"service_provider"`
+	EndedAt                 *time.Time    `json:"ended_at,omitempty"`
+	Claimed                 MeteredUsage  `json:"claimed"`
+	Observed                *MeteredUsage `json:"observed,omitempty"`
+	SignedValue             string        `json:"signed_value"`
+	Divergence              float64       `json:"divergence"`
+	Disputed                bool          `json:"disputed"`
+	GlobalBudgetRemaining   string        `json:"global_budget_remaining,omitempty"`
+	ProviderBudgetRemaining string        `json:"provider_budget_remaining,omitempty"`
+}
+
+// settle records the consumer settlement of session, which ended: the value
+// signed for it, the usage observed for it when checked and the budgets left
+func (s *Sidecar) settle(session *sidecar.Session) {
+	settlement := &sidecar.ConsumerSettlement{SignedValue: authorizedValue(session)}
+	if check := s.blacklist.lastCheck(session.ID); check != nil {
+		applyUsageCheck(settlement, check)
+	}
+	settlement.GlobalBudgetRemaining, settlement.ProviderBudgetRemaining = s.budgetsRemaining(session.Receiver)
+	session.SetConsumerSettlement(settlement)
+
+	s.logger.Info("session settled",
+		zap.String("session_id", session.ID),
+		zap.Stringer("signed_value", settlement.SignedValue),
+		zap.Float64("divergence", settlement.Divergence),
+		zap.Bool("disputed", settlement.Disputed),
+	)
+}
+
+func applyUsageCheck(settlement *sidecar.ConsumerSettlement, check *UsageCheck) {
+	settlement.Observed = &sidecar.ObservedUsage{
+		BlocksProcessed:  check.Observed.BlocksProcessed,
+		BytesTransferred: check.Observed.BytesTransferred,
+	}
+	settlement.Divergence = check.Divergence
+	settlement.Disputed = check.Disputed
+}
+
+// budgetsRemaining returns the global budget and the budget of serviceProvider
+// left, nil when unlimited
+func (s *Sidecar) budgetsRemaining(serviceProvider eth.Address) (global, provider *big.Int) {
+	status := s.BudgetStatus()
+	global = remainingBudget(status.GlobalLimit, status.GlobalSpent)
+	for _, budget := range status.Providers {
+		if sidecar.AddressesEqual(budget.ServiceProvider, serviceProvider) {
+			provider = remainingBudget(budget.Limit, budget.Spent)
+		}
+	}
+	return global, provider
+}
+
+func remainingBudget(limit, spent *big.Int) *big.Int {
+	if limit == nil {
+		return nil
+	}
+
+	remaining := new(big.Int).Sub(limit, spent)
+	if remaining.Sign() < 0 {
+		remaining.SetInt64(0)
+	}
+	return remaining
+}
+
+// settlementToProto returns the settlement of session, nil when it has none
+func settlementToProto(session *sidecar.Session) *consumerv1.SessionSettlement {
+	settlement := session.GetConsumerSettlement()
+	if settlement == nil {
+		return nil
+	}
+
+	out := &consumerv1.SessionSettlement{
+		ClaimedUsage: session.GetUsage(),
+		SignedValue:  commonv1.BigIntFromNative(settlement.SignedValue),
+		Divergence:   settlement.Divergence,
+		Disputed:     settlement.Disputed,
+	}
+	if settlement.Observed != nil {
+		out.ObservedUsage = &commonv1.Usage{
+			BlocksProcessed:  settlement.Observed.BlocksProcessed,
+			BytesTransferred: settlement.Observed.BytesTransferred,
+		}
+	}
+	if settlement.GlobalBudgetRemaining != nil {
+		out.GlobalBudgetRemaining = commonv1.BigIntFromNative(settlement.GlobalBudgetRemaining)
+	}
+	if settlement.ProviderBudgetRemaining != nil {
+		out.ProviderBudgetRemaining = commonv1.BigIntFromNative(settlement.ProviderBudgetRemaining)
+	}
+	return out
+}
+
+// Settlements returns the settlements of the ended sessions of serviceProvider
+// (of every service provider when nil), ordered by session creation time
+func (s *Sidecar) Settlements(serviceProvider eth.Address) []*SettlementResponse {
+	out := make([]*SettlementResponse, 0)
+	for _, session := range s.sessions.List() {
+		if serviceProvider != nil && !sidecar.AddressesEqual(session.Receiver, serviceProvider) {
+			continue
+		}
+		settlement := session.GetConsumerSettlement()
+		if settlement == nil {
+			continue
+		}
+
+		usage := session.GetUsage()
+		response := &SettlementResponse{
+			SessionID:       session.ID,
+			ServiceProvider: session.Receiver.Pretty(),
+			EndedAt:         session.Snapshot().EndedAt,
+			Claimed:         MeteredUsage{BlocksProcessed: usage.BlocksProcessed, BytesTransferred: usage.BytesTransferred},
+			SignedValue:     settlement.SignedValue.String(),
+			Divergence:      settlement.Divergence,
+			Disputed:        settlement.Disputed,
+		}
+		if settlement.Observed != nil {
+			response.Observed = &MeteredUsage{BlocksProcessed: settlement.Observed.BlocksProcessed, BytesTransferred: settlement.Observed.BytesTransferred}
+		}
+		if settlement.GlobalBudgetRemaining != nil {
+			response.GlobalBudgetRemaining = settlement.GlobalBudgetRemaining.String()
+		}
+		if settlement.ProviderBudgetRemaining != nil {
+			response.ProviderBudgetRemaining = settlement.ProviderBudgetRemaining.String()
+		}
+		out = append(out, response)
+	}
+	return out
+}
+
+func (s *Sidecar) handleAdminGetSettlements(w http.ResponseWriter, r *http.Request) {
+	var serviceProvider eth.Address
+	if value := r.URL.Query().Get("service_provider"); value != "" {
+		var err error
+		if serviceProvider, err = eth.NewAddress(value); err != nil {
+			s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid service provider address: %s", err)})
+			return
+		}
+	}
+
+	s.writeJSON(w, http.StatusOK, s.Settlements(serviceProvider))
+}
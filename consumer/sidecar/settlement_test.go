@@ -0,0 +1,109 @@
+package sidecar
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	consumerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/consumer/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestEndSession_Settlement(t *testing.T) {
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	storePath := filepath.Join(t.TempDir(), "sessions")
+
+	s := New(&Config{
+		ListenAddr:      ":0",
+		SignerKey:       signerKey,
+		Domain:          horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+		AdminListenAddr: ":0",
+		GlobalBudget:    big.NewInt(100),
+		StorePath:       storePath,
+	}, zap.NewNop())
+
+	provider := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+	s.SetProviderBudget(provider, big.NewInt(30))
+
+	sessionID := initBudgetTestSession(t, s, provider)
+	_, err = reportCost(s, sessionID, 10)
+	require.NoError(t, err)
+
+	end, err := s.EndSession(context.Background(), connect.NewRequest(&consumerv1.EndSessionRequest{
+		SessionId:     sessionID,
+		FinalUsage:    &commonv1.Usage{BlocksProcessed: 1, Cost: commonv1.BigIntFromNative(big.NewInt(5))},
+		ObservedUsage: &commonv1.Usage{BlocksProcessed: 1},
+	}))
+	require.NoError(t, err)
+
+	settlement := end.Msg.Settlement
+	require.NotNil(t, settlement)
+	assert.Equal(t, uint64(2), settlement.ClaimedUsage.BlocksProcessed)
+	assert.Equal(t, uint64(1), settlement.ObservedUsage.BlocksProcessed)
+	assert.Equal(t, "15", settlement.SignedValue.ToNative().String())
+	assert.Equal(t, 1.0, settlement.Divergence)
+	assert.True(t, settlement.Disputed)
+	assert.Equal(t, "85", settlement.GlobalBudgetRemaining.ToNative().String())
+	assert.Equal(t, "15", settlement.ProviderBudgetRemaining.ToNative().String())
+
+	// Usage observed once the session ended is acknowledged too
+	other := initBudgetTestSession(t, s, eth.MustNewAddress("0x5555555555555555555555555555555555555555"))
+	_, err = reportCost(s, other, 20)
+	require.NoError(t, err)
+	end, err = s.EndSession(context.Background(), connect.NewRequest(&consumerv1.EndSessionRequest{SessionId: other}))
+	require.NoError(t, err)
+	assert.Nil(t, end.Msg.Settlement.ObservedUsage)
+	assert.Nil(t, end.Msg.Settlement.ProviderBudgetRemaining)
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.admin.Handler().ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+	rec := serve(http.MethodPost, "/v1/sessions/"+other+"/observed-usage", `{"blocks_processed": 1}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = serve(http.MethodGet, "/v1/spend/settlements", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var settlements []*SettlementResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&settlements))
+	require.Len(t, settlements, 2)
+	assert.Equal(t, sessionID, settlements[0].SessionID)
+	assert.Equal(t, "15", settlements[0].SignedValue)
+	assert.Equal(t, "15", settlements[0].ProviderBudgetRemaining)
+	assert.Equal(t, other, settlements[1].SessionID)
+	assert.Equal(t, &MeteredUsage{BlocksProcessed: 1}, settlements[1].Observed)
+	assert.False(t, settlements[1].Disputed)
+	assert.Equal(t, "65", settlements[1].GlobalBudgetRemaining)
+
+	rec = serve(http.MethodGet, "/v1/spend/settlements?service_provider="+provider.Pretty(), "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	settlements = nil
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&settlements))
+	require.Len(t, settlements, 1)
+	assert.Equal(t, sessionID, settlements[0].SessionID)
+
+	// Settlements are persisted with their session
+	s.closeSessionStore()
+	store := sidecar.NewBoltSessionStore(storePath)
+	defer store.Close()
+	exports, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, exports, 2)
+	require.NotNil(t, exports[1].ConsumerSettlement)
+	assert.Equal(t, "20", exports[1].ConsumerSettlement.SignedValue.String())
+	assert.Equal(t, &sidecar.ObservedUsage{BlocksProcessed: 1}, exports[1].ConsumerSettlement.Observed)
+}
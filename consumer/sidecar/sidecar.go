@@ -2,11 +2,14 @@ package sidecar
 
 import (
 	"context"
+	"fmt"
 	"math/big"
 	"net/http"
+	"time"
 
 	"connectrpc.com/connect"
 	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
 	"github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/consumer/v1/consumerv1connect"
 	"github.com/graphprotocol/substreams-data-service/sidecar"
 	"github.com/streamingfast/dgrpc/server"
@@ -28,9 +31,64 @@ type Sidecar struct {
 	// Session management
 	sessions *sidecar.SessionManager
 
-	// Signing configuration
-	signerKey *eth.PrivateKey
-	domain    *horizon.Domain
+	// Signing configuration, signer holds the key or queues requests for an
+	// offline signer
+	signer        ravSigner
+	signerAddress eth.Address
+	domain        *horizon.Domain
+	signingQueue  *signingQueue
+
+	// RAV sessions start from on Init
+	initialRAVStrategy InitialRAVStrategy
+	// Advisory validity window attached to signed RAVs, disabled when zero
+	ravValidity time.Duration
+	// Payment mode of sessions not selecting one on Init
+	paymentMode commonv1.PaymentMode
+
+	// EIP-712 domains of the accepted collectors, keyed by collector address,
+	// the default collector being domain.VerifyingContract
+	collectorDomains map[string]*horizon.Domain
+	// Reads escrow account balances from the PaymentsEscrow contract, nil when
+	// not configured
+	escrowBalance escrowBalanceFunc
+
+	// Admin server exposing /healthz and /readyz, nil when not configured
+	admin *sidecar.AdminServer
+
+	// Spending budgets and signing freeze, adjustable through the admin server
+	budgets        *budgets
+	spendingLimits SpendingLimits
+
+	// Sessions whose provider endpoint proved the service provider identity
+	identities *providerIdentities
+	// Active sessions without activity for longer are expired, never when zero
+	sessionIdleTimeout time.Duration
+
+	// Negotiated price parameters and track record per service provider,
+	// scored for provider selection
+	priceBooks *priceBooks
+
+	// Spend threshold and signing refusal events, posted to the spend webhook
+	spendNotifier *spendNotifier
+
+	// Local audit trail of every signed RAV, nil when disabled
+	archive *ravArchive
+
+	// Strictly increasing timestamps for the RAVs signed
+	ravClock *ravClock
+
+	// Service providers refused new sessions, for diverging usage claims or by
+	// operators
+	blacklist *providerBlacklist
+
+	// Sessions are persisted to store, when set, so clients can resume them
+	// after a restart, changes being written by storeWriter every
+	// sessionFlushInterval
+	store                sidecar.SessionStore
+	storeWriter          *sidecar.SessionWriter
+	sessionFlushInterval time.Duration
+	// Ended sessions are pruned this long after they ended, never when zero
+	sessionRetention time.Duration
 
 	// Provider gateway endpoint (set during Init)
 	// In production, this would be dynamically determined
@@ -39,21 +97,260 @@ type Sidecar struct {
 type Config struct {
 	ListenAddr string
 	SignerKey  *eth.PrivateKey
-	Domain     *horizon.Domain
+
+	// Signer signs RAVs in place of SignerKey, e.g. a horizon.RemoteSigner for
+	// gateways whose keys cannot be held in memory. It takes precedence over
+	// SignerKey and offline signing.
+	Signer horizon.Signer
+
+	// OfflineSigningDir enables offline signing when Signer and SignerKey are
+	// nil: RAV signing requests are queued as files in this directory and
+	// signed by sds-offline-signer with SignerAddress's key, kept in an
+	// air-gapped environment. Signing calls wait for the response file.
+	OfflineSigningDir string
+	SignerAddress     eth.Address
+	// Domain is the EIP-712 domain of the default collector, used by sessions
+	// that do not specify a collector
+	Domain *horizon.Domain
+
+	// Collectors are additional collector contracts sessions may be paid
+	// through, each signing RAVs under its own EIP-712 domain
+	Collectors []eth.Address
+
+	// RPCEndpoint and EscrowAddr (the PaymentsEscrow contract) enable reading
+	// the balance of each escrow account through the admin server, disabled
+	// when either is empty
+	RPCEndpoint string
+	EscrowAddr  eth.Address
+
+	// SigningConcurrency bounds the number of concurrent RAV signing calls,
+	// DefaultSigningConcurrency is used when zero
+	SigningConcurrency int
+
+	// AdminListenAddr is the address of the admin server serving /healthz and
+	// /readyz, disabled when empty
+	AdminListenAddr string
+
+	// GlobalBudget is the maximum value authorized through signed RAVs across
+	// all sessions, unlimited when nil. Budgets can be adjusted at runtime
+	// through the admin server.
+	GlobalBudget *big.Int
+
+	// SpendingLimits bound the value signed for each session, each payer, per
+	// processed block and per RAV, all unlimited when zero
+	SpendingLimits SpendingLimits
+
+	// VerifyProviderIdentity requires the provider endpoint of each session to
+	// sign an identity challenge with the service provider key on Init, sessions
+	// are refused when it does not so no RAV ever pays an impostor endpoint
+	VerifyProviderIdentity bool
+
+	// PriceBooks are the price parameters negotiated with service providers,
+	// keyed by service provider address. Price books can be updated at runtime
+	// through the admin server.
+	PriceBooks map[string]*sidecar.PricingConfig
+
+	// ProviderScorer scores service providers for session selection,
+	// DefaultProviderScorer is used when nil
+	ProviderScorer ProviderScorer
+
+	// SpendWebhookURL receives a JSON SpendEvent (POST) when the value
+	// authorized against a budget crosses one of SpendThresholds and when a RAV
+	// signing is refused by policy, events are only logged when empty
+	SpendWebhookURL string
+
+	// SpendThresholds are the budget fractions reported when crossed,
+	// DefaultSpendThresholds is used when empty
+	SpendThresholds []float64
+
+	// InitialRAVStrategy selects the RAV sessions start from on Init,
+	// InitialRAVZero when empty
+	InitialRAVStrategy InitialRAVStrategy
+
+	// RAVValidity attaches an advisory validity window to every signed RAV, see
+	// horizon.EncodeRAVValidityMetadata: providers honoring it refuse the RAV to
+	// open new sessions once RAVValidity elapsed since it was signed. Disabled
+	// when zero.
+	RAVValidity time.Duration
+
+	// PaymentMode is the payment mode of sessions not selecting one on Init,
+	// PAYMENT_MODE_RAV when unspecified. In PAYMENT_MODE_RECEIPTS a receipt is
+	// signed for each usage report instead of an updated RAV, the provider
+	// sidecar aggregating the receipts into RAVs, which requires Signer or
+	// SignerKey.
+	PaymentMode commonv1.PaymentMode
+
+	// ArchiveDir enables the RAV archive: every signed RAV is appended to daily
+	// files of this directory before being handed out, see ReadRAVArchive
+	ArchiveDir string
+	// ArchiveRetention is how long archive files are kept, forever when zero
+	ArchiveRetention time.Duration
+
+	// RAVClockPath is the file the timestamp of the last RAV signed is
+	// persisted to. RAVs are always signed with a timestamp after it, even when
+	// the system clock goes backwards (e.g. a VM snapshot restored), as
+	// providers reject RAVs older than the previous one. The last timestamp is
+	// only tracked in memory, and lost on restart, when empty.
+	RAVClockPath string
+
+	// UsageDivergenceTolerance is the relative difference between the usage a
+	// provider claimed for a session and the usage the gateway observed
+	// (reported through the admin server), blocks or bytes, above which the
+	// session is disputed, DefaultUsageDivergenceTolerance when zero
+	UsageDivergenceTolerance float64
+	// BlacklistAfterDivergences blacklists service providers after this many
+	// disputed sessions, refusing them new sessions until cleared through the
+	// admin server. Providers are only blacklisted by operators when zero.
+	BlacklistAfterDivergences int
+
+	// StorePath is the directory where sessions, with their usage and last
+	// signed RAV, are persisted (sidecar.BoltSessionStore) so they survive
+	// restarts and clients can re-attach to them with ResumeSession. Sessions
+	// are kept in memory only when empty.
+	StorePath string
+	// SessionStore persists sessions in a custom store, it takes precedence
+	// over StorePath
+	SessionStore sidecar.SessionStore
+	// SessionFlushInterval is how often session changes are written to the
+	// session store, sidecar.DefaultSessionFlushInterval when zero. Changes
+	// made since the last flush are lost on a crash.
+	SessionFlushInterval time.Duration
+	// EndedSessionRetention is how long ended sessions are kept, in memory and
+	// in the session store. Pruned sessions no longer count towards budgets and
+	// spending limits, nor are their RAVs resumed from. Ended sessions are kept
+	// forever when zero.
+	EndedSessionRetention time.Duration
+
+	// SessionIdleTimeout expires active sessions without usage report, RAV or
+	// state change for longer, e.g. abandoned by a crashed client: they are
+	// ended as client disconnects and can no longer be resumed. Sessions never
+	// expire when zero.
+	SessionIdleTimeout time.Duration
 }
 
 func New(config *Config, logger *zap.Logger) *Sidecar {
-	return &Sidecar{
-		Shutter:    shutter.New(),
-		listenAddr: config.ListenAddr,
-		logger:     logger,
-		sessions:   sidecar.NewSessionManager(),
-		signerKey:  config.SignerKey,
-		domain:     config.Domain,
+	collectorDomains := map[string]*horizon.Domain{
+		config.Domain.VerifyingContract.Pretty(): config.Domain,
+	}
+	for _, collector := range config.Collectors {
+		if _, found := collectorDomains[collector.Pretty()]; found {
+			continue
+		}
+		collectorDomains[collector.Pretty()] = &horizon.Domain{
+			Name:              config.Domain.Name,
+			Version:           config.Domain.Version,
+			ChainID:           config.Domain.ChainID,
+			VerifyingContract: collector,
+		}
+	}
+
+	var admin *sidecar.AdminServer
+	if config.AdminListenAddr != "" {
+		admin = sidecar.NewAdminServer(config.AdminListenAddr, logger, sidecar.ListenerReadinessCheck("grpc", config.ListenAddr))
+	}
+
+	var signer ravSigner = &keySigner{signer: config.Signer}
+	signerAddress := config.SignerAddress
+	if config.Signer != nil {
+		signerAddress = config.Signer.Address()
+	} else if config.SignerKey != nil {
+		signer = &keySigner{signer: horizon.NewLocalSigner(config.SignerKey)}
+		signerAddress = config.SignerKey.PublicKey().Address()
+	} else if config.OfflineSigningDir != "" {
+		signer = newOfflineSigner(config.OfflineSigningDir, config.SignerAddress)
+	}
+
+	initialRAVStrategy := config.InitialRAVStrategy
+	if initialRAVStrategy == "" {
+		initialRAVStrategy = InitialRAVZero
+	}
+
+	s := &Sidecar{
+		Shutter:            shutter.New(),
+		listenAddr:         config.ListenAddr,
+		logger:             logger,
+		sessions:           sidecar.NewSessionManager(),
+		signer:             signer,
+		signerAddress:      signerAddress,
+		domain:             config.Domain,
+		signingQueue:       newSigningQueue(config.SigningConcurrency),
+		initialRAVStrategy: initialRAVStrategy,
+		ravValidity:        config.RAVValidity,
+		paymentMode:        config.PaymentMode,
+		collectorDomains:   collectorDomains,
+		admin:              admin,
+		budgets:            newBudgets(config.GlobalBudget),
+		spendingLimits:     config.SpendingLimits,
+		identities:         newProviderIdentities(config.VerifyProviderIdentity),
+		sessionIdleTimeout: config.SessionIdleTimeout,
+		priceBooks:         newPriceBooks(config.ProviderScorer),
+		spendNotifier:      newSpendNotifier(config.SpendWebhookURL, config.SpendThresholds, logger),
+		blacklist:          newProviderBlacklist(config.UsageDivergenceTolerance, config.BlacklistAfterDivergences),
+		ravClock:           newRAVClock(config.RAVClockPath),
 	}
+	if config.ArchiveDir != "" {
+		s.archive = newRAVArchive(config.ArchiveDir, config.ArchiveRetention)
+	}
+	if config.RPCEndpoint != "" && len(config.EscrowAddr) != 0 {
+		s.escrowBalance = sidecar.NewEscrowQuerier(config.RPCEndpoint, config.EscrowAddr).GetBalance
+	}
+
+	switch {
+	case config.SessionStore != nil:
+		s.store = config.SessionStore
+	case config.StorePath != "":
+		s.store = sidecar.NewBoltSessionStore(config.StorePath)
+	}
+	if s.store != nil {
+		s.storeWriter = sidecar.NewSessionWriter(s.store)
+	}
+	s.sessionFlushInterval = config.SessionFlushInterval
+	if s.sessionFlushInterval <= 0 {
+		s.sessionFlushInterval = sidecar.DefaultSessionFlushInterval
+	}
+	s.sessionRetention = config.EndedSessionRetention
+
+	s.OnTerminating(func(_ error) {
+		s.spendNotifier.close()
+		if s.archive != nil {
+			s.archive.close()
+		}
+	})
+
+	for addressHex, pricing := range config.PriceBooks {
+		s.SetProviderPricing(eth.MustNewAddress(addressHex), pricing)
+	}
+
+	if admin != nil {
+		s.adminHandlers(admin)
+	}
+
+	return s
+}
+
+// resolveCollector returns the collector to use for a session, the default
+// collector when none is requested, or an error if it is not accepted
+func (s *Sidecar) resolveCollector(requested eth.Address) (eth.Address, error) {
+	if len(requested) == 0 {
+		return s.domain.VerifyingContract, nil
+	}
+	if _, found := s.collectorDomains[requested.Pretty()]; !found {
+		return nil, fmt.Errorf("collector %s is not accepted by this sidecar", requested.Pretty())
+	}
+	return requested, nil
 }
 
 func (s *Sidecar) Run() {
+	if err := s.restoreSessions(); err != nil {
+		s.Shutdown(err)
+		return
+	}
+	if s.store != nil {
+		s.OnTerminated(func(_ error) {
+			s.closeSessionStore()
+		})
+	}
+
 	handlerGetters := []connectrpc.HandlerGetter{
 		func(opts ...connect.HandlerOption) (string, http.Handler) {
 			return consumerv1connect.NewConsumerSidecarServiceHandler(s, opts...)
@@ -77,6 +374,23 @@ func (s *Sidecar) Run() {
 		s.server.Shutdown(nil)
 	})
 
+	if s.admin != nil {
+		s.admin.OnTerminated(func(err error) {
+			s.Shutdown(err)
+		})
+		s.OnTerminating(func(_ error) {
+			s.admin.Shutdown(nil)
+		})
+		go s.admin.Run()
+	}
+
+	if s.sessionIdleTimeout > 0 {
+		go s.watchSessionExpiry()
+	}
+	if s.store != nil || s.sessionRetention > 0 {
+		go s.watchSessionStore(s.sessionFlushInterval)
+	}
+
 	s.logger.Info("starting consumer sidecar", zap.String("listen_addr", s.listenAddr))
 	s.server.Launch(s.listenAddr)
 }
@@ -85,14 +399,43 @@ func (s *Sidecar) healthCheck(ctx context.Context) (isReady bool, out interface{
 	return true, nil, nil
 }
 
-// signRAV creates a signed RAV for the given parameters
+// signRAV creates a signed RAV for the given parameters under the EIP-712 domain
+// of collector (the default collector when nil). Signing goes through the
+// signing queue, fairly shared across service providers, final RAVs being signed
+// with SigningPriorityFinal so session teardown is not delayed by streaming sessions.
+// Without metadata, the RAV carries the validity window of Config.RAVValidity
+// when set.
 func (s *Sidecar) signRAV(
+	ctx context.Context,
+	priority SigningPriority,
+	collector eth.Address,
 	collectionID horizon.CollectionID,
 	payer, dataService, serviceProvider eth.Address,
 	timestampNs uint64,
 	valueAggregate *big.Int,
 	metadata []byte,
 ) (*horizon.SignedRAV, error) {
+	if err := s.budgets.checkFrozen(); err != nil {
+		return nil, err
+	}
+
+	// Never sign a RAV older than one already signed, whatever the system clock
+	stampedNs, repaired, err := s.ravClock.stamp(timestampNs)
+	if err != nil {
+		return nil, err
+	}
+	if repaired {
+		s.logger.Warn("system clock behind last signed RAV, signing with a later timestamp",
+			zap.Uint64("requested_timestamp_ns", timestampNs),
+			zap.Uint64("timestamp_ns", stampedNs),
+		)
+	}
+	timestampNs = stampedNs
+
+	if metadata == nil && s.ravValidity > 0 {
+		metadata = horizon.EncodeRAVValidityMetadata(collectionID, time.Unix(0, int64(timestampNs)).Add(s.ravValidity))
+	}
+
 	rav := &horizon.RAV{
 		CollectionID:    collectionID,
 		Payer:           payer,
@@ -103,5 +446,33 @@ func (s *Sidecar) signRAV(
 		Metadata:        metadata,
 	}
 
-	return horizon.Sign(s.domain, rav, s.signerKey)
+	collector, err = s.resolveCollector(collector)
+	if err != nil {
+		return nil, err
+	}
+	domain := s.collectorDomains[collector.Pretty()]
+
+	var signedRAV *horizon.SignedRAV
+	err = s.signingQueue.Do(ctx, serviceProvider.Pretty(), priority, func() (err error) {
+		signedRAV, err = s.signer.SignRAV(ctx, domain, rav)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A RAV missing from the archive is never handed out
+	if s.archive != nil {
+		if err := s.archive.record(&ArchivedRAV{
+			SignedAt:  time.Now().UTC(),
+			Final:     priority == SigningPriorityFinal,
+			Domain:    domain,
+			RAV:       signedRAV.Message,
+			Signature: eth.Hex(signedRAV.Signature[:]),
+		}); err != nil {
+			return nil, fmt.Errorf("archiving signed RAV: %w", err)
+		}
+	}
+
+	return signedRAV, nil
 }
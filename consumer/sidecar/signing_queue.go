@@ -0,0 +1,139 @@
+package sidecar
+
+import (
+	"context"
+	"sync"
+)
+
+// SigningPriority orders pending signing requests in the signing queue
+type SigningPriority int
+
+const (
+	// SigningPriorityNormal is used for RAVs signed while a session is streaming
+	SigningPriorityNormal SigningPriority = iota
+	// SigningPriorityFinal is used for session-ending final RAVs, which are
+	// always dispatched before any pending normal request
+	SigningPriorityFinal
+
+	signingPriorityCount
+)
+
+// DefaultSigningConcurrency is the default number of concurrent signing calls
+const DefaultSigningConcurrency = 4
+
+// signingQueue bounds the number of concurrent calls made to the signing key
+// backend. Pending requests are dispatched by priority first, then round-robin
+// across providers so that a single bursty provider cannot starve the others.
+type signingQueue struct {
+	mu          sync.Mutex
+	concurrency int
+	running     int
+
+	// Per priority, pending requests keyed by provider and the round-robin
+	// order in which providers are served
+	pending [signingPriorityCount]map[string][]*signingRequest
+	order   [signingPriorityCount][]string
+}
+
+type signingRequest struct {
+	ready     chan struct{}
+	cancelled bool
+}
+
+func newSigningQueue(concurrency int) *signingQueue {
+	if concurrency <= 0 {
+		concurrency = DefaultSigningConcurrency
+	}
+
+	q := &signingQueue{concurrency: concurrency}
+	for i := range q.pending {
+		q.pending[i] = make(map[string][]*signingRequest)
+	}
+	return q
+}
+
+// Do waits for a signing slot for the given provider and priority, then runs fn.
+// Returns ctx.Err() without running fn if the context is done before a slot
+// becomes available.
+func (q *signingQueue) Do(ctx context.Context, provider string, priority SigningPriority, fn func() error) error {
+	req := &signingRequest{ready: make(chan struct{})}
+
+	q.mu.Lock()
+	q.enqueue(provider, priority, req)
+	q.dispatch()
+	q.mu.Unlock()
+
+	select {
+	case <-req.ready:
+	case <-ctx.Done():
+		q.mu.Lock()
+		select {
+		case <-req.ready:
+			// Slot was granted concurrently, give it back
+			q.running--
+			q.dispatch()
+		default:
+			req.cancelled = true
+		}
+		q.mu.Unlock()
+		return ctx.Err()
+	}
+
+	defer func() {
+		q.mu.Lock()
+		q.running--
+		q.dispatch()
+		q.mu.Unlock()
+	}()
+
+	return fn()
+}
+
+func (q *signingQueue) enqueue(provider string, priority SigningPriority, req *signingRequest) {
+	if priority < 0 || priority >= signingPriorityCount {
+		priority = SigningPriorityNormal
+	}
+
+	if len(q.pending[priority][provider]) == 0 {
+		q.order[priority] = append(q.order[priority], provider)
+	}
+	q.pending[priority][provider] = append(q.pending[priority][provider], req)
+}
+
+// dispatch grants free slots to pending requests, must be called with q.mu held
+func (q *signingQueue) dispatch() {
+	for q.running < q.concurrency {
+		req := q.next()
+		if req == nil {
+			return
+		}
+
+		q.running++
+		close(req.ready)
+	}
+}
+
+// next pops the next non-cancelled request, highest priority first and
+// round-robin across providers within a priority
+func (q *signingQueue) next() *signingRequest {
+	for priority := signingPriorityCount - 1; priority >= 0; priority-- {
+		for len(q.order[priority]) > 0 {
+			provider := q.order[priority][0]
+			q.order[priority] = q.order[priority][1:]
+
+			requests := q.pending[priority][provider]
+			req, rest := requests[0], requests[1:]
+			if len(rest) == 0 {
+				delete(q.pending[priority], provider)
+			} else {
+				q.pending[priority][provider] = rest
+				q.order[priority] = append(q.order[priority], provider)
+			}
+
+			if !req.cancelled {
+				return req
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package sidecar
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigningQueue_BoundsConcurrency(t *testing.T) {
+	q := newSigningQueue(2)
+
+	var running, maxRunning atomic.Int32
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := q.Do(context.Background(), "provider", SigningPriorityNormal, func() error {
+				current := running.Add(1)
+				defer running.Add(-1)
+
+				for {
+					seen := maxRunning.Load()
+					if current <= seen || maxRunning.CompareAndSwap(seen, current) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				return nil
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(2), maxRunning.Load())
+}
+
+func TestSigningQueue_DispatchOrder(t *testing.T) {
+	q := newSigningQueue(1)
+
+	// Hold the single slot while enqueuing the requests under test
+	release := make(chan struct{})
+	holding := make(chan struct{})
+	go q.Do(context.Background(), "holder", SigningPriorityNormal, func() error {
+		close(holding)
+		<-release
+		return nil
+	})
+	<-holding
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	pending := 0
+	submit := func(provider string, priority SigningPriority, label string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := q.Do(context.Background(), provider, priority, func() error {
+				mu.Lock()
+				order = append(order, label)
+				mu.Unlock()
+				return nil
+			})
+			assert.NoError(t, err)
+		}()
+		pending++
+		waitForPending(t, q, pending)
+	}
+
+	submit("a", SigningPriorityNormal, "a1")
+	submit("a", SigningPriorityNormal, "a2")
+	submit("a", SigningPriorityNormal, "a3")
+	submit("b", SigningPriorityNormal, "b1")
+	submit("c", SigningPriorityFinal, "c-final")
+
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, []string{"c-final", "a1", "b1", "a2", "a3"}, order)
+}
+
+func TestSigningQueue_ContextCancelledWhileWaiting(t *testing.T) {
+	q := newSigningQueue(1)
+
+	release := make(chan struct{})
+	holding := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		q.Do(context.Background(), "holder", SigningPriorityNormal, func() error {
+			close(holding)
+			<-release
+			return nil
+		})
+	}()
+	<-holding
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	called := false
+	err := q.Do(ctx, "provider", SigningPriorityFinal, func() error {
+		called = true
+		return nil
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, called)
+
+	close(release)
+	<-done
+
+	// The cancelled request must not hold a slot
+	require.NoError(t, q.Do(context.Background(), "provider", SigningPriorityNormal, func() error { return nil }))
+}
+
+// waitForPending waits until the queue holds the expected number of pending requests
+func waitForPending(t *testing.T, q *signingQueue, expected int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		q.mu.Lock()
+		count := 0
+		for _, pending := range q.pending {
+			for _, requests := range pending {
+				count += len(requests)
+			}
+		}
+		q.mu.Unlock()
+
+		if count == expected {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected %d pending requests", expected)
+}
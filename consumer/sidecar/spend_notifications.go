@@ -0,0 +1,260 @@
+package sidecar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"go.uber.org/zap"
+)
+
+// DefaultSpendThresholds are the budget fractions reported when crossed,
+// unless Config.SpendThresholds overrides them
+var DefaultSpendThresholds = []float64{0.5, 0.9, 1.0}
+
+// SpendEventType identifies the kind of SpendEvent
+type SpendEventType string
+
+const (
+	// SpendEventThresholdCrossed is emitted when the value authorized against a
+	// budget reaches one of the configured spend thresholds
+	SpendEventThresholdCrossed SpendEventType = "spend_threshold_crossed"
+	// SpendEventSigningRefused is emitted when a RAV is not signed because
+	// signing is frozen, a budget would be exceeded or the service provider
+	// identity is not verified
+	SpendEventSigningRefused SpendEventType = "signing_refused"
+)
+
+// SpendEvent is the JSON body posted to the spend webhook, GRT values are
+// decimal wei strings
+type SpendEvent struct {
+	Type            SpendEventType `json:"type"`
+	Time            time.Time      `json:"time"`
+	SessionID       string         `json:"session_id,omitempty"`
+	ServiceProvider string         `json:"service_provider,omitempty"`
+
+	// Budget is "global" or the service provider address the threshold applies
+	// to, Threshold the crossed budget fraction (e.g. 0.9)
+	Budget    string  `json:"budget,omitempty"`
+	Threshold float64 `json:"threshold,omitempty"`
+	Limit     string  `json:"limit,omitempty"`
+	Spent     string  `json:"spent,omitempty"`
+
+	// Reason is the signing refusal error
+	Reason string `json:"reason,omitempty"`
+}
+
+const (
+	spendWebhookQueueSize = 64
+	spendWebhookTimeout   = 10 * time.Second
+	spendWebhookAttempts  = 3
+)
+
+// spendNotifier tracks which spend thresholds each budget crossed and delivers
+// spend events to a webhook. Delivery is asynchronous so a slow or unreachable
+// endpoint never delays signing, events are dropped when the queue is full.
+type spendNotifier struct {
+	logger     *zap.Logger
+	url        string
+	client     *http.Client
+	thresholds []float64
+
+	mu sync.Mutex
+	// levels holds the number of thresholds crossed per budget, lowered when
+	// spend falls back under a threshold (e.g. the budget was raised) so that
+	// crossing it again is reported again
+	levels map[string]int
+
+	events chan *SpendEvent
+	done   chan struct{}
+}
+
+func newSpendNotifier(url string, thresholds []float64, logger *zap.Logger) *spendNotifier {
+	if len(thresholds) == 0 {
+		thresholds = DefaultSpendThresholds
+	}
+	thresholds = append([]float64(nil), thresholds...)
+	sort.Float64s(thresholds)
+
+	n := &spendNotifier{
+		logger:     logger,
+		url:        url,
+		client:     &http.Client{Timeout: spendWebhookTimeout},
+		thresholds: thresholds,
+		levels:     make(map[string]int),
+		events:     make(chan *SpendEvent, spendWebhookQueueSize),
+		done:       make(chan struct{}),
+	}
+	if url != "" {
+		go n.run()
+	}
+	return n
+}
+
+// observe records the spend of a budget and emits an event for every
+// threshold crossed since the previous observation
+func (n *spendNotifier) observe(budget string, limit, spent *big.Int, base *SpendEvent) {
+	if limit == nil {
+		return
+	}
+
+	level := 0
+	if spent.Sign() > 0 {
+		for _, threshold := range n.thresholds {
+			if !spendReached(spent, limit, threshold) {
+				break
+			}
+			level++
+		}
+	}
+
+	n.mu.Lock()
+	previous := n.levels[budget]
+	n.levels[budget] = level
+	n.mu.Unlock()
+
+	for i := previous; i < level; i++ {
+		event := *base
+		event.Type = SpendEventThresholdCrossed
+		event.Budget = budget
+		event.Threshold = n.thresholds[i]
+		event.Limit = limit.String()
+		event.Spent = spent.String()
+		n.emit(&event)
+	}
+}
+
+// spendReached returns whether spent >= threshold * limit
+func spendReached(spent, limit *big.Int, threshold float64) bool {
+	target := new(big.Rat).SetInt(limit)
+	target.Mul(target, new(big.Rat).SetFloat64(threshold))
+	return new(big.Rat).SetInt(spent).Cmp(target) >= 0
+}
+
+func (n *spendNotifier) emit(event *SpendEvent) {
+	n.logger.Warn("spend event",
+		zap.String("type", string(event.Type)),
+		zap.String("session_id", event.SessionID),
+		zap.String("budget", event.Budget),
+		zap.Float64("threshold", event.Threshold),
+		zap.String("spent", event.Spent),
+		zap.String("reason", event.Reason),
+	)
+
+	if n.url == "" {
+		return
+	}
+
+	select {
+	case n.events <- event:
+	default:
+		n.logger.Warn("spend webhook queue full, dropping event", zap.String("type", string(event.Type)))
+	}
+}
+
+func (n *spendNotifier) run() {
+	for {
+		select {
+		case <-n.done:
+			return
+		case event := <-n.events:
+			if err := n.deliver(event); err != nil {
+				n.logger.Warn("unable to deliver spend event", zap.String("type", string(event.Type)), zap.Error(err))
+			}
+		}
+	}
+}
+
+// deliver posts event to the webhook, retrying failed attempts
+func (n *spendNotifier) deliver(event *SpendEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encoding spend event: %w", err)
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= spendWebhookAttempts; attempt++ {
+		if lastErr = n.post(body); lastErr == nil {
+			return nil
+		}
+
+		select {
+		case <-n.done:
+			return lastErr
+		case <-time.After(time.Duration(attempt) * time.Second):
+		}
+	}
+	return lastErr
+}
+
+func (n *spendNotifier) post(body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), spendWebhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+func (n *spendNotifier) close() {
+	select {
+	case <-n.done:
+	default:
+		close(n.done)
+	}
+}
+
+// observeSpend reports the thresholds crossed by the global budget and the
+// budget of the session's service provider after a RAV of session was signed
+func (s *Sidecar) observeSpend(session *sidecar.Session) {
+	status := s.BudgetStatus()
+	base := &SpendEvent{
+		Time:            time.Now(),
+		SessionID:       session.ID,
+		ServiceProvider: session.Receiver.Pretty(),
+	}
+
+	s.spendNotifier.observe("global", status.GlobalLimit, status.GlobalSpent, base)
+	for _, provider := range status.Providers {
+		if provider.ServiceProvider.Pretty() == session.Receiver.Pretty() {
+			s.spendNotifier.observe(provider.ServiceProvider.Pretty(), provider.Limit, provider.Spent, base)
+		}
+	}
+}
+
+// notifySigningRefused reports a RAV of session not signed because of err,
+// only refusals by policy (freeze, budget, provider identity) are reported
+func (s *Sidecar) notifySigningRefused(session *sidecar.Session, err error) {
+	if !errors.Is(err, ErrSigningFrozen) && !errors.Is(err, ErrBudgetExceeded) && !errors.Is(err, ErrProviderNotVerified) {
+		return
+	}
+
+	s.spendNotifier.emit(&SpendEvent{
+		Type:            SpendEventSigningRefused,
+		Time:            time.Now(),
+		SessionID:       session.ID,
+		ServiceProvider: session.Receiver.Pretty(),
+		Reason:          err.Error(),
+	})
+}
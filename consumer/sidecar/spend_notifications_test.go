@@ -0,0 +1,131 @@
+package sidecar
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type spendWebhookRecorder struct {
+	mu     sync.Mutex
+	events []*SpendEvent
+}
+
+func (r *spendWebhookRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var event SpendEvent
+	if err := json.NewDecoder(req.Body).Decode(&event); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	r.mu.Lock()
+	r.events = append(r.events, &event)
+	r.mu.Unlock()
+}
+
+func (r *spendWebhookRecorder) waitFor(t *testing.T, count int) []*SpendEvent {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return len(r.events) >= count
+	}, 5*time.Second, 10*time.Millisecond)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*SpendEvent(nil), r.events...)
+}
+
+func TestSpendNotifications(t *testing.T) {
+	recorder := &spendWebhookRecorder{}
+	webhook := httptest.NewServer(recorder)
+	defer webhook.Close()
+
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	s := New(&Config{
+		ListenAddr:      ":0",
+		SignerKey:       signerKey,
+		Domain:          horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+		GlobalBudget:    big.NewInt(100),
+		SpendWebhookURL: webhook.URL,
+	}, zap.NewNop())
+	defer s.spendNotifier.close()
+
+	provider := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+	sessionID := initBudgetTestSession(t, s, provider)
+
+	// 40%: nothing crossed
+	_, err = reportCost(s, sessionID, 40)
+	require.NoError(t, err)
+
+	// 95%: 50% and 90% crossed at once
+	_, err = reportCost(s, sessionID, 55)
+	require.NoError(t, err)
+	events := recorder.waitFor(t, 2)
+	require.Len(t, events, 2)
+	for i, threshold := range []float64{0.5, 0.9} {
+		assert.Equal(t, SpendEventThresholdCrossed, events[i].Type)
+		assert.Equal(t, "global", events[i].Budget)
+		assert.Equal(t, threshold, events[i].Threshold)
+		assert.Equal(t, "100", events[i].Limit)
+		assert.Equal(t, "95", events[i].Spent)
+		assert.Equal(t, sessionID, events[i].SessionID)
+		assert.Equal(t, provider.Pretty(), events[i].ServiceProvider)
+	}
+
+	// 100%, then refused
+	_, err = reportCost(s, sessionID, 5)
+	require.NoError(t, err)
+	resp, err := reportCost(s, sessionID, 1)
+	require.NoError(t, err)
+	assert.False(t, resp.ShouldContinue)
+
+	events = recorder.waitFor(t, 4)
+	require.Len(t, events, 4)
+	assert.Equal(t, SpendEventThresholdCrossed, events[2].Type)
+	assert.Equal(t, 1.0, events[2].Threshold)
+	assert.Equal(t, SpendEventSigningRefused, events[3].Type)
+	assert.Contains(t, events[3].Reason, ErrBudgetExceeded.Error())
+
+	// Raising the budget re-arms the thresholds now under the spend level
+	s.SetGlobalBudget(big.NewInt(1000))
+	_, err = reportCost(s, sessionID, 1)
+	require.NoError(t, err)
+	s.SetGlobalBudget(big.NewInt(150))
+	_, err = reportCost(s, sessionID, 1)
+	require.NoError(t, err)
+
+	events = recorder.waitFor(t, 5)
+	require.Len(t, events, 5)
+	assert.Equal(t, 0.5, events[4].Threshold)
+	assert.Equal(t, "102", events[4].Spent)
+}
+
+func TestSpendNotifier_Levels(t *testing.T) {
+	notifier := newSpendNotifier("", []float64{1, 0.25}, zap.NewNop())
+	assert.Equal(t, []float64{0.25, 1}, notifier.thresholds)
+
+	var levels []int
+	for _, spent := range []int64{1, 2, 8, 0} {
+		notifier.observe("0x44", big.NewInt(8), big.NewInt(spent), &SpendEvent{})
+		levels = append(levels, notifier.levels["0x44"])
+	}
+	assert.Equal(t, []int{0, 1, 2, 0}, levels)
+
+	// Unlimited budgets are ignored
+	notifier.observe("global", nil, big.NewInt(1), &SpendEvent{})
+	assert.NotContains(t, notifier.levels, "global")
+}
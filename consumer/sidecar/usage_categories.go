@@ -0,0 +1,58 @@
+package sidecar
+
+import (
+	"fmt"
+	"net/http"
+
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+)
+
+// CategorySpendResponse is the usage and cost of one usage category across
+// sessions, the cost is a decimal wei string
+type CategorySpendResponse struct {
+	Category         string `json:"category"`
+	BlocksProcessed  uint64 `json:"blocks_processed"`
+	BytesTransferred uint64 `json:"bytes_transferred"`
+	Requests         uint64 `json:"requests"`
+	Cost             string `json:"cost"`
+}
+
+// CategorySpend returns the usage reported per usage category across the
+// sessions of serviceProvider (of every service provider when nil), ordered by
+// category name. Categories may overlap, e.g. a module breakdown with the
+// preprocessed and live one, so they do not add up to the total spend.
+func (s *Sidecar) CategorySpend(serviceProvider eth.Address) []*commonv1.UsageCategory {
+	var breakdowns [][]*commonv1.UsageCategory
+	for _, session := range s.sessions.List() {
+		if serviceProvider != nil && !sidecar.AddressesEqual(session.Receiver, serviceProvider) {
+			continue
+		}
+		breakdowns = append(breakdowns, session.GetUsage().Categories)
+	}
+	return sidecar.MergeUsageCategories(breakdowns...)
+}
+
+func (s *Sidecar) handleAdminGetCategorySpend(w http.ResponseWriter, r *http.Request) {
+	var serviceProvider eth.Address
+	if value := r.URL.Query().Get("service_provider"); value != "" {
+		var err error
+		if serviceProvider, err = eth.NewAddress(value); err != nil {
+			s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid service provider address: %s", err)})
+			return
+		}
+	}
+
+	out := make([]*CategorySpendResponse, 0)
+	for _, category := range s.CategorySpend(serviceProvider) {
+		out = append(out, &CategorySpendResponse{
+			Category:         category.Name,
+			BlocksProcessed:  category.BlocksProcessed,
+			BytesTransferred: category.BytesTransferred,
+			Requests:         category.Requests,
+			Cost:             category.Cost.ToNative().String(),
+		})
+	}
+	s.writeJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,61 @@
+package sidecar
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	consumerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/consumer/v1"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCategorySpend(t *testing.T) {
+	s := newBudgetTestSidecar(t, nil)
+	first := initBudgetTestSession(t, s, eth.MustNewAddress("0x4444444444444444444444444444444444444444"))
+	second := initBudgetTestSession(t, s, eth.MustNewAddress("0x5555555555555555555555555555555555555555"))
+
+	report := func(sessionID string, categories ...*commonv1.UsageCategory) {
+		_, err := s.ReportUsage(context.Background(), connect.NewRequest(&consumerv1.ReportUsageRequest{
+			SessionId: sessionID,
+			Usage:     &commonv1.Usage{BlocksProcessed: 10, Cost: commonv1.BigIntFromNative(big.NewInt(50)), Categories: categories},
+		}))
+		require.NoError(t, err)
+	}
+	category := func(name string, blocks uint64, cost int64) *commonv1.UsageCategory {
+		return &commonv1.UsageCategory{Name: name, BlocksProcessed: blocks, Cost: commonv1.BigIntFromNative(big.NewInt(cost))}
+	}
+
+	report(first, category("cached", 8, 10), category("live", 2, 40))
+	report(second, category("live", 10, 50))
+
+	serve := func(target string) (int, []*CategorySpendResponse) {
+		rec := httptest.NewRecorder()
+		s.admin.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		var out []*CategorySpendResponse
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+		}
+		return rec.Code, out
+	}
+
+	code, out := serve("/v1/spend/categories")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []*CategorySpendResponse{
+		{Category: "cached", BlocksProcessed: 8, Cost: "10"},
+		{Category: "live", BlocksProcessed: 12, Cost: "90"},
+	}, out)
+
+	code, out = serve("/v1/spend/categories?service_provider=0x5555555555555555555555555555555555555555")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []*CategorySpendResponse{{Category: "live", BlocksProcessed: 10, Cost: "50"}}, out)
+
+	code, _ = serve("/v1/spend/categories?service_provider=nope")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
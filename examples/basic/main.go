@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/graphprotocol/substreams-data-service/horizon/devenv"
 	"github.com/streamingfast/eth-go"
 )
 
@@ -37,14 +38,15 @@ func main() {
 	fmt.Printf("Sender Address: %s\n", senderAddr.Pretty())
 	fmt.Printf("Aggregator Address: %s\n\n", aggregatorAddr.Pretty())
 
-	// Setup collection and addresses
-	var collectionID horizon.CollectionID
-	copy(collectionID[:], eth.MustNewHash("0xabababababababababababababababababababababababababababababababab")[:])
+	// Setup collection (from the example package fixtures) and addresses
+	pkg := devenv.EthereumExplorerPackage
+	collectionID := pkg.CollectionID()
 
 	payer := senderAddr
 	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
 	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
 
+	fmt.Printf("Package: %s\n", pkg)
 	fmt.Printf("Collection ID: %x\n", collectionID[:8])
 	fmt.Printf("Data Service: %s\n", dataService.Pretty())
 	fmt.Printf("Service Provider: %s\n\n", serviceProvider.Pretty())
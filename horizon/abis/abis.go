@@ -0,0 +1,112 @@
+package abis
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/graphprotocol/substreams-data-service/horizon/devenv/contracts"
+	"github.com/streamingfast/eth-go"
+)
+
+// ErrUnknownContract is returned when no artifact is embedded for a contract
+var ErrUnknownContract = errors.New("unknown contract")
+
+// The ABIs are parsed from the contract artifacts embedded for the development
+// environment, once per contract on first use and shared afterwards. They must
+// not be modified.
+var (
+	mu     sync.Mutex
+	loaded = make(map[string]*eth.ABI)
+)
+
+// GraphTallyCollector returns the ABI of the GraphTallyCollector contract,
+// collecting RAVs and managing the authorized signers of payers
+func GraphTallyCollector() *eth.ABI {
+	return mustGet("GraphTallyCollector")
+}
+
+// PaymentsEscrow returns the ABI of the PaymentsEscrow contract holding the
+// escrow accounts of payers
+func PaymentsEscrow() *eth.ABI {
+	return mustGet("PaymentsEscrow")
+}
+
+// GraphPayments returns the ABI of the GraphPayments contract distributing
+// collected payments
+func GraphPayments() *eth.ABI {
+	return mustGet("GraphPayments")
+}
+
+// Staking returns the ABI of the staking contract of the development
+// environment, the subset of HorizonStaking the data service and collector
+// use (provisions, operators and delegation pools)
+func Staking() *eth.ABI {
+	return mustGet("MockStaking")
+}
+
+// SubstreamsDataService returns the ABI of the SubstreamsDataService contract
+func SubstreamsDataService() *eth.ABI {
+	return mustGet("SubstreamsDataService")
+}
+
+// Get returns the ABI of the contract named name, one of Names
+func Get(name string) (*eth.ABI, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if abi, found := loaded[name]; found {
+		return abi, nil
+	}
+
+	data, err := contracts.FS.ReadFile(name + ".json")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w %q", ErrUnknownContract, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s artifact: %w", name, err)
+	}
+
+	var artifact struct {
+		ABI json.RawMessage `json:"abi"`
+	}
+	if err := json.Unmarshal(data, &artifact); err != nil {
+		return nil, fmt.Errorf("parsing %s artifact: %w", name, err)
+	}
+
+	abi, err := eth.ParseABIFromBytes(artifact.ABI)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s ABI: %w", name, err)
+	}
+
+	loaded[name] = abi
+	return abi, nil
+}
+
+// Names returns the names of the contracts whose ABI is embedded, sorted
+func Names() []string {
+	entries, _ := contracts.FS.ReadDir(".")
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if name, found := strings.CutSuffix(entry.Name(), ".json"); found {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// mustGet returns the ABI of the contract named name, the artifacts being
+// embedded a failure is a build defect
+func mustGet(name string) *eth.ABI {
+	abi, err := Get(name)
+	if err != nil {
+		panic(err)
+	}
+	return abi
+}
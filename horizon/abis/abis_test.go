@@ -0,0 +1,40 @@
+package abis
+
+import (
+	"testing"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestABIs(t *testing.T) {
+	for name, abi := range map[string]*eth.ABI{
+		"GraphTallyCollector":   GraphTallyCollector(),
+		"PaymentsEscrow":        PaymentsEscrow(),
+		"GraphPayments":         GraphPayments(),
+		"Staking":               Staking(),
+		"SubstreamsDataService": SubstreamsDataService(),
+	} {
+		require.NotNil(t, abi, name)
+	}
+
+	isAuthorized := GraphTallyCollector().FindFunctionByName("isAuthorized")
+	require.NotNil(t, isAuthorized)
+	data, err := isAuthorized.NewCall(eth.MustNewAddress("0x1111111111111111111111111111111111111111"), eth.MustNewAddress("0x2222222222222222222222222222222222222222")).Encode()
+	require.NoError(t, err)
+	assert.Len(t, data, 4+2*32)
+
+	// Every embedded artifact parses, and is only parsed once
+	for _, name := range Names() {
+		abi, err := Get(name)
+		require.NoError(t, err, name)
+		again, err := Get(name)
+		require.NoError(t, err)
+		assert.Same(t, abi, again)
+	}
+	assert.Contains(t, Names(), "GraphTallyCollector")
+
+	_, err = Get("Unknown")
+	assert.ErrorIs(t, err, ErrUnknownContract)
+}
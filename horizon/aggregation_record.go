@@ -0,0 +1,169 @@
+package horizon
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/streamingfast/eth-go"
+)
+
+var ErrAggregationRecordMismatch = errors.New("aggregation record does not match RAV and receipts")
+
+// AggregationRecordSchema versions the JSON encoding of AggregationRecord.
+// Version 2 added ReceiptOrdering, records of version 1 listed receipts in the
+// order they were given.
+var AggregationRecordSchema = NewSchema("aggregation record", "schema_version", 2)
+
+func init() {
+	AggregationRecordSchema.RegisterMigration(1, func(fields map[string]json.RawMessage) error {
+		fields["receipt_ordering"] = json.RawMessage("0")
+		return nil
+	})
+}
+
+// AggregationRecord is an audit record of a single Receipt→RAV aggregation. It
+// references every input by its EIP-712 digest so it can be persisted alongside
+// the RAV and later used to prove which receipts back it, for example during a
+// dispute.
+type AggregationRecord struct {
+	// SchemaVersion is the AggregationRecordSchema version of the record
+	SchemaVersion int `json:"schema_version"`
+	// ReceiptOrdering is the version of the rule ReceiptDigests are ordered by,
+	// see ReceiptOrdering, zero when in the order the receipts were given
+	ReceiptOrdering int `json:"receipt_ordering"`
+
+	RAVDigest eth.Hash `json:"rav_digest"`
+	// PreviousRAVDigest is nil when the RAV was aggregated without a previous RAV
+	PreviousRAVDigest eth.Hash   `json:"previous_rav_digest,omitempty"`
+	ReceiptDigests    []eth.Hash `json:"receipt_digests"`
+
+	// PreviousValueAggregate + ReceiptsTotal == ValueAggregate
+	PreviousValueAggregate *big.Int `json:"previous_value_aggregate"`
+	ReceiptsTotal          *big.Int `json:"receipts_total"`
+	ValueAggregate         *big.Int `json:"value_aggregate"`
+
+	// DroppedDuplicates counts the exact duplicate receipts dropped from the
+	// batch with WithDuplicateReceiptTolerance, they are not part of ReceiptDigests
+	DroppedDuplicates int `json:"dropped_duplicates,omitempty"`
+}
+
+// NewAggregationRecord builds the audit record of the aggregation of receipts
+// (on top of the optional previousRAV) into rav, receipts being listed in the
+// canonical order of SortReceipts whatever the order they are given in
+func NewAggregationRecord(
+	domain *Domain,
+	receipts []*SignedReceipt,
+	previousRAV *SignedRAV,
+	rav *SignedRAV,
+) (*AggregationRecord, error) {
+	receipts = slices.Clone(receipts)
+	SortReceipts(receipts)
+
+	return newAggregationRecord(domain, receipts, previousRAV, rav, ReceiptOrdering)
+}
+
+func newAggregationRecord(
+	domain *Domain,
+	receipts []*SignedReceipt,
+	previousRAV *SignedRAV,
+	rav *SignedRAV,
+	ordering int,
+) (*AggregationRecord, error) {
+	ravDigest, err := HashTypedData(domain, rav.Message)
+	if err != nil {
+		return nil, fmt.Errorf("computing RAV digest: %w", err)
+	}
+
+	record := &AggregationRecord{
+		SchemaVersion:          AggregationRecordSchema.Version(),
+		ReceiptOrdering:        ordering,
+		RAVDigest:              ravDigest,
+		ReceiptDigests:         make([]eth.Hash, 0, len(receipts)),
+		PreviousValueAggregate: big.NewInt(0),
+		ReceiptsTotal:          big.NewInt(0),
+		ValueAggregate:         new(big.Int).Set(rav.Message.ValueAggregate),
+	}
+
+	if previousRAV != nil {
+		record.PreviousRAVDigest, err = HashTypedData(domain, previousRAV.Message)
+		if err != nil {
+			return nil, fmt.Errorf("computing previous RAV digest: %w", err)
+		}
+		record.PreviousValueAggregate.Set(previousRAV.Message.ValueAggregate)
+	}
+
+	for i, r := range receipts {
+		digest, err := HashTypedData(domain, r.Message)
+		if err != nil {
+			return nil, fmt.Errorf("computing receipt %d digest: %w", i, err)
+		}
+
+		record.ReceiptDigests = append(record.ReceiptDigests, digest)
+		record.ReceiptsTotal.Add(record.ReceiptsTotal, r.Message.Value)
+	}
+
+	return record, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, records of older schema versions
+// are migrated to the current one
+func (r *AggregationRecord) UnmarshalJSON(data []byte) error {
+	type plain AggregationRecord
+	return AggregationRecordSchema.Unmarshal(data, (*plain)(r))
+}
+
+// Verify checks that the record describes the aggregation of exactly the given
+// receipts on top of previousRAV into rav. Receipts may be given in any order,
+// they are sorted by the record's ordering rule, except for records of
+// aggregations in the given order (ReceiptOrdering zero). Returns
+// ErrAggregationRecordMismatch if any digest or value differs.
+func (r *AggregationRecord) Verify(
+	domain *Domain,
+	receipts []*SignedReceipt,
+	previousRAV *SignedRAV,
+	rav *SignedRAV,
+) error {
+	if sum := new(big.Int).Add(r.PreviousValueAggregate, r.ReceiptsTotal); sum.Cmp(r.ValueAggregate) != 0 {
+		return fmt.Errorf("%w: previous value %s plus receipts total %s is not value aggregate %s",
+			ErrAggregationRecordMismatch, r.PreviousValueAggregate, r.ReceiptsTotal, r.ValueAggregate)
+	}
+
+	var expected *AggregationRecord
+	var err error
+	switch r.ReceiptOrdering {
+	case 0:
+		expected, err = newAggregationRecord(domain, receipts, previousRAV, rav, 0)
+	case ReceiptOrdering:
+		expected, err = NewAggregationRecord(domain, receipts, previousRAV, rav)
+	default:
+		return fmt.Errorf("%w: unknown receipt ordering %d", ErrAggregationRecordMismatch, r.ReceiptOrdering)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(r.RAVDigest, expected.RAVDigest) {
+		return fmt.Errorf("%w: RAV digest", ErrAggregationRecordMismatch)
+	}
+	if !bytes.Equal(r.PreviousRAVDigest, expected.PreviousRAVDigest) {
+		return fmt.Errorf("%w: previous RAV digest", ErrAggregationRecordMismatch)
+	}
+	if len(r.ReceiptDigests) != len(expected.ReceiptDigests) {
+		return fmt.Errorf("%w: %d receipt digests, got %d receipts",
+			ErrAggregationRecordMismatch, len(r.ReceiptDigests), len(expected.ReceiptDigests))
+	}
+	for i := range r.ReceiptDigests {
+		if !bytes.Equal(r.ReceiptDigests[i], expected.ReceiptDigests[i]) {
+			return fmt.Errorf("%w: receipt %d digest", ErrAggregationRecordMismatch, i)
+		}
+	}
+	if r.ValueAggregate.Cmp(expected.ValueAggregate) != 0 || r.ReceiptsTotal.Cmp(expected.ReceiptsTotal) != 0 {
+		return fmt.Errorf("%w: values", ErrAggregationRecordMismatch)
+	}
+
+	return nil
+}
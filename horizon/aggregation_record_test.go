@@ -0,0 +1,74 @@
+package horizon
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregationRecord_AggregateReceiptsWithRecord(t *testing.T) {
+	domain := NewDomain(1, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+
+	senderKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	aggregatorKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	senderAddr := senderKey.PublicKey().Address()
+	aggregator := NewAggregator(domain, aggregatorKey, []eth.Address{senderAddr, aggregatorKey.PublicKey().Address()})
+
+	newReceipts := func(timestampNs uint64, values ...int64) []*SignedReceipt {
+		var receipts []*SignedReceipt
+		for i, value := range values {
+			receipt := &Receipt{
+				Payer:           senderAddr,
+				DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+				ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+				TimestampNs:     timestampNs + uint64(i),
+				Nonce:           timestampNs + uint64(i),
+				Value:           big.NewInt(value),
+			}
+			signed, err := Sign(domain, receipt, senderKey)
+			require.NoError(t, err)
+			receipts = append(receipts, signed)
+		}
+		return receipts
+	}
+
+	batch1 := newReceipts(uint64(time.Now().UnixNano()), 100, 200)
+	rav1, record1, err := aggregator.AggregateReceiptsWithRecord(batch1, nil)
+	require.NoError(t, err)
+	require.Nil(t, record1.PreviousRAVDigest)
+	require.Len(t, record1.ReceiptDigests, 2)
+	require.Equal(t, big.NewInt(300), record1.ReceiptsTotal)
+	require.NoError(t, record1.Verify(domain, batch1, nil, rav1))
+
+	receiptDigest, err := HashTypedData(domain, batch1[0].Message)
+	require.NoError(t, err)
+	require.Equal(t, receiptDigest, record1.ReceiptDigests[0])
+
+	batch2 := newReceipts(rav1.Message.TimestampNs+1, 50)
+	rav2, record2, err := aggregator.AggregateReceiptsWithRecord(batch2, rav1)
+	require.NoError(t, err)
+	require.Equal(t, record1.RAVDigest, record2.PreviousRAVDigest)
+	require.Equal(t, big.NewInt(300), record2.PreviousValueAggregate)
+	require.Equal(t, big.NewInt(50), record2.ReceiptsTotal)
+	require.Equal(t, big.NewInt(350), record2.ValueAggregate)
+	require.NoError(t, record2.Verify(domain, batch2, rav1, rav2))
+
+	// Record survives a JSON round trip
+	data, err := json.Marshal(record2)
+	require.NoError(t, err)
+	var decoded AggregationRecord
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.NoError(t, decoded.Verify(domain, batch2, rav1, rav2))
+
+	// Record does not prove a different set of receipts
+	require.ErrorIs(t, record2.Verify(domain, batch1, rav1, rav2), ErrAggregationRecordMismatch)
+	require.ErrorIs(t, record2.Verify(domain, batch2, nil, rav2), ErrAggregationRecordMismatch)
+	require.ErrorIs(t, record1.Verify(domain, batch1, nil, rav2), ErrAggregationRecordMismatch)
+}
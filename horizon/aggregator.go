@@ -1,8 +1,14 @@
 package horizon
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"math/big"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
 
 	"github.com/streamingfast/eth-go"
 )
@@ -12,6 +18,7 @@ var (
 	ErrAggregateOverflow       = errors.New("aggregating receipt results in overflow")
 	ErrDuplicateSignature      = errors.New("duplicate receipt signature detected")
 	ErrInvalidTimestamp        = errors.New("receipt timestamp not greater than previous RAV")
+	ErrInvalidReceiptValue     = errors.New("receipt value is not a uint128")
 	ErrCollectionMismatch      = errors.New("receipts have different collection IDs")
 	ErrPayerMismatch           = errors.New("receipts have different payer addresses")
 	ErrServiceProviderMismatch = errors.New("receipts have different service provider addresses")
@@ -20,24 +27,40 @@ var (
 	ErrRAVSignerMismatch       = errors.New("previous RAV signed by unauthorized signer")
 )
 
-// Aggregator handles receipt validation and RAV generation
+// Aggregator handles receipt validation and RAV generation.
+//
+// An Aggregator is safe for concurrent use. Aggregations of different
+// collections run in parallel while aggregations of the same collection are
+// serialized, so registered MetadataValidators observe the RAVs of a collection
+// one at a time and may keep per-collection state without their own locking.
 type Aggregator struct {
 	domain          *Domain
-	signerKey       *eth.PrivateKey
+	signer          Signer
 	acceptedSigners map[string]bool
+	locks           collectionLocks
+	options
 }
 
-// NewAggregator creates a new RAV aggregator
-func NewAggregator(domain *Domain, signerKey *eth.PrivateKey, acceptedSigners []eth.Address) *Aggregator {
+// NewAggregator creates a new RAV aggregator signing RAVs with signerKey, or
+// with the signer given through WithSigner
+func NewAggregator(domain *Domain, signerKey *eth.PrivateKey, acceptedSigners []eth.Address, opts ...Option) *Aggregator {
 	signerMap := make(map[string]bool, len(acceptedSigners))
 	for _, addr := range acceptedSigners {
 		signerMap[addr.Pretty()] = true
 	}
 
+	o := newOptions(opts)
+	signer := o.signer
+	if signer == nil {
+		signer = NewLocalSigner(signerKey)
+	}
+
 	return &Aggregator{
 		domain:          domain,
-		signerKey:       signerKey,
+		signer:          signer,
 		acceptedSigners: signerMap,
+		locks:           collectionLocks{locks: make(map[CollectionID]*collectionLock)},
+		options:         o,
 	}
 }
 
@@ -46,53 +69,132 @@ func (a *Aggregator) AggregateReceipts(
 	receipts []*SignedReceipt,
 	previousRAV *SignedRAV,
 ) (*SignedRAV, error) {
+	signedRAV, _, err := a.AggregateReceiptsWithRecord(receipts, previousRAV)
+	return signedRAV, err
+}
 
+// AggregateReceiptsWithRecord validates receipts and creates a signed RAV along
+// with the AggregationRecord proving which receipts back it
+func (a *Aggregator) AggregateReceiptsWithRecord(
+	receipts []*SignedReceipt,
+	previousRAV *SignedRAV,
+) (*SignedRAV, *AggregationRecord, error) {
 	if len(receipts) == 0 {
-		return nil, ErrNoReceipts
+		return nil, nil, ErrNoReceipts
 	}
 
-	// Validate signatures are unique (malleability protection)
-	if err := a.checkSignaturesUnique(receipts); err != nil {
-		return nil, err
+	// Serialize aggregations of the collection, receipts of other collections
+	// are rejected below by the consistency checks
+	unlock := a.locks.lock(receipts[0].Message.CollectionID)
+	defer unlock()
+
+	// Validate signatures are unique (malleability protection), exact
+	// duplicates being dropped instead when tolerated
+	receipts, dropped, err := a.checkSignaturesUnique(receipts)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Verify all receipts are from accepted signers
+	// Verify all receipts are from accepted signers before sorting them, errors
+	// naming the first failing receipt by its index in the caller's order
+	// (exact duplicates dropped)
 	if err := a.verifyReceiptSigners(receipts); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
+	// Aggregate in the canonical order whatever the order receipts were given
+	// in, without reordering the caller's slice
+	receipts = slices.Clone(receipts)
+	SortReceipts(receipts)
+
 	// Verify previous RAV signer if present
 	if previousRAV != nil {
 		if err := a.verifyRAVSigner(previousRAV); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
-	// Check receipt timestamps are after previous RAV
-	if err := checkReceiptTimestamps(receipts, previousRAV); err != nil {
-		return nil, err
+	// Check receipt values, timestamps and fields against each other and the
+	// previous RAV
+	if err := ValidateReceipts(receipts, previousRAV); err != nil {
+		return nil, nil, err
 	}
 
-	// Validate field consistency across all receipts
-	if err := validateReceiptConsistency(receipts); err != nil {
-		return nil, err
+	// Perform aggregation
+	rav, err := aggregate(receipts, previousRAV)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Verify previous RAV fields match receipts
-	if previousRAV != nil {
-		if err := validateRAVConsistency(receipts[0].Message, previousRAV.Message); err != nil {
-			return nil, err
+	if a.receiptsMerkleRoot {
+		tree, err := ReceiptsMerkleTree(a.domain, receipts, previousRAV)
+		if err != nil {
+			return nil, nil, fmt.Errorf("building receipts merkle tree: %w", err)
 		}
+		rav.Metadata = EncodeReceiptsRootMetadata(rav.CollectionID, tree.Root())
 	}
 
-	// Perform aggregation
-	rav, err := aggregate(receipts, previousRAV)
+	if err := a.validateMetadata(previousRAV, rav, receipts); err != nil {
+		return nil, nil, err
+	}
+
+	signedRAV, err := SignWith(a.domain, rav, a.signer)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	// Sign and return
-	return Sign(a.domain, rav, a.signerKey)
+	record, err := NewAggregationRecord(a.domain, receipts, previousRAV, signedRAV)
+	if err != nil {
+		return nil, nil, fmt.Errorf("building aggregation record: %w", err)
+	}
+	record.DroppedDuplicates = dropped
+
+	return signedRAV, record, nil
+}
+
+// collectionLocks hands out one mutex per collection, dropped once no
+// aggregation holds or waits for it so the map does not grow with every
+// collection ever seen
+type collectionLocks struct {
+	mu    sync.Mutex
+	locks map[CollectionID]*collectionLock
+}
+
+type collectionLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// lock blocks until the collection lock is acquired and returns its release
+func (l *collectionLocks) lock(id CollectionID) (unlock func()) {
+	l.mu.Lock()
+	entry, found := l.locks[id]
+	if !found {
+		entry = &collectionLock{}
+		l.locks[id] = entry
+	}
+	entry.refs++
+	l.mu.Unlock()
+
+	entry.mu.Lock()
+	return func() {
+		entry.mu.Unlock()
+
+		l.mu.Lock()
+		entry.refs--
+		if entry.refs == 0 {
+			delete(l.locks, id)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// held returns the number of collections with an aggregation in progress or
+// waiting
+func (l *collectionLocks) held() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.locks)
 }
 
 // aggregate creates a RAV from validated receipts
@@ -136,27 +238,127 @@ func aggregate(receipts []*SignedReceipt, previousRAV *SignedRAV) (*RAV, error)
 	}, nil
 }
 
-func (a *Aggregator) checkSignaturesUnique(receipts []*SignedReceipt) error {
-	seen := make(map[[65]byte]bool, len(receipts))
-	for _, r := range receipts {
+// checkSignaturesUnique returns ErrDuplicateSignature when two receipts share
+// a signature in normalized form, see UniqueSignedMessages
+func (a *Aggregator) checkSignaturesUnique(receipts []*SignedReceipt) ([]*SignedReceipt, int, error) {
+	return UniqueSignedMessages(a.domain, receipts, a.dropDuplicateReceipts)
+}
+
+// UniqueSignedMessages returns ErrDuplicateSignature when two of messages
+// share a signature in normalized form. When dropDuplicates is set (see
+// WithDuplicateReceiptTolerance), exact duplicates (same message, same
+// signature bytes) are dropped instead and counted, only malleated variants of
+// a signature are rejected. The messages kept are returned in order.
+func UniqueSignedMessages[T EIP712Encodable](domain *Domain, messages []*SignedMessage[T], dropDuplicates bool) ([]*SignedMessage[T], int, error) {
+	seen := make(map[[65]byte]*SignedMessage[T], len(messages))
+	unique := make([]*SignedMessage[T], 0, len(messages))
+	for _, r := range messages {
 		normalized := normalizeSignature(r.Signature)
-		if seen[normalized] {
-			return ErrDuplicateSignature
+		first, found := seen[normalized]
+		if !found {
+			seen[normalized] = r
+			unique = append(unique, r)
+			continue
+		}
+
+		if !dropDuplicates {
+			return nil, 0, ErrDuplicateSignature
+		}
+		exact, err := isExactDuplicate(domain, first, r)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !exact {
+			return nil, 0, ErrDuplicateSignature
 		}
-		seen[normalized] = true
 	}
-	return nil
+	return unique, len(messages) - len(unique), nil
 }
 
+// isExactDuplicate reports whether other is a byte for byte copy of first, as resent by
+// a consumer retrying a request, rather than a malleated signature or another
+// receipt under the same signature
+func isExactDuplicate[T EIP712Encodable](domain *Domain, first, other *SignedMessage[T]) (bool, error) {
+	if first.Signature != other.Signature {
+		return false, nil
+	}
+
+	firstDigest, err := HashTypedData(domain, first.Message)
+	if err != nil {
+		return false, fmt.Errorf("computing receipt digest: %w", err)
+	}
+	otherDigest, err := HashTypedData(domain, other.Message)
+	if err != nil {
+		return false, fmt.Errorf("computing receipt digest: %w", err)
+	}
+	return bytes.Equal(firstDigest, otherDigest), nil
+}
+
+// parallelRecoveryThreshold is the number of receipts from which signatures
+// are recovered concurrently, spawning workers costs more than it saves below
+const parallelRecoveryThreshold = 64
+
+// verifyReceiptSigners checks that every receipt is signed by an accepted
+// signer. Signatures of large batches are recovered on GOMAXPROCS workers, the
+// error returned still being the one of the first failing receipt whatever the
+// order the workers ran in.
 func (a *Aggregator) verifyReceiptSigners(receipts []*SignedReceipt) error {
-	for _, r := range receipts {
-		signer, err := r.RecoverSigner(a.domain)
+	domainSep := a.domain.Separator()
+	verify := func(i int) error {
+		signer, err := receipts[i].Signature.Recover(hashTypedData(domainSep, receipts[i].Message))
 		if err != nil {
-			return err
+			return fmt.Errorf("receipt %d: recovering signer: %w", i, err)
 		}
 		if !a.acceptedSigners[signer.Pretty()] {
-			return ErrInvalidSigner
+			return fmt.Errorf("receipt %d: %w", i, ErrInvalidSigner)
+		}
+		return nil
+	}
+
+	workers := min(runtime.GOMAXPROCS(0), len(receipts))
+	if len(receipts) < parallelRecoveryThreshold || workers <= 1 {
+		for i := range receipts {
+			if err := verify(i); err != nil {
+				return err
+			}
 		}
+		return nil
+	}
+
+	// Indexes are handed out in order and firstFailure only decreases, so every
+	// receipt before the first failing one is verified while the ones after it
+	// are skipped
+	var next atomic.Int64
+	var firstFailure atomic.Int64
+	firstFailure.Store(int64(len(receipts)))
+	errs := make([]error, len(receipts))
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := next.Add(1) - 1
+				if i >= int64(len(receipts)) || i > firstFailure.Load() {
+					return
+				}
+				if errs[i] = verify(int(i)); errs[i] == nil {
+					continue
+				}
+				for {
+					failed := firstFailure.Load()
+					if i >= failed || firstFailure.CompareAndSwap(failed, i) {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if failed := firstFailure.Load(); failed < int64(len(receipts)) {
+		return errs[failed]
 	}
 	return nil
 }
@@ -172,6 +374,33 @@ func (a *Aggregator) verifyRAVSigner(rav *SignedRAV) error {
 	return nil
 }
 
+// ValidateReceipts applies the checks receipts must pass, signatures aside, to
+// be aggregated on top of previousRAV (nil for the first RAV of a collection):
+// values fit in uint128, timestamps are after previousRAV and every receipt is
+// for the same collection and parties as the others and previousRAV. Receipts
+// passing it can be buffered for a later aggregation without failing it, as
+// long as the aggregated value does not overflow.
+func ValidateReceipts(receipts []*SignedReceipt, previousRAV *SignedRAV) error {
+	for i, r := range receipts {
+		if err := CheckUint128(r.Message.Value); err != nil {
+			return fmt.Errorf("receipt %d: %w: %w", i, ErrInvalidReceiptValue, err)
+		}
+	}
+
+	if err := checkReceiptTimestamps(receipts, previousRAV); err != nil {
+		return err
+	}
+	if err := validateReceiptConsistency(receipts); err != nil {
+		return err
+	}
+	if previousRAV != nil && len(receipts) > 0 {
+		if err := validateRAVConsistency(receipts[0].Message, previousRAV.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func checkReceiptTimestamps(receipts []*SignedReceipt, previousRAV *SignedRAV) error {
 	if previousRAV == nil {
 		return nil
@@ -0,0 +1,173 @@
+package horizon
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The tests below are meant to run with the race detector (go test -race), it
+// flags any unsynchronized access to aggregator or metadata validator state.
+
+func newConcurrencyReceipt(t *testing.T, domain *Domain, key *eth.PrivateKey, collectionID CollectionID, timestampNs, nonce uint64) *SignedReceipt {
+	t.Helper()
+
+	signed, err := Sign(domain, &Receipt{
+		CollectionID:    collectionID,
+		Payer:           key.PublicKey().Address(),
+		DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+		ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		TimestampNs:     timestampNs,
+		Nonce:           nonce,
+		Value:           big.NewInt(100),
+	}, key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestAggregator_ConcurrentCollections(t *testing.T) {
+	domain := NewDomain(1, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+	senderKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	aggregatorKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	acceptedSigners := []eth.Address{senderKey.PublicKey().Address(), aggregatorKey.PublicKey().Address()}
+	aggregator := NewAggregator(domain, aggregatorKey, acceptedSigners, WithReceiptsMerkleRoot())
+
+	const collections, rounds = 8, 5
+	finalRAVs := make([]*SignedRAV, collections)
+
+	var wg sync.WaitGroup
+	for c := 0; c < collections; c++ {
+		wg.Add(1)
+		go func(c int) {
+			defer wg.Done()
+
+			collectionID := CollectionID{byte(c + 1)}
+			var rav *SignedRAV
+			for round := 0; round < rounds; round++ {
+				receipts := []*SignedReceipt{
+					newConcurrencyReceipt(t, domain, senderKey, collectionID, uint64(round*2+1), 1),
+					newConcurrencyReceipt(t, domain, senderKey, collectionID, uint64(round*2+2), 2),
+				}
+
+				next, err := aggregator.AggregateReceipts(receipts, rav)
+				if !assert.NoError(t, err) {
+					return
+				}
+				rav = next
+			}
+			finalRAVs[c] = rav
+		}(c)
+	}
+	wg.Wait()
+
+	for c, rav := range finalRAVs {
+		require.NotNil(t, rav, "collection %d", c)
+		assert.Equal(t, CollectionID{byte(c + 1)}, rav.Message.CollectionID)
+		assert.Equal(t, int64(rounds*2*100), rav.Message.ValueAggregate.Int64())
+		assert.Equal(t, uint64(rounds*2), rav.Message.TimestampNs)
+	}
+	assert.Zero(t, aggregator.locks.held())
+}
+
+func TestAggregator_ConcurrentSameCollection(t *testing.T) {
+	domain := NewDomain(1, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+	senderKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	aggregatorKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	// Per-collection validator state deliberately unsynchronized, it relies on
+	// the aggregator serializing aggregations of a collection
+	type validatorState struct {
+		inFlight    int
+		maxInFlight int
+		calls       int
+	}
+
+	const collections, workers = 4, 8
+	states := make(map[CollectionID]*validatorState, collections)
+	for c := 0; c < collections; c++ {
+		states[CollectionID{byte(c + 1)}] = &validatorState{}
+	}
+
+	aggregator := NewAggregator(domain, aggregatorKey, []eth.Address{senderKey.PublicKey().Address()},
+		WithMetadataValidator(func(previous *RAV, next *RAV, receipts []*SignedReceipt) error {
+			state := states[next.CollectionID]
+			state.inFlight++
+			state.maxInFlight = max(state.maxInFlight, state.inFlight)
+			state.calls++
+			time.Sleep(time.Millisecond)
+			state.inFlight--
+			return nil
+		}),
+	)
+
+	var wg sync.WaitGroup
+	for c := 0; c < collections; c++ {
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(c, w int) {
+				defer wg.Done()
+
+				receipt := newConcurrencyReceipt(t, domain, senderKey, CollectionID{byte(c + 1)}, 1, uint64(w))
+				_, err := aggregator.AggregateReceipts([]*SignedReceipt{receipt}, nil)
+				assert.NoError(t, err)
+			}(c, w)
+		}
+	}
+	wg.Wait()
+
+	for id, state := range states {
+		assert.Equal(t, workers, state.calls, "collection %x", id[:1])
+		assert.Equal(t, 1, state.maxInFlight, "collection %x", id[:1])
+	}
+	assert.Zero(t, aggregator.locks.held())
+}
+
+func TestAggregator_ConcurrentFailuresReleaseLocks(t *testing.T) {
+	domain := NewDomain(1, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+	senderKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	unknownKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	aggregatorKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	aggregator := NewAggregator(domain, aggregatorKey, []eth.Address{senderKey.PublicKey().Address()})
+	collectionID := CollectionID{1}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 16; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			key := senderKey
+			if w%2 == 1 {
+				key = unknownKey
+			}
+			receipt := newConcurrencyReceipt(t, domain, key, collectionID, 1, uint64(w))
+			_, err := aggregator.AggregateReceipts([]*SignedReceipt{receipt}, nil)
+			if w%2 == 1 {
+				assert.ErrorIs(t, err, ErrInvalidSigner)
+			} else {
+				assert.NoError(t, err)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	assert.Zero(t, aggregator.locks.held())
+
+	// The collection is usable again once every aggregation released its lock
+	_, err = aggregator.AggregateReceipts([]*SignedReceipt{newConcurrencyReceipt(t, domain, senderKey, collectionID, 2, 99)}, nil)
+	require.NoError(t, err)
+}
@@ -0,0 +1,208 @@
+package horizon
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// AggregatorAPIVersion is the version of the tap-rs aggregator JSON-RPC API
+// served by AggregatorServer
+const AggregatorAPIVersion = "0.0"
+
+// DefaultAggregatorMaxRequestSize bounds the JSON-RPC requests accepted by
+// AggregatorServer, as tap-aggregator does by default
+const DefaultAggregatorMaxRequestSize = 10 << 20
+
+// JSON-RPC error codes, the last two being those of tap-aggregator
+const (
+	jsonRPCParseError          = -32700
+	jsonRPCInvalidRequest      = -32600
+	jsonRPCMethodNotFound      = -32601
+	jsonRPCInvalidParams       = -32602
+	aggregatorInvalidVersion   = -32001
+	aggregatorAggregationError = -32002
+)
+
+// AggregatorServer serves an Aggregator over JSON-RPC with the request and
+// response schema of the tap-rs aggregator (tap-aggregator), so that indexer
+// stacks can point at it unchanged. Two methods are served:
+//   - aggregate_receipts(api_version, receipts, previous_rav) returning
+//     {"data": <signed RAV>}
+//   - api_versions() returning {"data": {"versions_supported": [...],
+//     "versions_deprecated": [...]}}
+//
+// Messages are encoded as TapSignedReceipt and TapSignedRAV.
+type AggregatorServer struct {
+	aggregator     *Aggregator
+	authToken      string
+	maxRequestSize int64
+}
+
+// NewAggregatorServer serves aggregator, requiring authToken as a bearer token
+// when not empty. Requests larger than maxRequestSize bytes are refused,
+// DefaultAggregatorMaxRequestSize is used when zero.
+func NewAggregatorServer(aggregator *Aggregator, authToken string, maxRequestSize int64) *AggregatorServer {
+	if maxRequestSize <= 0 {
+		maxRequestSize = DefaultAggregatorMaxRequestSize
+	}
+
+	return &AggregatorServer{
+		aggregator:     aggregator,
+		authToken:      authToken,
+		maxRequestSize: maxRequestSize,
+	}
+}
+
+type aggregatorRPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type aggregatorRPCResponse struct {
+	JSONRPC string              `json:"jsonrpc"`
+	ID      json.RawMessage     `json:"id"`
+	Result  any                 `json:"result,omitempty"`
+	Error   *aggregatorRPCError `json:"error,omitempty"`
+}
+
+type aggregatorRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// aggregatorResult wraps results as tap-aggregator does, no warning being
+// raised as there is no deprecated API version
+type aggregatorResult struct {
+	Data any `json:"data"`
+}
+
+type aggregatorVersions struct {
+	VersionsSupported  []string `json:"versions_supported"`
+	VersionsDeprecated []string `json:"versions_deprecated"`
+}
+
+// aggregateReceiptsParams are the parameters of aggregate_receipts, given by
+// position or by name
+type aggregateReceiptsParams struct {
+	APIVersion  string             `json:"api_version"`
+	Receipts    []TapSignedReceipt `json:"receipts"`
+	PreviousRAV *TapSignedRAV      `json:"previous_rav"`
+}
+
+func (p *aggregateReceiptsParams) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '[' {
+		type named aggregateReceiptsParams
+		return json.Unmarshal(data, (*named)(p))
+	}
+
+	var positional []json.RawMessage
+	if err := json.Unmarshal(data, &positional); err != nil {
+		return err
+	}
+	if len(positional) < 2 || len(positional) > 3 {
+		return fmt.Errorf("expected 2 or 3 parameters, got %d", len(positional))
+	}
+	if err := json.Unmarshal(positional[0], &p.APIVersion); err != nil {
+		return fmt.Errorf("api_version: %w", err)
+	}
+	if err := json.Unmarshal(positional[1], &p.Receipts); err != nil {
+		return fmt.Errorf("receipts: %w", err)
+	}
+	if len(positional) == 3 {
+		if err := json.Unmarshal(positional[2], &p.PreviousRAV); err != nil {
+			return fmt.Errorf("previous_rav: %w", err)
+		}
+	}
+	return nil
+}
+
+// ServeHTTP implements http.Handler
+func (s *AggregatorServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.authToken != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.authToken)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req aggregatorRPCRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.maxRequestSize)).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("request larger than %d bytes", s.maxRequestSize), http.StatusRequestEntityTooLarge)
+			return
+		}
+		s.writeResponse(w, nil, nil, &aggregatorRPCError{Code: jsonRPCParseError, Message: err.Error()})
+		return
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		s.writeResponse(w, req.ID, nil, &aggregatorRPCError{Code: jsonRPCInvalidRequest, Message: "invalid JSON-RPC 2.0 request"})
+		return
+	}
+
+	result, rpcErr := s.call(req.Method, req.Params)
+	s.writeResponse(w, req.ID, result, rpcErr)
+}
+
+func (s *AggregatorServer) call(method string, params json.RawMessage) (any, *aggregatorRPCError) {
+	switch method {
+	case "api_versions":
+		return &aggregatorResult{Data: &aggregatorVersions{
+			VersionsSupported:  []string{AggregatorAPIVersion},
+			VersionsDeprecated: []string{},
+		}}, nil
+
+	case "aggregate_receipts":
+		var in aggregateReceiptsParams
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, &aggregatorRPCError{Code: jsonRPCInvalidParams, Message: err.Error()}
+		}
+		if in.APIVersion != AggregatorAPIVersion {
+			return nil, &aggregatorRPCError{Code: aggregatorInvalidVersion, Message: fmt.Sprintf("unsupported API version %q, supported versions are [%s]", in.APIVersion, AggregatorAPIVersion)}
+		}
+
+		receipts := make([]*SignedReceipt, 0, len(in.Receipts))
+		for i, receipt := range in.Receipts {
+			if receipt.SignedReceipt == nil {
+				return nil, &aggregatorRPCError{Code: jsonRPCInvalidParams, Message: fmt.Sprintf("receipt %d is null", i)}
+			}
+			receipts = append(receipts, receipt.SignedReceipt)
+		}
+		var previousRAV *SignedRAV
+		if in.PreviousRAV != nil {
+			previousRAV = in.PreviousRAV.SignedRAV
+		}
+
+		rav, err := s.aggregator.AggregateReceipts(receipts, previousRAV)
+		if err != nil {
+			return nil, &aggregatorRPCError{Code: aggregatorAggregationError, Message: err.Error()}
+		}
+		return &aggregatorResult{Data: TapSignedRAV{rav}}, nil
+	}
+
+	return nil, &aggregatorRPCError{Code: jsonRPCMethodNotFound, Message: fmt.Sprintf("method %q not found", method)}
+}
+
+func (s *AggregatorServer) writeResponse(w http.ResponseWriter, id json.RawMessage, result any, rpcErr *aggregatorRPCError) {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&aggregatorRPCResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Result:  result,
+		Error:   rpcErr,
+	})
+}
@@ -0,0 +1,156 @@
+package horizon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTapSignedRAV_JSON(t *testing.T) {
+	domain := NewDomain(1, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+	key, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	signedRAV, err := Sign(domain, &RAV{
+		CollectionID:    CollectionID{0x01},
+		Payer:           eth.MustNewAddress("0x1111111111111111111111111111111111111111"),
+		ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+		TimestampNs:     42,
+		ValueAggregate:  big.NewInt(1000),
+		Metadata:        []byte{0xca, 0xfe},
+	}, key)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(TapSignedRAV{signedRAV})
+	require.NoError(t, err)
+
+	var out struct {
+		Message   map[string]any    `json:"message"`
+		Signature map[string]string `json:"signature"`
+	}
+	require.NoError(t, json.Unmarshal(data, &out))
+	assert.Equal(t, "0x1111111111111111111111111111111111111111", out.Message["payer"])
+	assert.Equal(t, "0xcafe", out.Message["metadata"])
+	assert.Equal(t, "0x"+signedRAV.Signature.R().Text(16), out.Signature["r"])
+	assert.Contains(t, []string{"0x0", "0x1"}, out.Signature["yParity"])
+
+	// Decodes back to a RAV signed by the same key, from the tap-rs form as
+	// well as from the default encoding of SignedRAV
+	defaultData, err := json.Marshal(signedRAV)
+	require.NoError(t, err)
+	for _, in := range [][]byte{data, defaultData} {
+		var decoded TapSignedRAV
+		require.NoError(t, json.Unmarshal(in, &decoded))
+		assert.Equal(t, signedRAV.Message.Metadata, decoded.Message.Metadata)
+		assert.Equal(t, "1000", decoded.Message.ValueAggregate.String())
+
+		signer, err := decoded.RecoverSigner(domain)
+		require.NoError(t, err)
+		assert.Equal(t, key.PublicKey().Address().Pretty(), signer.Pretty())
+	}
+}
+
+func TestAggregatorServer(t *testing.T) {
+	domain := NewDomain(1, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+	senderKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	aggregatorKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	// Previous RAVs are signed by the aggregator key, accepted as a signer too
+	aggregator := NewAggregator(domain, aggregatorKey, []eth.Address{senderKey.PublicKey().Address(), aggregatorKey.PublicKey().Address()})
+	server := httptest.NewServer(NewAggregatorServer(aggregator, "secret", 0))
+	defer server.Close()
+
+	call := func(token string, method string, params any) (int, map[string]json.RawMessage) {
+		body, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 7, "method": method, "params": params})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, server.URL, bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var out map[string]json.RawMessage
+		if resp.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		}
+		return resp.StatusCode, out
+	}
+	errorCode := func(out map[string]json.RawMessage) int {
+		var rpcErr aggregatorRPCError
+		require.NoError(t, json.Unmarshal(out["error"], &rpcErr))
+		return rpcErr.Code
+	}
+
+	status, _ := call("wrong", "api_versions", []any{})
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	_, out := call("secret", "api_versions", []any{})
+	assert.JSONEq(t, `{"data":{"versions_supported":["0.0"],"versions_deprecated":[]}}`, string(out["result"]))
+	assert.JSONEq(t, `7`, string(out["id"]))
+
+	var receipts []TapSignedReceipt
+	for i := 0; i < 3; i++ {
+		receipt := NewReceipt(CollectionID{0x01}, senderKey.PublicKey().Address(), eth.MustNewAddress("0x2222222222222222222222222222222222222222"), eth.MustNewAddress("0x3333333333333333333333333333333333333333"), big.NewInt(100))
+		signed, err := Sign(domain, receipt, senderKey)
+		require.NoError(t, err)
+		receipts = append(receipts, TapSignedReceipt{signed})
+	}
+
+	_, out = call("secret", "aggregate_receipts", []any{AggregatorAPIVersion, receipts[:2], nil})
+	var first struct {
+		Data TapSignedRAV `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(out["result"], &first))
+	assert.Equal(t, "200", first.Data.Message.ValueAggregate.String())
+	signer, err := first.Data.RecoverSigner(domain)
+	require.NoError(t, err)
+	assert.Equal(t, aggregatorKey.PublicKey().Address().Pretty(), signer.Pretty())
+
+	// Named parameters, aggregating on top of the previous RAV
+	_, out = call("secret", "aggregate_receipts", map[string]any{
+		"api_version":  AggregatorAPIVersion,
+		"receipts":     receipts[2:],
+		"previous_rav": first.Data,
+	})
+	var second struct {
+		Data TapSignedRAV `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(out["result"], &second))
+	assert.Equal(t, "300", second.Data.Message.ValueAggregate.String())
+
+	_, out = call("secret", "aggregate_receipts", []any{"1.0", receipts, nil})
+	assert.Equal(t, aggregatorInvalidVersion, errorCode(out))
+
+	_, out = call("secret", "aggregate_receipts", []any{AggregatorAPIVersion, []any{}, nil})
+	assert.Equal(t, aggregatorAggregationError, errorCode(out))
+
+	_, out = call("secret", "aggregate_receipts", []any{AggregatorAPIVersion})
+	assert.Equal(t, jsonRPCInvalidParams, errorCode(out))
+
+	_, out = call("secret", "unknown_method", []any{})
+	assert.Equal(t, jsonRPCMethodNotFound, errorCode(out))
+}
+
+func TestAggregatorServer_MaxRequestSize(t *testing.T) {
+	server := httptest.NewServer(NewAggregatorServer(nil, "", 64))
+	defer server.Close()
+
+	body := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"api_versions","params":[%q]}`, bytes.Repeat([]byte("a"), 64))
+	resp, err := http.Post(server.URL, "application/json", bytes.NewReader([]byte(body)))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+}
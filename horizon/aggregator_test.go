@@ -1,11 +1,15 @@
 package horizon
 
 import (
+	"errors"
+	"fmt"
 	"math/big"
+	"runtime"
 	"testing"
 	"time"
 
 	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -170,6 +174,64 @@ func TestAggregator_DuplicateSignature(t *testing.T) {
 	require.ErrorIs(t, err, ErrDuplicateSignature)
 }
 
+func TestAggregator_DuplicateReceiptTolerance(t *testing.T) {
+	domain := NewDomain(1, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+
+	senderKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	aggregatorKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	aggregator := NewAggregator(domain, aggregatorKey, []eth.Address{senderKey.PublicKey().Address()}, WithDuplicateReceiptTolerance())
+
+	newReceipt := func(nonce uint64) *SignedReceipt {
+		signed, err := Sign(domain, &Receipt{
+			Payer:           senderKey.PublicKey().Address(),
+			DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+			ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+			TimestampNs:     uint64(time.Now().UnixNano()),
+			Nonce:           nonce,
+			Value:           big.NewInt(100),
+		}, senderKey)
+		require.NoError(t, err)
+		return signed
+	}
+
+	t.Run("exact duplicates dropped", func(t *testing.T) {
+		first, second := newReceipt(1), newReceipt(2)
+		retried := &SignedReceipt{Message: first.Message, Signature: first.Signature}
+
+		rav, record, err := aggregator.AggregateReceiptsWithRecord([]*SignedReceipt{first, second, retried, second}, nil)
+		require.NoError(t, err)
+		require.Equal(t, big.NewInt(200), rav.Message.ValueAggregate)
+		require.Equal(t, 2, record.DroppedDuplicates)
+		require.Len(t, record.ReceiptDigests, 2)
+		require.NoError(t, record.Verify(domain, []*SignedReceipt{first, second}, nil, rav))
+	})
+
+	t.Run("malleated variant rejected", func(t *testing.T) {
+		receipt := newReceipt(1)
+
+		// Same signature in its high-S form
+		malleated := &SignedReceipt{Message: receipt.Message, Signature: receipt.Signature}
+		s := new(big.Int).Sub(secp256k1N, new(big.Int).SetBytes(receipt.Signature[32:64]))
+		clear(malleated.Signature[32:64])
+		s.FillBytes(malleated.Signature[32:64])
+		malleated.Signature[64] ^= 1
+
+		_, err := aggregator.AggregateReceipts([]*SignedReceipt{receipt, malleated}, nil)
+		require.ErrorIs(t, err, ErrDuplicateSignature)
+	})
+
+	t.Run("other receipt under the same signature rejected", func(t *testing.T) {
+		receipt := newReceipt(1)
+		forged := &SignedReceipt{Message: newReceipt(2).Message, Signature: receipt.Signature}
+
+		_, err := aggregator.AggregateReceipts([]*SignedReceipt{receipt, forged}, nil)
+		require.ErrorIs(t, err, ErrDuplicateSignature)
+	})
+}
+
 func TestAggregator_InvalidTimestamp(t *testing.T) {
 	chainID := uint64(1)
 	verifyingContract := eth.MustNewAddress("0x1234567890123456789012345678901234567890")
@@ -357,3 +419,195 @@ func TestAggregator_NoReceipts(t *testing.T) {
 	_, err = aggregator.AggregateReceipts([]*SignedReceipt{}, nil)
 	require.ErrorIs(t, err, ErrNoReceipts)
 }
+
+func TestValidateReceipts(t *testing.T) {
+	var collectionID, otherCollectionID CollectionID
+	otherCollectionID[0] = 1
+
+	receipt := func(collectionID CollectionID, timestampNs uint64, value *big.Int) *SignedReceipt {
+		return &SignedReceipt{Message: &Receipt{
+			CollectionID:    collectionID,
+			Payer:           eth.MustNewAddress("0x1111111111111111111111111111111111111111"),
+			DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+			ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+			TimestampNs:     timestampNs,
+			Value:           value,
+		}}
+	}
+	previousRAV := &SignedRAV{Message: &RAV{
+		CollectionID:    collectionID,
+		Payer:           eth.MustNewAddress("0x1111111111111111111111111111111111111111"),
+		DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+		ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		TimestampNs:     10,
+		ValueAggregate:  big.NewInt(100),
+	}}
+
+	require.NoError(t, ValidateReceipts([]*SignedReceipt{receipt(collectionID, 11, big.NewInt(1)), receipt(collectionID, 12, MaxUint128)}, previousRAV))
+
+	assert.ErrorIs(t, ValidateReceipts([]*SignedReceipt{receipt(collectionID, 11, nil)}, nil), ErrInvalidReceiptValue)
+	assert.ErrorIs(t, ValidateReceipts([]*SignedReceipt{receipt(collectionID, 11, big.NewInt(-1))}, nil), ErrInvalidReceiptValue)
+	assert.ErrorIs(t, ValidateReceipts([]*SignedReceipt{receipt(collectionID, 11, new(big.Int).Add(MaxUint128, big.NewInt(1)))}, nil), ErrInvalidReceiptValue)
+	assert.ErrorIs(t, ValidateReceipts([]*SignedReceipt{receipt(collectionID, 10, big.NewInt(1))}, previousRAV), ErrInvalidTimestamp)
+	assert.ErrorIs(t, ValidateReceipts([]*SignedReceipt{receipt(collectionID, 11, big.NewInt(1)), receipt(otherCollectionID, 12, big.NewInt(1))}, nil), ErrCollectionMismatch)
+	assert.ErrorIs(t, ValidateReceipts([]*SignedReceipt{receipt(otherCollectionID, 11, big.NewInt(1))}, previousRAV), ErrCollectionMismatch)
+}
+
+func TestAggregator_MetadataValidator(t *testing.T) {
+	chainID := uint64(1)
+	verifyingContract := eth.MustNewAddress("0x1234567890123456789012345678901234567890")
+	domain := NewDomain(chainID, verifyingContract)
+
+	senderKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	aggregatorKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	errTooManyReceipts := errors.New("too many receipts")
+	var seenReceipts int
+	aggregator := NewAggregator(domain, aggregatorKey, []eth.Address{senderKey.PublicKey().Address()},
+		WithMetadataValidator(func(previous *RAV, next *RAV, receipts []*SignedReceipt) error {
+			seenReceipts = len(receipts)
+			if len(receipts) > 1 {
+				return errTooManyReceipts
+			}
+			return nil
+		}),
+	)
+
+	newReceipt := func(nonce uint64) *SignedReceipt {
+		signed, err := Sign(domain, &Receipt{
+			Payer:           senderKey.PublicKey().Address(),
+			DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+			ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+			TimestampNs:     uint64(time.Now().UnixNano()),
+			Nonce:           nonce,
+			Value:           big.NewInt(100),
+		}, senderKey)
+		require.NoError(t, err)
+		return signed
+	}
+
+	_, err = aggregator.AggregateReceipts([]*SignedReceipt{newReceipt(1)}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, seenReceipts)
+
+	_, err = aggregator.AggregateReceipts([]*SignedReceipt{newReceipt(2), newReceipt(3)}, nil)
+	require.ErrorIs(t, err, ErrMetadataRejected)
+	require.ErrorIs(t, err, errTooManyReceipts)
+}
+
+func TestAggregator_WithSigner(t *testing.T) {
+	domain := NewDomain(1, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+
+	senderKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	aggregatorKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	aggregator := NewAggregator(domain, nil, []eth.Address{senderKey.PublicKey().Address()}, WithSigner(NewLocalSigner(aggregatorKey)))
+
+	receipt, err := Sign(domain, &Receipt{
+		Payer:           senderKey.PublicKey().Address(),
+		DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+		ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		TimestampNs:     uint64(time.Now().UnixNano()),
+		Value:           big.NewInt(100),
+	}, senderKey)
+	require.NoError(t, err)
+
+	signedRAV, err := aggregator.AggregateReceipts([]*SignedReceipt{receipt}, nil)
+	require.NoError(t, err)
+
+	signer, err := signedRAV.RecoverSigner(domain)
+	require.NoError(t, err)
+	require.True(t, addressesEqual(aggregatorKey.PublicKey().Address(), signer))
+}
+
+// newSignedReceiptBatch signs count receipts of a single collection, with
+// increasing timestamps
+func newSignedReceiptBatch(tb testing.TB, domain *Domain, key *eth.PrivateKey, count int) []*SignedReceipt {
+	tb.Helper()
+
+	start := uint64(time.Now().UnixNano())
+	receipts := make([]*Receipt, count)
+	for i := range receipts {
+		receipts[i] = &Receipt{
+			Payer:           key.PublicKey().Address(),
+			DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+			ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+			TimestampNs:     start + uint64(i),
+			Nonce:           uint64(i),
+			Value:           big.NewInt(100),
+		}
+	}
+
+	signed, err := SignBatch(domain, receipts, ParallelKey(key, 0))
+	require.NoError(tb, err)
+	return signed
+}
+
+func TestAggregator_ParallelSignerVerification(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	domain := NewDomain(1, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+	senderKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	otherKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	aggregatorKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	aggregator := NewAggregator(domain, aggregatorKey, []eth.Address{senderKey.PublicKey().Address()})
+	receipts := newSignedReceiptBatch(t, domain, senderKey, 4*parallelRecoveryThreshold)
+
+	signedRAV, err := aggregator.AggregateReceipts(receipts, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(100*len(receipts)), signedRAV.Message.ValueAggregate.Int64())
+
+	// Receipts re-signed by an unauthorized key, the first one in the given
+	// order is reported whichever worker recovers it first
+	resign := func(i int) {
+		signed, err := Sign(domain, receipts[i].Message, otherKey)
+		require.NoError(t, err)
+		receipts[i] = signed
+	}
+	resign(200)
+	resign(130)
+	resign(250)
+
+	for range 10 {
+		_, err = aggregator.AggregateReceipts(receipts, nil)
+		require.ErrorIs(t, err, ErrInvalidSigner)
+		require.ErrorContains(t, err, "receipt 130:")
+	}
+}
+
+func BenchmarkAggregator_AggregateReceipts(b *testing.B) {
+	domain := NewDomain(1, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+	senderKey, err := eth.NewRandomPrivateKey()
+	require.NoError(b, err)
+	aggregatorKey, err := eth.NewRandomPrivateKey()
+	require.NoError(b, err)
+
+	aggregator := NewAggregator(domain, aggregatorKey, []eth.Address{senderKey.PublicKey().Address()})
+	receipts := newSignedReceiptBatch(b, domain, senderKey, 10_000)
+
+	procsList := []int{1}
+	if cpus := runtime.NumCPU(); cpus > 1 {
+		procsList = append(procsList, cpus)
+	}
+
+	for _, procs := range procsList {
+		b.Run(fmt.Sprintf("receipts=%d/procs=%d", len(receipts), procs), func(b *testing.B) {
+			defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+
+			for b.Loop() {
+				if _, err := aggregator.AggregateReceipts(receipts, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(len(receipts)*b.N)/b.Elapsed().Seconds(), "receipts/s")
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package horizon
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/streamingfast/eth-go"
+)
+
+// RAVParties are the accounts a RAV settles between: the payer whose escrow
+// pays, the service provider paid and the data service collecting on its behalf
+type RAVParties struct {
+	Payer           eth.Address
+	ServiceProvider eth.Address
+	DataService     eth.Address
+}
+
+// NewBootstrapRAV returns the zero-value RAV (RAV0) a session starts from. It
+// establishes the session parties without committing to any value: its value
+// aggregate is zero, its metadata empty and it is timestamped with clock,
+// time.Now when nil.
+func NewBootstrapRAV(parties RAVParties, collectionID CollectionID, clock func() time.Time) *RAV {
+	if clock == nil {
+		clock = time.Now
+	}
+
+	return &RAV{
+		CollectionID:    collectionID,
+		Payer:           parties.Payer,
+		ServiceProvider: parties.ServiceProvider,
+		DataService:     parties.DataService,
+		TimestampNs:     uint64(clock().UnixNano()),
+		ValueAggregate:  big.NewInt(0),
+	}
+}
+
+// SignBootstrapRAV signs the RAV returned by NewBootstrapRAV with signer
+func SignBootstrapRAV(domain *Domain, parties RAVParties, collectionID CollectionID, clock func() time.Time, signer Signer) (*SignedRAV, error) {
+	return SignWith(domain, NewBootstrapRAV(parties, collectionID, clock), signer)
+}
+
+// IsBootstrap returns true if the RAV is a zero-value bootstrap RAV, as
+// returned by NewBootstrapRAV
+func (r *RAV) IsBootstrap() bool {
+	return r.ValueAggregate == nil || r.ValueAggregate.Sign() == 0
+}
@@ -0,0 +1,49 @@
+package horizon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBootstrapRAV(t *testing.T) {
+	parties := RAVParties{
+		Payer:           eth.MustNewAddress("0x1111111111111111111111111111111111111111"),
+		ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+	}
+	now := time.Unix(1700000000, 42)
+
+	rav := NewBootstrapRAV(parties, CollectionID{0x01}, func() time.Time { return now })
+	assert.Equal(t, CollectionID{0x01}, rav.CollectionID)
+	assert.True(t, addressesEqual(parties.Payer, rav.Payer))
+	assert.True(t, addressesEqual(parties.ServiceProvider, rav.ServiceProvider))
+	assert.True(t, addressesEqual(parties.DataService, rav.DataService))
+	assert.Equal(t, uint64(now.UnixNano()), rav.TimestampNs)
+	assert.Equal(t, 0, rav.ValueAggregate.Sign())
+	assert.Empty(t, rav.Metadata)
+	assert.True(t, rav.IsBootstrap())
+
+	rav.ValueAggregate.SetInt64(1)
+	assert.False(t, rav.IsBootstrap())
+
+	// Without a clock, the RAV is stamped with the current time
+	before := time.Now()
+	rav = NewBootstrapRAV(parties, CollectionID{}, nil)
+	assert.GreaterOrEqual(t, rav.TimestampNs, uint64(before.UnixNano()))
+
+	domain := NewDomain(1337, eth.MustNewAddress("0x4444444444444444444444444444444444444444"))
+	key, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	signed, err := SignBootstrapRAV(domain, parties, CollectionID{0x01}, func() time.Time { return now }, NewLocalSigner(key))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(now.UnixNano()), signed.Message.TimestampNs)
+
+	signer, err := signed.RecoverSigner(domain)
+	require.NoError(t, err)
+	assert.True(t, addressesEqual(key.PublicKey().Address(), signer))
+}
@@ -0,0 +1,319 @@
+package horizon
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/streamingfast/eth-go"
+)
+
+var (
+	// DataServiceCollectMethod is SubstreamsDataService.collect(serviceProvider, paymentType, data)
+	DataServiceCollectMethod = eth.MustNewMethodDef("collect(address,uint8,bytes)")
+	// TokensCollectedMethod is GraphTallyCollector.tokensCollected(dataService, collectionId, receiver, payer)
+	TokensCollectedMethod = eth.MustNewMethodDef("tokensCollected(address,bytes32,address,address)")
+)
+
+// RAVCollectedEvent is GraphTallyCollector.RAVCollected, emitted for each RAV
+// collected with the collected RAV fields. Its indexed parameters are
+// collectionId, payer and dataService.
+var RAVCollectedEvent = mustParseRAVCollectedEvent()
+
+func mustParseRAVCollectedEvent() *eth.LogEventDef {
+	abi, err := eth.ParseABIFromBytes([]byte(`{
+		"abi": [{
+			"type": "event",
+			"name": "RAVCollected",
+			"anonymous": false,
+			"inputs": [
+				{"name": "collectionId", "type": "bytes32", "indexed": true},
+				{"name": "payer", "type": "address", "indexed": true},
+				{"name": "serviceProvider", "type": "address", "indexed": false},
+				{"name": "dataService", "type": "address", "indexed": true},
+				{"name": "timestampNs", "type": "uint64", "indexed": false},
+				{"name": "valueAggregate", "type": "uint128", "indexed": false},
+				{"name": "metadata", "type": "bytes", "indexed": false},
+				{"name": "signature", "type": "bytes", "indexed": false}
+			]
+		}]
+	}`))
+	if err != nil {
+		panic(fmt.Sprintf("parsing RAVCollected event ABI: %v", err))
+	}
+
+	return abi.FindLogByName("RAVCollected")
+}
+
+// ErrInvalidCollectData is returned when ABI encoded collect data, or a
+// SignedRAV tuple, cannot be decoded
+var ErrInvalidCollectData = errors.New("invalid collect data")
+
+// signedRAVComponents is the ABI definition of the IGraphTallyCollector.SignedRAV tuple
+const signedRAVComponents = `[
+	{
+		"name": "rav",
+		"type": "tuple",
+		"components": [
+			{"name": "collectionId", "type": "bytes32"},
+			{"name": "payer", "type": "address"},
+			{"name": "serviceProvider", "type": "address"},
+			{"name": "dataService", "type": "address"},
+			{"name": "timestampNs", "type": "uint64"},
+			{"name": "valueAggregate", "type": "uint128"},
+			{"name": "metadata", "type": "bytes"}
+		]
+	},
+	{"name": "signature", "type": "bytes"}
+]`
+
+// collectDataEncoder and signedRAVEncoder are synthetic ABIs used to encode the
+// data parameter of SubstreamsDataService.collect, abi.encode(SignedRAV,
+// uint256 dataServiceCut), and a lone SignedRAV tuple, abi.encode(SignedRAV)
+var collectDataEncoder, signedRAVEncoder = mustParseCollectDataEncoders()
+
+func mustParseCollectDataEncoders() (*eth.MethodDef, *eth.MethodDef) {
+	abi, err := eth.ParseABIFromBytes([]byte(`{
+		"abi": [{
+			"type": "function",
+			"name": "encode",
+			"inputs": [
+				{"name": "signedRAV", "type": "tuple", "components": ` + signedRAVComponents + `},
+				{"name": "dataServiceCut", "type": "uint256"}
+			]
+		}, {
+			"type": "function",
+			"name": "encodeSignedRAV",
+			"inputs": [
+				{"name": "signedRAV", "type": "tuple", "components": ` + signedRAVComponents + `}
+			]
+		}]
+	}`))
+	if err != nil {
+		panic(fmt.Sprintf("parsing collect data encoder ABI: %v", err))
+	}
+
+	return abi.FindFunctionByName("encode"), abi.FindFunctionByName("encodeSignedRAV")
+}
+
+// EncodeCollectData encodes the data parameter of SubstreamsDataService.collect
+// for signedRAV, dataServiceCut is the share of the collected tokens requested
+// for the data service
+func EncodeCollectData(signedRAV *SignedRAV, dataServiceCut PPM) ([]byte, error) {
+	if signedRAV == nil || signedRAV.Message == nil {
+		return nil, ErrRAVMissing
+	}
+	if err := dataServiceCut.Validate(); err != nil {
+		return nil, fmt.Errorf("data service cut: %w", err)
+	}
+
+	data, err := collectDataEncoder.NewCall(signedRAVTuple(signedRAV), dataServiceCut.BigInt()).Encode()
+	if err != nil {
+		return nil, fmt.Errorf("encoding collect data: %w", err)
+	}
+
+	// Strip the synthetic method selector, only the ABI encoded arguments are wanted
+	return data[4:], nil
+}
+
+// EncodeDataServiceCollect encodes the complete SubstreamsDataService.collect
+// call collecting signedRAV on behalf of its service provider as query fees
+func EncodeDataServiceCollect(signedRAV *SignedRAV, dataServiceCut PPM) ([]byte, error) {
+	return EncodeDataServiceCollectWithPaymentType(signedRAV, PaymentTypeQueryFee, dataServiceCut)
+}
+
+// EncodeDataServiceCollectWithPaymentType encodes the complete
+// SubstreamsDataService.collect call collecting signedRAV under paymentType
+func EncodeDataServiceCollectWithPaymentType(signedRAV *SignedRAV, paymentType uint8, dataServiceCut PPM) ([]byte, error) {
+	if _, err := PaymentTypeName(paymentType); err != nil {
+		return nil, err
+	}
+
+	data, err := EncodeCollectData(signedRAV, dataServiceCut)
+	if err != nil {
+		return nil, err
+	}
+
+	call, err := DataServiceCollectMethod.NewCall(signedRAV.Message.ServiceProvider, paymentType, data).Encode()
+	if err != nil {
+		return nil, fmt.Errorf("encoding collect call: %w", err)
+	}
+	return call, nil
+}
+
+// EncodeSignedRAV ABI encodes signedRAV as a lone IGraphTallyCollector.SignedRAV
+// tuple, abi.encode(SignedRAV), with the signature in R+S+V order
+func EncodeSignedRAV(signedRAV *SignedRAV) ([]byte, error) {
+	if signedRAV == nil || signedRAV.Message == nil {
+		return nil, ErrRAVMissing
+	}
+
+	data, err := signedRAVEncoder.NewCall(signedRAVTuple(signedRAV)).Encode()
+	if err != nil {
+		return nil, fmt.Errorf("encoding signed RAV: %w", err)
+	}
+	return data[4:], nil
+}
+
+// DecodeSignedRAV decodes a SignedRAV tuple encoded by EncodeSignedRAV
+func DecodeSignedRAV(data []byte) (*SignedRAV, error) {
+	values, err := decodeArguments(signedRAVEncoder, data)
+	if err != nil {
+		return nil, err
+	}
+	return signedRAVFromTuple(values[0])
+}
+
+// DecodeCollectData decodes the data parameter of SubstreamsDataService.collect
+// encoded by EncodeCollectData
+func DecodeCollectData(data []byte) (*SignedRAV, PPM, error) {
+	values, err := decodeArguments(collectDataEncoder, data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	signedRAV, err := signedRAVFromTuple(values[0])
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cut, ok := values[1].(*big.Int)
+	if !ok || !cut.IsUint64() {
+		return nil, 0, fmt.Errorf("%w: data service cut is not a uint256", ErrInvalidCollectData)
+	}
+	dataServiceCut, err := NewPPM(cut.Uint64())
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: data service cut: %w", ErrInvalidCollectData, err)
+	}
+	return signedRAV, dataServiceCut, nil
+}
+
+// DataServiceCollect is a decoded SubstreamsDataService.collect call
+type DataServiceCollect struct {
+	ServiceProvider eth.Address
+	PaymentType     uint8
+	SignedRAV       *SignedRAV
+	DataServiceCut  PPM
+}
+
+// DecodeDataServiceCollect decodes a complete SubstreamsDataService.collect
+// call encoded by EncodeDataServiceCollect, method selector included
+func DecodeDataServiceCollect(call []byte) (*DataServiceCollect, error) {
+	if len(call) < 4 || !bytes.Equal(call[:4], DataServiceCollectMethod.MethodID()) {
+		return nil, fmt.Errorf("%w: not a %s call", ErrInvalidCollectData, DataServiceCollectMethod.Signature())
+	}
+
+	values, err := eth.NewDecoder(call[4:]).ReadOutput(DataServiceCollectMethod.Parameters)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCollectData, err)
+	}
+
+	serviceProvider, _ := values[0].(eth.Address)
+	paymentType, _ := values[1].(uint8)
+	data, _ := values[2].([]byte)
+
+	signedRAV, dataServiceCut, err := DecodeCollectData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DataServiceCollect{
+		ServiceProvider: serviceProvider,
+		PaymentType:     paymentType,
+		SignedRAV:       signedRAV,
+		DataServiceCut:  dataServiceCut,
+	}, nil
+}
+
+// signedRAVTuple returns the ABI tuple of signedRAV, signature in R+S+V order
+func signedRAVTuple(signedRAV *SignedRAV) map[string]interface{} {
+	rav := signedRAV.Message
+	ravTuple := map[string]interface{}{
+		"collectionId":    rav.CollectionID[:],
+		"payer":           rav.Payer,
+		"serviceProvider": rav.ServiceProvider,
+		"dataService":     rav.DataService,
+		"timestampNs":     rav.TimestampNs,
+		"valueAggregate":  rav.ValueAggregate,
+		"metadata":        rav.Metadata,
+	}
+
+	return map[string]interface{}{
+		"rav":       ravTuple,
+		"signature": signatureToRSV(signedRAV.Signature),
+	}
+}
+
+// signedRAVFromTuple converts a decoded SignedRAV tuple back, the signature
+// into eth-go's V+R+S layout
+func signedRAVFromTuple(value interface{}) (*SignedRAV, error) {
+	tuple, ok := value.([]interface{})
+	if !ok || len(tuple) != 2 {
+		return nil, fmt.Errorf("%w: SignedRAV is not a (rav, signature) tuple", ErrInvalidCollectData)
+	}
+	ravTuple, ok := tuple[0].([]interface{})
+	if !ok || len(ravTuple) != 7 {
+		return nil, fmt.Errorf("%w: RAV is not a 7 fields tuple", ErrInvalidCollectData)
+	}
+
+	collectionID, ok1 := ravTuple[0].([]byte)
+	payer, ok2 := ravTuple[1].(eth.Address)
+	serviceProvider, ok3 := ravTuple[2].(eth.Address)
+	dataService, ok4 := ravTuple[3].(eth.Address)
+	timestampNs, ok5 := ravTuple[4].(uint64)
+	valueAggregate, ok6 := ravTuple[5].(*big.Int)
+	metadata, ok7 := ravTuple[6].([]byte)
+	if !ok1 || !ok2 || !ok3 || !ok4 || !ok5 || !ok6 || !ok7 {
+		return nil, fmt.Errorf("%w: unexpected RAV field types", ErrInvalidCollectData)
+	}
+
+	rsv, ok := tuple[1].([]byte)
+	if !ok || len(rsv) != 65 {
+		return nil, fmt.Errorf("%w: signature is not 65 bytes", ErrInvalidCollectData)
+	}
+
+	rav := &RAV{
+		Payer:           payer,
+		ServiceProvider: serviceProvider,
+		DataService:     dataService,
+		TimestampNs:     timestampNs,
+		ValueAggregate:  valueAggregate,
+		Metadata:        metadata,
+	}
+	copy(rav.CollectionID[:], collectionID)
+
+	return &SignedRAV{Message: rav, Signature: signatureFromRSV(rsv)}, nil
+}
+
+// decodeArguments decodes ABI encoded arguments of the synthetic encoder method
+func decodeArguments(encoder *eth.MethodDef, data []byte) ([]interface{}, error) {
+	values, err := eth.NewDecoder(data).ReadOutput(encoder.Parameters)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCollectData, err)
+	}
+	return values, nil
+}
+
+// signatureToRSV converts an eth-go V+R+S signature into the R+S+V layout
+// expected by Solidity's ECDSA.recover, V always being 27/28
+func signatureToRSV(sig eth.Signature) []byte {
+	rsv := make([]byte, 65)
+	copy(rsv[0:32], sig[1:33])
+	copy(rsv[32:64], sig[33:65])
+	rsv[64] = sig[0]
+	if v, ok := recoveryV(sig[0]); ok {
+		rsv[64] = v
+	}
+	return rsv
+}
+
+// signatureFromRSV converts a Solidity R+S+V signature back into eth-go's
+// V+R+S layout, the inverse of signatureToRSV
+func signatureFromRSV(rsv []byte) eth.Signature {
+	var sig eth.Signature
+	sig[0] = rsv[64]
+	copy(sig[1:33], rsv[0:32])
+	copy(sig[33:65], rsv[32:64])
+	return sig
+}
@@ -0,0 +1,116 @@
+package horizon
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeCollectData(t *testing.T) {
+	key, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	rav := validContractRAV()
+	rav.CollectionID = CollectionID{0xaa}
+	rav.Metadata = []byte("meta")
+
+	signedRAV, err := Sign(NewDomain(1337, eth.MustNewAddress("0x4444444444444444444444444444444444444444")), rav, key)
+	require.NoError(t, err)
+
+	data, err := EncodeCollectData(signedRAV, 100_000)
+	require.NoError(t, err)
+
+	// Head: offset of the dynamic SignedRAV tuple then the dataServiceCut
+	assert.Equal(t, big.NewInt(64), new(big.Int).SetBytes(data[0:32]))
+	assert.Equal(t, big.NewInt(100000), new(big.Int).SetBytes(data[32:64]))
+	assert.Equal(t, 0, len(data)%32)
+
+	sig := signedRAV.Signature
+	rsv := append(append(append([]byte{}, sig[1:33]...), sig[33:65]...), sig[0])
+	assert.True(t, bytes.Contains(data, rsv), "signature must be encoded in R+S+V order")
+	assert.True(t, bytes.Contains(data, rav.CollectionID[:]))
+
+	call, err := EncodeDataServiceCollect(signedRAV, 100_000)
+	require.NoError(t, err)
+	assert.Equal(t, DataServiceCollectMethod.MethodID(), []byte(call[0:4]))
+	assert.Equal(t, []byte(rav.ServiceProvider), []byte(call[4+12:4+32]))
+	assert.Equal(t, uint8(PaymentTypeQueryFee), call[4+63])
+}
+
+func TestEncodeCollectData_Invalid(t *testing.T) {
+	_, err := EncodeCollectData(nil, 0)
+	assert.ErrorIs(t, err, ErrRAVMissing)
+
+	_, err = EncodeDataServiceCollect(&SignedRAV{}, 0)
+	assert.ErrorIs(t, err, ErrRAVMissing)
+
+	_, err = EncodeCollectData(&SignedRAV{Message: validContractRAV()}, MaxPPM+1)
+	assert.ErrorIs(t, err, ErrInvalidPPM)
+}
+
+func TestDecodeCollectData_RoundTrip(t *testing.T) {
+	key, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	domain := NewDomain(1337, eth.MustNewAddress("0x4444444444444444444444444444444444444444"))
+	rav := validContractRAV()
+	rav.CollectionID = CollectionID{0xaa}
+	rav.Metadata = []byte("meta")
+
+	signedRAV, err := Sign(domain, rav, key)
+	require.NoError(t, err)
+
+	data, err := EncodeSignedRAV(signedRAV)
+	require.NoError(t, err)
+	decoded, err := DecodeSignedRAV(data)
+	require.NoError(t, err)
+	assert.Equal(t, signedRAV, decoded)
+
+	signer, err := decoded.RecoverSigner(domain)
+	require.NoError(t, err)
+	assert.Equal(t, key.PublicKey().Address(), signer)
+
+	data, err = EncodeCollectData(signedRAV, 100_000)
+	require.NoError(t, err)
+	decoded, dataServiceCut, err := DecodeCollectData(data)
+	require.NoError(t, err)
+	assert.Equal(t, signedRAV, decoded)
+	assert.Equal(t, PPM(100_000), dataServiceCut)
+
+	call, err := EncodeDataServiceCollect(signedRAV, 100_000)
+	require.NoError(t, err)
+	collect, err := DecodeDataServiceCollect(call)
+	require.NoError(t, err)
+	assert.Equal(t, rav.ServiceProvider, collect.ServiceProvider)
+	assert.Equal(t, uint8(PaymentTypeQueryFee), collect.PaymentType)
+	assert.Equal(t, signedRAV, collect.SignedRAV)
+	assert.Equal(t, PPM(100_000), collect.DataServiceCut)
+
+	call, err = EncodeDataServiceCollectWithPaymentType(signedRAV, PaymentTypeIndexingFee, 100_000)
+	require.NoError(t, err)
+	collect, err = DecodeDataServiceCollect(call)
+	require.NoError(t, err)
+	assert.Equal(t, PaymentTypeIndexingFee, collect.PaymentType)
+
+	_, err = EncodeDataServiceCollectWithPaymentType(signedRAV, 3, 100_000)
+	assert.ErrorIs(t, err, ErrInvalidPaymentType)
+}
+
+func TestDecodeCollectData_Invalid(t *testing.T) {
+	_, err := DecodeSignedRAV([]byte{0x01, 0x02})
+	assert.ErrorIs(t, err, ErrInvalidCollectData)
+
+	_, _, err = DecodeCollectData(nil)
+	assert.ErrorIs(t, err, ErrInvalidCollectData)
+
+	signedRAV := &SignedRAV{Message: validContractRAV()}
+	data, err := EncodeCollectData(signedRAV, 0)
+	require.NoError(t, err)
+
+	_, err = DecodeDataServiceCollect(data)
+	assert.ErrorIs(t, err, ErrInvalidCollectData, "collect data without the method selector")
+}
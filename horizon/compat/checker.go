@@ -0,0 +1,184 @@
+// Package compat checks that the EIP-712 hashing and signing of RAVs in
+// package horizon match the GraphTallyCollector deployed on a network. Go
+// results are compared with the collector's encodeRAV and recoverRAVSigner
+// view functions, through eth_call, on randomized RAVs covering the edges of
+// each field (zero and uint128 maximum values, empty and multi-word metadata,
+// extreme timestamps). A mismatch means RAVs signed by the sidecars would not
+// be collectable on that network.
+package compat
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/graphprotocol/substreams-data-service/horizon/abis"
+	"github.com/streamingfast/eth-go"
+	"github.com/streamingfast/eth-go/rpc"
+)
+
+// Errors reported for a RAV on which Go and the collector disagree
+var (
+	ErrHashMismatch   = errors.New("EIP-712 hash differs from the collector's encodeRAV")
+	ErrSignerMismatch = errors.New("signer differs from the collector's recoverRAVSigner")
+)
+
+// Checker compares the Go EIP-712 hashing and signer recovery of RAVs with the
+// GraphTallyCollector deployed at a collector address. It is safe for
+// concurrent use.
+type Checker struct {
+	client           *rpc.Client
+	collector        eth.Address
+	domain           *horizon.Domain
+	encodeRAV        *eth.MethodDef
+	recoverRAVSigner *eth.MethodDef
+}
+
+// NewChecker creates a checker calling the GraphTallyCollector at collector
+// through rpcEndpoint, hashing RAVs in Go under the Horizon domain of chainID
+// and collector
+func NewChecker(rpcEndpoint string, chainID uint64, collector eth.Address, opts ...rpc.Option) *Checker {
+	abi := abis.GraphTallyCollector()
+	return &Checker{
+		client:           rpc.NewClient(rpcEndpoint, opts...),
+		collector:        collector,
+		domain:           horizon.NewDomain(chainID, collector),
+		encodeRAV:        abi.FindFunctionByName("encodeRAV"),
+		recoverRAVSigner: abi.FindFunctionByName("recoverRAVSigner"),
+	}
+}
+
+// Domain returns the EIP-712 domain RAVs are hashed and signed under in Go
+func (c *Checker) Domain() *horizon.Domain {
+	return c.domain
+}
+
+// EncodeRAV returns the EIP-712 hash of rav computed by the collector
+func (c *Checker) EncodeRAV(ctx context.Context, rav *horizon.RAV) (eth.Hash, error) {
+	data, err := c.encodeRAV.NewCall(ravTuple(rav)).Encode()
+	if err != nil {
+		return nil, fmt.Errorf("encoding encodeRAV call: %w", err)
+	}
+
+	result, err := c.call(ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("calling encodeRAV: %w", err)
+	}
+	if len(result) != 32 {
+		return nil, fmt.Errorf("calling encodeRAV: unexpected result length %d", len(result))
+	}
+	return eth.Hash(result), nil
+}
+
+// RecoverRAVSigner returns the signer of signedRAV recovered by the collector
+func (c *Checker) RecoverRAVSigner(ctx context.Context, signedRAV *horizon.SignedRAV) (eth.Address, error) {
+	args, err := horizon.EncodeSignedRAV(signedRAV)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.call(ctx, append(c.recoverRAVSigner.MethodID(), args...))
+	if err != nil {
+		return nil, fmt.Errorf("calling recoverRAVSigner: %w", err)
+	}
+	if len(result) != 32 {
+		return nil, fmt.Errorf("calling recoverRAVSigner: unexpected result length %d", len(result))
+	}
+	return eth.Address(result[12:32]), nil
+}
+
+// CheckRAV signs rav with key and compares the Go EIP-712 hash and recovered
+// signer with the collector's. Disagreements are reported wrapping
+// ErrHashMismatch or ErrSignerMismatch, any other error is a failure to check.
+func (c *Checker) CheckRAV(ctx context.Context, rav *horizon.RAV, key *eth.PrivateKey) error {
+	goHash, err := horizon.HashTypedData(c.domain, rav)
+	if err != nil {
+		return fmt.Errorf("hashing RAV: %w", err)
+	}
+	contractHash, err := c.EncodeRAV(ctx, rav)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if !bytes.Equal(goHash, contractHash) {
+		errs = append(errs, fmt.Errorf("%w: go %s, collector %s", ErrHashMismatch, goHash.Pretty(), contractHash.Pretty()))
+	}
+
+	signedRAV, err := horizon.Sign(c.domain, rav, key)
+	if err != nil {
+		return fmt.Errorf("signing RAV: %w", err)
+	}
+	contractSigner, err := c.RecoverRAVSigner(ctx, signedRAV)
+	if err != nil {
+		return err
+	}
+	if expected := key.PublicKey().Address(); !bytes.Equal(expected, contractSigner) {
+		errs = append(errs, fmt.Errorf("%w: signed by %s, collector recovered %s", ErrSignerMismatch, expected.Pretty(), contractSigner.Pretty()))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Mismatch is a RAV on which Go and the collector disagree
+type Mismatch struct {
+	RAV    *horizon.RAV
+	Signer eth.Address
+	Err    error
+}
+
+// Report is the outcome of Run
+type Report struct {
+	Checked    int
+	Mismatches []*Mismatch
+}
+
+// Run checks samples RAVs drawn by RandomRAV from rng, each signed with a key
+// drawn from rng too, so that a seeded rng reproduces a failing run. Mismatches
+// are collected in the report, Run stops on the first failure to check.
+func (c *Checker) Run(ctx context.Context, samples int, rng *rand.Rand) (*Report, error) {
+	report := &Report{}
+	for i := range samples {
+		rav := RandomRAV(rng)
+		key, err := randomKey(rng)
+		if err != nil {
+			return report, fmt.Errorf("sample %d: %w", i, err)
+		}
+
+		err = c.CheckRAV(ctx, rav, key)
+		switch {
+		case errors.Is(err, ErrHashMismatch) || errors.Is(err, ErrSignerMismatch):
+			report.Mismatches = append(report.Mismatches, &Mismatch{RAV: rav, Signer: key.PublicKey().Address(), Err: err})
+		case err != nil:
+			return report, fmt.Errorf("sample %d: %w", i, err)
+		}
+		report.Checked++
+	}
+	return report, nil
+}
+
+func (c *Checker) call(ctx context.Context, data []byte) ([]byte, error) {
+	resultHex, err := c.client.Call(ctx, rpc.CallParams{To: c.collector, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(strings.TrimPrefix(resultHex, "0x"))
+}
+
+// ravTuple returns the ABI tuple of rav, as IGraphTallyCollector.ReceiptAggregateVoucher
+func ravTuple(rav *horizon.RAV) map[string]interface{} {
+	return map[string]interface{}{
+		"collectionId":    rav.CollectionID[:],
+		"payer":           rav.Payer,
+		"serviceProvider": rav.ServiceProvider,
+		"dataService":     rav.DataService,
+		"timestampNs":     rav.TimestampNs,
+		"valueAggregate":  rav.ValueAggregate,
+		"metadata":        rav.Metadata,
+	}
+}
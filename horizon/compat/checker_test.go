@@ -0,0 +1,146 @@
+package compat
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/graphprotocol/substreams-data-service/horizon/abis"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCollector serves eth_call for encodeRAV and recoverRAVSigner, computing
+// them with package horizon under domain
+func fakeCollector(t *testing.T, domain *horizon.Domain) *httptest.Server {
+	encodeRAV := abis.GraphTallyCollector().FindFunctionByName("encodeRAV")
+	recoverRAVSigner := abis.GraphTallyCollector().FindFunctionByName("recoverRAVSigner")
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     any               `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "eth_call", req.Method)
+
+		var params struct {
+			Data string `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(req.Params[0], &params))
+		data, err := hex.DecodeString(strings.TrimPrefix(params.Data, "0x"))
+		require.NoError(t, err)
+
+		result := make([]byte, 32)
+		switch {
+		case bytes.HasPrefix(data, encodeRAV.MethodID()):
+			values, err := eth.NewDecoder(data[4:]).ReadOutput(encodeRAV.Parameters)
+			require.NoError(t, err)
+			hash, err := horizon.HashTypedData(domain, ravFromTuple(t, values[0]))
+			require.NoError(t, err)
+			copy(result, hash)
+		case bytes.HasPrefix(data, recoverRAVSigner.MethodID()):
+			signedRAV, err := horizon.DecodeSignedRAV(data[4:])
+			require.NoError(t, err)
+			signer, err := signedRAV.RecoverSigner(domain)
+			require.NoError(t, err)
+			copy(result[12:], signer)
+		default:
+			t.Fatalf("unexpected call data %x", data)
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+			"result":  "0x" + hex.EncodeToString(result),
+		})
+	}))
+}
+
+func ravFromTuple(t *testing.T, value interface{}) *horizon.RAV {
+	tuple, ok := value.([]interface{})
+	require.True(t, ok)
+	require.Len(t, tuple, 7)
+
+	rav := &horizon.RAV{
+		Payer:           tuple[1].(eth.Address),
+		ServiceProvider: tuple[2].(eth.Address),
+		DataService:     tuple[3].(eth.Address),
+		TimestampNs:     tuple[4].(uint64),
+		ValueAggregate:  tuple[5].(*big.Int),
+		Metadata:        tuple[6].([]byte),
+	}
+	copy(rav.CollectionID[:], tuple[0].([]byte))
+	return rav
+}
+
+func TestChecker_Run(t *testing.T) {
+	collector := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
+
+	backend := fakeCollector(t, horizon.NewDomain(1337, collector))
+	defer backend.Close()
+
+	report, err := NewChecker(backend.URL, 1337, collector).Run(context.Background(), 50, rand.New(rand.NewSource(1)))
+	require.NoError(t, err)
+	assert.Equal(t, 50, report.Checked)
+	assert.Empty(t, report.Mismatches)
+}
+
+func TestChecker_Mismatch(t *testing.T) {
+	collector := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
+
+	// The collector hashing under another chain ID disagrees on every hash and
+	// recovers another signer from every signature
+	backend := fakeCollector(t, horizon.NewDomain(1, collector))
+	defer backend.Close()
+
+	report, err := NewChecker(backend.URL, 1337, collector).Run(context.Background(), 3, rand.New(rand.NewSource(1)))
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Checked)
+	require.Len(t, report.Mismatches, 3)
+	for _, mismatch := range report.Mismatches {
+		assert.ErrorIs(t, mismatch.Err, ErrHashMismatch)
+		assert.ErrorIs(t, mismatch.Err, ErrSignerMismatch)
+	}
+}
+
+func TestChecker_CallFailure(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer backend.Close()
+
+	report, err := NewChecker(backend.URL, 1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")).Run(context.Background(), 3, rand.New(rand.NewSource(1)))
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrHashMismatch)
+	assert.Equal(t, 0, report.Checked)
+}
+
+func TestRandomRAV(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	var zeroValue, maxValue, emptyMetadata, wordMetadata bool
+	for range 500 {
+		rav := RandomRAV(rng)
+		require.NoError(t, horizon.ValidateAgainstContractSemantics(rav))
+
+		zeroValue = zeroValue || rav.ValueAggregate.Sign() == 0
+		maxValue = maxValue || rav.ValueAggregate.Cmp(horizon.MaxUint128) == 0
+		emptyMetadata = emptyMetadata || len(rav.Metadata) == 0
+		wordMetadata = wordMetadata || (len(rav.Metadata) > 0 && len(rav.Metadata)%32 == 0)
+	}
+	assert.True(t, zeroValue && maxValue && emptyMetadata && wordMetadata, "edge values drawn")
+
+	// Seeded draws are reproducible
+	assert.Equal(t, RandomRAV(rand.New(rand.NewSource(7))), RandomRAV(rand.New(rand.NewSource(7))))
+}
@@ -0,0 +1,92 @@
+package compat
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+)
+
+// maxRandomMetadataSize bounds random metadata, spanning several 32 bytes words
+// without making eth_call payloads large
+const maxRandomMetadataSize = 200
+
+// RandomRAV draws a RAV from rng. Each field is drawn at one of its edges (zero,
+// maximum, ABI word boundaries) about a quarter of the time, uniformly
+// otherwise, as encoding bugs hide at the edges.
+func RandomRAV(rng *rand.Rand) *horizon.RAV {
+	rav := &horizon.RAV{
+		Payer:           randomAddress(rng),
+		ServiceProvider: randomAddress(rng),
+		DataService:     randomAddress(rng),
+		TimestampNs:     randomTimestamp(rng),
+		ValueAggregate:  randomValue(rng),
+		Metadata:        randomMetadata(rng),
+	}
+	if rng.Intn(4) != 0 {
+		rng.Read(rav.CollectionID[:])
+	}
+	return rav
+}
+
+func randomAddress(rng *rand.Rand) eth.Address {
+	addr := make(eth.Address, 20)
+	rng.Read(addr)
+	return addr
+}
+
+func randomTimestamp(rng *rand.Rand) uint64 {
+	switch rng.Intn(8) {
+	case 0:
+		return 0
+	case 1:
+		return math.MaxUint64
+	}
+	return rng.Uint64()
+}
+
+func randomValue(rng *rand.Rand) *big.Int {
+	switch rng.Intn(8) {
+	case 0:
+		return big.NewInt(0)
+	case 1:
+		return new(big.Int).Set(horizon.MaxUint128)
+	}
+	// Random bit length so that small values are as likely as large ones
+	return new(big.Int).Rand(rng, new(big.Int).Lsh(big.NewInt(1), uint(rng.Intn(128)+1)))
+}
+
+func randomMetadata(rng *rand.Rand) []byte {
+	var size int
+	switch rng.Intn(4) {
+	case 0:
+		size = 0
+	case 1:
+		size = 32 * (rng.Intn(3) + 1)
+	default:
+		size = rng.Intn(maxRandomMetadataSize + 1)
+	}
+	metadata := make([]byte, size)
+	rng.Read(metadata)
+	return metadata
+}
+
+// randomKey draws a signing key from rng, redrawing the rare 32 bytes that are
+// not a valid secp256k1 scalar
+func randomKey(rng *rand.Rand) (*eth.PrivateKey, error) {
+	var lastErr error
+	for range 8 {
+		var b [32]byte
+		rng.Read(b[:])
+		key, err := eth.NewPrivateKey(hex.EncodeToString(b[:]))
+		if err == nil {
+			return key, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("drawing signing key: %w", lastErr)
+}
@@ -0,0 +1,172 @@
+package horizon
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/streamingfast/eth-go"
+)
+
+// Errors returned by ValidateAgainstContractSemantics and ValidateCollect, each
+// one mirrors a way a collect call of the RAV fails on-chain. ContractRevertName
+// returns the custom error the contracts revert with for them.
+var (
+	// ErrRAVMissing is returned for a nil RAV
+	ErrRAVMissing = errors.New("RAV is missing")
+	// ErrRAVValueMissing, ErrRAVValueNegative and ErrRAVValueOverflow mirror a
+	// valueAggregate that is not a uint128, the collect calldata cannot be
+	// encoded and the ABI decoder reverts without data
+	ErrRAVValueMissing  = errors.New("RAV value aggregate is missing")
+	ErrRAVValueNegative = errors.New("RAV value aggregate is negative")
+	ErrRAVValueOverflow = errors.New("RAV value aggregate exceeds uint128")
+	// ErrRAVZeroPayer mirrors GraphTallyCollectorInvalidRAVSigner(), no signer
+	// can be authorized for the zero payer
+	ErrRAVZeroPayer = errors.New("RAV payer is the zero address")
+	// ErrRAVZeroServiceProvider mirrors SubstreamsDataServiceIndexerNotRegistered,
+	// the zero address cannot register with the data service
+	ErrRAVZeroServiceProvider = errors.New("RAV service provider is the zero address")
+	// ErrRAVZeroDataService mirrors GraphTallyCollectorCallerNotDataService, the
+	// zero address can never be the caller of collect
+	ErrRAVZeroDataService = errors.New("RAV data service is the zero address")
+
+	// ErrRAVCallerNotDataService mirrors GraphTallyCollectorCallerNotDataService,
+	// collect is called by another address than the RAV data service
+	ErrRAVCallerNotDataService = errors.New("collect caller is not the RAV data service")
+	// ErrRAVNothingToCollect mirrors GraphTallyCollectorInconsistentRAVTokens, the
+	// RAV value aggregate is not above what was already collected
+	ErrRAVNothingToCollect = errors.New("RAV value aggregate was already collected")
+	// ErrRAVInvalidTokensToCollect mirrors
+	// GraphTallyCollectorInvalidTokensToCollectAmount, the requested amount is
+	// above what is left to collect
+	ErrRAVInvalidTokensToCollect = errors.New("tokens to collect exceed what is left to collect")
+	// ErrRAVInsufficientEscrow mirrors PaymentsEscrowInsufficientBalance, the
+	// payer's escrow does not hold the amount collected
+	ErrRAVInsufficientEscrow = errors.New("escrow balance is below the amount collected")
+)
+
+// contractReverts maps the errors above to the custom error they mirror
+var contractReverts = []struct {
+	err    error
+	revert string
+}{
+	{ErrRAVZeroPayer, "GraphTallyCollectorInvalidRAVSigner"},
+	{ErrRAVZeroServiceProvider, "SubstreamsDataServiceIndexerNotRegistered"},
+	{ErrRAVZeroDataService, "GraphTallyCollectorCallerNotDataService"},
+	{ErrRAVCallerNotDataService, "GraphTallyCollectorCallerNotDataService"},
+	{ErrRAVNothingToCollect, "GraphTallyCollectorInconsistentRAVTokens"},
+	{ErrRAVInvalidTokensToCollect, "GraphTallyCollectorInvalidTokensToCollectAmount"},
+	{ErrRAVInsufficientEscrow, "PaymentsEscrowInsufficientBalance"},
+}
+
+// ContractRevertName returns the name of the custom error the Horizon contracts
+// revert with for err, as returned by RevertName for the on-chain revert data.
+// It is empty when err mirrors no custom error, value errors revert without
+// data. For joined errors the first violation the contracts check is returned.
+func ContractRevertName(err error) string {
+	for _, revert := range contractReverts {
+		if errors.Is(err, revert.err) {
+			return revert.revert
+		}
+	}
+	return ""
+}
+
+// ValidateAgainstContractSemantics replicates the checks a collect call applies
+// to a RAV regardless of the chain state, so that RAVs that can never be
+// collected are caught locally instead of failing on-chain:
+//   - valueAggregate must fit in uint128, ABI decoding reverts otherwise
+//   - payer must be non-zero, no signer can be authorized for it
+//   - serviceProvider must be non-zero, it cannot be a registered indexer
+//   - dataService must be non-zero, it can never be the caller of collect
+//
+// The timestampNs < 2^64 constraint is enforced by the uint64 type. Metadata is
+// not bounded by the contracts, see Policy.MaxMetadataSize. All violations are
+// reported, joined, so each can be tested with errors.Is.
+func ValidateAgainstContractSemantics(rav *RAV) error {
+	if rav == nil {
+		return ErrRAVMissing
+	}
+
+	var errs []error
+
+	switch {
+	case rav.ValueAggregate == nil:
+		errs = append(errs, ErrRAVValueMissing)
+	case rav.ValueAggregate.Sign() < 0:
+		errs = append(errs, fmt.Errorf("%w: %s", ErrRAVValueNegative, rav.ValueAggregate))
+	case rav.ValueAggregate.Cmp(MaxUint128) > 0:
+		errs = append(errs, fmt.Errorf("%w: %s", ErrRAVValueOverflow, rav.ValueAggregate))
+	}
+
+	if isZeroAddress(rav.Payer) {
+		errs = append(errs, ErrRAVZeroPayer)
+	}
+	if isZeroAddress(rav.ServiceProvider) {
+		errs = append(errs, ErrRAVZeroServiceProvider)
+	}
+	if isZeroAddress(rav.DataService) {
+		errs = append(errs, ErrRAVZeroDataService)
+	}
+
+	return errors.Join(errs...)
+}
+
+// CollectState is the chain state a collect call of a RAV runs against
+type CollectState struct {
+	// Caller is the address calling GraphTallyCollector.collect, the data
+	// service contract when collecting through SubstreamsDataService
+	Caller eth.Address
+	// TokensCollected is GraphTallyCollector.tokensCollected for the RAV's
+	// collection, nothing collected yet when nil
+	TokensCollected *big.Int
+	// TokensToCollect is the amount requested, everything left when nil or zero
+	TokensToCollect *big.Int
+	// EscrowBalance is the payer's escrow balance for the collector and service
+	// provider, not checked when nil
+	EscrowBalance *big.Int
+}
+
+// ValidateCollect replicates, in order, the checks GraphTallyCollector and
+// PaymentsEscrow apply when collecting rav against state, after those of
+// ValidateAgainstContractSemantics. The signature and signer authorization are
+// not checked, see Validator. The first failing check is returned.
+func ValidateCollect(rav *RAV, state CollectState) error {
+	if err := ValidateAgainstContractSemantics(rav); err != nil {
+		return err
+	}
+
+	if state.Caller != nil && !addressesEqual(state.Caller, rav.DataService) {
+		return fmt.Errorf("%w: caller %s, data service %s", ErrRAVCallerNotDataService, state.Caller.Pretty(), rav.DataService.Pretty())
+	}
+
+	collected := state.TokensCollected
+	if collected == nil {
+		collected = new(big.Int)
+	}
+	if rav.ValueAggregate.Cmp(collected) <= 0 {
+		return fmt.Errorf("%w: value aggregate %s, collected %s", ErrRAVNothingToCollect, rav.ValueAggregate, collected)
+	}
+
+	tokens := new(big.Int).Sub(rav.ValueAggregate, collected)
+	if state.TokensToCollect != nil && state.TokensToCollect.Sign() != 0 {
+		if state.TokensToCollect.Cmp(tokens) > 0 {
+			return fmt.Errorf("%w: %s > %s", ErrRAVInvalidTokensToCollect, state.TokensToCollect, tokens)
+		}
+		tokens = state.TokensToCollect
+	}
+
+	if state.EscrowBalance != nil && state.EscrowBalance.Cmp(tokens) < 0 {
+		return fmt.Errorf("%w: balance %s, collecting %s", ErrRAVInsufficientEscrow, state.EscrowBalance, tokens)
+	}
+	return nil
+}
+
+func isZeroAddress(addr []byte) bool {
+	for _, b := range addr {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,122 @@
+package horizon
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validContractRAV() *RAV {
+	return &RAV{
+		Payer:           eth.MustNewAddress("0x1111111111111111111111111111111111111111"),
+		DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+		ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		TimestampNs:     1234567890,
+		ValueAggregate:  big.NewInt(1000),
+	}
+}
+
+func TestValidateAgainstContractSemantics(t *testing.T) {
+	zero := eth.MustNewAddress("0x0000000000000000000000000000000000000000")
+
+	tests := []struct {
+		name     string
+		mutate   func(rav *RAV)
+		expected []error
+	}{
+		{"valid", func(rav *RAV) {}, nil},
+		{"max uint128 value", func(rav *RAV) { rav.ValueAggregate = new(big.Int).Set(MaxUint128) }, nil},
+		{"large metadata", func(rav *RAV) { rav.Metadata = make([]byte, 64*1024) }, nil},
+		{"value overflow", func(rav *RAV) { rav.ValueAggregate = new(big.Int).Add(MaxUint128, big.NewInt(1)) }, []error{ErrRAVValueOverflow}},
+		{"negative value", func(rav *RAV) { rav.ValueAggregate = big.NewInt(-1) }, []error{ErrRAVValueNegative}},
+		{"missing value", func(rav *RAV) { rav.ValueAggregate = nil }, []error{ErrRAVValueMissing}},
+		{"zero payer", func(rav *RAV) { rav.Payer = zero }, []error{ErrRAVZeroPayer}},
+		{"nil service provider", func(rav *RAV) { rav.ServiceProvider = nil }, []error{ErrRAVZeroServiceProvider}},
+		{"zero data service", func(rav *RAV) { rav.DataService = zero }, []error{ErrRAVZeroDataService}},
+		{
+			"multiple violations",
+			func(rav *RAV) { rav.Payer = zero; rav.DataService = zero },
+			[]error{ErrRAVZeroPayer, ErrRAVZeroDataService},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rav := validContractRAV()
+			tt.mutate(rav)
+
+			err := ValidateAgainstContractSemantics(rav)
+			if len(tt.expected) == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			for _, expected := range tt.expected {
+				assert.ErrorIs(t, err, expected)
+			}
+		})
+	}
+}
+
+func TestValidateAgainstContractSemantics_NilRAV(t *testing.T) {
+	assert.ErrorIs(t, ValidateAgainstContractSemantics(nil), ErrRAVMissing)
+}
+
+func TestValidateCollect(t *testing.T) {
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	other := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+
+	tests := []struct {
+		name     string
+		state    CollectState
+		expected error
+		revert   string
+	}{
+		{"nothing collected", CollectState{Caller: dataService}, nil, ""},
+		{"partially collected", CollectState{TokensCollected: big.NewInt(400), EscrowBalance: big.NewInt(600)}, nil, ""},
+		{"requested amount", CollectState{TokensCollected: big.NewInt(400), TokensToCollect: big.NewInt(100), EscrowBalance: big.NewInt(100)}, nil, ""},
+		{"caller not data service", CollectState{Caller: other}, ErrRAVCallerNotDataService, "GraphTallyCollectorCallerNotDataService"},
+		{"entirely collected", CollectState{TokensCollected: big.NewInt(1000)}, ErrRAVNothingToCollect, "GraphTallyCollectorInconsistentRAVTokens"},
+		{"requested above left", CollectState{TokensCollected: big.NewInt(400), TokensToCollect: big.NewInt(601)}, ErrRAVInvalidTokensToCollect, "GraphTallyCollectorInvalidTokensToCollectAmount"},
+		{"insufficient escrow", CollectState{TokensCollected: big.NewInt(400), EscrowBalance: big.NewInt(599)}, ErrRAVInsufficientEscrow, "PaymentsEscrowInsufficientBalance"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCollect(validContractRAV(), tt.state)
+			if tt.expected == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, tt.expected)
+			assert.Equal(t, tt.revert, ContractRevertName(err))
+		})
+	}
+}
+
+func TestContractRevertName(t *testing.T) {
+	for _, revert := range contractReverts {
+		found := false
+		for _, known := range revertErrors {
+			if known.name == revert.revert {
+				found = true
+				break
+			}
+		}
+		assert.True(t, found, "%s is not a known Horizon revert", revert.revert)
+	}
+
+	zero := eth.MustNewAddress("0x0000000000000000000000000000000000000000")
+	rav := validContractRAV()
+	rav.Payer = zero
+	assert.Equal(t, "GraphTallyCollectorInvalidRAVSigner", ContractRevertName(ValidateAgainstContractSemantics(rav)))
+
+	rav = validContractRAV()
+	rav.ValueAggregate = big.NewInt(-1)
+	assert.Empty(t, ContractRevertName(ValidateAgainstContractSemantics(rav)))
+}
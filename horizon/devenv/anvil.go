@@ -0,0 +1,97 @@
+package devenv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/streamingfast/eth-go/rpc"
+)
+
+// LatestBlock returns the chain head of the environment
+func (env *Env) LatestBlock() (*rpc.Block, error) {
+	return env.rpcClient.GetBlockByNumber(env.ctx, rpc.LatestBlock)
+}
+
+// Mine mines the given number of blocks (Anvil-specific)
+func (env *Env) Mine(blocks uint64) error {
+	defer env.invalidateReads()
+
+	if _, err := rpc.Do[json.RawMessage](env.rpcClient, env.ctx, "anvil_mine", []interface{}{fmt.Sprintf("0x%x", blocks)}); err != nil {
+		return fmt.Errorf("mining %d blocks: %w", blocks, err)
+	}
+	return nil
+}
+
+// IncreaseTime moves the chain time forward by d and mines a block so the new
+// time is visible to contracts (Anvil-specific)
+func (env *Env) IncreaseTime(d time.Duration) error {
+	seconds := int64(d / time.Second)
+	if seconds <= 0 {
+		return fmt.Errorf("duration must be at least 1s, got %s", d)
+	}
+
+	if _, err := rpc.Do[json.RawMessage](env.rpcClient, env.ctx, "evm_increaseTime", []interface{}{seconds}); err != nil {
+		return fmt.Errorf("increasing time by %s: %w", d, err)
+	}
+
+	return env.Mine(1)
+}
+
+// SetNextBlockTimestamp stamps the next mined block with t, which must be
+// later than the chain head (Anvil-specific). With deterministic timestamps,
+// following blocks are stamped relative to it.
+func (env *Env) SetNextBlockTimestamp(t time.Time) error {
+	if _, err := rpc.Do[json.RawMessage](env.rpcClient, env.ctx, "evm_setNextBlockTimestamp", []interface{}{t.Unix()}); err != nil {
+		return fmt.Errorf("setting next block timestamp to %s: %w", t.UTC(), err)
+	}
+	return nil
+}
+
+// setBlockTimestampInterval makes each block mined interval later than its
+// parent instead of following the wall clock (Anvil-specific)
+func setBlockTimestampInterval(ctx context.Context, rpcClient *rpc.Client, interval time.Duration) error {
+	if _, err := rpc.Do[json.RawMessage](rpcClient, ctx, "anvil_setBlockTimestampInterval", []interface{}{int64(interval / time.Second)}); err != nil {
+		return fmt.Errorf("setting block timestamp interval to %s: %w", interval, err)
+	}
+	return nil
+}
+
+// FundETH sends ETH to an address from the Anvil dev account, the transfer is
+// only simulated in dry-run mode
+func (env *Env) FundETH(to eth.Address, amount *big.Int) error {
+	accounts, err := rpc.Do[[]string](env.rpcClient, env.ctx, "eth_accounts", nil)
+	if err != nil {
+		return fmt.Errorf("getting dev accounts: %w", err)
+	}
+	if len(accounts) == 0 {
+		return fmt.Errorf("no dev account available")
+	}
+
+	devAccount := eth.MustNewAddress(accounts[0])
+	if env.DryRun() {
+		_, err := env.simulateTransaction(devAccount, &to, amount, nil)
+		return err
+	}
+
+	defer env.invalidateReads()
+	return fundFromDevAccount(env.ctx, env.rpcClient, devAccount, to, amount)
+}
+
+// GetETHBalance returns the ETH balance of an address in wei, served from the
+// read cache when enabled
+func (env *Env) GetETHBalance(addr eth.Address) (*big.Int, error) {
+	balance, err := env.ethBalance(addr)
+	if err != nil {
+		return nil, fmt.Errorf("getting balance: %w", err)
+	}
+	return balance, nil
+}
+
+// GetGRTBalance returns the GRT balance of an address in wei
+func (env *Env) GetGRTBalance(addr eth.Address) (*big.Int, error) {
+	return env.GRT().BalanceOf(env.ctx, addr)
+}
@@ -0,0 +1,49 @@
+package devenv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/streamingfast/eth-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeterministicTimestamps(t *testing.T) {
+	genesis := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	config := DefaultConfig()
+	assert.Equal(t, "--chain-id 1337", anvilArgs(config, 1337))
+
+	WithDeterministicTimestamps(genesis, 12*time.Second)(config)
+	assert.Equal(t, "--chain-id 1337 --timestamp 1735689600", anvilArgs(config, 1337))
+
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		calls = append(calls, fmt.Sprintf("%s%s", req.Method, req.Params))
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":null}`, req.ID)
+	}))
+	defer server.Close()
+
+	rpcClient := rpc.NewClient(server.URL)
+	require.NoError(t, setBlockTimestampInterval(context.Background(), rpcClient, config.BlockTimestampInterval))
+
+	env := &Env{ctx: context.Background(), rpcClient: rpcClient}
+	require.NoError(t, env.SetNextBlockTimestamp(genesis.Add(time.Hour)))
+
+	assert.Equal(t, []string{
+		`anvil_setBlockTimestampInterval["0xc"]`,
+		`evm_setNextBlockTimestamp["0x67749390"]`,
+	}, calls)
+}
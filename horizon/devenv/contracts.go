@@ -8,6 +8,7 @@ import (
 	"math/big"
 	"strings"
 
+	"github.com/graphprotocol/substreams-data-service/horizon/abis"
 	"github.com/graphprotocol/substreams-data-service/horizon/devenv/contracts"
 	"github.com/streamingfast/eth-go"
 	"github.com/streamingfast/eth-go/rpc"
@@ -55,17 +56,21 @@ type ContractArtifact struct {
 
 // mustLoadContract loads a contract ABI from embedded artifact and returns a Contract with zero address
 func mustLoadContract(name string) *Contract {
-	artifact, err := loadContractArtifact(name)
+	contract, err := loadContract(name)
 	if err != nil {
-		panic(fmt.Sprintf("loading %s artifact: %v", name, err))
+		panic(err.Error())
 	}
+	return contract
+}
 
-	abi, err := eth.ParseABIFromBytes(artifact.ABI)
+// loadContract loads a contract ABI from embedded artifact and returns a Contract with zero address
+func loadContract(name string) (*Contract, error) {
+	abi, err := abis.Get(name)
 	if err != nil {
-		panic(fmt.Sprintf("parsing %s ABI: %v", name, err))
+		return nil, err
 	}
 
-	return &Contract{ABI: abi}
+	return &Contract{ABI: abi}, nil
 }
 
 // loadContractArtifact loads a contract artifact (ABI and bytecode) from embedded JSON
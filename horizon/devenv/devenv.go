@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/graphprotocol/substreams-data-service/horizon"
 	"github.com/streamingfast/eth-go"
 	"github.com/streamingfast/eth-go/rpc"
 	"github.com/streamingfast/logging"
@@ -27,6 +28,11 @@ type Env struct {
 	RPCURL         string
 	ChainID        uint64
 
+	// FaultProxyURL is the URL of the fault proxy in front of RPCURL started
+	// with WithFaultInjection, empty otherwise
+	FaultProxyURL string
+	faultProxy    *FaultProxy
+
 	// Contracts (ABI loaded at init, address set after deployment)
 	GRTToken      *Contract
 	Controller    *Contract
@@ -43,8 +49,28 @@ type Env struct {
 	User1           Account
 	User2           Account
 	User3           Account
+
+	// Example substreams packages, along with their collection IDs
+	Packages []ExamplePackage
+
+	// Secondary is the second chain started with WithSecondaryChain, nil
+	// otherwise. It has its own Anvil instance and contract deployment (at
+	// different addresses than the primary chain) with the same test accounts.
+	Secondary *Env
+
+	// Dry-run mode (see SetDryRun)
+	dryRunMu  sync.Mutex
+	dryRun    bool
+	simulated []*SimulatedTransaction
+
+	// Chain reads cached between writes (see SetReadCache)
+	readCache readCache
 }
 
+// ProtocolPaymentCut is the GraphPayments protocol payment cut deployed by the
+// development environment
+const ProtocolPaymentCut horizon.PPM = 10_000
+
 var (
 	globalEnv     *Env
 	globalEnvOnce sync.Once
@@ -76,6 +102,12 @@ func Shutdown() {
 
 // cleanup terminates the environment
 func (env *Env) cleanup() {
+	if env.Secondary != nil {
+		env.Secondary.cleanup()
+	}
+	if env.faultProxy != nil {
+		env.faultProxy.Close()
+	}
 	if env.anvilContainer != nil {
 		env.anvilContainer.Terminate(env.ctx)
 	}
@@ -88,11 +120,60 @@ func start(ctx context.Context, opts ...Option) (*Env, error) {
 		opt(config)
 	}
 
+	if config.SecondaryChainID != 0 && config.SecondaryChainID == config.ChainID {
+		return nil, fmt.Errorf("secondary chain ID must differ from chain ID %d", config.ChainID)
+	}
+	if !config.GenesisTimestamp.IsZero() && config.BlockTimestampInterval < time.Second {
+		return nil, fmt.Errorf("block timestamp interval must be at least 1s, got %s", config.BlockTimestampInterval)
+	}
+	if config.Faults != nil {
+		if err := config.Faults.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid faults: %w", err)
+		}
+	}
+
 	report := config.Reporter.ReportProgress
 
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
 
 	zlog.Info("starting development environment")
+	env, err := startChain(ctx, config, config.ChainID, false)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	env.cancel = cancel
+
+	if config.SecondaryChainID != 0 {
+		report(fmt.Sprintf("Starting secondary chain %d...", config.SecondaryChainID))
+		env.Secondary, err = startChain(ctx, config, config.SecondaryChainID, true)
+		if err != nil {
+			env.cleanup()
+			return nil, fmt.Errorf("starting secondary chain %d: %w", config.SecondaryChainID, err)
+		}
+	}
+
+	if config.Faults != nil {
+		report("Starting fault proxy...")
+		env.faultProxy, err = StartFaultProxy(env.RPCURL, *config.Faults)
+		if err != nil {
+			env.cleanup()
+			return nil, fmt.Errorf("starting fault proxy: %w", err)
+		}
+		env.FaultProxyURL = env.faultProxy.URL
+	}
+
+	report("Development environment ready")
+
+	return env, nil
+}
+
+// startChain starts an Anvil instance with chainID, funds the test accounts
+// and deploys the contracts. The returned Env does not cancel ctx on cleanup.
+// When offsetAddresses is set, the deployer nonce is bumped before deploying so
+// contract addresses differ from a deployment on a fresh chain.
+func startChain(ctx context.Context, config *Config, chainID uint64, offsetAddresses bool) (*Env, error) {
+	report := config.Reporter.ReportProgress
 
 	// Pre-load all contracts (ABIs loaded now, addresses set after deployment)
 	report("Loading contract ABIs...")
@@ -106,69 +187,23 @@ func start(ctx context.Context, opts ...Option) (*Env, error) {
 
 	// Start Anvil container
 	report("Starting Anvil container...")
-	anvilReq := testcontainers.ContainerRequest{
-		Image: "ghcr.io/foundry-rs/foundry:latest",
-		Cmd: []string{
-			fmt.Sprintf("anvil --host 0.0.0.0 --port 8545 --chain-id %d", config.ChainID),
-		},
-		ExposedPorts: []string{"8545/tcp"},
-		WaitingFor: wait.ForListeningPort("8545/tcp").
-			WithStartupTimeout(60 * time.Second),
-	}
-
-	anvilContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
-		ContainerRequest: anvilReq,
-		Started:          true,
-	})
+	anvilContainer, rpcURL, rpcClient, chainIDInt, err := startAnvil(ctx, report, anvilArgs(config, chainID))
 	if err != nil {
-		zlog.Error("failed to start Anvil container", zap.Error(err))
-		cancel()
-		return nil, fmt.Errorf("starting anvil container: %w", err)
-	}
-
-	mappedPort, err := anvilContainer.MappedPort(ctx, "8545/tcp")
-	if err != nil {
-		zlog.Error("failed to get mapped port", zap.Error(err))
-		anvilContainer.Terminate(ctx)
-		cancel()
-		return nil, fmt.Errorf("getting mapped port: %w", err)
+		return nil, err
 	}
 
-	// Use localhost for Docker published ports - using container.Host() may return
-	// an IP that goes through a proxy and gets blocked
-	rpcURL := fmt.Sprintf("http://localhost:%s", mappedPort.Port())
-	zlog.Info("Anvil RPC endpoint ready", zap.String("rpc_url", rpcURL))
-
-	// Create RPC client
-	rpcClient := rpc.NewClient(rpcURL)
-
-	// Wait for RPC to be responsive and get the chain ID
-	report("Waiting for Anvil RPC to be ready...")
-	var chainIDInt *big.Int
-	for i := 0; i < 20; i++ {
-		time.Sleep(500 * time.Millisecond)
-		zlog.Debug("attempting to query chain ID", zap.Int("attempt", i+1))
-		chainIDInt, err = rpcClient.ChainID(ctx)
-		if err == nil && chainIDInt != nil && chainIDInt.Sign() > 0 {
-			zlog.Info("chain ID successfully retrieved", zap.Uint64("chain_id", chainIDInt.Uint64()))
-			break
-		} else {
-			zlog.Debug("chain ID query failed", zap.Error(err))
+	if !config.GenesisTimestamp.IsZero() {
+		if err := setBlockTimestampInterval(ctx, rpcClient, config.BlockTimestampInterval); err != nil {
+			anvilContainer.Terminate(ctx)
+			return nil, err
 		}
 	}
-	if chainIDInt == nil {
-		zlog.Error("failed to get valid chain ID after all retries")
-		anvilContainer.Terminate(ctx)
-		cancel()
-		return nil, fmt.Errorf("failed to get valid chain ID after retries")
-	}
 
 	// Get dev account (funded by Anvil)
 	accounts, err := rpc.Do[[]string](rpcClient, ctx, "eth_accounts", nil)
 	if err != nil || len(accounts) == 0 {
 		zlog.Error("failed to get dev accounts", zap.Error(err), zap.Int("num_accounts", len(accounts)))
 		anvilContainer.Terminate(ctx)
-		cancel()
 		return nil, fmt.Errorf("getting dev accounts: %w", err)
 	}
 	devAccount := eth.MustNewAddress(accounts[0])
@@ -198,24 +233,29 @@ func start(ctx context.Context, opts ...Option) (*Env, error) {
 		if err := fundFromDevAccount(ctx, rpcClient, devAccount, addr, fundAmount); err != nil {
 			zlog.Error("failed to fund account", zap.String("name", name), zap.Error(err))
 			anvilContainer.Terminate(ctx)
-			cancel()
 			return nil, fmt.Errorf("funding %s: %w", name, err)
 		}
 	}
 
-	chainID := chainIDInt.Uint64()
+	chainID = chainIDInt.Uint64()
+
+	if offsetAddresses {
+		if err := SendTransaction(ctx, rpcClient, deployer.PrivateKey, chainID, &deployer.Address, big.NewInt(0), nil); err != nil {
+			anvilContainer.Terminate(ctx)
+			return nil, fmt.Errorf("bumping deployer nonce: %w", err)
+		}
+	}
 
 	// Deploy all contracts
 	report("Deploying contracts...")
 	if err := deployAllContracts(ctx, rpcClient, chainID, deployer, grtToken, controller, staking, escrow, graphPayments, collector, dataService); err != nil {
 		anvilContainer.Terminate(ctx)
-		cancel()
 		return nil, err
 	}
 
 	env := &Env{
 		ctx:             ctx,
-		cancel:          cancel,
+		cancel:          func() {},
 		anvilContainer:  anvilContainer,
 		rpcClient:       rpcClient,
 		RPCURL:          rpcURL,
@@ -233,29 +273,113 @@ func start(ctx context.Context, opts ...Option) (*Env, error) {
 		User1:           user1,
 		User2:           user2,
 		User3:           user3,
+		Packages:        ExamplePackages,
+	}
+	env.SetReadCache(config.ReadCache)
+
+	// Value math is only meaningful locally if the mock token scales amounts
+	// as mainnet GRT does
+	if err := env.GRT().CheckDecimals(ctx); err != nil {
+		env.cleanup()
+		return nil, err
 	}
 
-	// Mint GRT to all test accounts
+	// Mint GRT to test accounts
 	report("Minting GRT to test accounts...")
-	for name, addr := range map[string]eth.Address{
-		"deployer":         deployer.Address,
-		"service_provider": serviceProvider.Address,
-		"payer":            payer.Address,
-		"user1":            user1.Address,
-		"user2":            user2.Address,
-		"user3":            user3.Address,
+	mint := config.MintAmounts
+	for name, mintTo := range map[string]struct {
+		address eth.Address
+		amount  *big.Int
+	}{
+		"deployer":         {deployer.Address, mint.Deployer},
+		"service_provider": {serviceProvider.Address, mint.ServiceProvider},
+		"payer":            {payer.Address, mint.Payer},
+		"user1":            {user1.Address, mint.Users},
+		"user2":            {user2.Address, mint.Users},
+		"user3":            {user3.Address, mint.Users},
 	} {
-		if err := env.MintGRT(addr, config.EscrowAmount); err != nil {
+		if mintTo.amount == nil || mintTo.amount.Sign() == 0 {
+			continue
+		}
+		if err := env.MintGRT(mintTo.address, mintTo.amount); err != nil {
 			env.cleanup()
 			return nil, fmt.Errorf("minting GRT to %s: %w", name, err)
 		}
 	}
 
-	report("Development environment ready")
-
 	return env, nil
 }
 
+// anvilArgs returns the Anvil command line arguments of a chain with chainID
+func anvilArgs(config *Config, chainID uint64) string {
+	args := fmt.Sprintf("--chain-id %d", chainID)
+	if !config.GenesisTimestamp.IsZero() {
+		args += fmt.Sprintf(" --timestamp %d", config.GenesisTimestamp.Unix())
+	}
+	return args
+}
+
+// startAnvil starts an Anvil container with the extra command line arguments
+// and waits for its RPC endpoint to answer, returning the endpoint URL, a
+// client and the chain ID. The container is terminated on error.
+func startAnvil(ctx context.Context, report func(message string), args string) (testcontainers.Container, string, *rpc.Client, *big.Int, error) {
+	anvilReq := testcontainers.ContainerRequest{
+		Image: "ghcr.io/foundry-rs/foundry:latest",
+		Cmd: []string{
+			"anvil --host 0.0.0.0 --port 8545 " + args,
+		},
+		ExposedPorts: []string{"8545/tcp"},
+		WaitingFor: wait.ForListeningPort("8545/tcp").
+			WithStartupTimeout(60 * time.Second),
+	}
+
+	anvilContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: anvilReq,
+		Started:          true,
+	})
+	if err != nil {
+		zlog.Error("failed to start Anvil container", zap.Error(err))
+		return nil, "", nil, nil, fmt.Errorf("starting anvil container: %w", err)
+	}
+
+	mappedPort, err := anvilContainer.MappedPort(ctx, "8545/tcp")
+	if err != nil {
+		zlog.Error("failed to get mapped port", zap.Error(err))
+		anvilContainer.Terminate(ctx)
+		return nil, "", nil, nil, fmt.Errorf("getting mapped port: %w", err)
+	}
+
+	// Use localhost for Docker published ports - using container.Host() may return
+	// an IP that goes through a proxy and gets blocked
+	rpcURL := fmt.Sprintf("http://localhost:%s", mappedPort.Port())
+	zlog.Info("Anvil RPC endpoint ready", zap.String("rpc_url", rpcURL))
+
+	// Create RPC client
+	rpcClient := rpc.NewClient(rpcURL)
+
+	// Wait for RPC to be responsive and get the chain ID
+	report("Waiting for Anvil RPC to be ready...")
+	var chainIDInt *big.Int
+	for i := 0; i < 20; i++ {
+		time.Sleep(500 * time.Millisecond)
+		zlog.Debug("attempting to query chain ID", zap.Int("attempt", i+1))
+		chainIDInt, err = rpcClient.ChainID(ctx)
+		if err == nil && chainIDInt != nil && chainIDInt.Sign() > 0 {
+			zlog.Info("chain ID successfully retrieved", zap.Uint64("chain_id", chainIDInt.Uint64()))
+			break
+		} else {
+			zlog.Debug("chain ID query failed", zap.Error(err))
+		}
+	}
+	if chainIDInt == nil {
+		zlog.Error("failed to get valid chain ID after all retries")
+		anvilContainer.Terminate(ctx)
+		return nil, "", nil, nil, fmt.Errorf("failed to get valid chain ID after retries")
+	}
+
+	return anvilContainer, rpcURL, rpcClient, chainIDInt, nil
+}
+
 func deployAllContracts(ctx context.Context, rpcClient *rpc.Client, chainID uint64, deployer Account, grtToken, controller, staking, escrow, graphPayments, collector, dataService *Contract) error {
 	// ============================================================================
 	// PHASE 1: Deploy all MOCK infrastructure contracts
@@ -390,8 +514,7 @@ func deployAllContracts(ctx context.Context, rpcClient *rpc.Client, chainID uint
 	if err != nil {
 		return fmt.Errorf("loading GraphPayments artifact: %w", err)
 	}
-	protocolCut := big.NewInt(10000) // 1% protocol cut
-	graphPayments.Address, err = deployContract(ctx, rpcClient, deployer.PrivateKey, chainID, graphPaymentsArtifact, graphPayments.ABI, controller.Address, protocolCut)
+	graphPayments.Address, err = deployContract(ctx, rpcClient, deployer.PrivateKey, chainID, graphPaymentsArtifact, graphPayments.ABI, controller.Address, ProtocolPaymentCut.BigInt())
 	if err != nil {
 		return fmt.Errorf("deploying GraphPayments: %w", err)
 	}
@@ -496,16 +619,20 @@ func (env *Env) PrintInfo(w io.Writer) {
 	fmt.Fprintf(w, "NETWORK:\n")
 	fmt.Fprintf(w, "  RPC URL:  %s\n", env.RPCURL)
 	fmt.Fprintf(w, "  Chain ID: %d\n", env.ChainID)
+	if env.faultProxy != nil {
+		fmt.Fprintf(w, "  Fault proxy RPC URL: %s (faults: %s)\n", env.FaultProxyURL, env.faultProxy.Faults())
+	}
 	fmt.Fprintf(w, "\n")
 	fmt.Fprintf(w, "CONTRACTS:\n")
-	fmt.Fprintf(w, "  GraphPayments:         %s\n", env.GraphPayments.Address.Pretty())
-	fmt.Fprintf(w, "  PaymentsEscrow:        %s\n", env.Escrow.Address.Pretty())
-	fmt.Fprintf(w, "  GraphTallyCollector:   %s\n", env.Collector.Address.Pretty())
-	fmt.Fprintf(w, "  SubstreamsDataService: %s\n", env.DataService.Address.Pretty())
-	fmt.Fprintf(w, "  MockGRTToken:          %s\n", env.GRTToken.Address.Pretty())
-	fmt.Fprintf(w, "  MockController:        %s\n", env.Controller.Address.Pretty())
-	fmt.Fprintf(w, "  MockStaking:           %s\n", env.Staking.Address.Pretty())
+	env.printContracts(w)
 	fmt.Fprintf(w, "\n")
+	if env.Secondary != nil {
+		fmt.Fprintf(w, "SECONDARY CHAIN (same test accounts):\n")
+		fmt.Fprintf(w, "  RPC URL:  %s\n", env.Secondary.RPCURL)
+		fmt.Fprintf(w, "  Chain ID: %d\n", env.Secondary.ChainID)
+		env.Secondary.printContracts(w)
+		fmt.Fprintf(w, "\n")
+	}
 	fmt.Fprintf(w, "TEST ACCOUNTS (10 ETH + 10,000 GRT each):\n")
 	fmt.Fprintf(w, "  Deployer:         %s (0x%s)\n", env.Deployer.Address.Pretty(), env.Deployer.PrivateKey.String())
 	fmt.Fprintf(w, "  Service Provider: %s (0x%s)\n", env.ServiceProvider.Address.Pretty(), env.ServiceProvider.PrivateKey.String())
@@ -514,5 +641,20 @@ func (env *Env) PrintInfo(w io.Writer) {
 	fmt.Fprintf(w, "  User2:            %s (0x%s)\n", env.User2.Address.Pretty(), env.User2.PrivateKey.String())
 	fmt.Fprintf(w, "  User3:            %s (0x%s)\n", env.User3.Address.Pretty(), env.User3.PrivateKey.String())
 	fmt.Fprintf(w, "\n")
+	fmt.Fprintf(w, "EXAMPLE PACKAGES (collection ID):\n")
+	for _, pkg := range env.Packages {
+		fmt.Fprintf(w, "  %-28s %s\n", pkg.String()+":", collectionIDHex(pkg.CollectionID()))
+	}
+	fmt.Fprintf(w, "\n")
 	fmt.Fprintf(w, "============================================================\n")
 }
+
+func (env *Env) printContracts(w io.Writer) {
+	fmt.Fprintf(w, "  GraphPayments:         %s\n", env.GraphPayments.Address.Pretty())
+	fmt.Fprintf(w, "  PaymentsEscrow:        %s\n", env.Escrow.Address.Pretty())
+	fmt.Fprintf(w, "  GraphTallyCollector:   %s\n", env.Collector.Address.Pretty())
+	fmt.Fprintf(w, "  SubstreamsDataService: %s\n", env.DataService.Address.Pretty())
+	fmt.Fprintf(w, "  MockGRTToken:          %s\n", env.GRTToken.Address.Pretty())
+	fmt.Fprintf(w, "  MockController:        %s\n", env.Controller.Address.Pretty())
+	fmt.Fprintf(w, "  MockStaking:           %s\n", env.Staking.Address.Pretty())
+}
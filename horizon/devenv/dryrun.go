@@ -0,0 +1,282 @@
+package devenv
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/streamingfast/eth-go/rpc"
+	"go.uber.org/zap"
+)
+
+// SimulatedTransaction is the would-be outcome of a transaction that was not
+// broadcast because the environment is in dry-run mode
+type SimulatedTransaction struct {
+	From  eth.Address
+	To    *eth.Address
+	Value *big.Int
+	Data  []byte
+	// Gas is the estimated gas the transaction would use
+	Gas uint64
+
+	// Method is the decoded method signature, empty if the target ABI is unknown
+	Method string
+	Args   []interface{}
+	// Events are the decoded logs the transaction would emit, only available
+	// when the node supports debug_traceCall
+	Events []*SimulatedEvent
+}
+
+// SimulatedEvent is a log that a simulated transaction would emit
+type SimulatedEvent struct {
+	Address eth.Address
+	// Name is the decoded event name, empty if the emitter ABI is unknown
+	Name string
+	Args []interface{}
+	Log  *eth.Log
+}
+
+// String returns a human readable multi-line description of the simulation
+func (t *SimulatedTransaction) String() string {
+	var b strings.Builder
+
+	to := "contract_creation"
+	if t.To != nil {
+		to = t.To.Pretty()
+	}
+
+	fmt.Fprintf(&b, "from:   %s\n", t.From.Pretty())
+	fmt.Fprintf(&b, "to:     %s\n", to)
+	if t.Value != nil && t.Value.Sign() > 0 {
+		fmt.Fprintf(&b, "value:  %s wei\n", t.Value)
+	}
+	fmt.Fprintf(&b, "gas:    %d\n", t.Gas)
+	if t.Method != "" {
+		fmt.Fprintf(&b, "method: %s %v\n", t.Method, t.Args)
+	}
+	fmt.Fprintf(&b, "data:   0x%x\n", t.Data)
+	for _, event := range t.Events {
+		if event.Name == "" {
+			fmt.Fprintf(&b, "event:  %s unknown (topics %d)\n", event.Address.Pretty(), len(event.Log.Topics))
+			continue
+		}
+		fmt.Fprintf(&b, "event:  %s %s %v\n", event.Address.Pretty(), event.Name, event.Args)
+	}
+
+	return b.String()
+}
+
+// SetDryRun enables or disables dry-run mode. In dry-run mode, every
+// transaction-sending helper (MintGRT, DepositEscrow, AuthorizeSigner, ...)
+// estimates and simulates its transaction against the current chain state,
+// records the outcome (see SimulatedTransactions) and returns without
+// broadcasting anything.
+func (env *Env) SetDryRun(enabled bool) {
+	env.dryRunMu.Lock()
+	defer env.dryRunMu.Unlock()
+
+	env.dryRun = enabled
+}
+
+// DryRun returns true if the environment is in dry-run mode
+func (env *Env) DryRun() bool {
+	env.dryRunMu.Lock()
+	defer env.dryRunMu.Unlock()
+
+	return env.dryRun
+}
+
+// SimulatedTransactions returns the transactions simulated so far in dry-run mode
+func (env *Env) SimulatedTransactions() []*SimulatedTransaction {
+	env.dryRunMu.Lock()
+	defer env.dryRunMu.Unlock()
+
+	return append([]*SimulatedTransaction(nil), env.simulated...)
+}
+
+// sendTransaction sends a transaction signed by key, or simulates it in dry-run mode
+func (env *Env) sendTransaction(key *eth.PrivateKey, to *eth.Address, value *big.Int, data []byte) error {
+	if !env.DryRun() {
+		defer env.invalidateReads()
+		return SendTransaction(env.ctx, env.rpcClient, key, env.ChainID, to, value, data)
+	}
+
+	_, err := env.simulateTransaction(key.PublicKey().Address(), to, value, data)
+	return err
+}
+
+// simulateTransaction estimates and traces a transaction without broadcasting it
+// and records the outcome in the simulated transactions
+func (env *Env) simulateTransaction(from eth.Address, to *eth.Address, value *big.Int, data []byte) (*SimulatedTransaction, error) {
+	params := rpc.CallParams{From: from, Value: value, Data: data}
+	if to != nil {
+		params.To = *to
+	}
+
+	gasHex, err := env.rpcClient.EstimateGas(env.ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("simulating transaction: %w", err)
+	}
+
+	gas, err := strconv.ParseUint(strings.TrimPrefix(gasHex, "0x"), 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parsing estimated gas %q: %w", gasHex, err)
+	}
+
+	simulated := &SimulatedTransaction{
+		From:  from,
+		To:    to,
+		Value: value,
+		Data:  data,
+		Gas:   gas,
+	}
+
+	if to != nil {
+		simulated.Method, simulated.Args = decodeCall(env.contracts(), *to, data)
+	}
+
+	logs, err := env.traceLogs(params)
+	if err != nil {
+		// debug_traceCall is optional, simulation results are still valid without events
+		zlog.Debug("unable to trace simulated transaction, events not decoded", zap.Error(err))
+	}
+	for _, log := range logs {
+		simulated.Events = append(simulated.Events, decodeEvent(env.contracts(), log))
+	}
+
+	env.dryRunMu.Lock()
+	env.simulated = append(env.simulated, simulated)
+	env.dryRunMu.Unlock()
+
+	zlog.Info("transaction simulated (dry-run)", zap.Stringer("from", from), zap.Uint64("gas", gas), zap.String("method", simulated.Method))
+	return simulated, nil
+}
+
+// callFrame is the result of the callTracer of debug_traceCall
+type callFrame struct {
+	Logs []struct {
+		Address eth.Address `json:"address"`
+		Topics  []eth.Hash  `json:"topics"`
+		Data    eth.Hex     `json:"data"`
+	} `json:"logs"`
+	Calls []*callFrame `json:"calls"`
+}
+
+// traceLogs returns the logs a call would emit, in emission order
+func (env *Env) traceLogs(params rpc.CallParams) ([]*eth.Log, error) {
+	tracerConfig := map[string]interface{}{
+		"tracer":       "callTracer",
+		"tracerConfig": map[string]interface{}{"withLog": true},
+	}
+
+	frame, err := rpc.Do[*callFrame](env.rpcClient, env.ctx, "debug_traceCall", []interface{}{params, "latest", tracerConfig})
+	if err != nil {
+		return nil, err
+	}
+
+	var logs []*eth.Log
+	var visit func(frame *callFrame)
+	visit = func(frame *callFrame) {
+		if frame == nil {
+			return
+		}
+		for _, l := range frame.Logs {
+			log := &eth.Log{Address: l.Address, Data: l.Data}
+			for _, topic := range l.Topics {
+				log.Topics = append(log.Topics, topic)
+			}
+			logs = append(logs, log)
+		}
+		for _, call := range frame.Calls {
+			visit(call)
+		}
+	}
+	visit(frame)
+
+	return logs, nil
+}
+
+// decodeCall decodes the method called by data on to, empty when to is not one
+// of contracts or the method is not in its ABI
+func decodeCall(contracts []*Contract, to eth.Address, data []byte) (method string, args []interface{}) {
+	if len(data) < 4 {
+		return "", nil
+	}
+
+	contract := contractAt(contracts, to)
+	if contract == nil {
+		return "", nil
+	}
+
+	fn := contract.ABI.FindFunction(data[:4])
+	if fn == nil {
+		return "", nil
+	}
+
+	args, err := eth.NewDecoder(data[4:]).ReadOutput(fn.Parameters)
+	if err != nil {
+		zlog.Debug("unable to decode simulated call arguments", zap.String("method", fn.Signature()), zap.Error(err))
+	}
+	return fn.Signature(), args
+}
+
+// decodeEvent decodes log against the ABI of the contract emitting it, the
+// event is left unnamed when the emitter is not one of contracts
+func decodeEvent(contracts []*Contract, log *eth.Log) *SimulatedEvent {
+	event := &SimulatedEvent{Address: log.Address, Log: log}
+
+	contract := contractAt(contracts, log.Address)
+	if contract == nil || len(log.Topics) == 0 {
+		return event
+	}
+
+	def := contract.ABI.FindLogByTopic(log.Topics[0])
+	if def == nil {
+		return event
+	}
+
+	decoder := eth.NewLogDecoder(log)
+	if _, err := decoder.ReadTopic(); err != nil {
+		return event
+	}
+
+	args := make([]interface{}, 0, len(def.Parameters))
+	for _, param := range def.Parameters {
+		var value interface{}
+		var err error
+		if param.Indexed {
+			value, err = decoder.ReadTypedTopic(param.TypeName)
+		} else if decoder.DataDecoder == nil {
+			return event
+		} else {
+			value, err = decoder.ReadData(param.TypeName)
+		}
+		if err != nil {
+			zlog.Debug("unable to decode simulated event", zap.String("event", def.Name), zap.Error(err))
+			return event
+		}
+		args = append(args, value)
+	}
+
+	event.Name = def.Name
+	event.Args = args
+	return event
+}
+
+// contracts returns the contracts deployed by the environment
+func (env *Env) contracts() []*Contract {
+	return []*Contract{env.GRTToken, env.Controller, env.Staking, env.Escrow, env.GraphPayments, env.Collector, env.DataService}
+}
+
+// contractAt returns the contract of contracts deployed at addr, if any
+func contractAt(contracts []*Contract, addr eth.Address) *Contract {
+	for _, contract := range contracts {
+		if contract != nil && bytes.Equal(contract.Address, addr) {
+			return contract
+		}
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package devenv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+	"github.com/streamingfast/eth-go/rpc"
+)
+
+// DefaultExportFile is the path where a running environment describes itself
+// so that other processes (e.g. `sds devenv status`) can interact with it
+var DefaultExportFile = filepath.Join(os.TempDir(), "sds-devenv.json")
+
+// ExportSchema versions the JSON encoding of Export
+var ExportSchema = horizon.NewSchema("devenv export", "schema_version", 1)
+
+// Export is the JSON description of a running environment
+type Export struct {
+	// SchemaVersion is the ExportSchema version of the export
+	SchemaVersion int `json:"schema_version"`
+
+	RPCURL  string `json:"rpc_url"`
+	ChainID uint64 `json:"chain_id"`
+	// FaultProxyURL is the URL of the fault proxy in front of RPCURL, when one was started
+	FaultProxyURL string            `json:"fault_proxy_url,omitempty"`
+	Contracts     ExportedContracts `json:"contracts"`
+	Accounts      []ExportedAccount `json:"accounts"`
+	// Secondary describes the secondary chain, when one was started
+	Secondary *Export `json:"secondary,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, exports of older schema versions
+// are migrated to the current one
+func (e *Export) UnmarshalJSON(data []byte) error {
+	type plain Export
+	return ExportSchema.Unmarshal(data, (*plain)(e))
+}
+
+// ExportedContracts holds the deployed contract addresses of an exported environment
+type ExportedContracts struct {
+	GRTToken      string `json:"grt_token"`
+	Controller    string `json:"controller"`
+	Staking       string `json:"staking"`
+	Escrow        string `json:"escrow"`
+	GraphPayments string `json:"graph_payments"`
+	Collector     string `json:"collector"`
+	DataService   string `json:"data_service"`
+}
+
+// ExportedAccount is a named test account of an exported environment
+type ExportedAccount struct {
+	Name       string `json:"name"`
+	Address    string `json:"address"`
+	PrivateKey string `json:"private_key"`
+}
+
+// NamedAccount is a test account along with its role in the environment
+type NamedAccount struct {
+	Name string
+	Account
+}
+
+// Accounts returns the environment test accounts in a stable order
+func (env *Env) Accounts() []NamedAccount {
+	return []NamedAccount{
+		{"deployer", env.Deployer},
+		{"service_provider", env.ServiceProvider},
+		{"payer", env.Payer},
+		{"user1", env.User1},
+		{"user2", env.User2},
+		{"user3", env.User3},
+	}
+}
+
+// Export returns the JSON-serializable description of the environment
+func (env *Env) Export() *Export {
+	export := &Export{
+		SchemaVersion: ExportSchema.Version(),
+		RPCURL:        env.RPCURL,
+		ChainID:       env.ChainID,
+		FaultProxyURL: env.FaultProxyURL,
+		Contracts: ExportedContracts{
+			GRTToken:      env.GRTToken.Address.Pretty(),
+			Controller:    env.Controller.Address.Pretty(),
+			Staking:       env.Staking.Address.Pretty(),
+			Escrow:        env.Escrow.Address.Pretty(),
+			GraphPayments: env.GraphPayments.Address.Pretty(),
+			Collector:     env.Collector.Address.Pretty(),
+			DataService:   env.DataService.Address.Pretty(),
+		},
+	}
+
+	for _, account := range env.Accounts() {
+		export.Accounts = append(export.Accounts, ExportedAccount{
+			Name:       account.Name,
+			Address:    account.Address.Pretty(),
+			PrivateKey: "0x" + account.PrivateKey.String(),
+		})
+	}
+
+	if env.Secondary != nil {
+		export.Secondary = env.Secondary.Export()
+	}
+
+	return export
+}
+
+// WriteExportFile writes the environment description to path
+func (env *Env) WriteExportFile(path string) error {
+	data, err := json.MarshalIndent(env.Export(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding export: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("writing export file %q: %w", path, err)
+	}
+	return nil
+}
+
+// Attach connects to an environment started by another process through its
+// export file. The returned Env supports every helper (MintGRT, DepositEscrow, ...)
+// but does not own the Anvil container, so it must not be shut down. The
+// secondary chain, if any, is attached as Env.Secondary.
+func Attach(ctx context.Context, path string) (*Env, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading export file %q (is the development environment running?): %w", path, err)
+	}
+
+	var export Export
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("parsing export file %q: %w", path, err)
+	}
+
+	env, err := attach(ctx, &export)
+	if err != nil {
+		return nil, err
+	}
+
+	if export.Secondary != nil {
+		env.Secondary, err = attach(ctx, export.Secondary)
+		if err != nil {
+			return nil, fmt.Errorf("secondary chain: %w", err)
+		}
+	}
+
+	return env, nil
+}
+
+func attach(ctx context.Context, export *Export) (*Env, error) {
+	var err error
+	env := &Env{
+		ctx:           ctx,
+		cancel:        func() {},
+		rpcClient:     rpc.NewClient(export.RPCURL),
+		RPCURL:        export.RPCURL,
+		ChainID:       export.ChainID,
+		FaultProxyURL: export.FaultProxyURL,
+		GRTToken:      mustLoadContract("MockGRTToken"),
+		Controller:    mustLoadContract("MockController"),
+		Staking:       mustLoadContract("MockStaking"),
+		Escrow:        mustLoadContract("PaymentsEscrow"),
+		GraphPayments: mustLoadContract("GraphPayments"),
+		Collector:     mustLoadContract("GraphTallyCollector"),
+		DataService:   mustLoadContract("SubstreamsDataService"),
+		Packages:      ExamplePackages,
+	}
+
+	for contract, address := range map[*Contract]string{
+		env.GRTToken:      export.Contracts.GRTToken,
+		env.Controller:    export.Contracts.Controller,
+		env.Staking:       export.Contracts.Staking,
+		env.Escrow:        export.Contracts.Escrow,
+		env.GraphPayments: export.Contracts.GraphPayments,
+		env.Collector:     export.Contracts.Collector,
+		env.DataService:   export.Contracts.DataService,
+	} {
+		contract.Address, err = eth.NewAddress(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid contract address %q in export file: %w", address, err)
+		}
+	}
+
+	accounts := map[string]*Account{
+		"deployer":         &env.Deployer,
+		"service_provider": &env.ServiceProvider,
+		"payer":            &env.Payer,
+		"user1":            &env.User1,
+		"user2":            &env.User2,
+		"user3":            &env.User3,
+	}
+	for _, exported := range export.Accounts {
+		account, found := accounts[exported.Name]
+		if !found {
+			continue
+		}
+
+		key, err := eth.NewPrivateKey(exported.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key for account %q in export file: %w", exported.Name, err)
+		}
+		*account = Account{Address: key.PublicKey().Address(), PrivateKey: key}
+	}
+
+	chainID, err := env.rpcClient.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("querying chain ID at %s (is the development environment running?): %w", export.RPCURL, err)
+	}
+	if chainID.Uint64() != export.ChainID {
+		return nil, fmt.Errorf("chain ID mismatch: export file has %d but %s reports %d", export.ChainID, export.RPCURL, chainID.Uint64())
+	}
+
+	return env, nil
+}
@@ -0,0 +1,54 @@
+package devenv
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport_SecondaryChain(t *testing.T) {
+	newEnv := func(chainID uint64, collector string) *Env {
+		account := mustAccountFromHex("dd02564c0e9836fb570322be23f8355761d4d04ebccdc53f4f53325227680a9f")
+		return &Env{
+			RPCURL:          "http://localhost:8545",
+			ChainID:         chainID,
+			GRTToken:        &Contract{},
+			Controller:      &Contract{},
+			Staking:         &Contract{},
+			Escrow:          &Contract{},
+			GraphPayments:   &Contract{},
+			Collector:       &Contract{Address: eth.MustNewAddress(collector)},
+			DataService:     &Contract{},
+			Deployer:        account,
+			ServiceProvider: account,
+			Payer:           account,
+			User1:           account,
+			User2:           account,
+			User3:           account,
+		}
+	}
+
+	env := newEnv(1337, "0x1111111111111111111111111111111111111111")
+
+	data, err := json.Marshal(env.Export())
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), `"secondary"`)
+
+	env.Secondary = newEnv(42161, "0x2222222222222222222222222222222222222222")
+
+	data, err = json.Marshal(env.Export())
+	require.NoError(t, err)
+
+	var export Export
+	require.NoError(t, json.Unmarshal(data, &export))
+	assert.Equal(t, uint64(1337), export.ChainID)
+	assert.Equal(t, "0x1111111111111111111111111111111111111111", export.Contracts.Collector)
+	require.NotNil(t, export.Secondary)
+	assert.Equal(t, uint64(42161), export.Secondary.ChainID)
+	assert.Equal(t, "0x2222222222222222222222222222222222222222", export.Secondary.Contracts.Collector)
+	assert.Len(t, export.Secondary.Accounts, 6)
+	assert.Nil(t, export.Secondary.Secondary)
+}
@@ -0,0 +1,392 @@
+package devenv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/streamingfast/eth-go"
+	"go.uber.org/zap"
+)
+
+// FaultsPath is the path of the fault proxy control endpoint: GET returns the
+// injected Faults, PUT replaces them
+const FaultsPath = "/faults"
+
+// Faults describes the failures injected by a FaultProxy in the JSON-RPC
+// requests it forwards to the chain. The zero value injects nothing.
+type Faults struct {
+	// Latency delays every request before it is forwarded
+	Latency time.Duration
+	// ErrorRate is the probability, between 0 and 1, that a request fails with
+	// a 503 Service Unavailable instead of being forwarded
+	ErrorRate float64
+	// DropTxRate is the probability, between 0 and 1, that an
+	// eth_sendRawTransaction is acknowledged with its transaction hash but
+	// never forwarded, so the transaction is never mined
+	DropTxRate float64
+	// Methods restricts latency and errors to these JSON-RPC methods (e.g.
+	// "eth_call"), all methods when empty
+	Methods []string
+	// Seed seeds the random source deciding which requests fail or are
+	// dropped, the same seed and sequence of requests injecting the same faults
+	Seed int64
+}
+
+// Validate checks that the fault rates are probabilities
+func (f Faults) Validate() error {
+	if f.Latency < 0 {
+		return fmt.Errorf("latency must not be negative, got %s", f.Latency)
+	}
+	if f.ErrorRate < 0 || f.ErrorRate > 1 {
+		return fmt.Errorf("error rate must be between 0 and 1, got %v", f.ErrorRate)
+	}
+	if f.DropTxRate < 0 || f.DropTxRate > 1 {
+		return fmt.Errorf("dropped transaction rate must be between 0 and 1, got %v", f.DropTxRate)
+	}
+	return nil
+}
+
+// String returns a one line summary of the faults
+func (f Faults) String() string {
+	if f.Latency == 0 && f.ErrorRate == 0 && f.DropTxRate == 0 {
+		return "none"
+	}
+
+	methods := "all methods"
+	if len(f.Methods) > 0 {
+		methods = fmt.Sprintf("%v", f.Methods)
+	}
+	return fmt.Sprintf("latency %s, error rate %v, dropped transaction rate %v (%s, seed %d)", f.Latency, f.ErrorRate, f.DropTxRate, methods, f.Seed)
+}
+
+type jsonFaults struct {
+	Latency    string   `json:"latency"`
+	ErrorRate  float64  `json:"error_rate"`
+	DropTxRate float64  `json:"drop_tx_rate"`
+	Methods    []string `json:"methods,omitempty"`
+	Seed       int64    `json:"seed"`
+}
+
+// MarshalJSON implements json.Marshaler, the latency is encoded as a duration string (e.g. "250ms")
+func (f Faults) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonFaults{
+		Latency:    f.Latency.String(),
+		ErrorRate:  f.ErrorRate,
+		DropTxRate: f.DropTxRate,
+		Methods:    f.Methods,
+		Seed:       f.Seed,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (f *Faults) UnmarshalJSON(data []byte) error {
+	var in jsonFaults
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	var latency time.Duration
+	if in.Latency != "" {
+		var err error
+		latency, err = time.ParseDuration(in.Latency)
+		if err != nil {
+			return fmt.Errorf("invalid latency %q: %w", in.Latency, err)
+		}
+	}
+
+	*f = Faults{
+		Latency:    latency,
+		ErrorRate:  in.ErrorRate,
+		DropTxRate: in.DropTxRate,
+		Methods:    in.Methods,
+		Seed:       in.Seed,
+	}
+	return nil
+}
+
+// FaultProxy is a JSON-RPC proxy in front of a chain RPC endpoint injecting
+// Faults in the requests it forwards, so the resilience of the sidecars (RPC
+// failover, collection retries, degraded mode) can be tested on demand. Point
+// the component under test at URL while the test itself keeps using the chain
+// RPC directly.
+//
+// Faults are decided from a random source seeded with Faults.Seed, a test
+// sending the same requests in the same order sees the same failures.
+// Batched JSON-RPC requests are delayed or failed as a whole and their
+// transactions are never dropped.
+type FaultProxy struct {
+	URL string
+
+	target   string
+	client   *http.Client
+	listener net.Listener
+	server   *http.Server
+
+	mu     sync.Mutex
+	faults Faults
+	random *rand.Rand
+}
+
+// StartFaultProxy starts a proxy forwarding JSON-RPC requests to target with
+// faults injected, listening on a random local port
+func StartFaultProxy(target string, faults Faults) (*FaultProxy, error) {
+	if err := faults.Validate(); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("listening: %w", err)
+	}
+
+	proxy := &FaultProxy{
+		URL:      "http://" + listener.Addr().String(),
+		target:   target,
+		client:   &http.Client{Timeout: time.Minute},
+		listener: listener,
+	}
+	proxy.SetFaults(faults)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+FaultsPath, proxy.handleGetFaults)
+	mux.HandleFunc("PUT "+FaultsPath, proxy.handleSetFaults)
+	mux.HandleFunc("POST /", proxy.handleRPC)
+	proxy.server = &http.Server{Handler: mux}
+
+	go func() {
+		if err := proxy.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			zlog.Warn("fault proxy stopped", zap.Error(err))
+		}
+	}()
+
+	zlog.Info("fault proxy started", zap.String("url", proxy.URL), zap.String("target", target), zap.Stringer("faults", faults))
+	return proxy, nil
+}
+
+// Faults returns the faults currently injected
+func (p *FaultProxy) Faults() Faults {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.faults
+}
+
+// SetFaults replaces the injected faults and reseeds the random source with
+// faults.Seed, e.g. the zero Faults stops injecting anything
+func (p *FaultProxy) SetFaults(faults Faults) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.faults = faults
+	p.random = rand.New(rand.NewSource(faults.Seed))
+}
+
+// Close stops the proxy
+func (p *FaultProxy) Close() error {
+	return p.server.Close()
+}
+
+// rpcRequest is the part of a JSON-RPC request the proxy looks at
+type rpcRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+// decision is the fault injected in a request
+type decision struct {
+	latency time.Duration
+	fail    bool
+	drop    bool
+}
+
+// decide picks the fault injected in a request calling methods, batches
+// calling several
+func (p *FaultProxy) decide(methods []string, batch bool) decision {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var d decision
+	targeted := len(p.faults.Methods) == 0
+	for _, method := range methods {
+		targeted = targeted || slices.Contains(p.faults.Methods, method)
+	}
+	if targeted {
+		d.latency = p.faults.Latency
+		d.fail = p.faults.ErrorRate > 0 && p.random.Float64() < p.faults.ErrorRate
+	}
+	if !d.fail && !batch && methods[0] == "eth_sendRawTransaction" {
+		d.drop = p.faults.DropTxRate > 0 && p.random.Float64() < p.faults.DropTxRate
+	}
+	return d
+}
+
+func (p *FaultProxy) handleRPC(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("reading request: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var requests []rpcRequest
+	batch := len(bytes.TrimSpace(body)) > 0 && bytes.TrimSpace(body)[0] == '['
+	if batch {
+		err = json.Unmarshal(body, &requests)
+	} else {
+		requests = make([]rpcRequest, 1)
+		err = json.Unmarshal(body, &requests[0])
+	}
+	if err != nil || len(requests) == 0 {
+		http.Error(w, "invalid JSON-RPC request", http.StatusBadRequest)
+		return
+	}
+
+	methods := make([]string, len(requests))
+	for i, request := range requests {
+		methods[i] = request.Method
+	}
+	d := p.decide(methods, batch)
+
+	if d.latency > 0 {
+		select {
+		case <-time.After(d.latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if d.fail {
+		zlog.Debug("fault proxy failing request", zap.Strings("methods", methods))
+		http.Error(w, "fault injected by devenv fault proxy", http.StatusServiceUnavailable)
+		return
+	}
+
+	if d.drop {
+		p.dropTransaction(w, requests[0])
+		return
+	}
+
+	p.forward(w, r.Context(), body)
+}
+
+// dropTransaction acknowledges an eth_sendRawTransaction with the hash of the
+// transaction without forwarding it
+func (p *FaultProxy) dropTransaction(w http.ResponseWriter, request rpcRequest) {
+	var rawTx eth.Hex
+	if len(request.Params) == 0 || json.Unmarshal(request.Params[0], &rawTx) != nil {
+		http.Error(w, "invalid eth_sendRawTransaction params", http.StatusBadRequest)
+		return
+	}
+
+	hash := eth.Hash(eth.Keccak256(rawTx))
+	zlog.Debug("fault proxy dropping transaction", zap.Stringer("hash", hash))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      request.ID,
+		"result":  hash.Pretty(),
+	})
+}
+
+func (p *FaultProxy) forward(w http.ResponseWriter, ctx context.Context, body []byte) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.target, bytes.NewReader(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("forwarding to chain RPC: %s", err), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+func (p *FaultProxy) handleGetFaults(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p.Faults())
+}
+
+func (p *FaultProxy) handleSetFaults(w http.ResponseWriter, r *http.Request) {
+	var faults Faults
+	if err := json.NewDecoder(r.Body).Decode(&faults); err != nil {
+		http.Error(w, fmt.Sprintf("invalid faults: %s", err), http.StatusBadRequest)
+		return
+	}
+	if err := faults.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	p.SetFaults(faults)
+	zlog.Info("fault proxy faults updated", zap.Stringer("faults", faults))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(faults)
+}
+
+// Faults returns the faults injected by the fault proxy of the environment,
+// which must have been started with WithFaultInjection
+func (env *Env) Faults(ctx context.Context) (Faults, error) {
+	var faults Faults
+	err := env.faultsRequest(ctx, http.MethodGet, nil, &faults)
+	return faults, err
+}
+
+// SetFaults replaces the faults injected by the fault proxy of the
+// environment, which must have been started with WithFaultInjection. It works
+// on attached environments too, e.g. to break the chain RPC of running
+// sidecars from another process.
+func (env *Env) SetFaults(ctx context.Context, faults Faults) error {
+	if err := faults.Validate(); err != nil {
+		return err
+	}
+	return env.faultsRequest(ctx, http.MethodPut, &faults, &faults)
+}
+
+func (env *Env) faultsRequest(ctx context.Context, method string, in, out *Faults) error {
+	if env.FaultProxyURL == "" {
+		return fmt.Errorf("the development environment has no fault proxy (see WithFaultInjection)")
+	}
+
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("encoding faults: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, env.FaultProxyURL+FaultsPath, body)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("reaching fault proxy at %s: %w", env.FaultProxyURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("fault proxy returned %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding faults: %w", err)
+	}
+	return nil
+}
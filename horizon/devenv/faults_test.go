@@ -0,0 +1,128 @@
+package devenv
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/streamingfast/eth-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChain is a JSON-RPC endpoint answering eth_blockNumber and
+// eth_sendRawTransaction, recording the methods it received
+type fakeChain struct {
+	mu      sync.Mutex
+	methods []string
+}
+
+func (c *fakeChain) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request rpcRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	c.methods = append(c.methods, request.Method)
+	c.mu.Unlock()
+
+	result := "0x539"
+	if request.Method == "eth_sendRawTransaction" {
+		result = eth.Hash(make([]byte, 32)).Pretty()
+	}
+	json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": request.ID, "result": result})
+}
+
+func (c *fakeChain) received() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.methods...)
+}
+
+func TestFaultProxy(t *testing.T) {
+	ctx := context.Background()
+	chain := &fakeChain{}
+	server := httptest.NewServer(chain)
+	defer server.Close()
+
+	proxy, err := StartFaultProxy(server.URL, Faults{})
+	require.NoError(t, err)
+	defer proxy.Close()
+
+	client := rpc.NewClient(proxy.URL)
+	rawTx := []byte{0x02, 0x01, 0x02, 0x03}
+
+	blockNumber := func() error {
+		_, err := client.DoRequest(ctx, "eth_blockNumber", nil)
+		return err
+	}
+
+	// No faults, requests are forwarded
+	result, err := client.DoRequest(ctx, "eth_blockNumber", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "0x539", result)
+
+	// Failing every request
+	proxy.SetFaults(Faults{ErrorRate: 1})
+	assert.ErrorContains(t, blockNumber(), "503")
+
+	// Failing only the targeted methods
+	proxy.SetFaults(Faults{ErrorRate: 1, Methods: []string{"eth_call"}})
+	require.NoError(t, blockNumber())
+
+	// Dropped transactions are acknowledged with their hash but never forwarded
+	proxy.SetFaults(Faults{DropTxRate: 1})
+	hash, err := client.SendRawTransaction(ctx, rawTx)
+	require.NoError(t, err)
+	assert.Equal(t, eth.Hash(eth.Keccak256(rawTx)).Pretty(), hash)
+	assert.Equal(t, []string{"eth_blockNumber", "eth_blockNumber"}, chain.received())
+
+	// Latency
+	proxy.SetFaults(Faults{Latency: 100 * time.Millisecond})
+	start := time.Now()
+	require.NoError(t, blockNumber())
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	// The same seed fails the same requests
+	failures := func() (failed []bool) {
+		proxy.SetFaults(Faults{ErrorRate: 0.5, Seed: 42})
+		for range 20 {
+			failed = append(failed, blockNumber() != nil)
+		}
+		return failed
+	}
+	first := failures()
+	assert.Contains(t, first, true)
+	assert.Contains(t, first, false)
+	assert.Equal(t, first, failures())
+}
+
+func TestEnv_SetFaults(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(&fakeChain{})
+	defer server.Close()
+
+	proxy, err := StartFaultProxy(server.URL, Faults{Latency: time.Second})
+	require.NoError(t, err)
+	defer proxy.Close()
+
+	env := &Env{FaultProxyURL: proxy.URL}
+	faults, err := env.Faults(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Faults{Latency: time.Second}, faults)
+
+	updated := Faults{Latency: 250 * time.Millisecond, ErrorRate: 0.1, DropTxRate: 0.5, Methods: []string{"eth_sendRawTransaction"}, Seed: 7}
+	require.NoError(t, env.SetFaults(ctx, updated))
+	assert.Equal(t, updated, proxy.Faults())
+
+	assert.ErrorContains(t, env.SetFaults(ctx, Faults{ErrorRate: 2}), "error rate must be between 0 and 1")
+	_, err = (&Env{}).Faults(ctx)
+	assert.ErrorContains(t, err, "no fault proxy")
+}
@@ -0,0 +1,67 @@
+package devenv
+
+import (
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+)
+
+// ExamplePackage is a substreams package fixture shared by scenarios, tests and
+// examples. Hash stands for the package content hash, the collection IDs are
+// derived from it so every scenario uses the same reproducible values instead
+// of inventing its own constants.
+type ExamplePackage struct {
+	Name    string
+	Version string
+	Hash    eth.Hash
+}
+
+// NewExamplePackage creates a package fixture whose hash is derived from its
+// name and version
+func NewExamplePackage(name, version string) ExamplePackage {
+	return ExamplePackage{
+		Name:    name,
+		Version: version,
+		Hash:    eth.Keccak256([]byte(name + "@" + version)),
+	}
+}
+
+// Example package fixtures
+var (
+	EthereumExplorerPackage = NewExamplePackage("ethereum-explorer", "v0.1.2")
+	UniswapV3Package        = NewExamplePackage("uniswap-v3", "v0.2.10")
+	ERC20BalancesPackage    = NewExamplePackage("erc20-balance-changes", "v1.3.0")
+)
+
+// ExamplePackages lists the example package fixtures
+var ExamplePackages = []ExamplePackage{
+	EthereumExplorerPackage,
+	UniswapV3Package,
+	ERC20BalancesPackage,
+}
+
+// String returns the package reference, e.g. "uniswap-v3@v0.2.10"
+func (p ExamplePackage) String() string {
+	return p.Name + "@" + p.Version
+}
+
+// CollectionID returns the collection ID of payments for the package
+func (p ExamplePackage) CollectionID() horizon.CollectionID {
+	return collectionIDFromHash(eth.Keccak256([]byte("collection"), p.Hash))
+}
+
+// ScenarioCollectionID returns a collection ID for the package distinct per
+// scenario. Scenarios collecting on-chain against the shared environment use it
+// so the tokens collected per collection do not add up across scenarios.
+func (p ExamplePackage) ScenarioCollectionID(scenario string) horizon.CollectionID {
+	return collectionIDFromHash(eth.Keccak256([]byte("collection"), p.Hash, []byte(scenario)))
+}
+
+func collectionIDFromHash(hash []byte) horizon.CollectionID {
+	var collectionID horizon.CollectionID
+	copy(collectionID[:], hash)
+	return collectionID
+}
+
+func collectionIDHex(collectionID horizon.CollectionID) string {
+	return eth.Hash(collectionID[:]).Pretty()
+}
@@ -0,0 +1,28 @@
+package devenv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExamplePackages_CollectionIDs(t *testing.T) {
+	seen := make(map[string]string)
+	for _, pkg := range ExamplePackages {
+		for _, id := range []string{
+			collectionIDHex(pkg.CollectionID()),
+			collectionIDHex(pkg.ScenarioCollectionID("scenario-a")),
+			collectionIDHex(pkg.ScenarioCollectionID("scenario-b")),
+		} {
+			other, found := seen[id]
+			assert.False(t, found, "collection ID %s of %s already used by %s", id, pkg, other)
+			seen[id] = pkg.String()
+		}
+	}
+
+	// Fixtures are deterministic
+	assert.Equal(t, UniswapV3Package.CollectionID(), NewExamplePackage("uniswap-v3", "v0.2.10").CollectionID())
+	assert.Equal(t, UniswapV3Package.ScenarioCollectionID("a"), UniswapV3Package.ScenarioCollectionID("a"))
+	assert.Equal(t, "uniswap-v3@v0.2.10", UniswapV3Package.String())
+	assert.Equal(t, "0x955fdd73bb4a098384be37c123bd660b6b6d7fd58d5aa0a3be334dc173f15286", collectionIDHex(UniswapV3Package.CollectionID()))
+}
@@ -0,0 +1,467 @@
+package devenv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+	"github.com/streamingfast/eth-go/rpc"
+	"github.com/testcontainers/testcontainers-go"
+	"go.uber.org/zap"
+)
+
+// Fork is a transient Anvil node forking a live chain, used to simulate a batch
+// of transactions (e.g. RAV collections) end-to-end against the real chain
+// state before submitting them for real. Unlike the development environment, a
+// fork is not a singleton: start as many as needed and Close them when done.
+type Fork struct {
+	ctx            context.Context
+	cancel         context.CancelFunc
+	anvilContainer testcontainers.Container
+	rpcClient      *rpc.Client
+	RPCURL         string
+	ChainID        uint64
+
+	// contracts are the known contracts of the forked chain, used to decode
+	// simulated calls and events
+	contracts []*Contract
+}
+
+// ForkConfig holds the configuration of a fork
+type ForkConfig struct {
+	// BlockNumber is the block the chain is forked at, the latest block when 0
+	BlockNumber uint64
+	// Contracts maps contract artifact names (e.g. "PaymentsEscrow") to their
+	// address on the forked chain, used to decode simulated calls and events
+	Contracts map[string]eth.Address
+	// Reporter is used to report progress during startup
+	Reporter Reporter
+}
+
+// ForkOption is a function that modifies a ForkConfig
+type ForkOption func(*ForkConfig)
+
+// WithForkBlockNumber forks the chain at the given block instead of the latest
+func WithForkBlockNumber(blockNumber uint64) ForkOption {
+	return func(c *ForkConfig) {
+		c.BlockNumber = blockNumber
+	}
+}
+
+// WithForkContract decodes calls and events of the contract deployed at address
+// on the forked chain with the ABI of the named artifact
+func WithForkContract(name string, address eth.Address) ForkOption {
+	return func(c *ForkConfig) {
+		c.Contracts[name] = address
+	}
+}
+
+// WithForkReporter sets the progress reporter
+func WithForkReporter(reporter Reporter) ForkOption {
+	return func(c *ForkConfig) {
+		c.Reporter = reporter
+	}
+}
+
+// StartFork starts an Anvil node forking the chain served by forkURL
+// (requires Docker). The fork must be reachable from the container, an archive
+// endpoint is required when forking at an old block.
+func StartFork(ctx context.Context, forkURL string, opts ...ForkOption) (*Fork, error) {
+	config := &ForkConfig{
+		Contracts: make(map[string]eth.Address),
+		Reporter:  NoopReporter{},
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	report := config.Reporter.ReportProgress
+
+	contracts := make([]*Contract, 0, len(config.Contracts))
+	for name, address := range config.Contracts {
+		contract, err := loadContract(name)
+		if err != nil {
+			return nil, err
+		}
+		contract.Address = address
+		contracts = append(contracts, contract)
+	}
+
+	args := "--fork-url " + forkURL
+	if config.BlockNumber != 0 {
+		args += fmt.Sprintf(" --fork-block-number %d", config.BlockNumber)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	zlog.Info("starting chain fork", zap.Uint64("block_number", config.BlockNumber))
+	report("Starting Anvil fork container...")
+	anvilContainer, rpcURL, rpcClient, chainID, err := startAnvil(ctx, report, args)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	report("Chain fork ready")
+
+	return &Fork{
+		ctx:            ctx,
+		cancel:         cancel,
+		anvilContainer: anvilContainer,
+		rpcClient:      rpcClient,
+		RPCURL:         rpcURL,
+		ChainID:        chainID.Uint64(),
+		contracts:      contracts,
+	}, nil
+}
+
+// Close terminates the fork
+func (f *Fork) Close() {
+	if f.anvilContainer != nil {
+		f.anvilContainer.Terminate(f.ctx)
+	}
+	f.cancel()
+}
+
+// ForkTransaction is a transaction to simulate on a fork, sent from From
+// without its key through account impersonation
+type ForkTransaction struct {
+	From  eth.Address
+	To    eth.Address
+	Value *big.Int
+	Data  []byte
+}
+
+// ForkSimulation is the outcome of a batch of transactions simulated on a fork
+type ForkSimulation struct {
+	Results []*ForkTransactionResult
+}
+
+// ForkTransactionResult is the outcome of one transaction of a simulated batch
+type ForkTransactionResult struct {
+	Transaction *ForkTransaction
+	// Hash is the transaction hash on the fork, empty when it was not mined
+	Hash eth.Hash
+	// GasUsed is the gas the transaction used on the fork
+	GasUsed uint64
+
+	// Reverted is true when the transaction would revert, RevertReason holds
+	// the decoded revert error
+	Reverted     bool
+	RevertReason string
+
+	// Method is the decoded method signature, empty if the target ABI is unknown
+	Method string
+	Args   []interface{}
+	// Events are the logs the transaction emitted, decoded when the emitter ABI is known
+	Events []*SimulatedEvent
+	// StateDiff lists the accounts whose state the transaction changed, only
+	// available when the node supports the prestate tracer
+	StateDiff []*AccountDiff
+}
+
+// AccountDiff is the change of an account state caused by a transaction
+type AccountDiff struct {
+	Address       eth.Address
+	BalanceBefore *big.Int
+	BalanceAfter  *big.Int
+	NonceBefore   uint64
+	NonceAfter    uint64
+	Storage       []*StorageDiff
+}
+
+// StorageDiff is the change of a storage slot caused by a transaction
+type StorageDiff struct {
+	Slot   eth.Hash
+	Before eth.Hash
+	After  eth.Hash
+}
+
+// Reverted returns true if any transaction of the batch would revert
+func (s *ForkSimulation) Reverted() bool {
+	return slices.ContainsFunc(s.Results, func(result *ForkTransactionResult) bool {
+		return result.Reverted
+	})
+}
+
+// String returns a human readable multi-line description of the simulation
+func (s *ForkSimulation) String() string {
+	var b strings.Builder
+	for i, result := range s.Results {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "transaction %d:\n%s", i, result)
+	}
+	return b.String()
+}
+
+// String returns a human readable multi-line description of the transaction outcome
+func (r *ForkTransactionResult) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "from:   %s\n", r.Transaction.From.Pretty())
+	fmt.Fprintf(&b, "to:     %s\n", r.Transaction.To.Pretty())
+	if r.Method != "" {
+		fmt.Fprintf(&b, "method: %s %v\n", r.Method, r.Args)
+	}
+	if r.Reverted {
+		fmt.Fprintf(&b, "status: reverted, %s\n", r.RevertReason)
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "status: success, gas used %d\n", r.GasUsed)
+	for _, event := range r.Events {
+		if event.Name == "" {
+			fmt.Fprintf(&b, "event:  %s unknown (topics %d)\n", event.Address.Pretty(), len(event.Log.Topics))
+			continue
+		}
+		fmt.Fprintf(&b, "event:  %s %s %v\n", event.Address.Pretty(), event.Name, event.Args)
+	}
+	for _, diff := range r.StateDiff {
+		fmt.Fprintf(&b, "state:  %s", diff.Address.Pretty())
+		if diff.BalanceBefore.Cmp(diff.BalanceAfter) != 0 {
+			fmt.Fprintf(&b, " balance %s -> %s", diff.BalanceBefore, diff.BalanceAfter)
+		}
+		if diff.NonceBefore != diff.NonceAfter {
+			fmt.Fprintf(&b, " nonce %d -> %d", diff.NonceBefore, diff.NonceAfter)
+		}
+		if len(diff.Storage) > 0 {
+			fmt.Fprintf(&b, " storage slots %d", len(diff.Storage))
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// Simulate executes txs in order on the fork, each transaction seeing the
+// effects of the previous ones, and reports their outcome. Senders are
+// impersonated so no key is needed, they must hold enough ETH for gas. A
+// reverting transaction does not stop the batch. The fork state is restored
+// afterwards, so batches can be simulated repeatedly on the same fork.
+func (f *Fork) Simulate(txs []*ForkTransaction) (*ForkSimulation, error) {
+	snapshot, err := rpc.Do[string](f.rpcClient, f.ctx, "evm_snapshot", nil)
+	if err != nil {
+		return nil, fmt.Errorf("snapshotting fork state: %w", err)
+	}
+	defer func() {
+		if _, err := rpc.Do[bool](f.rpcClient, f.ctx, "evm_revert", []interface{}{snapshot}); err != nil {
+			zlog.Warn("unable to restore fork state after simulation", zap.Error(err))
+		}
+	}()
+
+	simulation := &ForkSimulation{}
+	for i, tx := range txs {
+		result, err := f.simulateTransaction(tx)
+		if err != nil {
+			return nil, fmt.Errorf("simulating transaction %d: %w", i, err)
+		}
+		simulation.Results = append(simulation.Results, result)
+	}
+
+	return simulation, nil
+}
+
+func (f *Fork) simulateTransaction(tx *ForkTransaction) (*ForkTransactionResult, error) {
+	result := &ForkTransactionResult{Transaction: tx}
+	result.Method, result.Args = decodeCall(f.contracts, tx.To, tx.Data)
+
+	if _, err := rpc.Do[json.RawMessage](f.rpcClient, f.ctx, "anvil_impersonateAccount", []interface{}{tx.From.Pretty()}); err != nil {
+		return nil, fmt.Errorf("impersonating %s: %w", tx.From.Pretty(), err)
+	}
+	defer func() {
+		if _, err := rpc.Do[json.RawMessage](f.rpcClient, f.ctx, "anvil_stopImpersonatingAccount", []interface{}{tx.From.Pretty()}); err != nil {
+			zlog.Debug("unable to stop impersonating account", zap.Stringer("account", tx.From), zap.Error(err))
+		}
+	}()
+
+	params := map[string]interface{}{
+		"from": tx.From.Pretty(),
+		"to":   tx.To.Pretty(),
+		"data": eth.Hex(tx.Data).Pretty(),
+	}
+	if tx.Value != nil {
+		params["value"] = fmt.Sprintf("0x%x", tx.Value)
+	}
+
+	// Anvil estimates the gas of unsigned transactions, failing with the revert data
+	txHash, err := rpc.Do[string](f.rpcClient, f.ctx, "eth_sendTransaction", []interface{}{params})
+	if err != nil {
+		reason, reverted := revertReason(err)
+		if !reverted {
+			return nil, fmt.Errorf("sending transaction: %w", err)
+		}
+		result.Reverted = true
+		result.RevertReason = reason
+		return result, nil
+	}
+
+	receipt, err := awaitReceipt(f.ctx, f.rpcClient, txHash)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Hash = receipt.TransactionHash
+	result.GasUsed = uint64(receipt.GasUsed)
+	if receipt.Status != nil && uint64(*receipt.Status) == 0 {
+		result.Reverted = true
+		result.RevertReason = "reverted during execution"
+	}
+
+	for _, entry := range receipt.Logs {
+		log := entry.ToLog()
+		result.Events = append(result.Events, decodeEvent(f.contracts, &log))
+	}
+
+	result.StateDiff, err = f.traceStateDiff(txHash)
+	if err != nil {
+		// The prestate tracer is optional, simulation results are still valid without state diffs
+		zlog.Debug("unable to trace simulated transaction state diff", zap.String("tx_hash", txHash), zap.Error(err))
+	}
+
+	return result, nil
+}
+
+// prestateAccount is an account state as reported by the prestateTracer
+type prestateAccount struct {
+	Balance string              `json:"balance"`
+	Nonce   *uint64             `json:"nonce"`
+	Storage map[string]eth.Hash `json:"storage"`
+}
+
+// traceStateDiff returns the accounts state changed by the transaction, ordered by address
+func (f *Fork) traceStateDiff(txHash string) ([]*AccountDiff, error) {
+	tracerConfig := map[string]interface{}{
+		"tracer":       "prestateTracer",
+		"tracerConfig": map[string]interface{}{"diffMode": true},
+	}
+
+	trace, err := rpc.Do[*struct {
+		Pre  map[string]*prestateAccount `json:"pre"`
+		Post map[string]*prestateAccount `json:"post"`
+	}](f.rpcClient, f.ctx, "debug_traceTransaction", []interface{}{txHash, tracerConfig})
+	if err != nil {
+		return nil, err
+	}
+
+	return newStateDiff(trace.Pre, trace.Post)
+}
+
+// newStateDiff combines the pre and post states of a prestateTracer diff,
+// fields missing from post are unchanged except storage slots, which are
+// cleared, and accounts or slots missing from pre did not exist before
+func newStateDiff(pre, post map[string]*prestateAccount) ([]*AccountDiff, error) {
+	addresses := make(map[string]bool, len(pre)+len(post))
+	for address := range pre {
+		addresses[address] = true
+	}
+	for address := range post {
+		addresses[address] = true
+	}
+
+	out := make([]*AccountDiff, 0, len(addresses))
+	for addressHex := range addresses {
+		address, err := eth.NewAddress(addressHex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid state diff address %q: %w", addressHex, err)
+		}
+
+		before, after := pre[addressHex], post[addressHex]
+		if before == nil {
+			before = &prestateAccount{}
+		}
+		if after == nil {
+			after = &prestateAccount{}
+		}
+
+		diff := &AccountDiff{Address: address}
+		diff.BalanceBefore, err = parseQuantity(before.Balance)
+		if err != nil {
+			return nil, fmt.Errorf("invalid balance of %s: %w", addressHex, err)
+		}
+		if before.Nonce != nil {
+			diff.NonceBefore = *before.Nonce
+		}
+
+		diff.BalanceAfter, diff.NonceAfter = diff.BalanceBefore, diff.NonceBefore
+		if after.Balance != "" {
+			diff.BalanceAfter, err = parseQuantity(after.Balance)
+			if err != nil {
+				return nil, fmt.Errorf("invalid balance of %s: %w", addressHex, err)
+			}
+		}
+		if after.Nonce != nil {
+			diff.NonceAfter = *after.Nonce
+		}
+
+		slots := make(map[string]*StorageDiff)
+		for slotHex, value := range before.Storage {
+			slot, err := eth.NewHash(slotHex)
+			if err != nil {
+				return nil, fmt.Errorf("invalid storage slot %q: %w", slotHex, err)
+			}
+			slots[slot.Pretty()] = &StorageDiff{Slot: slot, Before: value, After: eth.Hash(make([]byte, 32))}
+		}
+		for slotHex, value := range after.Storage {
+			slot, err := eth.NewHash(slotHex)
+			if err != nil {
+				return nil, fmt.Errorf("invalid storage slot %q: %w", slotHex, err)
+			}
+			storage, found := slots[slot.Pretty()]
+			if !found {
+				storage = &StorageDiff{Slot: slot, Before: eth.Hash(make([]byte, 32))}
+				slots[slot.Pretty()] = storage
+			}
+			storage.After = value
+		}
+		for _, storage := range slots {
+			diff.Storage = append(diff.Storage, storage)
+		}
+		slices.SortFunc(diff.Storage, func(a, b *StorageDiff) int {
+			return strings.Compare(a.Slot.Pretty(), b.Slot.Pretty())
+		})
+
+		out = append(out, diff)
+	}
+
+	slices.SortFunc(out, func(a, b *AccountDiff) int {
+		return strings.Compare(a.Address.Pretty(), b.Address.Pretty())
+	})
+	return out, nil
+}
+
+// parseQuantity parses a hex encoded JSON-RPC quantity, 0 when empty
+func parseQuantity(quantity string) (*big.Int, error) {
+	if quantity == "" {
+		return new(big.Int), nil
+	}
+
+	value, ok := new(big.Int).SetString(strings.TrimPrefix(quantity, "0x"), 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid quantity %q", quantity)
+	}
+	return value, nil
+}
+
+// revertReason decodes the revert error of a failed RPC call, reverted is
+// false when err is not a deterministic execution failure
+func revertReason(err error) (reason string, reverted bool) {
+	var rpcErr *rpc.ErrResponse
+	if !errors.As(err, &rpcErr) {
+		return err.Error(), false
+	}
+
+	if dataHex, ok := rpcErr.Data.(string); ok {
+		if data, decodeErr := eth.NewHex(dataHex); decodeErr == nil && len(data) > 0 {
+			return horizon.DecodeRevert(data), true
+		}
+	}
+	return rpcErr.Message, rpc.IsDeterministicError(rpcErr)
+}
@@ -0,0 +1,109 @@
+package devenv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/streamingfast/eth-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFork_Simulate(t *testing.T) {
+	grt := mustLoadContract("MockGRTToken")
+	grt.Address = eth.MustNewAddress("0x1111111111111111111111111111111111111111")
+	sender := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	receiver := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+
+	transferTopic := eth.Keccak256([]byte("Transfer(address,address,uint256)"))
+	// PaymentsEscrowInsufficientBalance(1000, 3000)
+	insufficientBalance := fmt.Sprintf("0x%x%064x%064x", eth.Keccak256([]byte("PaymentsEscrowInsufficientBalance(uint256,uint256)"))[:4], 1000, 3000)
+	txHash := fmt.Sprintf("0x%064x", 0xabcd)
+
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		methods = append(methods, req.Method)
+
+		result := "null"
+		switch req.Method {
+		case "evm_snapshot":
+			result = `"0x1"`
+		case "evm_revert":
+			result = "true"
+		case "eth_sendTransaction":
+			var params struct {
+				Data string `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(req.Params[0], &params))
+			if params.Data == "0xdead" {
+				fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"error":{"code":3,"message":"execution reverted","data":"%s"}}`, req.ID, insufficientBalance)
+				return
+			}
+			result = fmt.Sprintf("%q", txHash)
+		case "eth_getTransactionReceipt":
+			result = fmt.Sprintf(`{"transactionHash":"%s","status":"0x1","gasUsed":"0x5208","logs":[{"address":"%s","topics":["0x%x","0x%064x","0x%064x"],"data":"0x%064x"}]}`,
+				txHash, grt.Address.Pretty(), transferTopic, []byte(sender), []byte(receiver), 500)
+		case "debug_traceTransaction":
+			result = fmt.Sprintf(`{"pre":{"%s":{"balance":"0x64","nonce":1,"storage":{"0x01":"0x%064x"}},"%s":{"balance":"0x0"}},"post":{"%s":{"balance":"0x32","nonce":2,"storage":{"0x02":"0x%064x"}}}}`,
+				sender.Pretty(), 7, grt.Address.Pretty(), sender.Pretty(), 9)
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%s}`, req.ID, result)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fork := &Fork{ctx: ctx, cancel: cancel, rpcClient: rpc.NewClient(server.URL), RPCURL: server.URL, contracts: []*Contract{grt}}
+	defer fork.Close()
+
+	simulation, err := fork.Simulate([]*ForkTransaction{
+		{From: sender, To: grt.Address, Data: grt.MustCallData("transfer", receiver, big.NewInt(500))},
+		{From: sender, To: grt.Address, Data: []byte{0xde, 0xad}},
+	})
+	require.NoError(t, err)
+	require.Len(t, simulation.Results, 2)
+	assert.True(t, simulation.Reverted())
+
+	transfer := simulation.Results[0]
+	assert.False(t, transfer.Reverted)
+	assert.Equal(t, "transfer(address,uint256)", transfer.Method)
+	assert.Equal(t, uint64(21000), transfer.GasUsed)
+	require.Len(t, transfer.Events, 1)
+	assert.Equal(t, "Transfer", transfer.Events[0].Name)
+
+	require.Len(t, transfer.StateDiff, 2)
+	grtDiff, senderDiff := transfer.StateDiff[0], transfer.StateDiff[1]
+	assert.Equal(t, grt.Address.Pretty(), grtDiff.Address.Pretty())
+	assert.Equal(t, 0, grtDiff.BalanceBefore.Cmp(grtDiff.BalanceAfter))
+
+	assert.Equal(t, sender.Pretty(), senderDiff.Address.Pretty())
+	assert.Equal(t, int64(100), senderDiff.BalanceBefore.Int64())
+	assert.Equal(t, int64(50), senderDiff.BalanceAfter.Int64())
+	assert.Equal(t, uint64(1), senderDiff.NonceBefore)
+	assert.Equal(t, uint64(2), senderDiff.NonceAfter)
+	require.Len(t, senderDiff.Storage, 2)
+	assert.Equal(t, fmt.Sprintf("0x%064x", 7), senderDiff.Storage[0].Before.Pretty())
+	assert.Equal(t, fmt.Sprintf("0x%064x", 0), senderDiff.Storage[0].After.Pretty())
+	assert.Equal(t, fmt.Sprintf("0x%064x", 0), senderDiff.Storage[1].Before.Pretty())
+	assert.Equal(t, fmt.Sprintf("0x%064x", 9), senderDiff.Storage[1].After.Pretty())
+
+	reverted := simulation.Results[1]
+	assert.True(t, reverted.Reverted)
+	assert.Equal(t, "PaymentsEscrowInsufficientBalance(1000, 3000)", reverted.RevertReason)
+	assert.Empty(t, reverted.Hash)
+	assert.Contains(t, simulation.String(), "status: reverted, PaymentsEscrowInsufficientBalance(1000, 3000)")
+
+	assert.Equal(t, "evm_snapshot", methods[0])
+	assert.Equal(t, "evm_revert", methods[len(methods)-1])
+}
@@ -0,0 +1,175 @@
+package devenv
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+	"github.com/streamingfast/eth-go/rpc"
+)
+
+// GRTDecimals is the number of decimals of the GRT token
+const GRTDecimals = horizon.GRTDecimals
+
+var (
+	erc20BalanceOf   = eth.MustNewMethodDef("balanceOf(address)")
+	erc20Allowance   = eth.MustNewMethodDef("allowance(address,address)")
+	erc20TotalSupply = eth.MustNewMethodDef("totalSupply()")
+	erc20Decimals    = eth.MustNewMethodDef("decimals()")
+	erc20Transfer    = eth.MustNewMethodDef("transfer(address,uint256)")
+	erc20Approve     = eth.MustNewMethodDef("approve(address,uint256)")
+)
+
+// GRTClient is a typed client for the GRT ERC-20 token. Calls are encoded from
+// the standard ERC-20 signatures so the client works against MockGRTToken in
+// the development environment as well as the real GRT token (e.g. on Arbitrum).
+type GRTClient struct {
+	Address eth.Address
+
+	call func(ctx context.Context, to eth.Address, data []byte) ([]byte, error)
+	send func(ctx context.Context, key *eth.PrivateKey, to *eth.Address, data []byte) error
+}
+
+// NewGRTClient creates a GRT client for the token deployed at address on the
+// chain served by rpcClient, transactions are signed for chainID
+func NewGRTClient(rpcClient *rpc.Client, chainID uint64, address eth.Address) *GRTClient {
+	return &GRTClient{
+		Address: address,
+		call: func(ctx context.Context, to eth.Address, data []byte) ([]byte, error) {
+			resultHex, err := rpcClient.Call(ctx, rpc.CallParams{To: to, Data: data})
+			if err != nil {
+				return nil, err
+			}
+			return eth.NewHex(resultHex)
+		},
+		send: func(ctx context.Context, key *eth.PrivateKey, to *eth.Address, data []byte) error {
+			return SendTransaction(ctx, rpcClient, key, chainID, to, big.NewInt(0), data)
+		},
+	}
+}
+
+// GRT returns a GRT client for the environment MockGRTToken, its transactions
+// are only simulated when the environment is in dry-run mode and its reads go
+// through the read cache when enabled
+func (env *Env) GRT() *GRTClient {
+	return &GRTClient{
+		Address: env.GRTToken.Address,
+		call: func(_ context.Context, to eth.Address, data []byte) ([]byte, error) {
+			return env.call(to, data)
+		},
+		send: func(_ context.Context, key *eth.PrivateKey, to *eth.Address, data []byte) error {
+			return env.sendTransaction(key, to, big.NewInt(0), data)
+		},
+	}
+}
+
+// BalanceOf returns the GRT balance of owner in wei
+func (c *GRTClient) BalanceOf(ctx context.Context, owner eth.Address) (*big.Int, error) {
+	return c.callUint256(ctx, erc20BalanceOf.NewCall(owner))
+}
+
+// Allowance returns the amount of GRT (wei) spender may transfer on behalf of owner
+func (c *GRTClient) Allowance(ctx context.Context, owner, spender eth.Address) (*big.Int, error) {
+	return c.callUint256(ctx, erc20Allowance.NewCall(owner, spender))
+}
+
+// TotalSupply returns the total GRT supply in wei
+func (c *GRTClient) TotalSupply(ctx context.Context) (*big.Int, error) {
+	return c.callUint256(ctx, erc20TotalSupply.NewCall())
+}
+
+// Decimals returns the number of decimals of the token
+func (c *GRTClient) Decimals(ctx context.Context) (uint64, error) {
+	decimals, err := c.callUint256(ctx, erc20Decimals.NewCall())
+	if err != nil {
+		return 0, err
+	}
+	if !decimals.IsUint64() {
+		return 0, fmt.Errorf("invalid decimals %s", decimals)
+	}
+	return decimals.Uint64(), nil
+}
+
+// CheckDecimals returns an error when the token does not have GRTDecimals
+// decimals as mainnet GRT does, amounts computed against it being then off by
+// orders of magnitude
+func (c *GRTClient) CheckDecimals(ctx context.Context) error {
+	decimals, err := c.Decimals(ctx)
+	if err != nil {
+		return fmt.Errorf("reading GRT token decimals: %w", err)
+	}
+	if decimals != GRTDecimals {
+		return fmt.Errorf("GRT token at %s has %d decimals, expected %d as mainnet GRT", c.Address.Pretty(), decimals, GRTDecimals)
+	}
+	return nil
+}
+
+// Transfer sends amount GRT (wei) from the key's account to to
+func (c *GRTClient) Transfer(ctx context.Context, key *eth.PrivateKey, to eth.Address, amount *big.Int) error {
+	data, err := erc20Transfer.NewCall(to, amount).Encode()
+	if err != nil {
+		return fmt.Errorf("encoding transfer call: %w", err)
+	}
+
+	return c.send(ctx, key, &c.Address, data)
+}
+
+// Approve allows spender to transfer up to amount GRT (wei) from the key's account
+func (c *GRTClient) Approve(ctx context.Context, key *eth.PrivateKey, spender eth.Address, amount *big.Int) error {
+	data, err := erc20Approve.NewCall(spender, amount).Encode()
+	if err != nil {
+		return fmt.Errorf("encoding approve call: %w", err)
+	}
+
+	return c.send(ctx, key, &c.Address, data)
+}
+
+func (c *GRTClient) callUint256(ctx context.Context, call *eth.MethodCall) (*big.Int, error) {
+	data, err := call.Encode()
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s call: %w", call.MethodDef.Name, err)
+	}
+
+	result, err := c.call(ctx, c.Address, data)
+	if err != nil {
+		return nil, fmt.Errorf("calling %s: %w", call.MethodDef.Name, err)
+	}
+
+	// Result is uint256 (32 bytes)
+	if len(result) != 32 {
+		return nil, fmt.Errorf("unexpected %s result length: %d", call.MethodDef.Name, len(result))
+	}
+
+	return new(big.Int).SetBytes(result), nil
+}
+
+// FormatGRT formats a wei amount as a decimal GRT string, see horizon.FormatGRT
+func FormatGRT(wei *big.Int) string {
+	return horizon.FormatGRT(wei)
+}
+
+// ParseGRT parses a decimal GRT amount (e.g. "1.5") into wei, amounts with more
+// than GRTDecimals decimals are rejected rather than truncated
+func ParseGRT(amount string) (*big.Int, error) {
+	amount = strings.TrimSpace(amount)
+	if amount == "" {
+		return nil, fmt.Errorf("empty amount")
+	}
+
+	integer, fraction, _ := strings.Cut(amount, ".")
+	if len(fraction) > GRTDecimals {
+		return nil, fmt.Errorf("amount %q has more than %d decimals", amount, GRTDecimals)
+	}
+	if integer == "" || integer == "-" {
+		integer += "0"
+	}
+
+	wei, ok := new(big.Int).SetString(integer+fraction+strings.Repeat("0", GRTDecimals-len(fraction)), 10)
+	if !ok || strings.ContainsAny(fraction, "+-") {
+		return nil, fmt.Errorf("invalid amount %q", amount)
+	}
+	return wei, nil
+}
@@ -0,0 +1,83 @@
+package devenv
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatGRT(t *testing.T) {
+	tests := []struct {
+		wei      string
+		expected string
+	}{
+		{"0", "0"},
+		{"1000000000000000000", "1"},
+		{"1500000000000000000", "1.5"},
+		{"1", "0.000000000000000001"},
+		{"-2500000000000000000", "-2.5"},
+		{"10000000000000000000000", "10000"},
+	}
+
+	for _, tt := range tests {
+		wei, ok := new(big.Int).SetString(tt.wei, 10)
+		require.True(t, ok)
+		assert.Equal(t, tt.expected, FormatGRT(wei), tt.wei)
+	}
+
+	assert.Equal(t, "0", FormatGRT(nil))
+}
+
+func TestParseGRT(t *testing.T) {
+	tests := []struct {
+		amount   string
+		expected string
+		wantErr  bool
+	}{
+		{"1", "1000000000000000000", false},
+		{"1.5", "1500000000000000000", false},
+		{".5", "500000000000000000", false},
+		{"0.000000000000000001", "1", false},
+		{" 10000 ", "10000000000000000000000", false},
+		{"0.0000000000000000001", "", true},
+		{"", "", true},
+		{"abc", "", true},
+		{"1.2.3", "", true},
+		{"1.-5", "", true},
+	}
+
+	for _, tt := range tests {
+		wei, err := ParseGRT(tt.amount)
+		if tt.wantErr {
+			assert.Error(t, err, tt.amount)
+			continue
+		}
+		require.NoError(t, err, tt.amount)
+		assert.Equal(t, tt.expected, wei.String(), tt.amount)
+	}
+}
+
+func TestGRTClient_CheckDecimals(t *testing.T) {
+	grt := func(decimals int64) *GRTClient {
+		return &GRTClient{
+			Address: eth.MustNewAddress("0x1111111111111111111111111111111111111111"),
+			call: func(_ context.Context, _ eth.Address, data []byte) ([]byte, error) {
+				assert.Equal(t, erc20Decimals.MethodID(), data)
+				return big.NewInt(decimals).FillBytes(make([]byte, 32)), nil
+			},
+		}
+	}
+
+	decimals, err := grt(18).Decimals(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(18), decimals)
+	assert.NoError(t, grt(18).CheckDecimals(context.Background()))
+
+	err = grt(6).CheckDecimals(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "has 6 decimals, expected 18")
+}
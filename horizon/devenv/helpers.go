@@ -2,10 +2,8 @@ package devenv
 
 import (
 	"context"
-	"encoding/hex"
 	"fmt"
 	"math/big"
-	"strings"
 	"time"
 
 	"github.com/graphprotocol/substreams-data-service/horizon"
@@ -15,8 +13,21 @@ import (
 	"go.uber.org/zap"
 )
 
-// waitForReceipt waits for a transaction receipt
+// waitForReceipt waits for a transaction receipt, failing when the
+// transaction reverted
 func waitForReceipt(ctx context.Context, rpcClient *rpc.Client, txHash string) error {
+	receipt, err := awaitReceipt(ctx, rpcClient, txHash)
+	if err != nil {
+		return err
+	}
+	if receipt.Status != nil && uint64(*receipt.Status) == 0 {
+		return fmt.Errorf("transaction failed: %s", txHash)
+	}
+	return nil
+}
+
+// awaitReceipt waits for a transaction to be mined and returns its receipt
+func awaitReceipt(ctx context.Context, rpcClient *rpc.Client, txHash string) (*rpc.TransactionReceipt, error) {
 	timeout := time.After(30 * time.Second)
 	ticker := time.NewTicker(500 * time.Millisecond)
 	defer ticker.Stop()
@@ -25,18 +36,15 @@ func waitForReceipt(ctx context.Context, rpcClient *rpc.Client, txHash string) e
 	for {
 		select {
 		case <-timeout:
-			return fmt.Errorf("timeout waiting for transaction %s", txHash)
+			return nil, fmt.Errorf("timeout waiting for transaction %s", txHash)
 		case <-ticker.C:
 			receipt, err := rpcClient.TransactionReceipt(ctx, hash)
 			if err != nil || receipt == nil {
 				continue // Not mined yet
 			}
-			if receipt.Status != nil && uint64(*receipt.Status) == 0 {
-				return fmt.Errorf("transaction failed: %s", txHash)
-			}
-			return nil
+			return receipt, nil
 		case <-ctx.Done():
-			return ctx.Err()
+			return nil, ctx.Err()
 		}
 	}
 }
@@ -100,23 +108,10 @@ func SendTransaction(ctx context.Context, rpcClient *rpc.Client, key *eth.Privat
 	return err
 }
 
-// CallContract makes a read-only contract call
+// CallContract makes a read-only contract call, served from the read cache
+// when enabled
 func (env *Env) CallContract(to eth.Address, data []byte) ([]byte, error) {
-	params := rpc.CallParams{
-		To:   to,
-		Data: data,
-	}
-
-	resultHex, err := env.rpcClient.Call(env.ctx, params)
-	if err != nil {
-		return nil, err
-	}
-
-	if strings.HasPrefix(resultHex, "0x") {
-		resultHex = resultHex[2:]
-	}
-
-	return hex.DecodeString(resultHex)
+	return env.call(to, data)
 }
 
 // MintGRT mints GRT tokens to an address
@@ -125,34 +120,22 @@ func (env *Env) MintGRT(to eth.Address, amount *big.Int) error {
 	if err != nil {
 		return err
 	}
-	return SendTransaction(env.ctx, env.rpcClient, env.Deployer.PrivateKey, env.ChainID, &env.GRTToken.Address, big.NewInt(0), data)
+	return env.sendTransaction(env.Deployer.PrivateKey, &env.GRTToken.Address, big.NewInt(0), data)
 }
 
 // ApproveGRT approves the escrow contract to spend GRT (from Payer account)
 func (env *Env) ApproveGRT(amount *big.Int) error {
-	data, err := env.GRTToken.CallData("approve", env.Escrow.Address, amount)
-	if err != nil {
-		return err
-	}
-	return SendTransaction(env.ctx, env.rpcClient, env.Payer.PrivateKey, env.ChainID, &env.GRTToken.Address, big.NewInt(0), data)
+	return env.GRT().Approve(env.ctx, env.Payer.PrivateKey, env.Escrow.Address, amount)
 }
 
 // DepositEscrow deposits GRT into escrow (from Payer to Collector for ServiceProvider)
 func (env *Env) DepositEscrow(amount *big.Int) error {
-	data, err := env.Escrow.CallData("deposit", env.Collector.Address, env.ServiceProvider.Address, amount)
-	if err != nil {
-		return err
-	}
-	return SendTransaction(env.ctx, env.rpcClient, env.Payer.PrivateKey, env.ChainID, &env.Escrow.Address, big.NewInt(0), data)
+	return env.depositEscrow(env.Payer, env.ServiceProvider.Address, amount)
 }
 
 // SetProvision sets provision tokens for service provider
-func (env *Env) SetProvision(tokens *big.Int, maxVerifierCut uint32, thawingPeriod uint64) error {
-	data, err := env.Staking.CallData("setProvision", env.ServiceProvider.Address, env.DataService.Address, tokens, maxVerifierCut, thawingPeriod)
-	if err != nil {
-		return err
-	}
-	return SendTransaction(env.ctx, env.rpcClient, env.Deployer.PrivateKey, env.ChainID, &env.Staking.Address, big.NewInt(0), data)
+func (env *Env) SetProvision(tokens *big.Int, maxVerifierCut horizon.PPM, thawingPeriod uint64) error {
+	return env.setProvision(env.ServiceProvider.Address, tokens, maxVerifierCut, thawingPeriod)
 }
 
 // SetProvisionTokensRange sets the minimum provision tokens for the data service
@@ -161,41 +144,17 @@ func (env *Env) SetProvisionTokensRange(minimumProvisionTokens *big.Int) error {
 	if err != nil {
 		return err
 	}
-	return SendTransaction(env.ctx, env.rpcClient, env.Deployer.PrivateKey, env.ChainID, &env.DataService.Address, big.NewInt(0), data)
+	return env.sendTransaction(env.Deployer.PrivateKey, &env.DataService.Address, big.NewInt(0), data)
 }
 
 // RegisterServiceProvider registers the service provider with the data service
 func (env *Env) RegisterServiceProvider() error {
-	// Encode the paymentsDestination as the data parameter (abi.encode(address))
-	registerData := make([]byte, 32)
-	copy(registerData[12:], env.ServiceProvider.Address[:])
-
-	data, err := env.DataService.CallData("register", env.ServiceProvider.Address, registerData)
-	if err != nil {
-		return err
-	}
-	return SendTransaction(env.ctx, env.rpcClient, env.ServiceProvider.PrivateKey, env.ChainID, &env.DataService.Address, big.NewInt(0), data)
+	return env.registerServiceProvider(env.ServiceProvider)
 }
 
 // AuthorizeSigner authorizes a signer key to sign RAVs for the payer
 func (env *Env) AuthorizeSigner(signerKey *eth.PrivateKey) error {
-	signerAddr := signerKey.PublicKey().Address()
-
-	// Generate proof with deadline 1 hour in the future
-	proofDeadline := uint64(time.Now().Add(1 * time.Hour).Unix())
-
-	proof, err := GenerateSignerProof(env.ChainID, env.Collector.Address, proofDeadline, env.Payer.Address, signerKey)
-	if err != nil {
-		return fmt.Errorf("generating signer proof: %w", err)
-	}
-
-	// Encode call: authorizeSigner(address signer, uint256 proofDeadline, bytes proof)
-	data, err := env.Collector.CallData("authorizeSigner", signerAddr, new(big.Int).SetUint64(proofDeadline), proof)
-	if err != nil {
-		return fmt.Errorf("encoding authorizeSigner call: %w", err)
-	}
-
-	return SendTransaction(env.ctx, env.rpcClient, env.Payer.PrivateKey, env.ChainID, &env.Collector.Address, big.NewInt(0), data)
+	return env.authorizeSigner(env.Payer, signerKey)
 }
 
 // ThawSigner initiates thawing for a signer
@@ -204,7 +163,7 @@ func (env *Env) ThawSigner(signer eth.Address) error {
 	if err != nil {
 		return fmt.Errorf("encoding thawSigner call: %w", err)
 	}
-	return SendTransaction(env.ctx, env.rpcClient, env.Payer.PrivateKey, env.ChainID, &env.Collector.Address, big.NewInt(0), data)
+	return env.sendTransaction(env.Payer.PrivateKey, &env.Collector.Address, big.NewInt(0), data)
 }
 
 // RevokeAuthorizedSigner revokes a signer after thawing
@@ -213,7 +172,7 @@ func (env *Env) RevokeAuthorizedSigner(signer eth.Address) error {
 	if err != nil {
 		return fmt.Errorf("encoding revokeAuthorizedSigner call: %w", err)
 	}
-	return SendTransaction(env.ctx, env.rpcClient, env.Payer.PrivateKey, env.ChainID, &env.Collector.Address, big.NewInt(0), data)
+	return env.sendTransaction(env.Payer.PrivateKey, &env.Collector.Address, big.NewInt(0), data)
 }
 
 // RevokeSigner performs the two-step revoke flow: thaw + revoke
@@ -1,6 +1,9 @@
 package devenv
 
-import "math/big"
+import (
+	"math/big"
+	"time"
+)
 
 // Reporter is an interface for reporting progress during devenv startup
 type Reporter interface {
@@ -16,14 +19,49 @@ func (NoopReporter) ReportProgress(message string) {}
 type Config struct {
 	// ChainID is the chain ID for the Anvil network (default: 1337)
 	ChainID uint64
+	// SecondaryChainID starts a second Anvil network with this chain ID and its
+	// own deployment of the contracts when non-zero (default: 0, disabled)
+	SecondaryChainID uint64
 	// EscrowAmount is the default amount to deposit in escrow (default: 10,000 GRT)
 	EscrowAmount *big.Int
+	// MintAmounts are the GRT amounts minted to the test accounts at startup
+	// (default: 10,000 GRT each)
+	MintAmounts MintAmounts
 	// ProvisionAmount is the default provision amount (default: 1,000 GRT)
 	ProvisionAmount *big.Int
+	// GenesisTimestamp makes block timestamps deterministic when non-zero: the
+	// genesis block is stamped with it and each following block is
+	// BlockTimestampInterval later than its parent, whatever the wall clock
+	// (default: zero, blocks follow the wall clock)
+	GenesisTimestamp time.Time
+	// BlockTimestampInterval is the time between two consecutive blocks when
+	// GenesisTimestamp is set (default: 1s)
+	BlockTimestampInterval time.Duration
+	// Faults starts a FaultProxy in front of the primary chain RPC injecting
+	// them when non-nil (default: nil, no proxy)
+	Faults *Faults
+	// ReadCache caches the chain reads of the environment helpers between two
+	// writes, see WithReadCache (default: false)
+	ReadCache bool
 	// Reporter is used to report progress during startup
 	Reporter Reporter
 }
 
+// MintAmounts are the GRT amounts (wei) minted to each class of test account
+// when the environment starts, nothing being minted to a class whose amount is
+// nil or zero
+type MintAmounts struct {
+	Deployer        *big.Int
+	ServiceProvider *big.Int
+	Payer           *big.Int
+	// Users is minted to each of User1, User2 and User3
+	Users *big.Int
+}
+
+// DefaultBlockTimestampInterval is the time between two consecutive blocks
+// when block timestamps are deterministic
+const DefaultBlockTimestampInterval = time.Second
+
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
 	escrow := new(big.Int)
@@ -32,11 +70,21 @@ func DefaultConfig() *Config {
 	provision := new(big.Int)
 	provision.SetString("1000000000000000000000", 10) // 1,000 GRT
 
+	mint := new(big.Int)
+	mint.SetString("10000000000000000000000", 10) // 10,000 GRT
+
 	return &Config{
 		ChainID:         1337,
 		EscrowAmount:    escrow,
 		ProvisionAmount: provision,
-		Reporter:        NoopReporter{},
+		MintAmounts: MintAmounts{
+			Deployer:        mint,
+			ServiceProvider: mint,
+			Payer:           mint,
+			Users:           mint,
+		},
+		BlockTimestampInterval: DefaultBlockTimestampInterval,
+		Reporter:               NoopReporter{},
 	}
 }
 
@@ -50,6 +98,46 @@ func WithChainID(chainID uint64) Option {
 	}
 }
 
+// WithSecondaryChain starts a second chain with the given chain ID alongside
+// the primary one, e.g. to test sidecars routing between chains
+func WithSecondaryChain(chainID uint64) Option {
+	return func(c *Config) {
+		c.SecondaryChainID = chainID
+	}
+}
+
+// WithDeterministicTimestamps stamps the genesis block with genesis and each
+// following block interval later than its parent, so tests comparing
+// timestampNs or waiting out thawing periods are reproducible across runs
+func WithDeterministicTimestamps(genesis time.Time, interval time.Duration) Option {
+	return func(c *Config) {
+		c.GenesisTimestamp = genesis
+		c.BlockTimestampInterval = interval
+	}
+}
+
+// WithFaultInjection starts a FaultProxy in front of the primary chain RPC,
+// injecting faults in the requests of the components pointed at
+// Env.FaultProxyURL. The environment helpers keep using the chain RPC
+// directly, and the faults can be changed later with Env.SetFaults.
+func WithFaultInjection(faults Faults) Option {
+	return func(c *Config) {
+		c.Faults = &faults
+	}
+}
+
+// WithReadCache caches the chain reads of the environment helpers (contract
+// calls, GRT and ETH balances) until the environment next sends a transaction,
+// mines a block or moves time. Sequential flows querying balances right after
+// their own transactions then skip the repeated RPC calls. Writes by other
+// processes (e.g. sidecars collecting RAVs) are seen after
+// Env.RefreshReadCache.
+func WithReadCache() Option {
+	return func(c *Config) {
+		c.ReadCache = true
+	}
+}
+
 // WithEscrowAmount sets the default escrow amount
 func WithEscrowAmount(amount *big.Int) Option {
 	return func(c *Config) {
@@ -57,6 +145,14 @@ func WithEscrowAmount(amount *big.Int) Option {
 	}
 }
 
+// WithMintAmounts sets the GRT amounts minted to the test accounts at startup,
+// independently of the escrow amount
+func WithMintAmounts(amounts MintAmounts) Option {
+	return func(c *Config) {
+		c.MintAmounts = amounts
+	}
+}
+
 // WithProvisionAmount sets the default provision amount
 func WithProvisionAmount(amount *big.Int) Option {
 	return func(c *Config) {
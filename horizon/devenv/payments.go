@@ -0,0 +1,190 @@
+package devenv
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+	"github.com/streamingfast/eth-go/rpc"
+)
+
+// delegationPoolsSlot is the storage slot of the MockStaking _delegationPools
+// mapping (serviceProvider => dataService => DelegationPool)
+const delegationPoolsSlot = 1
+
+// DelegationPool is the delegation pool of a service provider for a data
+// service, as returned by HorizonStaking.getDelegationPool
+type DelegationPool struct {
+	Tokens *big.Int
+	Shares *big.Int
+}
+
+// ProtocolPaymentCut returns the protocol payment cut GraphPayments takes on
+// every collected payment
+func (env *Env) ProtocolPaymentCut() (horizon.PPM, error) {
+	data, err := env.GraphPayments.CallData("PROTOCOL_PAYMENT_CUT")
+	if err != nil {
+		return 0, fmt.Errorf("encoding PROTOCOL_PAYMENT_CUT call: %w", err)
+	}
+
+	result, err := env.CallContract(env.GraphPayments.Address, data)
+	if err != nil {
+		return 0, fmt.Errorf("calling PROTOCOL_PAYMENT_CUT: %w", err)
+	}
+	if len(result) != 32 {
+		return 0, fmt.Errorf("unexpected result length: %d", len(result))
+	}
+
+	return horizon.NewPPM(new(big.Int).SetBytes(result).Uint64())
+}
+
+// SetDelegationFeeCut sets the cut of paymentType payments (see
+// horizon.PaymentTypeQueryFee) GraphPayments sends to the service provider
+// delegation pool for the data service
+func (env *Env) SetDelegationFeeCut(paymentType uint8, cut horizon.PPM) error {
+	if err := cut.Validate(); err != nil {
+		return err
+	}
+
+	data, err := env.Staking.CallData("setDelegationFeeCut", env.ServiceProvider.Address, env.DataService.Address, paymentType, cut.BigInt())
+	if err != nil {
+		return fmt.Errorf("encoding setDelegationFeeCut call: %w", err)
+	}
+	return env.sendTransaction(env.Deployer.PrivateKey, &env.Staking.Address, big.NewInt(0), data)
+}
+
+// DelegationFeeCut returns the cut of paymentType payments sent to the service
+// provider delegation pool for the data service
+func (env *Env) DelegationFeeCut(paymentType uint8) (horizon.PPM, error) {
+	data, err := env.Staking.CallData("getDelegationFeeCut", env.ServiceProvider.Address, env.DataService.Address, paymentType)
+	if err != nil {
+		return 0, fmt.Errorf("encoding getDelegationFeeCut call: %w", err)
+	}
+
+	result, err := env.CallContract(env.Staking.Address, data)
+	if err != nil {
+		return 0, fmt.Errorf("calling getDelegationFeeCut: %w", err)
+	}
+	if len(result) != 32 {
+		return 0, fmt.Errorf("unexpected result length: %d", len(result))
+	}
+
+	return horizon.NewPPM(new(big.Int).SetBytes(result).Uint64())
+}
+
+// AddToDelegationPool adds tokens, minted to the deployer, to the service
+// provider delegation pool for the data service.
+//
+// GraphPayments only pays the delegation fee cut to pools holding shares,
+// which MockStaking does not track, so a pool without shares is given as many
+// shares as it holds tokens (Anvil-specific), as a first delegation would be.
+func (env *Env) AddToDelegationPool(tokens *big.Int) error {
+	if err := env.MintGRT(env.Deployer.Address, tokens); err != nil {
+		return fmt.Errorf("minting GRT: %w", err)
+	}
+	if err := env.GRT().Approve(env.ctx, env.Deployer.PrivateKey, env.Staking.Address, tokens); err != nil {
+		return fmt.Errorf("approving GRT: %w", err)
+	}
+
+	data, err := env.Staking.CallData("addToDelegationPool", env.ServiceProvider.Address, env.DataService.Address, tokens)
+	if err != nil {
+		return fmt.Errorf("encoding addToDelegationPool call: %w", err)
+	}
+	if err := env.sendTransaction(env.Deployer.PrivateKey, &env.Staking.Address, big.NewInt(0), data); err != nil {
+		return err
+	}
+	if env.DryRun() {
+		return nil
+	}
+
+	pool, err := env.DelegationPool()
+	if err != nil {
+		return err
+	}
+	if pool.Shares.Sign() > 0 {
+		return nil
+	}
+	return env.setStorage(env.Staking.Address, delegationPoolSharesSlot(env.ServiceProvider.Address, env.DataService.Address), pool.Tokens)
+}
+
+// DelegationPool returns the service provider delegation pool for the data
+// service
+func (env *Env) DelegationPool() (*DelegationPool, error) {
+	data, err := env.Staking.CallData("getDelegationPool", env.ServiceProvider.Address, env.DataService.Address)
+	if err != nil {
+		return nil, fmt.Errorf("encoding getDelegationPool call: %w", err)
+	}
+
+	result, err := env.CallContract(env.Staking.Address, data)
+	if err != nil {
+		return nil, fmt.Errorf("calling getDelegationPool: %w", err)
+	}
+
+	// Result is the DelegationPool struct (tokens, shares, tokensThawing,
+	// sharesThawing, thawingNonce), 32 bytes each
+	if len(result) != 5*32 {
+		return nil, fmt.Errorf("unexpected result length: %d", len(result))
+	}
+
+	return &DelegationPool{
+		Tokens: new(big.Int).SetBytes(result[0:32]),
+		Shares: new(big.Int).SetBytes(result[32:64]),
+	}, nil
+}
+
+// ExpectedPaymentSplit returns how GraphPayments distributes tokens collected
+// for paymentType with dataServiceCut, from the deployed protocol payment cut
+// and the service provider delegation fee cut. The delegation fee cut is not
+// taken while the delegation pool holds no shares.
+func (env *Env) ExpectedPaymentSplit(tokens *big.Int, paymentType uint8, dataServiceCut horizon.PPM) (*horizon.PaymentSplit, error) {
+	protocolCut, err := env.ProtocolPaymentCut()
+	if err != nil {
+		return nil, fmt.Errorf("reading protocol payment cut: %w", err)
+	}
+
+	delegationFeeCut, err := env.DelegationFeeCut(paymentType)
+	if err != nil {
+		return nil, fmt.Errorf("reading delegation fee cut: %w", err)
+	}
+	pool, err := env.DelegationPool()
+	if err != nil {
+		return nil, fmt.Errorf("reading delegation pool: %w", err)
+	}
+	if pool.Shares.Sign() == 0 {
+		delegationFeeCut = 0
+	}
+
+	return horizon.SplitPayment(tokens, protocolCut, dataServiceCut, delegationFeeCut)
+}
+
+// delegationPoolSharesSlot returns the storage slot of the shares of the
+// serviceProvider delegation pool for dataService in MockStaking
+func delegationPoolSharesSlot(serviceProvider, dataService eth.Address) []byte {
+	outer := eth.Keccak256(storageWord(serviceProvider), storageWord(big.NewInt(delegationPoolsSlot).Bytes()))
+	pool := eth.Keccak256(storageWord(dataService), outer)
+
+	// shares follows tokens in the DelegationPool struct
+	slot := new(big.Int).SetBytes(pool)
+	return storageWord(slot.Add(slot, big.NewInt(1)).Bytes())
+}
+
+// storageWord left-pads value to a 32 bytes storage word
+func storageWord(value []byte) []byte {
+	word := make([]byte, 32)
+	copy(word[32-len(value):], value)
+	return word
+}
+
+// setStorage writes value to the storage slot of the contract at addr
+// (Anvil-specific)
+func (env *Env) setStorage(addr eth.Address, slot []byte, value *big.Int) error {
+	defer env.invalidateReads()
+
+	params := []interface{}{addr.Pretty(), eth.Hex(slot).Pretty(), eth.Hex(storageWord(value.Bytes())).Pretty()}
+	if _, err := rpc.Do[json.RawMessage](env.rpcClient, env.ctx, "anvil_setStorageAt", params); err != nil {
+		return fmt.Errorf("setting storage slot %s of %s: %w", eth.Hex(slot).Pretty(), addr.Pretty(), err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package devenv
+
+import (
+	"encoding/hex"
+	"math/big"
+	"sync"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/streamingfast/eth-go/rpc"
+)
+
+// readCache memoizes the chain reads of an Env (contract calls and ETH
+// balances) between two writes. Every transaction sent, block mined or time
+// move through the Env clears it, so a flow reads its own writes. Writes by
+// other processes, e.g. a sidecar collecting a RAV, are only seen once the
+// cache is refreshed or cleared by the next write.
+type readCache struct {
+	mu      sync.Mutex
+	enabled bool
+	entries map[string][]byte
+	// generation is bumped on every invalidation, so a read racing with a
+	// write is not cached
+	generation uint64
+
+	hits   uint64
+	misses uint64
+}
+
+// ReadCacheStats reports the reads served by the read cache and those sent to
+// the chain since it was enabled
+type ReadCacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// SetReadCache enables or disables the read cache, see WithReadCache. Disabling
+// it drops the cached reads.
+func (env *Env) SetReadCache(enabled bool) {
+	env.readCache.mu.Lock()
+	defer env.readCache.mu.Unlock()
+
+	env.readCache.enabled = enabled
+	env.readCache.entries = nil
+	env.readCache.generation++
+}
+
+// ReadCacheEnabled returns true if the read cache is enabled
+func (env *Env) ReadCacheEnabled() bool {
+	env.readCache.mu.Lock()
+	defer env.readCache.mu.Unlock()
+
+	return env.readCache.enabled
+}
+
+// RefreshReadCache drops the cached reads so the next ones hit the chain, to
+// observe the writes of other processes
+func (env *Env) RefreshReadCache() {
+	env.readCache.mu.Lock()
+	defer env.readCache.mu.Unlock()
+
+	env.readCache.entries = nil
+	env.readCache.generation++
+}
+
+// ReadCacheStats returns the read cache hits and misses
+func (env *Env) ReadCacheStats() ReadCacheStats {
+	env.readCache.mu.Lock()
+	defer env.readCache.mu.Unlock()
+
+	return ReadCacheStats{Hits: env.readCache.hits, Misses: env.readCache.misses}
+}
+
+// cachedRead returns the cached value of key, calling read and caching its
+// result on a miss. Reads always hit the chain when the cache is disabled.
+func (env *Env) cachedRead(key string, read func() ([]byte, error)) ([]byte, error) {
+	cache := &env.readCache
+
+	cache.mu.Lock()
+	if !cache.enabled {
+		cache.mu.Unlock()
+		return read()
+	}
+	if value, found := cache.entries[key]; found {
+		cache.hits++
+		cache.mu.Unlock()
+		return value, nil
+	}
+	cache.misses++
+	generation := cache.generation
+	cache.mu.Unlock()
+
+	value, err := read()
+	if err != nil {
+		return nil, err
+	}
+
+	cache.mu.Lock()
+	if cache.enabled && cache.generation == generation {
+		if cache.entries == nil {
+			cache.entries = make(map[string][]byte)
+		}
+		cache.entries[key] = value
+	}
+	cache.mu.Unlock()
+
+	return value, nil
+}
+
+// invalidateReads drops the cached reads after a write to the chain
+func (env *Env) invalidateReads() {
+	env.RefreshReadCache()
+}
+
+// call runs a read-only contract call through the read cache
+func (env *Env) call(to eth.Address, data []byte) ([]byte, error) {
+	return env.cachedRead("call:"+to.Pretty()+":"+hex.EncodeToString(data), func() ([]byte, error) {
+		resultHex, err := env.rpcClient.Call(env.ctx, rpc.CallParams{To: to, Data: data})
+		if err != nil {
+			return nil, err
+		}
+		return eth.NewHex(resultHex)
+	})
+}
+
+// ethBalance returns the ETH balance of addr through the read cache
+func (env *Env) ethBalance(addr eth.Address) (*big.Int, error) {
+	value, err := env.cachedRead("balance:"+addr.Pretty(), func() ([]byte, error) {
+		balance, err := env.rpcClient.GetBalance(env.ctx, addr, nil)
+		if err != nil {
+			return nil, err
+		}
+		return balance.Amount.Bytes(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(value), nil
+}
@@ -0,0 +1,95 @@
+package devenv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/streamingfast/eth-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnv_ReadCache(t *testing.T) {
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	balance := int64(100)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		mu.Lock()
+		defer mu.Unlock()
+		calls[req.Method]++
+
+		result := "null"
+		switch req.Method {
+		case "eth_call":
+			result = fmt.Sprintf(`"0x%064x"`, balance)
+		case "eth_getBalance":
+			result = fmt.Sprintf(`"0x%x"`, balance)
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%s}`, req.ID, result)
+	}))
+	defer server.Close()
+
+	env := &Env{
+		ctx:       context.Background(),
+		rpcClient: rpc.NewClient(server.URL),
+		GRTToken:  &Contract{Address: eth.MustNewAddress("0x1111111111111111111111111111111111111111")},
+	}
+	owner := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+
+	grtBalance := func() *big.Int {
+		value, err := env.GetGRTBalance(owner)
+		require.NoError(t, err)
+		return value
+	}
+	count := func(method string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls[method]
+	}
+
+	// Disabled by default, every read hits the chain
+	grtBalance()
+	grtBalance()
+	assert.Equal(t, 2, count("eth_call"))
+
+	env.SetReadCache(true)
+	assert.Equal(t, big.NewInt(100), grtBalance())
+	assert.Equal(t, big.NewInt(100), grtBalance())
+	assert.Equal(t, 3, count("eth_call"))
+	assert.Equal(t, ReadCacheStats{Hits: 1, Misses: 1}, env.ReadCacheStats())
+
+	_, err := env.GetETHBalance(owner)
+	require.NoError(t, err)
+	ethBalance, err := env.GetETHBalance(owner)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(100), ethBalance)
+	assert.Equal(t, 1, count("eth_getBalance"))
+
+	// A write by another process is only seen after a refresh
+	mu.Lock()
+	balance = 250
+	mu.Unlock()
+	assert.Equal(t, big.NewInt(100), grtBalance())
+	env.RefreshReadCache()
+	assert.Equal(t, big.NewInt(250), grtBalance())
+	assert.Equal(t, 4, count("eth_call"))
+
+	// Mining a block invalidates the cached reads
+	require.NoError(t, env.Mine(1))
+	grtBalance()
+	assert.Equal(t, 5, count("eth_call"))
+}
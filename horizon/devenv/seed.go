@@ -0,0 +1,288 @@
+package devenv
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+)
+
+// ErrUnknownSeedScenario is returned by Seed for a scenario not in SeedScenarios
+var ErrUnknownSeedScenario = errors.New("unknown seed scenario")
+
+// SeedStep is an on-chain setup step applied by a seed scenario
+type SeedStep string
+
+const (
+	// SeedEscrowDeposit mints GRT to the payer and deposits it in escrow for
+	// the service provider
+	SeedEscrowDeposit SeedStep = "escrow-deposit"
+	// SeedProvision provisions the service provider to the data service
+	SeedProvision SeedStep = "provision"
+	// SeedRegistration registers the service provider with the data service,
+	// skipped when already registered
+	SeedRegistration SeedStep = "registration"
+	// SeedSignerAuthorization authorizes a signer for the payer, skipped when
+	// already authorized
+	SeedSignerAuthorization SeedStep = "signer-authorization"
+)
+
+// SeedScenario is a named sequence of setup steps applied to a running
+// environment, so demos can be reset without restarting the chain
+type SeedScenario struct {
+	Name        string
+	Description string
+	Steps       []SeedStep
+}
+
+// SeedScenarios lists the scenarios Seed applies
+var SeedScenarios = []*SeedScenario{
+	{
+		Name:        "funded-session",
+		Description: "everything a paid session needs: escrow deposit, provision, registration and signer authorization",
+		Steps:       []SeedStep{SeedEscrowDeposit, SeedProvision, SeedRegistration, SeedSignerAuthorization},
+	},
+	{
+		Name:        "escrow",
+		Description: "tops up the escrow of the payer for the service provider",
+		Steps:       []SeedStep{SeedEscrowDeposit},
+	},
+	{
+		Name:        "provider",
+		Description: "provisions and registers the service provider",
+		Steps:       []SeedStep{SeedProvision, SeedRegistration},
+	},
+	{
+		Name:        "signer",
+		Description: "authorizes a signer for the payer",
+		Steps:       []SeedStep{SeedSignerAuthorization},
+	},
+}
+
+// LookupSeedScenario returns the scenario named name, nil when unknown
+func LookupSeedScenario(name string) *SeedScenario {
+	for _, scenario := range SeedScenarios {
+		if scenario.Name == name {
+			return scenario
+		}
+	}
+	return nil
+}
+
+// SeedConfig parameterizes the steps of a seed scenario, zero fields use the
+// environment defaults
+type SeedConfig struct {
+	// Payer deposits in escrow and authorizes the signer, env.Payer when zero
+	Payer Account
+	// ServiceProvider is provisioned and registered, env.ServiceProvider when zero
+	ServiceProvider Account
+	// SignerKey is the signer authorized for the payer, a random one when nil
+	SignerKey *eth.PrivateKey
+	// EscrowAmount is deposited in escrow (default: 10,000 GRT)
+	EscrowAmount *big.Int
+	// ProvisionAmount is provisioned to the data service (default: 1,000 GRT)
+	ProvisionAmount *big.Int
+}
+
+// SeedResult reports what Seed applied
+type SeedResult struct {
+	Scenario *SeedScenario
+	Payer    eth.Address
+	// ServiceProvider is the seeded service provider
+	ServiceProvider eth.Address
+	// Applied and Skipped list the steps sent on-chain and the steps that were
+	// already in place
+	Applied []SeedStep
+	Skipped []SeedStep
+	// SignerKey is the signer authorized for the payer, nil when the scenario
+	// does not authorize one
+	SignerKey *eth.PrivateKey
+}
+
+// Seed applies the seed scenario named scenario to the environment, e.g.
+// "funded-session", it can be applied again any number of times. The steps
+// are applied in order and stop at the first failure.
+func (env *Env) Seed(scenario string, config *SeedConfig) (*SeedResult, error) {
+	seedScenario := LookupSeedScenario(scenario)
+	if seedScenario == nil {
+		names := make([]string, len(SeedScenarios))
+		for i, known := range SeedScenarios {
+			names[i] = known.Name
+		}
+		return nil, fmt.Errorf("%w %q, expected one of %s", ErrUnknownSeedScenario, scenario, strings.Join(names, ", "))
+	}
+
+	seeded := env.seedDefaults(config)
+	result := &SeedResult{Scenario: seedScenario, Payer: seeded.Payer.Address, ServiceProvider: seeded.ServiceProvider.Address}
+	for _, step := range seedScenario.Steps {
+		applied, err := env.seedStep(step, seeded)
+		if err != nil {
+			return result, fmt.Errorf("%s: %w", step, err)
+		}
+
+		if applied {
+			result.Applied = append(result.Applied, step)
+		} else {
+			result.Skipped = append(result.Skipped, step)
+		}
+		if step == SeedSignerAuthorization {
+			result.SignerKey = seeded.SignerKey
+		}
+	}
+	return result, nil
+}
+
+func (env *Env) seedDefaults(config *SeedConfig) *SeedConfig {
+	seeded := &SeedConfig{}
+	if config != nil {
+		*seeded = *config
+	}
+
+	defaults := DefaultTestSetupConfig()
+	if seeded.Payer.PrivateKey == nil {
+		seeded.Payer = env.Payer
+	}
+	if seeded.ServiceProvider.PrivateKey == nil {
+		seeded.ServiceProvider = env.ServiceProvider
+	}
+	if seeded.EscrowAmount == nil {
+		seeded.EscrowAmount = defaults.EscrowAmount
+	}
+	if seeded.ProvisionAmount == nil {
+		seeded.ProvisionAmount = defaults.ProvisionAmount
+	}
+	return seeded
+}
+
+// seedStep applies step, reporting false when it was already in place
+func (env *Env) seedStep(step SeedStep, config *SeedConfig) (bool, error) {
+	switch step {
+	case SeedEscrowDeposit:
+		if err := env.MintGRT(config.Payer.Address, config.EscrowAmount); err != nil {
+			return false, fmt.Errorf("minting GRT: %w", err)
+		}
+		if err := env.GRT().Approve(env.ctx, config.Payer.PrivateKey, env.Escrow.Address, config.EscrowAmount); err != nil {
+			return false, fmt.Errorf("approving GRT: %w", err)
+		}
+		if err := env.depositEscrow(config.Payer, config.ServiceProvider.Address, config.EscrowAmount); err != nil {
+			return false, fmt.Errorf("depositing to escrow: %w", err)
+		}
+		return true, nil
+
+	case SeedProvision:
+		if err := env.SetProvisionTokensRange(big.NewInt(0)); err != nil {
+			return false, fmt.Errorf("setting provision tokens range: %w", err)
+		}
+		if err := env.setProvision(config.ServiceProvider.Address, config.ProvisionAmount, 0, 0); err != nil {
+			return false, fmt.Errorf("setting provision: %w", err)
+		}
+		return true, nil
+
+	case SeedRegistration:
+		registered, err := env.IsRegistered(config.ServiceProvider.Address)
+		if err != nil {
+			return false, err
+		}
+		if registered {
+			return false, nil
+		}
+		return true, env.registerServiceProvider(config.ServiceProvider)
+
+	case SeedSignerAuthorization:
+		if config.SignerKey == nil {
+			signerKey, err := eth.NewRandomPrivateKey()
+			if err != nil {
+				return false, fmt.Errorf("creating signer key: %w", err)
+			}
+			config.SignerKey = signerKey
+		}
+
+		authorized, err := env.IsAuthorized(config.Payer.Address, config.SignerKey.PublicKey().Address())
+		if err != nil {
+			return false, err
+		}
+		if authorized {
+			return false, nil
+		}
+		return true, env.authorizeSigner(config.Payer, config.SignerKey)
+	}
+
+	return false, fmt.Errorf("unknown seed step %q", step)
+}
+
+// IsRegistered checks if serviceProvider is registered with the data service
+func (env *Env) IsRegistered(serviceProvider eth.Address) (bool, error) {
+	data, err := env.DataService.CallData("isRegistered", serviceProvider)
+	if err != nil {
+		return false, fmt.Errorf("encoding isRegistered call: %w", err)
+	}
+
+	result, err := env.CallContract(env.DataService.Address, data)
+	if err != nil {
+		return false, err
+	}
+	if len(result) != 32 {
+		return false, fmt.Errorf("unexpected result length: %d", len(result))
+	}
+
+	// Result is bool (32 bytes, last byte is 0 or 1)
+	return result[31] == 1, nil
+}
+
+// depositEscrow deposits amount GRT from payer into escrow, for receiver
+// through the collector
+func (env *Env) depositEscrow(payer Account, receiver eth.Address, amount *big.Int) error {
+	data, err := env.Escrow.CallData("deposit", env.Collector.Address, receiver, amount)
+	if err != nil {
+		return err
+	}
+	return env.sendTransaction(payer.PrivateKey, &env.Escrow.Address, big.NewInt(0), data)
+}
+
+// setProvision sets the provision of serviceProvider to the data service
+func (env *Env) setProvision(serviceProvider eth.Address, tokens *big.Int, maxVerifierCut horizon.PPM, thawingPeriod uint64) error {
+	data, err := env.Staking.CallData("setProvision", serviceProvider, env.DataService.Address, tokens, uint32(maxVerifierCut), thawingPeriod)
+	if err != nil {
+		return err
+	}
+	return env.sendTransaction(env.Deployer.PrivateKey, &env.Staking.Address, big.NewInt(0), data)
+}
+
+// registerServiceProvider registers serviceProvider with the data service,
+// paying to itself
+func (env *Env) registerServiceProvider(serviceProvider Account) error {
+	// Encode the paymentsDestination as the data parameter (abi.encode(address))
+	registerData := make([]byte, 32)
+	copy(registerData[12:], serviceProvider.Address[:])
+
+	data, err := env.DataService.CallData("register", serviceProvider.Address, registerData)
+	if err != nil {
+		return err
+	}
+	return env.sendTransaction(serviceProvider.PrivateKey, &env.DataService.Address, big.NewInt(0), data)
+}
+
+// authorizeSigner authorizes signerKey to sign RAVs for payer
+func (env *Env) authorizeSigner(payer Account, signerKey *eth.PrivateKey) error {
+	signerAddr := signerKey.PublicKey().Address()
+
+	// Generate proof with deadline 1 hour in the future
+	proofDeadline := uint64(time.Now().Add(1 * time.Hour).Unix())
+
+	proof, err := GenerateSignerProof(env.ChainID, env.Collector.Address, proofDeadline, payer.Address, signerKey)
+	if err != nil {
+		return fmt.Errorf("generating signer proof: %w", err)
+	}
+
+	// Encode call: authorizeSigner(address signer, uint256 proofDeadline, bytes proof)
+	data, err := env.Collector.CallData("authorizeSigner", signerAddr, new(big.Int).SetUint64(proofDeadline), proof)
+	if err != nil {
+		return fmt.Errorf("encoding authorizeSigner call: %w", err)
+	}
+
+	return env.sendTransaction(payer.PrivateKey, &env.Collector.Address, big.NewInt(0), data)
+}
@@ -0,0 +1,22 @@
+package devenv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupSeedScenario(t *testing.T) {
+	scenario := LookupSeedScenario("funded-session")
+	require.NotNil(t, scenario)
+	assert.Equal(t, []SeedStep{SeedEscrowDeposit, SeedProvision, SeedRegistration, SeedSignerAuthorization}, scenario.Steps)
+
+	assert.Nil(t, LookupSeedScenario("unknown"))
+}
+
+func TestSeed_UnknownScenario(t *testing.T) {
+	_, err := (&Env{}).Seed("unknown", nil)
+	assert.ErrorIs(t, err, ErrUnknownSeedScenario)
+	assert.Contains(t, err.Error(), "funded-session")
+}
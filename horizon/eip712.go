@@ -2,6 +2,8 @@ package horizon
 
 import (
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/streamingfast/eth-go"
@@ -15,10 +17,10 @@ type EIP712Encodable interface {
 
 // Domain represents an EIP-712 domain separator for V2 (Horizon)
 type Domain struct {
-	Name              string
-	Version           string
-	ChainID           *big.Int
-	VerifyingContract eth.Address
+	Name              string      `json:"name"`
+	Version           string      `json:"version"`
+	ChainID           *big.Int    `json:"chainId"`
+	VerifyingContract eth.Address `json:"verifyingContract"`
 }
 
 // EIP712 type hashes (pre-computed)
@@ -33,11 +35,24 @@ var (
 		"ReceiptAggregateVoucher(bytes32 collectionId,address payer,address serviceProvider,address dataService,uint64 timestampNs,uint128 valueAggregate,bytes metadata)"))
 )
 
-// NewDomain creates a V2 Horizon EIP-712 domain
+// EIP-712 domain name and version of the GraphTallyCollector contract
+const (
+	DefaultDomainName    = "GraphTallyCollector"
+	DefaultDomainVersion = "1"
+)
+
+// NewDomain creates a V2 Horizon EIP-712 domain with the GraphTallyCollector
+// default name and version
 func NewDomain(chainID uint64, verifyingContract eth.Address) *Domain {
+	return NewDomainWithNameVersion(DefaultDomainName, DefaultDomainVersion, chainID, verifyingContract)
+}
+
+// NewDomainWithNameVersion creates a V2 Horizon EIP-712 domain for collector
+// deployments using another name or version than the defaults
+func NewDomainWithNameVersion(name, version string, chainID uint64, verifyingContract eth.Address) *Domain {
 	return &Domain{
-		Name:              "GraphTallyCollector",
-		Version:           "1",
+		Name:              name,
+		Version:           version,
 		ChainID:           big.NewInt(int64(chainID)),
 		VerifyingContract: verifyingContract,
 	}
@@ -63,13 +78,13 @@ func (r *Receipt) EIP712TypeHash() eth.Hash {
 // EIP712EncodeData returns the ABI-encoded data for Receipt
 func (r *Receipt) EIP712EncodeData() []byte {
 	encoded := make([]byte, 0, 32*7)
-	encoded = append(encoded, r.CollectionID[:]...)                 // bytes32
-	encoded = append(encoded, padLeft(r.Payer[:], 32)...)           // address
-	encoded = append(encoded, padLeft(r.DataService[:], 32)...)     // address
-	encoded = append(encoded, padLeft(r.ServiceProvider[:], 32)...) // address
-	encoded = append(encoded, encodeUint64(r.TimestampNs)...)       // uint64
-	encoded = append(encoded, encodeUint64(r.Nonce)...)             // uint64
-	encoded = append(encoded, encodeUint128(r.Value)...)            // uint128
+	encoded = append(encoded, r.CollectionID[:]...)                // bytes32
+	encoded = append(encoded, EncodeAddress(r.Payer)...)           // address
+	encoded = append(encoded, EncodeAddress(r.DataService)...)     // address
+	encoded = append(encoded, EncodeAddress(r.ServiceProvider)...) // address
+	encoded = append(encoded, EncodeUint64(r.TimestampNs)...)      // uint64
+	encoded = append(encoded, EncodeUint64(r.Nonce)...)            // uint64
+	encoded = append(encoded, EncodeUint128(r.Value)...)           // uint128
 	return encoded
 }
 
@@ -81,21 +96,26 @@ func (r *RAV) EIP712TypeHash() eth.Hash {
 // EIP712EncodeData returns the ABI-encoded data for RAV
 func (r *RAV) EIP712EncodeData() []byte {
 	encoded := make([]byte, 0, 32*7)
-	encoded = append(encoded, r.CollectionID[:]...)                 // bytes32
-	encoded = append(encoded, padLeft(r.Payer[:], 32)...)           // address
-	encoded = append(encoded, padLeft(r.ServiceProvider[:], 32)...) // address
-	encoded = append(encoded, padLeft(r.DataService[:], 32)...)     // address
-	encoded = append(encoded, encodeUint64(r.TimestampNs)...)       // uint64
-	encoded = append(encoded, encodeUint128(r.ValueAggregate)...)   // uint128
-	encoded = append(encoded, keccak256(r.Metadata)[:]...)          // keccak256(bytes)
+	encoded = append(encoded, r.CollectionID[:]...)                // bytes32
+	encoded = append(encoded, EncodeAddress(r.Payer)...)           // address
+	encoded = append(encoded, EncodeAddress(r.ServiceProvider)...) // address
+	encoded = append(encoded, EncodeAddress(r.DataService)...)     // address
+	encoded = append(encoded, EncodeUint64(r.TimestampNs)...)      // uint64
+	encoded = append(encoded, EncodeUint128(r.ValueAggregate)...)  // uint128
+	encoded = append(encoded, keccak256(r.Metadata)[:]...)         // keccak256(bytes)
 	return encoded
 }
 
 // HashTypedData computes the EIP-712 hash for signing
 // Returns: keccak256("\x19\x01" || domainSeparator || structHash)
 func HashTypedData[T EIP712Encodable](domain *Domain, message T) (eth.Hash, error) {
+	return hashTypedData(domain.Separator(), message), nil
+}
+
+// hashTypedData computes the EIP-712 hash of message under an already
+// computed domain separator
+func hashTypedData[T EIP712Encodable](domainSep eth.Hash, message T) eth.Hash {
 	structHash := hashStruct(message)
-	domainSep := domain.Separator()
 
 	// EIP-712: "\x19\x01" || domainSeparator || structHash
 	data := make([]byte, 0, 2+32+32)
@@ -103,7 +123,7 @@ func HashTypedData[T EIP712Encodable](domain *Domain, message T) (eth.Hash, erro
 	data = append(data, domainSep[:]...)
 	data = append(data, structHash[:]...)
 
-	return keccak256(data), nil
+	return keccak256(data)
 }
 
 // hashStruct computes keccak256(typeHash || encodeData)
@@ -133,17 +153,41 @@ func padLeft(b []byte, size int) []byte {
 	return result
 }
 
-func encodeUint64(v uint64) []byte {
+// ErrNotUint128 is returned by CheckUint128 for values out of the uint128 range
+var ErrNotUint128 = errors.New("value is not a uint128")
+
+// CheckUint128 returns ErrNotUint128 when v is nil, negative or above
+// MaxUint128. Values must pass it before being encoded with EncodeUint128.
+func CheckUint128(v *big.Int) error {
+	switch {
+	case v == nil:
+		return fmt.Errorf("%w: missing", ErrNotUint128)
+	case v.Sign() < 0, v.Cmp(MaxUint128) > 0:
+		return fmt.Errorf("%w: %s", ErrNotUint128, v)
+	}
+	return nil
+}
+
+// EncodeAddress ABI-encodes addr as a 32 bytes word, for the EIP712EncodeData
+// of messages of other packages
+func EncodeAddress(addr eth.Address) []byte {
+	return padLeft(addr[:], 32)
+}
+
+// EncodeUint64 ABI-encodes v as a 32 bytes word
+func EncodeUint64(v uint64) []byte {
 	result := make([]byte, 32)
 	binary.BigEndian.PutUint64(result[24:], v)
 	return result
 }
 
-func encodeUint128(v *big.Int) []byte {
-	result := make([]byte, 32)
-	if v != nil {
-		b := v.Bytes()
-		copy(result[32-len(b):], b)
+// EncodeUint128 ABI-encodes v as a 32 bytes word, v being checked with
+// CheckUint128 beforehand. Values out of range do not panic but are encoded
+// wrongly (nil as zero, the absolute value of negative ones, the low 256 bits
+// of larger ones) so their signatures never verify.
+func EncodeUint128(v *big.Int) []byte {
+	if v == nil {
+		return make([]byte, 32)
 	}
-	return result
+	return padLeft(v.Bytes(), 32)
 }
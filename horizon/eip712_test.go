@@ -30,6 +30,30 @@ func TestDomain_Separator(t *testing.T) {
 	require.Equal(t, 32, len(separator))
 }
 
+func TestDomain_NameVersion(t *testing.T) {
+	verifyingContract := eth.MustNewAddress("0x1234567890123456789012345678901234567890")
+	domain := NewDomainWithNameVersion("GraphTallyCollector", "2", 1, verifyingContract)
+
+	require.Equal(t, "GraphTallyCollector", domain.Name)
+	require.Equal(t, "2", domain.Version)
+	require.NotEqual(t, NewDomain(1, verifyingContract).Separator(), domain.Separator())
+	require.Equal(t, NewDomain(1, verifyingContract).Separator(), NewDomainWithNameVersion(DefaultDomainName, DefaultDomainVersion, 1, verifyingContract).Separator())
+
+	// A RAV signed under one version does not recover to its signer under another
+	key, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	signedRAV, err := Sign(domain, &RAV{Payer: verifyingContract, ValueAggregate: big.NewInt(1)}, key)
+	require.NoError(t, err)
+
+	signer, err := signedRAV.RecoverSigner(domain)
+	require.NoError(t, err)
+	require.Equal(t, key.PublicKey().Address(), signer)
+
+	signer, err = signedRAV.RecoverSigner(NewDomain(1, verifyingContract))
+	require.NoError(t, err)
+	require.NotEqual(t, key.PublicKey().Address(), signer)
+}
+
 func TestReceipt_EIP712Encoding(t *testing.T) {
 	var collectionID CollectionID
 	copy(collectionID[:], eth.MustNewHash("0xabababababababababababababababababababababababababababababababab")[:])
@@ -168,19 +192,19 @@ func TestEncoding_Helpers(t *testing.T) {
 		require.Equal(t, []byte{2, 3, 4, 5, 6}, padded2) // Takes last 5
 	})
 
-	// Test encodeUint64
-	t.Run("encodeUint64", func(t *testing.T) {
-		encoded := encodeUint64(0x123456789ABCDEF0)
+	// Test EncodeUint64
+	t.Run("EncodeUint64", func(t *testing.T) {
+		encoded := EncodeUint64(0x123456789ABCDEF0)
 		require.Equal(t, 32, len(encoded))
 		// Check last 8 bytes contain the value
 		require.Equal(t, byte(0x12), encoded[24])
 		require.Equal(t, byte(0xF0), encoded[31])
 	})
 
-	// Test encodeUint128
-	t.Run("encodeUint128", func(t *testing.T) {
+	// Test EncodeUint128
+	t.Run("EncodeUint128", func(t *testing.T) {
 		value := big.NewInt(12345)
-		encoded := encodeUint128(value)
+		encoded := EncodeUint128(value)
 		require.Equal(t, 32, len(encoded))
 
 		// Decode and verify
@@ -188,13 +212,26 @@ func TestEncoding_Helpers(t *testing.T) {
 		require.Equal(t, 0, value.Cmp(decoded))
 	})
 
-	// Test encodeUint128 with nil
-	t.Run("encodeUint128_nil", func(t *testing.T) {
-		encoded := encodeUint128(nil)
+	// Test EncodeUint128 with nil
+	t.Run("EncodeUint128_nil", func(t *testing.T) {
+		encoded := EncodeUint128(nil)
 		require.Equal(t, 32, len(encoded))
 		// Should be all zeros
 		for _, b := range encoded {
 			require.Equal(t, byte(0), b)
 		}
 	})
+
+	// Values out of range are rejected by CheckUint128, encoding them does not
+	// panic
+	t.Run("CheckUint128", func(t *testing.T) {
+		require.NoError(t, CheckUint128(big.NewInt(0)))
+		require.NoError(t, CheckUint128(MaxUint128))
+		require.ErrorIs(t, CheckUint128(nil), ErrNotUint128)
+		require.ErrorIs(t, CheckUint128(big.NewInt(-1)), ErrNotUint128)
+
+		huge := new(big.Int).Lsh(big.NewInt(1), 300)
+		require.ErrorIs(t, CheckUint128(huge), ErrNotUint128)
+		require.Equal(t, 32, len(EncodeUint128(huge)))
+	})
 }
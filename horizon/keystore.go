@@ -0,0 +1,245 @@
+package horizon
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/streamingfast/eth-go"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Scrypt parameters of the keystores created by EncryptKeystore. The standard
+// ones are those of geth, the light ones trade brute force resistance for a
+// faster decryption, e.g. for test keys.
+const (
+	StandardScryptN = 1 << 18
+	StandardScryptP = 1
+	LightScryptN    = 1 << 12
+	LightScryptP    = 6
+)
+
+const (
+	keystoreVersion = 3
+	keystoreCipher  = "aes-128-ctr"
+	keystoreScryptR = 8
+	keystoreDKLen   = 32
+)
+
+// ErrKeystorePassphrase is returned when a keystore does not decrypt with the
+// passphrase given
+var ErrKeystorePassphrase = errors.New("could not decrypt keystore with the passphrase given")
+
+// keystoreFile is a Web3 Secret Storage (version 3) key file, the encrypted
+// key files of geth and most Ethereum wallets
+type keystoreFile struct {
+	Address string         `json:"address,omitempty"`
+	Crypto  keystoreCrypto `json:"crypto"`
+	ID      string         `json:"id"`
+	Version int            `json:"version"`
+}
+
+type keystoreCrypto struct {
+	Cipher       string `json:"cipher"`
+	CipherText   string `json:"ciphertext"`
+	CipherParams struct {
+		IV string `json:"iv"`
+	} `json:"cipherparams"`
+	KDF       string            `json:"kdf"`
+	KDFParams keystoreKDFParams `json:"kdfparams"`
+	MAC       string            `json:"mac"`
+}
+
+// keystoreKDFParams are the parameters of the scrypt (n, r, p) or pbkdf2 (c,
+// prf) key derivation
+type keystoreKDFParams struct {
+	DKLen int    `json:"dklen"`
+	N     int    `json:"n,omitempty"`
+	P     int    `json:"p,omitempty"`
+	R     int    `json:"r,omitempty"`
+	C     int    `json:"c,omitempty"`
+	PRF   string `json:"prf,omitempty"`
+	Salt  string `json:"salt"`
+}
+
+// EncryptKeystore encrypts key with passphrase into a Web3 Secret Storage
+// (geth keystore) JSON file, deriving the encryption key with scrypt of
+// scryptN and scryptP, see StandardScryptN and LightScryptN
+func EncryptKeystore(key *eth.PrivateKey, passphrase string, scryptN, scryptP int) ([]byte, error) {
+	salt, err := randomBytes(32)
+	if err != nil {
+		return nil, err
+	}
+	derivedKey, err := scrypt.Key([]byte(passphrase), salt, scryptN, keystoreScryptR, scryptP, keystoreDKLen)
+	if err != nil {
+		return nil, fmt.Errorf("deriving keystore key: %w", err)
+	}
+
+	iv, err := randomBytes(aes.BlockSize)
+	if err != nil {
+		return nil, err
+	}
+	cipherText, err := aesCTR(derivedKey[:16], iv, key.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := randomBytes(16)
+	if err != nil {
+		return nil, err
+	}
+	id[6] = id[6]&0x0f | 0x40 // UUID version 4
+	id[8] = id[8]&0x3f | 0x80 // UUID variant
+
+	file := &keystoreFile{
+		Address: strings.TrimPrefix(key.PublicKey().Address().Pretty(), "0x"),
+		ID:      fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:]),
+		Version: keystoreVersion,
+	}
+	file.Crypto = keystoreCrypto{
+		Cipher:     keystoreCipher,
+		CipherText: hex.EncodeToString(cipherText),
+		KDF:        "scrypt",
+		KDFParams: keystoreKDFParams{
+			DKLen: keystoreDKLen,
+			N:     scryptN,
+			P:     scryptP,
+			R:     keystoreScryptR,
+			Salt:  hex.EncodeToString(salt),
+		},
+		MAC: hex.EncodeToString(eth.Keccak256(derivedKey[16:32], cipherText)),
+	}
+	file.Crypto.CipherParams.IV = hex.EncodeToString(iv)
+
+	return json.MarshalIndent(file, "", "  ")
+}
+
+// DecryptKeystore decrypts the key of a Web3 Secret Storage (geth keystore)
+// JSON file with passphrase, returning ErrKeystorePassphrase when it does not
+// match. Keys derived with scrypt and pbkdf2 are supported.
+func DecryptKeystore(keystoreJSON []byte, passphrase string) (*eth.PrivateKey, error) {
+	var file keystoreFile
+	if err := json.Unmarshal(keystoreJSON, &file); err != nil {
+		return nil, fmt.Errorf("decoding keystore: %w", err)
+	}
+	if file.Version != keystoreVersion {
+		return nil, fmt.Errorf("unsupported keystore version %d, expected %d", file.Version, keystoreVersion)
+	}
+	if file.Crypto.Cipher != keystoreCipher {
+		return nil, fmt.Errorf("unsupported keystore cipher %q, expected %q", file.Crypto.Cipher, keystoreCipher)
+	}
+
+	derivedKey, err := file.Crypto.deriveKey(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	cipherText, err := hex.DecodeString(file.Crypto.CipherText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore ciphertext: %w", err)
+	}
+	mac, err := hex.DecodeString(file.Crypto.MAC)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore mac: %w", err)
+	}
+	if !bytes.Equal(eth.Keccak256(derivedKey[16:32], cipherText), mac) {
+		return nil, ErrKeystorePassphrase
+	}
+
+	iv, err := hex.DecodeString(file.Crypto.CipherParams.IV)
+	if err != nil || len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("invalid keystore iv %q", file.Crypto.CipherParams.IV)
+	}
+	keyBytes, err := aesCTR(derivedKey[:16], iv, cipherText)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := eth.NewPrivateKey(hex.EncodeToString(keyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore key: %w", err)
+	}
+
+	if file.Address != "" {
+		expected, err := eth.NewAddress(file.Address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid keystore address %q: %w", file.Address, err)
+		}
+		if address := key.PublicKey().Address(); !bytes.Equal(address, expected) {
+			return nil, fmt.Errorf("keystore key is for %s, not the keystore address %s", address.Pretty(), expected.Pretty())
+		}
+	}
+	return key, nil
+}
+
+// LoadKeystore reads and decrypts the keystore file at path, see
+// DecryptKeystore
+func LoadKeystore(path string, passphrase string) (*eth.PrivateKey, error) {
+	keystoreJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading keystore: %w", err)
+	}
+	return DecryptKeystore(keystoreJSON, passphrase)
+}
+
+func (c *keystoreCrypto) deriveKey(passphrase string) ([]byte, error) {
+	params := c.KDFParams
+	if params.DKLen < 32 {
+		return nil, fmt.Errorf("keystore derived key length must be at least 32 bytes, got %d", params.DKLen)
+	}
+	salt, err := hex.DecodeString(params.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore salt: %w", err)
+	}
+
+	switch c.KDF {
+	case "scrypt":
+		derivedKey, err := scrypt.Key([]byte(passphrase), salt, params.N, params.R, params.P, params.DKLen)
+		if err != nil {
+			return nil, fmt.Errorf("deriving keystore key: %w", err)
+		}
+		return derivedKey, nil
+	case "pbkdf2":
+		if params.PRF != "hmac-sha256" {
+			return nil, fmt.Errorf("unsupported keystore pbkdf2 prf %q, expected \"hmac-sha256\"", params.PRF)
+		}
+		if params.C <= 0 {
+			return nil, fmt.Errorf("invalid keystore pbkdf2 iteration count %d", params.C)
+		}
+		derivedKey, err := pbkdf2.Key(sha256.New, passphrase, salt, params.C, params.DKLen)
+		if err != nil {
+			return nil, fmt.Errorf("deriving keystore key: %w", err)
+		}
+		return derivedKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported keystore kdf %q, expected \"scrypt\" or \"pbkdf2\"", c.KDF)
+	}
+}
+
+func aesCTR(key, iv, in []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("creating keystore cipher: %w", err)
+	}
+
+	out := make([]byte, len(in))
+	cipher.NewCTR(block, iv).XORKeyStream(out, in)
+	return out, nil
+}
+
+func randomBytes(n int) ([]byte, error) {
+	out := make([]byte, n)
+	if _, err := rand.Read(out); err != nil {
+		return nil, fmt.Errorf("reading random bytes: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,88 @@
+package horizon
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeystore_RoundTrip(t *testing.T) {
+	key, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	keystoreJSON, err := EncryptKeystore(key, "correct horse", LightScryptN, LightScryptP)
+	require.NoError(t, err)
+
+	var file map[string]any
+	require.NoError(t, json.Unmarshal(keystoreJSON, &file))
+	assert.Equal(t, float64(3), file["version"])
+	assert.NotContains(t, string(keystoreJSON), key.String())
+
+	path := filepath.Join(t.TempDir(), "signer.json")
+	require.NoError(t, os.WriteFile(path, keystoreJSON, 0o600))
+
+	decrypted, err := LoadKeystore(path, "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, key.String(), decrypted.String())
+
+	_, err = DecryptKeystore(keystoreJSON, "wrong horse")
+	assert.ErrorIs(t, err, ErrKeystorePassphrase)
+}
+
+func TestDecryptKeystore_PBKDF2(t *testing.T) {
+	// Test vector of the Web3 Secret Storage definition
+	keystoreJSON := []byte(`{
+		"crypto": {
+			"cipher": "aes-128-ctr",
+			"cipherparams": {"iv": "6087dab2f9fdbbfaddc31a909735c1e6"},
+			"ciphertext": "5318b4d5bcd28de64ee5559e671353e16f075ecae9f99c7a79a38af5f869aa46",
+			"kdf": "pbkdf2",
+			"kdfparams": {
+				"c": 262144,
+				"dklen": 32,
+				"prf": "hmac-sha256",
+				"salt": "ae3cd4e7013836a3df6bd7241b12db061dbe2c6785853cce422d148a624ce0bd"
+			},
+			"mac": "517ead924a9d0dc3124507e3393d175ce3ff7c1e96529c6c555ce9e51205e9b2"
+		},
+		"id": "3198bc9c-6672-5ab3-d995-4942343ae5b6",
+		"version": 3
+	}`)
+
+	key, err := DecryptKeystore(keystoreJSON, "testpassword")
+	require.NoError(t, err)
+	assert.Equal(t, "7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d", key.String())
+}
+
+func TestDecryptKeystore_Invalid(t *testing.T) {
+	key, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	other, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	keystoreJSON, err := EncryptKeystore(key, "secret", LightScryptN, LightScryptP)
+	require.NoError(t, err)
+
+	tamper := func(mutate func(file map[string]any)) []byte {
+		var file map[string]any
+		require.NoError(t, json.Unmarshal(keystoreJSON, &file))
+		mutate(file)
+		out, err := json.Marshal(file)
+		require.NoError(t, err)
+		return out
+	}
+
+	_, err = DecryptKeystore(tamper(func(file map[string]any) { file["version"] = 2 }), "secret")
+	assert.ErrorContains(t, err, "unsupported keystore version 2")
+
+	_, err = DecryptKeystore(tamper(func(file map[string]any) { file["crypto"].(map[string]any)["kdf"] = "argon2" }), "secret")
+	assert.ErrorContains(t, err, `unsupported keystore kdf "argon2"`)
+
+	_, err = DecryptKeystore(tamper(func(file map[string]any) { file["address"] = other.PublicKey().Address().Pretty() }), "secret")
+	assert.ErrorContains(t, err, "not the keystore address")
+}
@@ -0,0 +1,129 @@
+package legacy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+)
+
+// ErrAllocationMismatch is returned when receipts, or the previous RAV, are for
+// different allocations. The other aggregation errors are the ones of package
+// horizon (horizon.ErrNoReceipts, horizon.ErrDuplicateSignature...).
+var ErrAllocationMismatch = errors.New("receipts have different allocation IDs")
+
+// Aggregator validates V1 receipts and aggregates them into V1 RAVs, with the
+// rules of the TAP v1 aggregator: unique signatures from accepted signers, one
+// allocation, uint128 values, timestamps after the previous RAV and no uint128
+// overflow. It is safe for concurrent use.
+type Aggregator struct {
+	domain          *horizon.Domain
+	signer          horizon.Signer
+	acceptedSigners map[string]bool
+	options         horizon.AggregatorOptions
+}
+
+// NewAggregator creates a V1 RAV aggregator signing RAVs with signerKey, or
+// with the signer given through horizon.WithSigner, under domain (see
+// NewDomain), accepting receipts signed by acceptedSigners. Of the horizon
+// options, those of horizon.AggregatorOptions apply.
+func NewAggregator(domain *horizon.Domain, signerKey *eth.PrivateKey, acceptedSigners []eth.Address, opts ...horizon.Option) *Aggregator {
+	signerMap := make(map[string]bool, len(acceptedSigners))
+	for _, addr := range acceptedSigners {
+		signerMap[addr.Pretty()] = true
+	}
+
+	options := horizon.NewAggregatorOptions(opts...)
+	signer := options.Signer
+	if signer == nil {
+		signer = horizon.NewLocalSigner(signerKey)
+	}
+
+	return &Aggregator{
+		domain:          domain,
+		signer:          signer,
+		acceptedSigners: signerMap,
+		options:         options,
+	}
+}
+
+// AggregateReceipts validates receipts and creates a signed RAV on top of
+// previousRAV, nil for the first RAV of the allocation
+func (a *Aggregator) AggregateReceipts(receipts []*SignedReceipt, previousRAV *SignedRAV) (*SignedRAV, error) {
+	if len(receipts) == 0 {
+		return nil, horizon.ErrNoReceipts
+	}
+
+	// Values are checked first, out of range ones cannot be encoded to recover
+	// their signer
+	for i, r := range receipts {
+		if err := horizon.CheckUint128(r.Message.Value); err != nil {
+			return nil, fmt.Errorf("receipt %d: %w: %w", i, horizon.ErrInvalidReceiptValue, err)
+		}
+	}
+	if previousRAV != nil {
+		if err := horizon.CheckUint128(previousRAV.Message.ValueAggregate); err != nil {
+			return nil, fmt.Errorf("previous RAV: %w", err)
+		}
+	}
+
+	receipts, _, err := horizon.UniqueSignedMessages(a.domain, receipts, a.options.DropDuplicateReceipts)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, r := range receipts {
+		signer, err := r.RecoverSigner(a.domain)
+		if err != nil {
+			return nil, fmt.Errorf("receipt %d: recovering signer: %w", i, err)
+		}
+		if !a.acceptedSigners[signer.Pretty()] {
+			return nil, fmt.Errorf("receipt %d: %w", i, horizon.ErrInvalidSigner)
+		}
+	}
+
+	allocationID := receipts[0].Message.AllocationID
+	var timestampMax uint64
+	valueAggregate := big.NewInt(0)
+
+	if previousRAV != nil {
+		signer, err := previousRAV.RecoverSigner(a.domain)
+		if err != nil {
+			return nil, err
+		}
+		if !a.acceptedSigners[signer.Pretty()] {
+			return nil, horizon.ErrRAVSignerMismatch
+		}
+		if !bytes.Equal(previousRAV.Message.AllocationID, allocationID) {
+			return nil, ErrAllocationMismatch
+		}
+
+		timestampMax = previousRAV.Message.TimestampNs
+		valueAggregate.Set(previousRAV.Message.ValueAggregate)
+	}
+
+	for _, r := range receipts {
+		receipt := r.Message
+		if !bytes.Equal(receipt.AllocationID, allocationID) {
+			return nil, ErrAllocationMismatch
+		}
+		if previousRAV != nil && receipt.TimestampNs <= previousRAV.Message.TimestampNs {
+			return nil, horizon.ErrInvalidTimestamp
+		}
+
+		valueAggregate.Add(valueAggregate, receipt.Value)
+		if valueAggregate.Cmp(horizon.MaxUint128) > 0 {
+			return nil, horizon.ErrAggregateOverflow
+		}
+		timestampMax = max(timestampMax, receipt.TimestampNs)
+	}
+
+	return horizon.SignWith(a.domain, &RAV{
+		AllocationID:   allocationID,
+		TimestampNs:    timestampMax,
+		ValueAggregate: valueAggregate,
+	}, a.signer)
+}
@@ -0,0 +1,96 @@
+package legacy
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregator_AggregateReceipts(t *testing.T) {
+	payerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	aggregatorKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	outsiderKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	domain := NewDomain(1337, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+	aggregator := NewAggregator(domain, aggregatorKey, []eth.Address{
+		payerKey.PublicKey().Address(),
+		aggregatorKey.PublicKey().Address(),
+	})
+
+	allocationID := eth.MustNewAddress("0xabababababababababababababababababababab")
+	newReceipt := func(allocationID eth.Address, timestampNs uint64, value int64, key *eth.PrivateKey) *SignedReceipt {
+		receipt := NewReceipt(allocationID, big.NewInt(value))
+		receipt.TimestampNs = timestampNs
+		signed, err := horizon.Sign(domain, receipt, key)
+		require.NoError(t, err)
+		return signed
+	}
+
+	rav, err := aggregator.AggregateReceipts([]*SignedReceipt{
+		newReceipt(allocationID, 10, 100, payerKey),
+		newReceipt(allocationID, 30, 200, payerKey),
+		newReceipt(allocationID, 20, 300, payerKey),
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, allocationID.Pretty(), rav.Message.AllocationID.Pretty())
+	assert.Equal(t, uint64(30), rav.Message.TimestampNs)
+	assert.Equal(t, "600", rav.Message.ValueAggregate.String())
+
+	signer, err := rav.RecoverSigner(domain)
+	require.NoError(t, err)
+	assert.Equal(t, aggregatorKey.PublicKey().Address().Pretty(), signer.Pretty())
+
+	next, err := aggregator.AggregateReceipts([]*SignedReceipt{newReceipt(allocationID, 40, 50, payerKey)}, rav)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(40), next.Message.TimestampNs)
+	assert.Equal(t, "650", next.Message.ValueAggregate.String())
+
+	duplicate := newReceipt(allocationID, 50, 10, payerKey)
+	otherAllocation := eth.MustNewAddress("0xcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcd")
+	overflow := newReceipt(allocationID, 60, 0, payerKey)
+	overflow.Message.Value = horizon.MaxUint128
+	overflow, err = horizon.Sign(domain, overflow.Message, payerKey)
+	require.NoError(t, err)
+
+	invalidValue := newReceipt(allocationID, 60, 0, payerKey)
+	invalidValue.Message.Value = new(big.Int).Lsh(big.NewInt(1), 300)
+
+	for name, tc := range map[string]struct {
+		receipts []*SignedReceipt
+		previous *SignedRAV
+		err      error
+	}{
+		"no receipts":         {nil, nil, horizon.ErrNoReceipts},
+		"duplicate signature": {[]*SignedReceipt{duplicate, duplicate}, nil, horizon.ErrDuplicateSignature},
+		"unauthorized signer": {[]*SignedReceipt{newReceipt(allocationID, 50, 10, outsiderKey)}, nil, horizon.ErrInvalidSigner},
+		"allocation mismatch": {[]*SignedReceipt{duplicate, newReceipt(otherAllocation, 50, 10, payerKey)}, nil, ErrAllocationMismatch},
+		"previous allocation": {[]*SignedReceipt{newReceipt(otherAllocation, 50, 10, payerKey)}, rav, ErrAllocationMismatch},
+		"stale timestamp":     {[]*SignedReceipt{newReceipt(allocationID, 30, 10, payerKey)}, rav, horizon.ErrInvalidTimestamp},
+		"overflow":            {[]*SignedReceipt{overflow}, rav, horizon.ErrAggregateOverflow},
+		"invalid value":       {[]*SignedReceipt{invalidValue}, nil, horizon.ErrInvalidReceiptValue},
+		"missing value":       {[]*SignedReceipt{{Message: &Receipt{AllocationID: allocationID}}}, nil, horizon.ErrNotUint128},
+	} {
+		_, err := aggregator.AggregateReceipts(tc.receipts, tc.previous)
+		assert.ErrorIs(t, err, tc.err, name)
+	}
+
+	// Exact duplicates are dropped with the horizon duplicate receipt tolerance
+	tolerant := NewAggregator(domain, nil, []eth.Address{payerKey.PublicKey().Address()},
+		horizon.WithSigner(horizon.NewLocalSigner(aggregatorKey)),
+		horizon.WithDuplicateReceiptTolerance(),
+	)
+	deduplicated, err := tolerant.AggregateReceipts([]*SignedReceipt{duplicate, duplicate}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "10", deduplicated.Message.ValueAggregate.String())
+
+	signer, err = deduplicated.RecoverSigner(domain)
+	require.NoError(t, err)
+	assert.Equal(t, aggregatorKey.PublicKey().Address().Pretty(), signer.Pretty())
+}
@@ -0,0 +1,101 @@
+package legacy
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+)
+
+// ErrNotAllocationCollection is returned when converting a Horizon message
+// whose collection ID is not derived from an allocation ID
+var ErrNotAllocationCollection = errors.New("collection ID is not derived from an allocation ID")
+
+// CollectionIDFromAllocationID returns the Horizon collection ID of the
+// allocation allocationID, the allocation ID left-padded to 32 bytes as the
+// SubgraphService derives it
+func CollectionIDFromAllocationID(allocationID eth.Address) horizon.CollectionID {
+	var id horizon.CollectionID
+	copy(id[len(id)-len(allocationID):], allocationID)
+	return id
+}
+
+// AllocationIDFromCollectionID returns the allocation ID collectionID is
+// derived from, see CollectionIDFromAllocationID
+func AllocationIDFromCollectionID(collectionID horizon.CollectionID) (eth.Address, error) {
+	prefix := len(collectionID) - 20
+	for _, b := range collectionID[:prefix] {
+		if b != 0 {
+			return nil, fmt.Errorf("%w: %s", ErrNotAllocationCollection, eth.Hash(collectionID[:]).Pretty())
+		}
+	}
+	return eth.Address(append([]byte(nil), collectionID[prefix:]...)), nil
+}
+
+// ToHorizon converts r to a Horizon receipt for parties, which V1 receipts do
+// not carry
+func (r *Receipt) ToHorizon(parties horizon.RAVParties) *horizon.Receipt {
+	return &horizon.Receipt{
+		CollectionID:    CollectionIDFromAllocationID(r.AllocationID),
+		Payer:           parties.Payer,
+		DataService:     parties.DataService,
+		ServiceProvider: parties.ServiceProvider,
+		TimestampNs:     r.TimestampNs,
+		Nonce:           r.Nonce,
+		Value:           copyValue(r.Value),
+	}
+}
+
+// ReceiptFromHorizon converts a Horizon receipt of an allocation-derived
+// collection to a V1 receipt, dropping its parties
+func ReceiptFromHorizon(r *horizon.Receipt) (*Receipt, error) {
+	allocationID, err := AllocationIDFromCollectionID(r.CollectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Receipt{
+		AllocationID: allocationID,
+		TimestampNs:  r.TimestampNs,
+		Nonce:        r.Nonce,
+		Value:        copyValue(r.Value),
+	}, nil
+}
+
+// ToHorizon converts r to a Horizon RAV for parties, which V1 RAVs do not
+// carry, with empty metadata
+func (r *RAV) ToHorizon(parties horizon.RAVParties) *horizon.RAV {
+	return &horizon.RAV{
+		CollectionID:    CollectionIDFromAllocationID(r.AllocationID),
+		Payer:           parties.Payer,
+		ServiceProvider: parties.ServiceProvider,
+		DataService:     parties.DataService,
+		TimestampNs:     r.TimestampNs,
+		ValueAggregate:  copyValue(r.ValueAggregate),
+		Metadata:        []byte{},
+	}
+}
+
+// RAVFromHorizon converts a Horizon RAV of an allocation-derived collection to
+// a V1 RAV, dropping its parties and metadata
+func RAVFromHorizon(r *horizon.RAV) (*RAV, error) {
+	allocationID, err := AllocationIDFromCollectionID(r.CollectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RAV{
+		AllocationID:   allocationID,
+		TimestampNs:    r.TimestampNs,
+		ValueAggregate: copyValue(r.ValueAggregate),
+	}, nil
+}
+
+func copyValue(v *big.Int) *big.Int {
+	if v == nil {
+		return nil
+	}
+	return new(big.Int).Set(v)
+}
@@ -0,0 +1,54 @@
+package legacy
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConversions(t *testing.T) {
+	allocationID := eth.MustNewAddress("0xabababababababababababababababababababab")
+	collectionID := CollectionIDFromAllocationID(allocationID)
+	assert.Equal(t, "0x000000000000000000000000abababababababababababababababababababab", eth.Hash(collectionID[:]).Pretty())
+
+	decoded, err := AllocationIDFromCollectionID(collectionID)
+	require.NoError(t, err)
+	assert.Equal(t, allocationID.Pretty(), decoded.Pretty())
+
+	_, err = AllocationIDFromCollectionID(horizon.CollectionID{0x01})
+	assert.ErrorIs(t, err, ErrNotAllocationCollection)
+
+	parties := horizon.RAVParties{
+		Payer:           eth.MustNewAddress("0x1111111111111111111111111111111111111111"),
+		ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+	}
+
+	receipt := &Receipt{AllocationID: allocationID, TimestampNs: 42, Nonce: 7, Value: big.NewInt(100)}
+	horizonReceipt := receipt.ToHorizon(parties)
+	assert.Equal(t, collectionID, horizonReceipt.CollectionID)
+	assert.Equal(t, parties.Payer.Pretty(), horizonReceipt.Payer.Pretty())
+	assert.Equal(t, uint64(7), horizonReceipt.Nonce)
+
+	back, err := ReceiptFromHorizon(horizonReceipt)
+	require.NoError(t, err)
+	assert.Equal(t, receipt, back)
+
+	rav := &RAV{AllocationID: allocationID, TimestampNs: 42, ValueAggregate: big.NewInt(500)}
+	horizonRAV := rav.ToHorizon(parties)
+	assert.Equal(t, collectionID, horizonRAV.CollectionID)
+	assert.Equal(t, parties.DataService.Pretty(), horizonRAV.DataService.Pretty())
+	require.NoError(t, horizon.ValidateAgainstContractSemantics(horizonRAV))
+
+	backRAV, err := RAVFromHorizon(horizonRAV)
+	require.NoError(t, err)
+	assert.Equal(t, rav, backRAV)
+
+	horizonRAV.CollectionID = horizon.CollectionID{0x01}
+	_, err = RAVFromHorizon(horizonRAV)
+	assert.ErrorIs(t, err, ErrNotAllocationCollection)
+}
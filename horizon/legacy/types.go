@@ -0,0 +1,110 @@
+// Package legacy implements the allocation-based TAP v1 receipts and RAVs,
+// signed under the TAPVerifier EIP-712 domain, next to the collection-based
+// Horizon (v2) ones of package horizon. Indexers still running TAP v1 can then
+// be served by the same sidecar while they migrate. Messages are converted
+// from one generation to the other field by field (Receipt.ToHorizon,
+// RAVFromHorizon...), signatures do not carry over: both generations sign
+// different structs under different domains, a converted message must be
+// signed again.
+package legacy
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"math/big"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+)
+
+// EIP-712 domain name and version of the TAPVerifier contract
+const (
+	DomainName    = "TAP"
+	DomainVersion = "1"
+)
+
+// NewDomain creates the TAP v1 EIP-712 domain of the TAPVerifier contract
+// deployed at verifyingContract
+func NewDomain(chainID uint64, verifyingContract eth.Address) *horizon.Domain {
+	return horizon.NewDomainWithNameVersion(DomainName, DomainVersion, chainID, verifyingContract)
+}
+
+// EIP712 type hashes (pre-computed)
+var (
+	receiptTypeHash = eth.Keccak256([]byte(
+		"Receipt(address allocation_id,uint64 timestamp_ns,uint64 nonce,uint128 value)"))
+
+	ravTypeHash = eth.Keccak256([]byte(
+		"ReceiptAggregateVoucher(address allocationId,uint64 timestampNs,uint128 valueAggregate)"))
+)
+
+// Receipt represents a V1 TAP receipt (allocation-based), the payer is the
+// signer and the service provider the allocation's indexer
+type Receipt struct {
+	AllocationID eth.Address `json:"allocation_id"`
+	TimestampNs  uint64      `json:"timestamp_ns"`
+	Nonce        uint64      `json:"nonce"`
+	Value        *big.Int    `json:"value"`
+}
+
+// NewReceipt creates a new receipt with current timestamp and random nonce
+func NewReceipt(allocationID eth.Address, value *big.Int) *Receipt {
+	return &Receipt{
+		AllocationID: allocationID,
+		TimestampNs:  uint64(time.Now().UnixNano()),
+		Nonce:        randomUint64(),
+		Value:        new(big.Int).Set(value),
+	}
+}
+
+// RAV represents a V1 Receipt Aggregate Voucher (allocation-based)
+type RAV struct {
+	AllocationID   eth.Address `json:"allocationId"`
+	TimestampNs    uint64      `json:"timestampNs"`
+	ValueAggregate *big.Int    `json:"valueAggregate"`
+}
+
+// SignedReceipt is a V1 receipt with its signature
+type SignedReceipt = horizon.SignedMessage[*Receipt]
+
+// SignedRAV is a V1 RAV with its signature
+type SignedRAV = horizon.SignedMessage[*RAV]
+
+// EIP712TypeHash returns the type hash for Receipt
+func (r *Receipt) EIP712TypeHash() eth.Hash {
+	return receiptTypeHash
+}
+
+// EIP712EncodeData returns the ABI-encoded data for Receipt
+func (r *Receipt) EIP712EncodeData() []byte {
+	encoded := make([]byte, 0, 32*4)
+	encoded = append(encoded, horizon.EncodeAddress(r.AllocationID)...) // address
+	encoded = append(encoded, horizon.EncodeUint64(r.TimestampNs)...)   // uint64
+	encoded = append(encoded, horizon.EncodeUint64(r.Nonce)...)         // uint64
+	encoded = append(encoded, horizon.EncodeUint128(r.Value)...)        // uint128
+	return encoded
+}
+
+// EIP712TypeHash returns the type hash for RAV
+func (r *RAV) EIP712TypeHash() eth.Hash {
+	return ravTypeHash
+}
+
+// EIP712EncodeData returns the ABI-encoded data for RAV
+func (r *RAV) EIP712EncodeData() []byte {
+	encoded := make([]byte, 0, 32*3)
+	encoded = append(encoded, horizon.EncodeAddress(r.AllocationID)...)   // address
+	encoded = append(encoded, horizon.EncodeUint64(r.TimestampNs)...)     // uint64
+	encoded = append(encoded, horizon.EncodeUint128(r.ValueAggregate)...) // uint128
+	return encoded
+}
+
+// randomUint64 generates a random uint64 for nonce
+func randomUint64() uint64 {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return binary.LittleEndian.Uint64(b[:])
+}
@@ -0,0 +1,45 @@
+package legacy
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndRecover(t *testing.T) {
+	key, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	verifier := eth.MustNewAddress("0x1234567890123456789012345678901234567890")
+	domain := NewDomain(1337, verifier)
+	assert.Equal(t, "TAP", domain.Name)
+	assert.Equal(t, "1", domain.Version)
+
+	allocationID := eth.MustNewAddress("0xabababababababababababababababababababab")
+	receipt, err := horizon.Sign(domain, NewReceipt(allocationID, big.NewInt(100)), key)
+	require.NoError(t, err)
+
+	signer, err := receipt.RecoverSigner(domain)
+	require.NoError(t, err)
+	assert.Equal(t, key.PublicKey().Address().Pretty(), signer.Pretty())
+
+	// The TAPVerifier domain does not verify Horizon signatures, and the other way around
+	signer, err = receipt.RecoverSigner(horizon.NewDomain(1337, verifier))
+	require.NoError(t, err)
+	assert.NotEqual(t, key.PublicKey().Address().Pretty(), signer.Pretty())
+
+	rav, err := horizon.Sign(domain, &RAV{AllocationID: allocationID, TimestampNs: 42, ValueAggregate: big.NewInt(500)}, key)
+	require.NoError(t, err)
+	signer, err = rav.RecoverSigner(domain)
+	require.NoError(t, err)
+	assert.Equal(t, key.PublicKey().Address().Pretty(), signer.Pretty())
+
+	// Every signed field is covered by the signature
+	rav.Message.ValueAggregate = big.NewInt(501)
+	signer, err = rav.RecoverSigner(domain)
+	require.NoError(t, err)
+	assert.NotEqual(t, key.PublicKey().Address().Pretty(), signer.Pretty())
+}
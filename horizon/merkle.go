@@ -0,0 +1,228 @@
+package horizon
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/streamingfast/eth-go"
+)
+
+var (
+	ErrNoMerkleLeaves       = errors.New("merkle tree requires at least one leaf")
+	ErrMerkleProofIndex     = errors.New("merkle proof index out of range")
+	ErrNoReceiptsRoot       = errors.New("RAV metadata carries no receipts merkle root")
+	ErrReceiptNotIncluded   = errors.New("receipt is not included in the RAV receipts merkle root")
+	ErrReceiptsRootMismatch = errors.New("RAV receipts merkle root does not match receipts")
+)
+
+// receiptsRootMetadataTag marks RAV metadata carrying a receipts merkle root
+var receiptsRootMetadataTag = []byte("RMR1")
+
+// ReceiptsRootMetadataLength is the length of RAV metadata carrying a receipts
+// merkle root: the collection ID (32 bytes, kept first as the proto RAV carries
+// the collection ID in the leading metadata bytes), a 4 bytes "RMR1" tag and
+// the 32 bytes root.
+const ReceiptsRootMetadataLength = 32 + 4 + 32
+
+// MerkleTree is a binary merkle tree using the OpenZeppelin MerkleProof
+// conventions: leaves are keccak256(value) and each node is the keccak256 of
+// its two children sorted, so proofs verify on-chain with MerkleProof.verify.
+// A node without sibling is promoted unchanged to the next level.
+type MerkleTree struct {
+	levels [][]eth.Hash
+}
+
+// NewMerkleTree builds the merkle tree over values, in order
+func NewMerkleTree(values []eth.Hash) (*MerkleTree, error) {
+	if len(values) == 0 {
+		return nil, ErrNoMerkleLeaves
+	}
+
+	level := make([]eth.Hash, len(values))
+	for i, value := range values {
+		level[i] = merkleLeaf(value)
+	}
+
+	levels := [][]eth.Hash{level}
+	for len(level) > 1 {
+		next := make([]eth.Hash, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, merkleNode(level[i], level[i+1]))
+		}
+		levels = append(levels, next)
+		level = next
+	}
+
+	return &MerkleTree{levels: levels}, nil
+}
+
+// Root returns the merkle root
+func (t *MerkleTree) Root() eth.Hash {
+	return t.levels[len(t.levels)-1][0]
+}
+
+// Proof returns the sibling hashes proving the inclusion of the value at index
+func (t *MerkleTree) Proof(index int) ([]eth.Hash, error) {
+	if index < 0 || index >= len(t.levels[0]) {
+		return nil, fmt.Errorf("%w: %d", ErrMerkleProofIndex, index)
+	}
+
+	var proof []eth.Hash
+	for _, level := range t.levels[:len(t.levels)-1] {
+		sibling := index ^ 1
+		if sibling < len(level) {
+			proof = append(proof, level[sibling])
+		}
+		index /= 2
+	}
+	return proof, nil
+}
+
+// VerifyMerkleProof reports whether proof proves value is included in the tree with root
+func VerifyMerkleProof(root, value eth.Hash, proof []eth.Hash) bool {
+	computed := merkleLeaf(value)
+	for _, sibling := range proof {
+		computed = merkleNode(computed, sibling)
+	}
+	return bytes.Equal(computed, root)
+}
+
+func merkleLeaf(value eth.Hash) eth.Hash {
+	return eth.Keccak256(value)
+}
+
+func merkleNode(a, b eth.Hash) eth.Hash {
+	if bytes.Compare(a, b) > 0 {
+		a, b = b, a
+	}
+	return eth.Keccak256(a, b)
+}
+
+// ReceiptsMerkleTree builds the merkle tree committing to the EIP-712 digests of
+// receipts. When previousRAV carries a receipts root, that root is the first
+// leaf so each RAV commits to all the receipts aggregated into it, through the
+// chain of previous RAVs. Receipts are taken in the order given, the Aggregator
+// commits to them in the canonical order of SortReceipts.
+func ReceiptsMerkleTree(domain *Domain, receipts []*SignedReceipt, previousRAV *SignedRAV) (*MerkleTree, error) {
+	values := make([]eth.Hash, 0, len(receipts)+1)
+	if root, found := DecodeReceiptsRootMetadata(previousRAVMetadata(previousRAV)); found {
+		values = append(values, root)
+	}
+
+	for i, r := range receipts {
+		digest, err := HashTypedData(domain, r.Message)
+		if err != nil {
+			return nil, fmt.Errorf("computing receipt %d digest: %w", i, err)
+		}
+		values = append(values, digest)
+	}
+
+	return NewMerkleTree(values)
+}
+
+// EncodeReceiptsRootMetadata encodes the RAV metadata committing to root
+func EncodeReceiptsRootMetadata(collectionID CollectionID, root eth.Hash) []byte {
+	metadata := make([]byte, 0, ReceiptsRootMetadataLength)
+	metadata = append(metadata, collectionID[:]...)
+	metadata = append(metadata, receiptsRootMetadataTag...)
+	return append(metadata, root...)
+}
+
+// DecodeReceiptsRootMetadata extracts the receipts merkle root from RAV
+// metadata, found is false when the metadata carries none
+func DecodeReceiptsRootMetadata(metadata []byte) (root eth.Hash, found bool) {
+	if len(metadata) != ReceiptsRootMetadataLength || !bytes.Equal(metadata[32:36], receiptsRootMetadataTag) {
+		return nil, false
+	}
+	return eth.Hash(bytes.Clone(metadata[36:])), true
+}
+
+// ReceiptInclusionProof proves that a receipt was aggregated into a RAV carrying
+// a receipts merkle root, by the receipt EIP-712 digest and its merkle proof
+type ReceiptInclusionProof struct {
+	ReceiptDigest eth.Hash   `json:"receipt_digest"`
+	Proof         []eth.Hash `json:"proof"`
+}
+
+// NewReceiptInclusionProof proves the inclusion of receipts[index] in the RAV
+// aggregated from receipts on top of previousRAV with WithReceiptsMerkleRoot
+func NewReceiptInclusionProof(domain *Domain, receipts []*SignedReceipt, previousRAV *SignedRAV, index int) (*ReceiptInclusionProof, error) {
+	tree, err := ReceiptsMerkleTree(domain, receipts, previousRAV)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= len(receipts) {
+		return nil, fmt.Errorf("%w: %d", ErrMerkleProofIndex, index)
+	}
+
+	leafIndex := index
+	if _, found := DecodeReceiptsRootMetadata(previousRAVMetadata(previousRAV)); found {
+		leafIndex++
+	}
+
+	proof, err := tree.Proof(leafIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	digest, err := HashTypedData(domain, receipts[index].Message)
+	if err != nil {
+		return nil, fmt.Errorf("computing receipt digest: %w", err)
+	}
+
+	return &ReceiptInclusionProof{ReceiptDigest: digest, Proof: proof}, nil
+}
+
+// VerifyReceiptInclusion checks that receipt is committed to by the receipts
+// merkle root in rav's metadata. Receipts of earlier RAVs are proven against
+// the RAV they were aggregated into, which is itself committed to by the next
+// RAV's root as its first leaf.
+func VerifyReceiptInclusion(domain *Domain, rav *RAV, receipt *SignedReceipt, proof *ReceiptInclusionProof) error {
+	root, found := DecodeReceiptsRootMetadata(rav.Metadata)
+	if !found {
+		return ErrNoReceiptsRoot
+	}
+
+	digest, err := HashTypedData(domain, receipt.Message)
+	if err != nil {
+		return fmt.Errorf("computing receipt digest: %w", err)
+	}
+	if !bytes.Equal(digest, proof.ReceiptDigest) {
+		return fmt.Errorf("%w: proof is for another receipt", ErrReceiptNotIncluded)
+	}
+
+	if !VerifyMerkleProof(root, digest, proof.Proof) {
+		return ErrReceiptNotIncluded
+	}
+	return nil
+}
+
+// VerifyReceiptsRoot checks that the receipts merkle root in rav's metadata
+// commits to exactly receipts aggregated on top of previousRAV
+func VerifyReceiptsRoot(domain *Domain, rav *RAV, receipts []*SignedReceipt, previousRAV *SignedRAV) error {
+	root, found := DecodeReceiptsRootMetadata(rav.Metadata)
+	if !found {
+		return ErrNoReceiptsRoot
+	}
+
+	tree, err := ReceiptsMerkleTree(domain, receipts, previousRAV)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(tree.Root(), root) {
+		return ErrReceiptsRootMismatch
+	}
+	return nil
+}
+
+func previousRAVMetadata(previousRAV *SignedRAV) []byte {
+	if previousRAV == nil || previousRAV.Message == nil {
+		return nil
+	}
+	return previousRAV.Message.Metadata
+}
@@ -0,0 +1,151 @@
+package horizon
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerkleTree_Proofs(t *testing.T) {
+	for size := 1; size <= 7; size++ {
+		values := make([]eth.Hash, size)
+		for i := range values {
+			values[i] = eth.Keccak256([]byte{byte(i)})
+		}
+
+		tree, err := NewMerkleTree(values)
+		require.NoError(t, err)
+
+		for i, value := range values {
+			proof, err := tree.Proof(i)
+			require.NoError(t, err)
+			assert.True(t, VerifyMerkleProof(tree.Root(), value, proof), "size %d, index %d", size, i)
+		}
+
+		_, err = tree.Proof(size)
+		assert.ErrorIs(t, err, ErrMerkleProofIndex)
+	}
+
+	_, err := NewMerkleTree(nil)
+	assert.ErrorIs(t, err, ErrNoMerkleLeaves)
+}
+
+func TestMerkleTree_PairRoot(t *testing.T) {
+	a := eth.Keccak256([]byte("a"))
+	b := eth.Keccak256([]byte("b"))
+
+	tree, err := NewMerkleTree([]eth.Hash{a, b})
+	require.NoError(t, err)
+
+	// Sorted pair hashing makes the root independent of the leaves order
+	reversed, err := NewMerkleTree([]eth.Hash{b, a})
+	require.NoError(t, err)
+	assert.Equal(t, tree.Root(), reversed.Root())
+
+	proof, err := tree.Proof(0)
+	require.NoError(t, err)
+	assert.False(t, VerifyMerkleProof(tree.Root(), eth.Keccak256([]byte("c")), proof))
+}
+
+func TestReceiptsRootMetadata(t *testing.T) {
+	collectionID := CollectionID{0x01, 0x02}
+	root := eth.Hash(eth.Keccak256([]byte("root")))
+
+	metadata := EncodeReceiptsRootMetadata(collectionID, root)
+	require.Len(t, metadata, ReceiptsRootMetadataLength)
+	assert.Equal(t, collectionID[:], metadata[:32])
+
+	decoded, found := DecodeReceiptsRootMetadata(metadata)
+	require.True(t, found)
+	assert.Equal(t, root, decoded)
+
+	_, found = DecodeReceiptsRootMetadata([]byte{})
+	assert.False(t, found)
+	_, found = DecodeReceiptsRootMetadata(make([]byte, ReceiptsRootMetadataLength))
+	assert.False(t, found)
+}
+
+func TestAggregator_ReceiptsMerkleRoot(t *testing.T) {
+	domain := NewDomain(1, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+
+	senderKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	aggregatorKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	aggregator := NewAggregator(domain, aggregatorKey,
+		[]eth.Address{senderKey.PublicKey().Address(), aggregatorKey.PublicKey().Address()},
+		WithReceiptsMerkleRoot(),
+	)
+
+	collectionID := CollectionID{0xAA}
+	baseTimestamp := uint64(time.Now().UnixNano())
+	newBatch := func(count int, after uint64, nonce uint64) []*SignedReceipt {
+		var batch []*SignedReceipt
+		for i := 0; i < count; i++ {
+			signed, err := Sign(domain, &Receipt{
+				CollectionID:    collectionID,
+				Payer:           senderKey.PublicKey().Address(),
+				DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+				ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+				TimestampNs:     after + uint64(i) + 1,
+				Nonce:           nonce + uint64(i),
+				Value:           big.NewInt(100),
+			}, senderKey)
+			require.NoError(t, err)
+			batch = append(batch, signed)
+		}
+		return batch
+	}
+
+	batch1 := newBatch(3, baseTimestamp, 0)
+	rav1, err := aggregator.AggregateReceipts(batch1, nil)
+	require.NoError(t, err)
+	require.Len(t, rav1.Message.Metadata, ReceiptsRootMetadataLength)
+	assert.Equal(t, collectionID[:], rav1.Message.Metadata[:32])
+	require.NoError(t, VerifyReceiptsRoot(domain, rav1.Message, batch1, nil))
+
+	for i, receipt := range batch1 {
+		proof, err := NewReceiptInclusionProof(domain, batch1, nil, i)
+		require.NoError(t, err)
+		require.NoError(t, VerifyReceiptInclusion(domain, rav1.Message, receipt, proof))
+	}
+
+	// The second RAV commits to the first one's root, then to its own receipts
+	batch2 := newBatch(2, rav1.Message.TimestampNs, 100)
+	rav2, err := aggregator.AggregateReceipts(batch2, rav1)
+	require.NoError(t, err)
+	require.NoError(t, VerifyReceiptsRoot(domain, rav2.Message, batch2, rav1))
+	assert.ErrorIs(t, VerifyReceiptsRoot(domain, rav2.Message, batch2, nil), ErrReceiptsRootMismatch)
+
+	proof, err := NewReceiptInclusionProof(domain, batch2, rav1, 1)
+	require.NoError(t, err)
+	require.NoError(t, VerifyReceiptInclusion(domain, rav2.Message, batch2[1], proof))
+
+	// A receipt of the first RAV is not a direct leaf of the second one
+	proof, err = NewReceiptInclusionProof(domain, batch1, nil, 0)
+	require.NoError(t, err)
+	assert.ErrorIs(t, VerifyReceiptInclusion(domain, rav2.Message, batch1[0], proof), ErrReceiptNotIncluded)
+
+	// A proof does not prove another receipt, nor once tampered with
+	proof, err = NewReceiptInclusionProof(domain, batch2, rav1, 0)
+	require.NoError(t, err)
+	assert.ErrorIs(t, VerifyReceiptInclusion(domain, rav2.Message, batch2[1], proof), ErrReceiptNotIncluded)
+
+	proof.Proof[0] = eth.Keccak256([]byte("tampered"))
+	assert.ErrorIs(t, VerifyReceiptInclusion(domain, rav2.Message, batch2[0], proof), ErrReceiptNotIncluded)
+
+	_, err = NewReceiptInclusionProof(domain, batch2, rav1, 2)
+	assert.ErrorIs(t, err, ErrMerkleProofIndex)
+}
+
+func TestVerifyReceiptInclusion_NoRoot(t *testing.T) {
+	domain := NewDomain(1, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+
+	err := VerifyReceiptInclusion(domain, &RAV{Metadata: []byte{}}, &SignedReceipt{Message: &Receipt{Value: big.NewInt(1)}}, &ReceiptInclusionProof{})
+	assert.ErrorIs(t, err, ErrNoReceiptsRoot)
+}
@@ -0,0 +1,62 @@
+package horizon
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Errors returned by ValidateMetadataSize and ValidateMetadataFormat
+var (
+	ErrMetadataTooLarge    = errors.New("RAV metadata exceeds maximum size")
+	ErrMetadataUnknownType = errors.New("RAV metadata is not of a known type")
+)
+
+// MetadataType identifies the layouts of RAV metadata produced by this package
+type MetadataType string
+
+const (
+	// MetadataTypeEmpty is RAV metadata carrying nothing
+	MetadataTypeEmpty MetadataType = "empty"
+	// MetadataTypeCollectionID is RAV metadata carrying only the collection ID
+	MetadataTypeCollectionID MetadataType = "collection_id"
+	// MetadataTypeReceiptsRoot is RAV metadata carrying the collection ID and the
+	// receipts merkle root, see EncodeReceiptsRootMetadata
+	MetadataTypeReceiptsRoot MetadataType = "receipts_root"
+	// MetadataTypeValidity is RAV metadata carrying the collection ID and a
+	// validity window, see EncodeRAVValidityMetadata
+	MetadataTypeValidity MetadataType = "validity"
+)
+
+// ValidateMetadataSize rejects metadata longer than maxSize bytes with
+// ErrMetadataTooLarge. Metadata is stored by the service provider and sent
+// on-chain on collect, where every byte costs gas.
+func ValidateMetadataSize(metadata []byte, maxSize int) error {
+	if len(metadata) > maxSize {
+		return fmt.Errorf("%w: %d bytes > %d", ErrMetadataTooLarge, len(metadata), maxSize)
+	}
+	return nil
+}
+
+// ValidateMetadataFormat returns the type of rav's metadata, or
+// ErrMetadataUnknownType when it matches none of the MetadataType layouts for
+// rav's collection
+func ValidateMetadataFormat(rav *RAV) (MetadataType, error) {
+	metadata := rav.Metadata
+	switch {
+	case len(metadata) == 0:
+		return MetadataTypeEmpty, nil
+	case len(metadata) < len(rav.CollectionID) || !bytes.Equal(metadata[:len(rav.CollectionID)], rav.CollectionID[:]):
+		return "", fmt.Errorf("%w: %d bytes not prefixed by the collection ID", ErrMetadataUnknownType, len(metadata))
+	case len(metadata) == len(rav.CollectionID):
+		return MetadataTypeCollectionID, nil
+	}
+
+	if _, found := DecodeReceiptsRootMetadata(metadata); found {
+		return MetadataTypeReceiptsRoot, nil
+	}
+	if _, found := DecodeRAVValidityMetadata(metadata); found {
+		return MetadataTypeValidity, nil
+	}
+	return "", fmt.Errorf("%w: %d bytes", ErrMetadataUnknownType, len(metadata))
+}
@@ -0,0 +1,48 @@
+package horizon
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateMetadataSize(t *testing.T) {
+	require.NoError(t, ValidateMetadataSize(nil, 0))
+	require.NoError(t, ValidateMetadataSize(make([]byte, 64), 64))
+	require.ErrorIs(t, ValidateMetadataSize(make([]byte, 65), 64), ErrMetadataTooLarge)
+}
+
+func TestValidateMetadataFormat(t *testing.T) {
+	collectionID := CollectionID{1, 2, 3}
+	root := bytes.Repeat([]byte{0xab}, 32)
+
+	tests := []struct {
+		name     string
+		metadata []byte
+		expected MetadataType
+		err      error
+	}{
+		{"empty", nil, MetadataTypeEmpty, nil},
+		{"collection ID", collectionID[:], MetadataTypeCollectionID, nil},
+		{"receipts root", EncodeReceiptsRootMetadata(collectionID, root), MetadataTypeReceiptsRoot, nil},
+		{"validity", EncodeRAVValidityMetadata(collectionID, time.Unix(1700000000, 0)), MetadataTypeValidity, nil},
+		{"other collection ID", make([]byte, 32), "", ErrMetadataUnknownType},
+		{"too short", []byte{1, 2, 3}, "", ErrMetadataUnknownType},
+		{"trailing bytes", append(bytes.Clone(collectionID[:]), 0xff), "", ErrMetadataUnknownType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typ, err := ValidateMetadataFormat(&RAV{CollectionID: collectionID, Metadata: tt.metadata})
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, typ)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package horizon
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/streamingfast/eth-go"
+)
+
+// Errors returned when resolving a known network
+var (
+	ErrUnknownNetwork        = errors.New("unknown network")
+	ErrNetworkAddressUnknown = errors.New("contract address of network is not known")
+)
+
+// Network is the preset of a network the data service runs on: its chain ID
+// and the canonical addresses of the contracts sidecars talk to. Addresses not
+// published yet for a network are nil and must be configured explicitly.
+type Network struct {
+	Name                  string
+	ChainID               uint64
+	GraphTallyCollector   eth.Address
+	SubstreamsDataService eth.Address
+}
+
+// KnownNetworks are the presets of the networks the data service runs on, by
+// name. The collector and data service of Arbitrum One and Arbitrum Sepolia
+// are left unset until their deployments are final. The devenv preset is the
+// deterministic deployment of the development environment (package devenv).
+var KnownNetworks = map[string]*Network{
+	"arbitrum-one": {
+		Name:    "arbitrum-one",
+		ChainID: 42161,
+	},
+	"arbitrum-sepolia": {
+		Name:    "arbitrum-sepolia",
+		ChainID: 421614,
+	},
+	"devenv": {
+		Name:                  "devenv",
+		ChainID:               1337,
+		GraphTallyCollector:   eth.MustNewAddress("0x1d01649b4f94722b55b5c3b3e10fe26cd90c1ba9"),
+		SubstreamsDataService: eth.MustNewAddress("0x37478fd2f5845e3664fe4155d74c00e1a4e7a5e2"),
+	},
+}
+
+// KnownNetworkNames returns the names of KnownNetworks, sorted
+func KnownNetworkNames() []string {
+	names := make([]string, 0, len(KnownNetworks))
+	for name := range KnownNetworks {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// LookupNetwork returns the preset of the known network named name
+func LookupNetwork(name string) (*Network, error) {
+	network, found := KnownNetworks[name]
+	if !found {
+		return nil, fmt.Errorf("%w %q, expected one of %s", ErrUnknownNetwork, name, strings.Join(KnownNetworkNames(), ", "))
+	}
+	return network, nil
+}
+
+// Domain returns the EIP-712 domain of the network's GraphTallyCollector
+func (n *Network) Domain() (*Domain, error) {
+	if n.GraphTallyCollector == nil {
+		return nil, fmt.Errorf("%w: GraphTallyCollector on %s", ErrNetworkAddressUnknown, n.Name)
+	}
+	return NewDomain(n.ChainID, n.GraphTallyCollector), nil
+}
+
+// DomainFor returns the EIP-712 domain of the GraphTallyCollector of the known
+// network named network
+func DomainFor(network string) (*Domain, error) {
+	n, err := LookupNetwork(network)
+	if err != nil {
+		return nil, err
+	}
+	return n.Domain()
+}
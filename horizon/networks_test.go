@@ -0,0 +1,30 @@
+package horizon
+
+import (
+	"testing"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKnownNetworks(t *testing.T) {
+	assert.Equal(t, []string{"arbitrum-one", "arbitrum-sepolia", "devenv"}, KnownNetworkNames())
+	for name, network := range KnownNetworks {
+		assert.Equal(t, name, network.Name)
+	}
+
+	domain, err := DomainFor("devenv")
+	require.NoError(t, err)
+	assert.Equal(t, NewDomain(1337, eth.MustNewAddress("0x1d01649b4f94722b55b5c3b3e10fe26cd90c1ba9")), domain)
+
+	network, err := LookupNetwork("arbitrum-one")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42161), network.ChainID)
+
+	_, err = DomainFor("arbitrum-one")
+	assert.ErrorIs(t, err, ErrNetworkAddressUnknown)
+
+	_, err = DomainFor("mainnet")
+	assert.ErrorIs(t, err, ErrUnknownNetwork)
+}
@@ -0,0 +1,136 @@
+package horizon
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/streamingfast/eth-go"
+)
+
+var (
+	ErrOfflineResponseMismatch = errors.New("offline signing response does not answer the request")
+	ErrOfflineSignerMismatch   = errors.New("offline signing response not signed by the expected signer")
+)
+
+// Schemas versioning the JSON encoding of offline signing requests and
+// responses, requests encoding their RAV canonically since version 2
+var (
+	OfflineSigningRequestSchema  = NewSchema("offline signing request", "schemaVersion", 2)
+	OfflineSigningResponseSchema = NewSchema("offline signing response", "schemaVersion", 1)
+)
+
+func init() {
+	OfflineSigningRequestSchema.RegisterMigration(1, CanonicalJSONMigration)
+}
+
+// File name suffixes of offline signing requests and responses, both named
+// after the request ID
+const (
+	OfflineRequestSuffix  = ".request.json"
+	OfflineResponseSuffix = ".response.json"
+)
+
+// OfflineSigningRequest asks a signer holding its key in an air-gapped
+// environment to sign a RAV. Requests and responses are exchanged as JSON
+// files, the domain travels with the RAV so the offline signer needs no
+// configuration besides its key.
+type OfflineSigningRequest struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	ID            string    `json:"id"`
+	Domain        *Domain   `json:"domain"`
+	RAV           *RAV      `json:"rav"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// OfflineSigningResponse carries the signature of the RAV of the request ID
+type OfflineSigningResponse struct {
+	SchemaVersion int     `json:"schemaVersion"`
+	ID            string  `json:"id"`
+	Signature     eth.Hex `json:"signature"`
+}
+
+// NewOfflineSigningRequest creates a request to sign rav under domain
+func NewOfflineSigningRequest(domain *Domain, rav *RAV) (*OfflineSigningRequest, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, fmt.Errorf("generating offline signing request ID: %w", err)
+	}
+
+	return &OfflineSigningRequest{
+		SchemaVersion: OfflineSigningRequestSchema.Version(),
+		ID:            hex.EncodeToString(id[:]),
+		Domain:        domain,
+		RAV:           rav,
+		CreatedAt:     time.Now().UTC(),
+	}, nil
+}
+
+// RequestFileName returns the file name of the request
+func (r *OfflineSigningRequest) RequestFileName() string {
+	return r.ID + OfflineRequestSuffix
+}
+
+// ResponseFileName returns the file name of the response to the request
+func (r *OfflineSigningRequest) ResponseFileName() string {
+	return r.ID + OfflineResponseSuffix
+}
+
+// Sign signs the request RAV with key, this is what the offline signer runs
+func (r *OfflineSigningRequest) Sign(key *eth.PrivateKey) (*OfflineSigningResponse, error) {
+	if r.Domain == nil || r.RAV == nil {
+		return nil, fmt.Errorf("offline signing request %s is missing its domain or RAV", r.ID)
+	}
+
+	signed, err := Sign(r.Domain, r.RAV, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OfflineSigningResponse{
+		SchemaVersion: OfflineSigningResponseSchema.Version(),
+		ID:            r.ID,
+		Signature:     eth.Hex(signed.Signature[:]),
+	}, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, requests of older schema versions
+// are migrated to the current one
+func (r *OfflineSigningRequest) UnmarshalJSON(data []byte) error {
+	type plain OfflineSigningRequest
+	return OfflineSigningRequestSchema.Unmarshal(data, (*plain)(r))
+}
+
+// UnmarshalJSON implements json.Unmarshaler, responses of older schema
+// versions are migrated to the current one
+func (r *OfflineSigningResponse) UnmarshalJSON(data []byte) error {
+	type plain OfflineSigningResponse
+	return OfflineSigningResponseSchema.Unmarshal(data, (*plain)(r))
+}
+
+// Complete returns the signed RAV from response, checking it answers the
+// request and is signed by signer
+func (r *OfflineSigningRequest) Complete(response *OfflineSigningResponse, signer eth.Address) (*SignedRAV, error) {
+	if response.ID != r.ID {
+		return nil, fmt.Errorf("%w: response %s, request %s", ErrOfflineResponseMismatch, response.ID, r.ID)
+	}
+	signature, err := ParseSignature(response.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOfflineResponseMismatch, err)
+	}
+
+	signed := &SignedRAV{Message: r.RAV, Signature: signature}
+
+	recovered, err := signed.RecoverSigner(r.Domain)
+	if err != nil {
+		return nil, fmt.Errorf("recovering offline signer: %w", err)
+	}
+	if !bytes.Equal(recovered, signer) {
+		return nil, fmt.Errorf("%w: signed by %s, expected %s", ErrOfflineSignerMismatch, recovered.Pretty(), signer.Pretty())
+	}
+
+	return signed, nil
+}
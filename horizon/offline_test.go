@@ -0,0 +1,64 @@
+package horizon
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOfflineSigningRequest_RoundTrip(t *testing.T) {
+	domain := NewDomain(1337, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+
+	key, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	otherKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	rav := &RAV{
+		CollectionID:    CollectionID{0x01},
+		Payer:           key.PublicKey().Address(),
+		DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+		ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		TimestampNs:     1234567890,
+		ValueAggregate:  big.NewInt(1000),
+		Metadata:        []byte{},
+	}
+
+	request, err := NewOfflineSigningRequest(domain, rav)
+	require.NoError(t, err)
+
+	// The offline signer only sees the request through its JSON file
+	data, err := json.Marshal(request)
+	require.NoError(t, err)
+	var received OfflineSigningRequest
+	require.NoError(t, json.Unmarshal(data, &received))
+
+	response, err := received.Sign(key)
+	require.NoError(t, err)
+
+	data, err = json.Marshal(response)
+	require.NoError(t, err)
+	var returned OfflineSigningResponse
+	require.NoError(t, json.Unmarshal(data, &returned))
+
+	signed, err := request.Complete(&returned, key.PublicKey().Address())
+	require.NoError(t, err)
+	assert.Same(t, rav, signed.Message)
+
+	expected, err := Sign(domain, rav, key)
+	require.NoError(t, err)
+	assert.Equal(t, expected.Signature, signed.Signature)
+
+	_, err = request.Complete(&returned, otherKey.PublicKey().Address())
+	assert.ErrorIs(t, err, ErrOfflineSignerMismatch)
+
+	_, err = request.Complete(&OfflineSigningResponse{ID: "other", Signature: returned.Signature}, key.PublicKey().Address())
+	assert.ErrorIs(t, err, ErrOfflineResponseMismatch)
+
+	_, err = request.Complete(&OfflineSigningResponse{ID: request.ID, Signature: eth.Hex{0x01}}, key.PublicKey().Address())
+	assert.ErrorIs(t, err, ErrOfflineResponseMismatch)
+}
@@ -0,0 +1,119 @@
+package horizon
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// ErrMetadataRejected is returned when a registered MetadataValidator refuses a RAV
+var ErrMetadataRejected = errors.New("RAV metadata rejected")
+
+// MetadataValidator inspects the metadata of a RAV about to be produced (by the
+// Aggregator) or accepted (by the Validator). previous is nil for the first RAV
+// of a collection and receipts is empty when validating a RAV directly. A
+// non-nil error rejects the RAV, it is wrapped with ErrMetadataRejected.
+//
+// It lets service-specific rules (e.g. block range continuity encoded in the
+// metadata) plug into the validation loop.
+type MetadataValidator func(previous *RAV, next *RAV, receipts []*SignedReceipt) error
+
+// Option configures an Aggregator or a Validator
+type Option func(*options)
+
+type options struct {
+	metadataValidators    []MetadataValidator
+	receiptsMerkleRoot    bool
+	escrowCapTolerance    *big.Int
+	dropDuplicateReceipts bool
+	signer                Signer
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithMetadataValidator registers a MetadataValidator, validators run in
+// registration order and the first error stops validation
+func WithMetadataValidator(validator MetadataValidator) Option {
+	return func(o *options) {
+		o.metadataValidators = append(o.metadataValidators, validator)
+	}
+}
+
+// WithReceiptsMerkleRoot makes the Aggregator embed in each RAV's metadata the
+// merkle root of the aggregated receipt digests (see ReceiptsMerkleTree), so
+// the inclusion of a receipt in a redeemed RAV can later be proven with a
+// ReceiptInclusionProof. It has no effect on a Validator.
+func WithReceiptsMerkleRoot() Option {
+	return func(o *options) {
+		o.receiptsMerkleRoot = true
+	}
+}
+
+// WithEscrowCapTolerance sets how much a RAV value aggregate may exceed the
+// escrow snapshot given as Policy.EscrowBalance, e.g. to absorb deposits not
+// yet reflected in the snapshot. It has no effect on an Aggregator.
+func WithEscrowCapTolerance(tolerance *big.Int) Option {
+	return func(o *options) {
+		o.escrowCapTolerance = tolerance
+	}
+}
+
+// WithDuplicateReceiptTolerance makes the Aggregator silently drop exact
+// duplicates of a receipt, typically resent by a consumer retrying a request,
+// instead of failing the whole batch with ErrDuplicateSignature. Malleated
+// variants of a signature are still rejected. The number of receipts dropped is
+// reported by AggregationRecord.DroppedDuplicates. It has no effect on a
+// Validator.
+func WithDuplicateReceiptTolerance() Option {
+	return func(o *options) {
+		o.dropDuplicateReceipts = true
+	}
+}
+
+// WithSigner makes the Aggregator sign RAVs with signer, e.g. a RemoteSigner,
+// instead of the signer key given to NewAggregator, which may then be nil. It
+// has no effect on a Validator.
+func WithSigner(signer Signer) Option {
+	return func(o *options) {
+		o.signer = signer
+	}
+}
+
+// AggregatorOptions are the options of an Aggregator that aggregators of other
+// receipt generations (see package legacy) honor too
+type AggregatorOptions struct {
+	// Signer is the signer set with WithSigner, nil when none
+	Signer Signer
+	// DropDuplicateReceipts is set by WithDuplicateReceiptTolerance
+	DropDuplicateReceipts bool
+}
+
+// NewAggregatorOptions returns the AggregatorOptions set by opts, the other
+// options are ignored
+func NewAggregatorOptions(opts ...Option) AggregatorOptions {
+	o := newOptions(opts)
+	return AggregatorOptions{
+		Signer:                o.signer,
+		DropDuplicateReceipts: o.dropDuplicateReceipts,
+	}
+}
+
+func (o *options) validateMetadata(previous *SignedRAV, next *RAV, receipts []*SignedReceipt) error {
+	var previousRAV *RAV
+	if previous != nil {
+		previousRAV = previous.Message
+	}
+
+	for _, validator := range o.metadataValidators {
+		if err := validator(previousRAV, next, receipts); err != nil {
+			return fmt.Errorf("%w: %w", ErrMetadataRejected, err)
+		}
+	}
+	return nil
+}
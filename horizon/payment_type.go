@@ -0,0 +1,72 @@
+package horizon
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// IGraphPayments.PaymentTypes values, the payment type a collection is made under
+const (
+	PaymentTypeQueryFee        uint8 = 0
+	PaymentTypeIndexingFee     uint8 = 1
+	PaymentTypeIndexingRewards uint8 = 2
+)
+
+// Errors returned for payment types unknown to GraphPayments or rejected by the
+// data service
+var (
+	ErrInvalidPaymentType     = errors.New("invalid payment type")
+	ErrUnsupportedPaymentType = errors.New("payment type not supported by the data service")
+)
+
+var paymentTypeNames = []string{
+	PaymentTypeQueryFee:        "query-fee",
+	PaymentTypeIndexingFee:     "indexing-fee",
+	PaymentTypeIndexingRewards: "indexing-rewards",
+}
+
+// ParsePaymentType parses a payment type, either by name ("query-fee",
+// "indexing-fee" or "indexing-rewards") or by its IGraphPayments.PaymentTypes
+// value (e.g. "0")
+func ParsePaymentType(value string) (uint8, error) {
+	value = strings.TrimSpace(value)
+	for paymentType, name := range paymentTypeNames {
+		if strings.EqualFold(value, name) {
+			return uint8(paymentType), nil
+		}
+	}
+
+	parsed, err := strconv.ParseUint(value, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("%w %q, expected one of %s or its value", ErrInvalidPaymentType, value, strings.Join(paymentTypeNames, ", "))
+	}
+	if _, err := PaymentTypeName(uint8(parsed)); err != nil {
+		return 0, err
+	}
+	return uint8(parsed), nil
+}
+
+// PaymentTypeName returns the name of paymentType as accepted by
+// ParsePaymentType, ErrInvalidPaymentType when GraphPayments does not define it
+func PaymentTypeName(paymentType uint8) (string, error) {
+	if int(paymentType) >= len(paymentTypeNames) {
+		return "", fmt.Errorf("%w %d", ErrInvalidPaymentType, paymentType)
+	}
+	return paymentTypeNames[paymentType], nil
+}
+
+// ValidateDataServicePaymentType checks paymentType against the rules of
+// SubstreamsDataService.collect, which only collects query fees and reverts
+// with SubstreamsDataServiceInvalidPaymentType for any other payment type
+func ValidateDataServicePaymentType(paymentType uint8) error {
+	name, err := PaymentTypeName(paymentType)
+	if err != nil {
+		return err
+	}
+	if paymentType != PaymentTypeQueryFee {
+		return fmt.Errorf("%w: %s", ErrUnsupportedPaymentType, name)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package horizon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePaymentType(t *testing.T) {
+	for in, expected := range map[string]uint8{
+		"query-fee":        PaymentTypeQueryFee,
+		"Indexing-Fee":     PaymentTypeIndexingFee,
+		"indexing-rewards": PaymentTypeIndexingRewards,
+		" 1 ":              PaymentTypeIndexingFee,
+	} {
+		paymentType, err := ParsePaymentType(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, expected, paymentType, in)
+	}
+
+	for _, in := range []string{"", "fees", "3", "256", "-1"} {
+		_, err := ParsePaymentType(in)
+		assert.ErrorIs(t, err, ErrInvalidPaymentType, in)
+	}
+}
+
+func TestValidateDataServicePaymentType(t *testing.T) {
+	assert.NoError(t, ValidateDataServicePaymentType(PaymentTypeQueryFee))
+	assert.ErrorIs(t, ValidateDataServicePaymentType(PaymentTypeIndexingFee), ErrUnsupportedPaymentType)
+	assert.ErrorIs(t, ValidateDataServicePaymentType(3), ErrInvalidPaymentType)
+}
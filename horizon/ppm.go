@@ -0,0 +1,127 @@
+package horizon
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// MaxPPM is one whole in parts per million, the PPMMath denominator
+const MaxPPM PPM = 1_000_000
+
+// ErrInvalidPPM is returned for PPM values above MaxPPM, which Horizon
+// contracts reject (PPMMathInvalidPPM)
+var ErrInvalidPPM = errors.New("invalid PPM")
+
+// PPM is a fraction in parts per million, the unit of Horizon cuts: protocol
+// payment cut, data service cut, delegation fee cut and verifier cut
+type PPM uint32
+
+// NewPPM returns value as a PPM, failing with ErrInvalidPPM above MaxPPM
+func NewPPM(value uint64) (PPM, error) {
+	if value > uint64(MaxPPM) {
+		return 0, fmt.Errorf("%w: %d exceeds %d", ErrInvalidPPM, value, MaxPPM)
+	}
+	return PPM(value), nil
+}
+
+// PPMFromPercent converts a percentage (e.g. 10 for 10%) to PPM, rounded to
+// the nearest part per million
+func PPMFromPercent(percent float64) (PPM, error) {
+	if math.IsNaN(percent) || percent < 0 || percent > 100 {
+		return 0, fmt.Errorf("%w: %v%% is not within 0%% and 100%%", ErrInvalidPPM, percent)
+	}
+	return PPM(math.Round(percent * float64(MaxPPM) / 100)), nil
+}
+
+// ParsePPM parses a PPM value, either an integer number of parts per million
+// (e.g. "100000") or a percentage (e.g. "10%")
+func ParsePPM(value string) (PPM, error) {
+	value = strings.TrimSpace(value)
+
+	if percent, found := strings.CutSuffix(value, "%"); found {
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(percent), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid percentage %q: %w", value, err)
+		}
+		return PPMFromPercent(parsed)
+	}
+
+	parsed, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid PPM %q, expected an integer or a percentage: %w", value, err)
+	}
+	return NewPPM(parsed)
+}
+
+// Validate returns ErrInvalidPPM when p exceeds MaxPPM
+func (p PPM) Validate() error {
+	if p > MaxPPM {
+		return fmt.Errorf("%w: %d exceeds %d", ErrInvalidPPM, uint32(p), MaxPPM)
+	}
+	return nil
+}
+
+// Percent returns p as a percentage, e.g. 10 for 100000
+func (p PPM) Percent() float64 {
+	return float64(p) * 100 / float64(MaxPPM)
+}
+
+// String renders p as a percentage followed by its PPM value, e.g. "10% (100000 PPM)"
+func (p PPM) String() string {
+	return fmt.Sprintf("%s%% (%d PPM)", strconv.FormatFloat(p.Percent(), 'f', -1, 64), uint32(p))
+}
+
+// BigInt returns p as a big.Int, e.g. for uint256 contract parameters
+func (p PPM) BigInt() *big.Int {
+	return new(big.Int).SetUint64(uint64(p))
+}
+
+// Mul returns tokens * p / MaxPPM rounded down, as PPMMath.mulPPM
+func (p PPM) Mul(tokens *big.Int) *big.Int {
+	out := new(big.Int).Mul(tokens, p.BigInt())
+	return out.Quo(out, MaxPPM.BigInt())
+}
+
+// MulRoundUp returns tokens * p / MaxPPM rounded up, as PPMMath.mulPPMRoundUp
+// which GraphPayments uses to take cuts
+func (p PPM) MulRoundUp(tokens *big.Int) *big.Int {
+	return new(big.Int).Sub(tokens, (MaxPPM - p).Mul(tokens))
+}
+
+// PaymentSplit is how GraphPayments distributes collected tokens
+type PaymentSplit struct {
+	Protocol        *big.Int
+	DataService     *big.Int
+	Delegation      *big.Int
+	ServiceProvider *big.Int
+}
+
+// SplitPayment applies the cuts of GraphPayments.collect to tokens: the
+// protocol payment cut is taken first, the data service cut from what remains,
+// then the delegation fee cut, the rest going to the service provider
+func SplitPayment(tokens *big.Int, protocolCut, dataServiceCut, delegationFeeCut PPM) (*PaymentSplit, error) {
+	for _, cut := range []PPM{protocolCut, dataServiceCut, delegationFeeCut} {
+		if err := cut.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	remaining := new(big.Int).Set(tokens)
+	split := &PaymentSplit{}
+
+	split.Protocol = protocolCut.MulRoundUp(remaining)
+	remaining.Sub(remaining, split.Protocol)
+
+	split.DataService = dataServiceCut.MulRoundUp(remaining)
+	remaining.Sub(remaining, split.DataService)
+
+	split.Delegation = delegationFeeCut.MulRoundUp(remaining)
+	remaining.Sub(remaining, split.Delegation)
+
+	split.ServiceProvider = remaining
+	return split, nil
+}
@@ -0,0 +1,81 @@
+package horizon
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePPM(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    PPM
+		wantErr bool
+	}{
+		{"0", 0, false},
+		{"100000", 100_000, false},
+		{"1000000", MaxPPM, false},
+		{"10%", 100_000, false},
+		{" 0.5 % ", 5_000, false},
+		{"100%", MaxPPM, false},
+		{"0.00005%", 1, false},
+		{"1000001", 0, true},
+		{"101%", 0, true},
+		{"-1%", 0, true},
+		{"-1", 0, true},
+		{"ten", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParsePPM(tt.in)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := ParsePPM("1000001")
+	assert.ErrorIs(t, err, ErrInvalidPPM)
+}
+
+func TestPPM(t *testing.T) {
+	cut := PPM(100_000)
+	assert.NoError(t, cut.Validate())
+	assert.ErrorIs(t, PPM(MaxPPM+1).Validate(), ErrInvalidPPM)
+	assert.Equal(t, 10.0, cut.Percent())
+	assert.Equal(t, "10% (100000 PPM)", cut.String())
+	assert.Equal(t, "0.0001% (1 PPM)", PPM(1).String())
+
+	assert.Equal(t, "100", cut.Mul(big.NewInt(1000)).String())
+	assert.Equal(t, "0", PPM(1).Mul(big.NewInt(999_999)).String())
+	assert.Equal(t, "1", PPM(1).MulRoundUp(big.NewInt(999_999)).String())
+	assert.Equal(t, "100", cut.MulRoundUp(big.NewInt(1000)).String())
+}
+
+func TestSplitPayment(t *testing.T) {
+	split, err := SplitPayment(big.NewInt(1_000_000), 10_000, 100_000, 0)
+	require.NoError(t, err)
+
+	assert.Equal(t, "10000", split.Protocol.String())
+	assert.Equal(t, "99000", split.DataService.String())
+	assert.Equal(t, "0", split.Delegation.String())
+	assert.Equal(t, "891000", split.ServiceProvider.String())
+
+	// Cuts are rounded up, in favor of the protocol then the data service
+	split, err = SplitPayment(big.NewInt(999), 10_000, 100_000, 500_000)
+	require.NoError(t, err)
+	assert.Equal(t, "10", split.Protocol.String())
+	assert.Equal(t, "99", split.DataService.String())
+	assert.Equal(t, "445", split.Delegation.String())
+	assert.Equal(t, "445", split.ServiceProvider.String())
+
+	_, err = SplitPayment(big.NewInt(1), 0, MaxPPM+1, 0)
+	assert.ErrorIs(t, err, ErrInvalidPPM)
+}
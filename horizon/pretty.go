@@ -0,0 +1,185 @@
+package horizon
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/streamingfast/eth-go"
+)
+
+// GRTDecimals is the number of decimals of the GRT token
+const GRTDecimals = 18
+
+var grtUnit = new(big.Int).Exp(big.NewInt(10), big.NewInt(GRTDecimals), nil)
+
+// FormatGRT formats a wei amount as a decimal GRT string, e.g. 1500000000000000000 as "1.5"
+func FormatGRT(wei *big.Int) string {
+	if wei == nil {
+		return "0"
+	}
+
+	sign := ""
+	abs := new(big.Int).Set(wei)
+	if abs.Sign() < 0 {
+		sign = "-"
+		abs.Neg(abs)
+	}
+
+	integer, fraction := new(big.Int).QuoRem(abs, grtUnit, new(big.Int))
+	if fraction.Sign() == 0 {
+		return sign + integer.String()
+	}
+
+	fractionStr := strings.TrimRight(fmt.Sprintf("%0*d", GRTDecimals, fraction), "0")
+	return fmt.Sprintf("%s%s.%s", sign, integer, fractionStr)
+}
+
+// String renders the collection ID truncated to its first and last 4 bytes
+func (c CollectionID) String() string {
+	return truncateHex(c[:])
+}
+
+// String renders the receipt on a single line for logs, the value in GRT and
+// the collection ID truncated
+func (r *Receipt) String() string {
+	if r == nil {
+		return "<nil Receipt>"
+	}
+
+	return fmt.Sprintf("Receipt(payer=%s service_provider=%s data_service=%s collection=%s value=%s GRT nonce=%d timestamp=%s)",
+		r.Payer.Pretty(), r.ServiceProvider.Pretty(), r.DataService.Pretty(), r.CollectionID, FormatGRT(r.Value), r.Nonce, formatTimestampNs(r.TimestampNs))
+}
+
+// Pretty renders the receipt over multiple lines with every field in full, for
+// CLI output
+func (r *Receipt) Pretty() string {
+	return renderPretty(r)
+}
+
+func (r *Receipt) prettyTitle() string { return "Receipt" }
+
+func (r *Receipt) prettyFields() [][2]string {
+	if r == nil {
+		return nil
+	}
+
+	return [][2]string{
+		{"Payer", r.Payer.Pretty()},
+		{"Service provider", r.ServiceProvider.Pretty()},
+		{"Data service", r.DataService.Pretty()},
+		{"Collection", eth.Hash(r.CollectionID[:]).Pretty()},
+		{"Value", FormatGRT(r.Value) + " GRT"},
+		{"Nonce", fmt.Sprintf("%d", r.Nonce)},
+		{"Timestamp", fmt.Sprintf("%s (%d ns)", formatTimestampNs(r.TimestampNs), r.TimestampNs)},
+	}
+}
+
+// String renders the RAV on a single line for logs, the value aggregate in GRT
+// and the collection ID truncated
+func (r *RAV) String() string {
+	if r == nil {
+		return "<nil RAV>"
+	}
+
+	return fmt.Sprintf("RAV(payer=%s service_provider=%s data_service=%s collection=%s value_aggregate=%s GRT timestamp=%s metadata=%d bytes)",
+		r.Payer.Pretty(), r.ServiceProvider.Pretty(), r.DataService.Pretty(), r.CollectionID, FormatGRT(r.ValueAggregate), formatTimestampNs(r.TimestampNs), len(r.Metadata))
+}
+
+// Pretty renders the RAV over multiple lines with every field in full, for CLI
+// output
+func (r *RAV) Pretty() string {
+	return renderPretty(r)
+}
+
+func (r *RAV) prettyTitle() string { return "RAV" }
+
+func (r *RAV) prettyFields() [][2]string {
+	if r == nil {
+		return nil
+	}
+
+	metadata := "(none)"
+	if len(r.Metadata) > 0 {
+		metadata = eth.Hex(r.Metadata).Pretty()
+	}
+
+	return [][2]string{
+		{"Payer", r.Payer.Pretty()},
+		{"Service provider", r.ServiceProvider.Pretty()},
+		{"Data service", r.DataService.Pretty()},
+		{"Collection", eth.Hash(r.CollectionID[:]).Pretty()},
+		{"Value aggregate", FormatGRT(r.ValueAggregate) + " GRT"},
+		{"Timestamp", fmt.Sprintf("%s (%d ns)", formatTimestampNs(r.TimestampNs), r.TimestampNs)},
+		{"Metadata", metadata},
+	}
+}
+
+// String renders the signed message on a single line for logs, the message as
+// rendered by its own String followed by the truncated signature
+func (sm *SignedMessage[T]) String() string {
+	if sm == nil {
+		return "<nil signed message>"
+	}
+
+	return fmt.Sprintf("%v signature=%s", sm.Message, truncateHex(sm.Signature[:]))
+}
+
+// Pretty renders the signed message over multiple lines, the message fields
+// followed by the full signature
+func (sm *SignedMessage[T]) Pretty() string {
+	if sm == nil {
+		return "<nil signed message>"
+	}
+
+	message, ok := any(sm.Message).(prettyMessage)
+	if !ok {
+		return fmt.Sprintf("%v\n  Signature: %s", sm.Message, eth.Hex(sm.Signature[:]).Pretty())
+	}
+
+	fields := append(message.prettyFields(), [2]string{"Signature", eth.Hex(sm.Signature[:]).Pretty()})
+	return formatPrettyFields("Signed "+message.prettyTitle(), fields)
+}
+
+// prettyMessage is implemented by the messages rendered by Pretty
+type prettyMessage interface {
+	prettyTitle() string
+	prettyFields() [][2]string
+}
+
+func renderPretty(message prettyMessage) string {
+	fields := message.prettyFields()
+	if fields == nil {
+		return "<nil " + message.prettyTitle() + ">"
+	}
+	return formatPrettyFields(message.prettyTitle(), fields)
+}
+
+// formatPrettyFields renders title followed by one indented, aligned line per field
+func formatPrettyFields(title string, fields [][2]string) string {
+	width := 0
+	for _, field := range fields {
+		width = max(width, len(field[0]))
+	}
+
+	var out strings.Builder
+	out.WriteString(title)
+	for _, field := range fields {
+		fmt.Fprintf(&out, "\n  %-*s  %s", width+1, field[0]+":", field[1])
+	}
+	return out.String()
+}
+
+// truncateHex renders b as hex keeping its first and last 4 bytes, shorter
+// values are rendered in full
+func truncateHex(b []byte) string {
+	if len(b) <= 8 {
+		return eth.Hex(b).Pretty()
+	}
+	return fmt.Sprintf("0x%x…%x", b[:4], b[len(b)-4:])
+}
+
+func formatTimestampNs(timestampNs uint64) string {
+	return time.Unix(0, int64(timestampNs)).UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,67 @@
+package horizon
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatGRT(t *testing.T) {
+	assert.Equal(t, "0", FormatGRT(nil))
+	assert.Equal(t, "1.5", FormatGRT(big.NewInt(1_500_000_000_000_000_000)))
+	assert.Equal(t, "-0.000000000000000001", FormatGRT(big.NewInt(-1)))
+}
+
+func TestRAV_String(t *testing.T) {
+	rav := &RAV{
+		CollectionID:    CollectionID{0x01, 0x02, 0x03, 0x04, 31: 0xff},
+		Payer:           eth.MustNewAddress("0x1111111111111111111111111111111111111111"),
+		ServiceProvider: eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+		DataService:     eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		TimestampNs:     1_700_000_000_000_000_000,
+		ValueAggregate:  big.NewInt(2_500_000_000_000_000_000),
+	}
+
+	assert.Equal(t, "RAV(payer=0x1111111111111111111111111111111111111111 service_provider=0x2222222222222222222222222222222222222222 "+
+		"data_service=0x3333333333333333333333333333333333333333 collection=0x01020304…000000ff value_aggregate=2.5 GRT "+
+		"timestamp=2023-11-14T22:13:20Z metadata=0 bytes)", rav.String())
+
+	assert.Equal(t, `RAV
+  Payer:             0x1111111111111111111111111111111111111111
+  Service provider:  0x2222222222222222222222222222222222222222
+  Data service:      0x3333333333333333333333333333333333333333
+  Collection:        0x01020304000000000000000000000000000000000000000000000000000000ff
+  Value aggregate:   2.5 GRT
+  Timestamp:         2023-11-14T22:13:20Z (1700000000000000000 ns)
+  Metadata:          (none)`, rav.Pretty())
+
+	signed := &SignedRAV{Message: rav, Signature: eth.Signature{0xaa, 64: 0x1b}}
+	assert.Equal(t, rav.String()+" signature=0xaa000000…0000001b", signed.String())
+	assert.Contains(t, signed.Pretty(), "Signed RAV\n  Payer:")
+	assert.Contains(t, signed.Pretty(), "\n  Signature:         0xaa")
+
+	var nilRAV *RAV
+	assert.Equal(t, "<nil RAV>", nilRAV.String())
+	assert.Equal(t, "<nil RAV>", nilRAV.Pretty())
+}
+
+func TestReceipt_String(t *testing.T) {
+	receipt := &Receipt{
+		CollectionID:    CollectionID{0xab},
+		Payer:           eth.MustNewAddress("0x1111111111111111111111111111111111111111"),
+		ServiceProvider: eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+		DataService:     eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		Nonce:           7,
+		Value:           big.NewInt(1_000_000_000_000_000),
+	}
+
+	assert.Equal(t, "Receipt(payer=0x1111111111111111111111111111111111111111 service_provider=0x2222222222222222222222222222222222222222 "+
+		"data_service=0x3333333333333333333333333333333333333333 collection=0xab000000…00000000 value=0.001 GRT nonce=7 "+
+		"timestamp=1970-01-01T00:00:00Z)", receipt.String())
+	assert.Contains(t, receipt.Pretty(), "\n  Nonce:             7")
+
+	signed := &SignedReceipt{Message: receipt}
+	assert.Contains(t, signed.String(), "value=0.001 GRT")
+}
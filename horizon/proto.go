@@ -0,0 +1,174 @@
+package horizon
+
+import (
+	"math/big"
+
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	"github.com/streamingfast/eth-go"
+)
+
+// ToProto converts the receipt to its proto message, nil for a nil receipt
+func (r *Receipt) ToProto() *commonv1.Receipt {
+	if r == nil {
+		return nil
+	}
+
+	return &commonv1.Receipt{
+		CollectionId:    r.CollectionID[:],
+		Payer:           commonv1.AddressFromEth(r.Payer),
+		DataService:     commonv1.AddressFromEth(r.DataService),
+		ServiceProvider: commonv1.AddressFromEth(r.ServiceProvider),
+		TimestampNs:     r.TimestampNs,
+		Nonce:           r.Nonce,
+		Value:           bigIntToProto(r.Value),
+	}
+}
+
+// ReceiptFromProto converts a proto receipt, nil for a nil message. Missing
+// addresses and values are left empty.
+func ReceiptFromProto(pr *commonv1.Receipt) *Receipt {
+	if pr == nil {
+		return nil
+	}
+
+	var collectionID CollectionID
+	copy(collectionID[:], pr.CollectionId)
+
+	return &Receipt{
+		CollectionID:    collectionID,
+		Payer:           addressFromProto(pr.Payer),
+		DataService:     addressFromProto(pr.DataService),
+		ServiceProvider: addressFromProto(pr.ServiceProvider),
+		TimestampNs:     pr.TimestampNs,
+		Nonce:           pr.Nonce,
+		Value:           bigIntFromProto(pr.Value),
+	}
+}
+
+// ToProto converts the RAV to its proto message, nil for a nil RAV. The proto
+// message has no collection ID of its own, it is carried by the metadata.
+func (r *RAV) ToProto() *commonv1.RAV {
+	if r == nil {
+		return nil
+	}
+
+	return &commonv1.RAV{
+		Payer:           commonv1.AddressFromEth(r.Payer),
+		DataService:     commonv1.AddressFromEth(r.DataService),
+		ServiceProvider: commonv1.AddressFromEth(r.ServiceProvider),
+		TimestampNs:     r.TimestampNs,
+		ValueAggregate:  bigIntToProto(r.ValueAggregate),
+		Metadata:        r.Metadata,
+	}
+}
+
+// RAVFromProto converts a proto RAV, nil for a nil message. The collection ID
+// is taken from the first 32 bytes of the metadata, it is left empty when the
+// metadata is shorter.
+func RAVFromProto(pr *commonv1.RAV) *RAV {
+	if pr == nil {
+		return nil
+	}
+
+	var collectionID CollectionID
+	if len(pr.Metadata) >= len(collectionID) {
+		copy(collectionID[:], pr.Metadata[:len(collectionID)])
+	}
+
+	return &RAV{
+		CollectionID:    collectionID,
+		Payer:           addressFromProto(pr.Payer),
+		DataService:     addressFromProto(pr.DataService),
+		ServiceProvider: addressFromProto(pr.ServiceProvider),
+		TimestampNs:     pr.TimestampNs,
+		ValueAggregate:  bigIntFromProto(pr.ValueAggregate),
+		Metadata:        pr.Metadata,
+	}
+}
+
+// SignedReceiptToProto converts a signed receipt to its proto message, the
+// signature being the V+R+S bytes of eth.Signature
+func SignedReceiptToProto(sr *SignedReceipt) *commonv1.SignedReceipt {
+	if sr == nil {
+		return nil
+	}
+
+	return &commonv1.SignedReceipt{
+		Receipt:   sr.Message.ToProto(),
+		Signature: sr.Signature[:],
+	}
+}
+
+// SignedReceiptFromProto converts a proto signed receipt, nil when it or its
+// receipt is nil. Malformed signatures are left zeroed, recovering their
+// signer then fails.
+func SignedReceiptFromProto(psr *commonv1.SignedReceipt) *SignedReceipt {
+	if psr == nil {
+		return nil
+	}
+
+	receipt := ReceiptFromProto(psr.Receipt)
+	if receipt == nil {
+		return nil
+	}
+
+	sig, _ := ParseSignature(psr.Signature)
+	return &SignedReceipt{
+		Message:   receipt,
+		Signature: sig,
+	}
+}
+
+// SignedRAVToProto converts a signed RAV to its proto message, the signature
+// being the V+R+S bytes of eth.Signature
+func SignedRAVToProto(sr *SignedRAV) *commonv1.SignedRAV {
+	if sr == nil {
+		return nil
+	}
+
+	return &commonv1.SignedRAV{
+		Rav:       sr.Message.ToProto(),
+		Signature: sr.Signature[:],
+	}
+}
+
+// SignedRAVFromProto converts a proto signed RAV, nil when it or its RAV is
+// nil. Malformed signatures are left zeroed, recovering their signer then
+// fails.
+func SignedRAVFromProto(psr *commonv1.SignedRAV) *SignedRAV {
+	if psr == nil {
+		return nil
+	}
+
+	rav := RAVFromProto(psr.Rav)
+	if rav == nil {
+		return nil
+	}
+
+	sig, _ := ParseSignature(psr.Signature)
+	return &SignedRAV{
+		Message:   rav,
+		Signature: sig,
+	}
+}
+
+func addressFromProto(pa *commonv1.Address) eth.Address {
+	if pa == nil {
+		return nil
+	}
+	return pa.ToEth()
+}
+
+func bigIntFromProto(pb *commonv1.BigInt) *big.Int {
+	if pb == nil {
+		return nil
+	}
+	return pb.ToNative()
+}
+
+func bigIntToProto(i *big.Int) *commonv1.BigInt {
+	if i == nil {
+		return nil
+	}
+	return commonv1.BigIntFromNative(i)
+}
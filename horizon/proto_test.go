@@ -0,0 +1,58 @@
+package horizon
+
+import (
+	"math/big"
+	"testing"
+
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtoConversions(t *testing.T) {
+	domain := NewDomain(1337, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+	key, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	receipt, err := Sign(domain, NewReceipt(CollectionID{0x01}, key.PublicKey().Address(), eth.MustNewAddress("0x2222222222222222222222222222222222222222"), eth.MustNewAddress("0x3333333333333333333333333333333333333333"), big.NewInt(100)), key)
+	require.NoError(t, err)
+
+	decodedReceipt := SignedReceiptFromProto(SignedReceiptToProto(receipt))
+	require.NotNil(t, decodedReceipt)
+	assert.Equal(t, receipt.Signature, decodedReceipt.Signature)
+	assert.Equal(t, receipt.Message.CollectionID, decodedReceipt.Message.CollectionID)
+	assert.Equal(t, receipt.Message.Nonce, decodedReceipt.Message.Nonce)
+	assert.Equal(t, "100", decodedReceipt.Message.Value.String())
+
+	// The proto RAV carries its collection ID in the metadata
+	collectionID := CollectionID{0x02}
+	metadata := append(collectionID[:], 0xca, 0xfe)
+	rav, err := Sign(domain, &RAV{
+		CollectionID:    collectionID,
+		Payer:           key.PublicKey().Address(),
+		ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+		TimestampNs:     42,
+		ValueAggregate:  big.NewInt(500),
+		Metadata:        metadata,
+	}, key)
+	require.NoError(t, err)
+
+	decodedRAV := SignedRAVFromProto(SignedRAVToProto(rav))
+	require.NotNil(t, decodedRAV)
+	assert.Equal(t, rav.Message.CollectionID, decodedRAV.Message.CollectionID)
+	assert.Equal(t, rav.Message.Metadata, decodedRAV.Message.Metadata)
+
+	signer, err := decodedRAV.RecoverSigner(domain)
+	require.NoError(t, err)
+	assert.True(t, addressesEqual(key.PublicKey().Address(), signer))
+
+	// Nil messages and missing fields do not panic
+	assert.Nil(t, (*RAV)(nil).ToProto())
+	assert.Nil(t, SignedRAVFromProto(&commonv1.SignedRAV{}))
+	partial := RAVFromProto(&commonv1.RAV{TimestampNs: 1})
+	require.NotNil(t, partial)
+	assert.Nil(t, partial.ValueAggregate)
+	assert.Nil(t, (&RAV{}).ToProto().ValueAggregate)
+}
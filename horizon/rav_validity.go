@@ -0,0 +1,51 @@
+package horizon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrRAVExpired is returned by CheckRAVValidity for a RAV presented after the
+// end of its validity window
+var ErrRAVExpired = errors.New("RAV validity window has ended")
+
+// ravValidityMetadataTag marks RAV metadata carrying a validity window
+var ravValidityMetadataTag = []byte("VAL1")
+
+// RAVValidityMetadataLength is the length of RAV metadata carrying a validity
+// window: the collection ID (32 bytes), a 4 bytes "VAL1" tag and the end of the
+// window as big-endian unix nanoseconds (8 bytes).
+const RAVValidityMetadataLength = 32 + 4 + 8
+
+// EncodeRAVValidityMetadata encodes the RAV metadata hinting that the RAV
+// should not be presented to open new sessions after validUntil. The window is
+// advisory: it is covered by the RAV signature but the collector contract
+// ignores it, the RAV remains collectable on-chain.
+func EncodeRAVValidityMetadata(collectionID CollectionID, validUntil time.Time) []byte {
+	metadata := make([]byte, 0, RAVValidityMetadataLength)
+	metadata = append(metadata, collectionID[:]...)
+	metadata = append(metadata, ravValidityMetadataTag...)
+	return binary.BigEndian.AppendUint64(metadata, uint64(validUntil.UnixNano()))
+}
+
+// DecodeRAVValidityMetadata extracts the end of the validity window from RAV
+// metadata, found is false when the metadata carries none
+func DecodeRAVValidityMetadata(metadata []byte) (validUntil time.Time, found bool) {
+	if len(metadata) != RAVValidityMetadataLength || !bytes.Equal(metadata[32:36], ravValidityMetadataTag) {
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(metadata[36:]))), true
+}
+
+// CheckRAVValidity returns ErrRAVExpired when rav carries a validity window
+// ended at now, RAVs without one are always valid
+func CheckRAVValidity(rav *RAV, now time.Time) error {
+	validUntil, found := DecodeRAVValidityMetadata(rav.Metadata)
+	if !found || now.Before(validUntil) {
+		return nil
+	}
+	return fmt.Errorf("%w: valid until %s", ErrRAVExpired, validUntil.UTC().Format(time.RFC3339))
+}
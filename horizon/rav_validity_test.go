@@ -0,0 +1,37 @@
+package horizon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRAVValidityMetadata(t *testing.T) {
+	collectionID := CollectionID{1, 2, 3}
+	validUntil := time.Unix(1700000000, 123)
+
+	metadata := EncodeRAVValidityMetadata(collectionID, validUntil)
+	require.Len(t, metadata, RAVValidityMetadataLength)
+	assert.Equal(t, collectionID[:], metadata[:32])
+
+	decoded, found := DecodeRAVValidityMetadata(metadata)
+	require.True(t, found)
+	assert.True(t, validUntil.Equal(decoded))
+
+	_, found = DecodeRAVValidityMetadata(EncodeReceiptsRootMetadata(collectionID, make([]byte, 32)))
+	assert.False(t, found)
+}
+
+func TestCheckRAVValidity(t *testing.T) {
+	validUntil := time.Unix(1700000000, 0)
+	rav := &RAV{Metadata: EncodeRAVValidityMetadata(CollectionID{}, validUntil)}
+
+	assert.NoError(t, CheckRAVValidity(rav, validUntil.Add(-time.Second)))
+	assert.ErrorIs(t, CheckRAVValidity(rav, validUntil), ErrRAVExpired)
+	assert.ErrorIs(t, CheckRAVValidity(rav, validUntil.Add(time.Hour)), ErrRAVExpired)
+
+	// RAVs without a validity window never expire
+	assert.NoError(t, CheckRAVValidity(&RAV{}, validUntil.Add(time.Hour)))
+}
@@ -0,0 +1,34 @@
+package horizon
+
+import (
+	"bytes"
+	"cmp"
+	"slices"
+)
+
+// ReceiptOrdering is the version of the rule SortReceipts orders receipts by,
+// recorded in each AggregationRecord so audits reproduce the order the
+// receipts were aggregated in. Version 1 orders receipts by timestamp, then
+// nonce, then signature in normalized (low-S) form. Zero denotes records of
+// aggregations taking receipts in the order they were given.
+const ReceiptOrdering = 1
+
+// SortReceipts sorts receipts in place in the canonical aggregation order (see
+// ReceiptOrdering), so two parties aggregating the same receipt set, received
+// in any order, produce byte-identical RAV inputs: receipts merkle root,
+// aggregation record and the receipts seen by metadata validators.
+func SortReceipts(receipts []*SignedReceipt) {
+	slices.SortStableFunc(receipts, compareReceipts)
+}
+
+func compareReceipts(a, b *SignedReceipt) int {
+	if c := cmp.Compare(a.Message.TimestampNs, b.Message.TimestampNs); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(a.Message.Nonce, b.Message.Nonce); c != 0 {
+		return c
+	}
+
+	aID, bID := a.UniqueID(), b.UniqueID()
+	return bytes.Compare(aID[:], bID[:])
+}
@@ -0,0 +1,62 @@
+package horizon
+
+import (
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortReceipts_DeterministicAggregation(t *testing.T) {
+	domain := NewDomain(1, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+
+	senderKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	aggregatorKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	newReceipt := func(timestampNs, nonce uint64, value int64) *SignedReceipt {
+		signed, err := Sign(domain, &Receipt{
+			Payer:           senderKey.PublicKey().Address(),
+			DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+			ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+			TimestampNs:     timestampNs,
+			Nonce:           nonce,
+			Value:           big.NewInt(value),
+		}, senderKey)
+		require.NoError(t, err)
+		return signed
+	}
+
+	// Two receipts share timestamp and nonce, the signature orders them
+	receipts := []*SignedReceipt{newReceipt(30, 1, 100), newReceipt(10, 2, 100), newReceipt(20, 1, 100), newReceipt(10, 1, 100), newReceipt(20, 1, 200)}
+	reversed := slices.Clone(receipts)
+	slices.Reverse(reversed)
+
+	sorted := slices.Clone(receipts)
+	SortReceipts(sorted)
+	for i, want := range [][2]uint64{{10, 1}, {10, 2}, {20, 1}, {20, 1}, {30, 1}} {
+		assert.Equal(t, want, [2]uint64{sorted[i].Message.TimestampNs, sorted[i].Message.Nonce})
+	}
+	sortedReversed := slices.Clone(reversed)
+	SortReceipts(sortedReversed)
+	assert.Equal(t, sorted, sortedReversed)
+
+	// Aggregating the same set in any order commits to the same receipts root
+	// and records the same digests
+	aggregator := NewAggregator(domain, aggregatorKey, []eth.Address{senderKey.PublicKey().Address()}, WithReceiptsMerkleRoot())
+	rav, record, err := aggregator.AggregateReceiptsWithRecord(receipts, nil)
+	require.NoError(t, err)
+	ravReversed, recordReversed, err := aggregator.AggregateReceiptsWithRecord(reversed, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, rav.Message, ravReversed.Message)
+	assert.Equal(t, record, recordReversed)
+	assert.Equal(t, ReceiptOrdering, record.ReceiptOrdering)
+	require.NoError(t, record.Verify(domain, reversed, nil, rav))
+
+	assert.Equal(t, uint64(30), receipts[0].Message.TimestampNs, "the caller's slice is left untouched")
+}
@@ -0,0 +1,193 @@
+package horizon
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/streamingfast/eth-go"
+)
+
+// ErrReceiptMissing is returned when a receipt or its message is nil
+var ErrReceiptMissing = errors.New("receipt is missing")
+
+// ReceiptPool buffers the signed receipts of a collection between aggregation
+// rounds. Receipts are validated as they are added (accepted signer, pool
+// collection, newer than the last checkpoint) and deduplicated by normalized
+// signature and by digest, so a round aggregates each receipt once whatever the number of
+// times it was sent.
+//
+// A round drains the pool with Checkpoint, passing the last RAV of the
+// collection, and aggregates the returned receipts on top of it:
+//
+//	receipts, err := pool.Checkpoint(lastRAV)
+//	...
+//	rav, err := aggregator.AggregateReceipts(receipts, lastRAV)
+//	if err != nil {
+//		pool.Restore(receipts)
+//	}
+//
+// Drained receipts keep being deduplicated until a later checkpoint covers
+// them, so a receipt resent while its round is in flight is not aggregated
+// twice.
+//
+// A ReceiptPool is safe for concurrent use.
+type ReceiptPool struct {
+	domain          *Domain
+	collectionID    CollectionID
+	acceptedSigners map[string]bool
+
+	mu           sync.Mutex
+	receipts     map[[65]byte]*pooledReceipt
+	digests      map[string]bool
+	pending      int
+	checkpointNs uint64
+}
+
+type pooledReceipt struct {
+	receipt *SignedReceipt
+	digest  []byte
+	pending bool
+}
+
+// NewReceiptPool creates a pool of the receipts of collectionID signed by one
+// of acceptedSigners
+func NewReceiptPool(domain *Domain, collectionID CollectionID, acceptedSigners []eth.Address) *ReceiptPool {
+	signerMap := make(map[string]bool, len(acceptedSigners))
+	for _, addr := range acceptedSigners {
+		signerMap[addr.Pretty()] = true
+	}
+
+	return &ReceiptPool{
+		domain:          domain,
+		collectionID:    collectionID,
+		acceptedSigners: signerMap,
+		receipts:        make(map[[65]byte]*pooledReceipt),
+		digests:         make(map[string]bool),
+	}
+}
+
+// Add validates receipt and buffers it until the next Checkpoint. It returns
+// false without error when the receipt is already pooled or drained by a
+// checkpoint not yet covered, and ErrDuplicateSignature when another receipt
+// was pooled under the same signature or the same receipt under another
+// signature, e.g. a malleated variant of it. Receipts not newer than the last
+// checkpoint are refused with ErrInvalidTimestamp, the previous RAV covering
+// them already.
+func (p *ReceiptPool) Add(receipt *SignedReceipt) (bool, error) {
+	if receipt == nil || receipt.Message == nil {
+		return false, ErrReceiptMissing
+	}
+	if receipt.Message.CollectionID != p.collectionID {
+		return false, ErrCollectionMismatch
+	}
+
+	// Recovering the signer is the costly part, it runs outside of the lock so
+	// concurrent additions do not wait on each other
+	digest, err := HashTypedData(p.domain, receipt.Message)
+	if err != nil {
+		return false, fmt.Errorf("computing receipt digest: %w", err)
+	}
+	signer, err := receipt.Signature.Recover(digest)
+	if err != nil {
+		return false, fmt.Errorf("recovering signer: %w", err)
+	}
+	if !p.acceptedSigners[signer.Pretty()] {
+		return false, ErrInvalidSigner
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if receipt.Message.TimestampNs <= p.checkpointNs {
+		return false, ErrInvalidTimestamp
+	}
+
+	normalized := normalizeSignature(receipt.Signature)
+	if pooled, found := p.receipts[normalized]; found {
+		if pooled.receipt.Signature != receipt.Signature || !bytes.Equal(pooled.digest, digest) {
+			return false, ErrDuplicateSignature
+		}
+		return false, nil
+	}
+	if p.digests[string(digest)] {
+		return false, ErrDuplicateSignature
+	}
+
+	p.receipts[normalized] = &pooledReceipt{receipt: receipt, digest: digest, pending: true}
+	p.digests[string(digest)] = true
+	p.pending++
+	return true, nil
+}
+
+// Checkpoint drains the receipts newer than lastRAV, the last RAV of the
+// collection or nil before the first one, and returns them in aggregation
+// order (see SortReceipts). Receipts lastRAV covers are forgotten and refused
+// by later additions. The checkpoint never moves back, an older lastRAV than
+// a previous one only drains the pending receipts.
+func (p *ReceiptPool) Checkpoint(lastRAV *SignedRAV) ([]*SignedReceipt, error) {
+	if lastRAV != nil {
+		if lastRAV.Message == nil {
+			return nil, ErrRAVMissing
+		}
+		if lastRAV.Message.CollectionID != p.collectionID {
+			return nil, ErrCollectionMismatch
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if lastRAV != nil && lastRAV.Message.TimestampNs > p.checkpointNs {
+		p.checkpointNs = lastRAV.Message.TimestampNs
+	}
+
+	drained := make([]*SignedReceipt, 0, p.pending)
+	for normalized, pooled := range p.receipts {
+		if pooled.receipt.Message.TimestampNs <= p.checkpointNs {
+			if pooled.pending {
+				p.pending--
+			}
+			delete(p.receipts, normalized)
+			delete(p.digests, string(pooled.digest))
+			continue
+		}
+		if pooled.pending {
+			pooled.pending = false
+			p.pending--
+			drained = append(drained, pooled.receipt)
+		}
+	}
+
+	SortReceipts(drained)
+	return drained, nil
+}
+
+// Restore puts back receipts drained by Checkpoint, e.g. when their
+// aggregation failed, so the next checkpoint returns them again. Receipts not
+// drained from this pool, or covered by a checkpoint since, are ignored.
+func (p *ReceiptPool) Restore(receipts []*SignedReceipt) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, receipt := range receipts {
+		if receipt == nil {
+			continue
+		}
+		pooled, found := p.receipts[normalizeSignature(receipt.Signature)]
+		if !found || pooled.pending || pooled.receipt != receipt {
+			continue
+		}
+		pooled.pending = true
+		p.pending++
+	}
+}
+
+// Len returns the number of receipts the next Checkpoint would drain, at most
+func (p *ReceiptPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.pending
+}
@@ -0,0 +1,126 @@
+package horizon
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceiptPool(t *testing.T) {
+	domain := NewDomain(1337, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+	key, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	receipts := newSignedReceiptBatch(t, domain, key, 6)
+
+	pool := NewReceiptPool(domain, CollectionID{}, []eth.Address{key.PublicKey().Address()})
+
+	// Receipts are added concurrently, each one several times
+	var wg sync.WaitGroup
+	for range 3 {
+		for _, receipt := range receipts[:4] {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := pool.Add(receipt)
+				assert.NoError(t, err)
+			}()
+		}
+	}
+	wg.Wait()
+	assert.Equal(t, 4, pool.Len())
+
+	drained, err := pool.Checkpoint(nil)
+	require.NoError(t, err)
+	require.Len(t, drained, 4)
+	for i, receipt := range drained {
+		assert.Same(t, receipts[i], receipt)
+	}
+	assert.Equal(t, 0, pool.Len())
+
+	// Drained receipts are still deduplicated while their round is in flight
+	added, err := pool.Add(receipts[1])
+	require.NoError(t, err)
+	assert.False(t, added)
+
+	// A failed round puts its receipts back
+	pool.Restore(drained[2:])
+	assert.Equal(t, 2, pool.Len())
+
+	added, err = pool.Add(receipts[4])
+	require.NoError(t, err)
+	assert.True(t, added)
+	added, err = pool.Add(receipts[5])
+	require.NoError(t, err)
+	assert.True(t, added)
+
+	// The RAV aggregating the first two receipts covers them, only newer ones
+	// are drained
+	aggregator := NewAggregator(domain, key, []eth.Address{key.PublicKey().Address()})
+	lastRAV, err := aggregator.AggregateReceipts(drained[:2], nil)
+	require.NoError(t, err)
+
+	drained, err = pool.Checkpoint(lastRAV)
+	require.NoError(t, err)
+	require.Len(t, drained, 4)
+	assert.Same(t, receipts[2], drained[0])
+	assert.Same(t, receipts[5], drained[3])
+
+	rav, err := aggregator.AggregateReceipts(drained, lastRAV)
+	require.NoError(t, err)
+	assert.Equal(t, "600", rav.Message.ValueAggregate.String())
+
+	_, err = pool.Add(receipts[0])
+	assert.ErrorIs(t, err, ErrInvalidTimestamp)
+}
+
+func TestReceiptPool_Validation(t *testing.T) {
+	domain := NewDomain(1337, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+	key, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	otherKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	pool := NewReceiptPool(domain, CollectionID{}, []eth.Address{key.PublicKey().Address()})
+	receipt := newSignedReceiptBatch(t, domain, key, 1)[0]
+
+	_, err = pool.Add(nil)
+	assert.ErrorIs(t, err, ErrReceiptMissing)
+
+	_, err = pool.Add(newSignedReceiptBatch(t, domain, otherKey, 1)[0])
+	assert.ErrorIs(t, err, ErrInvalidSigner)
+
+	otherCollection := *receipt.Message
+	otherCollection.CollectionID = CollectionID{0x01}
+	signed, err := Sign(domain, &otherCollection, key)
+	require.NoError(t, err)
+	_, err = pool.Add(signed)
+	assert.ErrorIs(t, err, ErrCollectionMismatch)
+
+	added, err := pool.Add(receipt)
+	require.NoError(t, err)
+	assert.True(t, added)
+
+	// Same receipt under the high-S form of its signature, V being 27 or 28
+	malleated := &SignedReceipt{Message: receipt.Message, Signature: receipt.Signature}
+	malleated.Signature[0] = 55 - receipt.Signature[0]
+	new(big.Int).Sub(secp256k1N, receipt.Signature.S()).FillBytes(malleated.Signature[33:65])
+	signer, err := malleated.RecoverSigner(domain)
+	require.NoError(t, err)
+	require.True(t, addressesEqual(key.PublicKey().Address(), signer))
+	_, err = pool.Add(malleated)
+	assert.ErrorIs(t, err, ErrDuplicateSignature)
+
+	// Another receipt under the same signature
+	forged := *receipt.Message
+	forged.Value = big.NewInt(1_000_000)
+	_, err = pool.Add(&SignedReceipt{Message: &forged, Signature: receipt.Signature})
+	assert.Error(t, err)
+
+	_, err = pool.Checkpoint(&SignedRAV{Message: &RAV{CollectionID: CollectionID{0x01}}})
+	assert.ErrorIs(t, err, ErrCollectionMismatch)
+	assert.Equal(t, 1, pool.Len())
+}
@@ -0,0 +1,95 @@
+package horizon
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/streamingfast/eth-go/rpc"
+)
+
+// DefaultRemoteSignerTimeout bounds how long a RemoteSigner waits for a
+// signature
+const DefaultRemoteSignerTimeout = 10 * time.Second
+
+// RemoteSigner signs through the eth_signTypedData_v4 JSON-RPC method of a
+// signing backend holding the key of Address, e.g. Web3Signer or Clef in front
+// of an HSM or a cloud KMS. Each signature is checked to recover to Address
+// before being returned.
+type RemoteSigner struct {
+	client  *rpc.Client
+	address eth.Address
+	timeout time.Duration
+}
+
+// NewRemoteSigner returns a Signer signing for address through the JSON-RPC
+// endpoint, DefaultRemoteSignerTimeout is used when timeout is zero
+func NewRemoteSigner(endpoint string, address eth.Address, timeout time.Duration, opts ...rpc.Option) *RemoteSigner {
+	if timeout <= 0 {
+		timeout = DefaultRemoteSignerTimeout
+	}
+
+	return &RemoteSigner{
+		client:  rpc.NewClient(endpoint, opts...),
+		address: address,
+		timeout: timeout,
+	}
+}
+
+func (s *RemoteSigner) Address() eth.Address {
+	return s.address
+}
+
+func (s *RemoteSigner) SignTypedData(domain *Domain, message EIP712Encodable) (eth.Signature, error) {
+	typedData, err := NewTypedData(domain, message)
+	if err != nil {
+		return eth.Signature{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	result, err := rpc.Do[string](s.client, ctx, "eth_signTypedData_v4", []any{s.address.Pretty(), typedData})
+	if err != nil {
+		return eth.Signature{}, fmt.Errorf("remote signer: %w", err)
+	}
+
+	sig, err := parseRemoteSignature(result)
+	if err != nil {
+		return eth.Signature{}, fmt.Errorf("remote signer: %w", err)
+	}
+
+	messageHash, err := HashTypedData(domain, message)
+	if err != nil {
+		return eth.Signature{}, fmt.Errorf("computing typed data hash: %w", err)
+	}
+
+	signer, err := sig.Recover(messageHash)
+	if err != nil {
+		return eth.Signature{}, fmt.Errorf("remote signer: recovering signer: %w", err)
+	}
+	if !bytes.Equal(signer, s.address) {
+		return eth.Signature{}, fmt.Errorf("remote signer: signature recovers to %s, expected %s", signer.Pretty(), s.address.Pretty())
+	}
+
+	return sig, nil
+}
+
+// parseRemoteSignature parses the hex R+S+V signature returned by
+// eth_signTypedData_v4, or its EIP-2098 compact form
+func parseRemoteSignature(in string) (eth.Signature, error) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(in, "0x"))
+	if err != nil {
+		return eth.Signature{}, fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+
+	if len(raw) == len(eth.Signature{}) {
+		sig := signatureFromRSV(raw)
+		return ParseSignature(sig[:])
+	}
+	return ParseSignature(raw)
+}
@@ -0,0 +1,106 @@
+package horizon
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// remoteSigningBackend serves eth_signTypedData_v4, signing every request with
+// key and returning the signature in R+S+V form
+func remoteSigningBackend(t *testing.T, key *eth.PrivateKey, domain *Domain, message EIP712Encodable) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     any               `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "eth_signTypedData_v4", req.Method)
+		require.Len(t, req.Params, 2)
+
+		var typedData TypedData
+		require.NoError(t, json.Unmarshal(req.Params[1], &typedData))
+		assert.Equal(t, "ReceiptAggregateVoucher", typedData.PrimaryType)
+		assert.Equal(t, "1337", typedData.Domain["chainId"])
+		assert.Equal(t, "1000", typedData.Message["valueAggregate"])
+
+		hash, err := HashTypedData(domain, message)
+		require.NoError(t, err)
+		sig, err := key.Sign(hash)
+		require.NoError(t, err)
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+			"result":  "0x" + hex.EncodeToString(signatureToRSV(sig)),
+		})
+	}))
+}
+
+func TestRemoteSigner(t *testing.T) {
+	domain := NewDomain(1337, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+	key, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	rav := &RAV{
+		Payer:           key.PublicKey().Address(),
+		ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+		TimestampNs:     1234567890,
+		ValueAggregate:  big.NewInt(1000),
+		Metadata:        []byte{0x01},
+	}
+
+	backend := remoteSigningBackend(t, key, domain, rav)
+	defer backend.Close()
+
+	signer := NewRemoteSigner(backend.URL, key.PublicKey().Address(), time.Second)
+	signed, err := SignWith(domain, rav, signer)
+	require.NoError(t, err)
+
+	local, err := Sign(domain, rav, key)
+	require.NoError(t, err)
+	assert.Equal(t, local.Signature, signed.Signature)
+
+	// A backend signing with another key than the expected one is refused
+	other, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	_, err = NewRemoteSigner(backend.URL, other.PublicKey().Address(), time.Second).SignTypedData(domain, rav)
+	assert.ErrorContains(t, err, "signature recovers to")
+}
+
+func TestLocalSigner(t *testing.T) {
+	domain := NewDomain(1337, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+	key, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	signer := NewLocalSigner(key)
+	assert.Equal(t, key.PublicKey().Address(), signer.Address())
+
+	rav := &RAV{ValueAggregate: big.NewInt(1)}
+	signed, err := SignWith(domain, rav, signer)
+	require.NoError(t, err)
+
+	recovered, err := signed.RecoverSigner(domain)
+	require.NoError(t, err)
+	assert.Equal(t, key.PublicKey().Address(), recovered)
+}
+
+type unknownMessage struct{}
+
+func (unknownMessage) EIP712TypeHash() eth.Hash { return eth.Hash{} }
+func (unknownMessage) EIP712EncodeData() []byte { return nil }
+
+func TestNewTypedData_UnsupportedMessage(t *testing.T) {
+	_, err := NewTypedData(NewDomain(1337, eth.Address{}), unknownMessage{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,138 @@
+package horizon
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/streamingfast/eth-go"
+)
+
+// revertErrors are the custom errors of the Horizon contracts involved in
+// collecting a RAV (SubstreamsDataService, GraphTallyCollector, PaymentsEscrow,
+// GraphPayments), keyed by selector
+var revertErrors = mustNewRevertErrors(
+	"Error(string)",
+	"Panic(uint256)",
+
+	"ProvisionManagerInvalidRange(uint256,uint256)",
+	"ProvisionManagerInvalidValue(bytes,uint256,uint256,uint256)",
+	"ProvisionManagerNotAuthorized(address,address)",
+	"ProvisionManagerProvisionNotFound(address)",
+	"SubstreamsDataServiceIndexerMismatch(address,address)",
+	"SubstreamsDataServiceIndexerNotRegistered(address)",
+	"SubstreamsDataServiceInvalidPaymentType(uint8)",
+
+	"AuthorizableSignerNotAuthorized(address,address)",
+	"ECDSAInvalidSignature()",
+	"ECDSAInvalidSignatureLength(uint256)",
+	"ECDSAInvalidSignatureS(bytes32)",
+	"GraphTallyCollectorCallerNotDataService(address,address)",
+	"GraphTallyCollectorInconsistentRAVTokens(uint256,uint256)",
+	"GraphTallyCollectorInvalidRAVSigner()",
+	"GraphTallyCollectorInvalidTokensToCollectAmount(uint256,uint256)",
+	"GraphTallyCollectorUnauthorizedDataService(address)",
+
+	"PaymentsEscrowInconsistentCollection(uint256,uint256,uint256)",
+	"PaymentsEscrowInsufficientBalance(uint256,uint256)",
+	"PaymentsEscrowInvalidZeroTokens()",
+	"PaymentsEscrowIsPaused()",
+
+	"GraphPaymentsInvalidCut(uint256)",
+	"GraphPaymentsInvalidProtocolPaymentCut(uint256)",
+	"PPMMathInvalidMulPPM(uint256,uint256)",
+	"PPMMathInvalidPPM(uint256)",
+)
+
+type revertError struct {
+	name   string
+	inputs []string
+}
+
+func mustNewRevertErrors(signatures ...string) map[string]*revertError {
+	out := make(map[string]*revertError, len(signatures))
+	for _, signature := range signatures {
+		name, args, _ := strings.Cut(strings.TrimSuffix(signature, ")"), "(")
+
+		var inputs []string
+		if args != "" {
+			inputs = strings.Split(args, ",")
+		}
+
+		selector := eth.Keccak256([]byte(signature))[:4]
+		out[string(selector)] = &revertError{name: name, inputs: inputs}
+	}
+	return out
+}
+
+// DecodeRevert renders the revert data returned by a failed call to a Horizon
+// contract as the custom error name and its arguments, e.g.
+// "PaymentsEscrowInsufficientBalance(1000, 2000)". Error(string) reverts are
+// rendered as their message. Unknown errors are rendered as raw hex.
+func DecodeRevert(data []byte) string {
+	if len(data) < 4 {
+		if len(data) == 0 {
+			return "reverted without data"
+		}
+		return "unknown revert " + eth.Hex(data).Pretty()
+	}
+
+	revert, found := revertErrors[string(data[:4])]
+	if !found {
+		return "unknown revert " + eth.Hex(data).Pretty()
+	}
+
+	args := data[4:]
+	if revert.name == "Error" {
+		if message, ok := decodeRevertString(args); ok {
+			return message
+		}
+	}
+
+	rendered := make([]string, 0, len(revert.inputs))
+	for i, input := range revert.inputs {
+		if len(args) < (i+1)*32 {
+			return revert.name + "(…)"
+		}
+		word := args[i*32 : (i+1)*32]
+
+		switch {
+		case input == "address":
+			rendered = append(rendered, eth.Address(word[12:]).Pretty())
+		case input == "bytes32":
+			rendered = append(rendered, eth.Hash(word).Pretty())
+		case strings.HasPrefix(input, "uint"):
+			rendered = append(rendered, new(big.Int).SetBytes(word).String())
+		default:
+			// Dynamic types are not rendered, their word is an offset
+			rendered = append(rendered, input)
+		}
+	}
+
+	return fmt.Sprintf("%s(%s)", revert.name, strings.Join(rendered, ", "))
+}
+
+// RevertName returns the custom error name of the revert data, empty when it
+// is not a known Horizon error
+func RevertName(data []byte) string {
+	if len(data) < 4 {
+		return ""
+	}
+	if revert, found := revertErrors[string(data[:4])]; found {
+		return revert.name
+	}
+	return ""
+}
+
+func decodeRevertString(args []byte) (string, bool) {
+	if len(args) < 64 {
+		return "", false
+	}
+
+	length := new(big.Int).SetBytes(args[32:64])
+	if !length.IsUint64() || uint64(len(args)-64) < length.Uint64() {
+		return "", false
+	}
+	return string(bytes.TrimRight(args[64:64+length.Uint64()], "\x00")), true
+}
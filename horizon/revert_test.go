@@ -0,0 +1,53 @@
+package horizon
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeRevert(t *testing.T) {
+	selector := func(signature string) string {
+		return hex.EncodeToString(eth.Keccak256([]byte(signature))[:4])
+	}
+
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+		revName  string
+	}{
+		{"empty", "", "reverted without data", ""},
+		{
+			"custom error with arguments",
+			selector("SubstreamsDataServiceIndexerMismatch(address,address)") +
+				"0000000000000000000000001111111111111111111111111111111111111111" +
+				"0000000000000000000000002222222222222222222222222222222222222222",
+			"SubstreamsDataServiceIndexerMismatch(0x1111111111111111111111111111111111111111, 0x2222222222222222222222222222222222222222)",
+			"SubstreamsDataServiceIndexerMismatch",
+		},
+		{"custom error without arguments", selector("GraphTallyCollectorInvalidRAVSigner()"), "GraphTallyCollectorInvalidRAVSigner()", "GraphTallyCollectorInvalidRAVSigner"},
+		{
+			"error string",
+			selector("Error(string)") +
+				"0000000000000000000000000000000000000000000000000000000000000020" +
+				"0000000000000000000000000000000000000000000000000000000000000004" +
+				"6f6f707300000000000000000000000000000000000000000000000000000000",
+			"oops",
+			"Error",
+		},
+		{"truncated arguments", selector("PaymentsEscrowInsufficientBalance(uint256,uint256)") + "00", "PaymentsEscrowInsufficientBalance(…)", "PaymentsEscrowInsufficientBalance"},
+		{"unknown", "deadbeef", "unknown revert 0xdeadbeef", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := hex.DecodeString(tt.data)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, DecodeRevert(data))
+			assert.Equal(t, tt.revName, RevertName(data))
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package horizon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Errors returned when decoding a persisted structure of an unknown version
+var (
+	ErrSchemaVersionTooNew    = errors.New("schema version is newer than supported")
+	ErrSchemaMigrationMissing = errors.New("no migration from schema version")
+	ErrSchemaVersionInvalid   = errors.New("invalid schema version")
+)
+
+// SchemaMigration upgrades a JSON document, decoded as its top-level fields, by
+// exactly one schema version. It may add, rename, remove or rewrite fields.
+type SchemaMigration func(fields map[string]json.RawMessage) error
+
+// CanonicalJSONMigration upgrades a structure embedding receipts or RAVs to
+// the version encoding them in their canonical JSON form (0x-prefixed addresses
+// and signatures, decimal values). Their previous encoding still decodes, so
+// documents are left unchanged: the version bump only makes older releases
+// refuse the new encoding instead of misreading it.
+func CanonicalJSONMigration(fields map[string]json.RawMessage) error {
+	return nil
+}
+
+// Schema versions a JSON structure persisted or exchanged by the sidecars and
+// tools (session exports, RAV archives, aggregation records, ...). The version
+// is stored in a top-level field of the document. Documents written before
+// versions were introduced have no such field and are version 1.
+//
+// When the format of a structure changes, its schema version is bumped and a
+// migration from the previous version is registered, so documents written by
+// older releases are upgraded when read instead of being misread. Documents
+// written by a newer release are refused with ErrSchemaVersionTooNew.
+type Schema struct {
+	name    string
+	field   string
+	version int
+
+	mu         sync.RWMutex
+	migrations map[int]SchemaMigration
+}
+
+// NewSchema creates the schema of the structure name, whose current version
+// is stored in field
+func NewSchema(name, field string, version int) *Schema {
+	return &Schema{name: name, field: field, version: version, migrations: make(map[int]SchemaMigration)}
+}
+
+// Name returns the name of the structure
+func (s *Schema) Name() string {
+	return s.name
+}
+
+// Version returns the current version of the structure
+func (s *Schema) Version() int {
+	return s.version
+}
+
+// RegisterMigration registers the migration of documents from version from to
+// version from+1
+func (s *Schema) RegisterMigration(from int, migration SchemaMigration) {
+	if from < 1 || from >= s.version {
+		panic(fmt.Sprintf("%s schema: cannot register a migration from version %d, current version is %d", s.name, from, s.version))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.migrations[from] = migration
+}
+
+// Migrate upgrades the document data to the current version, returning it
+// unchanged when already current. The version field of the result is always
+// set.
+func (s *Schema) Migrate(data []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", s.name, err)
+	}
+	if fields == nil {
+		return nil, fmt.Errorf("decoding %s: not a JSON object", s.name)
+	}
+
+	version := 1
+	raw, found := fields[s.field]
+	if found {
+		if err := json.Unmarshal(raw, &version); err != nil || version < 1 {
+			return nil, fmt.Errorf("%s: %w %s", s.name, ErrSchemaVersionInvalid, raw)
+		}
+	}
+
+	if found && version == s.version {
+		return data, nil
+	}
+	if version > s.version {
+		return nil, fmt.Errorf("%s: %w, version %d but at most %d is supported, upgrade to read it", s.name, ErrSchemaVersionTooNew, version, s.version)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for ; version < s.version; version++ {
+		migration, found := s.migrations[version]
+		if !found {
+			return nil, fmt.Errorf("%s: %w %d to %d", s.name, ErrSchemaMigrationMissing, version, version+1)
+		}
+		if err := migration(fields); err != nil {
+			return nil, fmt.Errorf("migrating %s from version %d to %d: %w", s.name, version, version+1, err)
+		}
+	}
+
+	fields[s.field] = json.RawMessage(fmt.Sprintf("%d", s.version))
+	return json.Marshal(fields)
+}
+
+// Unmarshal migrates data to the current version then decodes it into v
+func (s *Schema) Unmarshal(data []byte, v any) error {
+	migrated, err := s.Migrate(data)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(migrated, v); err != nil {
+		return fmt.Errorf("decoding %s: %w", s.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package horizon
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchema_Migrate(t *testing.T) {
+	type record struct {
+		Version int    `json:"version"`
+		Name    string `json:"name"`
+		Count   int    `json:"count"`
+	}
+
+	schema := NewSchema("test record", "version", 3)
+	// Version 2 renamed "label" to "name"
+	schema.RegisterMigration(1, func(fields map[string]json.RawMessage) error {
+		fields["name"] = fields["label"]
+		delete(fields, "label")
+		return nil
+	})
+	// Version 3 introduced "count", defaulting to 1
+	schema.RegisterMigration(2, func(fields map[string]json.RawMessage) error {
+		fields["count"] = json.RawMessage("1")
+		return nil
+	})
+
+	var out record
+	require.NoError(t, schema.Unmarshal([]byte(`{"version":3,"name":"current","count":5}`), &out))
+	assert.Equal(t, record{Version: 3, Name: "current", Count: 5}, out)
+
+	// Documents without a version predate versioning and are version 1
+	out = record{}
+	require.NoError(t, schema.Unmarshal([]byte(`{"label":"legacy"}`), &out))
+	assert.Equal(t, record{Version: 3, Name: "legacy", Count: 1}, out)
+
+	out = record{}
+	require.NoError(t, schema.Unmarshal([]byte(`{"version":2,"name":"older"}`), &out))
+	assert.Equal(t, record{Version: 3, Name: "older", Count: 1}, out)
+
+	err := schema.Unmarshal([]byte(`{"version":4,"name":"newer"}`), &out)
+	assert.ErrorIs(t, err, ErrSchemaVersionTooNew)
+
+	err = schema.Unmarshal([]byte(`{"version":0}`), &out)
+	assert.ErrorIs(t, err, ErrSchemaVersionInvalid)
+
+	err = schema.Unmarshal([]byte(`[1, 2]`), &out)
+	assert.Error(t, err)
+
+	err = NewSchema("test record", "version", 2).Unmarshal([]byte(`{"version":1}`), &out)
+	assert.ErrorIs(t, err, ErrSchemaMigrationMissing)
+
+	assert.Panics(t, func() { schema.RegisterMigration(3, nil) })
+}
+
+func TestSchema_PersistedStructures(t *testing.T) {
+	// Structures persisted before schema versions were introduced still decode
+	var record AggregationRecord
+	require.NoError(t, json.Unmarshal([]byte(`{"rav_digest":"0x01","receipt_digests":[],"value_aggregate":10}`), &record))
+	assert.Equal(t, AggregationRecordSchema.Version(), record.SchemaVersion)
+	assert.Equal(t, 0, record.ReceiptOrdering, "receipts of records predating the ordering rule are in the given order")
+	assert.Equal(t, int64(10), record.ValueAggregate.Int64())
+
+	// Version 1 requests encode their RAV with unprefixed addresses, a numeric
+	// value and base64 metadata
+	var request OfflineSigningRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"id":"abc","rav":{"payer":"1111111111111111111111111111111111111111","valueAggregate":1000,"metadata":"yv4="}}`), &request))
+	assert.Equal(t, OfflineSigningRequestSchema.Version(), request.SchemaVersion)
+	assert.Equal(t, "abc", request.ID)
+	assert.Equal(t, "0x1111111111111111111111111111111111111111", request.RAV.Payer.Pretty())
+	assert.Equal(t, "1000", request.RAV.ValueAggregate.String())
+	assert.Equal(t, []byte{0xca, 0xfe}, request.RAV.Metadata)
+
+	var response OfflineSigningResponse
+	err := json.Unmarshal([]byte(`{"schemaVersion":99,"id":"abc"}`), &response)
+	assert.ErrorIs(t, err, ErrSchemaVersionTooNew)
+}
@@ -0,0 +1,103 @@
+package horizon
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/streamingfast/eth-go"
+)
+
+// DigestSigner signs EIP-712 digests, *eth.PrivateKey implements it and remote
+// or hardware key backends can too
+type DigestSigner interface {
+	Sign(digest eth.Hash) (eth.Signature, error)
+}
+
+// ConcurrentDigestSigner is a DigestSigner able to sign several digests at
+// once, SignBatch then spreads a batch over SignConcurrency workers
+type ConcurrentDigestSigner interface {
+	DigestSigner
+	// SignConcurrency is the number of digests that may be signed at once
+	SignConcurrency() int
+}
+
+// parallelKey signs with a local private key on several CPUs, signing only
+// reads the key so concurrent signatures are safe
+type parallelKey struct {
+	*eth.PrivateKey
+	concurrency int
+}
+
+func (k *parallelKey) SignConcurrency() int {
+	return k.concurrency
+}
+
+// ParallelKey returns a signer using key on up to concurrency CPUs at once,
+// all of them (GOMAXPROCS) when concurrency is zero or negative
+func ParallelKey(key *eth.PrivateKey, concurrency int) ConcurrentDigestSigner {
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	return &parallelKey{PrivateKey: key, concurrency: concurrency}
+}
+
+// SignBatch signs messages under domain, e.g. the receipts a gateway issues,
+// returning them signed in the same order. It is equivalent to calling Sign
+// on each message but computes the domain separator once and, when signer is
+// a ConcurrentDigestSigner (see ParallelKey), signs concurrently. The whole
+// batch fails when any message cannot be signed.
+func SignBatch[T EIP712Encodable](domain *Domain, messages []T, signer DigestSigner) ([]*SignedMessage[T], error) {
+	domainSep := domain.Separator()
+	out := make([]*SignedMessage[T], len(messages))
+
+	sign := func(i int) error {
+		sig, err := signer.Sign(hashTypedData(domainSep, messages[i]))
+		if err != nil {
+			return fmt.Errorf("signing message %d: %w", i, err)
+		}
+		out[i] = &SignedMessage[T]{Message: messages[i], Signature: sig}
+		return nil
+	}
+
+	concurrency := 1
+	if concurrent, ok := signer.(ConcurrentDigestSigner); ok {
+		concurrency = min(concurrent.SignConcurrency(), len(messages))
+	}
+
+	if concurrency <= 1 {
+		for i := range messages {
+			if err := sign(i); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+
+	indexes := make(chan int)
+	errs := make([]error, concurrency)
+	var wg sync.WaitGroup
+	for worker := range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if errs[worker] == nil {
+					errs[worker] = sign(i)
+				}
+			}
+		}()
+	}
+	for i := range messages {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,72 @@
+package horizon
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingSigner struct {
+	*eth.PrivateKey
+	calls int
+}
+
+func (s *failingSigner) Sign(digest eth.Hash) (eth.Signature, error) {
+	s.calls++
+	if s.calls == 3 {
+		return eth.Signature{}, errors.New("signer unavailable")
+	}
+	return s.PrivateKey.Sign(digest)
+}
+
+func TestSignBatch(t *testing.T) {
+	domain := NewDomain(1337, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+	key, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	receipts := make([]*Receipt, 50)
+	for i := range receipts {
+		receipts[i] = &Receipt{
+			Payer:           key.PublicKey().Address(),
+			DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+			ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+			TimestampNs:     1234567890,
+			Nonce:           uint64(i),
+			Value:           big.NewInt(int64(1000 + i)),
+		}
+	}
+
+	for name, signer := range map[string]DigestSigner{
+		"sequential": key,
+		"parallel":   ParallelKey(key, 4),
+	} {
+		t.Run(name, func(t *testing.T) {
+			signed, err := SignBatch(domain, receipts, signer)
+			require.NoError(t, err)
+			require.Len(t, signed, len(receipts))
+
+			for i, receipt := range signed {
+				assert.Same(t, receipts[i], receipt.Message)
+
+				single, err := Sign(domain, receipts[i], key)
+				require.NoError(t, err)
+				assert.Equal(t, single.Signature, receipt.Signature)
+
+				recovered, err := receipt.RecoverSigner(domain)
+				require.NoError(t, err)
+				assert.Equal(t, key.PublicKey().Address(), recovered)
+			}
+		})
+	}
+
+	empty, err := SignBatch(domain, []*Receipt{}, ParallelKey(key, 0))
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	_, err = SignBatch(domain, receipts, &failingSigner{PrivateKey: key})
+	assert.ErrorContains(t, err, "signing message 2: signer unavailable")
+}
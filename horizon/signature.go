@@ -1,6 +1,8 @@
 package horizon
 
 import (
+	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/streamingfast/eth-go"
@@ -11,6 +13,13 @@ var secp256k1N, _ = new(big.Int).SetString(
 	"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141", 16)
 var secp256k1HalfN = new(big.Int).Rsh(secp256k1N, 1)
 
+// CompactSignatureLength is the length of an EIP-2098 compact signature
+const CompactSignatureLength = 64
+
+// ErrInvalidSignature is returned when parsing a signature that is neither a
+// 65-byte nor a 64-byte EIP-2098 compact signature
+var ErrInvalidSignature = errors.New("invalid signature")
+
 // normalizeSignature returns signature in low-S canonical form
 // This prevents signature malleability attacks where the same message
 // can have two valid signatures that recover to the same address
@@ -43,3 +52,51 @@ func SignaturesEqual(a, b eth.Signature) bool {
 	normB := normalizeSignature(b)
 	return normA == normB
 }
+
+// ParseSignature parses the signature of a receipt or RAV as received on input.
+// Two forms are accepted:
+//   - 65 bytes laid out V+R+S (eth.Signature), V being 27/28 or the bare
+//     recovery ID 0/1 some tools produce
+//   - 64 bytes EIP-2098 compact R+YParityAndS, the recovery ID being the top
+//     bit of S
+//
+// The signature is returned in eth.Signature form with V normalized to 27/28,
+// signatureToRSV turns it into the canonical 65-byte R+S+V of contract calls.
+func ParseSignature(in []byte) (eth.Signature, error) {
+	var sig eth.Signature
+
+	switch len(in) {
+	case len(sig):
+		v, ok := recoveryV(in[0])
+		if !ok {
+			return eth.Signature{}, fmt.Errorf("%w: unknown recovery byte %d", ErrInvalidSignature, in[0])
+		}
+		copy(sig[:], in)
+		sig[0] = v
+
+	case CompactSignatureLength:
+		copy(sig[1:], in)
+		sig[0] = 27 + sig[33]>>7
+		sig[33] &= 0x7f
+
+	default:
+		return eth.Signature{}, fmt.Errorf("%w: expected %d or %d (EIP-2098 compact) bytes, got %d", ErrInvalidSignature, len(sig), CompactSignatureLength, len(in))
+	}
+
+	return sig, nil
+}
+
+// recoveryV maps the recovery byte of a signature to Ethereum's 27/28, it
+// accepts the bare recovery ID (0/1), Ethereum's 27/28 and the 31/32 btcec
+// uses for compressed public keys
+func recoveryV(v byte) (byte, bool) {
+	switch v {
+	case 0, 1:
+		return 27 + v, true
+	case 27, 28:
+		return v, true
+	case 31, 32:
+		return v - 4, true
+	}
+	return 0, false
+}
@@ -193,3 +193,52 @@ func TestUniqueID(t *testing.T) {
 	normalized := normalizeSignature(signed.Signature)
 	require.Equal(t, normalized, uniqueID)
 }
+
+func TestParseSignature(t *testing.T) {
+	domain := NewDomain(1, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+	key, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	rav := &RAV{
+		Payer:           key.PublicKey().Address(),
+		DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+		ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		TimestampNs:     1234567890,
+		ValueAggregate:  big.NewInt(1000),
+	}
+	signed, err := Sign(domain, rav, key)
+	require.NoError(t, err)
+	canonical := signatureToRSV(signed.Signature)
+
+	// EIP-2098: r || (yParity << 255 | s)
+	compact := make([]byte, CompactSignatureLength)
+	copy(compact, signed.Signature[1:])
+	compact[32] |= (signed.Signature[0] - 27) << 7
+
+	// Bare recovery ID as V
+	bareV := append([]byte{signed.Signature[0] - 27}, signed.Signature[1:]...)
+
+	for name, in := range map[string][]byte{
+		"65 bytes": signed.Signature[:],
+		"bare V":   bareV,
+		"compact":  compact,
+	} {
+		t.Run(name, func(t *testing.T) {
+			sig, err := ParseSignature(in)
+			require.NoError(t, err)
+			require.Equal(t, signed.Signature, sig)
+			require.Equal(t, canonical, signatureToRSV(sig))
+
+			recovered, err := (&SignedRAV{Message: rav, Signature: sig}).RecoverSigner(domain)
+			require.NoError(t, err)
+			require.Equal(t, key.PublicKey().Address(), recovered)
+		})
+	}
+
+	_, err = ParseSignature(make([]byte, 63))
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	invalidV := append([]byte{5}, signed.Signature[1:]...)
+	_, err = ParseSignature(invalidV)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+}
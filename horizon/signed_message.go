@@ -20,20 +20,7 @@ type SignedRAV = SignedMessage[*RAV]
 
 // Sign creates a signed message using the domain and private key
 func Sign[T EIP712Encodable](domain *Domain, message T, key *eth.PrivateKey) (*SignedMessage[T], error) {
-	messageHash, err := HashTypedData(domain, message)
-	if err != nil {
-		return nil, fmt.Errorf("computing typed data hash: %w", err)
-	}
-
-	sig, err := key.Sign(messageHash)
-	if err != nil {
-		return nil, fmt.Errorf("signing message: %w", err)
-	}
-
-	return &SignedMessage[T]{
-		Message:   message,
-		Signature: sig,
-	}, nil
+	return SignWith(domain, message, NewLocalSigner(key))
 }
 
 // RecoverSigner recovers the signer address from the signature
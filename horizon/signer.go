@@ -0,0 +1,55 @@
+package horizon
+
+import (
+	"fmt"
+
+	"github.com/streamingfast/eth-go"
+)
+
+// Signer signs EIP-712 typed data, receipts and RAVs, on behalf of Address.
+// LocalSigner signs with a private key held in memory, RemoteSigner delegates
+// to a signing backend so the key never reaches the process, e.g. a gateway
+// whose keys live in an HSM.
+type Signer interface {
+	// Address is the address whose key signs, the one recovered from signatures
+	Address() eth.Address
+	// SignTypedData signs message under domain
+	SignTypedData(domain *Domain, message EIP712Encodable) (eth.Signature, error)
+}
+
+// LocalSigner signs with a private key held in memory
+type LocalSigner struct {
+	key *eth.PrivateKey
+}
+
+// NewLocalSigner returns a Signer signing with key
+func NewLocalSigner(key *eth.PrivateKey) *LocalSigner {
+	return &LocalSigner{key: key}
+}
+
+func (s *LocalSigner) Address() eth.Address {
+	return s.key.PublicKey().Address()
+}
+
+func (s *LocalSigner) SignTypedData(domain *Domain, message EIP712Encodable) (eth.Signature, error) {
+	messageHash, err := HashTypedData(domain, message)
+	if err != nil {
+		return eth.Signature{}, fmt.Errorf("computing typed data hash: %w", err)
+	}
+
+	return s.key.Sign(messageHash)
+}
+
+// SignWith creates a signed message using the domain and signer, see Sign to
+// sign with a private key
+func SignWith[T EIP712Encodable](domain *Domain, message T, signer Signer) (*SignedMessage[T], error) {
+	sig, err := signer.SignTypedData(domain, message)
+	if err != nil {
+		return nil, fmt.Errorf("signing message: %w", err)
+	}
+
+	return &SignedMessage[T]{
+		Message:   message,
+		Signature: sig,
+	}, nil
+}
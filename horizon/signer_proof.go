@@ -0,0 +1,91 @@
+package horizon
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/streamingfast/eth-go"
+)
+
+// ErrInvalidSignerProof is returned when a signer proof does not recover to the
+// expected signer
+var ErrInvalidSignerProof = errors.New("invalid signer proof")
+
+// SignerProofMessageHash returns the message a signer signs (as an EIP-191
+// personal message) to prove it agrees to sign RAVs on behalf of authorizer,
+// as verified by GraphTallyCollector.authorizeSigner (Authorizable.sol):
+//
+//	bytes32 messageHash = keccak256(
+//	    abi.encodePacked(block.chainid, address(this), "authorizeSignerProof", _proofDeadline, msg.sender)
+//	);
+//	bytes32 digest = MessageHashUtils.toEthSignedMessageHash(messageHash);
+//	require(ECDSA.recover(digest, _proof) == _signer, AuthorizableInvalidSignerProof());
+//
+// The authorizer is the account sending the authorizeSigner transaction (the
+// payer), which may differ from the signer: the proof can be produced offline
+// and handed over.
+func SignerProofMessageHash(chainID uint64, collector eth.Address, proofDeadline uint64, authorizer eth.Address) eth.Hash {
+	// encodePacked: uint256 values are 32 bytes, addresses 20 bytes (no padding)
+	message := make([]byte, 0, 32+20+20+32+20)
+	message = append(message, uint256Bytes(chainID)...)
+	message = append(message, collector[:]...)
+	message = append(message, []byte("authorizeSignerProof")...)
+	message = append(message, uint256Bytes(proofDeadline)...)
+	message = append(message, authorizer[:]...)
+
+	return eth.Keccak256(message)
+}
+
+// NewSignerProof signs the signer proof for authorizer with signerKey, the
+// returned bytes are the `proof` argument of authorizeSigner
+func NewSignerProof(chainID uint64, collector eth.Address, proofDeadline uint64, authorizer eth.Address, signerKey *eth.PrivateKey) ([]byte, error) {
+	signature, err := signerKey.SignPersonal(eth.Hex(SignerProofMessageHash(chainID, collector, proofDeadline, authorizer)))
+	if err != nil {
+		return nil, fmt.Errorf("signing signer proof: %w", err)
+	}
+
+	// Solidity ECDSA.recover expects R + S + V, eth-go signatures are V + R + S
+	inverted := signature.ToInverted()
+	return inverted[:], nil
+}
+
+// RecoverSignerProof returns the signer of a proof produced by NewSignerProof
+// (or any R + S + V signature of the same message)
+func RecoverSignerProof(chainID uint64, collector eth.Address, proofDeadline uint64, authorizer eth.Address, proof []byte) (eth.Address, error) {
+	inverted, err := eth.NewInvertedSignatureFromBytes(proof)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSignerProof, err)
+	}
+
+	// Accept both the 0/1 and the 27/28 recovery ID conventions
+	if inverted[64] < 27 {
+		inverted[64] += 27
+	}
+
+	signer, err := inverted.RecoverPersonal(eth.Hex(SignerProofMessageHash(chainID, collector, proofDeadline, authorizer)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSignerProof, err)
+	}
+	return signer, nil
+}
+
+// VerifySignerProof checks proof was signed by signer for the given collector,
+// deadline and authorizer. The deadline itself is not checked, the contract
+// requires it to be after the block timestamp of the authorizeSigner transaction.
+func VerifySignerProof(chainID uint64, collector eth.Address, proofDeadline uint64, authorizer, signer eth.Address, proof []byte) error {
+	recovered, err := RecoverSignerProof(chainID, collector, proofDeadline, authorizer, proof)
+	if err != nil {
+		return err
+	}
+	if !addressesEqual(recovered, signer) {
+		return fmt.Errorf("%w: signed by %s, expected %s", ErrInvalidSignerProof, recovered.Pretty(), signer.Pretty())
+	}
+	return nil
+}
+
+func uint256Bytes(value uint64) []byte {
+	out := make([]byte, 32)
+	new(big.Int).SetUint64(value).FillBytes(out)
+	return out
+}
@@ -0,0 +1,50 @@
+package horizon
+
+import (
+	"testing"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignerProof(t *testing.T) {
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	signer := signerKey.PublicKey().Address()
+
+	collector := eth.MustNewAddress("0x1d01649b4f94722b55b5c3b3e10fe26cd90c1ba9")
+	authorizer := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	const chainID, deadline = uint64(42161), uint64(1_800_000_000)
+
+	proof, err := NewSignerProof(chainID, collector, deadline, authorizer, signerKey)
+	require.NoError(t, err)
+	require.Len(t, proof, 65)
+	assert.Contains(t, []byte{27, 28}, proof[64], "V must be last, in the 27/28 convention")
+
+	recovered, err := RecoverSignerProof(chainID, collector, deadline, authorizer, proof)
+	require.NoError(t, err)
+	assert.Equal(t, signer.Pretty(), recovered.Pretty())
+	require.NoError(t, VerifySignerProof(chainID, collector, deadline, authorizer, signer, proof))
+
+	t.Run("recovery id 0/1", func(t *testing.T) {
+		lowV := append([]byte(nil), proof...)
+		lowV[64] -= 27
+		require.NoError(t, VerifySignerProof(chainID, collector, deadline, authorizer, signer, lowV))
+	})
+
+	t.Run("bound to every field", func(t *testing.T) {
+		other := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+
+		assert.ErrorIs(t, VerifySignerProof(chainID+1, collector, deadline, authorizer, signer, proof), ErrInvalidSignerProof)
+		assert.ErrorIs(t, VerifySignerProof(chainID, other, deadline, authorizer, signer, proof), ErrInvalidSignerProof)
+		assert.ErrorIs(t, VerifySignerProof(chainID, collector, deadline+1, authorizer, signer, proof), ErrInvalidSignerProof)
+		assert.ErrorIs(t, VerifySignerProof(chainID, collector, deadline, other, signer, proof), ErrInvalidSignerProof)
+		assert.ErrorIs(t, VerifySignerProof(chainID, collector, deadline, authorizer, other, proof), ErrInvalidSignerProof)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		_, err := RecoverSignerProof(chainID, collector, deadline, authorizer, proof[:64])
+		assert.ErrorIs(t, err, ErrInvalidSignerProof)
+	})
+}
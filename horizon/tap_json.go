@@ -0,0 +1,342 @@
+package horizon
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/streamingfast/eth-go"
+)
+
+// TapSignedReceipt encodes a SignedReceipt in the JSON form of the tap-rs
+// aggregator (tap-aggregator): 0x-prefixed addresses and a signature object
+// with its r, s and parity components. Decoding also accepts the default JSON
+// encoding of SignedReceipt.
+type TapSignedReceipt struct {
+	*SignedReceipt
+}
+
+// TapSignedRAV encodes a SignedRAV in the JSON form of the tap-rs aggregator,
+// see TapSignedReceipt, its metadata being 0x-prefixed hex
+type TapSignedRAV struct {
+	*SignedRAV
+}
+
+type tapSignedMessage[T any] struct {
+	Message   T               `json:"message"`
+	Signature json.RawMessage `json:"signature"`
+}
+
+type tapReceipt struct {
+	CollectionID    CollectionID `json:"collection_id"`
+	Payer           hexAddress   `json:"payer"`
+	DataService     hexAddress   `json:"data_service"`
+	ServiceProvider hexAddress   `json:"service_provider"`
+	TimestampNs     uint64       `json:"timestamp_ns"`
+	Nonce           uint64       `json:"nonce"`
+	Value           *tapValue    `json:"value"`
+}
+
+type tapRAV struct {
+	CollectionID    CollectionID `json:"collectionId"`
+	Payer           hexAddress   `json:"payer"`
+	ServiceProvider hexAddress   `json:"serviceProvider"`
+	DataService     hexAddress   `json:"dataService"`
+	TimestampNs     uint64       `json:"timestampNs"`
+	ValueAggregate  *tapValue    `json:"valueAggregate"`
+	Metadata        hexBytes     `json:"metadata"`
+}
+
+// tapSignature is the signature object of tap-rs messages, r and s being hex
+// quantities and yParity (or v) the recovery ID, 0/1 or 27/28
+type tapSignature struct {
+	R       json.RawMessage `json:"r"`
+	S       json.RawMessage `json:"s"`
+	YParity json.RawMessage `json:"yParity"`
+	V       json.RawMessage `json:"v"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r TapSignedReceipt) MarshalJSON() ([]byte, error) {
+	if r.SignedReceipt == nil || r.Message == nil {
+		return []byte("null"), nil
+	}
+
+	signature, err := marshalTapSignature(r.Signature)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(tapSignedMessage[tapReceipt]{
+		Message: tapReceipt{
+			CollectionID:    r.Message.CollectionID,
+			Payer:           hexAddress(r.Message.Payer),
+			DataService:     hexAddress(r.Message.DataService),
+			ServiceProvider: hexAddress(r.Message.ServiceProvider),
+			TimestampNs:     r.Message.TimestampNs,
+			Nonce:           r.Message.Nonce,
+			Value:           (*tapValue)(r.Message.Value),
+		},
+		Signature: signature,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (r *TapSignedReceipt) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		r.SignedReceipt = nil
+		return nil
+	}
+
+	var in tapSignedMessage[tapReceipt]
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	signature, err := unmarshalJSONSignature(in.Signature)
+	if err != nil {
+		return fmt.Errorf("receipt signature: %w", err)
+	}
+
+	r.SignedReceipt = &SignedReceipt{
+		Message: &Receipt{
+			CollectionID:    in.Message.CollectionID,
+			Payer:           eth.Address(in.Message.Payer),
+			DataService:     eth.Address(in.Message.DataService),
+			ServiceProvider: eth.Address(in.Message.ServiceProvider),
+			TimestampNs:     in.Message.TimestampNs,
+			Nonce:           in.Message.Nonce,
+			Value:           (*big.Int)(in.Message.Value),
+		},
+		Signature: signature,
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (r TapSignedRAV) MarshalJSON() ([]byte, error) {
+	if r.SignedRAV == nil || r.Message == nil {
+		return []byte("null"), nil
+	}
+
+	signature, err := marshalTapSignature(r.Signature)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(tapSignedMessage[tapRAV]{
+		Message: tapRAV{
+			CollectionID:    r.Message.CollectionID,
+			Payer:           hexAddress(r.Message.Payer),
+			ServiceProvider: hexAddress(r.Message.ServiceProvider),
+			DataService:     hexAddress(r.Message.DataService),
+			TimestampNs:     r.Message.TimestampNs,
+			ValueAggregate:  (*tapValue)(r.Message.ValueAggregate),
+			Metadata:        hexBytes(r.Message.Metadata),
+		},
+		Signature: signature,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (r *TapSignedRAV) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		r.SignedRAV = nil
+		return nil
+	}
+
+	var in tapSignedMessage[tapRAV]
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	signature, err := unmarshalJSONSignature(in.Signature)
+	if err != nil {
+		return fmt.Errorf("RAV signature: %w", err)
+	}
+
+	r.SignedRAV = &SignedRAV{
+		Message: &RAV{
+			CollectionID:    in.Message.CollectionID,
+			Payer:           eth.Address(in.Message.Payer),
+			ServiceProvider: eth.Address(in.Message.ServiceProvider),
+			DataService:     eth.Address(in.Message.DataService),
+			TimestampNs:     in.Message.TimestampNs,
+			ValueAggregate:  (*big.Int)(in.Message.ValueAggregate),
+			Metadata:        []byte(in.Message.Metadata),
+		},
+		Signature: signature,
+	}
+	return nil
+}
+
+// marshalTapSignature encodes sig as the signature object of tap-rs messages,
+// r and s as hex quantities and the recovery ID as both yParity and v
+func marshalTapSignature(sig eth.Signature) (json.RawMessage, error) {
+	v, ok := recoveryV(sig[0])
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown recovery byte %d", ErrInvalidSignature, sig[0])
+	}
+	parity := fmt.Sprintf("0x%x", v-27)
+
+	return json.Marshal(struct {
+		R       string `json:"r"`
+		S       string `json:"s"`
+		YParity string `json:"yParity"`
+		V       string `json:"v"`
+	}{
+		R:       "0x" + sig.R().Text(16),
+		S:       "0x" + sig.S().Text(16),
+		YParity: parity,
+		V:       parity,
+	})
+}
+
+// unmarshalJSONSignature decodes the signature of a JSON encoded receipt or
+// RAV: the signature object of tap-rs messages, a 0x-prefixed R+S+V hex
+// string, the base64 encoded V+R+S bytes of the proto messages (as encoded to
+// JSON by protojson) or the byte array eth.Signature is encoded to by default
+func unmarshalJSONSignature(data json.RawMessage) (eth.Signature, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return eth.Signature{}, fmt.Errorf("%w: missing", ErrInvalidSignature)
+	}
+
+	switch data[0] {
+	case '"':
+		var in string
+		if err := json.Unmarshal(data, &in); err != nil {
+			return eth.Signature{}, err
+		}
+		if strings.HasPrefix(in, "0x") {
+			return parseRemoteSignature(in)
+		}
+		raw, err := base64.StdEncoding.DecodeString(in)
+		if err != nil {
+			return eth.Signature{}, fmt.Errorf("%w: expected 0x-prefixed hex or base64: %w", ErrInvalidSignature, err)
+		}
+		return ParseSignature(raw)
+
+	case '[':
+		var in eth.Signature
+		if err := json.Unmarshal(data, &in); err != nil {
+			return eth.Signature{}, err
+		}
+		return ParseSignature(in[:])
+	}
+
+	var in tapSignature
+	if err := json.Unmarshal(data, &in); err != nil {
+		return eth.Signature{}, err
+	}
+	r, err := parseTapQuantity(in.R)
+	if err != nil {
+		return eth.Signature{}, fmt.Errorf("%w: r: %w", ErrInvalidSignature, err)
+	}
+	s, err := parseTapQuantity(in.S)
+	if err != nil {
+		return eth.Signature{}, fmt.Errorf("%w: s: %w", ErrInvalidSignature, err)
+	}
+	parity := in.YParity
+	if len(parity) == 0 || string(parity) == "null" {
+		parity = in.V
+	}
+	v, err := parseTapQuantity(parity)
+	if err != nil {
+		return eth.Signature{}, fmt.Errorf("%w: parity: %w", ErrInvalidSignature, err)
+	}
+	if r.BitLen() > 256 || s.BitLen() > 256 || !v.IsUint64() || v.Uint64() > 255 {
+		return eth.Signature{}, fmt.Errorf("%w: component out of range", ErrInvalidSignature)
+	}
+
+	var sig eth.Signature
+	sig[0] = byte(v.Uint64())
+	r.FillBytes(sig[1:33])
+	s.FillBytes(sig[33:65])
+	return ParseSignature(sig[:])
+}
+
+// parseTapQuantity parses a JSON number or a string holding a 0x-prefixed hex
+// or a decimal integer
+func parseTapQuantity(data json.RawMessage) (*big.Int, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("missing")
+	}
+
+	in := string(data)
+	if data[0] == '"' {
+		if err := json.Unmarshal(data, &in); err != nil {
+			return nil, err
+		}
+	}
+
+	value, ok := new(big.Int), false
+	if hexValue, found := strings.CutPrefix(in, "0x"); found {
+		value, ok = value.SetString(hexValue, 16)
+	} else {
+		value, ok = value.SetString(in, 10)
+	}
+	if !ok || value.Sign() < 0 {
+		return nil, fmt.Errorf("invalid quantity %s", data)
+	}
+	return value, nil
+}
+
+// tapValue is a big integer encoded as a JSON number, as tap-rs expects it,
+// decoding also accepts decimal and 0x-prefixed hex strings
+type tapValue big.Int
+
+func (v *tapValue) MarshalJSON() ([]byte, error) {
+	return (*big.Int)(v).MarshalJSON()
+}
+
+func (v *tapValue) UnmarshalJSON(data []byte) error {
+	return (*decimalInt)(v).UnmarshalJSON(data)
+}
+
+// hexAddress is an address encoded 0x-prefixed, as tap-rs expects it and as
+// the JSON encoding of receipts and RAVs uses
+type hexAddress eth.Address
+
+func (a hexAddress) MarshalJSON() ([]byte, error) {
+	return json.Marshal(eth.Address(a).Pretty())
+}
+
+func (a *hexAddress) UnmarshalJSON(data []byte) error {
+	return (*eth.Address)(a).UnmarshalJSON(data)
+}
+
+// hexBytes is a byte string encoded as 0x-prefixed hex, decoding also accepts
+// the base64 encoding of []byte
+type hexBytes []byte
+
+func (b hexBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal("0x" + hex.EncodeToString(b))
+}
+
+func (b *hexBytes) UnmarshalJSON(data []byte) error {
+	var in *string
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if in == nil || *in == "" || *in == "0x" {
+		*b = nil
+		return nil
+	}
+
+	if hexValue, found := strings.CutPrefix(*in, "0x"); found {
+		out, err := hex.DecodeString(hexValue)
+		if err != nil {
+			return fmt.Errorf("invalid hex bytes: %w", err)
+		}
+		*b = out
+		return nil
+	}
+
+	out, err := base64.StdEncoding.DecodeString(*in)
+	if err != nil {
+		return fmt.Errorf("invalid bytes, expected 0x-prefixed hex: %w", err)
+	}
+	*b = out
+	return nil
+}
@@ -0,0 +1,329 @@
+package horizon
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/streamingfast/eth-go"
+)
+
+// ErrTypedDataMismatch is returned when an eth_signTypedData_v4 payload is not
+// the typed data of a receipt or a RAV as produced by NewTypedData
+var ErrTypedDataMismatch = errors.New("typed data is not a receipt or RAV")
+
+// TypedDataField is a field of an EIP-712 struct type
+type TypedDataField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// TypedData is the JSON form of an EIP-712 message signed by
+// eth_signTypedData_v4, integers are rendered as decimal strings and bytes as
+// 0x-prefixed hex
+type TypedData struct {
+	Types       map[string][]TypedDataField `json:"types"`
+	PrimaryType string                      `json:"primaryType"`
+	Domain      map[string]string           `json:"domain"`
+	Message     map[string]string           `json:"message"`
+}
+
+var eip712DomainFields = []TypedDataField{
+	{Name: "name", Type: "string"},
+	{Name: "version", Type: "string"},
+	{Name: "chainId", Type: "uint256"},
+	{Name: "verifyingContract", Type: "address"},
+}
+
+// NewTypedData returns the typed data of message, a *Receipt or a *RAV, under
+// domain
+func NewTypedData(domain *Domain, message EIP712Encodable) (*TypedData, error) {
+	typedData := &TypedData{
+		Types: map[string][]TypedDataField{"EIP712Domain": eip712DomainFields},
+		Domain: map[string]string{
+			"name":              domain.Name,
+			"version":           domain.Version,
+			"chainId":           domain.ChainID.String(),
+			"verifyingContract": domain.VerifyingContract.Pretty(),
+		},
+	}
+
+	switch message := message.(type) {
+	case *Receipt:
+		typedData.PrimaryType = "Receipt"
+		typedData.Types["Receipt"] = []TypedDataField{
+			{Name: "collection_id", Type: "bytes32"},
+			{Name: "payer", Type: "address"},
+			{Name: "data_service", Type: "address"},
+			{Name: "service_provider", Type: "address"},
+			{Name: "timestamp_ns", Type: "uint64"},
+			{Name: "nonce", Type: "uint64"},
+			{Name: "value", Type: "uint128"},
+		}
+		typedData.Message = map[string]string{
+			"collection_id":    "0x" + hex.EncodeToString(message.CollectionID[:]),
+			"payer":            message.Payer.Pretty(),
+			"data_service":     message.DataService.Pretty(),
+			"service_provider": message.ServiceProvider.Pretty(),
+			"timestamp_ns":     strconv.FormatUint(message.TimestampNs, 10),
+			"nonce":            strconv.FormatUint(message.Nonce, 10),
+			"value":            decimalString(message.Value),
+		}
+
+	case *RAV:
+		typedData.PrimaryType = "ReceiptAggregateVoucher"
+		typedData.Types["ReceiptAggregateVoucher"] = []TypedDataField{
+			{Name: "collectionId", Type: "bytes32"},
+			{Name: "payer", Type: "address"},
+			{Name: "serviceProvider", Type: "address"},
+			{Name: "dataService", Type: "address"},
+			{Name: "timestampNs", Type: "uint64"},
+			{Name: "valueAggregate", Type: "uint128"},
+			{Name: "metadata", Type: "bytes"},
+		}
+		typedData.Message = map[string]string{
+			"collectionId":    "0x" + hex.EncodeToString(message.CollectionID[:]),
+			"payer":           message.Payer.Pretty(),
+			"serviceProvider": message.ServiceProvider.Pretty(),
+			"dataService":     message.DataService.Pretty(),
+			"timestampNs":     strconv.FormatUint(message.TimestampNs, 10),
+			"valueAggregate":  decimalString(message.ValueAggregate),
+			"metadata":        "0x" + hex.EncodeToString(message.Metadata),
+		}
+
+	default:
+		return nil, fmt.Errorf("no typed data for message of type %T", message)
+	}
+
+	return typedData, nil
+}
+
+// decimalString renders an uint128 of typed data, nil being zero as in
+// EIP712EncodeData
+func decimalString(v *big.Int) string {
+	if v == nil {
+		return "0"
+	}
+	return v.String()
+}
+
+// TypedDataJSON returns the eth_signTypedData_v4 payload of message, a
+// *Receipt or a *RAV, under domain: the EIP-712 JSON structure (types,
+// primaryType, domain and message) browser and hardware wallets sign. The
+// signature they return is verified with VerifyTypedDataJSON.
+func TypedDataJSON(domain *Domain, message EIP712Encodable) ([]byte, error) {
+	typedData, err := NewTypedData(domain, message)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(typedData)
+}
+
+// ParseTypedDataJSON parses an eth_signTypedData_v4 payload as produced by
+// TypedDataJSON, returning its domain and its message, a *Receipt or a *RAV.
+// Its types must be exactly those of the message, integers may be decimal or
+// 0x-prefixed hex strings as well as JSON numbers.
+func ParseTypedDataJSON(data []byte) (*Domain, EIP712Encodable, error) {
+	var in struct {
+		Types       map[string][]TypedDataField `json:"types"`
+		PrimaryType string                      `json:"primaryType"`
+		Domain      map[string]json.RawMessage  `json:"domain"`
+		Message     map[string]json.RawMessage  `json:"message"`
+	}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, nil, fmt.Errorf("decoding typed data: %w", err)
+	}
+
+	var message EIP712Encodable
+	switch in.PrimaryType {
+	case "Receipt":
+		message = &Receipt{}
+	case "ReceiptAggregateVoucher":
+		message = &RAV{}
+	default:
+		return nil, nil, fmt.Errorf("%w: unsupported primary type %q", ErrTypedDataMismatch, in.PrimaryType)
+	}
+
+	// The wallet hashes the payload types, they must be the ones the message
+	// hash is computed with
+	expected, err := NewTypedData(&Domain{ChainID: new(big.Int)}, message)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(in.Types) != len(expected.Types) {
+		return nil, nil, fmt.Errorf("%w: unexpected types", ErrTypedDataMismatch)
+	}
+	for name, fields := range expected.Types {
+		if !slices.Equal(in.Types[name], fields) {
+			return nil, nil, fmt.Errorf("%w: unexpected fields for type %s", ErrTypedDataMismatch, name)
+		}
+	}
+
+	fields := typedDataFields{values: in.Domain}
+	domain := &Domain{
+		Name:              fields.string("name"),
+		Version:           fields.string("version"),
+		ChainID:           fields.uint("chainId", 256),
+		VerifyingContract: fields.address("verifyingContract"),
+	}
+	if fields.err != nil {
+		return nil, nil, fmt.Errorf("domain: %w", fields.err)
+	}
+
+	fields = typedDataFields{values: in.Message}
+	switch message := message.(type) {
+	case *Receipt:
+		*message = Receipt{
+			CollectionID:    fields.bytes32("collection_id"),
+			Payer:           fields.address("payer"),
+			DataService:     fields.address("data_service"),
+			ServiceProvider: fields.address("service_provider"),
+			TimestampNs:     fields.uint64("timestamp_ns"),
+			Nonce:           fields.uint64("nonce"),
+			Value:           fields.uint("value", 128),
+		}
+	case *RAV:
+		*message = RAV{
+			CollectionID:    fields.bytes32("collectionId"),
+			Payer:           fields.address("payer"),
+			ServiceProvider: fields.address("serviceProvider"),
+			DataService:     fields.address("dataService"),
+			TimestampNs:     fields.uint64("timestampNs"),
+			ValueAggregate:  fields.uint("valueAggregate", 128),
+			Metadata:        fields.bytes("metadata"),
+		}
+	}
+	if fields.err != nil {
+		return nil, nil, fmt.Errorf("message: %w", fields.err)
+	}
+
+	return domain, message, nil
+}
+
+// VerifyTypedDataJSON recovers the address that signed the eth_signTypedData_v4
+// payload data, parsed with ParseTypedDataJSON, signature being the hex R+S+V
+// signature returned by the wallet (0x prefix optional) or its EIP-2098
+// compact form
+func VerifyTypedDataJSON(data []byte, signature string) (eth.Address, error) {
+	domain, message, err := ParseTypedDataJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := parseRemoteSignature(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	digest, err := HashTypedData(domain, message)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := sig.Recover(digest)
+	if err != nil {
+		return nil, fmt.Errorf("recovering signer: %w", err)
+	}
+	return signer, nil
+}
+
+// typedDataFields decodes the fields of a typed data struct, keeping the
+// first error met so a struct is decoded in one go
+type typedDataFields struct {
+	values map[string]json.RawMessage
+	err    error
+}
+
+func (f *typedDataFields) raw(name string) json.RawMessage {
+	value, found := f.values[name]
+	if !found && f.err == nil {
+		f.err = fmt.Errorf("missing field %s", name)
+	}
+	return value
+}
+
+func (f *typedDataFields) fail(name string, err error) {
+	if f.err == nil {
+		f.err = fmt.Errorf("field %s: %w", name, err)
+	}
+}
+
+func (f *typedDataFields) string(name string) string {
+	raw := f.raw(name)
+	if raw == nil {
+		return ""
+	}
+
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		f.fail(name, err)
+	}
+	return value
+}
+
+func (f *typedDataFields) hex(name string) []byte {
+	value := f.string(name)
+	if f.err != nil {
+		return nil
+	}
+
+	out, err := hex.DecodeString(strings.TrimPrefix(value, "0x"))
+	if err != nil {
+		f.fail(name, err)
+	}
+	return out
+}
+
+func (f *typedDataFields) bytes(name string) []byte {
+	out := f.hex(name)
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+func (f *typedDataFields) bytes32(name string) (out CollectionID) {
+	value := f.hex(name)
+	if f.err == nil && len(value) != len(out) {
+		f.fail(name, fmt.Errorf("expected %d bytes, got %d", len(out), len(value)))
+	}
+	copy(out[:], value)
+	return out
+}
+
+func (f *typedDataFields) address(name string) eth.Address {
+	value := f.hex(name)
+	if f.err == nil && len(value) != 20 {
+		f.fail(name, fmt.Errorf("expected 20 bytes address, got %d", len(value)))
+	}
+	return eth.Address(value)
+}
+
+func (f *typedDataFields) uint(name string, bits int) *big.Int {
+	raw := f.raw(name)
+	if raw == nil {
+		return nil
+	}
+
+	value, err := parseTapQuantity(raw)
+	if err == nil && value.BitLen() > bits {
+		err = fmt.Errorf("%s overflows uint%d", value, bits)
+	}
+	if err != nil {
+		f.fail(name, err)
+		return nil
+	}
+	return value
+}
+
+func (f *typedDataFields) uint64(name string) uint64 {
+	value := f.uint(name, 64)
+	if value == nil {
+		return 0
+	}
+	return value.Uint64()
+}
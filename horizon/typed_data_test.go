@@ -0,0 +1,85 @@
+package horizon
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypedDataJSON(t *testing.T) {
+	domain := NewDomain(1337, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+	key, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	rav := &RAV{
+		CollectionID:    CollectionID{0x01},
+		Payer:           key.PublicKey().Address(),
+		ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+		TimestampNs:     42,
+		ValueAggregate:  big.NewInt(1000),
+		Metadata:        []byte{0xca, 0xfe},
+	}
+	receipt := NewReceipt(CollectionID{0x01}, key.PublicKey().Address(), eth.MustNewAddress("0x2222222222222222222222222222222222222222"), eth.MustNewAddress("0x3333333333333333333333333333333333333333"), big.NewInt(100))
+
+	for _, message := range []EIP712Encodable{rav, receipt} {
+		data, err := TypedDataJSON(domain, message)
+		require.NoError(t, err)
+
+		parsedDomain, parsed, err := ParseTypedDataJSON(data)
+		require.NoError(t, err)
+		assert.Equal(t, domain.Separator(), parsedDomain.Separator())
+		assert.Equal(t, message.EIP712EncodeData(), parsed.EIP712EncodeData())
+
+		// Wallets return the R+S+V signature of the payload
+		digest, err := HashTypedData(domain, message)
+		require.NoError(t, err)
+		sig, err := key.Sign(digest)
+		require.NoError(t, err)
+
+		signer, err := VerifyTypedDataJSON(data, "0x"+hex.EncodeToString(signatureToRSV(sig)))
+		require.NoError(t, err)
+		assert.Equal(t, key.PublicKey().Address(), signer)
+	}
+
+	data, err := TypedDataJSON(domain, rav)
+	require.NoError(t, err)
+
+	var payload map[string]any
+	require.NoError(t, json.Unmarshal(data, &payload))
+	assert.Equal(t, "ReceiptAggregateVoucher", payload["primaryType"])
+	assert.Equal(t, "1000", payload["message"].(map[string]any)["valueAggregate"])
+
+	// Integers given as JSON numbers or hex strings, as some wallets do
+	edited := strings.Replace(string(data), `"chainId":"1337"`, `"chainId":1337`, 1)
+	edited = strings.Replace(edited, `"valueAggregate":"1000"`, `"valueAggregate":"0x3e8"`, 1)
+	_, parsed, err := ParseTypedDataJSON([]byte(edited))
+	require.NoError(t, err)
+	assert.Equal(t, rav.EIP712EncodeData(), parsed.EIP712EncodeData())
+
+	// A payload whose types differ from the message's is refused, the wallet
+	// would have signed another struct
+	edited = strings.Replace(string(data), `"type":"uint128"`, `"type":"uint256"`, 1)
+	_, _, err = ParseTypedDataJSON([]byte(edited))
+	assert.ErrorIs(t, err, ErrTypedDataMismatch)
+
+	edited = strings.Replace(string(data), `"primaryType":"ReceiptAggregateVoucher"`, `"primaryType":"Mail"`, 1)
+	_, _, err = ParseTypedDataJSON([]byte(edited))
+	assert.ErrorIs(t, err, ErrTypedDataMismatch)
+
+	edited = strings.Replace(string(data), `"valueAggregate":"1000"`, `"valueAggregate":"`+new(big.Int).Lsh(big.NewInt(1), 128).String()+`"`, 1)
+	_, _, err = ParseTypedDataJSON([]byte(edited))
+	assert.ErrorContains(t, err, "overflows uint128")
+
+	_, _, err = ParseTypedDataJSON([]byte(strings.Replace(string(data), `"payer":`, `"payor":`, 1)))
+	assert.ErrorContains(t, err, "missing field payer")
+
+	_, err = VerifyTypedDataJSON(data, "0xnothex")
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
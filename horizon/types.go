@@ -3,6 +3,7 @@ package horizon
 import (
 	"crypto/rand"
 	"encoding/json"
+	"fmt"
 	"math/big"
 	"time"
 
@@ -23,7 +24,13 @@ func (c *CollectionID) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &s); err != nil {
 		return err
 	}
-	h := eth.MustNewHash(s)
+	h, err := eth.NewHash(s)
+	if err != nil {
+		return fmt.Errorf("invalid collection ID %q: %w", s, err)
+	}
+	if len(h) > len(c) {
+		return fmt.Errorf("invalid collection ID %q: longer than %d bytes", s, len(c))
+	}
 	copy(c[:], h)
 	return nil
 }
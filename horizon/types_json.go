@@ -0,0 +1,168 @@
+package horizon
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/streamingfast/eth-go"
+)
+
+// Receipts, RAVs and their signed wrappers share one JSON encoding across
+// CLIs, stores and APIs: 0x-prefixed addresses, metadata and signatures (R+S+V,
+// as produced by eth_signTypedData_v4) and values as decimal strings, exact
+// whatever their size. Decoding also accepts the forms these types were
+// encoded to before (unprefixed addresses, numeric values, base64 metadata and
+// byte array signatures) and base64 signatures holding the V+R+S bytes of the
+// proto messages.
+
+type jsonReceipt struct {
+	CollectionID    CollectionID `json:"collection_id"`
+	Payer           hexAddress   `json:"payer"`
+	DataService     hexAddress   `json:"data_service"`
+	ServiceProvider hexAddress   `json:"service_provider"`
+	TimestampNs     uint64       `json:"timestamp_ns"`
+	Nonce           uint64       `json:"nonce"`
+	Value           *decimalInt  `json:"value"`
+}
+
+type jsonRAV struct {
+	CollectionID    CollectionID `json:"collectionId"`
+	Payer           hexAddress   `json:"payer"`
+	ServiceProvider hexAddress   `json:"serviceProvider"`
+	DataService     hexAddress   `json:"dataService"`
+	TimestampNs     uint64       `json:"timestampNs"`
+	ValueAggregate  *decimalInt  `json:"valueAggregate"`
+	Metadata        hexBytes     `json:"metadata"`
+}
+
+type jsonSignedMessage[T any] struct {
+	Message   T               `json:"message"`
+	Signature json.RawMessage `json:"signature"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r Receipt) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonReceipt{
+		CollectionID:    r.CollectionID,
+		Payer:           hexAddress(r.Payer),
+		DataService:     hexAddress(r.DataService),
+		ServiceProvider: hexAddress(r.ServiceProvider),
+		TimestampNs:     r.TimestampNs,
+		Nonce:           r.Nonce,
+		Value:           (*decimalInt)(r.Value),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (r *Receipt) UnmarshalJSON(data []byte) error {
+	var in jsonReceipt
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	*r = Receipt{
+		CollectionID:    in.CollectionID,
+		Payer:           eth.Address(in.Payer),
+		DataService:     eth.Address(in.DataService),
+		ServiceProvider: eth.Address(in.ServiceProvider),
+		TimestampNs:     in.TimestampNs,
+		Nonce:           in.Nonce,
+		Value:           (*big.Int)(in.Value),
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (r RAV) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonRAV{
+		CollectionID:    r.CollectionID,
+		Payer:           hexAddress(r.Payer),
+		ServiceProvider: hexAddress(r.ServiceProvider),
+		DataService:     hexAddress(r.DataService),
+		TimestampNs:     r.TimestampNs,
+		ValueAggregate:  (*decimalInt)(r.ValueAggregate),
+		Metadata:        hexBytes(r.Metadata),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (r *RAV) UnmarshalJSON(data []byte) error {
+	var in jsonRAV
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	*r = RAV{
+		CollectionID:    in.CollectionID,
+		Payer:           eth.Address(in.Payer),
+		ServiceProvider: eth.Address(in.ServiceProvider),
+		DataService:     eth.Address(in.DataService),
+		TimestampNs:     in.TimestampNs,
+		ValueAggregate:  (*big.Int)(in.ValueAggregate),
+		Metadata:        []byte(in.Metadata),
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, the signature is encoded as 0x-prefixed
+// R+S+V hex, or null when not set
+func (sm SignedMessage[T]) MarshalJSON() ([]byte, error) {
+	signature := json.RawMessage("null")
+	if sm.Signature != (eth.Signature{}) {
+		encoded, err := json.Marshal("0x" + hex.EncodeToString(signatureToRSV(sm.Signature)))
+		if err != nil {
+			return nil, err
+		}
+		signature = encoded
+	}
+
+	return json.Marshal(jsonSignedMessage[T]{
+		Message:   sm.Message,
+		Signature: signature,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see unmarshalJSONSignature for the
+// signature forms accepted
+func (sm *SignedMessage[T]) UnmarshalJSON(data []byte) error {
+	var in jsonSignedMessage[T]
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	var signature eth.Signature
+	if len(in.Signature) > 0 && !bytes.Equal(bytes.TrimSpace(in.Signature), []byte("null")) {
+		var err error
+		if signature, err = unmarshalJSONSignature(in.Signature); err != nil {
+			return fmt.Errorf("signature: %w", err)
+		}
+	}
+
+	sm.Message = in.Message
+	sm.Signature = signature
+	return nil
+}
+
+// decimalInt is a big integer encoded as a decimal string, decoding also
+// accepts JSON numbers and 0x-prefixed hex strings
+type decimalInt big.Int
+
+func (d *decimalInt) MarshalJSON() ([]byte, error) {
+	return json.Marshal((*big.Int)(d).String())
+}
+
+func (d *decimalInt) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+
+	value, err := parseTapQuantity(data)
+	if err != nil {
+		return err
+	}
+	(*big.Int)(d).Set(value)
+	return nil
+}
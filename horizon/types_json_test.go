@@ -0,0 +1,120 @@
+package horizon
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignedRAV_CanonicalJSON(t *testing.T) {
+	domain := NewDomain(1337, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+	key, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	// Larger than what a float64 holds exactly
+	value, ok := new(big.Int).SetString("123456789012345678901234567890", 10)
+	require.True(t, ok)
+
+	signedRAV, err := Sign(domain, &RAV{
+		CollectionID:    CollectionID{0x01},
+		Payer:           eth.MustNewAddress("0x1111111111111111111111111111111111111111"),
+		ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+		TimestampNs:     42,
+		ValueAggregate:  value,
+		Metadata:        []byte{0xca, 0xfe},
+	}, key)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(signedRAV)
+	require.NoError(t, err)
+
+	var out struct {
+		Message   map[string]any `json:"message"`
+		Signature string         `json:"signature"`
+	}
+	require.NoError(t, json.Unmarshal(data, &out))
+	assert.Equal(t, "0x1111111111111111111111111111111111111111", out.Message["payer"])
+	assert.Equal(t, "123456789012345678901234567890", out.Message["valueAggregate"])
+	assert.Equal(t, "0xcafe", out.Message["metadata"])
+	assert.Equal(t, "0x"+hex.EncodeToString(signatureToRSV(signedRAV.Signature)), out.Signature)
+
+	var decoded SignedRAV
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, signedRAV.Signature, decoded.Signature)
+	assert.Equal(t, value.String(), decoded.Message.ValueAggregate.String())
+	assert.Equal(t, signedRAV.Message.Metadata, decoded.Message.Metadata)
+
+	signer, err := decoded.RecoverSigner(domain)
+	require.NoError(t, err)
+	assert.True(t, addressesEqual(key.PublicKey().Address(), signer))
+
+	// Signatures given as the base64 encoded proto bytes
+	base64Data, err := json.Marshal(map[string]any{
+		"message":   signedRAV.Message,
+		"signature": base64.StdEncoding.EncodeToString(signedRAV.Signature[:]),
+	})
+	require.NoError(t, err)
+	decoded = SignedRAV{}
+	require.NoError(t, json.Unmarshal(base64Data, &decoded))
+	assert.Equal(t, signedRAV.Signature, decoded.Signature)
+}
+
+func TestReceipt_CanonicalJSON(t *testing.T) {
+	receipt := &Receipt{
+		CollectionID:    CollectionID{0x01},
+		Payer:           eth.MustNewAddress("0x1111111111111111111111111111111111111111"),
+		DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+		ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		TimestampNs:     42,
+		Nonce:           7,
+		Value:           big.NewInt(1000),
+	}
+
+	data, err := json.Marshal(receipt)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"collection_id": "0x0100000000000000000000000000000000000000000000000000000000000000",
+		"payer": "0x1111111111111111111111111111111111111111",
+		"data_service": "0x2222222222222222222222222222222222222222",
+		"service_provider": "0x3333333333333333333333333333333333333333",
+		"timestamp_ns": 42,
+		"nonce": 7,
+		"value": "1000"
+	}`, string(data))
+
+	var decoded Receipt
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, receipt.CollectionID, decoded.CollectionID)
+	assert.True(t, addressesEqual(receipt.Payer, decoded.Payer))
+	assert.Equal(t, "1000", decoded.Value.String())
+
+	// The encoding used before, unprefixed addresses and a numeric value, with
+	// the byte array signature of eth.Signature
+	var signed SignedReceipt
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"message": {"payer": "1111111111111111111111111111111111111111", "value": 1000, "nonce": 7},
+		"signature": [27`+strings.Repeat(",1", 64)+`]
+	}`), &signed))
+	assert.Equal(t, "1000", signed.Message.Value.String())
+	assert.Equal(t, uint64(7), signed.Message.Nonce)
+	assert.Equal(t, byte(27), signed.Signature[0])
+	assert.Equal(t, byte(1), signed.Signature[64])
+
+	// Unsigned messages round trip with a null signature
+	data, err = json.Marshal(&SignedReceipt{Message: receipt})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"signature":null`)
+	signed = SignedReceipt{}
+	require.NoError(t, json.Unmarshal(data, &signed))
+	assert.Equal(t, eth.Signature{}, signed.Signature)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"collection_id": "0xzz"}`), &decoded))
+}
@@ -0,0 +1,241 @@
+package horizon
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/streamingfast/eth-go"
+)
+
+// Errors returned by Validator.Validate
+var (
+	ErrRAVNotCollectable      = errors.New("RAV not collectable")
+	ErrRAVSignatureInvalid    = errors.New("RAV signature is invalid")
+	ErrRAVUnauthorizedSigner  = errors.New("RAV signed by unauthorized signer")
+	ErrRAVPartiesMismatch     = errors.New("RAV payer, service provider or data service differs from expected")
+	ErrRAVCollectionMismatch  = errors.New("RAV collection ID differs from expected")
+	ErrRAVCollectionChanged   = errors.New("RAV collection ID differs from previous RAV")
+	ErrRAVPartiesChanged      = errors.New("RAV payer, service provider or data service differs from previous RAV")
+	ErrRAVTimestampRegression = errors.New("RAV timestamp is before previous RAV")
+	ErrRAVValueDecreased      = errors.New("RAV value aggregate is less than previous RAV")
+	ErrPreviousRAVMissing     = errors.New("previous RAV is missing its message")
+	ErrRAVExceedsEscrow       = errors.New("RAV value aggregate exceeds escrow balance")
+)
+
+// Policy holds the checks of Validator.Validate depending on the caller's
+// context, its zero value only runs the checks every RAV goes through
+type Policy struct {
+	// Parties the RAV must be for, any when nil
+	Parties *RAVParties
+	// CollectionID the RAV must be for, any when nil
+	CollectionID *CollectionID
+	// EscrowBalance returns a snapshot of the escrow balance of payer, the RAV
+	// value aggregate must not exceed it plus the tolerance set with
+	// WithEscrowCapTolerance. It is only called for RAVs passing the other
+	// checks, so it may query the chain. It takes precedence over the cap set
+	// with Validator.WithEscrowCap. The value is not capped when the balance
+	// it returns is nil.
+	EscrowBalance func(payer eth.Address) *big.Int
+	// Collected returns the value of collectionID already collected on-chain,
+	// paid out of the escrow so no longer backed by it: only the value
+	// aggregate above it is capped by the escrow balance. Nothing is collected
+	// when it or the value it returns is nil.
+	Collected func(collectionID CollectionID) *big.Int
+	// MaxMetadataSize bounds the RAV metadata, in bytes, the collector does not
+	// bound it itself. Not checked when zero.
+	MaxMetadataSize int
+	// StrictMetadata rejects metadata of none of the known MetadataType layouts
+	StrictMetadata bool
+	// Now rejects RAVs whose validity window (see EncodeRAVValidityMetadata)
+	// ended before it, not checked when zero
+	Now time.Time
+	// AllowTimestampRegression accepts RAVs timestamped before the previous
+	// one, e.g. for the caller to hold them for review instead
+	AllowTimestampRegression bool
+}
+
+// Validator verifies RAVs received from payers, each one being checked on its
+// own (signature, contract semantics), against the previous RAV of the same
+// collection (same parties, monotonic timestamp and value) and against a
+// Policy. Accepted signers can be added while in use.
+type Validator struct {
+	domain *Domain
+
+	// mu and acceptedSigners are shared with the copies made by WithEscrowCap
+	mu              *sync.RWMutex
+	acceptedSigners map[string]bool
+
+	escrowCap *big.Int
+	options
+}
+
+// NewValidator creates a RAV validator accepting RAVs signed by acceptedSigners
+func NewValidator(domain *Domain, acceptedSigners []eth.Address, opts ...Option) *Validator {
+	signerMap := make(map[string]bool, len(acceptedSigners))
+	for _, addr := range acceptedSigners {
+		signerMap[addr.Pretty()] = true
+	}
+
+	return &Validator{
+		domain:          domain,
+		mu:              new(sync.RWMutex),
+		acceptedSigners: signerMap,
+		options:         newOptions(opts),
+	}
+}
+
+// WithEscrowCap returns a copy of the validator also rejecting RAVs whose value
+// aggregate exceeds balance, a snapshot of the payer's escrow balance, plus the
+// tolerance set with WithEscrowCapTolerance. A nil balance removes the cap. It
+// is the Policy.EscrowBalance of Validate calls not setting one, accepted
+// signers are shared with the copy.
+func (v *Validator) WithEscrowCap(balance *big.Int) *Validator {
+	capped := *v
+	capped.escrowCap = balance
+	return &capped
+}
+
+// AddAcceptedSigner accepts RAVs signed by addr from now on
+func (v *Validator) AddAcceptedSigner(addr eth.Address) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.acceptedSigners[addr.Pretty()] = true
+}
+
+// IsAcceptedSigner returns true if RAVs signed by addr are accepted
+func (v *Validator) IsAcceptedSigner(addr eth.Address) bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	return v.acceptedSigners[addr.Pretty()]
+}
+
+// AcceptedSigners returns the accepted signers, in no particular order
+func (v *Validator) AcceptedSigners() []eth.Address {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	signers := make([]eth.Address, 0, len(v.acceptedSigners))
+	for addr := range v.acceptedSigners {
+		signers = append(signers, eth.MustNewAddress(addr))
+	}
+	return signers
+}
+
+// Validate checks next, previous being the last accepted RAV of the collection
+// or nil for the first one, under policy. The previous RAV is trusted and not
+// re-verified. Checks run in order: metadata, validity window, contract
+// semantics, signature, signer, policy parties and collection, continuity with
+// previous, escrow balance and registered MetadataValidators. The first one
+// failing is returned, wrapping one of the errors above or of the metadata and
+// validity checks so callers can tell them apart with errors.Is.
+func (v *Validator) Validate(previous, next *SignedRAV, policy Policy) error {
+	if next == nil || next.Message == nil {
+		return ErrRAVMissing
+	}
+	rav := next.Message
+
+	if policy.MaxMetadataSize > 0 {
+		if err := ValidateMetadataSize(rav.Metadata, policy.MaxMetadataSize); err != nil {
+			return err
+		}
+	}
+	if policy.StrictMetadata {
+		if _, err := ValidateMetadataFormat(rav); err != nil {
+			return err
+		}
+	}
+	if !policy.Now.IsZero() {
+		if err := CheckRAVValidity(rav, policy.Now); err != nil {
+			return err
+		}
+	}
+
+	if err := ValidateAgainstContractSemantics(rav); err != nil {
+		return fmt.Errorf("%w: %w", ErrRAVNotCollectable, err)
+	}
+
+	signer, err := next.RecoverSigner(v.domain)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRAVSignatureInvalid, err)
+	}
+	if !v.IsAcceptedSigner(signer) {
+		return fmt.Errorf("%w: %s", ErrRAVUnauthorizedSigner, signer.Pretty())
+	}
+
+	if parties := policy.Parties; parties != nil {
+		if !addressesEqual(rav.Payer, parties.Payer) ||
+			!addressesEqual(rav.ServiceProvider, parties.ServiceProvider) ||
+			!addressesEqual(rav.DataService, parties.DataService) {
+			return fmt.Errorf("%w: payer %s, service provider %s, data service %s", ErrRAVPartiesMismatch, rav.Payer.Pretty(), rav.ServiceProvider.Pretty(), rav.DataService.Pretty())
+		}
+	}
+	if policy.CollectionID != nil && rav.CollectionID != *policy.CollectionID {
+		return fmt.Errorf("%w: %x", ErrRAVCollectionMismatch, rav.CollectionID[:])
+	}
+
+	if previous != nil {
+		if previous.Message == nil {
+			return ErrPreviousRAVMissing
+		}
+		if err := validateRAVContinuity(previous.Message, rav, policy.AllowTimestampRegression); err != nil {
+			return err
+		}
+	}
+
+	balance := v.escrowCap
+	if policy.EscrowBalance != nil {
+		balance = policy.EscrowBalance(rav.Payer)
+	}
+	if balance != nil {
+		capped := rav
+		if policy.Collected != nil {
+			if collected := policy.Collected(rav.CollectionID); collected != nil && collected.Sign() > 0 {
+				uncollected := *rav
+				uncollected.ValueAggregate = new(big.Int).Sub(rav.ValueAggregate, collected)
+				capped = &uncollected
+			}
+		}
+		if err := ValidateEscrowCap(capped, balance, v.escrowCapTolerance); err != nil {
+			return err
+		}
+	}
+
+	return v.validateMetadata(previous, rav, nil)
+}
+
+func validateRAVContinuity(previous, next *RAV, allowTimestampRegression bool) error {
+	if previous.CollectionID != next.CollectionID {
+		return ErrRAVCollectionChanged
+	}
+	if !addressesEqual(previous.Payer, next.Payer) ||
+		!addressesEqual(previous.ServiceProvider, next.ServiceProvider) ||
+		!addressesEqual(previous.DataService, next.DataService) {
+		return ErrRAVPartiesChanged
+	}
+	if next.TimestampNs < previous.TimestampNs && !allowTimestampRegression {
+		return fmt.Errorf("%w: %d < %d", ErrRAVTimestampRegression, next.TimestampNs, previous.TimestampNs)
+	}
+	if previous.ValueAggregate != nil && next.ValueAggregate.Cmp(previous.ValueAggregate) < 0 {
+		return fmt.Errorf("%w: %s < %s", ErrRAVValueDecreased, next.ValueAggregate, previous.ValueAggregate)
+	}
+	return nil
+}
+
+// ValidateEscrowCap rejects rav when its value aggregate exceeds the escrow
+// balance snapshot plus tolerance (none when nil). Such a RAV cannot be fully
+// collected, accepting it means serving data that may never be paid.
+func ValidateEscrowCap(rav *RAV, balance, tolerance *big.Int) error {
+	limit := new(big.Int).Set(balance)
+	if tolerance != nil {
+		limit.Add(limit, tolerance)
+	}
+	if rav.ValueAggregate.Cmp(limit) > 0 {
+		return fmt.Errorf("%w: %s > %s", ErrRAVExceedsEscrow, rav.ValueAggregate, limit)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package horizon
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidator_Validate(t *testing.T) {
+	domain := NewDomain(1, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	otherKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	validator := NewValidator(domain, []eth.Address{signerKey.PublicKey().Address()})
+
+	newRAV := func(key *eth.PrivateKey, timestampNs uint64, value int64, mutate func(rav *RAV)) *SignedRAV {
+		rav := &RAV{
+			CollectionID:    CollectionID{0x01},
+			Payer:           eth.MustNewAddress("0x1111111111111111111111111111111111111111"),
+			DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+			ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+			TimestampNs:     timestampNs,
+			ValueAggregate:  big.NewInt(value),
+		}
+		if mutate != nil {
+			mutate(rav)
+		}
+
+		signed, err := Sign(domain, rav, key)
+		require.NoError(t, err)
+		return signed
+	}
+
+	previous := newRAV(signerKey, 100, 1000, nil)
+	parties := RAVParties{
+		Payer:           previous.Message.Payer,
+		ServiceProvider: previous.Message.ServiceProvider,
+		DataService:     previous.Message.DataService,
+	}
+
+	tests := []struct {
+		name     string
+		previous *SignedRAV
+		next     *SignedRAV
+		policy   Policy
+		expected error
+	}{
+		{"first RAV", nil, previous, Policy{}, nil},
+		{"next RAV", previous, newRAV(signerKey, 200, 2000, nil), Policy{}, nil},
+		{"same RAV", previous, previous, Policy{}, nil},
+		{"missing RAV", previous, nil, Policy{}, ErrRAVMissing},
+		{"unauthorized signer", nil, newRAV(otherKey, 200, 2000, nil), Policy{}, ErrRAVUnauthorizedSigner},
+		{"not collectable", nil, newRAV(signerKey, 200, 2000, func(rav *RAV) { rav.Payer = make(eth.Address, 20) }), Policy{}, ErrRAVZeroPayer},
+		{"collection changed", previous, newRAV(signerKey, 200, 2000, func(rav *RAV) { rav.CollectionID = CollectionID{0x02} }), Policy{}, ErrRAVCollectionChanged},
+		{"payer changed", previous, newRAV(signerKey, 200, 2000, func(rav *RAV) {
+			rav.Payer = eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+		}), Policy{}, ErrRAVPartiesChanged},
+		{"timestamp regression", previous, newRAV(signerKey, 50, 2000, nil), Policy{}, ErrRAVTimestampRegression},
+		{"value decreased", previous, newRAV(signerKey, 200, 500, nil), Policy{}, ErrRAVValueDecreased},
+		{"invalid signature", nil, &SignedRAV{Message: previous.Message}, Policy{}, ErrRAVSignatureInvalid},
+		{"expected parties", nil, previous, Policy{Parties: &parties}, nil},
+		{"parties mismatch", nil, newRAV(signerKey, 200, 2000, func(rav *RAV) {
+			rav.DataService = eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+		}), Policy{Parties: &parties}, ErrRAVPartiesMismatch},
+		{"collection mismatch", nil, previous, Policy{CollectionID: &CollectionID{0x02}}, ErrRAVCollectionMismatch},
+		{"metadata too large", nil, newRAV(signerKey, 200, 2000, func(rav *RAV) { rav.Metadata = make([]byte, 64) }), Policy{MaxMetadataSize: 32}, ErrMetadataTooLarge},
+		{"unknown metadata", nil, newRAV(signerKey, 200, 2000, func(rav *RAV) { rav.Metadata = []byte{0xca, 0xfe} }), Policy{StrictMetadata: true}, ErrMetadataUnknownType},
+		{"expired", nil, newRAV(signerKey, 200, 2000, func(rav *RAV) {
+			rav.Metadata = EncodeRAVValidityMetadata(rav.CollectionID, time.Unix(100, 0))
+		}), Policy{Now: time.Unix(200, 0)}, ErrRAVExpired},
+		{"timestamp regression allowed", previous, newRAV(signerKey, 50, 2000, nil), Policy{AllowTimestampRegression: true}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.Validate(tt.previous, tt.next, tt.policy)
+			if tt.expected == nil {
+				require.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.expected)
+		})
+	}
+
+	// Signers can be accepted while the validator is in use
+	validator.AddAcceptedSigner(otherKey.PublicKey().Address())
+	assert.True(t, validator.IsAcceptedSigner(otherKey.PublicKey().Address()))
+	assert.Len(t, validator.AcceptedSigners(), 2)
+	require.NoError(t, validator.Validate(nil, newRAV(otherKey, 200, 2000, nil), Policy{}))
+}
+
+func TestValidator_MetadataValidator(t *testing.T) {
+	domain := NewDomain(1, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	errGap := errors.New("block range gap")
+
+	// Metadata holds the last block covered, each RAV must continue the previous one
+	validator := NewValidator(domain, []eth.Address{signerKey.PublicKey().Address()},
+		WithMetadataValidator(func(previous *RAV, next *RAV, receipts []*SignedReceipt) error {
+			if previous == nil {
+				return nil
+			}
+			if !bytes.HasPrefix(next.Metadata, previous.Metadata) {
+				return errGap
+			}
+			return nil
+		}),
+	)
+
+	newRAV := func(timestampNs uint64, metadata string) *SignedRAV {
+		signed, err := Sign(domain, &RAV{
+			Payer:           eth.MustNewAddress("0x1111111111111111111111111111111111111111"),
+			DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+			ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+			TimestampNs:     timestampNs,
+			ValueAggregate:  big.NewInt(100),
+			Metadata:        []byte(metadata),
+		}, signerKey)
+		require.NoError(t, err)
+		return signed
+	}
+
+	previous := newRAV(100, "0-100")
+
+	require.NoError(t, validator.Validate(previous, newRAV(200, "0-100,101-200"), Policy{}))
+
+	err = validator.Validate(previous, newRAV(200, "150-200"), Policy{})
+	assert.ErrorIs(t, err, ErrMetadataRejected)
+	assert.ErrorIs(t, err, errGap)
+}
+
+func TestValidator_EscrowBalance(t *testing.T) {
+	domain := NewDomain(1, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	newRAV := func(value int64) *SignedRAV {
+		signed, err := Sign(domain, &RAV{
+			CollectionID:    CollectionID{0x01},
+			Payer:           eth.MustNewAddress("0x1111111111111111111111111111111111111111"),
+			DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+			ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+			TimestampNs:     100,
+			ValueAggregate:  big.NewInt(value),
+		}, signerKey)
+		require.NoError(t, err)
+		return signed
+	}
+
+	signers := []eth.Address{signerKey.PublicKey().Address()}
+
+	validator := NewValidator(domain, signers)
+	capped := Policy{EscrowBalance: func(payer eth.Address) *big.Int { return big.NewInt(1000) }}
+	require.NoError(t, validator.Validate(nil, newRAV(1000), capped))
+	assert.ErrorIs(t, validator.Validate(nil, newRAV(1001), capped), ErrRAVExceedsEscrow)
+	require.NoError(t, validator.Validate(nil, newRAV(1001), Policy{}))
+	require.NoError(t, validator.Validate(nil, newRAV(1001), Policy{EscrowBalance: func(eth.Address) *big.Int { return nil }}))
+
+	tolerant := NewValidator(domain, signers, WithEscrowCapTolerance(big.NewInt(100)))
+	require.NoError(t, tolerant.Validate(nil, newRAV(1100), capped))
+	assert.ErrorIs(t, tolerant.Validate(nil, newRAV(1101), capped), ErrRAVExceedsEscrow)
+
+	// Only the value not collected yet is bounded by the escrow balance
+	capped.Collected = func(collectionID CollectionID) *big.Int { return big.NewInt(500) }
+	require.NoError(t, validator.Validate(nil, newRAV(1500), capped))
+	assert.ErrorIs(t, validator.Validate(nil, newRAV(1501), capped), ErrRAVExceedsEscrow)
+}
+
+func TestValidator_WithEscrowCap(t *testing.T) {
+	domain := NewDomain(1, eth.MustNewAddress("0x1234567890123456789012345678901234567890"))
+
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	otherKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	newRAV := func(key *eth.PrivateKey, value int64) *SignedRAV {
+		signed, err := Sign(domain, &RAV{
+			CollectionID:    CollectionID{0x01},
+			Payer:           eth.MustNewAddress("0x1111111111111111111111111111111111111111"),
+			DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+			ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+			TimestampNs:     100,
+			ValueAggregate:  big.NewInt(value),
+		}, key)
+		require.NoError(t, err)
+		return signed
+	}
+
+	validator := NewValidator(domain, []eth.Address{signerKey.PublicKey().Address()})
+	capped := validator.WithEscrowCap(big.NewInt(1000))
+	require.NoError(t, capped.Validate(nil, newRAV(signerKey, 1000), Policy{}))
+	assert.ErrorIs(t, capped.Validate(nil, newRAV(signerKey, 1001), Policy{}), ErrRAVExceedsEscrow)
+
+	// The cap applies to the returned copy only, a policy escrow balance
+	// replaces it
+	require.NoError(t, validator.Validate(nil, newRAV(signerKey, 1001), Policy{}))
+	require.NoError(t, capped.WithEscrowCap(nil).Validate(nil, newRAV(signerKey, 1001), Policy{}))
+	require.NoError(t, capped.Validate(nil, newRAV(signerKey, 1500), Policy{EscrowBalance: func(eth.Address) *big.Int { return big.NewInt(2000) }}))
+
+	// Accepted signers are shared with the copy
+	validator.AddAcceptedSigner(otherKey.PublicKey().Address())
+	require.NoError(t, capped.Validate(nil, newRAV(otherKey, 1000), Policy{}))
+}
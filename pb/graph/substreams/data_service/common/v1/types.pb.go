@@ -21,7 +21,62 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
-// EndReason indicates why a session ended.
+// PaymentMode is how usage of a session is paid for.
+type PaymentMode int32
+
+const (
+	// The sidecar's configured default mode
+	PaymentMode_PAYMENT_MODE_UNSPECIFIED PaymentMode = 0
+	// The consumer signs an updated RAV for each usage report
+	PaymentMode_PAYMENT_MODE_RAV PaymentMode = 1
+	// The consumer signs a receipt for each usage report, the provider sidecar
+	// aggregates the receipts into RAVs periodically (classic TAP flow)
+	PaymentMode_PAYMENT_MODE_RECEIPTS PaymentMode = 2
+)
+
+// Enum value maps for PaymentMode.
+var (
+	PaymentMode_name = map[int32]string{
+		0: "PAYMENT_MODE_UNSPECIFIED",
+		1: "PAYMENT_MODE_RAV",
+		2: "PAYMENT_MODE_RECEIPTS",
+	}
+	PaymentMode_value = map[string]int32{
+		"PAYMENT_MODE_UNSPECIFIED": 0,
+		"PAYMENT_MODE_RAV":         1,
+		"PAYMENT_MODE_RECEIPTS":    2,
+	}
+)
+
+func (x PaymentMode) Enum() *PaymentMode {
+	p := new(PaymentMode)
+	*p = x
+	return p
+}
+
+func (x PaymentMode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (PaymentMode) Descriptor() protoreflect.EnumDescriptor {
+	return file_graph_substreams_data_service_common_v1_types_proto_enumTypes[0].Descriptor()
+}
+
+func (PaymentMode) Type() protoreflect.EnumType {
+	return &file_graph_substreams_data_service_common_v1_types_proto_enumTypes[0]
+}
+
+func (x PaymentMode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use PaymentMode.Descriptor instead.
+func (PaymentMode) EnumDescriptor() ([]byte, []int) {
+	return file_graph_substreams_data_service_common_v1_types_proto_rawDescGZIP(), []int{0}
+}
+
+// EndReason indicates why a session ended. Values are stable machine codes,
+// UIs map them to localized text.
 type EndReason int32
 
 const (
@@ -69,11 +124,11 @@ func (x EndReason) String() string {
 }
 
 func (EndReason) Descriptor() protoreflect.EnumDescriptor {
-	return file_graph_substreams_data_service_common_v1_types_proto_enumTypes[0].Descriptor()
+	return file_graph_substreams_data_service_common_v1_types_proto_enumTypes[1].Descriptor()
 }
 
 func (EndReason) Type() protoreflect.EnumType {
-	return &file_graph_substreams_data_service_common_v1_types_proto_enumTypes[0]
+	return &file_graph_substreams_data_service_common_v1_types_proto_enumTypes[1]
 }
 
 func (x EndReason) Number() protoreflect.EnumNumber {
@@ -82,7 +137,129 @@ func (x EndReason) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use EndReason.Descriptor instead.
 func (EndReason) EnumDescriptor() ([]byte, []int) {
-	return file_graph_substreams_data_service_common_v1_types_proto_rawDescGZIP(), []int{0}
+	return file_graph_substreams_data_service_common_v1_types_proto_rawDescGZIP(), []int{1}
+}
+
+// RejectionCode is the stable machine code of why a payment, RAV or session was
+// rejected or stopped. The human-readable reason sent along is meant for logs
+// and may change, UIs localize and tests assert on the code instead.
+type RejectionCode int32
+
+const (
+	RejectionCode_REJECTION_CODE_UNSPECIFIED RejectionCode = 0
+	// The RAV is missing or malformed
+	RejectionCode_REJECTION_CODE_INVALID_RAV RejectionCode = 1
+	// The collector contract would refuse to collect the RAV
+	RejectionCode_REJECTION_CODE_RAV_NOT_COLLECTABLE RejectionCode = 2
+	// The RAV signature could not be verified
+	RejectionCode_REJECTION_CODE_INVALID_SIGNATURE RejectionCode = 3
+	// The RAV signer is not authorized by the payer
+	RejectionCode_REJECTION_CODE_UNAUTHORIZED_SIGNER RejectionCode = 4
+	// The RAV or escrow account parties do not match the session or the service provider
+	RejectionCode_REJECTION_CODE_PARTY_MISMATCH RejectionCode = 5
+	// The escrow account collector is not supported by the service provider
+	RejectionCode_REJECTION_CODE_UNSUPPORTED_COLLECTOR RejectionCode = 6
+	// The RAV value aggregate is lower than the current RAV's
+	RejectionCode_REJECTION_CODE_RAV_VALUE_DECREASED RejectionCode = 7
+	// The RAV value aggregate exceeds the payer's escrow balance
+	RejectionCode_REJECTION_CODE_EXCEEDS_ESCROW RejectionCode = 8
+	// The session-initiating RAV was already used to open an ended session
+	RejectionCode_REJECTION_CODE_RAV_REPLAYED RejectionCode = 9
+	// The session does not exist
+	RejectionCode_REJECTION_CODE_SESSION_NOT_FOUND RejectionCode = 10
+	// The session is not active anymore
+	RejectionCode_REJECTION_CODE_SESSION_NOT_ACTIVE RejectionCode = 11
+	// The request is malformed, e.g. receipts submitted along with a RAV
+	RejectionCode_REJECTION_CODE_INVALID_REQUEST RejectionCode = 12
+	// The aggregator could not aggregate the submitted receipts
+	RejectionCode_REJECTION_CODE_AGGREGATION_FAILED RejectionCode = 13
+	// The payer's escrow funds are insufficient
+	RejectionCode_REJECTION_CODE_INSUFFICIENT_FUNDS RejectionCode = 14
+	// The consumer spending budget is exhausted
+	RejectionCode_REJECTION_CODE_BUDGET_EXCEEDED RejectionCode = 15
+	// An internal error occurred
+	RejectionCode_REJECTION_CODE_INTERNAL RejectionCode = 16
+	// The consumer did not return a signed RAV in time
+	RejectionCode_REJECTION_CODE_RAV_OVERDUE RejectionCode = 17
+	// The payment violates the service provider's acceptance policy
+	RejectionCode_REJECTION_CODE_POLICY_VIOLATION RejectionCode = 18
+	// The service provider is shutting down
+	RejectionCode_REJECTION_CODE_SHUTTING_DOWN RejectionCode = 19
+)
+
+// Enum value maps for RejectionCode.
+var (
+	RejectionCode_name = map[int32]string{
+		0:  "REJECTION_CODE_UNSPECIFIED",
+		1:  "REJECTION_CODE_INVALID_RAV",
+		2:  "REJECTION_CODE_RAV_NOT_COLLECTABLE",
+		3:  "REJECTION_CODE_INVALID_SIGNATURE",
+		4:  "REJECTION_CODE_UNAUTHORIZED_SIGNER",
+		5:  "REJECTION_CODE_PARTY_MISMATCH",
+		6:  "REJECTION_CODE_UNSUPPORTED_COLLECTOR",
+		7:  "REJECTION_CODE_RAV_VALUE_DECREASED",
+		8:  "REJECTION_CODE_EXCEEDS_ESCROW",
+		9:  "REJECTION_CODE_RAV_REPLAYED",
+		10: "REJECTION_CODE_SESSION_NOT_FOUND",
+		11: "REJECTION_CODE_SESSION_NOT_ACTIVE",
+		12: "REJECTION_CODE_INVALID_REQUEST",
+		13: "REJECTION_CODE_AGGREGATION_FAILED",
+		14: "REJECTION_CODE_INSUFFICIENT_FUNDS",
+		15: "REJECTION_CODE_BUDGET_EXCEEDED",
+		16: "REJECTION_CODE_INTERNAL",
+		17: "REJECTION_CODE_RAV_OVERDUE",
+		18: "REJECTION_CODE_POLICY_VIOLATION",
+		19: "REJECTION_CODE_SHUTTING_DOWN",
+	}
+	RejectionCode_value = map[string]int32{
+		"REJECTION_CODE_UNSPECIFIED":           0,
+		"REJECTION_CODE_INVALID_RAV":           1,
+		"REJECTION_CODE_RAV_NOT_COLLECTABLE":   2,
+		"REJECTION_CODE_INVALID_SIGNATURE":     3,
+		"REJECTION_CODE_UNAUTHORIZED_SIGNER":   4,
+		"REJECTION_CODE_PARTY_MISMATCH":        5,
+		"REJECTION_CODE_UNSUPPORTED_COLLECTOR": 6,
+		"REJECTION_CODE_RAV_VALUE_DECREASED":   7,
+		"REJECTION_CODE_EXCEEDS_ESCROW":        8,
+		"REJECTION_CODE_RAV_REPLAYED":          9,
+		"REJECTION_CODE_SESSION_NOT_FOUND":     10,
+		"REJECTION_CODE_SESSION_NOT_ACTIVE":    11,
+		"REJECTION_CODE_INVALID_REQUEST":       12,
+		"REJECTION_CODE_AGGREGATION_FAILED":    13,
+		"REJECTION_CODE_INSUFFICIENT_FUNDS":    14,
+		"REJECTION_CODE_BUDGET_EXCEEDED":       15,
+		"REJECTION_CODE_INTERNAL":              16,
+		"REJECTION_CODE_RAV_OVERDUE":           17,
+		"REJECTION_CODE_POLICY_VIOLATION":      18,
+		"REJECTION_CODE_SHUTTING_DOWN":         19,
+	}
+)
+
+func (x RejectionCode) Enum() *RejectionCode {
+	p := new(RejectionCode)
+	*p = x
+	return p
+}
+
+func (x RejectionCode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RejectionCode) Descriptor() protoreflect.EnumDescriptor {
+	return file_graph_substreams_data_service_common_v1_types_proto_enumTypes[2].Descriptor()
+}
+
+func (RejectionCode) Type() protoreflect.EnumType {
+	return &file_graph_substreams_data_service_common_v1_types_proto_enumTypes[2]
+}
+
+func (x RejectionCode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RejectionCode.Descriptor instead.
+func (RejectionCode) EnumDescriptor() ([]byte, []int) {
+	return file_graph_substreams_data_service_common_v1_types_proto_rawDescGZIP(), []int{2}
 }
 
 // Address represents an Ethereum address (20 bytes).
@@ -324,6 +501,162 @@ func (x *RAV) GetMetadata() []byte {
 	return nil
 }
 
+// SignedReceipt represents a signed receipt, the unit of payment that
+// aggregators combine into RAVs.
+type SignedReceipt struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The receipt data that was signed
+	Receipt *Receipt `protobuf:"bytes,1,opt,name=receipt,proto3" json:"receipt,omitempty"`
+	// The signature over the receipt (EIP-712 typed data signature)
+	Signature     []byte `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SignedReceipt) Reset() {
+	*x = SignedReceipt{}
+	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SignedReceipt) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SignedReceipt) ProtoMessage() {}
+
+func (x *SignedReceipt) ProtoReflect() protoreflect.Message {
+	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SignedReceipt.ProtoReflect.Descriptor instead.
+func (*SignedReceipt) Descriptor() ([]byte, []int) {
+	return file_graph_substreams_data_service_common_v1_types_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SignedReceipt) GetReceipt() *Receipt {
+	if x != nil {
+		return x.Receipt
+	}
+	return nil
+}
+
+func (x *SignedReceipt) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+// Receipt represents a single payment for usage, aggregated into a RAV later on.
+type Receipt struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The collection this receipt belongs to (32 bytes)
+	CollectionId []byte `protobuf:"bytes,1,opt,name=collection_id,json=collectionId,proto3" json:"collection_id,omitempty"`
+	// The payer's address
+	Payer *Address `protobuf:"bytes,2,opt,name=payer,proto3" json:"payer,omitempty"`
+	// The data service contract address
+	DataService *Address `protobuf:"bytes,3,opt,name=data_service,json=dataService,proto3" json:"data_service,omitempty"`
+	// The service provider's address
+	ServiceProvider *Address `protobuf:"bytes,4,opt,name=service_provider,json=serviceProvider,proto3" json:"service_provider,omitempty"`
+	// Timestamp when this receipt was created (Unix nanoseconds)
+	TimestampNs uint64 `protobuf:"varint,5,opt,name=timestamp_ns,json=timestampNs,proto3" json:"timestamp_ns,omitempty"`
+	// Random nonce making the receipt unique
+	Nonce uint64 `protobuf:"varint,6,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	// Value in GRT (wei) of this receipt
+	Value         *BigInt `protobuf:"bytes,7,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Receipt) Reset() {
+	*x = Receipt{}
+	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Receipt) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Receipt) ProtoMessage() {}
+
+func (x *Receipt) ProtoReflect() protoreflect.Message {
+	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Receipt.ProtoReflect.Descriptor instead.
+func (*Receipt) Descriptor() ([]byte, []int) {
+	return file_graph_substreams_data_service_common_v1_types_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Receipt) GetCollectionId() []byte {
+	if x != nil {
+		return x.CollectionId
+	}
+	return nil
+}
+
+func (x *Receipt) GetPayer() *Address {
+	if x != nil {
+		return x.Payer
+	}
+	return nil
+}
+
+func (x *Receipt) GetDataService() *Address {
+	if x != nil {
+		return x.DataService
+	}
+	return nil
+}
+
+func (x *Receipt) GetServiceProvider() *Address {
+	if x != nil {
+		return x.ServiceProvider
+	}
+	return nil
+}
+
+func (x *Receipt) GetTimestampNs() uint64 {
+	if x != nil {
+		return x.TimestampNs
+	}
+	return 0
+}
+
+func (x *Receipt) GetNonce() uint64 {
+	if x != nil {
+		return x.Nonce
+	}
+	return 0
+}
+
+func (x *Receipt) GetValue() *BigInt {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
 // Usage represents metered usage during a session.
 type Usage struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -334,14 +667,21 @@ type Usage struct {
 	// Number of requests made
 	Requests uint64 `protobuf:"varint,3,opt,name=requests,proto3" json:"requests,omitempty"`
 	// Computed cost in GRT (wei) for this usage
-	Cost          *BigInt `protobuf:"bytes,4,opt,name=cost,proto3" json:"cost,omitempty"`
+	Cost *BigInt `protobuf:"bytes,4,opt,name=cost,proto3" json:"cost,omitempty"`
+	// When the usage was metered (Unix nanoseconds), set on the entries of
+	// batched usage reports
+	TimestampNs uint64 `protobuf:"varint,5,opt,name=timestamp_ns,json=timestampNs,proto3" json:"timestamp_ns,omitempty"`
+	// Breakdown of the usage by category (e.g. preprocessed vs live blocks,
+	// egress bytes, or a module), for pricing and spend reports. Categories
+	// are parts of the totals above, usage not in any category is uncategorized.
+	Categories    []*UsageCategory `protobuf:"bytes,6,rep,name=categories,proto3" json:"categories,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *Usage) Reset() {
 	*x = Usage{}
-	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[4]
+	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -353,7 +693,7 @@ func (x *Usage) String() string {
 func (*Usage) ProtoMessage() {}
 
 func (x *Usage) ProtoReflect() protoreflect.Message {
-	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[4]
+	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -366,7 +706,7 @@ func (x *Usage) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Usage.ProtoReflect.Descriptor instead.
 func (*Usage) Descriptor() ([]byte, []int) {
-	return file_graph_substreams_data_service_common_v1_types_proto_rawDescGZIP(), []int{4}
+	return file_graph_substreams_data_service_common_v1_types_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *Usage) GetBlocksProcessed() uint64 {
@@ -397,6 +737,20 @@ func (x *Usage) GetCost() *BigInt {
 	return nil
 }
 
+func (x *Usage) GetTimestampNs() uint64 {
+	if x != nil {
+		return x.TimestampNs
+	}
+	return 0
+}
+
+func (x *Usage) GetCategories() []*UsageCategory {
+	if x != nil {
+		return x.Categories
+	}
+	return nil
+}
+
 // EscrowAccount identifies an escrow deposit that funds payments.
 type EscrowAccount struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -405,14 +759,18 @@ type EscrowAccount struct {
 	// The receiver's address (service provider)
 	Receiver *Address `protobuf:"bytes,2,opt,name=receiver,proto3" json:"receiver,omitempty"`
 	// The data service contract address
-	DataService   *Address `protobuf:"bytes,3,opt,name=data_service,json=dataService,proto3" json:"data_service,omitempty"`
+	DataService *Address `protobuf:"bytes,3,opt,name=data_service,json=dataService,proto3" json:"data_service,omitempty"`
+	// The collector contract address, escrow accounts are keyed by
+	// (payer, collector, receiver). When unset, the sidecar's default collector
+	// is used.
+	Collector     *Address `protobuf:"bytes,4,opt,name=collector,proto3" json:"collector,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *EscrowAccount) Reset() {
 	*x = EscrowAccount{}
-	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[5]
+	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -424,7 +782,7 @@ func (x *EscrowAccount) String() string {
 func (*EscrowAccount) ProtoMessage() {}
 
 func (x *EscrowAccount) ProtoReflect() protoreflect.Message {
-	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[5]
+	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -437,7 +795,7 @@ func (x *EscrowAccount) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EscrowAccount.ProtoReflect.Descriptor instead.
 func (*EscrowAccount) Descriptor() ([]byte, []int) {
-	return file_graph_substreams_data_service_common_v1_types_proto_rawDescGZIP(), []int{5}
+	return file_graph_substreams_data_service_common_v1_types_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *EscrowAccount) GetPayer() *Address {
@@ -461,6 +819,13 @@ func (x *EscrowAccount) GetDataService() *Address {
 	return nil
 }
 
+func (x *EscrowAccount) GetCollector() *Address {
+	if x != nil {
+		return x.Collector
+	}
+	return nil
+}
+
 // SessionInfo contains information about an active payment session.
 type SessionInfo struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -478,7 +843,7 @@ type SessionInfo struct {
 
 func (x *SessionInfo) Reset() {
 	*x = SessionInfo{}
-	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[6]
+	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -490,7 +855,7 @@ func (x *SessionInfo) String() string {
 func (*SessionInfo) ProtoMessage() {}
 
 func (x *SessionInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[6]
+	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -503,7 +868,7 @@ func (x *SessionInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SessionInfo.ProtoReflect.Descriptor instead.
 func (*SessionInfo) Descriptor() ([]byte, []int) {
-	return file_graph_substreams_data_service_common_v1_types_proto_rawDescGZIP(), []int{6}
+	return file_graph_substreams_data_service_common_v1_types_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *SessionInfo) GetSessionId() string {
@@ -549,7 +914,7 @@ type ServiceParameters struct {
 
 func (x *ServiceParameters) Reset() {
 	*x = ServiceParameters{}
-	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[7]
+	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -561,7 +926,7 @@ func (x *ServiceParameters) String() string {
 func (*ServiceParameters) ProtoMessage() {}
 
 func (x *ServiceParameters) ProtoReflect() protoreflect.Message {
-	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[7]
+	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -574,7 +939,7 @@ func (x *ServiceParameters) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ServiceParameters.ProtoReflect.Descriptor instead.
 func (*ServiceParameters) Descriptor() ([]byte, []int) {
-	return file_graph_substreams_data_service_common_v1_types_proto_rawDescGZIP(), []int{7}
+	return file_graph_substreams_data_service_common_v1_types_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *ServiceParameters) GetRequiredBlocksPreproc() uint64 {
@@ -617,7 +982,7 @@ type PaymentStatus struct {
 
 func (x *PaymentStatus) Reset() {
 	*x = PaymentStatus{}
-	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[8]
+	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -629,7 +994,7 @@ func (x *PaymentStatus) String() string {
 func (*PaymentStatus) ProtoMessage() {}
 
 func (x *PaymentStatus) ProtoReflect() protoreflect.Message {
-	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[8]
+	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -642,7 +1007,7 @@ func (x *PaymentStatus) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PaymentStatus.ProtoReflect.Descriptor instead.
 func (*PaymentStatus) Descriptor() ([]byte, []int) {
-	return file_graph_substreams_data_service_common_v1_types_proto_rawDescGZIP(), []int{8}
+	return file_graph_substreams_data_service_common_v1_types_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *PaymentStatus) GetCurrentRavValue() *BigInt {
@@ -680,6 +1045,90 @@ func (x *PaymentStatus) GetEstimatedBlocksRemaining() uint64 {
 	return 0
 }
 
+// UsageCategory is the part of a usage in one category.
+type UsageCategory struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Name of the category: "preprocessed" and "live" for blocks computed
+	// ahead of and at the chain head, "cached" for blocks served from cache,
+	// "egress" for bytes sent, "module:<name>" for a module's share
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Number of blocks processed in the category
+	BlocksProcessed uint64 `protobuf:"varint,2,opt,name=blocks_processed,json=blocksProcessed,proto3" json:"blocks_processed,omitempty"`
+	// Number of bytes transferred in the category
+	BytesTransferred uint64 `protobuf:"varint,3,opt,name=bytes_transferred,json=bytesTransferred,proto3" json:"bytes_transferred,omitempty"`
+	// Number of requests made in the category
+	Requests uint64 `protobuf:"varint,4,opt,name=requests,proto3" json:"requests,omitempty"`
+	// Computed cost in GRT (wei) of the category
+	Cost          *BigInt `protobuf:"bytes,5,opt,name=cost,proto3" json:"cost,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UsageCategory) Reset() {
+	*x = UsageCategory{}
+	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UsageCategory) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UsageCategory) ProtoMessage() {}
+
+func (x *UsageCategory) ProtoReflect() protoreflect.Message {
+	mi := &file_graph_substreams_data_service_common_v1_types_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UsageCategory.ProtoReflect.Descriptor instead.
+func (*UsageCategory) Descriptor() ([]byte, []int) {
+	return file_graph_substreams_data_service_common_v1_types_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *UsageCategory) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *UsageCategory) GetBlocksProcessed() uint64 {
+	if x != nil {
+		return x.BlocksProcessed
+	}
+	return 0
+}
+
+func (x *UsageCategory) GetBytesTransferred() uint64 {
+	if x != nil {
+		return x.BytesTransferred
+	}
+	return 0
+}
+
+func (x *UsageCategory) GetRequests() uint64 {
+	if x != nil {
+		return x.Requests
+	}
+	return 0
+}
+
+func (x *UsageCategory) GetCost() *BigInt {
+	if x != nil {
+		return x.Cost
+	}
+	return nil
+}
+
 var File_graph_substreams_data_service_common_v1_types_proto protoreflect.FileDescriptor
 
 const file_graph_substreams_data_service_common_v1_types_proto_rawDesc = "" +
@@ -698,16 +1147,32 @@ const file_graph_substreams_data_service_common_v1_types_proto_rawDesc = "" +
 	"\x10service_provider\x18\x03 \x01(\v20.graph.substreams.data_service.common.v1.AddressR\x0fserviceProvider\x12!\n" +
 	"\ftimestamp_ns\x18\x04 \x01(\x04R\vtimestampNs\x12X\n" +
 	"\x0fvalue_aggregate\x18\x05 \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\x0evalueAggregate\x12\x1a\n" +
-	"\bmetadata\x18\x06 \x01(\fR\bmetadata\"\xc0\x01\n" +
+	"\bmetadata\x18\x06 \x01(\fR\bmetadata\"y\n" +
+	"\rSignedReceipt\x12J\n" +
+	"\areceipt\x18\x01 \x01(\v20.graph.substreams.data_service.common.v1.ReceiptR\areceipt\x12\x1c\n" +
+	"\tsignature\x18\x02 \x01(\fR\tsignature\"\xa8\x03\n" +
+	"\aReceipt\x12#\n" +
+	"\rcollection_id\x18\x01 \x01(\fR\fcollectionId\x12F\n" +
+	"\x05payer\x18\x02 \x01(\v20.graph.substreams.data_service.common.v1.AddressR\x05payer\x12S\n" +
+	"\fdata_service\x18\x03 \x01(\v20.graph.substreams.data_service.common.v1.AddressR\vdataService\x12[\n" +
+	"\x10service_provider\x18\x04 \x01(\v20.graph.substreams.data_service.common.v1.AddressR\x0fserviceProvider\x12!\n" +
+	"\ftimestamp_ns\x18\x05 \x01(\x04R\vtimestampNs\x12\x14\n" +
+	"\x05nonce\x18\x06 \x01(\x04R\x05nonce\x12E\n" +
+	"\x05value\x18\a \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\x05value\"\xbb\x02\n" +
 	"\x05Usage\x12)\n" +
 	"\x10blocks_processed\x18\x01 \x01(\x04R\x0fblocksProcessed\x12+\n" +
 	"\x11bytes_transferred\x18\x02 \x01(\x04R\x10bytesTransferred\x12\x1a\n" +
 	"\brequests\x18\x03 \x01(\x04R\brequests\x12C\n" +
-	"\x04cost\x18\x04 \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\x04cost\"\xfa\x01\n" +
+	"\x04cost\x18\x04 \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\x04cost\x12!\n" +
+	"\ftimestamp_ns\x18\x05 \x01(\x04R\vtimestampNs\x12V\n" +
+	"\n" +
+	"categories\x18\x06 \x03(\v26.graph.substreams.data_service.common.v1.UsageCategoryR\n" +
+	"categories\"\xca\x02\n" +
 	"\rEscrowAccount\x12F\n" +
 	"\x05payer\x18\x01 \x01(\v20.graph.substreams.data_service.common.v1.AddressR\x05payer\x12L\n" +
 	"\breceiver\x18\x02 \x01(\v20.graph.substreams.data_service.common.v1.AddressR\breceiver\x12S\n" +
-	"\fdata_service\x18\x03 \x01(\v20.graph.substreams.data_service.common.v1.AddressR\vdataService\"\xbd\x02\n" +
+	"\fdata_service\x18\x03 \x01(\v20.graph.substreams.data_service.common.v1.AddressR\vdataService\x12N\n" +
+	"\tcollector\x18\x04 \x01(\v20.graph.substreams.data_service.common.v1.AddressR\tcollector\"\xbd\x02\n" +
 	"\vSessionInfo\x12\x1d\n" +
 	"\n" +
 	"session_id\x18\x01 \x01(\tR\tsessionId\x12]\n" +
@@ -724,14 +1189,46 @@ const file_graph_substreams_data_service_common_v1_types_proto_rawDesc = "" +
 	"\x17accumulated_usage_value\x18\x02 \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\x15accumulatedUsageValue\x12V\n" +
 	"\x0eescrow_balance\x18\x03 \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\rescrowBalance\x12)\n" +
 	"\x10funds_sufficient\x18\x04 \x01(\bR\x0ffundsSufficient\x12<\n" +
-	"\x1aestimated_blocks_remaining\x18\x05 \x01(\x04R\x18estimatedBlocksRemaining*\xb4\x01\n" +
+	"\x1aestimated_blocks_remaining\x18\x05 \x01(\x04R\x18estimatedBlocksRemaining\"\xdc\x01\n" +
+	"\rUsageCategory\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12)\n" +
+	"\x10blocks_processed\x18\x02 \x01(\x04R\x0fblocksProcessed\x12+\n" +
+	"\x11bytes_transferred\x18\x03 \x01(\x04R\x10bytesTransferred\x12\x1a\n" +
+	"\brequests\x18\x04 \x01(\x04R\brequests\x12C\n" +
+	"\x04cost\x18\x05 \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\x04cost*\\\n" +
+	"\vPaymentMode\x12\x1c\n" +
+	"\x18PAYMENT_MODE_UNSPECIFIED\x10\x00\x12\x14\n" +
+	"\x10PAYMENT_MODE_RAV\x10\x01\x12\x19\n" +
+	"\x15PAYMENT_MODE_RECEIPTS\x10\x02*\xb4\x01\n" +
 	"\tEndReason\x12\x1a\n" +
 	"\x16END_REASON_UNSPECIFIED\x10\x00\x12\x17\n" +
 	"\x13END_REASON_COMPLETE\x10\x01\x12 \n" +
 	"\x1cEND_REASON_CLIENT_DISCONNECT\x10\x02\x12\x1c\n" +
 	"\x18END_REASON_PROVIDER_STOP\x10\x03\x12\x14\n" +
 	"\x10END_REASON_ERROR\x10\x04\x12\x1c\n" +
-	"\x18END_REASON_PAYMENT_ISSUE\x10\x05B\xdc\x02\n" +
+	"\x18END_REASON_PAYMENT_ISSUE\x10\x05*\xe5\x05\n" +
+	"\rRejectionCode\x12\x1e\n" +
+	"\x1aREJECTION_CODE_UNSPECIFIED\x10\x00\x12\x1e\n" +
+	"\x1aREJECTION_CODE_INVALID_RAV\x10\x01\x12&\n" +
+	"\"REJECTION_CODE_RAV_NOT_COLLECTABLE\x10\x02\x12$\n" +
+	" REJECTION_CODE_INVALID_SIGNATURE\x10\x03\x12&\n" +
+	"\"REJECTION_CODE_UNAUTHORIZED_SIGNER\x10\x04\x12!\n" +
+	"\x1dREJECTION_CODE_PARTY_MISMATCH\x10\x05\x12(\n" +
+	"$REJECTION_CODE_UNSUPPORTED_COLLECTOR\x10\x06\x12&\n" +
+	"\"REJECTION_CODE_RAV_VALUE_DECREASED\x10\a\x12!\n" +
+	"\x1dREJECTION_CODE_EXCEEDS_ESCROW\x10\b\x12\x1f\n" +
+	"\x1bREJECTION_CODE_RAV_REPLAYED\x10\t\x12$\n" +
+	" REJECTION_CODE_SESSION_NOT_FOUND\x10\n" +
+	"\x12%\n" +
+	"!REJECTION_CODE_SESSION_NOT_ACTIVE\x10\v\x12\"\n" +
+	"\x1eREJECTION_CODE_INVALID_REQUEST\x10\f\x12%\n" +
+	"!REJECTION_CODE_AGGREGATION_FAILED\x10\r\x12%\n" +
+	"!REJECTION_CODE_INSUFFICIENT_FUNDS\x10\x0e\x12\"\n" +
+	"\x1eREJECTION_CODE_BUDGET_EXCEEDED\x10\x0f\x12\x1b\n" +
+	"\x17REJECTION_CODE_INTERNAL\x10\x10\x12\x1e\n" +
+	"\x1aREJECTION_CODE_RAV_OVERDUE\x10\x11\x12#\n" +
+	"\x1fREJECTION_CODE_POLICY_VIOLATION\x10\x12\x12 \n" +
+	"\x1cREJECTION_CODE_SHUTTING_DOWN\x10\x13B\xdc\x02\n" +
 	"+com.graph.substreams.data_service.common.v1B\n" +
 	"TypesProtoP\x01Zdgithub.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1;commonv1\xa2\x02\x04GSDC\xaa\x02&Graph.Substreams.DataService.Common.V1\xca\x02&Graph\\Substreams\\DataService\\Common\\V1\xe2\x022Graph\\Substreams\\DataService\\Common\\V1\\GPBMetadata\xea\x02*Graph::Substreams::DataService::Common::V1b\x06proto3"
 
@@ -747,42 +1244,55 @@ func file_graph_substreams_data_service_common_v1_types_proto_rawDescGZIP() []by
 	return file_graph_substreams_data_service_common_v1_types_proto_rawDescData
 }
 
-var file_graph_substreams_data_service_common_v1_types_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_graph_substreams_data_service_common_v1_types_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_graph_substreams_data_service_common_v1_types_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_graph_substreams_data_service_common_v1_types_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
 var file_graph_substreams_data_service_common_v1_types_proto_goTypes = []any{
-	(EndReason)(0),            // 0: graph.substreams.data_service.common.v1.EndReason
-	(*Address)(nil),           // 1: graph.substreams.data_service.common.v1.Address
-	(*BigInt)(nil),            // 2: graph.substreams.data_service.common.v1.BigInt
-	(*SignedRAV)(nil),         // 3: graph.substreams.data_service.common.v1.SignedRAV
-	(*RAV)(nil),               // 4: graph.substreams.data_service.common.v1.RAV
-	(*Usage)(nil),             // 5: graph.substreams.data_service.common.v1.Usage
-	(*EscrowAccount)(nil),     // 6: graph.substreams.data_service.common.v1.EscrowAccount
-	(*SessionInfo)(nil),       // 7: graph.substreams.data_service.common.v1.SessionInfo
-	(*ServiceParameters)(nil), // 8: graph.substreams.data_service.common.v1.ServiceParameters
-	(*PaymentStatus)(nil),     // 9: graph.substreams.data_service.common.v1.PaymentStatus
+	(PaymentMode)(0),          // 0: graph.substreams.data_service.common.v1.PaymentMode
+	(EndReason)(0),            // 1: graph.substreams.data_service.common.v1.EndReason
+	(RejectionCode)(0),        // 2: graph.substreams.data_service.common.v1.RejectionCode
+	(*Address)(nil),           // 3: graph.substreams.data_service.common.v1.Address
+	(*BigInt)(nil),            // 4: graph.substreams.data_service.common.v1.BigInt
+	(*SignedRAV)(nil),         // 5: graph.substreams.data_service.common.v1.SignedRAV
+	(*RAV)(nil),               // 6: graph.substreams.data_service.common.v1.RAV
+	(*SignedReceipt)(nil),     // 7: graph.substreams.data_service.common.v1.SignedReceipt
+	(*Receipt)(nil),           // 8: graph.substreams.data_service.common.v1.Receipt
+	(*Usage)(nil),             // 9: graph.substreams.data_service.common.v1.Usage
+	(*EscrowAccount)(nil),     // 10: graph.substreams.data_service.common.v1.EscrowAccount
+	(*SessionInfo)(nil),       // 11: graph.substreams.data_service.common.v1.SessionInfo
+	(*ServiceParameters)(nil), // 12: graph.substreams.data_service.common.v1.ServiceParameters
+	(*PaymentStatus)(nil),     // 13: graph.substreams.data_service.common.v1.PaymentStatus
+	(*UsageCategory)(nil),     // 14: graph.substreams.data_service.common.v1.UsageCategory
 }
 var file_graph_substreams_data_service_common_v1_types_proto_depIdxs = []int32{
-	4,  // 0: graph.substreams.data_service.common.v1.SignedRAV.rav:type_name -> graph.substreams.data_service.common.v1.RAV
-	1,  // 1: graph.substreams.data_service.common.v1.RAV.payer:type_name -> graph.substreams.data_service.common.v1.Address
-	1,  // 2: graph.substreams.data_service.common.v1.RAV.data_service:type_name -> graph.substreams.data_service.common.v1.Address
-	1,  // 3: graph.substreams.data_service.common.v1.RAV.service_provider:type_name -> graph.substreams.data_service.common.v1.Address
-	2,  // 4: graph.substreams.data_service.common.v1.RAV.value_aggregate:type_name -> graph.substreams.data_service.common.v1.BigInt
-	2,  // 5: graph.substreams.data_service.common.v1.Usage.cost:type_name -> graph.substreams.data_service.common.v1.BigInt
-	1,  // 6: graph.substreams.data_service.common.v1.EscrowAccount.payer:type_name -> graph.substreams.data_service.common.v1.Address
-	1,  // 7: graph.substreams.data_service.common.v1.EscrowAccount.receiver:type_name -> graph.substreams.data_service.common.v1.Address
-	1,  // 8: graph.substreams.data_service.common.v1.EscrowAccount.data_service:type_name -> graph.substreams.data_service.common.v1.Address
-	6,  // 9: graph.substreams.data_service.common.v1.SessionInfo.escrow_account:type_name -> graph.substreams.data_service.common.v1.EscrowAccount
-	3,  // 10: graph.substreams.data_service.common.v1.SessionInfo.current_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
-	5,  // 11: graph.substreams.data_service.common.v1.SessionInfo.accumulated_usage:type_name -> graph.substreams.data_service.common.v1.Usage
-	2,  // 12: graph.substreams.data_service.common.v1.ServiceParameters.price_per_block:type_name -> graph.substreams.data_service.common.v1.BigInt
-	2,  // 13: graph.substreams.data_service.common.v1.PaymentStatus.current_rav_value:type_name -> graph.substreams.data_service.common.v1.BigInt
-	2,  // 14: graph.substreams.data_service.common.v1.PaymentStatus.accumulated_usage_value:type_name -> graph.substreams.data_service.common.v1.BigInt
-	2,  // 15: graph.substreams.data_service.common.v1.PaymentStatus.escrow_balance:type_name -> graph.substreams.data_service.common.v1.BigInt
-	16, // [16:16] is the sub-list for method output_type
-	16, // [16:16] is the sub-list for method input_type
-	16, // [16:16] is the sub-list for extension type_name
-	16, // [16:16] is the sub-list for extension extendee
-	0,  // [0:16] is the sub-list for field type_name
+	6,  // 0: graph.substreams.data_service.common.v1.SignedRAV.rav:type_name -> graph.substreams.data_service.common.v1.RAV
+	3,  // 1: graph.substreams.data_service.common.v1.RAV.payer:type_name -> graph.substreams.data_service.common.v1.Address
+	3,  // 2: graph.substreams.data_service.common.v1.RAV.data_service:type_name -> graph.substreams.data_service.common.v1.Address
+	3,  // 3: graph.substreams.data_service.common.v1.RAV.service_provider:type_name -> graph.substreams.data_service.common.v1.Address
+	4,  // 4: graph.substreams.data_service.common.v1.RAV.value_aggregate:type_name -> graph.substreams.data_service.common.v1.BigInt
+	8,  // 5: graph.substreams.data_service.common.v1.SignedReceipt.receipt:type_name -> graph.substreams.data_service.common.v1.Receipt
+	3,  // 6: graph.substreams.data_service.common.v1.Receipt.payer:type_name -> graph.substreams.data_service.common.v1.Address
+	3,  // 7: graph.substreams.data_service.common.v1.Receipt.data_service:type_name -> graph.substreams.data_service.common.v1.Address
+	3,  // 8: graph.substreams.data_service.common.v1.Receipt.service_provider:type_name -> graph.substreams.data_service.common.v1.Address
+	4,  // 9: graph.substreams.data_service.common.v1.Receipt.value:type_name -> graph.substreams.data_service.common.v1.BigInt
+	4,  // 10: graph.substreams.data_service.common.v1.Usage.cost:type_name -> graph.substreams.data_service.common.v1.BigInt
+	14, // 11: graph.substreams.data_service.common.v1.Usage.categories:type_name -> graph.substreams.data_service.common.v1.UsageCategory
+	3,  // 12: graph.substreams.data_service.common.v1.EscrowAccount.payer:type_name -> graph.substreams.data_service.common.v1.Address
+	3,  // 13: graph.substreams.data_service.common.v1.EscrowAccount.receiver:type_name -> graph.substreams.data_service.common.v1.Address
+	3,  // 14: graph.substreams.data_service.common.v1.EscrowAccount.data_service:type_name -> graph.substreams.data_service.common.v1.Address
+	3,  // 15: graph.substreams.data_service.common.v1.EscrowAccount.collector:type_name -> graph.substreams.data_service.common.v1.Address
+	10, // 16: graph.substreams.data_service.common.v1.SessionInfo.escrow_account:type_name -> graph.substreams.data_service.common.v1.EscrowAccount
+	5,  // 17: graph.substreams.data_service.common.v1.SessionInfo.current_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
+	9,  // 18: graph.substreams.data_service.common.v1.SessionInfo.accumulated_usage:type_name -> graph.substreams.data_service.common.v1.Usage
+	4,  // 19: graph.substreams.data_service.common.v1.ServiceParameters.price_per_block:type_name -> graph.substreams.data_service.common.v1.BigInt
+	4,  // 20: graph.substreams.data_service.common.v1.PaymentStatus.current_rav_value:type_name -> graph.substreams.data_service.common.v1.BigInt
+	4,  // 21: graph.substreams.data_service.common.v1.PaymentStatus.accumulated_usage_value:type_name -> graph.substreams.data_service.common.v1.BigInt
+	4,  // 22: graph.substreams.data_service.common.v1.PaymentStatus.escrow_balance:type_name -> graph.substreams.data_service.common.v1.BigInt
+	4,  // 23: graph.substreams.data_service.common.v1.UsageCategory.cost:type_name -> graph.substreams.data_service.common.v1.BigInt
+	24, // [24:24] is the sub-list for method output_type
+	24, // [24:24] is the sub-list for method input_type
+	24, // [24:24] is the sub-list for extension type_name
+	24, // [24:24] is the sub-list for extension extendee
+	0,  // [0:24] is the sub-list for field type_name
 }
 
 func init() { file_graph_substreams_data_service_common_v1_types_proto_init() }
@@ -795,8 +1305,8 @@ func file_graph_substreams_data_service_common_v1_types_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_graph_substreams_data_service_common_v1_types_proto_rawDesc), len(file_graph_substreams_data_service_common_v1_types_proto_rawDesc)),
-			NumEnums:      1,
-			NumMessages:   9,
+			NumEnums:      3,
+			NumMessages:   12,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
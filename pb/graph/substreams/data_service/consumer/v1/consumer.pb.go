@@ -22,6 +22,74 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// BudgetLimit is a spending limit of the consumer sidecar
+type BudgetLimit int32
+
+const (
+	BudgetLimit_BUDGET_LIMIT_UNSPECIFIED BudgetLimit = 0
+	// The maximum value authorized across all sessions
+	BudgetLimit_BUDGET_LIMIT_GLOBAL BudgetLimit = 1
+	// The maximum value authorized to the service provider
+	BudgetLimit_BUDGET_LIMIT_PROVIDER BudgetLimit = 2
+	// The maximum value aggregate of a session
+	BudgetLimit_BUDGET_LIMIT_SESSION BudgetLimit = 3
+	// The maximum value authorized across the sessions of the payer
+	BudgetLimit_BUDGET_LIMIT_PAYER BudgetLimit = 4
+	// The maximum value per block processed of a session
+	BudgetLimit_BUDGET_LIMIT_VALUE_PER_BLOCK BudgetLimit = 5
+	// The maximum value added by a single RAV
+	BudgetLimit_BUDGET_LIMIT_RAV_DELTA BudgetLimit = 6
+)
+
+// Enum value maps for BudgetLimit.
+var (
+	BudgetLimit_name = map[int32]string{
+		0: "BUDGET_LIMIT_UNSPECIFIED",
+		1: "BUDGET_LIMIT_GLOBAL",
+		2: "BUDGET_LIMIT_PROVIDER",
+		3: "BUDGET_LIMIT_SESSION",
+		4: "BUDGET_LIMIT_PAYER",
+		5: "BUDGET_LIMIT_VALUE_PER_BLOCK",
+		6: "BUDGET_LIMIT_RAV_DELTA",
+	}
+	BudgetLimit_value = map[string]int32{
+		"BUDGET_LIMIT_UNSPECIFIED":     0,
+		"BUDGET_LIMIT_GLOBAL":          1,
+		"BUDGET_LIMIT_PROVIDER":        2,
+		"BUDGET_LIMIT_SESSION":         3,
+		"BUDGET_LIMIT_PAYER":           4,
+		"BUDGET_LIMIT_VALUE_PER_BLOCK": 5,
+		"BUDGET_LIMIT_RAV_DELTA":       6,
+	}
+)
+
+func (x BudgetLimit) Enum() *BudgetLimit {
+	p := new(BudgetLimit)
+	*p = x
+	return p
+}
+
+func (x BudgetLimit) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (BudgetLimit) Descriptor() protoreflect.EnumDescriptor {
+	return file_graph_substreams_data_service_consumer_v1_consumer_proto_enumTypes[0].Descriptor()
+}
+
+func (BudgetLimit) Type() protoreflect.EnumType {
+	return &file_graph_substreams_data_service_consumer_v1_consumer_proto_enumTypes[0]
+}
+
+func (x BudgetLimit) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use BudgetLimit.Descriptor instead.
+func (BudgetLimit) EnumDescriptor() ([]byte, []int) {
+	return file_graph_substreams_data_service_consumer_v1_consumer_proto_rawDescGZIP(), []int{0}
+}
+
 type InitRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// The escrow account to use for funding this session
@@ -29,7 +97,10 @@ type InitRequest struct {
 	// The provider endpoint to connect to
 	ProviderEndpoint string `protobuf:"bytes,2,opt,name=provider_endpoint,json=providerEndpoint,proto3" json:"provider_endpoint,omitempty"`
 	// Optional: existing RAV to continue from (for session resumption)
-	ExistingRav   *v1.SignedRAV `protobuf:"bytes,3,opt,name=existing_rav,json=existingRav,proto3" json:"existing_rav,omitempty"`
+	ExistingRav *v1.SignedRAV `protobuf:"bytes,3,opt,name=existing_rav,json=existingRav,proto3" json:"existing_rav,omitempty"`
+	// How usage of the session is paid for, the sidecar's default mode when
+	// unspecified
+	PaymentMode   v1.PaymentMode `protobuf:"varint,4,opt,name=payment_mode,json=paymentMode,proto3,enum=graph.substreams.data_service.common.v1.PaymentMode" json:"payment_mode,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -85,12 +156,21 @@ func (x *InitRequest) GetExistingRav() *v1.SignedRAV {
 	return nil
 }
 
+func (x *InitRequest) GetPaymentMode() v1.PaymentMode {
+	if x != nil {
+		return x.PaymentMode
+	}
+	return v1.PaymentMode(0)
+}
+
 type InitResponse struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// The session information including the RAV to use
 	Session *v1.SessionInfo `protobuf:"bytes,1,opt,name=session,proto3" json:"session,omitempty"`
 	// The RAV to include in the payment header when connecting to provider
-	PaymentRav    *v1.SignedRAV `protobuf:"bytes,2,opt,name=payment_rav,json=paymentRav,proto3" json:"payment_rav,omitempty"`
+	PaymentRav *v1.SignedRAV `protobuf:"bytes,2,opt,name=payment_rav,json=paymentRav,proto3" json:"payment_rav,omitempty"`
+	// The payment mode of the session
+	PaymentMode   v1.PaymentMode `protobuf:"varint,3,opt,name=payment_mode,json=paymentMode,proto3,enum=graph.substreams.data_service.common.v1.PaymentMode" json:"payment_mode,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -139,6 +219,13 @@ func (x *InitResponse) GetPaymentRav() *v1.SignedRAV {
 	return nil
 }
 
+func (x *InitResponse) GetPaymentMode() v1.PaymentMode {
+	if x != nil {
+		return x.PaymentMode
+	}
+	return v1.PaymentMode(0)
+}
+
 type ReportUsageRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// The session ID
@@ -200,7 +287,14 @@ type ReportUsageResponse struct {
 	// Whether the session should continue
 	ShouldContinue bool `protobuf:"varint,2,opt,name=should_continue,json=shouldContinue,proto3" json:"should_continue,omitempty"`
 	// If should_continue is false, the reason for stopping
-	StopReason    string `protobuf:"bytes,3,opt,name=stop_reason,json=stopReason,proto3" json:"stop_reason,omitempty"`
+	StopReason string `protobuf:"bytes,3,opt,name=stop_reason,json=stopReason,proto3" json:"stop_reason,omitempty"`
+	// If should_continue is false, the machine code of the stop reason
+	StopCode v1.RejectionCode `protobuf:"varint,4,opt,name=stop_code,json=stopCode,proto3,enum=graph.substreams.data_service.common.v1.RejectionCode" json:"stop_code,omitempty"`
+	// When stop_code is REJECTION_CODE_BUDGET_EXCEEDED, the limit exceeded
+	BudgetViolation *BudgetViolation `protobuf:"bytes,5,opt,name=budget_violation,json=budgetViolation,proto3" json:"budget_violation,omitempty"`
+	// In PAYMENT_MODE_RECEIPTS, the receipt paying for the reported usage, to be
+	// submitted to the provider sidecar (SubmitRAV), updated_rav is not set
+	Receipt       *v1.SignedReceipt `protobuf:"bytes,6,opt,name=receipt,proto3" json:"receipt,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -256,19 +350,109 @@ func (x *ReportUsageResponse) GetStopReason() string {
 	return ""
 }
 
+func (x *ReportUsageResponse) GetStopCode() v1.RejectionCode {
+	if x != nil {
+		return x.StopCode
+	}
+	return v1.RejectionCode(0)
+}
+
+func (x *ReportUsageResponse) GetBudgetViolation() *BudgetViolation {
+	if x != nil {
+		return x.BudgetViolation
+	}
+	return nil
+}
+
+func (x *ReportUsageResponse) GetReceipt() *v1.SignedReceipt {
+	if x != nil {
+		return x.Receipt
+	}
+	return nil
+}
+
+// BudgetViolation details why the consumer sidecar refused to sign a RAV
+// exceeding a spending limit. It is sent in ReportUsageResponse and as the
+// error detail of the Init and EndSession calls refused for it.
+type BudgetViolation struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The limit exceeded
+	Limit BudgetLimit `protobuf:"varint,1,opt,name=limit,proto3,enum=graph.substreams.data_service.consumer.v1.BudgetLimit" json:"limit,omitempty"`
+	// The configured limit
+	Max *v1.BigInt `protobuf:"bytes,2,opt,name=max,proto3" json:"max,omitempty"`
+	// The value the RAV would have reached against the limit
+	Requested     *v1.BigInt `protobuf:"bytes,3,opt,name=requested,proto3" json:"requested,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BudgetViolation) Reset() {
+	*x = BudgetViolation{}
+	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BudgetViolation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BudgetViolation) ProtoMessage() {}
+
+func (x *BudgetViolation) ProtoReflect() protoreflect.Message {
+	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BudgetViolation.ProtoReflect.Descriptor instead.
+func (*BudgetViolation) Descriptor() ([]byte, []int) {
+	return file_graph_substreams_data_service_consumer_v1_consumer_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BudgetViolation) GetLimit() BudgetLimit {
+	if x != nil {
+		return x.Limit
+	}
+	return BudgetLimit_BUDGET_LIMIT_UNSPECIFIED
+}
+
+func (x *BudgetViolation) GetMax() *v1.BigInt {
+	if x != nil {
+		return x.Max
+	}
+	return nil
+}
+
+func (x *BudgetViolation) GetRequested() *v1.BigInt {
+	if x != nil {
+		return x.Requested
+	}
+	return nil
+}
+
 type EndSessionRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// The session ID
 	SessionId string `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
 	// Final usage to report
-	FinalUsage    *v1.Usage `protobuf:"bytes,2,opt,name=final_usage,json=finalUsage,proto3" json:"final_usage,omitempty"`
+	FinalUsage *v1.Usage `protobuf:"bytes,2,opt,name=final_usage,json=finalUsage,proto3" json:"final_usage,omitempty"`
+	// Usage the client observed over the whole session, blocks and bytes,
+	// compared with the usage the provider claimed. Unset when not observed.
+	ObservedUsage *v1.Usage `protobuf:"bytes,3,opt,name=observed_usage,json=observedUsage,proto3" json:"observed_usage,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *EndSessionRequest) Reset() {
 	*x = EndSessionRequest{}
-	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[4]
+	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -280,7 +464,7 @@ func (x *EndSessionRequest) String() string {
 func (*EndSessionRequest) ProtoMessage() {}
 
 func (x *EndSessionRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[4]
+	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -293,7 +477,7 @@ func (x *EndSessionRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EndSessionRequest.ProtoReflect.Descriptor instead.
 func (*EndSessionRequest) Descriptor() ([]byte, []int) {
-	return file_graph_substreams_data_service_consumer_v1_consumer_proto_rawDescGZIP(), []int{4}
+	return file_graph_substreams_data_service_consumer_v1_consumer_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *EndSessionRequest) GetSessionId() string {
@@ -310,19 +494,32 @@ func (x *EndSessionRequest) GetFinalUsage() *v1.Usage {
 	return nil
 }
 
+func (x *EndSessionRequest) GetObservedUsage() *v1.Usage {
+	if x != nil {
+		return x.ObservedUsage
+	}
+	return nil
+}
+
 type EndSessionResponse struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// The final signed RAV for this session
 	FinalRav *v1.SignedRAV `protobuf:"bytes,1,opt,name=final_rav,json=finalRav,proto3" json:"final_rav,omitempty"`
 	// Total usage for the session
-	TotalUsage    *v1.Usage `protobuf:"bytes,2,opt,name=total_usage,json=totalUsage,proto3" json:"total_usage,omitempty"`
+	TotalUsage *v1.Usage `protobuf:"bytes,2,opt,name=total_usage,json=totalUsage,proto3" json:"total_usage,omitempty"`
+	// In PAYMENT_MODE_RECEIPTS, the receipt paying for the final usage, final_rav
+	// then being the RAV the session started from as the provider sidecar
+	// aggregates the receipts
+	FinalReceipt *v1.SignedReceipt `protobuf:"bytes,3,opt,name=final_receipt,json=finalReceipt,proto3" json:"final_receipt,omitempty"`
+	// The consumer side settlement of the session
+	Settlement    *SessionSettlement `protobuf:"bytes,4,opt,name=settlement,proto3" json:"settlement,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *EndSessionResponse) Reset() {
 	*x = EndSessionResponse{}
-	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[5]
+	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -334,7 +531,7 @@ func (x *EndSessionResponse) String() string {
 func (*EndSessionResponse) ProtoMessage() {}
 
 func (x *EndSessionResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[5]
+	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -347,7 +544,7 @@ func (x *EndSessionResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EndSessionResponse.ProtoReflect.Descriptor instead.
 func (*EndSessionResponse) Descriptor() ([]byte, []int) {
-	return file_graph_substreams_data_service_consumer_v1_consumer_proto_rawDescGZIP(), []int{5}
+	return file_graph_substreams_data_service_consumer_v1_consumer_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *EndSessionResponse) GetFinalRav() *v1.SignedRAV {
@@ -364,43 +561,633 @@ func (x *EndSessionResponse) GetTotalUsage() *v1.Usage {
 	return nil
 }
 
+func (x *EndSessionResponse) GetFinalReceipt() *v1.SignedReceipt {
+	if x != nil {
+		return x.FinalReceipt
+	}
+	return nil
+}
+
+func (x *EndSessionResponse) GetSettlement() *SessionSettlement {
+	if x != nil {
+		return x.Settlement
+	}
+	return nil
+}
+
+type ListSessionsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only the sessions paying this service provider, all when unset
+	ServiceProvider *v1.Address `protobuf:"bytes,1,opt,name=service_provider,json=serviceProvider,proto3" json:"service_provider,omitempty"`
+	// Only the sessions whose current RAV is for this collection (32 bytes), all
+	// when empty
+	CollectionId []byte `protobuf:"bytes,2,opt,name=collection_id,json=collectionId,proto3" json:"collection_id,omitempty"`
+	// Only the active sessions
+	ActiveOnly    bool `protobuf:"varint,3,opt,name=active_only,json=activeOnly,proto3" json:"active_only,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSessionsRequest) Reset() {
+	*x = ListSessionsRequest{}
+	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSessionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSessionsRequest) ProtoMessage() {}
+
+func (x *ListSessionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSessionsRequest.ProtoReflect.Descriptor instead.
+func (*ListSessionsRequest) Descriptor() ([]byte, []int) {
+	return file_graph_substreams_data_service_consumer_v1_consumer_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListSessionsRequest) GetServiceProvider() *v1.Address {
+	if x != nil {
+		return x.ServiceProvider
+	}
+	return nil
+}
+
+func (x *ListSessionsRequest) GetCollectionId() []byte {
+	if x != nil {
+		return x.CollectionId
+	}
+	return nil
+}
+
+func (x *ListSessionsRequest) GetActiveOnly() bool {
+	if x != nil {
+		return x.ActiveOnly
+	}
+	return false
+}
+
+type ListSessionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sessions      []*Session             `protobuf:"bytes,1,rep,name=sessions,proto3" json:"sessions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSessionsResponse) Reset() {
+	*x = ListSessionsResponse{}
+	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSessionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSessionsResponse) ProtoMessage() {}
+
+func (x *ListSessionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSessionsResponse.ProtoReflect.Descriptor instead.
+func (*ListSessionsResponse) Descriptor() ([]byte, []int) {
+	return file_graph_substreams_data_service_consumer_v1_consumer_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListSessionsResponse) GetSessions() []*Session {
+	if x != nil {
+		return x.Sessions
+	}
+	return nil
+}
+
+type GetSessionRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The session ID
+	SessionId     string `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSessionRequest) Reset() {
+	*x = GetSessionRequest{}
+	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSessionRequest) ProtoMessage() {}
+
+func (x *GetSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSessionRequest.ProtoReflect.Descriptor instead.
+func (*GetSessionRequest) Descriptor() ([]byte, []int) {
+	return file_graph_substreams_data_service_consumer_v1_consumer_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetSessionRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type GetSessionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Session       *Session               `protobuf:"bytes,1,opt,name=session,proto3" json:"session,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSessionResponse) Reset() {
+	*x = GetSessionResponse{}
+	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSessionResponse) ProtoMessage() {}
+
+func (x *GetSessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSessionResponse.ProtoReflect.Descriptor instead.
+func (*GetSessionResponse) Descriptor() ([]byte, []int) {
+	return file_graph_substreams_data_service_consumer_v1_consumer_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetSessionResponse) GetSession() *Session {
+	if x != nil {
+		return x.Session
+	}
+	return nil
+}
+
+// Session is a payment session of the consumer sidecar.
+type Session struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The session information including its current RAV and usage
+	Info *v1.SessionInfo `protobuf:"bytes,1,opt,name=info,proto3" json:"info,omitempty"`
+	// Whether the session is active
+	Active bool `protobuf:"varint,2,opt,name=active,proto3" json:"active,omitempty"`
+	// The provider endpoint given on Init, empty when none
+	ProviderEndpoint string `protobuf:"bytes,3,opt,name=provider_endpoint,json=providerEndpoint,proto3" json:"provider_endpoint,omitempty"`
+	// When the session was initialized (Unix nanoseconds)
+	CreatedAtNs uint64 `protobuf:"varint,4,opt,name=created_at_ns,json=createdAtNs,proto3" json:"created_at_ns,omitempty"`
+	// When the session ended (Unix nanoseconds), zero while active
+	EndedAtNs uint64 `protobuf:"varint,5,opt,name=ended_at_ns,json=endedAtNs,proto3" json:"ended_at_ns,omitempty"`
+	// Why the session ended
+	EndReason v1.EndReason `protobuf:"varint,6,opt,name=end_reason,json=endReason,proto3,enum=graph.substreams.data_service.common.v1.EndReason" json:"end_reason,omitempty"`
+	// How usage of the session is paid for
+	PaymentMode v1.PaymentMode `protobuf:"varint,7,opt,name=payment_mode,json=paymentMode,proto3,enum=graph.substreams.data_service.common.v1.PaymentMode" json:"payment_mode,omitempty"`
+	// In PAYMENT_MODE_RECEIPTS, the total value of the receipts signed
+	ReceiptsValue *v1.BigInt `protobuf:"bytes,8,opt,name=receipts_value,json=receiptsValue,proto3" json:"receipts_value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Session) Reset() {
+	*x = Session{}
+	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Session) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Session) ProtoMessage() {}
+
+func (x *Session) ProtoReflect() protoreflect.Message {
+	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Session.ProtoReflect.Descriptor instead.
+func (*Session) Descriptor() ([]byte, []int) {
+	return file_graph_substreams_data_service_consumer_v1_consumer_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *Session) GetInfo() *v1.SessionInfo {
+	if x != nil {
+		return x.Info
+	}
+	return nil
+}
+
+func (x *Session) GetActive() bool {
+	if x != nil {
+		return x.Active
+	}
+	return false
+}
+
+func (x *Session) GetProviderEndpoint() string {
+	if x != nil {
+		return x.ProviderEndpoint
+	}
+	return ""
+}
+
+func (x *Session) GetCreatedAtNs() uint64 {
+	if x != nil {
+		return x.CreatedAtNs
+	}
+	return 0
+}
+
+func (x *Session) GetEndedAtNs() uint64 {
+	if x != nil {
+		return x.EndedAtNs
+	}
+	return 0
+}
+
+func (x *Session) GetEndReason() v1.EndReason {
+	if x != nil {
+		return x.EndReason
+	}
+	return v1.EndReason(0)
+}
+
+func (x *Session) GetPaymentMode() v1.PaymentMode {
+	if x != nil {
+		return x.PaymentMode
+	}
+	return v1.PaymentMode(0)
+}
+
+func (x *Session) GetReceiptsValue() *v1.BigInt {
+	if x != nil {
+		return x.ReceiptsValue
+	}
+	return nil
+}
+
+type ResumeSessionRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The session ID
+	SessionId     string `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResumeSessionRequest) Reset() {
+	*x = ResumeSessionRequest{}
+	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResumeSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResumeSessionRequest) ProtoMessage() {}
+
+func (x *ResumeSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResumeSessionRequest.ProtoReflect.Descriptor instead.
+func (*ResumeSessionRequest) Descriptor() ([]byte, []int) {
+	return file_graph_substreams_data_service_consumer_v1_consumer_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ResumeSessionRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type ResumeSessionResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The session, with its accumulated usage and last signed RAV
+	Session *Session `protobuf:"bytes,1,opt,name=session,proto3" json:"session,omitempty"`
+	// The RAV to include in the payment header when reconnecting to the
+	// provider, the last RAV signed for the session
+	PaymentRav    *v1.SignedRAV `protobuf:"bytes,2,opt,name=payment_rav,json=paymentRav,proto3" json:"payment_rav,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResumeSessionResponse) Reset() {
+	*x = ResumeSessionResponse{}
+	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResumeSessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResumeSessionResponse) ProtoMessage() {}
+
+func (x *ResumeSessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResumeSessionResponse.ProtoReflect.Descriptor instead.
+func (*ResumeSessionResponse) Descriptor() ([]byte, []int) {
+	return file_graph_substreams_data_service_consumer_v1_consumer_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ResumeSessionResponse) GetSession() *Session {
+	if x != nil {
+		return x.Session
+	}
+	return nil
+}
+
+func (x *ResumeSessionResponse) GetPaymentRav() *v1.SignedRAV {
+	if x != nil {
+		return x.PaymentRav
+	}
+	return nil
+}
+
+// SessionSettlement summarizes, on the consumer side, what an ended session is
+// paid against the usage observed and the budget left. GRT values are in wei.
+type SessionSettlement struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Usage the provider claimed, as reported through ReportUsage and EndSession
+	ClaimedUsage *v1.Usage `protobuf:"bytes,1,opt,name=claimed_usage,json=claimedUsage,proto3" json:"claimed_usage,omitempty"`
+	// Usage the client observed, unset when it was not reported
+	ObservedUsage *v1.Usage `protobuf:"bytes,2,opt,name=observed_usage,json=observedUsage,proto3" json:"observed_usage,omitempty"`
+	// Value signed for the session, its final RAV plus the receipts signed on top
+	// of it in PAYMENT_MODE_RECEIPTS
+	SignedValue *v1.BigInt `protobuf:"bytes,3,opt,name=signed_value,json=signedValue,proto3" json:"signed_value,omitempty"`
+	// Largest relative difference between the claimed and observed blocks or
+	// bytes, relative to the observed usage
+	Divergence float64 `protobuf:"fixed64,4,opt,name=divergence,proto3" json:"divergence,omitempty"`
+	// Set when the divergence exceeds the tolerance, a dispute being recorded
+	// against the service provider
+	Disputed bool `protobuf:"varint,5,opt,name=disputed,proto3" json:"disputed,omitempty"`
+	// Global budget left once the session ended, unset when unlimited
+	GlobalBudgetRemaining *v1.BigInt `protobuf:"bytes,6,opt,name=global_budget_remaining,json=globalBudgetRemaining,proto3" json:"global_budget_remaining,omitempty"`
+	// Budget of the service provider left once the session ended, unset when
+	// unlimited
+	ProviderBudgetRemaining *v1.BigInt `protobuf:"bytes,7,opt,name=provider_budget_remaining,json=providerBudgetRemaining,proto3" json:"provider_budget_remaining,omitempty"`
+	unknownFields           protoimpl.UnknownFields
+	sizeCache               protoimpl.SizeCache
+}
+
+func (x *SessionSettlement) Reset() {
+	*x = SessionSettlement{}
+	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SessionSettlement) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionSettlement) ProtoMessage() {}
+
+func (x *SessionSettlement) ProtoReflect() protoreflect.Message {
+	mi := &file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionSettlement.ProtoReflect.Descriptor instead.
+func (*SessionSettlement) Descriptor() ([]byte, []int) {
+	return file_graph_substreams_data_service_consumer_v1_consumer_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *SessionSettlement) GetClaimedUsage() *v1.Usage {
+	if x != nil {
+		return x.ClaimedUsage
+	}
+	return nil
+}
+
+func (x *SessionSettlement) GetObservedUsage() *v1.Usage {
+	if x != nil {
+		return x.ObservedUsage
+	}
+	return nil
+}
+
+func (x *SessionSettlement) GetSignedValue() *v1.BigInt {
+	if x != nil {
+		return x.SignedValue
+	}
+	return nil
+}
+
+func (x *SessionSettlement) GetDivergence() float64 {
+	if x != nil {
+		return x.Divergence
+	}
+	return 0
+}
+
+func (x *SessionSettlement) GetDisputed() bool {
+	if x != nil {
+		return x.Disputed
+	}
+	return false
+}
+
+func (x *SessionSettlement) GetGlobalBudgetRemaining() *v1.BigInt {
+	if x != nil {
+		return x.GlobalBudgetRemaining
+	}
+	return nil
+}
+
+func (x *SessionSettlement) GetProviderBudgetRemaining() *v1.BigInt {
+	if x != nil {
+		return x.ProviderBudgetRemaining
+	}
+	return nil
+}
+
 var File_graph_substreams_data_service_consumer_v1_consumer_proto protoreflect.FileDescriptor
 
 const file_graph_substreams_data_service_consumer_v1_consumer_proto_rawDesc = "" +
 	"\n" +
-	"8graph/substreams/data_service/consumer/v1/consumer.proto\x12)graph.substreams.data_service.consumer.v1\x1a3graph/substreams/data_service/common/v1/types.proto\"\xf0\x01\n" +
+	"8graph/substreams/data_service/consumer/v1/consumer.proto\x12)graph.substreams.data_service.consumer.v1\x1a3graph/substreams/data_service/common/v1/types.proto\"\xc9\x02\n" +
 	"\vInitRequest\x12]\n" +
 	"\x0eescrow_account\x18\x01 \x01(\v26.graph.substreams.data_service.common.v1.EscrowAccountR\rescrowAccount\x12+\n" +
 	"\x11provider_endpoint\x18\x02 \x01(\tR\x10providerEndpoint\x12U\n" +
-	"\fexisting_rav\x18\x03 \x01(\v22.graph.substreams.data_service.common.v1.SignedRAVR\vexistingRav\"\xb3\x01\n" +
+	"\fexisting_rav\x18\x03 \x01(\v22.graph.substreams.data_service.common.v1.SignedRAVR\vexistingRav\x12W\n" +
+	"\fpayment_mode\x18\x04 \x01(\x0e24.graph.substreams.data_service.common.v1.PaymentModeR\vpaymentMode\"\x8c\x02\n" +
 	"\fInitResponse\x12N\n" +
 	"\asession\x18\x01 \x01(\v24.graph.substreams.data_service.common.v1.SessionInfoR\asession\x12S\n" +
 	"\vpayment_rav\x18\x02 \x01(\v22.graph.substreams.data_service.common.v1.SignedRAVR\n" +
-	"paymentRav\"y\n" +
+	"paymentRav\x12W\n" +
+	"\fpayment_mode\x18\x03 \x01(\x0e24.graph.substreams.data_service.common.v1.PaymentModeR\vpaymentMode\"y\n" +
 	"\x12ReportUsageRequest\x12\x1d\n" +
 	"\n" +
 	"session_id\x18\x01 \x01(\tR\tsessionId\x12D\n" +
-	"\x05usage\x18\x02 \x01(\v2..graph.substreams.data_service.common.v1.UsageR\x05usage\"\xb4\x01\n" +
+	"\x05usage\x18\x02 \x01(\v2..graph.substreams.data_service.common.v1.UsageR\x05usage\"\xc2\x03\n" +
 	"\x13ReportUsageResponse\x12S\n" +
 	"\vupdated_rav\x18\x01 \x01(\v22.graph.substreams.data_service.common.v1.SignedRAVR\n" +
 	"updatedRav\x12'\n" +
 	"\x0fshould_continue\x18\x02 \x01(\bR\x0eshouldContinue\x12\x1f\n" +
 	"\vstop_reason\x18\x03 \x01(\tR\n" +
-	"stopReason\"\x83\x01\n" +
+	"stopReason\x12S\n" +
+	"\tstop_code\x18\x04 \x01(\x0e26.graph.substreams.data_service.common.v1.RejectionCodeR\bstopCode\x12e\n" +
+	"\x10budget_violation\x18\x05 \x01(\v2:.graph.substreams.data_service.consumer.v1.BudgetViolationR\x0fbudgetViolation\x12P\n" +
+	"\areceipt\x18\x06 \x01(\v26.graph.substreams.data_service.common.v1.SignedReceiptR\areceipt\"\xf1\x01\n" +
+	"\x0fBudgetViolation\x12L\n" +
+	"\x05limit\x18\x01 \x01(\x0e26.graph.substreams.data_service.consumer.v1.BudgetLimitR\x05limit\x12A\n" +
+	"\x03max\x18\x02 \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\x03max\x12M\n" +
+	"\trequested\x18\x03 \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\trequested\"\xda\x01\n" +
 	"\x11EndSessionRequest\x12\x1d\n" +
 	"\n" +
 	"session_id\x18\x01 \x01(\tR\tsessionId\x12O\n" +
 	"\vfinal_usage\x18\x02 \x01(\v2..graph.substreams.data_service.common.v1.UsageR\n" +
-	"finalUsage\"\xb6\x01\n" +
+	"finalUsage\x12U\n" +
+	"\x0eobserved_usage\x18\x03 \x01(\v2..graph.substreams.data_service.common.v1.UsageR\robservedUsage\"\xf1\x02\n" +
 	"\x12EndSessionResponse\x12O\n" +
 	"\tfinal_rav\x18\x01 \x01(\v22.graph.substreams.data_service.common.v1.SignedRAVR\bfinalRav\x12O\n" +
 	"\vtotal_usage\x18\x02 \x01(\v2..graph.substreams.data_service.common.v1.UsageR\n" +
-	"totalUsage2\xac\x03\n" +
+	"totalUsage\x12[\n" +
+	"\rfinal_receipt\x18\x03 \x01(\v26.graph.substreams.data_service.common.v1.SignedReceiptR\ffinalReceipt\x12\\\n" +
+	"\n" +
+	"settlement\x18\x04 \x01(\v2<.graph.substreams.data_service.consumer.v1.SessionSettlementR\n" +
+	"settlement\"\xb8\x01\n" +
+	"\x13ListSessionsRequest\x12[\n" +
+	"\x10service_provider\x18\x01 \x01(\v20.graph.substreams.data_service.common.v1.AddressR\x0fserviceProvider\x12#\n" +
+	"\rcollection_id\x18\x02 \x01(\fR\fcollectionId\x12\x1f\n" +
+	"\vactive_only\x18\x03 \x01(\bR\n" +
+	"activeOnly\"f\n" +
+	"\x14ListSessionsResponse\x12N\n" +
+	"\bsessions\x18\x01 \x03(\v22.graph.substreams.data_service.consumer.v1.SessionR\bsessions\"2\n" +
+	"\x11GetSessionRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"b\n" +
+	"\x12GetSessionResponse\x12L\n" +
+	"\asession\x18\x01 \x01(\v22.graph.substreams.data_service.consumer.v1.SessionR\asession\"\xe0\x03\n" +
+	"\aSession\x12H\n" +
+	"\x04info\x18\x01 \x01(\v24.graph.substreams.data_service.common.v1.SessionInfoR\x04info\x12\x16\n" +
+	"\x06active\x18\x02 \x01(\bR\x06active\x12+\n" +
+	"\x11provider_endpoint\x18\x03 \x01(\tR\x10providerEndpoint\x12\"\n" +
+	"\rcreated_at_ns\x18\x04 \x01(\x04R\vcreatedAtNs\x12\x1e\n" +
+	"\vended_at_ns\x18\x05 \x01(\x04R\tendedAtNs\x12Q\n" +
+	"\n" +
+	"end_reason\x18\x06 \x01(\x0e22.graph.substreams.data_service.common.v1.EndReasonR\tendReason\x12W\n" +
+	"\fpayment_mode\x18\a \x01(\x0e24.graph.substreams.data_service.common.v1.PaymentModeR\vpaymentMode\x12V\n" +
+	"\x0ereceipts_value\x18\b \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\rreceiptsValue\"5\n" +
+	"\x14ResumeSessionRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"\xba\x01\n" +
+	"\x15ResumeSessionResponse\x12L\n" +
+	"\asession\x18\x01 \x01(\v22.graph.substreams.data_service.consumer.v1.SessionR\asession\x12S\n" +
+	"\vpayment_rav\x18\x02 \x01(\v22.graph.substreams.data_service.common.v1.SignedRAVR\n" +
+	"paymentRav\"\xa5\x04\n" +
+	"\x11SessionSettlement\x12S\n" +
+	"\rclaimed_usage\x18\x01 \x01(\v2..graph.substreams.data_service.common.v1.UsageR\fclaimedUsage\x12U\n" +
+	"\x0eobserved_usage\x18\x02 \x01(\v2..graph.substreams.data_service.common.v1.UsageR\robservedUsage\x12R\n" +
+	"\fsigned_value\x18\x03 \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\vsignedValue\x12\x1e\n" +
+	"\n" +
+	"divergence\x18\x04 \x01(\x01R\n" +
+	"divergence\x12\x1a\n" +
+	"\bdisputed\x18\x05 \x01(\bR\bdisputed\x12g\n" +
+	"\x17global_budget_remaining\x18\x06 \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\x15globalBudgetRemaining\x12k\n" +
+	"\x19provider_budget_remaining\x18\a \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\x17providerBudgetRemaining*\xcf\x01\n" +
+	"\vBudgetLimit\x12\x1c\n" +
+	"\x18BUDGET_LIMIT_UNSPECIFIED\x10\x00\x12\x17\n" +
+	"\x13BUDGET_LIMIT_GLOBAL\x10\x01\x12\x19\n" +
+	"\x15BUDGET_LIMIT_PROVIDER\x10\x02\x12\x18\n" +
+	"\x14BUDGET_LIMIT_SESSION\x10\x03\x12\x16\n" +
+	"\x12BUDGET_LIMIT_PAYER\x10\x04\x12 \n" +
+	"\x1cBUDGET_LIMIT_VALUE_PER_BLOCK\x10\x05\x12\x1a\n" +
+	"\x16BUDGET_LIMIT_RAV_DELTA\x10\x062\xdf\x06\n" +
 	"\x16ConsumerSidecarService\x12w\n" +
 	"\x04Init\x126.graph.substreams.data_service.consumer.v1.InitRequest\x1a7.graph.substreams.data_service.consumer.v1.InitResponse\x12\x8c\x01\n" +
 	"\vReportUsage\x12=.graph.substreams.data_service.consumer.v1.ReportUsageRequest\x1a>.graph.substreams.data_service.consumer.v1.ReportUsageResponse\x12\x89\x01\n" +
 	"\n" +
-	"EndSession\x12<.graph.substreams.data_service.consumer.v1.EndSessionRequest\x1a=.graph.substreams.data_service.consumer.v1.EndSessionResponseB\xed\x02\n" +
+	"EndSession\x12<.graph.substreams.data_service.consumer.v1.EndSessionRequest\x1a=.graph.substreams.data_service.consumer.v1.EndSessionResponse\x12\x8f\x01\n" +
+	"\fListSessions\x12>.graph.substreams.data_service.consumer.v1.ListSessionsRequest\x1a?.graph.substreams.data_service.consumer.v1.ListSessionsResponse\x12\x89\x01\n" +
+	"\n" +
+	"GetSession\x12<.graph.substreams.data_service.consumer.v1.GetSessionRequest\x1a=.graph.substreams.data_service.consumer.v1.GetSessionResponse\x12\x92\x01\n" +
+	"\rResumeSession\x12?.graph.substreams.data_service.consumer.v1.ResumeSessionRequest\x1a@.graph.substreams.data_service.consumer.v1.ResumeSessionResponseB\xed\x02\n" +
 	"-com.graph.substreams.data_service.consumer.v1B\rConsumerProtoP\x01Zhgithub.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/consumer/v1;consumerv1\xa2\x02\x04GSDC\xaa\x02(Graph.Substreams.DataService.Consumer.V1\xca\x02(Graph\\Substreams\\DataService\\Consumer\\V1\xe2\x024Graph\\Substreams\\DataService\\Consumer\\V1\\GPBMetadata\xea\x02,Graph::Substreams::DataService::Consumer::V1b\x06proto3"
 
 var (
@@ -415,40 +1202,88 @@ func file_graph_substreams_data_service_consumer_v1_consumer_proto_rawDescGZIP()
 	return file_graph_substreams_data_service_consumer_v1_consumer_proto_rawDescData
 }
 
-var file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_graph_substreams_data_service_consumer_v1_consumer_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
 var file_graph_substreams_data_service_consumer_v1_consumer_proto_goTypes = []any{
-	(*InitRequest)(nil),         // 0: graph.substreams.data_service.consumer.v1.InitRequest
-	(*InitResponse)(nil),        // 1: graph.substreams.data_service.consumer.v1.InitResponse
-	(*ReportUsageRequest)(nil),  // 2: graph.substreams.data_service.consumer.v1.ReportUsageRequest
-	(*ReportUsageResponse)(nil), // 3: graph.substreams.data_service.consumer.v1.ReportUsageResponse
-	(*EndSessionRequest)(nil),   // 4: graph.substreams.data_service.consumer.v1.EndSessionRequest
-	(*EndSessionResponse)(nil),  // 5: graph.substreams.data_service.consumer.v1.EndSessionResponse
-	(*v1.EscrowAccount)(nil),    // 6: graph.substreams.data_service.common.v1.EscrowAccount
-	(*v1.SignedRAV)(nil),        // 7: graph.substreams.data_service.common.v1.SignedRAV
-	(*v1.SessionInfo)(nil),      // 8: graph.substreams.data_service.common.v1.SessionInfo
-	(*v1.Usage)(nil),            // 9: graph.substreams.data_service.common.v1.Usage
+	(BudgetLimit)(0),              // 0: graph.substreams.data_service.consumer.v1.BudgetLimit
+	(*InitRequest)(nil),           // 1: graph.substreams.data_service.consumer.v1.InitRequest
+	(*InitResponse)(nil),          // 2: graph.substreams.data_service.consumer.v1.InitResponse
+	(*ReportUsageRequest)(nil),    // 3: graph.substreams.data_service.consumer.v1.ReportUsageRequest
+	(*ReportUsageResponse)(nil),   // 4: graph.substreams.data_service.consumer.v1.ReportUsageResponse
+	(*BudgetViolation)(nil),       // 5: graph.substreams.data_service.consumer.v1.BudgetViolation
+	(*EndSessionRequest)(nil),     // 6: graph.substreams.data_service.consumer.v1.EndSessionRequest
+	(*EndSessionResponse)(nil),    // 7: graph.substreams.data_service.consumer.v1.EndSessionResponse
+	(*ListSessionsRequest)(nil),   // 8: graph.substreams.data_service.consumer.v1.ListSessionsRequest
+	(*ListSessionsResponse)(nil),  // 9: graph.substreams.data_service.consumer.v1.ListSessionsResponse
+	(*GetSessionRequest)(nil),     // 10: graph.substreams.data_service.consumer.v1.GetSessionRequest
+	(*GetSessionResponse)(nil),    // 11: graph.substreams.data_service.consumer.v1.GetSessionResponse
+	(*Session)(nil),               // 12: graph.substreams.data_service.consumer.v1.Session
+	(*ResumeSessionRequest)(nil),  // 13: graph.substreams.data_service.consumer.v1.ResumeSessionRequest
+	(*ResumeSessionResponse)(nil), // 14: graph.substreams.data_service.consumer.v1.ResumeSessionResponse
+	(*SessionSettlement)(nil),     // 15: graph.substreams.data_service.consumer.v1.SessionSettlement
+	(*v1.EscrowAccount)(nil),      // 16: graph.substreams.data_service.common.v1.EscrowAccount
+	(*v1.SignedRAV)(nil),          // 17: graph.substreams.data_service.common.v1.SignedRAV
+	(v1.PaymentMode)(0),           // 18: graph.substreams.data_service.common.v1.PaymentMode
+	(*v1.SessionInfo)(nil),        // 19: graph.substreams.data_service.common.v1.SessionInfo
+	(*v1.Usage)(nil),              // 20: graph.substreams.data_service.common.v1.Usage
+	(v1.RejectionCode)(0),         // 21: graph.substreams.data_service.common.v1.RejectionCode
+	(*v1.SignedReceipt)(nil),      // 22: graph.substreams.data_service.common.v1.SignedReceipt
+	(*v1.BigInt)(nil),             // 23: graph.substreams.data_service.common.v1.BigInt
+	(*v1.Address)(nil),            // 24: graph.substreams.data_service.common.v1.Address
+	(v1.EndReason)(0),             // 25: graph.substreams.data_service.common.v1.EndReason
 }
 var file_graph_substreams_data_service_consumer_v1_consumer_proto_depIdxs = []int32{
-	6,  // 0: graph.substreams.data_service.consumer.v1.InitRequest.escrow_account:type_name -> graph.substreams.data_service.common.v1.EscrowAccount
-	7,  // 1: graph.substreams.data_service.consumer.v1.InitRequest.existing_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
-	8,  // 2: graph.substreams.data_service.consumer.v1.InitResponse.session:type_name -> graph.substreams.data_service.common.v1.SessionInfo
-	7,  // 3: graph.substreams.data_service.consumer.v1.InitResponse.payment_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
-	9,  // 4: graph.substreams.data_service.consumer.v1.ReportUsageRequest.usage:type_name -> graph.substreams.data_service.common.v1.Usage
-	7,  // 5: graph.substreams.data_service.consumer.v1.ReportUsageResponse.updated_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
-	9,  // 6: graph.substreams.data_service.consumer.v1.EndSessionRequest.final_usage:type_name -> graph.substreams.data_service.common.v1.Usage
-	7,  // 7: graph.substreams.data_service.consumer.v1.EndSessionResponse.final_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
-	9,  // 8: graph.substreams.data_service.consumer.v1.EndSessionResponse.total_usage:type_name -> graph.substreams.data_service.common.v1.Usage
-	0,  // 9: graph.substreams.data_service.consumer.v1.ConsumerSidecarService.Init:input_type -> graph.substreams.data_service.consumer.v1.InitRequest
-	2,  // 10: graph.substreams.data_service.consumer.v1.ConsumerSidecarService.ReportUsage:input_type -> graph.substreams.data_service.consumer.v1.ReportUsageRequest
-	4,  // 11: graph.substreams.data_service.consumer.v1.ConsumerSidecarService.EndSession:input_type -> graph.substreams.data_service.consumer.v1.EndSessionRequest
-	1,  // 12: graph.substreams.data_service.consumer.v1.ConsumerSidecarService.Init:output_type -> graph.substreams.data_service.consumer.v1.InitResponse
-	3,  // 13: graph.substreams.data_service.consumer.v1.ConsumerSidecarService.ReportUsage:output_type -> graph.substreams.data_service.consumer.v1.ReportUsageResponse
-	5,  // 14: graph.substreams.data_service.consumer.v1.ConsumerSidecarService.EndSession:output_type -> graph.substreams.data_service.consumer.v1.EndSessionResponse
-	12, // [12:15] is the sub-list for method output_type
-	9,  // [9:12] is the sub-list for method input_type
-	9,  // [9:9] is the sub-list for extension type_name
-	9,  // [9:9] is the sub-list for extension extendee
-	0,  // [0:9] is the sub-list for field type_name
+	16, // 0: graph.substreams.data_service.consumer.v1.InitRequest.escrow_account:type_name -> graph.substreams.data_service.common.v1.EscrowAccount
+	17, // 1: graph.substreams.data_service.consumer.v1.InitRequest.existing_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
+	18, // 2: graph.substreams.data_service.consumer.v1.InitRequest.payment_mode:type_name -> graph.substreams.data_service.common.v1.PaymentMode
+	19, // 3: graph.substreams.data_service.consumer.v1.InitResponse.session:type_name -> graph.substreams.data_service.common.v1.SessionInfo
+	17, // 4: graph.substreams.data_service.consumer.v1.InitResponse.payment_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
+	18, // 5: graph.substreams.data_service.consumer.v1.InitResponse.payment_mode:type_name -> graph.substreams.data_service.common.v1.PaymentMode
+	20, // 6: graph.substreams.data_service.consumer.v1.ReportUsageRequest.usage:type_name -> graph.substreams.data_service.common.v1.Usage
+	17, // 7: graph.substreams.data_service.consumer.v1.ReportUsageResponse.updated_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
+	21, // 8: graph.substreams.data_service.consumer.v1.ReportUsageResponse.stop_code:type_name -> graph.substreams.data_service.common.v1.RejectionCode
+	5,  // 9: graph.substreams.data_service.consumer.v1.ReportUsageResponse.budget_violation:type_name -> graph.substreams.data_service.consumer.v1.BudgetViolation
+	22, // 10: graph.substreams.data_service.consumer.v1.ReportUsageResponse.receipt:type_name -> graph.substreams.data_service.common.v1.SignedReceipt
+	0,  // 11: graph.substreams.data_service.consumer.v1.BudgetViolation.limit:type_name -> graph.substreams.data_service.consumer.v1.BudgetLimit
+	23, // 12: graph.substreams.data_service.consumer.v1.BudgetViolation.max:type_name -> graph.substreams.data_service.common.v1.BigInt
+	23, // 13: graph.substreams.data_service.consumer.v1.BudgetViolation.requested:type_name -> graph.substreams.data_service.common.v1.BigInt
+	20, // 14: graph.substreams.data_service.consumer.v1.EndSessionRequest.final_usage:type_name -> graph.substreams.data_service.common.v1.Usage
+	20, // 15: graph.substreams.data_service.consumer.v1.EndSessionRequest.observed_usage:type_name -> graph.substreams.data_service.common.v1.Usage
+	17, // 16: graph.substreams.data_service.consumer.v1.EndSessionResponse.final_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
+	20, // 17: graph.substreams.data_service.consumer.v1.EndSessionResponse.total_usage:type_name -> graph.substreams.data_service.common.v1.Usage
+	22, // 18: graph.substreams.data_service.consumer.v1.EndSessionResponse.final_receipt:type_name -> graph.substreams.data_service.common.v1.SignedReceipt
+	15, // 19: graph.substreams.data_service.consumer.v1.EndSessionResponse.settlement:type_name -> graph.substreams.data_service.consumer.v1.SessionSettlement
+	24, // 20: graph.substreams.data_service.consumer.v1.ListSessionsRequest.service_provider:type_name -> graph.substreams.data_service.common.v1.Address
+	12, // 21: graph.substreams.data_service.consumer.v1.ListSessionsResponse.sessions:type_name -> graph.substreams.data_service.consumer.v1.Session
+	12, // 22: graph.substreams.data_service.consumer.v1.GetSessionResponse.session:type_name -> graph.substreams.data_service.consumer.v1.Session
+	19, // 23: graph.substreams.data_service.consumer.v1.Session.info:type_name -> graph.substreams.data_service.common.v1.SessionInfo
+	25, // 24: graph.substreams.data_service.consumer.v1.Session.end_reason:type_name -> graph.substreams.data_service.common.v1.EndReason
+	18, // 25: graph.substreams.data_service.consumer.v1.Session.payment_mode:type_name -> graph.substreams.data_service.common.v1.PaymentMode
+	23, // 26: graph.substreams.data_service.consumer.v1.Session.receipts_value:type_name -> graph.substreams.data_service.common.v1.BigInt
+	12, // 27: graph.substreams.data_service.consumer.v1.ResumeSessionResponse.session:type_name -> graph.substreams.data_service.consumer.v1.Session
+	17, // 28: graph.substreams.data_service.consumer.v1.ResumeSessionResponse.payment_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
+	20, // 29: graph.substreams.data_service.consumer.v1.SessionSettlement.claimed_usage:type_name -> graph.substreams.data_service.common.v1.Usage
+	20, // 30: graph.substreams.data_service.consumer.v1.SessionSettlement.observed_usage:type_name -> graph.substreams.data_service.common.v1.Usage
+	23, // 31: graph.substreams.data_service.consumer.v1.SessionSettlement.signed_value:type_name -> graph.substreams.data_service.common.v1.BigInt
+	23, // 32: graph.substreams.data_service.consumer.v1.SessionSettlement.global_budget_remaining:type_name -> graph.substreams.data_service.common.v1.BigInt
+	23, // 33: graph.substreams.data_service.consumer.v1.SessionSettlement.provider_budget_remaining:type_name -> graph.substreams.data_service.common.v1.BigInt
+	1,  // 34: graph.substreams.data_service.consumer.v1.ConsumerSidecarService.Init:input_type -> graph.substreams.data_service.consumer.v1.InitRequest
+	3,  // 35: graph.substreams.data_service.consumer.v1.ConsumerSidecarService.ReportUsage:input_type -> graph.substreams.data_service.consumer.v1.ReportUsageRequest
+	6,  // 36: graph.substreams.data_service.consumer.v1.ConsumerSidecarService.EndSession:input_type -> graph.substreams.data_service.consumer.v1.EndSessionRequest
+	8,  // 37: graph.substreams.data_service.consumer.v1.ConsumerSidecarService.ListSessions:input_type -> graph.substreams.data_service.consumer.v1.ListSessionsRequest
+	10, // 38: graph.substreams.data_service.consumer.v1.ConsumerSidecarService.GetSession:input_type -> graph.substreams.data_service.consumer.v1.GetSessionRequest
+	13, // 39: graph.substreams.data_service.consumer.v1.ConsumerSidecarService.ResumeSession:input_type -> graph.substreams.data_service.consumer.v1.ResumeSessionRequest
+	2,  // 40: graph.substreams.data_service.consumer.v1.ConsumerSidecarService.Init:output_type -> graph.substreams.data_service.consumer.v1.InitResponse
+	4,  // 41: graph.substreams.data_service.consumer.v1.ConsumerSidecarService.ReportUsage:output_type -> graph.substreams.data_service.consumer.v1.ReportUsageResponse
+	7,  // 42: graph.substreams.data_service.consumer.v1.ConsumerSidecarService.EndSession:output_type -> graph.substreams.data_service.consumer.v1.EndSessionResponse
+	9,  // 43: graph.substreams.data_service.consumer.v1.ConsumerSidecarService.ListSessions:output_type -> graph.substreams.data_service.consumer.v1.ListSessionsResponse
+	11, // 44: graph.substreams.data_service.consumer.v1.ConsumerSidecarService.GetSession:output_type -> graph.substreams.data_service.consumer.v1.GetSessionResponse
+	14, // 45: graph.substreams.data_service.consumer.v1.ConsumerSidecarService.ResumeSession:output_type -> graph.substreams.data_service.consumer.v1.ResumeSessionResponse
+	40, // [40:46] is the sub-list for method output_type
+	34, // [34:40] is the sub-list for method input_type
+	34, // [34:34] is the sub-list for extension type_name
+	34, // [34:34] is the sub-list for extension extendee
+	0,  // [0:34] is the sub-list for field type_name
 }
 
 func init() { file_graph_substreams_data_service_consumer_v1_consumer_proto_init() }
@@ -461,13 +1296,14 @@ func file_graph_substreams_data_service_consumer_v1_consumer_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_graph_substreams_data_service_consumer_v1_consumer_proto_rawDesc), len(file_graph_substreams_data_service_consumer_v1_consumer_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   6,
+			NumEnums:      1,
+			NumMessages:   15,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_graph_substreams_data_service_consumer_v1_consumer_proto_goTypes,
 		DependencyIndexes: file_graph_substreams_data_service_consumer_v1_consumer_proto_depIdxs,
+		EnumInfos:         file_graph_substreams_data_service_consumer_v1_consumer_proto_enumTypes,
 		MessageInfos:      file_graph_substreams_data_service_consumer_v1_consumer_proto_msgTypes,
 	}.Build()
 	File_graph_substreams_data_service_consumer_v1_consumer_proto = out.File
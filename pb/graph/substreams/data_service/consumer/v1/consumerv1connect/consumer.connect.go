@@ -42,6 +42,15 @@ const (
 	// ConsumerSidecarServiceEndSessionProcedure is the fully-qualified name of the
 	// ConsumerSidecarService's EndSession RPC.
 	ConsumerSidecarServiceEndSessionProcedure = "/graph.substreams.data_service.consumer.v1.ConsumerSidecarService/EndSession"
+	// ConsumerSidecarServiceListSessionsProcedure is the fully-qualified name of the
+	// ConsumerSidecarService's ListSessions RPC.
+	ConsumerSidecarServiceListSessionsProcedure = "/graph.substreams.data_service.consumer.v1.ConsumerSidecarService/ListSessions"
+	// ConsumerSidecarServiceGetSessionProcedure is the fully-qualified name of the
+	// ConsumerSidecarService's GetSession RPC.
+	ConsumerSidecarServiceGetSessionProcedure = "/graph.substreams.data_service.consumer.v1.ConsumerSidecarService/GetSession"
+	// ConsumerSidecarServiceResumeSessionProcedure is the fully-qualified name of the
+	// ConsumerSidecarService's ResumeSession RPC.
+	ConsumerSidecarServiceResumeSessionProcedure = "/graph.substreams.data_service.consumer.v1.ConsumerSidecarService/ResumeSession"
 )
 
 // ConsumerSidecarServiceClient is a client for the
@@ -57,6 +66,17 @@ type ConsumerSidecarServiceClient interface {
 	// EndSession ends the current session and reports final usage.
 	// Called by substreams when the stream ends.
 	EndSession(context.Context, *connect.Request[v1.EndSessionRequest]) (*connect.Response[v1.EndSessionResponse], error)
+	// ListSessions lists the sessions of the sidecar, active and ended, ordered
+	// by creation time. A client streaming from several providers runs all its
+	// sessions through a single sidecar and finds them back here.
+	ListSessions(context.Context, *connect.Request[v1.ListSessionsRequest]) (*connect.Response[v1.ListSessionsResponse], error)
+	// GetSession gets a session by ID.
+	GetSession(context.Context, *connect.Request[v1.GetSessionRequest]) (*connect.Response[v1.GetSessionResponse], error)
+	// ResumeSession re-attaches a client to an active session, e.g. after the
+	// client or the sidecar restarted mid-stream. The session continues from its
+	// accumulated usage and last signed RAV, restored from the session store when
+	// the sidecar restarted.
+	ResumeSession(context.Context, *connect.Request[v1.ResumeSessionRequest]) (*connect.Response[v1.ResumeSessionResponse], error)
 }
 
 // NewConsumerSidecarServiceClient constructs a client for the
@@ -89,14 +109,35 @@ func NewConsumerSidecarServiceClient(httpClient connect.HTTPClient, baseURL stri
 			connect.WithSchema(consumerSidecarServiceMethods.ByName("EndSession")),
 			connect.WithClientOptions(opts...),
 		),
+		listSessions: connect.NewClient[v1.ListSessionsRequest, v1.ListSessionsResponse](
+			httpClient,
+			baseURL+ConsumerSidecarServiceListSessionsProcedure,
+			connect.WithSchema(consumerSidecarServiceMethods.ByName("ListSessions")),
+			connect.WithClientOptions(opts...),
+		),
+		getSession: connect.NewClient[v1.GetSessionRequest, v1.GetSessionResponse](
+			httpClient,
+			baseURL+ConsumerSidecarServiceGetSessionProcedure,
+			connect.WithSchema(consumerSidecarServiceMethods.ByName("GetSession")),
+			connect.WithClientOptions(opts...),
+		),
+		resumeSession: connect.NewClient[v1.ResumeSessionRequest, v1.ResumeSessionResponse](
+			httpClient,
+			baseURL+ConsumerSidecarServiceResumeSessionProcedure,
+			connect.WithSchema(consumerSidecarServiceMethods.ByName("ResumeSession")),
+			connect.WithClientOptions(opts...),
+		),
 	}
 }
 
 // consumerSidecarServiceClient implements ConsumerSidecarServiceClient.
 type consumerSidecarServiceClient struct {
-	init        *connect.Client[v1.InitRequest, v1.InitResponse]
-	reportUsage *connect.Client[v1.ReportUsageRequest, v1.ReportUsageResponse]
-	endSession  *connect.Client[v1.EndSessionRequest, v1.EndSessionResponse]
+	init          *connect.Client[v1.InitRequest, v1.InitResponse]
+	reportUsage   *connect.Client[v1.ReportUsageRequest, v1.ReportUsageResponse]
+	endSession    *connect.Client[v1.EndSessionRequest, v1.EndSessionResponse]
+	listSessions  *connect.Client[v1.ListSessionsRequest, v1.ListSessionsResponse]
+	getSession    *connect.Client[v1.GetSessionRequest, v1.GetSessionResponse]
+	resumeSession *connect.Client[v1.ResumeSessionRequest, v1.ResumeSessionResponse]
 }
 
 // Init calls graph.substreams.data_service.consumer.v1.ConsumerSidecarService.Init.
@@ -114,6 +155,21 @@ func (c *consumerSidecarServiceClient) EndSession(ctx context.Context, req *conn
 	return c.endSession.CallUnary(ctx, req)
 }
 
+// ListSessions calls graph.substreams.data_service.consumer.v1.ConsumerSidecarService.ListSessions.
+func (c *consumerSidecarServiceClient) ListSessions(ctx context.Context, req *connect.Request[v1.ListSessionsRequest]) (*connect.Response[v1.ListSessionsResponse], error) {
+	return c.listSessions.CallUnary(ctx, req)
+}
+
+// GetSession calls graph.substreams.data_service.consumer.v1.ConsumerSidecarService.GetSession.
+func (c *consumerSidecarServiceClient) GetSession(ctx context.Context, req *connect.Request[v1.GetSessionRequest]) (*connect.Response[v1.GetSessionResponse], error) {
+	return c.getSession.CallUnary(ctx, req)
+}
+
+// ResumeSession calls graph.substreams.data_service.consumer.v1.ConsumerSidecarService.ResumeSession.
+func (c *consumerSidecarServiceClient) ResumeSession(ctx context.Context, req *connect.Request[v1.ResumeSessionRequest]) (*connect.Response[v1.ResumeSessionResponse], error) {
+	return c.resumeSession.CallUnary(ctx, req)
+}
+
 // ConsumerSidecarServiceHandler is an implementation of the
 // graph.substreams.data_service.consumer.v1.ConsumerSidecarService service.
 type ConsumerSidecarServiceHandler interface {
@@ -127,6 +183,17 @@ type ConsumerSidecarServiceHandler interface {
 	// EndSession ends the current session and reports final usage.
 	// Called by substreams when the stream ends.
 	EndSession(context.Context, *connect.Request[v1.EndSessionRequest]) (*connect.Response[v1.EndSessionResponse], error)
+	// ListSessions lists the sessions of the sidecar, active and ended, ordered
+	// by creation time. A client streaming from several providers runs all its
+	// sessions through a single sidecar and finds them back here.
+	ListSessions(context.Context, *connect.Request[v1.ListSessionsRequest]) (*connect.Response[v1.ListSessionsResponse], error)
+	// GetSession gets a session by ID.
+	GetSession(context.Context, *connect.Request[v1.GetSessionRequest]) (*connect.Response[v1.GetSessionResponse], error)
+	// ResumeSession re-attaches a client to an active session, e.g. after the
+	// client or the sidecar restarted mid-stream. The session continues from its
+	// accumulated usage and last signed RAV, restored from the session store when
+	// the sidecar restarted.
+	ResumeSession(context.Context, *connect.Request[v1.ResumeSessionRequest]) (*connect.Response[v1.ResumeSessionResponse], error)
 }
 
 // NewConsumerSidecarServiceHandler builds an HTTP handler from the service implementation. It
@@ -154,6 +221,24 @@ func NewConsumerSidecarServiceHandler(svc ConsumerSidecarServiceHandler, opts ..
 		connect.WithSchema(consumerSidecarServiceMethods.ByName("EndSession")),
 		connect.WithHandlerOptions(opts...),
 	)
+	consumerSidecarServiceListSessionsHandler := connect.NewUnaryHandler(
+		ConsumerSidecarServiceListSessionsProcedure,
+		svc.ListSessions,
+		connect.WithSchema(consumerSidecarServiceMethods.ByName("ListSessions")),
+		connect.WithHandlerOptions(opts...),
+	)
+	consumerSidecarServiceGetSessionHandler := connect.NewUnaryHandler(
+		ConsumerSidecarServiceGetSessionProcedure,
+		svc.GetSession,
+		connect.WithSchema(consumerSidecarServiceMethods.ByName("GetSession")),
+		connect.WithHandlerOptions(opts...),
+	)
+	consumerSidecarServiceResumeSessionHandler := connect.NewUnaryHandler(
+		ConsumerSidecarServiceResumeSessionProcedure,
+		svc.ResumeSession,
+		connect.WithSchema(consumerSidecarServiceMethods.ByName("ResumeSession")),
+		connect.WithHandlerOptions(opts...),
+	)
 	return "/graph.substreams.data_service.consumer.v1.ConsumerSidecarService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case ConsumerSidecarServiceInitProcedure:
@@ -162,6 +247,12 @@ func NewConsumerSidecarServiceHandler(svc ConsumerSidecarServiceHandler, opts ..
 			consumerSidecarServiceReportUsageHandler.ServeHTTP(w, r)
 		case ConsumerSidecarServiceEndSessionProcedure:
 			consumerSidecarServiceEndSessionHandler.ServeHTTP(w, r)
+		case ConsumerSidecarServiceListSessionsProcedure:
+			consumerSidecarServiceListSessionsHandler.ServeHTTP(w, r)
+		case ConsumerSidecarServiceGetSessionProcedure:
+			consumerSidecarServiceGetSessionHandler.ServeHTTP(w, r)
+		case ConsumerSidecarServiceResumeSessionProcedure:
+			consumerSidecarServiceResumeSessionHandler.ServeHTTP(w, r)
 		default:
 			http.NotFound(w, r)
 		}
@@ -182,3 +273,15 @@ func (UnimplementedConsumerSidecarServiceHandler) ReportUsage(context.Context, *
 func (UnimplementedConsumerSidecarServiceHandler) EndSession(context.Context, *connect.Request[v1.EndSessionRequest]) (*connect.Response[v1.EndSessionResponse], error) {
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("graph.substreams.data_service.consumer.v1.ConsumerSidecarService.EndSession is not implemented"))
 }
+
+func (UnimplementedConsumerSidecarServiceHandler) ListSessions(context.Context, *connect.Request[v1.ListSessionsRequest]) (*connect.Response[v1.ListSessionsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("graph.substreams.data_service.consumer.v1.ConsumerSidecarService.ListSessions is not implemented"))
+}
+
+func (UnimplementedConsumerSidecarServiceHandler) GetSession(context.Context, *connect.Request[v1.GetSessionRequest]) (*connect.Response[v1.GetSessionResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("graph.substreams.data_service.consumer.v1.ConsumerSidecarService.GetSession is not implemented"))
+}
+
+func (UnimplementedConsumerSidecarServiceHandler) ResumeSession(context.Context, *connect.Request[v1.ResumeSessionRequest]) (*connect.Response[v1.ResumeSessionResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("graph.substreams.data_service.consumer.v1.ConsumerSidecarService.ResumeSession is not implemented"))
+}
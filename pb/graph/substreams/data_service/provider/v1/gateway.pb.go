@@ -75,7 +75,7 @@ func (x SessionControl_Action) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use SessionControl_Action.Descriptor instead.
 func (SessionControl_Action) EnumDescriptor() ([]byte, []int) {
-	return file_graph_substreams_data_service_provider_v1_gateway_proto_rawDescGZIP(), []int{11, 0}
+	return file_graph_substreams_data_service_provider_v1_gateway_proto_rawDescGZIP(), []int{13, 0}
 }
 
 type StartSessionRequest struct {
@@ -83,7 +83,11 @@ type StartSessionRequest struct {
 	// The escrow account funding this session
 	EscrowAccount *v1.EscrowAccount `protobuf:"bytes,1,opt,name=escrow_account,json=escrowAccount,proto3" json:"escrow_account,omitempty"`
 	// Initial RAV (can be a zero-value RAV for new sessions)
-	InitialRav    *v1.SignedRAV `protobuf:"bytes,2,opt,name=initial_rav,json=initialRav,proto3" json:"initial_rav,omitempty"`
+	InitialRav *v1.SignedRAV `protobuf:"bytes,2,opt,name=initial_rav,json=initialRav,proto3" json:"initial_rav,omitempty"`
+	// How usage of the session is paid for, PAYMENT_MODE_RAV when unspecified.
+	// In PAYMENT_MODE_RECEIPTS, receipts submitted through SubmitRAV are
+	// aggregated into the session RAV periodically.
+	PaymentMode   v1.PaymentMode `protobuf:"varint,3,opt,name=payment_mode,json=paymentMode,proto3,enum=graph.substreams.data_service.common.v1.PaymentMode" json:"payment_mode,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -132,6 +136,13 @@ func (x *StartSessionRequest) GetInitialRav() *v1.SignedRAV {
 	return nil
 }
 
+func (x *StartSessionRequest) GetPaymentMode() v1.PaymentMode {
+	if x != nil {
+		return x.PaymentMode
+	}
+	return v1.PaymentMode(0)
+}
+
 type StartSessionResponse struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// Unique session identifier assigned by the provider
@@ -142,8 +153,10 @@ type StartSessionResponse struct {
 	Accepted bool `protobuf:"varint,3,opt,name=accepted,proto3" json:"accepted,omitempty"`
 	// If not accepted, the reason for rejection
 	RejectionReason string `protobuf:"bytes,4,opt,name=rejection_reason,json=rejectionReason,proto3" json:"rejection_reason,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+	// If not accepted, the machine code of the rejection
+	RejectionCode v1.RejectionCode `protobuf:"varint,5,opt,name=rejection_code,json=rejectionCode,proto3,enum=graph.substreams.data_service.common.v1.RejectionCode" json:"rejection_code,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *StartSessionResponse) Reset() {
@@ -204,6 +217,13 @@ func (x *StartSessionResponse) GetRejectionReason() string {
 	return ""
 }
 
+func (x *StartSessionResponse) GetRejectionCode() v1.RejectionCode {
+	if x != nil {
+		return x.RejectionCode
+	}
+	return v1.RejectionCode(0)
+}
+
 type SubmitRAVRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// The session ID
@@ -211,7 +231,11 @@ type SubmitRAVRequest struct {
 	// The signed RAV being submitted
 	SignedRav *v1.SignedRAV `protobuf:"bytes,2,opt,name=signed_rav,json=signedRav,proto3" json:"signed_rav,omitempty"`
 	// The usage this RAV covers
-	Usage         *v1.Usage `protobuf:"bytes,3,opt,name=usage,proto3" json:"usage,omitempty"`
+	Usage *v1.Usage `protobuf:"bytes,3,opt,name=usage,proto3" json:"usage,omitempty"`
+	// Signed receipts to aggregate into a RAV through the provider's aggregator,
+	// mutually exclusive with signed_rav. They are aggregated right away, or
+	// periodically for sessions in PAYMENT_MODE_RECEIPTS.
+	Receipts      []*v1.SignedReceipt `protobuf:"bytes,4,rep,name=receipts,proto3" json:"receipts,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -267,6 +291,13 @@ func (x *SubmitRAVRequest) GetUsage() *v1.Usage {
 	return nil
 }
 
+func (x *SubmitRAVRequest) GetReceipts() []*v1.SignedReceipt {
+	if x != nil {
+		return x.Receipts
+	}
+	return nil
+}
+
 type SubmitRAVResponse struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// Whether the RAV was accepted
@@ -275,8 +306,13 @@ type SubmitRAVResponse struct {
 	RejectionReason string `protobuf:"bytes,2,opt,name=rejection_reason,json=rejectionReason,proto3" json:"rejection_reason,omitempty"`
 	// Whether the session should continue
 	ShouldContinue bool `protobuf:"varint,3,opt,name=should_continue,json=shouldContinue,proto3" json:"should_continue,omitempty"`
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+	// The RAV produced by the aggregator when receipts were submitted and
+	// aggregated right away
+	AggregatedRav *v1.SignedRAV `protobuf:"bytes,4,opt,name=aggregated_rav,json=aggregatedRav,proto3" json:"aggregated_rav,omitempty"`
+	// If not accepted, the machine code of the rejection
+	RejectionCode v1.RejectionCode `protobuf:"varint,5,opt,name=rejection_code,json=rejectionCode,proto3,enum=graph.substreams.data_service.common.v1.RejectionCode" json:"rejection_code,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *SubmitRAVResponse) Reset() {
@@ -330,6 +366,128 @@ func (x *SubmitRAVResponse) GetShouldContinue() bool {
 	return false
 }
 
+func (x *SubmitRAVResponse) GetAggregatedRav() *v1.SignedRAV {
+	if x != nil {
+		return x.AggregatedRav
+	}
+	return nil
+}
+
+func (x *SubmitRAVResponse) GetRejectionCode() v1.RejectionCode {
+	if x != nil {
+		return x.RejectionCode
+	}
+	return v1.RejectionCode(0)
+}
+
+type ProveIdentityRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Random challenge chosen by the consumer (32 bytes)
+	Challenge []byte `protobuf:"bytes,1,opt,name=challenge,proto3" json:"challenge,omitempty"`
+	// The service provider the consumer expects to pay
+	ServiceProvider *v1.Address `protobuf:"bytes,2,opt,name=service_provider,json=serviceProvider,proto3" json:"service_provider,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ProveIdentityRequest) Reset() {
+	*x = ProveIdentityRequest{}
+	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProveIdentityRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProveIdentityRequest) ProtoMessage() {}
+
+func (x *ProveIdentityRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProveIdentityRequest.ProtoReflect.Descriptor instead.
+func (*ProveIdentityRequest) Descriptor() ([]byte, []int) {
+	return file_graph_substreams_data_service_provider_v1_gateway_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ProveIdentityRequest) GetChallenge() []byte {
+	if x != nil {
+		return x.Challenge
+	}
+	return nil
+}
+
+func (x *ProveIdentityRequest) GetServiceProvider() *v1.Address {
+	if x != nil {
+		return x.ServiceProvider
+	}
+	return nil
+}
+
+type ProveIdentityResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The service provider the challenge is signed for
+	ServiceProvider *v1.Address `protobuf:"bytes,1,opt,name=service_provider,json=serviceProvider,proto3" json:"service_provider,omitempty"`
+	// EIP-191 personal signature of the identity challenge by the service provider key
+	Signature     []byte `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProveIdentityResponse) Reset() {
+	*x = ProveIdentityResponse{}
+	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProveIdentityResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProveIdentityResponse) ProtoMessage() {}
+
+func (x *ProveIdentityResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProveIdentityResponse.ProtoReflect.Descriptor instead.
+func (*ProveIdentityResponse) Descriptor() ([]byte, []int) {
+	return file_graph_substreams_data_service_provider_v1_gateway_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ProveIdentityResponse) GetServiceProvider() *v1.Address {
+	if x != nil {
+		return x.ServiceProvider
+	}
+	return nil
+}
+
+func (x *ProveIdentityResponse) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
 // Messages from consumer sidecar to provider sidecar in the bidirectional stream
 type PaymentSessionRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -345,7 +503,7 @@ type PaymentSessionRequest struct {
 
 func (x *PaymentSessionRequest) Reset() {
 	*x = PaymentSessionRequest{}
-	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[4]
+	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -357,7 +515,7 @@ func (x *PaymentSessionRequest) String() string {
 func (*PaymentSessionRequest) ProtoMessage() {}
 
 func (x *PaymentSessionRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[4]
+	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -370,7 +528,7 @@ func (x *PaymentSessionRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PaymentSessionRequest.ProtoReflect.Descriptor instead.
 func (*PaymentSessionRequest) Descriptor() ([]byte, []int) {
-	return file_graph_substreams_data_service_provider_v1_gateway_proto_rawDescGZIP(), []int{4}
+	return file_graph_substreams_data_service_provider_v1_gateway_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *PaymentSessionRequest) GetMessage() isPaymentSessionRequest_Message {
@@ -447,7 +605,7 @@ type PaymentSessionResponse struct {
 
 func (x *PaymentSessionResponse) Reset() {
 	*x = PaymentSessionResponse{}
-	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[5]
+	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -459,7 +617,7 @@ func (x *PaymentSessionResponse) String() string {
 func (*PaymentSessionResponse) ProtoMessage() {}
 
 func (x *PaymentSessionResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[5]
+	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -472,7 +630,7 @@ func (x *PaymentSessionResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PaymentSessionResponse.ProtoReflect.Descriptor instead.
 func (*PaymentSessionResponse) Descriptor() ([]byte, []int) {
-	return file_graph_substreams_data_service_provider_v1_gateway_proto_rawDescGZIP(), []int{5}
+	return file_graph_substreams_data_service_provider_v1_gateway_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *PaymentSessionResponse) GetMessage() isPaymentSessionResponse_Message {
@@ -546,7 +704,7 @@ type SignedRAVSubmission struct {
 
 func (x *SignedRAVSubmission) Reset() {
 	*x = SignedRAVSubmission{}
-	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[6]
+	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -558,7 +716,7 @@ func (x *SignedRAVSubmission) String() string {
 func (*SignedRAVSubmission) ProtoMessage() {}
 
 func (x *SignedRAVSubmission) ProtoReflect() protoreflect.Message {
-	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[6]
+	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -571,7 +729,7 @@ func (x *SignedRAVSubmission) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SignedRAVSubmission.ProtoReflect.Descriptor instead.
 func (*SignedRAVSubmission) Descriptor() ([]byte, []int) {
-	return file_graph_substreams_data_service_provider_v1_gateway_proto_rawDescGZIP(), []int{6}
+	return file_graph_substreams_data_service_provider_v1_gateway_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *SignedRAVSubmission) GetSignedRav() *v1.SignedRAV {
@@ -600,7 +758,7 @@ type FundsAcknowledgment struct {
 
 func (x *FundsAcknowledgment) Reset() {
 	*x = FundsAcknowledgment{}
-	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[7]
+	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -612,7 +770,7 @@ func (x *FundsAcknowledgment) String() string {
 func (*FundsAcknowledgment) ProtoMessage() {}
 
 func (x *FundsAcknowledgment) ProtoReflect() protoreflect.Message {
-	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[7]
+	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -625,7 +783,7 @@ func (x *FundsAcknowledgment) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use FundsAcknowledgment.ProtoReflect.Descriptor instead.
 func (*FundsAcknowledgment) Descriptor() ([]byte, []int) {
-	return file_graph_substreams_data_service_provider_v1_gateway_proto_rawDescGZIP(), []int{7}
+	return file_graph_substreams_data_service_provider_v1_gateway_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *FundsAcknowledgment) GetWillDeposit() bool {
@@ -652,7 +810,7 @@ type UsageReport struct {
 
 func (x *UsageReport) Reset() {
 	*x = UsageReport{}
-	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[8]
+	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -664,7 +822,7 @@ func (x *UsageReport) String() string {
 func (*UsageReport) ProtoMessage() {}
 
 func (x *UsageReport) ProtoReflect() protoreflect.Message {
-	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[8]
+	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -677,7 +835,7 @@ func (x *UsageReport) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UsageReport.ProtoReflect.Descriptor instead.
 func (*UsageReport) Descriptor() ([]byte, []int) {
-	return file_graph_substreams_data_service_provider_v1_gateway_proto_rawDescGZIP(), []int{8}
+	return file_graph_substreams_data_service_provider_v1_gateway_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *UsageReport) GetUsage() *v1.Usage {
@@ -701,7 +859,7 @@ type RAVRequest struct {
 
 func (x *RAVRequest) Reset() {
 	*x = RAVRequest{}
-	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[9]
+	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -713,7 +871,7 @@ func (x *RAVRequest) String() string {
 func (*RAVRequest) ProtoMessage() {}
 
 func (x *RAVRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[9]
+	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -726,7 +884,7 @@ func (x *RAVRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RAVRequest.ProtoReflect.Descriptor instead.
 func (*RAVRequest) Descriptor() ([]byte, []int) {
-	return file_graph_substreams_data_service_provider_v1_gateway_proto_rawDescGZIP(), []int{9}
+	return file_graph_substreams_data_service_provider_v1_gateway_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *RAVRequest) GetCurrentRav() *v1.SignedRAV {
@@ -766,7 +924,7 @@ type NeedMoreFunds struct {
 
 func (x *NeedMoreFunds) Reset() {
 	*x = NeedMoreFunds{}
-	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[10]
+	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -778,7 +936,7 @@ func (x *NeedMoreFunds) String() string {
 func (*NeedMoreFunds) ProtoMessage() {}
 
 func (x *NeedMoreFunds) ProtoReflect() protoreflect.Message {
-	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[10]
+	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -791,7 +949,7 @@ func (x *NeedMoreFunds) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use NeedMoreFunds.ProtoReflect.Descriptor instead.
 func (*NeedMoreFunds) Descriptor() ([]byte, []int) {
-	return file_graph_substreams_data_service_provider_v1_gateway_proto_rawDescGZIP(), []int{10}
+	return file_graph_substreams_data_service_provider_v1_gateway_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *NeedMoreFunds) GetOutstandingRavs() []*v1.SignedRAV {
@@ -826,14 +984,16 @@ type SessionControl struct {
 	state  protoimpl.MessageState `protogen:"open.v1"`
 	Action SessionControl_Action  `protobuf:"varint,1,opt,name=action,proto3,enum=graph.substreams.data_service.provider.v1.SessionControl_Action" json:"action,omitempty"`
 	// Reason for the action
-	Reason        string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	Reason string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	// Machine code of the reason, for ACTION_STOP
+	Code          v1.RejectionCode `protobuf:"varint,3,opt,name=code,proto3,enum=graph.substreams.data_service.common.v1.RejectionCode" json:"code,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *SessionControl) Reset() {
 	*x = SessionControl{}
-	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[11]
+	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -845,7 +1005,7 @@ func (x *SessionControl) String() string {
 func (*SessionControl) ProtoMessage() {}
 
 func (x *SessionControl) ProtoReflect() protoreflect.Message {
-	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[11]
+	mi := &file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -858,7 +1018,7 @@ func (x *SessionControl) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SessionControl.ProtoReflect.Descriptor instead.
 func (*SessionControl) Descriptor() ([]byte, []int) {
-	return file_graph_substreams_data_service_provider_v1_gateway_proto_rawDescGZIP(), []int{11}
+	return file_graph_substreams_data_service_provider_v1_gateway_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *SessionControl) GetAction() SessionControl_Action {
@@ -875,31 +1035,49 @@ func (x *SessionControl) GetReason() string {
 	return ""
 }
 
+func (x *SessionControl) GetCode() v1.RejectionCode {
+	if x != nil {
+		return x.Code
+	}
+	return v1.RejectionCode(0)
+}
+
 var File_graph_substreams_data_service_provider_v1_gateway_proto protoreflect.FileDescriptor
 
 const file_graph_substreams_data_service_provider_v1_gateway_proto_rawDesc = "" +
 	"\n" +
-	"7graph/substreams/data_service/provider/v1/gateway.proto\x12)graph.substreams.data_service.provider.v1\x1a3graph/substreams/data_service/common/v1/types.proto\"\xc9\x01\n" +
+	"7graph/substreams/data_service/provider/v1/gateway.proto\x12)graph.substreams.data_service.provider.v1\x1a3graph/substreams/data_service/common/v1/types.proto\"\xa2\x02\n" +
 	"\x13StartSessionRequest\x12]\n" +
 	"\x0eescrow_account\x18\x01 \x01(\v26.graph.substreams.data_service.common.v1.EscrowAccountR\rescrowAccount\x12S\n" +
 	"\vinitial_rav\x18\x02 \x01(\v22.graph.substreams.data_service.common.v1.SignedRAVR\n" +
-	"initialRav\"\xc9\x01\n" +
+	"initialRav\x12W\n" +
+	"\fpayment_mode\x18\x03 \x01(\x0e24.graph.substreams.data_service.common.v1.PaymentModeR\vpaymentMode\"\xa8\x02\n" +
 	"\x14StartSessionResponse\x12\x1d\n" +
 	"\n" +
 	"session_id\x18\x01 \x01(\tR\tsessionId\x12K\n" +
 	"\ause_rav\x18\x02 \x01(\v22.graph.substreams.data_service.common.v1.SignedRAVR\x06useRav\x12\x1a\n" +
 	"\baccepted\x18\x03 \x01(\bR\baccepted\x12)\n" +
-	"\x10rejection_reason\x18\x04 \x01(\tR\x0frejectionReason\"\xca\x01\n" +
+	"\x10rejection_reason\x18\x04 \x01(\tR\x0frejectionReason\x12]\n" +
+	"\x0erejection_code\x18\x05 \x01(\x0e26.graph.substreams.data_service.common.v1.RejectionCodeR\rrejectionCode\"\x9e\x02\n" +
 	"\x10SubmitRAVRequest\x12\x1d\n" +
 	"\n" +
 	"session_id\x18\x01 \x01(\tR\tsessionId\x12Q\n" +
 	"\n" +
 	"signed_rav\x18\x02 \x01(\v22.graph.substreams.data_service.common.v1.SignedRAVR\tsignedRav\x12D\n" +
-	"\x05usage\x18\x03 \x01(\v2..graph.substreams.data_service.common.v1.UsageR\x05usage\"\x83\x01\n" +
+	"\x05usage\x18\x03 \x01(\v2..graph.substreams.data_service.common.v1.UsageR\x05usage\x12R\n" +
+	"\breceipts\x18\x04 \x03(\v26.graph.substreams.data_service.common.v1.SignedReceiptR\breceipts\"\xbd\x02\n" +
 	"\x11SubmitRAVResponse\x12\x1a\n" +
 	"\baccepted\x18\x01 \x01(\bR\baccepted\x12)\n" +
 	"\x10rejection_reason\x18\x02 \x01(\tR\x0frejectionReason\x12'\n" +
-	"\x0fshould_continue\x18\x03 \x01(\bR\x0eshouldContinue\"\xc7\x02\n" +
+	"\x0fshould_continue\x18\x03 \x01(\bR\x0eshouldContinue\x12Y\n" +
+	"\x0eaggregated_rav\x18\x04 \x01(\v22.graph.substreams.data_service.common.v1.SignedRAVR\raggregatedRav\x12]\n" +
+	"\x0erejection_code\x18\x05 \x01(\x0e26.graph.substreams.data_service.common.v1.RejectionCodeR\rrejectionCode\"\x91\x01\n" +
+	"\x14ProveIdentityRequest\x12\x1c\n" +
+	"\tchallenge\x18\x01 \x01(\fR\tchallenge\x12[\n" +
+	"\x10service_provider\x18\x02 \x01(\v20.graph.substreams.data_service.common.v1.AddressR\x0fserviceProvider\"\x92\x01\n" +
+	"\x15ProveIdentityResponse\x12[\n" +
+	"\x10service_provider\x18\x01 \x01(\v20.graph.substreams.data_service.common.v1.AddressR\x0fserviceProvider\x12\x1c\n" +
+	"\tsignature\x18\x02 \x01(\fR\tsignature\"\xc7\x02\n" +
 	"\x15PaymentSessionRequest\x12g\n" +
 	"\x0erav_submission\x18\x01 \x01(\v2>.graph.substreams.data_service.provider.v1.SignedRAVSubmissionH\x00R\rravSubmission\x12]\n" +
 	"\tfunds_ack\x18\x02 \x01(\v2>.graph.substreams.data_service.provider.v1.FundsAcknowledgmentH\x00R\bfundsAck\x12[\n" +
@@ -930,19 +1108,21 @@ const file_graph_substreams_data_service_provider_v1_gateway_proto_rawDesc = ""
 	"\x10outstanding_ravs\x18\x01 \x03(\v22.graph.substreams.data_service.common.v1.SignedRAVR\x0foutstandingRavs\x12\\\n" +
 	"\x11total_outstanding\x18\x02 \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\x10totalOutstanding\x12V\n" +
 	"\x0eescrow_balance\x18\x03 \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\rescrowBalance\x12V\n" +
-	"\x0eminimum_needed\x18\x04 \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\rminimumNeeded\"\xdc\x01\n" +
+	"\x0eminimum_needed\x18\x04 \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\rminimumNeeded\"\xa8\x02\n" +
 	"\x0eSessionControl\x12X\n" +
 	"\x06action\x18\x01 \x01(\x0e2@.graph.substreams.data_service.provider.v1.SessionControl.ActionR\x06action\x12\x16\n" +
-	"\x06reason\x18\x02 \x01(\tR\x06reason\"X\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\x12J\n" +
+	"\x04code\x18\x03 \x01(\x0e26.graph.substreams.data_service.common.v1.RejectionCodeR\x04code\"X\n" +
 	"\x06Action\x12\x16\n" +
 	"\x12ACTION_UNSPECIFIED\x10\x00\x12\x13\n" +
 	"\x0fACTION_CONTINUE\x10\x01\x12\x0f\n" +
 	"\vACTION_STOP\x10\x02\x12\x10\n" +
-	"\fACTION_PAUSE\x10\x032\xce\x03\n" +
+	"\fACTION_PAUSE\x10\x032\xe3\x04\n" +
 	"\x15PaymentGatewayService\x12\x8f\x01\n" +
 	"\fStartSession\x12>.graph.substreams.data_service.provider.v1.StartSessionRequest\x1a?.graph.substreams.data_service.provider.v1.StartSessionResponse\x12\x86\x01\n" +
 	"\tSubmitRAV\x12;.graph.substreams.data_service.provider.v1.SubmitRAVRequest\x1a<.graph.substreams.data_service.provider.v1.SubmitRAVResponse\x12\x99\x01\n" +
-	"\x0ePaymentSession\x12@.graph.substreams.data_service.provider.v1.PaymentSessionRequest\x1aA.graph.substreams.data_service.provider.v1.PaymentSessionResponse(\x010\x01B\xec\x02\n" +
+	"\x0ePaymentSession\x12@.graph.substreams.data_service.provider.v1.PaymentSessionRequest\x1aA.graph.substreams.data_service.provider.v1.PaymentSessionResponse(\x010\x01\x12\x92\x01\n" +
+	"\rProveIdentity\x12?.graph.substreams.data_service.provider.v1.ProveIdentityRequest\x1a@.graph.substreams.data_service.provider.v1.ProveIdentityResponseB\xec\x02\n" +
 	"-com.graph.substreams.data_service.provider.v1B\fGatewayProtoP\x01Zhgithub.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1;providerv1\xa2\x02\x04GSDP\xaa\x02(Graph.Substreams.DataService.Provider.V1\xca\x02(Graph\\Substreams\\DataService\\Provider\\V1\xe2\x024Graph\\Substreams\\DataService\\Provider\\V1\\GPBMetadata\xea\x02,Graph::Substreams::DataService::Provider::V1b\x06proto3"
 
 var (
@@ -958,60 +1138,76 @@ func file_graph_substreams_data_service_provider_v1_gateway_proto_rawDescGZIP()
 }
 
 var file_graph_substreams_data_service_provider_v1_gateway_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
 var file_graph_substreams_data_service_provider_v1_gateway_proto_goTypes = []any{
 	(SessionControl_Action)(0),     // 0: graph.substreams.data_service.provider.v1.SessionControl.Action
 	(*StartSessionRequest)(nil),    // 1: graph.substreams.data_service.provider.v1.StartSessionRequest
 	(*StartSessionResponse)(nil),   // 2: graph.substreams.data_service.provider.v1.StartSessionResponse
 	(*SubmitRAVRequest)(nil),       // 3: graph.substreams.data_service.provider.v1.SubmitRAVRequest
 	(*SubmitRAVResponse)(nil),      // 4: graph.substreams.data_service.provider.v1.SubmitRAVResponse
-	(*PaymentSessionRequest)(nil),  // 5: graph.substreams.data_service.provider.v1.PaymentSessionRequest
-	(*PaymentSessionResponse)(nil), // 6: graph.substreams.data_service.provider.v1.PaymentSessionResponse
-	(*SignedRAVSubmission)(nil),    // 7: graph.substreams.data_service.provider.v1.SignedRAVSubmission
-	(*FundsAcknowledgment)(nil),    // 8: graph.substreams.data_service.provider.v1.FundsAcknowledgment
-	(*UsageReport)(nil),            // 9: graph.substreams.data_service.provider.v1.UsageReport
-	(*RAVRequest)(nil),             // 10: graph.substreams.data_service.provider.v1.RAVRequest
-	(*NeedMoreFunds)(nil),          // 11: graph.substreams.data_service.provider.v1.NeedMoreFunds
-	(*SessionControl)(nil),         // 12: graph.substreams.data_service.provider.v1.SessionControl
-	(*v1.EscrowAccount)(nil),       // 13: graph.substreams.data_service.common.v1.EscrowAccount
-	(*v1.SignedRAV)(nil),           // 14: graph.substreams.data_service.common.v1.SignedRAV
-	(*v1.Usage)(nil),               // 15: graph.substreams.data_service.common.v1.Usage
-	(*v1.BigInt)(nil),              // 16: graph.substreams.data_service.common.v1.BigInt
+	(*ProveIdentityRequest)(nil),   // 5: graph.substreams.data_service.provider.v1.ProveIdentityRequest
+	(*ProveIdentityResponse)(nil),  // 6: graph.substreams.data_service.provider.v1.ProveIdentityResponse
+	(*PaymentSessionRequest)(nil),  // 7: graph.substreams.data_service.provider.v1.PaymentSessionRequest
+	(*PaymentSessionResponse)(nil), // 8: graph.substreams.data_service.provider.v1.PaymentSessionResponse
+	(*SignedRAVSubmission)(nil),    // 9: graph.substreams.data_service.provider.v1.SignedRAVSubmission
+	(*FundsAcknowledgment)(nil),    // 10: graph.substreams.data_service.provider.v1.FundsAcknowledgment
+	(*UsageReport)(nil),            // 11: graph.substreams.data_service.provider.v1.UsageReport
+	(*RAVRequest)(nil),             // 12: graph.substreams.data_service.provider.v1.RAVRequest
+	(*NeedMoreFunds)(nil),          // 13: graph.substreams.data_service.provider.v1.NeedMoreFunds
+	(*SessionControl)(nil),         // 14: graph.substreams.data_service.provider.v1.SessionControl
+	(*v1.EscrowAccount)(nil),       // 15: graph.substreams.data_service.common.v1.EscrowAccount
+	(*v1.SignedRAV)(nil),           // 16: graph.substreams.data_service.common.v1.SignedRAV
+	(v1.PaymentMode)(0),            // 17: graph.substreams.data_service.common.v1.PaymentMode
+	(v1.RejectionCode)(0),          // 18: graph.substreams.data_service.common.v1.RejectionCode
+	(*v1.Usage)(nil),               // 19: graph.substreams.data_service.common.v1.Usage
+	(*v1.SignedReceipt)(nil),       // 20: graph.substreams.data_service.common.v1.SignedReceipt
+	(*v1.Address)(nil),             // 21: graph.substreams.data_service.common.v1.Address
+	(*v1.BigInt)(nil),              // 22: graph.substreams.data_service.common.v1.BigInt
 }
 var file_graph_substreams_data_service_provider_v1_gateway_proto_depIdxs = []int32{
-	13, // 0: graph.substreams.data_service.provider.v1.StartSessionRequest.escrow_account:type_name -> graph.substreams.data_service.common.v1.EscrowAccount
-	14, // 1: graph.substreams.data_service.provider.v1.StartSessionRequest.initial_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
-	14, // 2: graph.substreams.data_service.provider.v1.StartSessionResponse.use_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
-	14, // 3: graph.substreams.data_service.provider.v1.SubmitRAVRequest.signed_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
-	15, // 4: graph.substreams.data_service.provider.v1.SubmitRAVRequest.usage:type_name -> graph.substreams.data_service.common.v1.Usage
-	7,  // 5: graph.substreams.data_service.provider.v1.PaymentSessionRequest.rav_submission:type_name -> graph.substreams.data_service.provider.v1.SignedRAVSubmission
-	8,  // 6: graph.substreams.data_service.provider.v1.PaymentSessionRequest.funds_ack:type_name -> graph.substreams.data_service.provider.v1.FundsAcknowledgment
-	9,  // 7: graph.substreams.data_service.provider.v1.PaymentSessionRequest.usage_report:type_name -> graph.substreams.data_service.provider.v1.UsageReport
-	10, // 8: graph.substreams.data_service.provider.v1.PaymentSessionResponse.rav_request:type_name -> graph.substreams.data_service.provider.v1.RAVRequest
-	11, // 9: graph.substreams.data_service.provider.v1.PaymentSessionResponse.need_more_funds:type_name -> graph.substreams.data_service.provider.v1.NeedMoreFunds
-	12, // 10: graph.substreams.data_service.provider.v1.PaymentSessionResponse.session_control:type_name -> graph.substreams.data_service.provider.v1.SessionControl
-	14, // 11: graph.substreams.data_service.provider.v1.SignedRAVSubmission.signed_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
-	15, // 12: graph.substreams.data_service.provider.v1.SignedRAVSubmission.usage:type_name -> graph.substreams.data_service.common.v1.Usage
-	16, // 13: graph.substreams.data_service.provider.v1.FundsAcknowledgment.deposit_amount:type_name -> graph.substreams.data_service.common.v1.BigInt
-	15, // 14: graph.substreams.data_service.provider.v1.UsageReport.usage:type_name -> graph.substreams.data_service.common.v1.Usage
-	14, // 15: graph.substreams.data_service.provider.v1.RAVRequest.current_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
-	15, // 16: graph.substreams.data_service.provider.v1.RAVRequest.usage:type_name -> graph.substreams.data_service.common.v1.Usage
-	14, // 17: graph.substreams.data_service.provider.v1.NeedMoreFunds.outstanding_ravs:type_name -> graph.substreams.data_service.common.v1.SignedRAV
-	16, // 18: graph.substreams.data_service.provider.v1.NeedMoreFunds.total_outstanding:type_name -> graph.substreams.data_service.common.v1.BigInt
-	16, // 19: graph.substreams.data_service.provider.v1.NeedMoreFunds.escrow_balance:type_name -> graph.substreams.data_service.common.v1.BigInt
-	16, // 20: graph.substreams.data_service.provider.v1.NeedMoreFunds.minimum_needed:type_name -> graph.substreams.data_service.common.v1.BigInt
-	0,  // 21: graph.substreams.data_service.provider.v1.SessionControl.action:type_name -> graph.substreams.data_service.provider.v1.SessionControl.Action
-	1,  // 22: graph.substreams.data_service.provider.v1.PaymentGatewayService.StartSession:input_type -> graph.substreams.data_service.provider.v1.StartSessionRequest
-	3,  // 23: graph.substreams.data_service.provider.v1.PaymentGatewayService.SubmitRAV:input_type -> graph.substreams.data_service.provider.v1.SubmitRAVRequest
-	5,  // 24: graph.substreams.data_service.provider.v1.PaymentGatewayService.PaymentSession:input_type -> graph.substreams.data_service.provider.v1.PaymentSessionRequest
-	2,  // 25: graph.substreams.data_service.provider.v1.PaymentGatewayService.StartSession:output_type -> graph.substreams.data_service.provider.v1.StartSessionResponse
-	4,  // 26: graph.substreams.data_service.provider.v1.PaymentGatewayService.SubmitRAV:output_type -> graph.substreams.data_service.provider.v1.SubmitRAVResponse
-	6,  // 27: graph.substreams.data_service.provider.v1.PaymentGatewayService.PaymentSession:output_type -> graph.substreams.data_service.provider.v1.PaymentSessionResponse
-	25, // [25:28] is the sub-list for method output_type
-	22, // [22:25] is the sub-list for method input_type
-	22, // [22:22] is the sub-list for extension type_name
-	22, // [22:22] is the sub-list for extension extendee
-	0,  // [0:22] is the sub-list for field type_name
+	15, // 0: graph.substreams.data_service.provider.v1.StartSessionRequest.escrow_account:type_name -> graph.substreams.data_service.common.v1.EscrowAccount
+	16, // 1: graph.substreams.data_service.provider.v1.StartSessionRequest.initial_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
+	17, // 2: graph.substreams.data_service.provider.v1.StartSessionRequest.payment_mode:type_name -> graph.substreams.data_service.common.v1.PaymentMode
+	16, // 3: graph.substreams.data_service.provider.v1.StartSessionResponse.use_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
+	18, // 4: graph.substreams.data_service.provider.v1.StartSessionResponse.rejection_code:type_name -> graph.substreams.data_service.common.v1.RejectionCode
+	16, // 5: graph.substreams.data_service.provider.v1.SubmitRAVRequest.signed_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
+	19, // 6: graph.substreams.data_service.provider.v1.SubmitRAVRequest.usage:type_name -> graph.substreams.data_service.common.v1.Usage
+	20, // 7: graph.substreams.data_service.provider.v1.SubmitRAVRequest.receipts:type_name -> graph.substreams.data_service.common.v1.SignedReceipt
+	16, // 8: graph.substreams.data_service.provider.v1.SubmitRAVResponse.aggregated_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
+	18, // 9: graph.substreams.data_service.provider.v1.SubmitRAVResponse.rejection_code:type_name -> graph.substreams.data_service.common.v1.RejectionCode
+	21, // 10: graph.substreams.data_service.provider.v1.ProveIdentityRequest.service_provider:type_name -> graph.substreams.data_service.common.v1.Address
+	21, // 11: graph.substreams.data_service.provider.v1.ProveIdentityResponse.service_provider:type_name -> graph.substreams.data_service.common.v1.Address
+	9,  // 12: graph.substreams.data_service.provider.v1.PaymentSessionRequest.rav_submission:type_name -> graph.substreams.data_service.provider.v1.SignedRAVSubmission
+	10, // 13: graph.substreams.data_service.provider.v1.PaymentSessionRequest.funds_ack:type_name -> graph.substreams.data_service.provider.v1.FundsAcknowledgment
+	11, // 14: graph.substreams.data_service.provider.v1.PaymentSessionRequest.usage_report:type_name -> graph.substreams.data_service.provider.v1.UsageReport
+	12, // 15: graph.substreams.data_service.provider.v1.PaymentSessionResponse.rav_request:type_name -> graph.substreams.data_service.provider.v1.RAVRequest
+	13, // 16: graph.substreams.data_service.provider.v1.PaymentSessionResponse.need_more_funds:type_name -> graph.substreams.data_service.provider.v1.NeedMoreFunds
+	14, // 17: graph.substreams.data_service.provider.v1.PaymentSessionResponse.session_control:type_name -> graph.substreams.data_service.provider.v1.SessionControl
+	16, // 18: graph.substreams.data_service.provider.v1.SignedRAVSubmission.signed_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
+	19, // 19: graph.substreams.data_service.provider.v1.SignedRAVSubmission.usage:type_name -> graph.substreams.data_service.common.v1.Usage
+	22, // 20: graph.substreams.data_service.provider.v1.FundsAcknowledgment.deposit_amount:type_name -> graph.substreams.data_service.common.v1.BigInt
+	19, // 21: graph.substreams.data_service.provider.v1.UsageReport.usage:type_name -> graph.substreams.data_service.common.v1.Usage
+	16, // 22: graph.substreams.data_service.provider.v1.RAVRequest.current_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
+	19, // 23: graph.substreams.data_service.provider.v1.RAVRequest.usage:type_name -> graph.substreams.data_service.common.v1.Usage
+	16, // 24: graph.substreams.data_service.provider.v1.NeedMoreFunds.outstanding_ravs:type_name -> graph.substreams.data_service.common.v1.SignedRAV
+	22, // 25: graph.substreams.data_service.provider.v1.NeedMoreFunds.total_outstanding:type_name -> graph.substreams.data_service.common.v1.BigInt
+	22, // 26: graph.substreams.data_service.provider.v1.NeedMoreFunds.escrow_balance:type_name -> graph.substreams.data_service.common.v1.BigInt
+	22, // 27: graph.substreams.data_service.provider.v1.NeedMoreFunds.minimum_needed:type_name -> graph.substreams.data_service.common.v1.BigInt
+	0,  // 28: graph.substreams.data_service.provider.v1.SessionControl.action:type_name -> graph.substreams.data_service.provider.v1.SessionControl.Action
+	18, // 29: graph.substreams.data_service.provider.v1.SessionControl.code:type_name -> graph.substreams.data_service.common.v1.RejectionCode
+	1,  // 30: graph.substreams.data_service.provider.v1.PaymentGatewayService.StartSession:input_type -> graph.substreams.data_service.provider.v1.StartSessionRequest
+	3,  // 31: graph.substreams.data_service.provider.v1.PaymentGatewayService.SubmitRAV:input_type -> graph.substreams.data_service.provider.v1.SubmitRAVRequest
+	7,  // 32: graph.substreams.data_service.provider.v1.PaymentGatewayService.PaymentSession:input_type -> graph.substreams.data_service.provider.v1.PaymentSessionRequest
+	5,  // 33: graph.substreams.data_service.provider.v1.PaymentGatewayService.ProveIdentity:input_type -> graph.substreams.data_service.provider.v1.ProveIdentityRequest
+	2,  // 34: graph.substreams.data_service.provider.v1.PaymentGatewayService.StartSession:output_type -> graph.substreams.data_service.provider.v1.StartSessionResponse
+	4,  // 35: graph.substreams.data_service.provider.v1.PaymentGatewayService.SubmitRAV:output_type -> graph.substreams.data_service.provider.v1.SubmitRAVResponse
+	8,  // 36: graph.substreams.data_service.provider.v1.PaymentGatewayService.PaymentSession:output_type -> graph.substreams.data_service.provider.v1.PaymentSessionResponse
+	6,  // 37: graph.substreams.data_service.provider.v1.PaymentGatewayService.ProveIdentity:output_type -> graph.substreams.data_service.provider.v1.ProveIdentityResponse
+	34, // [34:38] is the sub-list for method output_type
+	30, // [30:34] is the sub-list for method input_type
+	30, // [30:30] is the sub-list for extension type_name
+	30, // [30:30] is the sub-list for extension extendee
+	0,  // [0:30] is the sub-list for field type_name
 }
 
 func init() { file_graph_substreams_data_service_provider_v1_gateway_proto_init() }
@@ -1019,12 +1215,12 @@ func file_graph_substreams_data_service_provider_v1_gateway_proto_init() {
 	if File_graph_substreams_data_service_provider_v1_gateway_proto != nil {
 		return
 	}
-	file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[4].OneofWrappers = []any{
+	file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[6].OneofWrappers = []any{
 		(*PaymentSessionRequest_RavSubmission)(nil),
 		(*PaymentSessionRequest_FundsAck)(nil),
 		(*PaymentSessionRequest_UsageReport)(nil),
 	}
-	file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[5].OneofWrappers = []any{
+	file_graph_substreams_data_service_provider_v1_gateway_proto_msgTypes[7].OneofWrappers = []any{
 		(*PaymentSessionResponse_RavRequest)(nil),
 		(*PaymentSessionResponse_NeedMoreFunds)(nil),
 		(*PaymentSessionResponse_SessionControl)(nil),
@@ -1035,7 +1231,7 @@ func file_graph_substreams_data_service_provider_v1_gateway_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_graph_substreams_data_service_provider_v1_gateway_proto_rawDesc), len(file_graph_substreams_data_service_provider_v1_gateway_proto_rawDesc)),
 			NumEnums:      1,
-			NumMessages:   12,
+			NumMessages:   14,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
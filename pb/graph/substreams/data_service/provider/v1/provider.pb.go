@@ -99,8 +99,10 @@ type ValidatePaymentResponse struct {
 	EscrowAccount *v1.EscrowAccount `protobuf:"bytes,5,opt,name=escrow_account,json=escrowAccount,proto3" json:"escrow_account,omitempty"`
 	// Available escrow balance in GRT (wei)
 	AvailableBalance *v1.BigInt `protobuf:"bytes,6,opt,name=available_balance,json=availableBalance,proto3" json:"available_balance,omitempty"`
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+	// If not valid, the machine code of the rejection
+	RejectionCode v1.RejectionCode `protobuf:"varint,7,opt,name=rejection_code,json=rejectionCode,proto3,enum=graph.substreams.data_service.common.v1.RejectionCode" json:"rejection_code,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ValidatePaymentResponse) Reset() {
@@ -175,12 +177,28 @@ func (x *ValidatePaymentResponse) GetAvailableBalance() *v1.BigInt {
 	return nil
 }
 
+func (x *ValidatePaymentResponse) GetRejectionCode() v1.RejectionCode {
+	if x != nil {
+		return x.RejectionCode
+	}
+	return v1.RejectionCode(0)
+}
+
 type ReportUsageRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// The session ID
 	SessionId string `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
 	// The usage to report
-	Usage         *v1.Usage `protobuf:"bytes,2,opt,name=usage,proto3" json:"usage,omitempty"`
+	Usage *v1.Usage `protobuf:"bytes,2,opt,name=usage,proto3" json:"usage,omitempty"`
+	// Identifies the provider instance (e.g. a tier2 pod) reporting the usage when
+	// several instances serve the same session through one sidecar. Usage is
+	// aggregated into the session and also tracked per instance. Optional.
+	InstanceId string `protobuf:"bytes,3,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"`
+	// Usage entries metered since the last report, each stamped with its
+	// timestamp_ns. The sidecar sums them, with usage when also set, into a
+	// single report so fine-grained reporters send one request per batch
+	// instead of one per entry.
+	UsageBatch    []*v1.Usage `protobuf:"bytes,4,rep,name=usage_batch,json=usageBatch,proto3" json:"usage_batch,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -229,6 +247,20 @@ func (x *ReportUsageRequest) GetUsage() *v1.Usage {
 	return nil
 }
 
+func (x *ReportUsageRequest) GetInstanceId() string {
+	if x != nil {
+		return x.InstanceId
+	}
+	return ""
+}
+
+func (x *ReportUsageRequest) GetUsageBatch() []*v1.Usage {
+	if x != nil {
+		return x.UsageBatch
+	}
+	return nil
+}
+
 type ReportUsageResponse struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// Whether the session should continue
@@ -236,7 +268,13 @@ type ReportUsageResponse struct {
 	// If should_continue is false, the reason for stopping
 	StopReason string `protobuf:"bytes,2,opt,name=stop_reason,json=stopReason,proto3" json:"stop_reason,omitempty"`
 	// Whether a new RAV has been received
-	RavUpdated    bool `protobuf:"varint,3,opt,name=rav_updated,json=ravUpdated,proto3" json:"rav_updated,omitempty"`
+	RavUpdated bool `protobuf:"varint,3,opt,name=rav_updated,json=ravUpdated,proto3" json:"rav_updated,omitempty"`
+	// If should_continue is false, the machine code of the stop reason
+	StopCode v1.RejectionCode `protobuf:"varint,4,opt,name=stop_code,json=stopCode,proto3,enum=graph.substreams.data_service.common.v1.RejectionCode" json:"stop_code,omitempty"`
+	// Whether the provider should obtain a newly signed RAV from the consumer
+	// for the usage its current RAV does not cover, set once the session
+	// reaches one of the sidecar's RAV request thresholds
+	RavRequested  bool `protobuf:"varint,5,opt,name=rav_requested,json=ravRequested,proto3" json:"rav_requested,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -292,6 +330,75 @@ func (x *ReportUsageResponse) GetRavUpdated() bool {
 	return false
 }
 
+func (x *ReportUsageResponse) GetStopCode() v1.RejectionCode {
+	if x != nil {
+		return x.StopCode
+	}
+	return v1.RejectionCode(0)
+}
+
+func (x *ReportUsageResponse) GetRavRequested() bool {
+	if x != nil {
+		return x.RavRequested
+	}
+	return false
+}
+
+type StreamUsageResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The session the outcome is about
+	SessionId string `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	// The outcome of the last usage reported for the session, rav_updated is
+	// only set when the session's RAV changed since the last outcome sent
+	Outcome       *ReportUsageResponse `protobuf:"bytes,2,opt,name=outcome,proto3" json:"outcome,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamUsageResponse) Reset() {
+	*x = StreamUsageResponse{}
+	mi := &file_graph_substreams_data_service_provider_v1_provider_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamUsageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamUsageResponse) ProtoMessage() {}
+
+func (x *StreamUsageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_graph_substreams_data_service_provider_v1_provider_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamUsageResponse.ProtoReflect.Descriptor instead.
+func (*StreamUsageResponse) Descriptor() ([]byte, []int) {
+	return file_graph_substreams_data_service_provider_v1_provider_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StreamUsageResponse) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *StreamUsageResponse) GetOutcome() *ReportUsageResponse {
+	if x != nil {
+		return x.Outcome
+	}
+	return nil
+}
+
 type EndSessionRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// The session ID
@@ -306,7 +413,7 @@ type EndSessionRequest struct {
 
 func (x *EndSessionRequest) Reset() {
 	*x = EndSessionRequest{}
-	mi := &file_graph_substreams_data_service_provider_v1_provider_proto_msgTypes[4]
+	mi := &file_graph_substreams_data_service_provider_v1_provider_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -318,7 +425,7 @@ func (x *EndSessionRequest) String() string {
 func (*EndSessionRequest) ProtoMessage() {}
 
 func (x *EndSessionRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_graph_substreams_data_service_provider_v1_provider_proto_msgTypes[4]
+	mi := &file_graph_substreams_data_service_provider_v1_provider_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -331,7 +438,7 @@ func (x *EndSessionRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EndSessionRequest.ProtoReflect.Descriptor instead.
 func (*EndSessionRequest) Descriptor() ([]byte, []int) {
-	return file_graph_substreams_data_service_provider_v1_provider_proto_rawDescGZIP(), []int{4}
+	return file_graph_substreams_data_service_provider_v1_provider_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *EndSessionRequest) GetSessionId() string {
@@ -359,17 +466,19 @@ type EndSessionResponse struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// The final RAV for this session
 	FinalRav *v1.SignedRAV `protobuf:"bytes,1,opt,name=final_rav,json=finalRav,proto3" json:"final_rav,omitempty"`
-	// Total usage for the session
-	TotalUsage *v1.Usage `protobuf:"bytes,2,opt,name=total_usage,json=totalUsage,proto3" json:"total_usage,omitempty"`
-	// Total value collected in GRT (wei)
-	TotalValue    *v1.BigInt `protobuf:"bytes,3,opt,name=total_value,json=totalValue,proto3" json:"total_value,omitempty"`
+	// Whether the provider should obtain a final signed RAV from the consumer
+	// for the usage the final RAV does not cover, accepted by SubmitRAV although
+	// the session ended
+	RavRequested bool `protobuf:"varint,4,opt,name=rav_requested,json=ravRequested,proto3" json:"rav_requested,omitempty"`
+	// Settlement summary of the session, as of the final RAV above
+	Settlement    *SessionSettlement `protobuf:"bytes,5,opt,name=settlement,proto3" json:"settlement,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *EndSessionResponse) Reset() {
 	*x = EndSessionResponse{}
-	mi := &file_graph_substreams_data_service_provider_v1_provider_proto_msgTypes[5]
+	mi := &file_graph_substreams_data_service_provider_v1_provider_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -381,7 +490,7 @@ func (x *EndSessionResponse) String() string {
 func (*EndSessionResponse) ProtoMessage() {}
 
 func (x *EndSessionResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_graph_substreams_data_service_provider_v1_provider_proto_msgTypes[5]
+	mi := &file_graph_substreams_data_service_provider_v1_provider_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -394,7 +503,7 @@ func (x *EndSessionResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EndSessionResponse.ProtoReflect.Descriptor instead.
 func (*EndSessionResponse) Descriptor() ([]byte, []int) {
-	return file_graph_substreams_data_service_provider_v1_provider_proto_rawDescGZIP(), []int{5}
+	return file_graph_substreams_data_service_provider_v1_provider_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *EndSessionResponse) GetFinalRav() *v1.SignedRAV {
@@ -404,16 +513,16 @@ func (x *EndSessionResponse) GetFinalRav() *v1.SignedRAV {
 	return nil
 }
 
-func (x *EndSessionResponse) GetTotalUsage() *v1.Usage {
+func (x *EndSessionResponse) GetRavRequested() bool {
 	if x != nil {
-		return x.TotalUsage
+		return x.RavRequested
 	}
-	return nil
+	return false
 }
 
-func (x *EndSessionResponse) GetTotalValue() *v1.BigInt {
+func (x *EndSessionResponse) GetSettlement() *SessionSettlement {
 	if x != nil {
-		return x.TotalValue
+		return x.Settlement
 	}
 	return nil
 }
@@ -428,7 +537,7 @@ type GetSessionStatusRequest struct {
 
 func (x *GetSessionStatusRequest) Reset() {
 	*x = GetSessionStatusRequest{}
-	mi := &file_graph_substreams_data_service_provider_v1_provider_proto_msgTypes[6]
+	mi := &file_graph_substreams_data_service_provider_v1_provider_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -440,7 +549,7 @@ func (x *GetSessionStatusRequest) String() string {
 func (*GetSessionStatusRequest) ProtoMessage() {}
 
 func (x *GetSessionStatusRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_graph_substreams_data_service_provider_v1_provider_proto_msgTypes[6]
+	mi := &file_graph_substreams_data_service_provider_v1_provider_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -453,7 +562,7 @@ func (x *GetSessionStatusRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetSessionStatusRequest.ProtoReflect.Descriptor instead.
 func (*GetSessionStatusRequest) Descriptor() ([]byte, []int) {
-	return file_graph_substreams_data_service_provider_v1_provider_proto_rawDescGZIP(), []int{6}
+	return file_graph_substreams_data_service_provider_v1_provider_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *GetSessionStatusRequest) GetSessionId() string {
@@ -471,13 +580,18 @@ type GetSessionStatusResponse struct {
 	Session *v1.SessionInfo `protobuf:"bytes,2,opt,name=session,proto3" json:"session,omitempty"`
 	// Current payment status
 	PaymentStatus *v1.PaymentStatus `protobuf:"bytes,3,opt,name=payment_status,json=paymentStatus,proto3" json:"payment_status,omitempty"`
+	// Usage breakdown per reporting instance, for usage reported with an instance_id
+	InstanceUsage []*InstanceUsage `protobuf:"bytes,4,rep,name=instance_usage,json=instanceUsage,proto3" json:"instance_usage,omitempty"`
+	// Whether the chain RPC is unreachable and the escrow balance in
+	// payment_status comes from the last successful query
+	Degraded      bool `protobuf:"varint,5,opt,name=degraded,proto3" json:"degraded,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *GetSessionStatusResponse) Reset() {
 	*x = GetSessionStatusResponse{}
-	mi := &file_graph_substreams_data_service_provider_v1_provider_proto_msgTypes[7]
+	mi := &file_graph_substreams_data_service_provider_v1_provider_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -489,7 +603,7 @@ func (x *GetSessionStatusResponse) String() string {
 func (*GetSessionStatusResponse) ProtoMessage() {}
 
 func (x *GetSessionStatusResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_graph_substreams_data_service_provider_v1_provider_proto_msgTypes[7]
+	mi := &file_graph_substreams_data_service_provider_v1_provider_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -502,7 +616,7 @@ func (x *GetSessionStatusResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetSessionStatusResponse.ProtoReflect.Descriptor instead.
 func (*GetSessionStatusResponse) Descriptor() ([]byte, []int) {
-	return file_graph_substreams_data_service_provider_v1_provider_proto_rawDescGZIP(), []int{7}
+	return file_graph_substreams_data_service_provider_v1_provider_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *GetSessionStatusResponse) GetActive() bool {
@@ -526,6 +640,203 @@ func (x *GetSessionStatusResponse) GetPaymentStatus() *v1.PaymentStatus {
 	return nil
 }
 
+func (x *GetSessionStatusResponse) GetInstanceUsage() []*InstanceUsage {
+	if x != nil {
+		return x.InstanceUsage
+	}
+	return nil
+}
+
+func (x *GetSessionStatusResponse) GetDegraded() bool {
+	if x != nil {
+		return x.Degraded
+	}
+	return false
+}
+
+// InstanceUsage is the usage a single provider instance reported for a session.
+type InstanceUsage struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The reporting instance ID
+	InstanceId string `protobuf:"bytes,1,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"`
+	// Usage accumulated from this instance's reports
+	Usage *v1.Usage `protobuf:"bytes,2,opt,name=usage,proto3" json:"usage,omitempty"`
+	// Number of usage reports received from this instance
+	Reports uint64 `protobuf:"varint,3,opt,name=reports,proto3" json:"reports,omitempty"`
+	// Timestamp of the last report received from this instance (Unix nanoseconds)
+	LastReportNs  uint64 `protobuf:"varint,4,opt,name=last_report_ns,json=lastReportNs,proto3" json:"last_report_ns,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InstanceUsage) Reset() {
+	*x = InstanceUsage{}
+	mi := &file_graph_substreams_data_service_provider_v1_provider_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InstanceUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InstanceUsage) ProtoMessage() {}
+
+func (x *InstanceUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_graph_substreams_data_service_provider_v1_provider_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InstanceUsage.ProtoReflect.Descriptor instead.
+func (*InstanceUsage) Descriptor() ([]byte, []int) {
+	return file_graph_substreams_data_service_provider_v1_provider_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *InstanceUsage) GetInstanceId() string {
+	if x != nil {
+		return x.InstanceId
+	}
+	return ""
+}
+
+func (x *InstanceUsage) GetUsage() *v1.Usage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+func (x *InstanceUsage) GetReports() uint64 {
+	if x != nil {
+		return x.Reports
+	}
+	return 0
+}
+
+func (x *InstanceUsage) GetLastReportNs() uint64 {
+	if x != nil {
+		return x.LastReportNs
+	}
+	return 0
+}
+
+// SessionSettlement summarizes what an ended session is paid. GRT values are
+// in wei.
+type SessionSettlement struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Total usage for the session
+	TotalUsage *v1.Usage `protobuf:"bytes,1,opt,name=total_usage,json=totalUsage,proto3" json:"total_usage,omitempty"`
+	// Value of the final RAV, signed by the consumer
+	SignedValue *v1.BigInt `protobuf:"bytes,2,opt,name=signed_value,json=signedValue,proto3" json:"signed_value,omitempty"`
+	// Value already collected on-chain for the collection of the final RAV
+	PreviouslyCollected *v1.BigInt `protobuf:"bytes,3,opt,name=previously_collected,json=previouslyCollected,proto3" json:"previously_collected,omitempty"`
+	// Value collecting the final RAV pays, signed_value minus
+	// previously_collected
+	PendingDelta *v1.BigInt `protobuf:"bytes,4,opt,name=pending_delta,json=pendingDelta,proto3" json:"pending_delta,omitempty"`
+	// Projected distribution of pending_delta once collected: the protocol
+	// payment cut, the data service cut, the delegation fee cut and the service
+	// provider payout left
+	ProtocolCut     *v1.BigInt `protobuf:"bytes,5,opt,name=protocol_cut,json=protocolCut,proto3" json:"protocol_cut,omitempty"`
+	DataServiceCut  *v1.BigInt `protobuf:"bytes,6,opt,name=data_service_cut,json=dataServiceCut,proto3" json:"data_service_cut,omitempty"`
+	DelegationCut   *v1.BigInt `protobuf:"bytes,7,opt,name=delegation_cut,json=delegationCut,proto3" json:"delegation_cut,omitempty"`
+	ProjectedPayout *v1.BigInt `protobuf:"bytes,8,opt,name=projected_payout,json=projectedPayout,proto3" json:"projected_payout,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *SessionSettlement) Reset() {
+	*x = SessionSettlement{}
+	mi := &file_graph_substreams_data_service_provider_v1_provider_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SessionSettlement) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionSettlement) ProtoMessage() {}
+
+func (x *SessionSettlement) ProtoReflect() protoreflect.Message {
+	mi := &file_graph_substreams_data_service_provider_v1_provider_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionSettlement.ProtoReflect.Descriptor instead.
+func (*SessionSettlement) Descriptor() ([]byte, []int) {
+	return file_graph_substreams_data_service_provider_v1_provider_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *SessionSettlement) GetTotalUsage() *v1.Usage {
+	if x != nil {
+		return x.TotalUsage
+	}
+	return nil
+}
+
+func (x *SessionSettlement) GetSignedValue() *v1.BigInt {
+	if x != nil {
+		return x.SignedValue
+	}
+	return nil
+}
+
+func (x *SessionSettlement) GetPreviouslyCollected() *v1.BigInt {
+	if x != nil {
+		return x.PreviouslyCollected
+	}
+	return nil
+}
+
+func (x *SessionSettlement) GetPendingDelta() *v1.BigInt {
+	if x != nil {
+		return x.PendingDelta
+	}
+	return nil
+}
+
+func (x *SessionSettlement) GetProtocolCut() *v1.BigInt {
+	if x != nil {
+		return x.ProtocolCut
+	}
+	return nil
+}
+
+func (x *SessionSettlement) GetDataServiceCut() *v1.BigInt {
+	if x != nil {
+		return x.DataServiceCut
+	}
+	return nil
+}
+
+func (x *SessionSettlement) GetDelegationCut() *v1.BigInt {
+	if x != nil {
+		return x.DelegationCut
+	}
+	return nil
+}
+
+func (x *SessionSettlement) GetProjectedPayout() *v1.BigInt {
+	if x != nil {
+		return x.ProjectedPayout
+	}
+	return nil
+}
+
 var File_graph_substreams_data_service_provider_v1_provider_proto protoreflect.FileDescriptor
 
 const file_graph_substreams_data_service_provider_v1_provider_proto_rawDesc = "" +
@@ -535,7 +846,7 @@ const file_graph_substreams_data_service_provider_v1_provider_proto_rawDesc = ""
 	"\vpayment_rav\x18\x01 \x01(\v22.graph.substreams.data_service.common.v1.SignedRAVR\n" +
 	"paymentRav\x12*\n" +
 	"\x11client_session_id\x18\x02 \x01(\tR\x0fclientSessionId\x12a\n" +
-	"\x0eservice_params\x18\x03 \x01(\v2:.graph.substreams.data_service.common.v1.ServiceParametersR\rserviceParams\"\x99\x03\n" +
+	"\x0eservice_params\x18\x03 \x01(\v2:.graph.substreams.data_service.common.v1.ServiceParametersR\rserviceParams\"\xf8\x03\n" +
 	"\x17ValidatePaymentResponse\x12\x14\n" +
 	"\x05valid\x18\x01 \x01(\bR\x05valid\x12)\n" +
 	"\x10rejection_reason\x18\x02 \x01(\tR\x0frejectionReason\x12\x1d\n" +
@@ -543,17 +854,28 @@ const file_graph_substreams_data_service_provider_v1_provider_proto_rawDesc = ""
 	"session_id\x18\x03 \x01(\tR\tsessionId\x12a\n" +
 	"\x0eservice_params\x18\x04 \x01(\v2:.graph.substreams.data_service.common.v1.ServiceParametersR\rserviceParams\x12]\n" +
 	"\x0eescrow_account\x18\x05 \x01(\v26.graph.substreams.data_service.common.v1.EscrowAccountR\rescrowAccount\x12\\\n" +
-	"\x11available_balance\x18\x06 \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\x10availableBalance\"y\n" +
+	"\x11available_balance\x18\x06 \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\x10availableBalance\x12]\n" +
+	"\x0erejection_code\x18\a \x01(\x0e26.graph.substreams.data_service.common.v1.RejectionCodeR\rrejectionCode\"\xeb\x01\n" +
 	"\x12ReportUsageRequest\x12\x1d\n" +
 	"\n" +
 	"session_id\x18\x01 \x01(\tR\tsessionId\x12D\n" +
-	"\x05usage\x18\x02 \x01(\v2..graph.substreams.data_service.common.v1.UsageR\x05usage\"\x80\x01\n" +
+	"\x05usage\x18\x02 \x01(\v2..graph.substreams.data_service.common.v1.UsageR\x05usage\x12\x1f\n" +
+	"\vinstance_id\x18\x03 \x01(\tR\n" +
+	"instanceId\x12O\n" +
+	"\vusage_batch\x18\x04 \x03(\v2..graph.substreams.data_service.common.v1.UsageR\n" +
+	"usageBatch\"\xfa\x01\n" +
 	"\x13ReportUsageResponse\x12'\n" +
 	"\x0fshould_continue\x18\x01 \x01(\bR\x0eshouldContinue\x12\x1f\n" +
 	"\vstop_reason\x18\x02 \x01(\tR\n" +
 	"stopReason\x12\x1f\n" +
 	"\vrav_updated\x18\x03 \x01(\bR\n" +
-	"ravUpdated\"\xcf\x01\n" +
+	"ravUpdated\x12S\n" +
+	"\tstop_code\x18\x04 \x01(\x0e26.graph.substreams.data_service.common.v1.RejectionCodeR\bstopCode\x12#\n" +
+	"\rrav_requested\x18\x05 \x01(\bR\fravRequested\"\x8e\x01\n" +
+	"\x13StreamUsageResponse\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12X\n" +
+	"\aoutcome\x18\x02 \x01(\v2>.graph.substreams.data_service.provider.v1.ReportUsageResponseR\aoutcome\"\xcf\x01\n" +
 	"\x11EndSessionRequest\x12\x1d\n" +
 	"\n" +
 	"session_id\x18\x01 \x01(\tR\tsessionId\x12O\n" +
@@ -561,21 +883,40 @@ const file_graph_substreams_data_service_provider_v1_provider_proto_rawDesc = ""
 	"finalUsage\x12J\n" +
 	"\x06reason\x18\x03 \x01(\x0e22.graph.substreams.data_service.common.v1.EndReasonR\x06reason\"\x88\x02\n" +
 	"\x12EndSessionResponse\x12O\n" +
-	"\tfinal_rav\x18\x01 \x01(\v22.graph.substreams.data_service.common.v1.SignedRAVR\bfinalRav\x12O\n" +
-	"\vtotal_usage\x18\x02 \x01(\v2..graph.substreams.data_service.common.v1.UsageR\n" +
-	"totalUsage\x12P\n" +
-	"\vtotal_value\x18\x03 \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\n" +
-	"totalValue\"8\n" +
+	"\tfinal_rav\x18\x01 \x01(\v22.graph.substreams.data_service.common.v1.SignedRAVR\bfinalRav\x12#\n" +
+	"\rrav_requested\x18\x04 \x01(\bR\fravRequested\x12\\\n" +
+	"\n" +
+	"settlement\x18\x05 \x01(\v2<.graph.substreams.data_service.provider.v1.SessionSettlementR\n" +
+	"settlementJ\x04\b\x02\x10\x04R\vtotal_usageR\vtotal_value\"8\n" +
 	"\x17GetSessionStatusRequest\x12\x1d\n" +
 	"\n" +
-	"session_id\x18\x01 \x01(\tR\tsessionId\"\xe1\x01\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"\xde\x02\n" +
 	"\x18GetSessionStatusResponse\x12\x16\n" +
 	"\x06active\x18\x01 \x01(\bR\x06active\x12N\n" +
 	"\asession\x18\x02 \x01(\v24.graph.substreams.data_service.common.v1.SessionInfoR\asession\x12]\n" +
-	"\x0epayment_status\x18\x03 \x01(\v26.graph.substreams.data_service.common.v1.PaymentStatusR\rpaymentStatus2\xec\x04\n" +
+	"\x0epayment_status\x18\x03 \x01(\v26.graph.substreams.data_service.common.v1.PaymentStatusR\rpaymentStatus\x12_\n" +
+	"\x0einstance_usage\x18\x04 \x03(\v28.graph.substreams.data_service.provider.v1.InstanceUsageR\rinstanceUsage\x12\x1a\n" +
+	"\bdegraded\x18\x05 \x01(\bR\bdegraded\"\xb6\x01\n" +
+	"\rInstanceUsage\x12\x1f\n" +
+	"\vinstance_id\x18\x01 \x01(\tR\n" +
+	"instanceId\x12D\n" +
+	"\x05usage\x18\x02 \x01(\v2..graph.substreams.data_service.common.v1.UsageR\x05usage\x12\x18\n" +
+	"\areports\x18\x03 \x01(\x04R\areports\x12$\n" +
+	"\x0elast_report_ns\x18\x04 \x01(\x04R\flastReportNs\"\xd5\x05\n" +
+	"\x11SessionSettlement\x12O\n" +
+	"\vtotal_usage\x18\x01 \x01(\v2..graph.substreams.data_service.common.v1.UsageR\n" +
+	"totalUsage\x12R\n" +
+	"\fsigned_value\x18\x02 \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\vsignedValue\x12b\n" +
+	"\x14previously_collected\x18\x03 \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\x13previouslyCollected\x12T\n" +
+	"\rpending_delta\x18\x04 \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\fpendingDelta\x12R\n" +
+	"\fprotocol_cut\x18\x05 \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\vprotocolCut\x12Y\n" +
+	"\x10data_service_cut\x18\x06 \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\x0edataServiceCut\x12V\n" +
+	"\x0edelegation_cut\x18\a \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\rdelegationCut\x12Z\n" +
+	"\x10projected_payout\x18\b \x01(\v2/.graph.substreams.data_service.common.v1.BigIntR\x0fprojectedPayout2\xff\x05\n" +
 	"\x16ProviderSidecarService\x12\x98\x01\n" +
 	"\x0fValidatePayment\x12A.graph.substreams.data_service.provider.v1.ValidatePaymentRequest\x1aB.graph.substreams.data_service.provider.v1.ValidatePaymentResponse\x12\x8c\x01\n" +
-	"\vReportUsage\x12=.graph.substreams.data_service.provider.v1.ReportUsageRequest\x1a>.graph.substreams.data_service.provider.v1.ReportUsageResponse\x12\x89\x01\n" +
+	"\vReportUsage\x12=.graph.substreams.data_service.provider.v1.ReportUsageRequest\x1a>.graph.substreams.data_service.provider.v1.ReportUsageResponse\x12\x90\x01\n" +
+	"\vStreamUsage\x12=.graph.substreams.data_service.provider.v1.ReportUsageRequest\x1a>.graph.substreams.data_service.provider.v1.StreamUsageResponse(\x010\x01\x12\x89\x01\n" +
 	"\n" +
 	"EndSession\x12<.graph.substreams.data_service.provider.v1.EndSessionRequest\x1a=.graph.substreams.data_service.provider.v1.EndSessionResponse\x12\x9b\x01\n" +
 	"\x10GetSessionStatus\x12B.graph.substreams.data_service.provider.v1.GetSessionStatusRequest\x1aC.graph.substreams.data_service.provider.v1.GetSessionStatusResponseB\xed\x02\n" +
@@ -593,52 +934,71 @@ func file_graph_substreams_data_service_provider_v1_provider_proto_rawDescGZIP()
 	return file_graph_substreams_data_service_provider_v1_provider_proto_rawDescData
 }
 
-var file_graph_substreams_data_service_provider_v1_provider_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_graph_substreams_data_service_provider_v1_provider_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
 var file_graph_substreams_data_service_provider_v1_provider_proto_goTypes = []any{
 	(*ValidatePaymentRequest)(nil),   // 0: graph.substreams.data_service.provider.v1.ValidatePaymentRequest
 	(*ValidatePaymentResponse)(nil),  // 1: graph.substreams.data_service.provider.v1.ValidatePaymentResponse
 	(*ReportUsageRequest)(nil),       // 2: graph.substreams.data_service.provider.v1.ReportUsageRequest
 	(*ReportUsageResponse)(nil),      // 3: graph.substreams.data_service.provider.v1.ReportUsageResponse
-	(*EndSessionRequest)(nil),        // 4: graph.substreams.data_service.provider.v1.EndSessionRequest
-	(*EndSessionResponse)(nil),       // 5: graph.substreams.data_service.provider.v1.EndSessionResponse
-	(*GetSessionStatusRequest)(nil),  // 6: graph.substreams.data_service.provider.v1.GetSessionStatusRequest
-	(*GetSessionStatusResponse)(nil), // 7: graph.substreams.data_service.provider.v1.GetSessionStatusResponse
-	(*v1.SignedRAV)(nil),             // 8: graph.substreams.data_service.common.v1.SignedRAV
-	(*v1.ServiceParameters)(nil),     // 9: graph.substreams.data_service.common.v1.ServiceParameters
-	(*v1.EscrowAccount)(nil),         // 10: graph.substreams.data_service.common.v1.EscrowAccount
-	(*v1.BigInt)(nil),                // 11: graph.substreams.data_service.common.v1.BigInt
-	(*v1.Usage)(nil),                 // 12: graph.substreams.data_service.common.v1.Usage
-	(v1.EndReason)(0),                // 13: graph.substreams.data_service.common.v1.EndReason
-	(*v1.SessionInfo)(nil),           // 14: graph.substreams.data_service.common.v1.SessionInfo
-	(*v1.PaymentStatus)(nil),         // 15: graph.substreams.data_service.common.v1.PaymentStatus
+	(*StreamUsageResponse)(nil),      // 4: graph.substreams.data_service.provider.v1.StreamUsageResponse
+	(*EndSessionRequest)(nil),        // 5: graph.substreams.data_service.provider.v1.EndSessionRequest
+	(*EndSessionResponse)(nil),       // 6: graph.substreams.data_service.provider.v1.EndSessionResponse
+	(*GetSessionStatusRequest)(nil),  // 7: graph.substreams.data_service.provider.v1.GetSessionStatusRequest
+	(*GetSessionStatusResponse)(nil), // 8: graph.substreams.data_service.provider.v1.GetSessionStatusResponse
+	(*InstanceUsage)(nil),            // 9: graph.substreams.data_service.provider.v1.InstanceUsage
+	(*SessionSettlement)(nil),        // 10: graph.substreams.data_service.provider.v1.SessionSettlement
+	(*v1.SignedRAV)(nil),             // 11: graph.substreams.data_service.common.v1.SignedRAV
+	(*v1.ServiceParameters)(nil),     // 12: graph.substreams.data_service.common.v1.ServiceParameters
+	(*v1.EscrowAccount)(nil),         // 13: graph.substreams.data_service.common.v1.EscrowAccount
+	(*v1.BigInt)(nil),                // 14: graph.substreams.data_service.common.v1.BigInt
+	(v1.RejectionCode)(0),            // 15: graph.substreams.data_service.common.v1.RejectionCode
+	(*v1.Usage)(nil),                 // 16: graph.substreams.data_service.common.v1.Usage
+	(v1.EndReason)(0),                // 17: graph.substreams.data_service.common.v1.EndReason
+	(*v1.SessionInfo)(nil),           // 18: graph.substreams.data_service.common.v1.SessionInfo
+	(*v1.PaymentStatus)(nil),         // 19: graph.substreams.data_service.common.v1.PaymentStatus
 }
 var file_graph_substreams_data_service_provider_v1_provider_proto_depIdxs = []int32{
-	8,  // 0: graph.substreams.data_service.provider.v1.ValidatePaymentRequest.payment_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
-	9,  // 1: graph.substreams.data_service.provider.v1.ValidatePaymentRequest.service_params:type_name -> graph.substreams.data_service.common.v1.ServiceParameters
-	9,  // 2: graph.substreams.data_service.provider.v1.ValidatePaymentResponse.service_params:type_name -> graph.substreams.data_service.common.v1.ServiceParameters
-	10, // 3: graph.substreams.data_service.provider.v1.ValidatePaymentResponse.escrow_account:type_name -> graph.substreams.data_service.common.v1.EscrowAccount
-	11, // 4: graph.substreams.data_service.provider.v1.ValidatePaymentResponse.available_balance:type_name -> graph.substreams.data_service.common.v1.BigInt
-	12, // 5: graph.substreams.data_service.provider.v1.ReportUsageRequest.usage:type_name -> graph.substreams.data_service.common.v1.Usage
-	12, // 6: graph.substreams.data_service.provider.v1.EndSessionRequest.final_usage:type_name -> graph.substreams.data_service.common.v1.Usage
-	13, // 7: graph.substreams.data_service.provider.v1.EndSessionRequest.reason:type_name -> graph.substreams.data_service.common.v1.EndReason
-	8,  // 8: graph.substreams.data_service.provider.v1.EndSessionResponse.final_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
-	12, // 9: graph.substreams.data_service.provider.v1.EndSessionResponse.total_usage:type_name -> graph.substreams.data_service.common.v1.Usage
-	11, // 10: graph.substreams.data_service.provider.v1.EndSessionResponse.total_value:type_name -> graph.substreams.data_service.common.v1.BigInt
-	14, // 11: graph.substreams.data_service.provider.v1.GetSessionStatusResponse.session:type_name -> graph.substreams.data_service.common.v1.SessionInfo
-	15, // 12: graph.substreams.data_service.provider.v1.GetSessionStatusResponse.payment_status:type_name -> graph.substreams.data_service.common.v1.PaymentStatus
-	0,  // 13: graph.substreams.data_service.provider.v1.ProviderSidecarService.ValidatePayment:input_type -> graph.substreams.data_service.provider.v1.ValidatePaymentRequest
-	2,  // 14: graph.substreams.data_service.provider.v1.ProviderSidecarService.ReportUsage:input_type -> graph.substreams.data_service.provider.v1.ReportUsageRequest
-	4,  // 15: graph.substreams.data_service.provider.v1.ProviderSidecarService.EndSession:input_type -> graph.substreams.data_service.provider.v1.EndSessionRequest
-	6,  // 16: graph.substreams.data_service.provider.v1.ProviderSidecarService.GetSessionStatus:input_type -> graph.substreams.data_service.provider.v1.GetSessionStatusRequest
-	1,  // 17: graph.substreams.data_service.provider.v1.ProviderSidecarService.ValidatePayment:output_type -> graph.substreams.data_service.provider.v1.ValidatePaymentResponse
-	3,  // 18: graph.substreams.data_service.provider.v1.ProviderSidecarService.ReportUsage:output_type -> graph.substreams.data_service.provider.v1.ReportUsageResponse
-	5,  // 19: graph.substreams.data_service.provider.v1.ProviderSidecarService.EndSession:output_type -> graph.substreams.data_service.provider.v1.EndSessionResponse
-	7,  // 20: graph.substreams.data_service.provider.v1.ProviderSidecarService.GetSessionStatus:output_type -> graph.substreams.data_service.provider.v1.GetSessionStatusResponse
-	17, // [17:21] is the sub-list for method output_type
-	13, // [13:17] is the sub-list for method input_type
-	13, // [13:13] is the sub-list for extension type_name
-	13, // [13:13] is the sub-list for extension extendee
-	0,  // [0:13] is the sub-list for field type_name
+	11, // 0: graph.substreams.data_service.provider.v1.ValidatePaymentRequest.payment_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
+	12, // 1: graph.substreams.data_service.provider.v1.ValidatePaymentRequest.service_params:type_name -> graph.substreams.data_service.common.v1.ServiceParameters
+	12, // 2: graph.substreams.data_service.provider.v1.ValidatePaymentResponse.service_params:type_name -> graph.substreams.data_service.common.v1.ServiceParameters
+	13, // 3: graph.substreams.data_service.provider.v1.ValidatePaymentResponse.escrow_account:type_name -> graph.substreams.data_service.common.v1.EscrowAccount
+	14, // 4: graph.substreams.data_service.provider.v1.ValidatePaymentResponse.available_balance:type_name -> graph.substreams.data_service.common.v1.BigInt
+	15, // 5: graph.substreams.data_service.provider.v1.ValidatePaymentResponse.rejection_code:type_name -> graph.substreams.data_service.common.v1.RejectionCode
+	16, // 6: graph.substreams.data_service.provider.v1.ReportUsageRequest.usage:type_name -> graph.substreams.data_service.common.v1.Usage
+	16, // 7: graph.substreams.data_service.provider.v1.ReportUsageRequest.usage_batch:type_name -> graph.substreams.data_service.common.v1.Usage
+	15, // 8: graph.substreams.data_service.provider.v1.ReportUsageResponse.stop_code:type_name -> graph.substreams.data_service.common.v1.RejectionCode
+	3,  // 9: graph.substreams.data_service.provider.v1.StreamUsageResponse.outcome:type_name -> graph.substreams.data_service.provider.v1.ReportUsageResponse
+	16, // 10: graph.substreams.data_service.provider.v1.EndSessionRequest.final_usage:type_name -> graph.substreams.data_service.common.v1.Usage
+	17, // 11: graph.substreams.data_service.provider.v1.EndSessionRequest.reason:type_name -> graph.substreams.data_service.common.v1.EndReason
+	11, // 12: graph.substreams.data_service.provider.v1.EndSessionResponse.final_rav:type_name -> graph.substreams.data_service.common.v1.SignedRAV
+	10, // 13: graph.substreams.data_service.provider.v1.EndSessionResponse.settlement:type_name -> graph.substreams.data_service.provider.v1.SessionSettlement
+	18, // 14: graph.substreams.data_service.provider.v1.GetSessionStatusResponse.session:type_name -> graph.substreams.data_service.common.v1.SessionInfo
+	19, // 15: graph.substreams.data_service.provider.v1.GetSessionStatusResponse.payment_status:type_name -> graph.substreams.data_service.common.v1.PaymentStatus
+	9,  // 16: graph.substreams.data_service.provider.v1.GetSessionStatusResponse.instance_usage:type_name -> graph.substreams.data_service.provider.v1.InstanceUsage
+	16, // 17: graph.substreams.data_service.provider.v1.InstanceUsage.usage:type_name -> graph.substreams.data_service.common.v1.Usage
+	16, // 18: graph.substreams.data_service.provider.v1.SessionSettlement.total_usage:type_name -> graph.substreams.data_service.common.v1.Usage
+	14, // 19: graph.substreams.data_service.provider.v1.SessionSettlement.signed_value:type_name -> graph.substreams.data_service.common.v1.BigInt
+	14, // 20: graph.substreams.data_service.provider.v1.SessionSettlement.previously_collected:type_name -> graph.substreams.data_service.common.v1.BigInt
+	14, // 21: graph.substreams.data_service.provider.v1.SessionSettlement.pending_delta:type_name -> graph.substreams.data_service.common.v1.BigInt
+	14, // 22: graph.substreams.data_service.provider.v1.SessionSettlement.protocol_cut:type_name -> graph.substreams.data_service.common.v1.BigInt
+	14, // 23: graph.substreams.data_service.provider.v1.SessionSettlement.data_service_cut:type_name -> graph.substreams.data_service.common.v1.BigInt
+	14, // 24: graph.substreams.data_service.provider.v1.SessionSettlement.delegation_cut:type_name -> graph.substreams.data_service.common.v1.BigInt
+	14, // 25: graph.substreams.data_service.provider.v1.SessionSettlement.projected_payout:type_name -> graph.substreams.data_service.common.v1.BigInt
+	0,  // 26: graph.substreams.data_service.provider.v1.ProviderSidecarService.ValidatePayment:input_type -> graph.substreams.data_service.provider.v1.ValidatePaymentRequest
+	2,  // 27: graph.substreams.data_service.provider.v1.ProviderSidecarService.ReportUsage:input_type -> graph.substreams.data_service.provider.v1.ReportUsageRequest
+	2,  // 28: graph.substreams.data_service.provider.v1.ProviderSidecarService.StreamUsage:input_type -> graph.substreams.data_service.provider.v1.ReportUsageRequest
+	5,  // 29: graph.substreams.data_service.provider.v1.ProviderSidecarService.EndSession:input_type -> graph.substreams.data_service.provider.v1.EndSessionRequest
+	7,  // 30: graph.substreams.data_service.provider.v1.ProviderSidecarService.GetSessionStatus:input_type -> graph.substreams.data_service.provider.v1.GetSessionStatusRequest
+	1,  // 31: graph.substreams.data_service.provider.v1.ProviderSidecarService.ValidatePayment:output_type -> graph.substreams.data_service.provider.v1.ValidatePaymentResponse
+	3,  // 32: graph.substreams.data_service.provider.v1.ProviderSidecarService.ReportUsage:output_type -> graph.substreams.data_service.provider.v1.ReportUsageResponse
+	4,  // 33: graph.substreams.data_service.provider.v1.ProviderSidecarService.StreamUsage:output_type -> graph.substreams.data_service.provider.v1.StreamUsageResponse
+	6,  // 34: graph.substreams.data_service.provider.v1.ProviderSidecarService.EndSession:output_type -> graph.substreams.data_service.provider.v1.EndSessionResponse
+	8,  // 35: graph.substreams.data_service.provider.v1.ProviderSidecarService.GetSessionStatus:output_type -> graph.substreams.data_service.provider.v1.GetSessionStatusResponse
+	31, // [31:36] is the sub-list for method output_type
+	26, // [26:31] is the sub-list for method input_type
+	26, // [26:26] is the sub-list for extension type_name
+	26, // [26:26] is the sub-list for extension extendee
+	0,  // [0:26] is the sub-list for field type_name
 }
 
 func init() { file_graph_substreams_data_service_provider_v1_provider_proto_init() }
@@ -652,7 +1012,7 @@ func file_graph_substreams_data_service_provider_v1_provider_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_graph_substreams_data_service_provider_v1_provider_proto_rawDesc), len(file_graph_substreams_data_service_provider_v1_provider_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   8,
+			NumMessages:   11,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -42,6 +42,9 @@ const (
 	// PaymentGatewayServicePaymentSessionProcedure is the fully-qualified name of the
 	// PaymentGatewayService's PaymentSession RPC.
 	PaymentGatewayServicePaymentSessionProcedure = "/graph.substreams.data_service.provider.v1.PaymentGatewayService/PaymentSession"
+	// PaymentGatewayServiceProveIdentityProcedure is the fully-qualified name of the
+	// PaymentGatewayService's ProveIdentity RPC.
+	PaymentGatewayServiceProveIdentityProcedure = "/graph.substreams.data_service.provider.v1.PaymentGatewayService/ProveIdentity"
 )
 
 // PaymentGatewayServiceClient is a client for the
@@ -53,11 +56,19 @@ type PaymentGatewayServiceClient interface {
 	StartSession(context.Context, *connect.Request[v1.StartSessionRequest]) (*connect.Response[v1.StartSessionResponse], error)
 	// SubmitRAV submits a signed RAV to the provider sidecar.
 	// Called when the provider requests a new RAV for continued service.
+	//
+	// When the provider sidecar is configured with an external aggregator, signed
+	// receipts can be submitted instead of a RAV, the provider sidecar then
+	// obtains the RAV from the aggregator and returns it in the response.
 	SubmitRAV(context.Context, *connect.Request[v1.SubmitRAVRequest]) (*connect.Response[v1.SubmitRAVResponse], error)
 	// PaymentSession is a bidirectional stream for ongoing payment negotiation.
 	// This allows the provider sidecar to request RAVs and notify about
 	// funding requirements in real-time.
 	PaymentSession(context.Context) *connect.BidiStreamForClient[v1.PaymentSessionRequest, v1.PaymentSessionResponse]
+	// ProveIdentity signs a consumer chosen challenge with the service provider
+	// key, proving the endpoint is operated by the service provider the consumer
+	// pays before it signs any non-zero RAV.
+	ProveIdentity(context.Context, *connect.Request[v1.ProveIdentityRequest]) (*connect.Response[v1.ProveIdentityResponse], error)
 }
 
 // NewPaymentGatewayServiceClient constructs a client for the
@@ -90,6 +101,12 @@ func NewPaymentGatewayServiceClient(httpClient connect.HTTPClient, baseURL strin
 			connect.WithSchema(paymentGatewayServiceMethods.ByName("PaymentSession")),
 			connect.WithClientOptions(opts...),
 		),
+		proveIdentity: connect.NewClient[v1.ProveIdentityRequest, v1.ProveIdentityResponse](
+			httpClient,
+			baseURL+PaymentGatewayServiceProveIdentityProcedure,
+			connect.WithSchema(paymentGatewayServiceMethods.ByName("ProveIdentity")),
+			connect.WithClientOptions(opts...),
+		),
 	}
 }
 
@@ -98,6 +115,7 @@ type paymentGatewayServiceClient struct {
 	startSession   *connect.Client[v1.StartSessionRequest, v1.StartSessionResponse]
 	submitRAV      *connect.Client[v1.SubmitRAVRequest, v1.SubmitRAVResponse]
 	paymentSession *connect.Client[v1.PaymentSessionRequest, v1.PaymentSessionResponse]
+	proveIdentity  *connect.Client[v1.ProveIdentityRequest, v1.ProveIdentityResponse]
 }
 
 // StartSession calls graph.substreams.data_service.provider.v1.PaymentGatewayService.StartSession.
@@ -116,6 +134,12 @@ func (c *paymentGatewayServiceClient) PaymentSession(ctx context.Context) *conne
 	return c.paymentSession.CallBidiStream(ctx)
 }
 
+// ProveIdentity calls
+// graph.substreams.data_service.provider.v1.PaymentGatewayService.ProveIdentity.
+func (c *paymentGatewayServiceClient) ProveIdentity(ctx context.Context, req *connect.Request[v1.ProveIdentityRequest]) (*connect.Response[v1.ProveIdentityResponse], error) {
+	return c.proveIdentity.CallUnary(ctx, req)
+}
+
 // PaymentGatewayServiceHandler is an implementation of the
 // graph.substreams.data_service.provider.v1.PaymentGatewayService service.
 type PaymentGatewayServiceHandler interface {
@@ -125,11 +149,19 @@ type PaymentGatewayServiceHandler interface {
 	StartSession(context.Context, *connect.Request[v1.StartSessionRequest]) (*connect.Response[v1.StartSessionResponse], error)
 	// SubmitRAV submits a signed RAV to the provider sidecar.
 	// Called when the provider requests a new RAV for continued service.
+	//
+	// When the provider sidecar is configured with an external aggregator, signed
+	// receipts can be submitted instead of a RAV, the provider sidecar then
+	// obtains the RAV from the aggregator and returns it in the response.
 	SubmitRAV(context.Context, *connect.Request[v1.SubmitRAVRequest]) (*connect.Response[v1.SubmitRAVResponse], error)
 	// PaymentSession is a bidirectional stream for ongoing payment negotiation.
 	// This allows the provider sidecar to request RAVs and notify about
 	// funding requirements in real-time.
 	PaymentSession(context.Context, *connect.BidiStream[v1.PaymentSessionRequest, v1.PaymentSessionResponse]) error
+	// ProveIdentity signs a consumer chosen challenge with the service provider
+	// key, proving the endpoint is operated by the service provider the consumer
+	// pays before it signs any non-zero RAV.
+	ProveIdentity(context.Context, *connect.Request[v1.ProveIdentityRequest]) (*connect.Response[v1.ProveIdentityResponse], error)
 }
 
 // NewPaymentGatewayServiceHandler builds an HTTP handler from the service implementation. It
@@ -157,6 +189,12 @@ func NewPaymentGatewayServiceHandler(svc PaymentGatewayServiceHandler, opts ...c
 		connect.WithSchema(paymentGatewayServiceMethods.ByName("PaymentSession")),
 		connect.WithHandlerOptions(opts...),
 	)
+	paymentGatewayServiceProveIdentityHandler := connect.NewUnaryHandler(
+		PaymentGatewayServiceProveIdentityProcedure,
+		svc.ProveIdentity,
+		connect.WithSchema(paymentGatewayServiceMethods.ByName("ProveIdentity")),
+		connect.WithHandlerOptions(opts...),
+	)
 	return "/graph.substreams.data_service.provider.v1.PaymentGatewayService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case PaymentGatewayServiceStartSessionProcedure:
@@ -165,6 +203,8 @@ func NewPaymentGatewayServiceHandler(svc PaymentGatewayServiceHandler, opts ...c
 			paymentGatewayServiceSubmitRAVHandler.ServeHTTP(w, r)
 		case PaymentGatewayServicePaymentSessionProcedure:
 			paymentGatewayServicePaymentSessionHandler.ServeHTTP(w, r)
+		case PaymentGatewayServiceProveIdentityProcedure:
+			paymentGatewayServiceProveIdentityHandler.ServeHTTP(w, r)
 		default:
 			http.NotFound(w, r)
 		}
@@ -185,3 +225,7 @@ func (UnimplementedPaymentGatewayServiceHandler) SubmitRAV(context.Context, *con
 func (UnimplementedPaymentGatewayServiceHandler) PaymentSession(context.Context, *connect.BidiStream[v1.PaymentSessionRequest, v1.PaymentSessionResponse]) error {
 	return connect.NewError(connect.CodeUnimplemented, errors.New("graph.substreams.data_service.provider.v1.PaymentGatewayService.PaymentSession is not implemented"))
 }
+
+func (UnimplementedPaymentGatewayServiceHandler) ProveIdentity(context.Context, *connect.Request[v1.ProveIdentityRequest]) (*connect.Response[v1.ProveIdentityResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("graph.substreams.data_service.provider.v1.PaymentGatewayService.ProveIdentity is not implemented"))
+}
@@ -39,6 +39,9 @@ const (
 	// ProviderSidecarServiceReportUsageProcedure is the fully-qualified name of the
 	// ProviderSidecarService's ReportUsage RPC.
 	ProviderSidecarServiceReportUsageProcedure = "/graph.substreams.data_service.provider.v1.ProviderSidecarService/ReportUsage"
+	// ProviderSidecarServiceStreamUsageProcedure is the fully-qualified name of the
+	// ProviderSidecarService's StreamUsage RPC.
+	ProviderSidecarServiceStreamUsageProcedure = "/graph.substreams.data_service.provider.v1.ProviderSidecarService/StreamUsage"
 	// ProviderSidecarServiceEndSessionProcedure is the fully-qualified name of the
 	// ProviderSidecarService's EndSession RPC.
 	ProviderSidecarServiceEndSessionProcedure = "/graph.substreams.data_service.provider.v1.ProviderSidecarService/EndSession"
@@ -56,6 +59,13 @@ type ProviderSidecarServiceClient interface {
 	// ReportUsage reports usage sent to a client.
 	// Called by the provider as data is sent during streaming.
 	ReportUsage(context.Context, *connect.Request[v1.ReportUsageRequest]) (*connect.Response[v1.ReportUsageResponse], error)
+	// StreamUsage reports usage on a single bidirectional stream, for providers
+	// reporting at a high frequency (e.g. every block) where one ReportUsage
+	// call per report becomes the bottleneck. Reports are handled like
+	// ReportUsage calls, for any number of sessions, but the sidecar only answers
+	// when there is something to act on: the session's RAV was updated or the
+	// session must stop. Requires HTTP/2.
+	StreamUsage(context.Context) *connect.BidiStreamForClient[v1.ReportUsageRequest, v1.StreamUsageResponse]
 	// EndSession ends a session and reports final usage.
 	// Called by the provider when a stream ends.
 	EndSession(context.Context, *connect.Request[v1.EndSessionRequest]) (*connect.Response[v1.EndSessionResponse], error)
@@ -87,6 +97,12 @@ func NewProviderSidecarServiceClient(httpClient connect.HTTPClient, baseURL stri
 			connect.WithSchema(providerSidecarServiceMethods.ByName("ReportUsage")),
 			connect.WithClientOptions(opts...),
 		),
+		streamUsage: connect.NewClient[v1.ReportUsageRequest, v1.StreamUsageResponse](
+			httpClient,
+			baseURL+ProviderSidecarServiceStreamUsageProcedure,
+			connect.WithSchema(providerSidecarServiceMethods.ByName("StreamUsage")),
+			connect.WithClientOptions(opts...),
+		),
 		endSession: connect.NewClient[v1.EndSessionRequest, v1.EndSessionResponse](
 			httpClient,
 			baseURL+ProviderSidecarServiceEndSessionProcedure,
@@ -106,6 +122,7 @@ func NewProviderSidecarServiceClient(httpClient connect.HTTPClient, baseURL stri
 type providerSidecarServiceClient struct {
 	validatePayment  *connect.Client[v1.ValidatePaymentRequest, v1.ValidatePaymentResponse]
 	reportUsage      *connect.Client[v1.ReportUsageRequest, v1.ReportUsageResponse]
+	streamUsage      *connect.Client[v1.ReportUsageRequest, v1.StreamUsageResponse]
 	endSession       *connect.Client[v1.EndSessionRequest, v1.EndSessionResponse]
 	getSessionStatus *connect.Client[v1.GetSessionStatusRequest, v1.GetSessionStatusResponse]
 }
@@ -121,6 +138,11 @@ func (c *providerSidecarServiceClient) ReportUsage(ctx context.Context, req *con
 	return c.reportUsage.CallUnary(ctx, req)
 }
 
+// StreamUsage calls graph.substreams.data_service.provider.v1.ProviderSidecarService.StreamUsage.
+func (c *providerSidecarServiceClient) StreamUsage(ctx context.Context) *connect.BidiStreamForClient[v1.ReportUsageRequest, v1.StreamUsageResponse] {
+	return c.streamUsage.CallBidiStream(ctx)
+}
+
 // EndSession calls graph.substreams.data_service.provider.v1.ProviderSidecarService.EndSession.
 func (c *providerSidecarServiceClient) EndSession(ctx context.Context, req *connect.Request[v1.EndSessionRequest]) (*connect.Response[v1.EndSessionResponse], error) {
 	return c.endSession.CallUnary(ctx, req)
@@ -141,6 +163,13 @@ type ProviderSidecarServiceHandler interface {
 	// ReportUsage reports usage sent to a client.
 	// Called by the provider as data is sent during streaming.
 	ReportUsage(context.Context, *connect.Request[v1.ReportUsageRequest]) (*connect.Response[v1.ReportUsageResponse], error)
+	// StreamUsage reports usage on a single bidirectional stream, for providers
+	// reporting at a high frequency (e.g. every block) where one ReportUsage
+	// call per report becomes the bottleneck. Reports are handled like
+	// ReportUsage calls, for any number of sessions, but the sidecar only answers
+	// when there is something to act on: the session's RAV was updated or the
+	// session must stop. Requires HTTP/2.
+	StreamUsage(context.Context, *connect.BidiStream[v1.ReportUsageRequest, v1.StreamUsageResponse]) error
 	// EndSession ends a session and reports final usage.
 	// Called by the provider when a stream ends.
 	EndSession(context.Context, *connect.Request[v1.EndSessionRequest]) (*connect.Response[v1.EndSessionResponse], error)
@@ -167,6 +196,12 @@ func NewProviderSidecarServiceHandler(svc ProviderSidecarServiceHandler, opts ..
 		connect.WithSchema(providerSidecarServiceMethods.ByName("ReportUsage")),
 		connect.WithHandlerOptions(opts...),
 	)
+	providerSidecarServiceStreamUsageHandler := connect.NewBidiStreamHandler(
+		ProviderSidecarServiceStreamUsageProcedure,
+		svc.StreamUsage,
+		connect.WithSchema(providerSidecarServiceMethods.ByName("StreamUsage")),
+		connect.WithHandlerOptions(opts...),
+	)
 	providerSidecarServiceEndSessionHandler := connect.NewUnaryHandler(
 		ProviderSidecarServiceEndSessionProcedure,
 		svc.EndSession,
@@ -185,6 +220,8 @@ func NewProviderSidecarServiceHandler(svc ProviderSidecarServiceHandler, opts ..
 			providerSidecarServiceValidatePaymentHandler.ServeHTTP(w, r)
 		case ProviderSidecarServiceReportUsageProcedure:
 			providerSidecarServiceReportUsageHandler.ServeHTTP(w, r)
+		case ProviderSidecarServiceStreamUsageProcedure:
+			providerSidecarServiceStreamUsageHandler.ServeHTTP(w, r)
 		case ProviderSidecarServiceEndSessionProcedure:
 			providerSidecarServiceEndSessionHandler.ServeHTTP(w, r)
 		case ProviderSidecarServiceGetSessionStatusProcedure:
@@ -206,6 +243,10 @@ func (UnimplementedProviderSidecarServiceHandler) ReportUsage(context.Context, *
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("graph.substreams.data_service.provider.v1.ProviderSidecarService.ReportUsage is not implemented"))
 }
 
+func (UnimplementedProviderSidecarServiceHandler) StreamUsage(context.Context, *connect.BidiStream[v1.ReportUsageRequest, v1.StreamUsageResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("graph.substreams.data_service.provider.v1.ProviderSidecarService.StreamUsage is not implemented"))
+}
+
 func (UnimplementedProviderSidecarServiceHandler) EndSession(context.Context, *connect.Request[v1.EndSessionRequest]) (*connect.Response[v1.EndSessionResponse], error) {
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("graph.substreams.data_service.provider.v1.ProviderSidecarService.EndSession is not implemented"))
 }
@@ -0,0 +1,193 @@
+// Package server helps Substreams providers accept payments in their own gRPC
+// servers: it extracts the payment header of incoming requests, validates it
+// with the provider sidecar and exposes the resulting payment session to the
+// handlers.
+package server
+
+import (
+	"context"
+	"math/big"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1/providerv1connect"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// IsBlocksMethod reports whether the full gRPC method name is the Substreams
+// Blocks() stream, of any version of the sf.substreams.rpc Stream service
+func IsBlocksMethod(method string) bool {
+	return strings.HasPrefix(method, "/sf.substreams.rpc.") && strings.HasSuffix(method, ".Stream/Blocks")
+}
+
+// PaymentSession is the payment session of a request, validated by the
+// provider sidecar
+type PaymentSession struct {
+	// ID is the provider sidecar session ID, to pass to ReportUsage and EndSession
+	ID string
+	// RAV is the signed RAV received in the payment header
+	RAV *horizon.SignedRAV
+	// Signer is the address recovered from the RAV signature
+	Signer eth.Address
+	// EscrowAccount is the escrow account paying for the session
+	EscrowAccount *commonv1.EscrowAccount
+	// AvailableBalance is the payer's escrow balance in GRT wei, nil when unknown
+	AvailableBalance *big.Int
+}
+
+type sessionKey struct{}
+
+// SessionFromContext returns the payment session attached to the request
+// context by PaymentValidator
+func SessionFromContext(ctx context.Context) (*PaymentSession, bool) {
+	session, ok := ctx.Value(sessionKey{}).(*PaymentSession)
+	return session, ok
+}
+
+// PaymentValidator requires a valid payment header on the paid requests of a
+// gRPC server (step 'p->psc: validate RAVx' of the payment flow).
+//
+// The header is decoded and checked locally first: its RAV must be complete,
+// address this service provider and carry a signature a signer can be
+// recovered from under the collector domain. Malformed or misdirected
+// payments are thus refused without reaching the sidecar. The RAV is
+// then validated by the provider sidecar (ValidatePayment), which opens or
+// resumes the payment session attached to the request context.
+type PaymentValidator struct {
+	sidecar         providerv1connect.ProviderSidecarServiceClient
+	domain          *horizon.Domain
+	serviceProvider eth.Address
+	methods         func(method string) bool
+	logger          *zap.Logger
+}
+
+// NewPaymentValidator returns a validator of the payment headers received by
+// serviceProvider, whose RAVs are signed under domain, through the provider
+// sidecar client
+func NewPaymentValidator(sidecar providerv1connect.ProviderSidecarServiceClient, domain *horizon.Domain, serviceProvider eth.Address, logger *zap.Logger) *PaymentValidator {
+	return &PaymentValidator{
+		sidecar:         sidecar,
+		domain:          domain,
+		serviceProvider: serviceProvider,
+		methods:         IsBlocksMethod,
+		logger:          logger,
+	}
+}
+
+// WithMethods replaces the predicate selecting the gRPC methods requiring a
+// payment, IsBlocksMethod by default
+func (v *PaymentValidator) WithMethods(methods func(method string) bool) *PaymentValidator {
+	v.methods = methods
+	return v
+}
+
+// UnaryServerInterceptor validates the payment of the selected unary calls
+func (v *PaymentValidator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !v.methods(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		session, err := v.Validate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(context.WithValue(ctx, sessionKey{}, session), req)
+	}
+}
+
+// StreamServerInterceptor validates the payment of the selected streams,
+// Blocks() by default
+func (v *PaymentValidator) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !v.methods(info.FullMethod) {
+			return handler(srv, stream)
+		}
+
+		session, err := v.Validate(stream.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &sessionStream{ServerStream: stream, ctx: context.WithValue(stream.Context(), sessionKey{}, session)})
+	}
+}
+
+// sessionStream overrides the context of a server stream to carry its payment session
+type sessionStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *sessionStream) Context() context.Context {
+	return s.ctx
+}
+
+// Validate extracts the payment header of the incoming request in ctx and
+// validates it, errors are gRPC statuses: Unauthenticated when the header is
+// missing or malformed, PermissionDenied when the payment is refused and
+// Unavailable when the provider sidecar cannot be reached
+func (v *PaymentValidator) Validate(ctx context.Context) (*PaymentSession, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(sidecar.PaymentHeader)
+	if len(values) == 0 {
+		return nil, status.Errorf(codes.Unauthenticated, "missing %q header", sidecar.PaymentHeader)
+	}
+
+	protoRAV, err := sidecar.DecodePaymentHeader(values[0])
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid %q header: %s", sidecar.PaymentHeader, err)
+	}
+
+	signedRAV, signer, err := v.checkLocally(protoRAV)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := v.sidecar.ValidatePayment(ctx, connect.NewRequest(&providerv1.ValidatePaymentRequest{PaymentRav: protoRAV}))
+	if err != nil {
+		v.logger.Warn("validating payment with provider sidecar failed", zap.Error(err))
+		return nil, status.Errorf(codes.Unavailable, "validating payment: %s", err)
+	}
+	if !resp.Msg.Valid {
+		return nil, status.Errorf(codes.PermissionDenied, "payment rejected (%s): %s", resp.Msg.RejectionCode, resp.Msg.RejectionReason)
+	}
+
+	session := &PaymentSession{
+		ID:            resp.Msg.SessionId,
+		RAV:           signedRAV,
+		Signer:        signer,
+		EscrowAccount: resp.Msg.EscrowAccount,
+	}
+	if resp.Msg.AvailableBalance != nil {
+		session.AvailableBalance = resp.Msg.AvailableBalance.ToNative()
+	}
+	return session, nil
+}
+
+// checkLocally verifies the RAV signature and that the RAV pays this service
+// provider, returning the RAV and its signer
+func (v *PaymentValidator) checkLocally(protoRAV *commonv1.SignedRAV) (*horizon.SignedRAV, eth.Address, error) {
+	if protoRAV.Rav.Payer == nil || protoRAV.Rav.DataService == nil || protoRAV.Rav.ServiceProvider == nil || protoRAV.Rav.ValueAggregate == nil {
+		return nil, nil, status.Error(codes.Unauthenticated, "invalid payment RAV: missing fields")
+	}
+
+	signedRAV := sidecar.ProtoSignedRAVToHorizon(protoRAV)
+	if !sidecar.AddressesEqual(signedRAV.Message.ServiceProvider, v.serviceProvider) {
+		return nil, nil, status.Errorf(codes.PermissionDenied, "payment RAV is for service provider %s, not %s", signedRAV.Message.ServiceProvider.Pretty(), v.serviceProvider.Pretty())
+	}
+
+	signer, err := signedRAV.RecoverSigner(v.domain)
+	if err != nil {
+		return nil, nil, status.Errorf(codes.Unauthenticated, "invalid payment RAV signature: %s", err)
+	}
+	return signedRAV, signer, nil
+}
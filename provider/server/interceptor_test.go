@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1/providerv1connect"
+	providersidecar "github.com/graphprotocol/substreams-data-service/provider/sidecar"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestPaymentValidator(t *testing.T) {
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	payer := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	domain := horizon.NewDomain(1337, eth.MustNewAddress("0x4444444444444444444444444444444444444444"))
+
+	providerSidecar := providersidecar.New(&providersidecar.Config{
+		ListenAddr:      ":0",
+		ServiceProvider: serviceProvider,
+		Domain:          domain,
+		AcceptedSigners: []eth.Address{signerKey.PublicKey().Address()},
+	}, zap.NewNop())
+
+	mux := http.NewServeMux()
+	mux.Handle(providerv1connect.NewProviderSidecarServiceHandler(providerSidecar))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	validator := NewPaymentValidator(providerv1connect.NewProviderSidecarServiceClient(http.DefaultClient, server.URL), domain, serviceProvider, zap.NewNop())
+	intercept := validator.StreamServerInterceptor()
+
+	header := func(receiver eth.Address, key *eth.PrivateKey) string {
+		rav, err := horizon.Sign(domain, &horizon.RAV{
+			Payer:           payer,
+			DataService:     dataService,
+			ServiceProvider: receiver,
+			TimestampNs:     1,
+			ValueAggregate:  big.NewInt(1000),
+		}, key)
+		require.NoError(t, err)
+
+		value, err := sidecar.EncodePaymentHeader(sidecar.HorizonSignedRAVToProto(rav))
+		require.NoError(t, err)
+		return value
+	}
+
+	call := func(method string, headers ...string) (*PaymentSession, error) {
+		ctx := context.Background()
+		if len(headers) > 0 {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(sidecar.PaymentHeader, headers[0]))
+		}
+
+		var session *PaymentSession
+		err := intercept(nil, &fakeServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: method, IsServerStream: true}, func(srv any, stream grpc.ServerStream) error {
+			session, _ = SessionFromContext(stream.Context())
+			return nil
+		})
+		return session, err
+	}
+
+	// Methods not paid for go through untouched
+	session, err := call("/sf.substreams.rpc.v2.EndpointInfo/Info")
+	require.NoError(t, err)
+	assert.Nil(t, session)
+
+	_, err = call("/sf.substreams.rpc.v2.Stream/Blocks")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = call("/sf.substreams.rpc.v2.Stream/Blocks", "not a RAV")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = call("/sf.substreams.rpc.v2.Stream/Blocks", header(payer, signerKey))
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.ErrorContains(t, err, "not "+serviceProvider.Pretty())
+
+	otherKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	_, err = call("/sf.substreams.rpc.v2.Stream/Blocks", header(serviceProvider, otherKey))
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.ErrorContains(t, err, "REJECTION_CODE_UNAUTHORIZED_SIGNER")
+
+	session, err = call("/sf.substreams.rpc.v2.Stream/Blocks", header(serviceProvider, signerKey))
+	require.NoError(t, err)
+	require.NotNil(t, session)
+	assert.NotEmpty(t, session.ID)
+	assert.Equal(t, signerKey.PublicKey().Address().Pretty(), session.Signer.Pretty())
+	assert.Equal(t, int64(1000), session.RAV.Message.ValueAggregate.Int64())
+	assert.Equal(t, payer.Pretty(), session.EscrowAccount.Payer.ToEth().Pretty())
+}
@@ -0,0 +1,112 @@
+package sidecar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+)
+
+// aggregatorAPIVersion is the TAP aggregator API version sent with every request
+const aggregatorAPIVersion = "0.0"
+
+// errReceiptsRejected is returned by aggregators refusing receipts, as opposed
+// to failing to be reached: aggregating the same receipts again fails the same
+var errReceiptsRejected = errors.New("aggregator rejected receipts")
+
+// ExternalAggregator forwards receipts to an external TAP aggregator service
+// (JSON-RPC `aggregate_receipts`) which returns a signed RAV.
+type ExternalAggregator struct {
+	url       string
+	authToken string
+	client    *http.Client
+	requestID atomic.Uint64
+}
+
+// NewExternalAggregator creates a client for the aggregator service at url. When
+// authToken is not empty, it is sent as a bearer token on every request.
+func NewExternalAggregator(url, authToken string) *ExternalAggregator {
+	return &ExternalAggregator{
+		url:       url,
+		authToken: authToken,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type jsonRPCRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type jsonRPCResponse[T any] struct {
+	Result *T            `json:"result"`
+	Error  *jsonRPCError `json:"error"`
+}
+
+type jsonRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// aggregateReceiptsResult is the result of `aggregate_receipts`, the RAV being
+// decoded from the tap-rs form as well as the default JSON form of SignedRAV
+type aggregateReceiptsResult struct {
+	Data     *horizon.TapSignedRAV `json:"data"`
+	Warnings []json.RawMessage     `json:"warnings,omitempty"`
+}
+
+// AggregateReceipts asks the aggregator to aggregate receipts on top of
+// previousRAV (nil for the first RAV of a collection) and returns the signed RAV
+func (a *ExternalAggregator) AggregateReceipts(ctx context.Context, receipts []*horizon.SignedReceipt, previousRAV *horizon.SignedRAV) (*horizon.SignedRAV, error) {
+	body, err := json.Marshal(jsonRPCRequest{
+		JSONRPC: "2.0",
+		ID:      a.requestID.Add(1),
+		Method:  "aggregate_receipts",
+		Params:  []interface{}{aggregatorAPIVersion, receipts, previousRAV},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encoding request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+a.authToken)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("calling aggregator: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("aggregator returned HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(message))
+	}
+
+	var out jsonRPCResponse[aggregateReceiptsResult]
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decoding aggregator response: %w", err)
+	}
+	if out.Error != nil {
+		return nil, fmt.Errorf("%w: aggregator error %d: %s", errReceiptsRejected, out.Error.Code, out.Error.Message)
+	}
+	if out.Result == nil || out.Result.Data == nil || out.Result.Data.SignedRAV == nil || out.Result.Data.Message == nil {
+		return nil, fmt.Errorf("aggregator returned no RAV")
+	}
+
+	return out.Result.Data.SignedRAV, nil
+}
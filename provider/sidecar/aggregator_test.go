@@ -0,0 +1,111 @@
+package sidecar
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExternalAggregator_AggregateReceipts(t *testing.T) {
+	payerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	aggregatorKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	domain := horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111"))
+	aggregator := horizon.NewAggregator(domain, aggregatorKey, []eth.Address{payerKey.PublicKey().Address()})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		var req struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "aggregate_receipts", req.Method)
+		require.Len(t, req.Params, 3)
+
+		var receipts []*horizon.SignedReceipt
+		require.NoError(t, json.Unmarshal(req.Params[1], &receipts))
+		var previousRAV *horizon.SignedRAV
+		require.NoError(t, json.Unmarshal(req.Params[2], &previousRAV))
+
+		rav, err := aggregator.AggregateReceipts(receipts, previousRAV)
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": -32000, "message": err.Error()}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"result": map[string]any{"data": rav}})
+	}))
+	defer server.Close()
+
+	receipt := horizon.NewReceipt(
+		horizon.CollectionID{0x01},
+		payerKey.PublicKey().Address(),
+		eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+		eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		big.NewInt(100),
+	)
+	signedReceipt, err := horizon.Sign(domain, receipt, payerKey)
+	require.NoError(t, err)
+
+	client := NewExternalAggregator(server.URL, "secret")
+
+	rav, err := client.AggregateReceipts(context.Background(), []*horizon.SignedReceipt{signedReceipt}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), rav.Message.ValueAggregate.Int64())
+
+	signer, err := rav.RecoverSigner(domain)
+	require.NoError(t, err)
+	assert.Equal(t, aggregatorKey.PublicKey().Address().Pretty(), signer.Pretty())
+
+	_, err = client.AggregateReceipts(context.Background(), nil, nil)
+	assert.ErrorContains(t, err, "aggregator error -32000")
+	assert.ErrorIs(t, err, errReceiptsRejected)
+}
+
+func TestExternalAggregator_AggregatorServer(t *testing.T) {
+	payerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	aggregatorKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	domain := horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111"))
+	aggregator := horizon.NewAggregator(domain, aggregatorKey, []eth.Address{payerKey.PublicKey().Address(), aggregatorKey.PublicKey().Address()})
+	server := httptest.NewServer(horizon.NewAggregatorServer(aggregator, "", 0))
+	defer server.Close()
+
+	sign := func() *horizon.SignedReceipt {
+		receipt := horizon.NewReceipt(
+			horizon.CollectionID{0x01},
+			payerKey.PublicKey().Address(),
+			eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+			eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+			big.NewInt(100),
+		)
+		signedReceipt, err := horizon.Sign(domain, receipt, payerKey)
+		require.NoError(t, err)
+		return signedReceipt
+	}
+
+	client := NewExternalAggregator(server.URL, "")
+
+	rav, err := client.AggregateReceipts(context.Background(), []*horizon.SignedReceipt{sign()}, nil)
+	require.NoError(t, err)
+	rav, err = client.AggregateReceipts(context.Background(), []*horizon.SignedReceipt{sign()}, rav)
+	require.NoError(t, err)
+	assert.Equal(t, int64(200), rav.Message.ValueAggregate.Int64())
+
+	signer, err := rav.RecoverSigner(domain)
+	require.NoError(t, err)
+	assert.Equal(t, aggregatorKey.PublicKey().Address().Pretty(), signer.Pretty())
+}
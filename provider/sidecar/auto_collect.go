@@ -0,0 +1,73 @@
+package sidecar
+
+import (
+	"context"
+
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"go.uber.org/zap"
+)
+
+// autoCollectQueueSize bounds the ended sessions awaiting automatic collection,
+// sessions ending while it is full are left pending for the admin API or the
+// next restart
+const autoCollectQueueSize = 1024
+
+// queueAutoCollect schedules the automatic collection of session's final RAV,
+// when enabled and the session holds a non-zero RAV
+func (s *Sidecar) queueAutoCollect(session *sidecar.Session) {
+	if s.autoCollect == nil {
+		return
+	}
+
+	rav := session.GetRAV()
+	if rav == nil || rav.Message == nil || rav.Message.ValueAggregate == nil || rav.Message.ValueAggregate.Sign() <= 0 {
+		return
+	}
+
+	select {
+	case s.autoCollect <- session:
+	default:
+		s.logger.Warn("automatic collection queue full, final RAV left pending", zap.String("session_id", session.ID))
+	}
+}
+
+// watchAutoCollect collects the final RAVs of ended sessions one at a time,
+// so collect transactions never race for the same nonce, until the sidecar
+// terminates. The RAVs left pending before a restart are collected first.
+func (s *Sidecar) watchAutoCollect() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.OnTerminating(func(_ error) {
+		cancel()
+	})
+
+	for _, session := range s.pendingCollections() {
+		if ctx.Err() != nil {
+			return
+		}
+		s.autoCollectSession(ctx, session)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case session := <-s.autoCollect:
+			s.autoCollectSession(ctx, session)
+		}
+	}
+}
+
+// autoCollectSession collects the final RAV of session unless it was collected
+// or dead-lettered in the meantime, failures being handled by collectSession
+func (s *Sidecar) autoCollectSession(ctx context.Context, session *sidecar.Session) {
+	if s.collections.isCollected(session.ID) {
+		return
+	}
+	if s.collectRetries != nil && s.collectRetries.isDeadLettered(session.ID) {
+		return
+	}
+
+	s.logger.Info("collecting final RAV automatically", zap.String("session_id", session.ID))
+	s.collectSession(ctx, session, false)
+}
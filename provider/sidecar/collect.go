@@ -0,0 +1,380 @@
+package sidecar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"go.uber.org/zap"
+)
+
+// collections tracks which ended sessions had their final RAV collected on-chain
+// and which are being collected right now, so concurrent requests never send
+// the same collect transaction twice. Collections of final RAVs sharing a
+// collection ID are also serialized, each then sees what the previous one
+// collected before sending its own transaction.
+type collections struct {
+	mu         sync.Mutex
+	collected  map[string]string // session ID -> collect transaction hash
+	inProgress map[string]bool
+	locks      map[horizon.CollectionID]*collectionLock
+}
+
+// collectionLock serializes the collections of one collection ID, it is
+// dropped once no collection holds or waits for it
+type collectionLock struct {
+	mu      sync.Mutex
+	holders int
+}
+
+func newCollections() *collections {
+	return &collections{
+		collected:  make(map[string]string),
+		inProgress: make(map[string]bool),
+		locks:      make(map[horizon.CollectionID]*collectionLock),
+	}
+}
+
+func (c *collections) isCollected(sessionID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, found := c.collected[sessionID]
+	return found
+}
+
+// begin marks sessionID as being collected, false when it already is or was collected
+func (c *collections) begin(sessionID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, found := c.collected[sessionID]; found || c.inProgress[sessionID] {
+		return false
+	}
+	c.inProgress[sessionID] = true
+	return true
+}
+
+// done ends the collection of sessionID, recording txHash when it succeeded
+func (c *collections) done(sessionID string, txHash string, succeeded bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.inProgress, sessionID)
+	if succeeded {
+		c.collected[sessionID] = txHash
+	}
+}
+
+// forget drops what is known of the collection of sessionID, pruned
+func (c *collections) forget(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.collected, sessionID)
+}
+
+// lockCollection waits for the other collections of collectionID to end and
+// returns the function ending this one
+func (c *collections) lockCollection(collectionID horizon.CollectionID) (unlock func()) {
+	c.mu.Lock()
+	lock, found := c.locks[collectionID]
+	if !found {
+		lock = &collectionLock{}
+		c.locks[collectionID] = lock
+	}
+	lock.holders++
+	c.mu.Unlock()
+
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		lock.holders--
+		if lock.holders == 0 {
+			delete(c.locks, collectionID)
+		}
+	}
+}
+
+// pendingCollections returns the ended sessions holding a non-zero final RAV
+// that was not collected yet nor moved to the dead-letter queue, oldest first
+func (s *Sidecar) pendingCollections() []*sidecar.Session {
+	var out []*sidecar.Session
+	endedAt := make(map[*sidecar.Session]time.Time)
+	for _, session := range s.sessions.List() {
+		snapshot := session.Snapshot()
+		if snapshot.State != sidecar.SessionStateEnded || snapshot.EndedAt == nil {
+			continue
+		}
+
+		rav := session.GetRAV()
+		if rav == nil || rav.Message == nil || rav.Message.ValueAggregate == nil || rav.Message.ValueAggregate.Sign() <= 0 {
+			continue
+		}
+
+		if s.collections.isCollected(session.ID) {
+			continue
+		}
+		if s.collectRetries != nil && s.collectRetries.isDeadLettered(session.ID) {
+			continue
+		}
+		out = append(out, session)
+		endedAt[session] = *snapshot.EndedAt
+	}
+
+	slices.SortFunc(out, func(a, b *sidecar.Session) int {
+		return endedAt[a].Compare(endedAt[b])
+	})
+	return out
+}
+
+// PendingRAV is a final RAV awaiting on-chain collection as served by the admin
+// API, its value is rendered in the configured amount unit
+type PendingRAV struct {
+	SessionID      string    `json:"session_id"`
+	Payer          string    `json:"payer"`
+	DataService    string    `json:"data_service"`
+	CollectionID   string    `json:"collection_id"`
+	TimestampNs    uint64    `json:"timestamp_ns"`
+	ValueAggregate string    `json:"value_aggregate"`
+	PaymentType    string    `json:"payment_type"`
+	EndedAt        time.Time `json:"ended_at"`
+}
+
+// CollectRequest selects the pending RAVs to collect, either by session ID or all of them
+type CollectRequest struct {
+	SessionIDs []string `json:"session_ids,omitempty"`
+	All        bool     `json:"all,omitempty"`
+	DryRun     bool     `json:"dry_run,omitempty"`
+}
+
+// CollectResult is the outcome of collecting (or simulating the collection of)
+// one pending RAV, GRT amounts are rendered in the configured amount unit while
+// gas price and fee are always in wei. Gas, GasPrice and Fee are estimates,
+// GasUsed and FeePaid what the mined transaction was charged. NothingToCollect
+// is set when the RAV value was already collected, e.g. by another final RAV of
+// the same collection, no transaction is then sent.
+type CollectResult struct {
+	SessionID        string `json:"session_id"`
+	ValueAggregate   string `json:"value_aggregate,omitempty"`
+	AlreadyCollected string `json:"already_collected,omitempty"`
+	NothingToCollect bool   `json:"nothing_to_collect,omitempty"`
+	TokensDelta      string `json:"tokens_delta,omitempty"`
+	Gas              uint64 `json:"gas,omitempty"`
+	GasPrice         string `json:"gas_price,omitempty"`
+	Fee              string `json:"fee,omitempty"`
+	TxHash           string `json:"tx_hash,omitempty"`
+	GasUsed          uint64 `json:"gas_used,omitempty"`
+	FeePaid          string `json:"fee_paid,omitempty"`
+	Error            string `json:"error,omitempty"`
+}
+
+// adminHandlers registers the collection endpoints on the admin server:
+//   - GET /v1/collections/pending: lists final RAVs awaiting on-chain collection
+//   - POST /v1/collections/collect: collects the selected pending RAVs, or only
+//     estimates gas and token deltas when dry_run is set
+//   - GET /v1/collections/gas-spend: totals the gas and fees of the collect
+//     transactions sent, per collection and per payer
+//   - GET /v1/collections/redeemability: lists the last simulated collection of
+//     each active collection's current RAV, when enabled
+//   - GET /v1/payers/stats: aggregates the sessions of each payer, value signed,
+//     collected and disputed and average session length, see PayerStats
+//   - GET /metrics: Prometheus metrics
+//
+// And the session migration endpoints, for incident recovery without shared storage:
+//   - GET /v1/sessions/{id}/export: exports a session as a blob signed with the
+//     identity key
+//   - POST /v1/sessions/import: imports a session exported by another instance
+//
+// And, when a quarantine policy is configured, the RAV quarantine review
+// endpoints, see quarantineHandlers. When failed collections are retried, the
+// dead-letter queue endpoints, see deadLetterHandlers.
+func (s *Sidecar) adminHandlers(admin *sidecar.AdminServer) {
+	admin.Handle("GET /v1/collections/pending", http.HandlerFunc(s.handleAdminPendingCollections))
+	admin.Handle("POST /v1/collections/collect", http.HandlerFunc(s.handleAdminCollect))
+	admin.Handle("GET /v1/collections/gas-spend", http.HandlerFunc(s.handleAdminGasSpend))
+	admin.Handle("GET /v1/payers/stats", http.HandlerFunc(s.handleAdminPayerStats))
+	admin.Handle("GET /metrics", s.metricsHandler())
+	admin.Handle("GET /v1/sessions/{id}/export", http.HandlerFunc(s.handleAdminExportSession))
+	admin.Handle("POST /v1/sessions/import", http.HandlerFunc(s.handleAdminImportSession))
+
+	if s.redeemability != nil {
+		admin.Handle("GET /v1/collections/redeemability", http.HandlerFunc(s.handleAdminRedeemability))
+	}
+	if s.quarantine != nil {
+		s.quarantineHandlers(admin)
+	}
+	if s.collectRetries != nil {
+		s.deadLetterHandlers(admin)
+	}
+}
+
+func (s *Sidecar) handleAdminPendingCollections(w http.ResponseWriter, r *http.Request) {
+	pending := s.pendingCollections()
+
+	out := make([]*PendingRAV, 0, len(pending))
+	for _, session := range pending {
+		out = append(out, newPendingRAV(session, s.display, s.paymentTypes))
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]any{"amount_unit": s.display.UnitName(), "pending": out})
+}
+
+func (s *Sidecar) handleAdminCollect(w http.ResponseWriter, r *http.Request) {
+	var req CollectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body: " + err.Error()})
+		return
+	}
+	if !req.All && len(req.SessionIDs) == 0 {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "either session_ids or all must be set"})
+		return
+	}
+	if s.ravCollector == nil {
+		s.writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "collection is not configured, an RPC endpoint and data service address are required"})
+		return
+	}
+	if !req.DryRun && !s.ravCollector.CanSend() {
+		s.writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "no collect key configured, only dry runs are possible"})
+		return
+	}
+
+	pending := s.pendingCollections()
+	if !req.All {
+		byID := make(map[string]*sidecar.Session, len(pending))
+		for _, session := range pending {
+			byID[session.ID] = session
+		}
+
+		selected := make([]*sidecar.Session, 0, len(req.SessionIDs))
+		for _, id := range req.SessionIDs {
+			session, found := byID[id]
+			if !found {
+				s.writeJSON(w, http.StatusNotFound, map[string]string{"error": "no pending RAV for session " + id})
+				return
+			}
+			selected = append(selected, session)
+		}
+		pending = selected
+	}
+
+	results := make([]*CollectResult, 0, len(pending))
+	for _, session := range pending {
+		results = append(results, s.collectSession(r.Context(), session, req.DryRun))
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]any{"amount_unit": s.display.UnitName(), "dry_run": req.DryRun, "results": results})
+}
+
+func (s *Sidecar) collectSession(ctx context.Context, session *sidecar.Session, dryRun bool) *CollectResult {
+	rav := session.GetRAV()
+	result := &CollectResult{
+		SessionID:      session.ID,
+		ValueAggregate: s.display.Format(rav.Message.ValueAggregate),
+	}
+
+	var estimate *sidecar.CollectEstimate
+	var err error
+	if dryRun {
+		estimate, err = s.ravCollector.Estimate(ctx, rav)
+	} else {
+		if !s.collections.begin(session.ID) {
+			result.Error = "collection already in progress"
+			return result
+		}
+
+		unlock := s.collections.lockCollection(rav.Message.CollectionID)
+		var receipt *sidecar.CollectReceipt
+		receipt, estimate, err = s.ravCollector.Collect(ctx, rav)
+		unlock()
+
+		// Another final RAV of the collection, worth as much or more, was
+		// collected first, there is nothing left to collect for this one
+		if errors.Is(err, sidecar.ErrNothingToCollect) {
+			s.logger.Info("final RAV already collected", zap.String("session_id", session.ID), s.display.Field("already_collected", estimate.AlreadyCollected))
+			result.NothingToCollect = true
+			err = nil
+		}
+
+		txHash := ""
+		if receipt != nil {
+			txHash = receipt.TxHash
+			s.gasSpend.record(rav.Message, receipt, estimate.TokensDelta, err == nil, time.Now())
+
+			result.TxHash = receipt.TxHash
+			if receipt.Mined {
+				result.GasUsed = receipt.GasUsed
+				result.FeePaid = receipt.Fee().String()
+			}
+		}
+		s.collections.done(session.ID, txHash, err == nil)
+		if err == nil {
+			s.persistSession(session)
+			if s.uncollected != nil {
+				s.uncollected.record(rav.Message.CollectionID, rav.Message.ValueAggregate)
+			}
+		}
+
+		if s.collectRetries != nil {
+			if err == nil {
+				s.collectRetries.succeeded(session.ID)
+			} else {
+				s.recordCollectFailure(session, rav, receipt, err)
+			}
+		}
+
+		if err == nil && receipt != nil {
+			s.logger.Info("collected final RAV",
+				zap.String("session_id", session.ID),
+				zap.String("tx_hash", txHash),
+				s.display.Field("tokens_delta", estimate.TokensDelta),
+				zap.Uint64("gas_used", receipt.GasUsed),
+				zap.Stringer("fee_paid", receipt.Fee()),
+			)
+		}
+	}
+
+	if estimate != nil {
+		result.AlreadyCollected = s.display.Format(estimate.AlreadyCollected)
+		result.TokensDelta = s.display.Format(estimate.TokensDelta)
+		result.Gas = estimate.Gas
+		result.GasPrice = estimate.GasPrice.String()
+		result.Fee = estimate.Fee().String()
+	}
+	if err != nil {
+		s.logger.Warn("collecting final RAV failed", zap.String("session_id", session.ID), zap.Error(err))
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+func newPendingRAV(session *sidecar.Session, display *sidecar.AmountDisplay, paymentTypes *sidecar.PaymentTypes) *PendingRAV {
+	rav := session.GetRAV().Message
+	paymentType, _ := horizon.PaymentTypeName(paymentTypes.Of(rav.CollectionID))
+
+	out := &PendingRAV{
+		SessionID:      session.ID,
+		Payer:          rav.Payer.Pretty(),
+		DataService:    rav.DataService.Pretty(),
+		CollectionID:   eth.Hash(rav.CollectionID[:]).Pretty(),
+		TimestampNs:    rav.TimestampNs,
+		ValueAggregate: display.Format(rav.ValueAggregate),
+		PaymentType:    paymentType,
+	}
+	// The session may have been resumed since it was listed as pending
+	if endedAt := session.Snapshot().EndedAt; endedAt != nil {
+		out.EndedAt = *endedAt
+	}
+	return out
+}
@@ -0,0 +1,382 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/streamingfast/eth-go"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultCollectMaxAttempts is the number of failed collect attempts after
+	// which a final RAV is moved to the dead-letter queue
+	DefaultCollectMaxAttempts = 5
+	// DefaultCollectRetryBackoff is the delay before retrying a failed collection
+	DefaultCollectRetryBackoff = time.Minute
+
+	// maxCollectRetryBackoff bounds the delay between retries, which doubles
+	// after each failed attempt
+	maxCollectRetryBackoff = time.Hour
+	// collectRetryCheckInterval is how often collections due for a retry are looked for
+	collectRetryCheckInterval = 10 * time.Second
+)
+
+// ErrDeadLetterNotFound is returned when retrying a session whose collection is
+// not in the dead-letter queue
+var ErrDeadLetterNotFound = errors.New("no dead-lettered collection for session")
+
+// CollectRetryPolicy controls the automatic retry of final RAV collections that
+// failed. Collections failing MaxAttempts times in a row are moved to a
+// dead-letter queue instead of being retried forever, until an operator
+// requeues them through the admin API. Only deterministic failures count
+// toward MaxAttempts: reverts, whether on estimation or on-chain, and RAVs
+// with nothing left to collect. Transient ones, such as an unreachable chain
+// RPC, are retried without limit.
+type CollectRetryPolicy struct {
+	// MaxAttempts is the number of counted failed attempts after which a
+	// collection is dead-lettered, DefaultCollectMaxAttempts when zero
+	MaxAttempts int
+	// Backoff is the delay before the first retry, doubled after each counted
+	// failed attempt up to an hour, DefaultCollectRetryBackoff when zero
+	Backoff time.Duration
+}
+
+// collectFailure tracks the failed collect attempts of a session's final RAV
+type collectFailure struct {
+	sessionID      string
+	signedRAV      *horizon.SignedRAV
+	attempts       int
+	lastError      string
+	reasonName     string
+	firstFailedAt  time.Time
+	lastAttemptAt  time.Time
+	nextAttemptAt  time.Time
+	deadLetteredAt time.Time
+}
+
+func (f *collectFailure) deadLettered() bool {
+	return !f.deadLetteredAt.IsZero()
+}
+
+// collectRetries schedules the retries of failed collections and holds the
+// dead-letter queue, exported as the sds_provider_collections_dead_lettered
+// gauge
+type collectRetries struct {
+	maxAttempts int
+	backoff     time.Duration
+
+	mu       sync.Mutex
+	failures map[string]*collectFailure // session ID -> failure
+
+	deadLetteredGauge prometheus.Gauge
+	retries           *prometheus.CounterVec
+}
+
+func newCollectRetries(policy *CollectRetryPolicy, registry *prometheus.Registry) *collectRetries {
+	r := &collectRetries{
+		maxAttempts: policy.MaxAttempts,
+		backoff:     policy.Backoff,
+		failures:    make(map[string]*collectFailure),
+		deadLetteredGauge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "sds_provider_collections_dead_lettered",
+			Help: "Final RAVs moved to the dead-letter queue after repeatedly failing to be collected, awaiting operator action",
+		}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "sds_provider_collect_retries_total",
+			Help: "Automatic retries of failed collections, status is 'succeeded' or 'failed'",
+		}, []string{"status"}),
+	}
+	if r.maxAttempts <= 0 {
+		r.maxAttempts = DefaultCollectMaxAttempts
+	}
+	if r.backoff <= 0 {
+		r.backoff = DefaultCollectRetryBackoff
+	}
+
+	if registry != nil {
+		registry.MustRegister(r.deadLetteredGauge, r.retries)
+	}
+	return r
+}
+
+// failed records a failed collect attempt of signedRAV, counted toward the
+// maximum number of attempts or not, and schedules its retry. It returns a copy
+// of the failure and reports whether the collection was moved to the
+// dead-letter queue because of it.
+func (r *collectRetries) failed(sessionID string, signedRAV *horizon.SignedRAV, reason, reasonName string, counted bool, now time.Time) (collectFailure, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	failure, found := r.failures[sessionID]
+	if !found {
+		failure = &collectFailure{sessionID: sessionID, firstFailedAt: now}
+		r.failures[sessionID] = failure
+	}
+
+	failure.signedRAV = signedRAV
+	failure.lastError = reason
+	failure.reasonName = reasonName
+	failure.lastAttemptAt = now
+	if counted {
+		failure.attempts++
+	}
+	failure.nextAttemptAt = now.Add(r.backoffAfter(failure.attempts))
+
+	deadLettered := false
+	if failure.attempts >= r.maxAttempts && !failure.deadLettered() {
+		failure.deadLetteredAt = now
+		deadLettered = true
+		r.updateGaugeLocked()
+	}
+	return *failure, deadLettered
+}
+
+// backoffAfter returns the delay before retrying a collection that failed
+// attempts counted times
+func (r *collectRetries) backoffAfter(attempts int) time.Duration {
+	backoff := r.backoff
+	for i := 1; i < attempts && backoff < maxCollectRetryBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxCollectRetryBackoff)
+}
+
+// succeeded forgets the failures of sessionID once collected
+func (r *collectRetries) succeeded(sessionID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.failures, sessionID)
+}
+
+// due returns the sessions whose failed collection should be retried, oldest
+// failure first
+func (r *collectRetries) due(now time.Time) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []*collectFailure
+	for _, failure := range r.failures {
+		if !failure.deadLettered() && !failure.nextAttemptAt.After(now) {
+			out = append(out, failure)
+		}
+	}
+	slices.SortFunc(out, func(a, b *collectFailure) int {
+		return a.firstFailedAt.Compare(b.firstFailedAt)
+	})
+
+	ids := make([]string, 0, len(out))
+	for _, failure := range out {
+		ids = append(ids, failure.sessionID)
+	}
+	return ids
+}
+
+func (r *collectRetries) isDeadLettered(sessionID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	failure, found := r.failures[sessionID]
+	return found && failure.deadLettered()
+}
+
+// deadLetters returns a copy of the dead-lettered collections, oldest first
+func (r *collectRetries) deadLetters() []collectFailure {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]collectFailure, 0)
+	for _, failure := range r.failures {
+		if failure.deadLettered() {
+			out = append(out, *failure)
+		}
+	}
+	slices.SortFunc(out, func(a, b collectFailure) int {
+		return a.deadLetteredAt.Compare(b.deadLetteredAt)
+	})
+	return out
+}
+
+// requeue takes the collection of sessionID out of the dead-letter queue, its
+// attempts are reset and it is retried right away
+func (r *collectRetries) requeue(sessionID string, now time.Time) (collectFailure, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	failure, found := r.failures[sessionID]
+	if !found || !failure.deadLettered() {
+		return collectFailure{}, fmt.Errorf("%w %s", ErrDeadLetterNotFound, sessionID)
+	}
+
+	requeued := *failure
+	failure.attempts = 0
+	failure.deadLetteredAt = time.Time{}
+	failure.nextAttemptAt = now
+	r.updateGaugeLocked()
+	return requeued, nil
+}
+
+// retain forgets the failures of sessions no longer pending collection, e.g.
+// collected through another instance. Dead-lettered collections are kept.
+func (r *collectRetries) retain(pending map[string]*sidecar.Session) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, failure := range r.failures {
+		if _, found := pending[id]; !found && !failure.deadLettered() {
+			delete(r.failures, id)
+		}
+	}
+}
+
+func (r *collectRetries) updateGaugeLocked() {
+	count := 0
+	for _, failure := range r.failures {
+		if failure.deadLettered() {
+			count++
+		}
+	}
+	r.deadLetteredGauge.Set(float64(count))
+}
+
+// collectFailureReason describes a failed collect attempt and reports whether
+// it counts toward the maximum number of attempts, see CollectRetryPolicy
+func collectFailureReason(receipt *sidecar.CollectReceipt, err error) (reason string, name string, counted bool) {
+	if errors.Is(err, sidecar.ErrNothingToCollect) || (receipt != nil && receipt.Mined) {
+		return err.Error(), "", true
+	}
+	return sidecar.RevertReason(err)
+}
+
+// recordCollectFailure schedules the retry of the failed collection of
+// session's final RAV, or moves it to the dead-letter queue
+func (s *Sidecar) recordCollectFailure(session *sidecar.Session, signedRAV *horizon.SignedRAV, receipt *sidecar.CollectReceipt, err error) {
+	reason, name, counted := collectFailureReason(receipt, err)
+	failure, deadLettered := s.collectRetries.failed(session.ID, signedRAV, reason, name, counted, time.Now())
+	if deadLettered {
+		s.logger.Warn("final RAV moved to the dead-letter queue, collecting it keeps failing",
+			zap.String("session_id", session.ID),
+			zap.Int("attempts", failure.attempts),
+			zap.String("reason", reason),
+		)
+	}
+}
+
+// watchCollectRetries retries the failed collections once their backoff
+// elapsed until the sidecar terminates
+func (s *Sidecar) watchCollectRetries() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.OnTerminating(func(_ error) {
+		cancel()
+	})
+
+	ticker := time.NewTicker(collectRetryCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.retryCollections(ctx)
+		}
+	}
+}
+
+// retryCollections collects again the final RAVs whose collection failed and
+// is due for a retry
+func (s *Sidecar) retryCollections(ctx context.Context) {
+	pending := s.pendingCollections()
+
+	byID := make(map[string]*sidecar.Session, len(pending))
+	for _, session := range pending {
+		byID[session.ID] = session
+	}
+	s.collectRetries.retain(byID)
+
+	for _, id := range s.collectRetries.due(time.Now()) {
+		session, found := byID[id]
+		if !found || ctx.Err() != nil {
+			continue
+		}
+
+		s.logger.Info("retrying failed collection of final RAV", zap.String("session_id", id))
+		result := s.collectSession(ctx, session, false)
+
+		status := "succeeded"
+		if result.Error != "" {
+			status = "failed"
+		}
+		s.collectRetries.retries.WithLabelValues(status).Inc()
+	}
+}
+
+// DeadLetteredCollection is a final RAV whose collection kept failing as served
+// by the admin API, its value is rendered in the configured amount unit
+type DeadLetteredCollection struct {
+	SessionID      string    `json:"session_id"`
+	Payer          string    `json:"payer"`
+	CollectionID   string    `json:"collection_id"`
+	ValueAggregate string    `json:"value_aggregate"`
+	Attempts       int       `json:"attempts"`
+	LastError      string    `json:"last_error"`
+	Reason         string    `json:"reason,omitempty"`
+	FirstFailedAt  time.Time `json:"first_failed_at"`
+	DeadLetteredAt time.Time `json:"dead_lettered_at"`
+}
+
+func (s *Sidecar) newDeadLetteredCollection(failure collectFailure) *DeadLetteredCollection {
+	rav := failure.signedRAV.Message
+	return &DeadLetteredCollection{
+		SessionID:      failure.sessionID,
+		Payer:          rav.Payer.Pretty(),
+		CollectionID:   eth.Hash(rav.CollectionID[:]).Pretty(),
+		ValueAggregate: s.display.Format(rav.ValueAggregate),
+		Attempts:       failure.attempts,
+		LastError:      failure.lastError,
+		Reason:         failure.reasonName,
+		FirstFailedAt:  failure.firstFailedAt,
+		DeadLetteredAt: failure.deadLetteredAt,
+	}
+}
+
+// deadLetterHandlers registers the dead-letter queue endpoints on the admin server:
+//   - GET /v1/collections/dead-letter: lists the collections that kept failing
+//   - POST /v1/collections/dead-letter/{session_id}/retry: requeues a dead-lettered
+//     collection, it is pending again and retried right away
+func (s *Sidecar) deadLetterHandlers(admin *sidecar.AdminServer) {
+	admin.Handle("GET /v1/collections/dead-letter", http.HandlerFunc(s.handleAdminDeadLetter))
+	admin.Handle("POST /v1/collections/dead-letter/{session_id}/retry", http.HandlerFunc(s.handleAdminRetryDeadLetter))
+}
+
+func (s *Sidecar) handleAdminDeadLetter(w http.ResponseWriter, r *http.Request) {
+	failures := s.collectRetries.deadLetters()
+
+	out := make([]*DeadLetteredCollection, 0, len(failures))
+	for _, failure := range failures {
+		out = append(out, s.newDeadLetteredCollection(failure))
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]any{"amount_unit": s.display.UnitName(), "dead_lettered": out})
+}
+
+func (s *Sidecar) handleAdminRetryDeadLetter(w http.ResponseWriter, r *http.Request) {
+	failure, err := s.collectRetries.requeue(r.PathValue("session_id"), time.Now())
+	if err != nil {
+		s.writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.logger.Info("dead-lettered collection requeued", zap.String("session_id", failure.sessionID))
+	s.writeJSON(w, http.StatusOK, map[string]any{"amount_unit": s.display.UnitName(), "requeued": s.newDeadLetteredCollection(failure)})
+}
@@ -0,0 +1,159 @@
+package sidecar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/streamingfast/eth-go"
+	"github.com/streamingfast/eth-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCollectRetries(t *testing.T) {
+	retries := newCollectRetries(&CollectRetryPolicy{MaxAttempts: 3, Backoff: time.Minute}, prometheus.NewRegistry())
+	rav := &horizon.SignedRAV{Message: &horizon.RAV{ValueAggregate: big.NewInt(100)}}
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	// Transient failures are retried without counting toward the attempts
+	failure, deadLettered := retries.failed("s1", rav, "connection refused", "", false, now)
+	assert.False(t, deadLettered)
+	assert.Equal(t, 0, failure.attempts)
+	assert.Equal(t, now.Add(time.Minute), failure.nextAttemptAt)
+
+	assert.Empty(t, retries.due(now))
+	assert.Equal(t, []string{"s1"}, retries.due(now.Add(time.Minute)))
+
+	// Counted failures back off exponentially until dead-lettered
+	failure, deadLettered = retries.failed("s1", rav, "reverted", "AuthorizableSignerNotAuthorized", true, now)
+	assert.False(t, deadLettered)
+	assert.Equal(t, now.Add(time.Minute), failure.nextAttemptAt)
+	failure, deadLettered = retries.failed("s1", rav, "reverted", "AuthorizableSignerNotAuthorized", true, now)
+	assert.False(t, deadLettered)
+	assert.Equal(t, now.Add(2*time.Minute), failure.nextAttemptAt)
+	failure, deadLettered = retries.failed("s1", rav, "reverted", "AuthorizableSignerNotAuthorized", true, now)
+	assert.True(t, deadLettered)
+	assert.Equal(t, 3, failure.attempts)
+
+	assert.True(t, retries.isDeadLettered("s1"))
+	assert.Empty(t, retries.due(now.Add(time.Hour)))
+
+	deadLetters := retries.deadLetters()
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, "AuthorizableSignerNotAuthorized", deadLetters[0].reasonName)
+
+	// Dead-lettered collections are kept when no longer pending
+	retries.failed("s2", rav, "reverted", "", true, now)
+	retries.retain(map[string]*sidecar.Session{})
+	assert.Equal(t, []string{"s1"}, failureIDs(retries))
+
+	_, err := retries.requeue("s2", now)
+	assert.ErrorIs(t, err, ErrDeadLetterNotFound)
+
+	requeued, err := retries.requeue("s1", now)
+	require.NoError(t, err)
+	assert.Equal(t, 3, requeued.attempts)
+	assert.False(t, retries.isDeadLettered("s1"))
+	assert.Equal(t, []string{"s1"}, retries.due(now))
+
+	retries.succeeded("s1")
+	assert.Empty(t, failureIDs(retries))
+
+	assert.Equal(t, time.Hour, retries.backoffAfter(20))
+}
+
+func failureIDs(r *collectRetries) (out []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id := range r.failures {
+		out = append(out, id)
+	}
+	return out
+}
+
+func TestCollectFailureReason(t *testing.T) {
+	_, _, counted := collectFailureReason(nil, fmt.Errorf("%w, 100 already collected", sidecar.ErrNothingToCollect))
+	assert.True(t, counted)
+
+	_, _, counted = collectFailureReason(&sidecar.CollectReceipt{TxHash: "0xabc", Mined: true}, errors.New("collect transaction 0xabc reverted"))
+	assert.True(t, counted)
+
+	_, _, counted = collectFailureReason(&sidecar.CollectReceipt{TxHash: "0xabc"}, errors.New("waiting for collect transaction 0xabc: context deadline exceeded"))
+	assert.False(t, counted)
+
+	_, _, counted = collectFailureReason(nil, errors.New("dial tcp: connection refused"))
+	assert.False(t, counted)
+
+	_, _, counted = collectFailureReason(nil, fmt.Errorf("estimating collect gas: %w", &rpc.ErrResponse{Code: 3, Message: "execution reverted", Data: "0x"}))
+	assert.True(t, counted)
+}
+
+func TestAdminDeadLetter(t *testing.T) {
+	payer := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+
+	s := New(&Config{
+		ListenAddr:      ":0",
+		ServiceProvider: serviceProvider,
+		Domain:          horizon.NewDomain(1337, eth.MustNewAddress("0x4444444444444444444444444444444444444444")),
+		AdminListenAddr: ":0",
+	}, zap.NewNop())
+
+	// Collection is not configured, retries are enabled by hand
+	s.collectRetries = newCollectRetries(&CollectRetryPolicy{MaxAttempts: 1}, s.metrics)
+	s.deadLetterHandlers(s.admin)
+
+	session := s.sessions.Create(payer, serviceProvider, dataService)
+	rav := &horizon.SignedRAV{Message: &horizon.RAV{
+		Payer:           payer,
+		DataService:     dataService,
+		ServiceProvider: serviceProvider,
+		ValueAggregate:  big.NewInt(1000),
+	}}
+	session.SetRAV(rav)
+	session.End(commonv1.EndReason_END_REASON_COMPLETE)
+
+	require.Len(t, s.pendingCollections(), 1)
+	s.recordCollectFailure(session, rav, &sidecar.CollectReceipt{TxHash: "0xabc", Mined: true}, errors.New("collect transaction 0xabc reverted"))
+	assert.Empty(t, s.pendingCollections())
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.admin.Handler().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader("")))
+		return rec
+	}
+
+	rec := serve(http.MethodGet, "/v1/collections/dead-letter")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var out struct {
+		DeadLettered []*DeadLetteredCollection `json:"dead_lettered"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+	require.Len(t, out.DeadLettered, 1)
+	assert.Equal(t, session.ID, out.DeadLettered[0].SessionID)
+	assert.Equal(t, "1000", out.DeadLettered[0].ValueAggregate)
+	assert.Equal(t, 1, out.DeadLettered[0].Attempts)
+	assert.Equal(t, "collect transaction 0xabc reverted", out.DeadLettered[0].LastError)
+
+	assert.Contains(t, serve(http.MethodGet, "/metrics").Body.String(), "sds_provider_collections_dead_lettered 1")
+
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/v1/collections/dead-letter/unknown/retry").Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/v1/collections/dead-letter/"+session.ID+"/retry").Code)
+	assert.Len(t, s.pendingCollections(), 1)
+	assert.Contains(t, serve(http.MethodGet, "/metrics").Body.String(), "sds_provider_collections_dead_lettered 0")
+}
@@ -0,0 +1,162 @@
+package sidecar
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestAdminPendingCollections(t *testing.T) {
+	payer := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+
+	s := New(&Config{
+		ListenAddr:      ":0",
+		ServiceProvider: serviceProvider,
+		Domain:          horizon.NewDomain(1337, eth.MustNewAddress("0x4444444444444444444444444444444444444444")),
+		AdminListenAddr: ":0",
+	}, zap.NewNop())
+
+	newSession := func(value int64, ended bool) string {
+		session := s.sessions.Create(payer, serviceProvider, dataService)
+		session.SetRAV(&horizon.SignedRAV{Message: &horizon.RAV{
+			Payer:           payer,
+			DataService:     dataService,
+			ServiceProvider: serviceProvider,
+			ValueAggregate:  big.NewInt(value),
+		}})
+		if ended {
+			session.End(commonv1.EndReason_END_REASON_COMPLETE)
+		}
+		return session.ID
+	}
+
+	pendingID := newSession(1000, true)
+	collectedID := newSession(2000, true)
+	newSession(3000, false)
+	newSession(0, true)
+
+	require.True(t, s.collections.begin(collectedID))
+	s.collections.done(collectedID, "0xabc", true)
+	assert.False(t, s.collections.begin(collectedID))
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.admin.Handler().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := serve(http.MethodGet, "/v1/collections/pending", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var out struct {
+		Pending []*PendingRAV `json:"pending"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+	require.Len(t, out.Pending, 1)
+	assert.Equal(t, pendingID, out.Pending[0].SessionID)
+	assert.Equal(t, "1000", out.Pending[0].ValueAggregate)
+	assert.Equal(t, payer.Pretty(), out.Pending[0].Payer)
+
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/v1/collections/collect", `{}`).Code)
+	assert.Equal(t, http.StatusServiceUnavailable, serve(http.MethodPost, "/v1/collections/collect", `{"all":true,"dry_run":true}`).Code)
+}
+
+func TestAutoCollect_QueuesEndedSessions(t *testing.T) {
+	payer := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	collectKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	s := New(&Config{
+		ListenAddr:      ":0",
+		ServiceProvider: serviceProvider,
+		Domain:          horizon.NewDomain(1337, eth.MustNewAddress("0x4444444444444444444444444444444444444444")),
+		RPCEndpoint:     "http://127.0.0.1:0",
+		DataServiceAddr: dataService,
+		CollectorAddr:   eth.MustNewAddress("0x4444444444444444444444444444444444444444"),
+		CollectKey:      collectKey,
+		AutoCollect:     true,
+	}, zap.NewNop())
+	require.NotNil(t, s.autoCollect)
+
+	endSession := func(value int64) string {
+		session := s.sessions.Create(payer, serviceProvider, dataService)
+		session.SetRAV(&horizon.SignedRAV{Message: &horizon.RAV{
+			Payer:           payer,
+			DataService:     dataService,
+			ServiceProvider: serviceProvider,
+			ValueAggregate:  big.NewInt(value),
+		}})
+		_, err := s.EndSession(context.Background(), connect.NewRequest(&providerv1.EndSessionRequest{
+			SessionId: session.ID,
+			Reason:    commonv1.EndReason_END_REASON_COMPLETE,
+		}))
+		require.NoError(t, err)
+		return session.ID
+	}
+
+	queuedID := endSession(1000)
+	endSession(0)
+
+	require.Len(t, s.autoCollect, 1)
+	assert.Equal(t, queuedID, (<-s.autoCollect).ID)
+
+	// Without a key to send collect transactions, nothing is collected automatically
+	disabled := New(&Config{
+		ListenAddr:      ":0",
+		ServiceProvider: serviceProvider,
+		Domain:          horizon.NewDomain(1337, eth.MustNewAddress("0x4444444444444444444444444444444444444444")),
+		AutoCollect:     true,
+	}, zap.NewNop())
+	assert.Nil(t, disabled.autoCollect)
+}
+
+func TestCollections_LockCollection(t *testing.T) {
+	c := newCollections()
+	first := horizon.CollectionID{0x01}
+
+	unlock := c.lockCollection(first)
+
+	// Another collection ID is not held up
+	c.lockCollection(horizon.CollectionID{0x02})()
+
+	locked := make(chan struct{})
+	go func() {
+		defer close(locked)
+		c.lockCollection(first)()
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("collection of the same collection ID did not wait")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("collection of the same collection ID never started")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	assert.Empty(t, c.locks, "locks are dropped once released")
+}
@@ -0,0 +1,131 @@
+package sidecar
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+)
+
+// DefaultDegradedGrace is how long the sidecar keeps serving cached escrow
+// balances after losing the chain RPC
+const DefaultDegradedGrace = 5 * time.Minute
+
+// escrowBalances caches the escrow balances last read from chain so that an
+// unreachable chain RPC degrades the sidecar instead of failing it: existing
+// sessions keep being served from the cached balances until the grace window,
+// counted from the last successful chain query, elapses.
+type escrowBalances struct {
+	mu          sync.Mutex
+	grace       time.Duration
+	balances    map[string]*big.Int
+	lastSuccess time.Time
+	lastError   error
+
+	now func() time.Time
+}
+
+func newEscrowBalances(grace time.Duration) *escrowBalances {
+	if grace <= 0 {
+		grace = DefaultDegradedGrace
+	}
+
+	return &escrowBalances{
+		grace:    grace,
+		balances: make(map[string]*big.Int),
+		now:      time.Now,
+	}
+}
+
+// record caches balance, read from chain for payer, and reports whether the
+// sidecar left degraded mode because of it
+func (b *escrowBalances) record(payer eth.Address, balance *big.Int) (recovered bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	recovered = b.lastError != nil
+	b.balances[payer.Pretty()] = balance
+	b.reachableLocked()
+	return recovered
+}
+
+// reachable records a successful chain query
+func (b *escrowBalances) reachable() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.reachableLocked()
+}
+
+func (b *escrowBalances) reachableLocked() {
+	b.lastSuccess = b.now()
+	b.lastError = nil
+}
+
+// unreachable records a failed chain query and reports whether the sidecar
+// entered degraded mode because of it
+func (b *escrowBalances) unreachable(err error) (entered bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entered = b.lastError == nil && b.withinGraceLocked()
+	b.lastError = err
+	return entered
+}
+
+// cached returns the cached balance of payer while within the grace window
+func (b *escrowBalances) cached(payer eth.Address) (*big.Int, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	balance, found := b.balances[payer.Pretty()]
+	if !found || !b.withinGraceLocked() {
+		return nil, false
+	}
+	return balance, true
+}
+
+// degraded reports whether the last chain query failed while cached balances
+// are still being served
+func (b *escrowBalances) degraded() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.lastError != nil && b.withinGraceLocked()
+}
+
+func (b *escrowBalances) withinGraceLocked() bool {
+	return !b.lastSuccess.IsZero() && b.now().Sub(b.lastSuccess) <= b.grace
+}
+
+// tolerate wraps a chain-dependent readiness check so that its failures only
+// flag the sidecar as degraded while within the grace window. A probe check
+// tells whether the chain RPC is reachable, other checks cannot tell an RPC
+// failure from a genuine one and are only tolerated while the chain RPC is
+// known to be unreachable.
+func (b *escrowBalances) tolerate(check sidecar.ReadinessCheck, probe bool) sidecar.ReadinessCheck {
+	return sidecar.ReadinessCheck{
+		Name: check.Name,
+		Check: func(ctx context.Context) error {
+			err := check.Check(ctx)
+			if err == nil {
+				if probe {
+					b.reachable()
+				}
+				return nil
+			}
+
+			if probe {
+				b.unreachable(err)
+			}
+			if b.degraded() {
+				return fmt.Errorf("%w: %w, serving cached escrow balances", sidecar.ErrDegraded, err)
+			}
+			return err
+		},
+	}
+}
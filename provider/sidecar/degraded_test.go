@@ -0,0 +1,86 @@
+package sidecar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSidecar_DegradedMode(t *testing.T) {
+	var down atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			http.Error(w, "unavailable", http.StatusBadGateway)
+			return
+		}
+
+		var req struct {
+			ID json.RawMessage `json:"id"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x%064x"}`, req.ID, 500)
+	}))
+	defer server.Close()
+
+	s := New(&Config{
+		ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		Domain:          horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+		RPCEndpoint:     server.URL,
+		EscrowAddr:      eth.MustNewAddress("0x4444444444444444444444444444444444444444"),
+		DegradedGrace:   time.Minute,
+	}, zap.NewNop())
+
+	now := time.Now()
+	s.escrowBalances.now = func() time.Time { return now }
+
+	payer := eth.MustNewAddress("0x5555555555555555555555555555555555555555")
+	session := s.sessions.Create(payer, s.serviceProvider, eth.MustNewAddress("0x2222222222222222222222222222222222222222"))
+
+	status := func() *providerv1.GetSessionStatusResponse {
+		resp, err := s.GetSessionStatus(context.Background(), connect.NewRequest(&providerv1.GetSessionStatusRequest{SessionId: session.ID}))
+		require.NoError(t, err)
+		return resp.Msg
+	}
+
+	first := status()
+	assert.False(t, first.Degraded)
+	assert.Equal(t, big.NewInt(500), first.PaymentStatus.EscrowBalance.ToNative())
+
+	// Within the grace window, the cached balance keeps the session served
+	down.Store(true)
+	now = now.Add(30 * time.Second)
+	degraded := status()
+	assert.True(t, degraded.Degraded)
+	assert.Equal(t, big.NewInt(500), degraded.PaymentStatus.EscrowBalance.ToNative())
+	assert.True(t, degraded.PaymentStatus.FundsSufficient)
+
+	// Past it, chain failures surface again
+	now = now.Add(time.Minute)
+	expired := status()
+	assert.False(t, expired.Degraded)
+	assert.Equal(t, big.NewInt(0), expired.PaymentStatus.EscrowBalance.ToNative())
+
+	_, err := s.GetEscrowBalance(context.Background(), payer)
+	assert.Error(t, err)
+
+	// Recovery leaves degraded mode
+	down.Store(false)
+	balance, err := s.GetEscrowBalance(context.Background(), payer)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(500), balance)
+	assert.False(t, s.Degraded())
+}
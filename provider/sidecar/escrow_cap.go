@@ -0,0 +1,86 @@
+package sidecar
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/streamingfast/eth-go"
+	"go.uber.org/zap"
+)
+
+// DefaultEscrowCapRefresh is how long an escrow balance snapshot bounds RAVs
+// before being refreshed from chain
+const DefaultEscrowCapRefresh = 30 * time.Second
+
+// escrowCaps bounds RAV value aggregates by a snapshot of each payer's escrow
+// balance, refreshed from chain once older than the refresh period. Without it
+// a payer is only found to be underfunded after the fact, when the RAV value
+// already exceeds what can be collected.
+type escrowCaps struct {
+	mu        sync.Mutex
+	refresh   time.Duration
+	snapshots map[string]escrowSnapshot
+
+	fetch func(ctx context.Context, payer eth.Address) (*big.Int, error)
+	now   func() time.Time
+}
+
+type escrowSnapshot struct {
+	balance   *big.Int
+	fetchedAt time.Time
+}
+
+func newEscrowCaps(
+	refresh time.Duration,
+	fetch func(ctx context.Context, payer eth.Address) (*big.Int, error),
+) *escrowCaps {
+	if refresh <= 0 {
+		refresh = DefaultEscrowCapRefresh
+	}
+
+	return &escrowCaps{
+		refresh:   refresh,
+		snapshots: make(map[string]escrowSnapshot),
+		fetch:     fetch,
+		now:       time.Now,
+	}
+}
+
+// balance returns the escrow balance snapshot of payer, refreshing it when
+// stale. The last snapshot is kept when the refresh fails, nil is returned when
+// there is none yet.
+func (c *escrowCaps) balance(ctx context.Context, payer eth.Address, logger *zap.Logger) *big.Int {
+	key := payer.Pretty()
+
+	c.mu.Lock()
+	snapshot, found := c.snapshots[key]
+	c.mu.Unlock()
+
+	if found && c.now().Sub(snapshot.fetchedAt) < c.refresh {
+		return snapshot.balance
+	}
+	return c.refreshBalance(ctx, payer, logger)
+}
+
+// refreshBalance reads the escrow balance of payer from chain regardless of the
+// age of its snapshot, the last snapshot is kept and returned when it fails
+func (c *escrowCaps) refreshBalance(ctx context.Context, payer eth.Address, logger *zap.Logger) *big.Int {
+	key := payer.Pretty()
+
+	balance, err := c.fetch(ctx, payer)
+	if err != nil || balance == nil {
+		logger.Warn("failed to refresh escrow balance snapshot, using last snapshot", zap.Stringer("payer", payer), zap.Error(err))
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.snapshots[key].balance
+	}
+
+	c.mu.Lock()
+	c.snapshots[key] = escrowSnapshot{balance: balance, fetchedAt: c.now()}
+	c.mu.Unlock()
+
+	return balance
+}
@@ -0,0 +1,50 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestEscrowCaps_Balance(t *testing.T) {
+	payer := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
+
+	balance := big.NewInt(1000)
+	var fetchErr error
+	fetches := 0
+	caps := newEscrowCaps(time.Minute, func(ctx context.Context, p eth.Address) (*big.Int, error) {
+		fetches++
+		return balance, fetchErr
+	})
+	now := time.Now()
+	caps.now = func() time.Time { return now }
+
+	get := func(payer eth.Address) *big.Int {
+		return caps.balance(context.Background(), payer, zap.NewNop())
+	}
+
+	assert.Equal(t, int64(1000), get(payer).Int64())
+	assert.Equal(t, int64(1000), get(payer).Int64())
+	assert.Equal(t, 1, fetches)
+
+	// A deposit is only seen once the snapshot is refreshed
+	balance = big.NewInt(5000)
+	assert.Equal(t, int64(1000), get(payer).Int64())
+	now = now.Add(time.Minute)
+	assert.Equal(t, int64(5000), get(payer).Int64())
+	assert.Equal(t, 2, fetches)
+
+	// The last snapshot still applies when the refresh fails
+	now = now.Add(time.Minute)
+	fetchErr = errors.New("rpc unavailable")
+	assert.Equal(t, int64(5000), get(payer).Int64())
+
+	// Without any snapshot there is no balance to bound RAVs by
+	assert.Nil(t, get(eth.MustNewAddress("0x4444444444444444444444444444444444444444")))
+}
@@ -0,0 +1,92 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"go.uber.org/zap"
+)
+
+// DefaultEscrowBalanceTTL is how long an escrow balance read from chain is
+// trusted by the escrow watch before being read again
+const DefaultEscrowBalanceTTL = 15 * time.Second
+
+// errEscrowExhausted stops sessions whose usage the payer's escrow no longer
+// covers
+var errEscrowExhausted = errors.New("escrow balance does not cover session usage")
+
+// escrowWatch decides whether streaming continues from the live escrow balance
+// of each payer. The balance is read from chain when a session starts and
+// refreshed once older than the TTL while usage is reported, so a payer thawing
+// or withdrawing its escrow mid-stream is noticed within the TTL instead of
+// when the final RAV fails to collect.
+type escrowWatch struct {
+	balances *escrowCaps
+}
+
+func newEscrowWatch(ttl time.Duration, fetch func(ctx context.Context, payer eth.Address) (*big.Int, error)) *escrowWatch {
+	if ttl <= 0 {
+		ttl = DefaultEscrowBalanceTTL
+	}
+
+	return &escrowWatch{balances: newEscrowCaps(ttl, fetch)}
+}
+
+// start reads the escrow balance of payer from chain as a session starts,
+// whatever the age of the cached one
+func (w *escrowWatch) start(ctx context.Context, payer eth.Address, logger *zap.Logger) *big.Int {
+	return w.balances.refreshBalance(ctx, payer, logger)
+}
+
+// check rejects session when what it owes, the highest of its accumulated
+// usage cost and its current RAV value, less the value of its collection
+// already collected (nil for none), exceeds the payer's escrow balance. Sessions
+// are not stopped while no balance could be read, an unreachable chain RPC must
+// not stop paid streams.
+func (w *escrowWatch) check(ctx context.Context, session *sidecar.Session, collected *big.Int, logger *zap.Logger) error {
+	balance := w.balances.balance(ctx, session.Payer, logger)
+	if balance == nil {
+		return nil
+	}
+
+	owed := session.GetUsage().Cost.ToNative()
+	if rav := ravValue(session.GetRAV()); rav.Cmp(owed) > 0 {
+		owed = rav
+	}
+	if collected != nil {
+		owed = new(big.Int).Sub(owed, collected)
+	}
+
+	if owed.Cmp(balance) > 0 {
+		return fmt.Errorf("%w: owes %s, escrow balance is %s", errEscrowExhausted, owed, balance)
+	}
+	return nil
+}
+
+// checkEscrowBalance rejects session when the payer's live escrow balance no
+// longer covers it, it always passes when the escrow watch is disabled
+func (s *Sidecar) checkEscrowBalance(ctx context.Context, session *sidecar.Session) error {
+	if s.escrowWatch == nil {
+		return nil
+	}
+
+	var collected *big.Int
+	if rav := session.GetRAV(); rav != nil && rav.Message != nil {
+		collected = s.collectedMidSession(rav.Message.CollectionID)
+	}
+	return s.escrowWatch.check(ctx, session, collected, s.logger)
+}
+
+// startEscrowBalance reads the escrow balance of payer as one of its sessions
+// starts, priming the escrow watch when enabled
+func (s *Sidecar) startEscrowBalance(ctx context.Context, payer eth.Address) (*big.Int, error) {
+	if s.escrowWatch == nil {
+		return s.GetEscrowBalance(ctx, payer)
+	}
+	return s.escrowWatch.start(ctx, payer, s.logger), nil
+}
@@ -0,0 +1,103 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestReportUsage_EscrowWatch(t *testing.T) {
+	payer := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+
+	s := New(&Config{
+		ServiceProvider: serviceProvider,
+		Domain:          horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+	}, zap.NewNop())
+
+	balance := big.NewInt(1000)
+	var fetchErr error
+	fetches := 0
+	s.escrowWatch = newEscrowWatch(time.Minute, func(ctx context.Context, p eth.Address) (*big.Int, error) {
+		fetches++
+		return balance, fetchErr
+	})
+	now := time.Now()
+	s.escrowWatch.balances.now = func() time.Time { return now }
+
+	session := s.sessions.Create(payer, serviceProvider, eth.MustNewAddress("0x2222222222222222222222222222222222222222"))
+	report := func(cost int64) *providerv1.ReportUsageResponse {
+		resp, err := s.ReportUsage(context.Background(), connect.NewRequest(&providerv1.ReportUsageRequest{
+			SessionId: session.ID,
+			Usage:     &commonv1.Usage{BlocksProcessed: 1, Cost: commonv1.BigIntFromNative(big.NewInt(cost))},
+		}))
+		require.NoError(t, err)
+		return resp.Msg
+	}
+
+	// Starting the session always reads the balance from chain
+	started, err := s.startEscrowBalance(context.Background(), payer)
+	require.NoError(t, err)
+	assert.Equal(t, "1000", started.String())
+	assert.Equal(t, 1, fetches)
+
+	assert.True(t, report(600).ShouldContinue)
+	assert.Equal(t, 1, fetches, "balance is cached for the TTL")
+
+	// A withdrawal is noticed once the cached balance expires
+	balance = big.NewInt(500)
+	assert.True(t, report(100).ShouldContinue)
+	now = now.Add(time.Minute)
+	resp := report(0)
+	assert.False(t, resp.ShouldContinue)
+	assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_INSUFFICIENT_FUNDS, resp.StopCode)
+	assert.Contains(t, resp.StopReason, errEscrowExhausted.Error())
+	assert.Equal(t, 2, fetches)
+
+	// The last balance still applies when the chain RPC fails
+	now = now.Add(time.Minute)
+	fetchErr = errors.New("rpc unavailable")
+	assert.False(t, report(0).ShouldContinue)
+}
+
+func TestEscrowWatch_Check(t *testing.T) {
+	payer := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+
+	var balance *big.Int
+	watch := newEscrowWatch(0, func(ctx context.Context, p eth.Address) (*big.Int, error) {
+		if balance == nil {
+			return nil, errors.New("rpc unavailable")
+		}
+		return balance, nil
+	})
+
+	s := New(&Config{ServiceProvider: serviceProvider, Domain: horizon.NewDomain(1337, dataService)}, zap.NewNop())
+	session := s.sessions.Create(payer, serviceProvider, dataService)
+	session.AddUsage(10, 0, 0, big.NewInt(100))
+	session.SetRAV(&horizon.SignedRAV{Message: &horizon.RAV{Payer: payer, ValueAggregate: big.NewInt(300)}})
+
+	// Sessions are not stopped while no balance could be read
+	require.NoError(t, watch.check(context.Background(), session, nil, zap.NewNop()))
+
+	// The current RAV value counts when above the usage cost
+	balance = big.NewInt(299)
+	watch.start(context.Background(), payer, zap.NewNop())
+	assert.ErrorIs(t, watch.check(context.Background(), session, nil, zap.NewNop()), errEscrowExhausted)
+
+	balance = big.NewInt(300)
+	watch.start(context.Background(), payer, zap.NewNop())
+	require.NoError(t, watch.check(context.Background(), session, nil, zap.NewNop()))
+}
@@ -0,0 +1,197 @@
+package sidecar
+
+import (
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/streamingfast/eth-go"
+)
+
+// CollectionGasSpend totals the collect transactions sent for a collection as
+// served by the admin API. Gas price and fees are in wei, tokens collected are
+// rendered in the configured amount unit.
+type CollectionGasSpend struct {
+	CollectionID       string    `json:"collection_id"`
+	Payer              string    `json:"payer"`
+	Transactions       uint64    `json:"transactions"`
+	FailedTransactions uint64    `json:"failed_transactions"`
+	GasUsed            uint64    `json:"gas_used"`
+	Fee                string    `json:"fee"`
+	TokensCollected    string    `json:"tokens_collected"`
+	LastTxHash         string    `json:"last_tx_hash"`
+	LastCollectedAt    time.Time `json:"last_collected_at"`
+}
+
+// PayerGasSpend totals the collect transactions sent for all collections of a payer
+type PayerGasSpend struct {
+	Payer              string `json:"payer"`
+	Collections        int    `json:"collections"`
+	Transactions       uint64 `json:"transactions"`
+	FailedTransactions uint64 `json:"failed_transactions"`
+	GasUsed            uint64 `json:"gas_used"`
+	Fee                string `json:"fee"`
+	TokensCollected    string `json:"tokens_collected"`
+}
+
+type collectionGasSpend struct {
+	collectionID    horizon.CollectionID
+	payer           string
+	transactions    uint64
+	failed          uint64
+	gasUsed         uint64
+	fee             *big.Int
+	tokensCollected *big.Int
+	lastTxHash      string
+	lastCollectedAt time.Time
+}
+
+// gasSpend accounts the gas charged to the collect transactions sent by the
+// sidecar per (payer, collection), including reverted ones, and exports it as
+// the sds_provider_collect_* counters
+type gasSpend struct {
+	mu          sync.Mutex
+	collections map[string]*collectionGasSpend // payer/collection ID -> totals
+
+	transactions *prometheus.CounterVec
+	gasUsed      *prometheus.CounterVec
+	fees         *prometheus.CounterVec
+}
+
+func newGasSpend(registry *prometheus.Registry) *gasSpend {
+	g := &gasSpend{
+		collections: make(map[string]*collectionGasSpend),
+		transactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "sds_provider_collect_transactions_total",
+			Help: "Collect transactions sent, status is 'succeeded' or 'failed' (reverted or not mined in time)",
+		}, []string{"collection_id", "payer", "status"}),
+		gasUsed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "sds_provider_collect_gas_used_total",
+			Help: "Gas used by mined collect transactions, reverted ones included",
+		}, []string{"collection_id", "payer"}),
+		fees: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "sds_provider_collect_fee_wei_total",
+			Help: "Fees paid in wei by mined collect transactions, reverted ones included",
+		}, []string{"collection_id", "payer"}),
+	}
+
+	if registry != nil {
+		registry.MustRegister(g.transactions, g.gasUsed, g.fees)
+	}
+	return g
+}
+
+// record accounts a sent collect transaction of rav, tokensCollected is what
+// it transferred when it succeeded
+func (g *gasSpend) record(rav *horizon.RAV, receipt *sidecar.CollectReceipt, tokensCollected *big.Int, succeeded bool, at time.Time) {
+	payer := rav.Payer.Pretty()
+	key := redeemabilityKey(payer, rav.CollectionID)
+	fee := receipt.Fee()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	totals, found := g.collections[key]
+	if !found {
+		totals = &collectionGasSpend{
+			collectionID:    rav.CollectionID,
+			payer:           payer,
+			fee:             new(big.Int),
+			tokensCollected: new(big.Int),
+		}
+		g.collections[key] = totals
+	}
+
+	totals.transactions++
+	totals.gasUsed += receipt.GasUsed
+	totals.fee.Add(totals.fee, fee)
+	totals.lastTxHash = receipt.TxHash
+	totals.lastCollectedAt = at
+
+	status := "succeeded"
+	if succeeded {
+		totals.tokensCollected.Add(totals.tokensCollected, tokensCollected)
+	} else {
+		totals.failed++
+		status = "failed"
+	}
+
+	collectionID := eth.Hash(rav.CollectionID[:]).Pretty()
+	g.transactions.WithLabelValues(collectionID, payer, status).Inc()
+	g.gasUsed.WithLabelValues(collectionID, payer).Add(float64(receipt.GasUsed))
+	feeFloat, _ := new(big.Float).SetInt(fee).Float64()
+	g.fees.WithLabelValues(collectionID, payer).Add(feeFloat)
+}
+
+// list returns a copy of the totals of each collection, sorted by payer then
+// collection ID
+func (g *gasSpend) list() []*collectionGasSpend {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	out := make([]*collectionGasSpend, 0, len(g.collections))
+	for _, totals := range g.collections {
+		copied := *totals
+		copied.fee = new(big.Int).Set(totals.fee)
+		copied.tokensCollected = new(big.Int).Set(totals.tokensCollected)
+		out = append(out, &copied)
+	}
+
+	slices.SortFunc(out, func(a, b *collectionGasSpend) int {
+		if c := strings.Compare(a.payer, b.payer); c != 0 {
+			return c
+		}
+		return strings.Compare(eth.Hash(a.collectionID[:]).Pretty(), eth.Hash(b.collectionID[:]).Pretty())
+	})
+	return out
+}
+
+func (s *Sidecar) handleAdminGasSpend(w http.ResponseWriter, r *http.Request) {
+	totals := s.gasSpend.list()
+
+	collections := make([]*CollectionGasSpend, 0, len(totals))
+	payers := make([]*PayerGasSpend, 0)
+	payerFees := make(map[string]*big.Int)
+	payerTokens := make(map[string]*big.Int)
+
+	for _, total := range totals {
+		collections = append(collections, &CollectionGasSpend{
+			CollectionID:       eth.Hash(total.collectionID[:]).Pretty(),
+			Payer:              total.payer,
+			Transactions:       total.transactions,
+			FailedTransactions: total.failed,
+			GasUsed:            total.gasUsed,
+			Fee:                total.fee.String(),
+			TokensCollected:    s.display.Format(total.tokensCollected),
+			LastTxHash:         total.lastTxHash,
+			LastCollectedAt:    total.lastCollectedAt,
+		})
+
+		// Totals are sorted by payer, so a payer's collections are contiguous
+		if len(payers) == 0 || payers[len(payers)-1].Payer != total.payer {
+			payers = append(payers, &PayerGasSpend{Payer: total.payer})
+			payerFees[total.payer] = new(big.Int)
+			payerTokens[total.payer] = new(big.Int)
+		}
+		payer := payers[len(payers)-1]
+		payer.Collections++
+		payer.Transactions += total.transactions
+		payer.FailedTransactions += total.failed
+		payer.GasUsed += total.gasUsed
+		payerFees[total.payer].Add(payerFees[total.payer], total.fee)
+		payerTokens[total.payer].Add(payerTokens[total.payer], total.tokensCollected)
+	}
+
+	for _, payer := range payers {
+		payer.Fee = payerFees[payer.Payer].String()
+		payer.TokensCollected = s.display.Format(payerTokens[payer.Payer])
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]any{"amount_unit": s.display.UnitName(), "collections": collections, "payers": payers})
+}
@@ -0,0 +1,91 @@
+package sidecar
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestAdminGasSpend(t *testing.T) {
+	payer1 := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
+	payer2 := eth.MustNewAddress("0x5555555555555555555555555555555555555555")
+
+	s := New(&Config{
+		ListenAddr:      ":0",
+		ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		Domain:          horizon.NewDomain(1337, eth.MustNewAddress("0x4444444444444444444444444444444444444444")),
+		AdminListenAddr: ":0",
+	}, zap.NewNop())
+
+	now := time.Now()
+	rav := func(payer eth.Address, collection byte) *horizon.RAV {
+		return &horizon.RAV{CollectionID: horizon.CollectionID{collection}, Payer: payer}
+	}
+	mined := func(txHash string, gasUsed uint64) *sidecar.CollectReceipt {
+		return &sidecar.CollectReceipt{TxHash: txHash, Mined: true, GasUsed: gasUsed, GasPrice: big.NewInt(10)}
+	}
+
+	s.gasSpend.record(rav(payer1, 0x01), mined("0xa1", 100_000), big.NewInt(1000), true, now)
+	s.gasSpend.record(rav(payer1, 0x01), mined("0xa2", 50_000), nil, false, now)
+	s.gasSpend.record(rav(payer1, 0x02), mined("0xa3", 80_000), big.NewInt(500), true, now)
+	// Not mined in time, counted as failed without gas
+	s.gasSpend.record(rav(payer2, 0x01), &sidecar.CollectReceipt{TxHash: "0xb1"}, nil, false, now)
+
+	serve := func(path string) string {
+		rec := httptest.NewRecorder()
+		s.admin.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		return rec.Body.String()
+	}
+
+	var out struct {
+		Collections []*CollectionGasSpend `json:"collections"`
+		Payers      []*PayerGasSpend      `json:"payers"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(serve("/v1/collections/gas-spend")), &out))
+
+	require.Len(t, out.Collections, 3)
+	first := out.Collections[0]
+	assert.Equal(t, payer1.Pretty(), first.Payer)
+	assert.Equal(t, eth.Hash(append([]byte{0x01}, make([]byte, 31)...)).Pretty(), first.CollectionID)
+	assert.Equal(t, uint64(2), first.Transactions)
+	assert.Equal(t, uint64(1), first.FailedTransactions)
+	assert.Equal(t, uint64(150_000), first.GasUsed)
+	assert.Equal(t, "1500000", first.Fee)
+	assert.Equal(t, "1000", first.TokensCollected)
+	assert.Equal(t, "0xa2", first.LastTxHash)
+
+	require.Len(t, out.Payers, 2)
+	assert.Equal(t, &PayerGasSpend{
+		Payer:              payer1.Pretty(),
+		Collections:        2,
+		Transactions:       3,
+		FailedTransactions: 1,
+		GasUsed:            230_000,
+		Fee:                "2300000",
+		TokensCollected:    "1500",
+	}, out.Payers[0])
+	assert.Equal(t, &PayerGasSpend{
+		Payer:              payer2.Pretty(),
+		Collections:        1,
+		Transactions:       1,
+		FailedTransactions: 1,
+		Fee:                "0",
+		TokensCollected:    "0",
+	}, out.Payers[1])
+
+	metrics := serve("/metrics")
+	assert.Contains(t, metrics, `sds_provider_collect_gas_used_total{collection_id="`+first.CollectionID+`",payer="`+payer1.Pretty()+`"} 150000`)
+	assert.Contains(t, metrics, `sds_provider_collect_fee_wei_total{collection_id="`+first.CollectionID+`",payer="`+payer1.Pretty()+`"} 1.5e+06`)
+	assert.Contains(t, metrics, `sds_provider_collect_transactions_total{collection_id="`+first.CollectionID+`",payer="`+payer1.Pretty()+`",status="failed"} 1`)
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 
 	"connectrpc.com/connect"
-	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
 	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
 	"github.com/graphprotocol/substreams-data-service/sidecar"
 	"go.uber.org/zap"
@@ -36,23 +35,35 @@ func (s *Sidecar) EndSession(
 		session.AddUsage(finalUsage.BlocksProcessed, finalUsage.BytesTransferred, finalUsage.Requests, finalUsage.Cost.ToNative())
 	}
 
-	// End the session
+	// End the session, aggregating the receipts still buffered into its final
+	// RAV in receipts payment mode
 	session.End(req.Msg.Reason)
+	if session.PaysWithReceipts() && s.aggregator != nil {
+		if err := s.aggregateSessionReceipts(ctx, session); err != nil {
+			s.logger.Warn("final receipts aggregation failed, retrying on next interval", zap.String("session_id", sessionID), zap.Error(err))
+		}
+	}
+	s.ravTurnaround.forget(sessionID)
+	ravRequested := s.ravRequests.requestFinal(session, ravRequestTriggerSessionEnd)
+	s.settle(ctx, session)
+	s.persistSession(session)
+	s.queueAutoCollect(session)
 
 	// Get the final RAV and usage
 	finalRAV := session.GetRAV()
 	totalUsage := session.GetUsage()
 
 	response := &providerv1.EndSessionResponse{
-		FinalRav:   sidecar.HorizonSignedRAVToProto(finalRAV),
-		TotalUsage: totalUsage,
-		TotalValue: commonv1.BigIntFromNative(session.TotalCost),
+		FinalRav:     sidecar.HorizonSignedRAVToProto(finalRAV),
+		RavRequested: ravRequested,
+		Settlement:   settlementToProto(session),
 	}
 
 	s.logger.Info("EndSession completed",
 		zap.String("session_id", sessionID),
 		zap.Uint64("total_blocks", totalUsage.BlocksProcessed),
 		zap.Uint64("total_bytes", totalUsage.BytesTransferred),
+		zap.Bool("rav_requested", ravRequested),
 	)
 
 	return connect.NewResponse(response), nil
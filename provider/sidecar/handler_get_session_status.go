@@ -7,6 +7,7 @@ import (
 	"connectrpc.com/connect"
 	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
 	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
 	"go.uber.org/zap"
 )
 
@@ -29,10 +30,42 @@ func (s *Sidecar) GetSessionStatus(
 		}), nil
 	}
 
-	// Build session info
-	sessionInfo := session.ToSessionInfo()
+	response := &providerv1.GetSessionStatusResponse{
+		Active:        session.IsActive(),
+		Session:       session.ToSessionInfo(),
+		PaymentStatus: s.paymentStatus(ctx, session),
+		InstanceUsage: instanceUsageToProto(session.GetInstanceUsage()),
+		Degraded:      s.Degraded(),
+	}
 
-	// Build payment status
+	return connect.NewResponse(response), nil
+}
+
+func instanceUsageToProto(instances []sidecar.InstanceUsage) []*providerv1.InstanceUsage {
+	if len(instances) == 0 {
+		return nil
+	}
+
+	out := make([]*providerv1.InstanceUsage, 0, len(instances))
+	for _, instance := range instances {
+		out = append(out, &providerv1.InstanceUsage{
+			InstanceId: instance.InstanceID,
+			Usage: &commonv1.Usage{
+				BlocksProcessed:  instance.BlocksProcessed,
+				BytesTransferred: instance.BytesTransferred,
+				Requests:         instance.Requests,
+				Cost:             commonv1.BigIntFromNative(instance.TotalCost),
+			},
+			Reports:      instance.Reports,
+			LastReportNs: uint64(instance.LastReportAt.UnixNano()),
+		})
+	}
+	return out
+}
+
+// paymentStatus computes the payment status of a session, querying the
+// escrow balance from chain when an RPC endpoint is configured.
+func (s *Sidecar) paymentStatus(ctx context.Context, session *sidecar.Session) *commonv1.PaymentStatus {
 	currentRAV := session.GetRAV()
 	var currentRavValue *big.Int
 	if currentRAV != nil && currentRAV.Message != nil {
@@ -69,19 +102,11 @@ func (s *Sidecar) GetSessionStatus(
 		}
 	}
 
-	paymentStatus := &commonv1.PaymentStatus{
+	return &commonv1.PaymentStatus{
 		CurrentRavValue:          commonv1.BigIntFromNative(currentRavValue),
 		AccumulatedUsageValue:    commonv1.BigIntFromNative(session.TotalCost),
 		EscrowBalance:            commonv1.BigIntFromNative(escrowBalance),
 		FundsSufficient:          fundsSufficient,
 		EstimatedBlocksRemaining: estimatedBlocksRemaining,
 	}
-
-	response := &providerv1.GetSessionStatusResponse{
-		Active:        session.IsActive(),
-		Session:       sessionInfo,
-		PaymentStatus: paymentStatus,
-	}
-
-	return connect.NewResponse(response), nil
 }
@@ -5,6 +5,8 @@ import (
 	"io"
 
 	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
 	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
 	"github.com/graphprotocol/substreams-data-service/sidecar"
 	"go.uber.org/zap"
@@ -34,7 +36,9 @@ func (s *Sidecar) PaymentSession(
 		// Handle the message based on type
 		switch m := msg.Message.(type) {
 		case *providerv1.PaymentSessionRequest_RavSubmission:
-			s.handleRAVSubmission(ctx, stream, m.RavSubmission)
+			if err := s.handleRAVSubmission(ctx, stream, m.RavSubmission); err != nil {
+				return err
+			}
 
 		case *providerv1.PaymentSessionRequest_FundsAck:
 			s.handleFundsAcknowledgment(ctx, stream, m.FundsAck)
@@ -52,7 +56,7 @@ func (s *Sidecar) handleRAVSubmission(
 	ctx context.Context,
 	stream *connect.BidiStream[providerv1.PaymentSessionRequest, providerv1.PaymentSessionResponse],
 	submission *providerv1.SignedRAVSubmission,
-) {
+) error {
 	s.logger.Debug("received RAV submission in stream")
 
 	// Validate the RAV
@@ -65,44 +69,37 @@ func (s *Sidecar) handleRAVSubmission(
 				SessionControl: &providerv1.SessionControl{
 					Action: providerv1.SessionControl_ACTION_STOP,
 					Reason: "invalid RAV",
+					Code:   commonv1.RejectionCode_REJECTION_CODE_INVALID_RAV,
 				},
 			},
 		})
-		return
+		return nil
 	}
 
-	// Verify signature
-	signerAddr, err := s.verifyRAVSignature(signedRAV)
-	if err != nil {
-		s.logger.Warn("RAV signature verification failed", zap.Error(err))
+	// Validate the RAV, the stream is not bound to a session so only the
+	// checks of the RAV itself apply
+	policy := horizon.Policy{MaxMetadataSize: s.maxMetadataSize, StrictMetadata: s.strictMetadata}
+	if err := s.validator.Validate(nil, signedRAV, policy); err != nil {
+		s.logger.Warn("rejecting RAV submitted via stream", zap.Error(err))
 		stream.Send(&providerv1.PaymentSessionResponse{
 			Message: &providerv1.PaymentSessionResponse_SessionControl{
 				SessionControl: &providerv1.SessionControl{
 					Action: providerv1.SessionControl_ACTION_STOP,
-					Reason: "signature verification failed",
+					Reason: err.Error(),
+					Code:   ravRejectionCode(err),
 				},
 			},
 		})
-		return
+		return nil
 	}
-
-	// Check if signer is authorized
-	if !s.isAcceptedSigner(signerAddr) {
-		s.logger.Warn("RAV signer not authorized", zap.Stringer("signer", signerAddr))
-		stream.Send(&providerv1.PaymentSessionResponse{
-			Message: &providerv1.PaymentSessionResponse_SessionControl{
-				SessionControl: &providerv1.SessionControl{
-					Action: providerv1.SessionControl_ACTION_STOP,
-					Reason: "signer not authorized",
-				},
-			},
-		})
-		return
+	signerAddr, err := s.verifyRAVSignature(signedRAV)
+	if err != nil {
+		return connect.NewError(connect.CodeInternal, err)
 	}
 
 	s.logger.Info("RAV accepted via stream",
 		zap.Stringer("signer", signerAddr),
-		zap.String("value", signedRAV.Message.ValueAggregate.String()),
+		s.display.Field("value", signedRAV.Message.ValueAggregate),
 	)
 
 	// Send continue message
@@ -113,6 +110,7 @@ func (s *Sidecar) handleRAVSubmission(
 			},
 		},
 	})
+	return nil
 }
 
 func (s *Sidecar) handleFundsAcknowledgment(
@@ -140,6 +138,7 @@ func (s *Sidecar) handleFundsAcknowledgment(
 				SessionControl: &providerv1.SessionControl{
 					Action: providerv1.SessionControl_ACTION_STOP,
 					Reason: "insufficient funds and no deposit planned",
+					Code:   commonv1.RejectionCode_REJECTION_CODE_INSUFFICIENT_FUNDS,
 				},
 			},
 		})
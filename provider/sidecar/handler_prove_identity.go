@@ -0,0 +1,43 @@
+package sidecar
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"connectrpc.com/connect"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"go.uber.org/zap"
+)
+
+// ProveIdentity signs a consumer chosen challenge with the service provider key,
+// proving to consumers this endpoint is operated by the service provider they pay.
+func (s *Sidecar) ProveIdentity(
+	ctx context.Context,
+	req *connect.Request[providerv1.ProveIdentityRequest],
+) (*connect.Response[providerv1.ProveIdentityResponse], error) {
+	if s.identityKey == nil {
+		return nil, connect.NewError(connect.CodeUnimplemented, fmt.Errorf("identity proofs are not configured on this provider"))
+	}
+
+	if expected := eth.Address(req.Msg.ServiceProvider.GetBytes()); len(expected) != 0 && !bytes.Equal(expected, s.serviceProvider) {
+		s.logger.Warn("identity challenge for another service provider",
+			zap.Stringer("expected", expected),
+			zap.Stringer("service_provider", s.serviceProvider),
+		)
+		return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("this endpoint serves service provider %s, not %s", s.serviceProvider.Pretty(), expected.Pretty()))
+	}
+
+	signature, err := sidecar.SignIdentityChallenge(s.identityKey, req.Msg.Challenge, s.serviceProvider)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	return connect.NewResponse(&providerv1.ProveIdentityResponse{
+		ServiceProvider: commonv1.AddressFromEth(s.serviceProvider),
+		Signature:       signature[:],
+	}), nil
+}
@@ -0,0 +1,57 @@
+package sidecar
+
+import (
+	"context"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestProveIdentity(t *testing.T) {
+	identityKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	serviceProvider := identityKey.PublicKey().Address()
+
+	newSidecar := func(key *eth.PrivateKey) *Sidecar {
+		return New(&Config{
+			ServiceProvider: serviceProvider,
+			Domain:          horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+			IdentityKey:     key,
+		}, zap.NewNop())
+	}
+
+	challenge, err := sidecar.NewIdentityChallenge()
+	require.NoError(t, err)
+
+	prove := func(s *Sidecar, expected eth.Address) (*providerv1.ProveIdentityResponse, error) {
+		resp, err := s.ProveIdentity(context.Background(), connect.NewRequest(&providerv1.ProveIdentityRequest{
+			Challenge:       challenge,
+			ServiceProvider: commonv1.AddressFromEth(expected),
+		}))
+		if err != nil {
+			return nil, err
+		}
+		return resp.Msg, nil
+	}
+
+	resp, err := prove(newSidecar(identityKey), serviceProvider)
+	require.NoError(t, err)
+
+	var signature eth.Signature
+	copy(signature[:], resp.Signature)
+	require.NoError(t, sidecar.VerifyIdentityChallenge(challenge, serviceProvider, signature))
+
+	_, err = prove(newSidecar(identityKey), eth.MustNewAddress("0x3333333333333333333333333333333333333333"))
+	assert.Equal(t, connect.CodeFailedPrecondition, connect.CodeOf(err))
+
+	_, err = prove(newSidecar(nil), serviceProvider)
+	assert.Equal(t, connect.CodeUnimplemented, connect.CodeOf(err))
+}
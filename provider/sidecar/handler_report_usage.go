@@ -2,9 +2,14 @@ package sidecar
 
 import (
 	"context"
+	"math/big"
+	"time"
 
 	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
 	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
 	"go.uber.org/zap"
 )
 
@@ -14,31 +19,136 @@ func (s *Sidecar) ReportUsage(
 	ctx context.Context,
 	req *connect.Request[providerv1.ReportUsageRequest],
 ) (*connect.Response[providerv1.ReportUsageResponse], error) {
-	sessionID := req.Msg.SessionId
+	defer s.latency.observe(ctx, "ReportUsage", time.Now())
 
 	s.logger.Debug("ReportUsage called",
-		zap.String("session_id", sessionID),
+		zap.String("session_id", req.Msg.SessionId),
+		zap.String("instance_id", req.Msg.InstanceId),
 	)
 
-	// Get the session
-	session, err := s.sessions.Get(sessionID)
+	session, err := s.sessions.Get(req.Msg.SessionId)
 	if err != nil {
-		s.logger.Warn("session not found", zap.String("session_id", sessionID))
+		s.logger.Warn("session not found", zap.String("session_id", req.Msg.SessionId))
 		return nil, connect.NewError(connect.CodeNotFound, err)
 	}
 
+	return connect.NewResponse(s.reportUsage(ctx, session, req.Msg)), nil
+}
+
+// reportUsage adds the usage of req to session and decides whether streaming
+// continues, shared by ReportUsage and StreamUsage
+func (s *Sidecar) reportUsage(ctx context.Context, session *sidecar.Session, req *providerv1.ReportUsageRequest) *providerv1.ReportUsageResponse {
+	sessionID := session.ID
+
 	// Check session is active
 	if !session.IsActive() {
-		return connect.NewResponse(&providerv1.ReportUsageResponse{
+		return &providerv1.ReportUsageResponse{
 			ShouldContinue: false,
 			StopReason:     "session is not active",
-		}), nil
+			StopCode:       commonv1.RejectionCode_REJECTION_CODE_SESSION_NOT_ACTIVE,
+		}
 	}
 
-	// Add usage to session
-	usage := req.Msg.Usage
+	// Add usage to session, tracking the reporting instance when several serve
+	// it. Usage may be reported without a cost, e.g. a batch without costs.
+	usage := reportedUsage(req)
+	var cost *big.Int
+	if usage.GetCost() != nil {
+		cost = usage.Cost.ToNative()
+	}
 	if usage != nil {
-		session.AddUsage(usage.BlocksProcessed, usage.BytesTransferred, usage.Requests, usage.Cost.ToNative())
+		session.AddInstanceUsage(req.InstanceId, usage.BlocksProcessed, usage.BytesTransferred, usage.Requests, cost)
+		session.AddUsageCategories(usage.Categories)
+	}
+
+	// Streaming stops on shutdown, the final RAV is requested while awaited
+	if s.draining.Load() {
+		s.persistSession(session)
+		return shuttingDown(s.ravRequests.finalPending(sessionID))
+	}
+
+	// Sessions started without a RAV must get one before leaving the trust window
+	reason := s.enforceTrustWindow(session)
+	s.persistSession(session)
+	if reason != "" {
+		s.logger.Warn("session exceeded its trust window without a RAV, ending it",
+			zap.String("session_id", sessionID),
+			zap.String("reason", reason),
+		)
+		return &providerv1.ReportUsageResponse{
+			ShouldContinue: false,
+			StopReason:     reason,
+			StopCode:       commonv1.RejectionCode_REJECTION_CODE_INVALID_RAV,
+		}
+	}
+
+	// Stop sessions the payer's escrow no longer covers
+	if err := s.checkEscrowBalance(ctx, session); err != nil {
+		s.logger.Warn("escrow balance no longer covers session, stopping it",
+			zap.String("session_id", sessionID),
+			zap.Stringer("payer", session.Payer),
+			zap.Error(err),
+		)
+		return &providerv1.ReportUsageResponse{
+			ShouldContinue: false,
+			StopReason:     err.Error(),
+			StopCode:       commonv1.RejectionCode_REJECTION_CODE_INSUFFICIENT_FUNDS,
+		}
+	}
+
+	// Collect the session's collection once it holds too much uncollected
+	// value, stopping the session when that is not possible
+	if err := s.checkUncollectedValue(ctx, session); err != nil {
+		s.logger.Warn("uncollected value ceiling reached, stopping session",
+			zap.String("session_id", sessionID),
+			zap.Stringer("payer", session.Payer),
+			zap.Error(err),
+		)
+		return &providerv1.ReportUsageResponse{
+			ShouldContinue: false,
+			StopReason:     err.Error(),
+			StopCode:       commonv1.RejectionCode_REJECTION_CODE_INSUFFICIENT_FUNDS,
+		}
+	}
+
+	// Stop sessions the payment policy no longer allows
+	var rav *horizon.RAV
+	if current := session.GetRAV(); current != nil {
+		rav = current.Message
+	}
+	if err := s.checkPaymentPolicy(ctx, "ReportUsage", &policyRequest{
+		payer:         session.Payer,
+		session:       session,
+		rav:           rav,
+		pricePerBlock: reportedPricePerBlock(usage.GetBlocksProcessed(), cost),
+	}); err != nil {
+		s.logger.Warn("session violates the payment policy, stopping it",
+			zap.String("session_id", sessionID),
+			zap.Stringer("payer", session.Payer),
+			zap.Error(err),
+		)
+		return &providerv1.ReportUsageResponse{
+			ShouldContinue: false,
+			StopReason:     err.Error(),
+			StopCode:       commonv1.RejectionCode_REJECTION_CODE_POLICY_VIOLATION,
+		}
+	}
+
+	// Request a RAV once the usage it does not cover reaches the thresholds,
+	// stopping sessions whose consumer is too slow returning it
+	now := time.Now()
+	ravRequested := s.ravRequests.check(session, now) != ""
+	if reason := s.checkRAVTurnaround(session, ravRequested, now); reason != "" {
+		s.logger.Warn("consumer RAV overdue, stopping session",
+			zap.String("session_id", sessionID),
+			zap.Stringer("payer", session.Payer),
+			zap.String("reason", reason),
+		)
+		return &providerv1.ReportUsageResponse{
+			ShouldContinue: false,
+			StopReason:     reason,
+			StopCode:       commonv1.RejectionCode_REJECTION_CODE_RAV_OVERDUE,
+		}
 	}
 
 	// Check if we need to request a new RAV
@@ -49,13 +159,48 @@ func (s *Sidecar) ReportUsage(
 	response := &providerv1.ReportUsageResponse{
 		ShouldContinue: true,
 		RavUpdated:     ravUpdated,
+		RavRequested:   ravRequested,
 	}
 
 	s.logger.Debug("ReportUsage completed",
 		zap.String("session_id", sessionID),
 		zap.Uint64("total_blocks", session.BlocksProcessed),
 		zap.Bool("rav_updated", ravUpdated),
+		zap.Bool("rav_requested", ravRequested),
 	)
 
-	return connect.NewResponse(response), nil
+	return response
+}
+
+// reportedUsage is the usage req reports: its usage and the entries of its
+// usage batch summed, nil when it reports none
+func reportedUsage(req *providerv1.ReportUsageRequest) *commonv1.Usage {
+	if len(req.UsageBatch) == 0 {
+		return req.Usage
+	}
+
+	total := &commonv1.Usage{}
+	var cost *big.Int
+	var categories [][]*commonv1.UsageCategory
+	for _, entry := range append([]*commonv1.Usage{req.Usage}, req.UsageBatch...) {
+		if entry == nil {
+			continue
+		}
+		categories = append(categories, entry.Categories)
+		total.BlocksProcessed += entry.BlocksProcessed
+		total.BytesTransferred += entry.BytesTransferred
+		total.Requests += entry.Requests
+		total.TimestampNs = max(total.TimestampNs, entry.TimestampNs)
+		if entry.Cost != nil {
+			if cost == nil {
+				cost = new(big.Int)
+			}
+			cost.Add(cost, entry.Cost.ToNative())
+		}
+	}
+	if cost != nil {
+		total.Cost = commonv1.BigIntFromNative(cost)
+	}
+	total.Categories = sidecar.MergeUsageCategories(categories...)
+	return total
 }
@@ -0,0 +1,147 @@
+package sidecar
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestReportUsage_InstanceBreakdown(t *testing.T) {
+	s := New(&Config{
+		ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		Domain:          horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+	}, zap.NewNop())
+
+	session := s.sessions.Create(
+		eth.MustNewAddress("0x4444444444444444444444444444444444444444"),
+		eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+	)
+
+	report := func(instanceID string, blocks uint64, cost int64) {
+		resp, err := s.ReportUsage(context.Background(), connect.NewRequest(&providerv1.ReportUsageRequest{
+			SessionId:  session.ID,
+			InstanceId: instanceID,
+			Usage:      &commonv1.Usage{BlocksProcessed: blocks, Cost: commonv1.BigIntFromNative(big.NewInt(cost))},
+		}))
+		require.NoError(t, err)
+		require.True(t, resp.Msg.ShouldContinue)
+	}
+
+	report("tier2-b", 10, 100)
+	report("tier2-a", 5, 50)
+	report("tier2-b", 1, 10)
+	report("", 2, 20)
+
+	resp, err := s.GetSessionStatus(context.Background(), connect.NewRequest(&providerv1.GetSessionStatusRequest{SessionId: session.ID}))
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(18), resp.Msg.Session.AccumulatedUsage.BlocksProcessed)
+	assert.Equal(t, "180", resp.Msg.Session.AccumulatedUsage.Cost.ToNative().String())
+
+	instances := resp.Msg.InstanceUsage
+	require.Len(t, instances, 2)
+
+	assert.Equal(t, "tier2-a", instances[0].InstanceId)
+	assert.Equal(t, uint64(5), instances[0].Usage.BlocksProcessed)
+	assert.Equal(t, uint64(1), instances[0].Reports)
+
+	assert.Equal(t, "tier2-b", instances[1].InstanceId)
+	assert.Equal(t, uint64(11), instances[1].Usage.BlocksProcessed)
+	assert.Equal(t, "110", instances[1].Usage.Cost.ToNative().String())
+	assert.Equal(t, uint64(2), instances[1].Reports)
+	assert.NotZero(t, instances[1].LastReportNs)
+}
+
+func TestReportUsage_UsageBatch(t *testing.T) {
+	s := New(&Config{
+		ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		Domain:          horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+	}, zap.NewNop())
+
+	session := s.sessions.Create(
+		eth.MustNewAddress("0x4444444444444444444444444444444444444444"),
+		eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+	)
+
+	resp, err := s.ReportUsage(context.Background(), connect.NewRequest(&providerv1.ReportUsageRequest{
+		SessionId:  session.ID,
+		InstanceId: "tier2-a",
+		Usage:      &commonv1.Usage{BlocksProcessed: 2, Cost: commonv1.BigIntFromNative(big.NewInt(20))},
+		UsageBatch: []*commonv1.Usage{
+			{BlocksProcessed: 1, BytesTransferred: 100, Cost: commonv1.BigIntFromNative(big.NewInt(10)), TimestampNs: 1000},
+			{BlocksProcessed: 1, BytesTransferred: 200, Requests: 1, TimestampNs: 2000},
+			{BlocksProcessed: 1, BytesTransferred: 300, Cost: commonv1.BigIntFromNative(big.NewInt(10)), TimestampNs: 3000},
+		},
+	}))
+	require.NoError(t, err)
+	require.True(t, resp.Msg.ShouldContinue)
+
+	status, err := s.GetSessionStatus(context.Background(), connect.NewRequest(&providerv1.GetSessionStatusRequest{SessionId: session.ID}))
+	require.NoError(t, err)
+
+	usage := status.Msg.Session.AccumulatedUsage
+	assert.Equal(t, uint64(5), usage.BlocksProcessed)
+	assert.Equal(t, uint64(600), usage.BytesTransferred)
+	assert.Equal(t, uint64(1), usage.Requests)
+	assert.Equal(t, "40", usage.Cost.ToNative().String())
+
+	// The batch is a single report of the instance
+	require.Len(t, status.Msg.InstanceUsage, 1)
+	assert.Equal(t, uint64(1), status.Msg.InstanceUsage[0].Reports)
+	assert.Equal(t, uint64(5), status.Msg.InstanceUsage[0].Usage.BlocksProcessed)
+}
+
+func TestReportUsage_UsageBatchWithoutCosts(t *testing.T) {
+	s := New(&Config{
+		ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		Domain:          horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+	}, zap.NewNop())
+
+	session := s.sessions.Create(
+		eth.MustNewAddress("0x4444444444444444444444444444444444444444"),
+		eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+	)
+
+	resp, err := s.ReportUsage(context.Background(), connect.NewRequest(&providerv1.ReportUsageRequest{
+		SessionId: session.ID,
+		UsageBatch: []*commonv1.Usage{
+			{BlocksProcessed: 1, BytesTransferred: 100, TimestampNs: 1000},
+			{BlocksProcessed: 2, BytesTransferred: 200, TimestampNs: 2000},
+		},
+	}))
+	require.NoError(t, err)
+	require.True(t, resp.Msg.ShouldContinue)
+
+	usage := session.GetUsage()
+	assert.Equal(t, uint64(3), usage.BlocksProcessed)
+	assert.Equal(t, uint64(300), usage.BytesTransferred)
+	assert.Equal(t, "0", usage.Cost.ToNative().String())
+}
+
+func TestReportedUsage(t *testing.T) {
+	usage := &commonv1.Usage{BlocksProcessed: 1}
+	assert.Same(t, usage, reportedUsage(&providerv1.ReportUsageRequest{Usage: usage}))
+	assert.Nil(t, reportedUsage(&providerv1.ReportUsageRequest{}))
+
+	total := reportedUsage(&providerv1.ReportUsageRequest{UsageBatch: []*commonv1.Usage{
+		{BlocksProcessed: 1, TimestampNs: 2000, Categories: []*commonv1.UsageCategory{{Name: "live", BlocksProcessed: 1}}},
+		{BlocksProcessed: 2, TimestampNs: 1000, Categories: []*commonv1.UsageCategory{{Name: "live", BlocksProcessed: 2}}},
+	}})
+	assert.Equal(t, uint64(3), total.BlocksProcessed)
+	assert.Equal(t, uint64(2000), total.TimestampNs)
+	assert.Nil(t, total.Cost)
+	require.Len(t, total.Categories, 1)
+	assert.Equal(t, uint64(3), total.Categories[0].BlocksProcessed)
+}
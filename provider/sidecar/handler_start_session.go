@@ -3,10 +3,14 @@ package sidecar
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
 	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
 	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
 	"go.uber.org/zap"
 )
 
@@ -19,6 +23,14 @@ func (s *Sidecar) StartSession(
 ) (*connect.Response[providerv1.StartSessionResponse], error) {
 	s.logger.Info("StartSession called")
 
+	if s.draining.Load() {
+		return connect.NewResponse(&providerv1.StartSessionResponse{
+			Accepted:        false,
+			RejectionReason: shutdownStopReason,
+			RejectionCode:   commonv1.RejectionCode_REJECTION_CODE_SHUTTING_DOWN,
+		}), nil
+	}
+
 	// Extract escrow account
 	ea := req.Msg.EscrowAccount
 	payer, receiver, dataService := ea.Payer.ToEth(), ea.Receiver.ToEth(), ea.DataService.ToEth()
@@ -32,57 +44,115 @@ func (s *Sidecar) StartSession(
 		return connect.NewResponse(&providerv1.StartSessionResponse{
 			Accepted:        false,
 			RejectionReason: "escrow account receiver does not match this service provider",
+			RejectionCode:   commonv1.RejectionCode_REJECTION_CODE_PARTY_MISMATCH,
 		}), nil
 	}
 
-	// Validate initial RAV if provided
-	initialRAV := sidecar.ProtoSignedRAVToHorizon(req.Msg.InitialRav)
-	if initialRAV != nil && initialRAV.Message != nil {
-		// Verify signature
-		signerAddr, err := s.verifyRAVSignature(initialRAV)
-		if err != nil {
-			s.logger.Warn("failed to verify initial RAV signature", zap.Error(err))
-			return connect.NewResponse(&providerv1.StartSessionResponse{
-				Accepted:        false,
-				RejectionReason: fmt.Sprintf("initial RAV signature verification failed: %v", err),
-			}), nil
-		}
+	// Verify collector, RAVs are only verified against this sidecar's collector domain
+	if collector := eth.Address(ea.Collector.GetBytes()); len(collector) != 0 && !sidecar.AddressesEqual(collector, s.domain.VerifyingContract) {
+		s.logger.Warn("escrow account collector mismatch",
+			zap.Stringer("expected", s.domain.VerifyingContract),
+			zap.Stringer("got", collector),
+		)
+		return connect.NewResponse(&providerv1.StartSessionResponse{
+			Accepted:        false,
+			RejectionReason: "escrow account collector is not supported by this service provider",
+			RejectionCode:   commonv1.RejectionCode_REJECTION_CODE_UNSUPPORTED_COLLECTOR,
+		}), nil
+	}
 
-		// Check if signer is authorized
-		if !s.isAcceptedSigner(signerAddr) {
-			s.logger.Warn("initial RAV signer not authorized",
-				zap.Stringer("signer", signerAddr),
-			)
+	// Receipts are aggregated by this sidecar, which requires an aggregator
+	switch req.Msg.PaymentMode {
+	case commonv1.PaymentMode_PAYMENT_MODE_UNSPECIFIED, commonv1.PaymentMode_PAYMENT_MODE_RAV:
+	case commonv1.PaymentMode_PAYMENT_MODE_RECEIPTS:
+		if s.aggregator == nil {
 			return connect.NewResponse(&providerv1.StartSessionResponse{
 				Accepted:        false,
-				RejectionReason: fmt.Sprintf("signer %s is not authorized", signerAddr.Pretty()),
+				RejectionReason: "receipts payment mode is not supported, no receipt aggregator configured",
+				RejectionCode:   commonv1.RejectionCode_REJECTION_CODE_INVALID_REQUEST,
 			}), nil
 		}
+	default:
+		return connect.NewResponse(&providerv1.StartSessionResponse{
+			Accepted:        false,
+			RejectionReason: fmt.Sprintf("unknown payment mode %s", req.Msg.PaymentMode),
+			RejectionCode:   commonv1.RejectionCode_REJECTION_CODE_INVALID_REQUEST,
+		}), nil
+	}
 
-		// Verify RAV addresses match
-		if !sidecar.AddressesEqual(initialRAV.Message.Payer, payer) {
+	// Validate initial RAV if provided, sessions without one are only started
+	// when allowed, bounded by the trust window when configured
+	initialRAV := sidecar.ProtoSignedRAVToHorizon(req.Msg.InitialRav)
+	if (initialRAV == nil || initialRAV.Message == nil) && s.requireInitialRAV && s.trustWindow == nil {
+		return connect.NewResponse(&providerv1.StartSessionResponse{
+			Accepted:        false,
+			RejectionReason: "an initial RAV is required to start a session",
+			RejectionCode:   commonv1.RejectionCode_REJECTION_CODE_INVALID_RAV,
+		}), nil
+	}
+	if initialRAV != nil && initialRAV.Message != nil {
+		// Validate the RAV: metadata, validity window the consumer attached to it
+		// (an old RAV must not open new sessions once its stream ended long
+		// ago), signature, signer, parties of the escrow account and escrow cap
+		policy := s.ravPolicy(ctx, horizon.RAVParties{Payer: payer, ServiceProvider: s.serviceProvider, DataService: dataService})
+		policy.Now = time.Now()
+		if err := s.validator.Validate(nil, initialRAV, policy); err != nil {
+			code := ravRejectionCode(err)
+			s.logger.Warn("rejecting initial RAV", zap.Stringer("payer", payer), zap.Stringer("code", code), zap.Error(err))
 			return connect.NewResponse(&providerv1.StartSessionResponse{
 				Accepted:        false,
-				RejectionReason: "RAV payer does not match escrow account payer",
+				RejectionReason: err.Error(),
+				RejectionCode:   code,
 			}), nil
 		}
-		if !sidecar.AddressesEqual(initialRAV.Message.ServiceProvider, s.serviceProvider) {
+
+		// Zero-value RAVs are only accepted as recent, the session is then
+		// bound to the collection and parties they declare
+		if err := s.checkBootstrapRAV(initialRAV.Message, time.Now()); err != nil {
+			s.logger.Warn("rejecting bootstrap RAV", zap.Stringer("payer", payer), zap.Error(err))
 			return connect.NewResponse(&providerv1.StartSessionResponse{
 				Accepted:        false,
-				RejectionReason: "RAV service provider does not match",
+				RejectionReason: err.Error(),
+				RejectionCode:   commonv1.RejectionCode_REJECTION_CODE_INVALID_RAV,
 			}), nil
 		}
 	}
 
+	// A consumer re-initializing after a crash with the last RAV of its session
+	// resumes it, keeping the usage accumulated since that RAV
+	if session := s.resumeSession(payer, dataService, initialRAV); session != nil {
+		s.persistSession(session)
+		s.logger.Info("StartSession resumed interrupted session",
+			zap.String("session_id", session.ID),
+			zap.Stringer("payer", payer),
+			zap.Uint64("blocks_processed", session.GetUsage().BlocksProcessed),
+		)
+
+		return connect.NewResponse(&providerv1.StartSessionResponse{
+			SessionId: session.ID,
+			UseRav:    req.Msg.InitialRav,
+			Accepted:  true,
+		}), nil
+	}
+
 	// Create session
-	session := s.sessions.Create(payer, s.serviceProvider, dataService)
+	session := s.sessions.CreateWithCollector(payer, s.serviceProvider, dataService, s.domain.VerifyingContract)
+	if req.Msg.PaymentMode == commonv1.PaymentMode_PAYMENT_MODE_RECEIPTS {
+		session.PaymentMode = req.Msg.PaymentMode
+	}
 	if initialRAV != nil {
 		session.SetRAV(initialRAV)
 	}
+	s.persistSession(session)
+
+	if s.escrowWatch != nil {
+		s.escrowWatch.start(ctx, payer, s.logger)
+	}
 
 	s.logger.Info("StartSession succeeded",
 		zap.String("session_id", session.ID),
 		zap.Stringer("payer", payer),
+		zap.Stringer("payment_mode", req.Msg.PaymentMode),
 	)
 
 	// Return the RAV to use (same as initial for now)
@@ -0,0 +1,67 @@
+package sidecar
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStartSession_RAVValidity(t *testing.T) {
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	payer := signerKey.PublicKey().Address()
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	domain := horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111"))
+
+	s := New(&Config{
+		ServiceProvider: serviceProvider,
+		Domain:          domain,
+		AcceptedSigners: []eth.Address{payer},
+	}, zap.NewNop())
+
+	start := func(validUntil time.Time) *providerv1.StartSessionResponse {
+		collectionID := horizon.CollectionID{1}
+		rav, err := horizon.Sign(domain, &horizon.RAV{
+			CollectionID:    collectionID,
+			Payer:           payer,
+			DataService:     dataService,
+			ServiceProvider: serviceProvider,
+			TimestampNs:     uint64(time.Now().UnixNano()),
+			ValueAggregate:  big.NewInt(100),
+			Metadata:        horizon.EncodeRAVValidityMetadata(collectionID, validUntil),
+		}, signerKey)
+		require.NoError(t, err)
+
+		resp, err := s.StartSession(context.Background(), connect.NewRequest(&providerv1.StartSessionRequest{
+			EscrowAccount: &commonv1.EscrowAccount{
+				Payer:       commonv1.AddressFromEth(payer),
+				Receiver:    commonv1.AddressFromEth(serviceProvider),
+				DataService: commonv1.AddressFromEth(dataService),
+			},
+			InitialRav: sidecar.HorizonSignedRAVToProto(rav),
+		}))
+		require.NoError(t, err)
+		return resp.Msg
+	}
+
+	resp := start(time.Now().Add(time.Hour))
+	assert.True(t, resp.Accepted, resp.RejectionReason)
+
+	resp = start(time.Now().Add(-time.Minute))
+	assert.False(t, resp.Accepted)
+	assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_INVALID_RAV, resp.RejectionCode)
+	assert.Contains(t, resp.RejectionReason, horizon.ErrRAVExpired.Error())
+}
@@ -0,0 +1,93 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/big"
+	"time"
+
+	"connectrpc.com/connect"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"go.uber.org/zap"
+)
+
+// StreamUsage receives usage reports on a bidirectional stream, for providers
+// reporting at a high frequency. Each report is handled like a ReportUsage
+// call, an outcome is only sent back when the session's RAV was updated since
+// the last outcome sent for it, when a RAV is requested or when the session
+// must stop. Unknown sessions
+// get a stop outcome instead of failing the stream, which carries the reports
+// of every session of the provider.
+func (s *Sidecar) StreamUsage(
+	ctx context.Context,
+	stream *connect.BidiStream[providerv1.ReportUsageRequest, providerv1.StreamUsageResponse],
+) error {
+	s.logger.Debug("StreamUsage opened")
+
+	// RAV value aggregate last sent for each session
+	sentRAVs := make(map[string]*big.Int)
+
+	usageStream := s.streams.open(stream.Send)
+	defer s.streams.close(usageStream)
+
+	for {
+		req, err := stream.Receive()
+		if errors.Is(err, io.EOF) {
+			s.logger.Debug("StreamUsage closed by provider")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		outcome := s.streamedUsageOutcome(ctx, req, sentRAVs)
+		usageStream.reporting(req.SessionId, outcome == nil || outcome.ShouldContinue)
+		if outcome == nil {
+			continue
+		}
+
+		if err := usageStream.Send(&providerv1.StreamUsageResponse{SessionId: req.SessionId, Outcome: outcome}); err != nil {
+			return err
+		}
+	}
+}
+
+// streamedUsageOutcome handles a usage report received on StreamUsage and
+// returns the outcome to send back, nil when there is nothing to act on
+func (s *Sidecar) streamedUsageOutcome(ctx context.Context, req *providerv1.ReportUsageRequest, sentRAVs map[string]*big.Int) *providerv1.ReportUsageResponse {
+	defer s.latency.observe(ctx, "StreamUsage", time.Now())
+
+	session, err := s.sessions.Get(req.SessionId)
+	if err != nil {
+		s.logger.Warn("streamed usage for unknown session", zap.String("session_id", req.SessionId))
+		return &providerv1.ReportUsageResponse{
+			ShouldContinue: false,
+			StopReason:     err.Error(),
+			StopCode:       commonv1.RejectionCode_REJECTION_CODE_SESSION_NOT_FOUND,
+		}
+	}
+
+	outcome := s.reportUsage(ctx, session, req)
+	if !outcome.ShouldContinue {
+		delete(sentRAVs, session.ID)
+		return outcome
+	}
+
+	var value *big.Int
+	if rav := session.GetRAV(); rav != nil {
+		value = ravValue(rav)
+	}
+	sent, known := sentRAVs[session.ID]
+	updated := value != nil && (!known || sent.Cmp(value) != 0)
+	if !updated && !outcome.RavRequested {
+		return nil
+	}
+
+	if updated {
+		sentRAVs[session.ID] = value
+	}
+	outcome.RavUpdated = updated
+	return outcome
+}
@@ -0,0 +1,96 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1/providerv1connect"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStreamUsage(t *testing.T) {
+	payer := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+
+	s := New(&Config{
+		ServiceProvider: serviceProvider,
+		Domain:          horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+	}, zap.NewNop())
+	session := s.sessions.Create(payer, serviceProvider, dataService)
+
+	// Bidirectional streams require HTTP/2
+	mux := http.NewServeMux()
+	mux.Handle(providerv1connect.NewProviderSidecarServiceHandler(s))
+	server := httptest.NewUnstartedServer(mux)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	client := providerv1connect.NewProviderSidecarServiceClient(server.Client(), server.URL)
+	stream := client.StreamUsage(context.Background())
+
+	send := func(sessionID string) {
+		require.NoError(t, stream.Send(&providerv1.ReportUsageRequest{
+			SessionId: sessionID,
+			Usage:     &commonv1.Usage{BlocksProcessed: 1, Cost: commonv1.BigIntFromNative(big.NewInt(10))},
+		}))
+	}
+	receive := func() *providerv1.StreamUsageResponse {
+		resp, err := stream.Receive()
+		require.NoError(t, err)
+		return resp
+	}
+	setRAV := func(value int64) {
+		session.SetRAV(&horizon.SignedRAV{Message: &horizon.RAV{Payer: payer, ServiceProvider: serviceProvider, DataService: dataService, ValueAggregate: big.NewInt(value)}})
+	}
+
+	// Nothing to act on without a RAV, the next outcome is the RAV update
+	send(session.ID)
+	setRAV(100)
+	send(session.ID)
+
+	resp := receive()
+	assert.Equal(t, session.ID, resp.SessionId)
+	assert.True(t, resp.Outcome.ShouldContinue)
+	assert.True(t, resp.Outcome.RavUpdated)
+
+	// The same RAV is not sent again, unknown sessions are told to stop
+	send(session.ID)
+	send("unknown")
+
+	resp = receive()
+	assert.Equal(t, "unknown", resp.SessionId)
+	assert.False(t, resp.Outcome.ShouldContinue)
+	assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_SESSION_NOT_FOUND, resp.Outcome.StopCode)
+
+	setRAV(200)
+	send(session.ID)
+	resp = receive()
+	assert.True(t, resp.Outcome.RavUpdated)
+
+	session.End(commonv1.EndReason_END_REASON_COMPLETE)
+	send(session.ID)
+	resp = receive()
+	assert.Equal(t, session.ID, resp.SessionId)
+	assert.False(t, resp.Outcome.ShouldContinue)
+	assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_SESSION_NOT_ACTIVE, resp.Outcome.StopCode)
+
+	require.NoError(t, stream.CloseRequest())
+	_, err := stream.Receive()
+	assert.True(t, errors.Is(err, io.EOF), "stream ends once the provider closes it, got %v", err)
+	require.NoError(t, stream.CloseResponse())
+
+	assert.Equal(t, uint64(4), session.GetUsage().BlocksProcessed)
+}
@@ -3,8 +3,12 @@ package sidecar
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
 	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
 	"github.com/graphprotocol/substreams-data-service/sidecar"
 	"go.uber.org/zap"
@@ -29,93 +33,198 @@ func (s *Sidecar) SubmitRAV(
 		return connect.NewResponse(&providerv1.SubmitRAVResponse{
 			Accepted:        false,
 			RejectionReason: "session not found",
+			RejectionCode:   commonv1.RejectionCode_REJECTION_CODE_SESSION_NOT_FOUND,
 			ShouldContinue:  false,
 		}), nil
 	}
 
-	// Check session is active
-	if !session.IsActive() {
+	// Check session is active, only the final RAV requested by EndSession is
+	// accepted once it ended
+	if !session.IsActive() && !s.ravRequests.finalPending(sessionID) {
 		return connect.NewResponse(&providerv1.SubmitRAVResponse{
 			Accepted:        false,
 			RejectionReason: "session is not active",
+			RejectionCode:   commonv1.RejectionCode_REJECTION_CODE_SESSION_NOT_ACTIVE,
 			ShouldContinue:  false,
 		}), nil
 	}
 
-	// Convert and validate the RAV
-	signedRAV := sidecar.ProtoSignedRAVToHorizon(req.Msg.SignedRav)
+	// Buffer receipts of sessions in receipts payment mode, they are aggregated
+	// into the session RAV periodically
+	if session.PaysWithReceipts() {
+		return s.submitReceipts(session, req.Msg)
+	}
+
+	// Convert and validate the RAV, obtaining it from the aggregator when
+	// receipts are submitted instead
+	var signedRAV *horizon.SignedRAV
+	var aggregatedRAV *commonv1.SignedRAV
+	if len(req.Msg.Receipts) > 0 {
+		if req.Msg.SignedRav != nil {
+			return connect.NewResponse(&providerv1.SubmitRAVResponse{
+				Accepted:        false,
+				RejectionReason: "receipts and RAV are mutually exclusive",
+				RejectionCode:   commonv1.RejectionCode_REJECTION_CODE_INVALID_REQUEST,
+				ShouldContinue:  true,
+			}), nil
+		}
+
+		signedRAV, err = s.aggregateReceipts(ctx, session, req.Msg.Receipts)
+		if err != nil {
+			s.logger.Warn("failed to aggregate receipts", zap.String("session_id", sessionID), zap.Error(err))
+			return connect.NewResponse(&providerv1.SubmitRAVResponse{
+				Accepted:        false,
+				RejectionReason: fmt.Sprintf("receipts aggregation failed: %v", err),
+				RejectionCode:   commonv1.RejectionCode_REJECTION_CODE_AGGREGATION_FAILED,
+				ShouldContinue:  true,
+			}), nil
+		}
+		aggregatedRAV = sidecar.HorizonSignedRAVToProto(signedRAV)
+	} else {
+		signedRAV = sidecar.ProtoSignedRAVToHorizon(req.Msg.SignedRav)
+	}
 	if signedRAV == nil || signedRAV.Message == nil {
 		return connect.NewResponse(&providerv1.SubmitRAVResponse{
 			Accepted:        false,
 			RejectionReason: "invalid or missing RAV",
+			RejectionCode:   commonv1.RejectionCode_REJECTION_CODE_INVALID_RAV,
 			ShouldContinue:  true,
 		}), nil
 	}
 
-	// Verify signature
-	signerAddr, err := s.verifyRAVSignature(signedRAV)
-	if err != nil {
-		s.logger.Warn("failed to verify RAV signature", zap.Error(err))
+	// Validate the RAV against the session's current one: signature, signer,
+	// session parties and collection, non-decreasing value and escrow cap.
+	// Backwards timestamps are left to the quarantine when enabled.
+	policy := s.ravPolicy(ctx, sessionParties(session))
+	policy.AllowTimestampRegression = s.quarantine != nil
+	if err := s.validator.Validate(session.GetRAV(), signedRAV, policy); err != nil {
+		code := ravRejectionCode(err)
+		s.logger.Warn("rejecting RAV", zap.String("session_id", sessionID), zap.Stringer("code", code), zap.Error(err))
+
+		// The stream must stop on RAVs the payer's escrow cannot cover, further
+		// usage would not be collectable either
 		return connect.NewResponse(&providerv1.SubmitRAVResponse{
 			Accepted:        false,
-			RejectionReason: fmt.Sprintf("signature verification failed: %v", err),
-			ShouldContinue:  true,
+			RejectionReason: err.Error(),
+			RejectionCode:   code,
+			ShouldContinue:  code != commonv1.RejectionCode_REJECTION_CODE_EXCEEDS_ESCROW,
 		}), nil
 	}
 
-	// Check if signer is authorized
-	if !s.isAcceptedSigner(signerAddr) {
-		s.logger.Warn("RAV signer not authorized",
-			zap.Stringer("signer", signerAddr),
-		)
-		return connect.NewResponse(&providerv1.SubmitRAVResponse{
-			Accepted:        false,
-			RejectionReason: fmt.Sprintf("signer %s is not authorized", signerAddr.Pretty()),
-			ShouldContinue:  true,
-		}), nil
+	// The signature was verified above, recovering the signer cannot fail
+	signerAddr, err := s.verifyRAVSignature(signedRAV)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
 	}
 
-	// Verify RAV is for the correct session participants
-	if !sidecar.AddressesEqual(signedRAV.Message.Payer, session.Payer) {
+	// Hold RAVs failing the soft checks for review instead of applying them
+	reasons, err := s.quarantineRAV(session, signerAddr, signedRAV)
+	if err != nil {
+		s.logger.Warn("rejecting suspicious RAV", zap.String("session_id", sessionID), zap.Strings("reasons", reasons), zap.Error(err))
 		return connect.NewResponse(&providerv1.SubmitRAVResponse{
 			Accepted:        false,
-			RejectionReason: "RAV payer does not match session",
+			RejectionReason: fmt.Sprintf("%v: %s", err, strings.Join(reasons, "; ")),
+			RejectionCode:   commonv1.RejectionCode_REJECTION_CODE_INVALID_RAV,
 			ShouldContinue:  true,
 		}), nil
 	}
-	if !sidecar.AddressesEqual(signedRAV.Message.ServiceProvider, s.serviceProvider) {
+	if len(reasons) > 0 {
 		return connect.NewResponse(&providerv1.SubmitRAVResponse{
 			Accepted:        false,
-			RejectionReason: "RAV service provider does not match",
+			RejectionReason: "RAV quarantined for review: " + strings.Join(reasons, "; "),
+			RejectionCode:   commonv1.RejectionCode_REJECTION_CODE_INVALID_RAV,
 			ShouldContinue:  true,
 		}), nil
 	}
 
-	// Verify RAV value is greater than or equal to previous RAV
-	currentRAV := session.GetRAV()
-	if currentRAV != nil && currentRAV.Message != nil {
-		if signedRAV.Message.ValueAggregate.Cmp(currentRAV.Message.ValueAggregate) < 0 {
-			return connect.NewResponse(&providerv1.SubmitRAVResponse{
-				Accepted:        false,
-				RejectionReason: "RAV value is less than current RAV",
-				ShouldContinue:  true,
-			}), nil
-		}
-	}
-
-	// Store the new RAV
+	// Store the new RAV, settling again an ended session as of its final RAV
 	session.SetRAV(signedRAV)
+	if !session.IsActive() {
+		s.settle(ctx, session)
+	}
+	s.persistSession(session)
+	s.ravTurnaround.received(sessionID, session.Payer, time.Now())
+	s.ravRequests.received(session, time.Now())
+	if !session.IsActive() {
+		s.ravRequests.forget(sessionID)
+		s.queueAutoCollect(session)
+	}
 
 	s.logger.Info("SubmitRAV accepted",
 		zap.String("session_id", sessionID),
 		zap.Stringer("signer", signerAddr),
-		zap.String("value", signedRAV.Message.ValueAggregate.String()),
+		s.display.Field("value", signedRAV.Message.ValueAggregate),
 	)
 
 	response := &providerv1.SubmitRAVResponse{
 		Accepted:       true,
 		ShouldContinue: true,
+		AggregatedRav:  aggregatedRAV,
 	}
 
 	return connect.NewResponse(response), nil
 }
+
+// submitReceipts buffers the receipts submitted for a session in receipts
+// payment mode until the next aggregation
+func (s *Sidecar) submitReceipts(session *sidecar.Session, req *providerv1.SubmitRAVRequest) (*connect.Response[providerv1.SubmitRAVResponse], error) {
+	if req.SignedRav != nil || len(req.Receipts) == 0 {
+		return connect.NewResponse(&providerv1.SubmitRAVResponse{
+			Accepted:        false,
+			RejectionReason: "sessions in receipts payment mode only accept receipts",
+			RejectionCode:   commonv1.RejectionCode_REJECTION_CODE_INVALID_REQUEST,
+			ShouldContinue:  true,
+		}), nil
+	}
+
+	receipts, err := toHorizonReceipts(req.Receipts)
+	if err == nil {
+		err = s.bufferReceipts(session, receipts)
+	}
+	if err != nil {
+		s.logger.Warn("rejecting receipts", zap.String("session_id", session.ID), zap.Error(err))
+		return connect.NewResponse(&providerv1.SubmitRAVResponse{
+			Accepted:        false,
+			RejectionReason: err.Error(),
+			RejectionCode:   commonv1.RejectionCode_REJECTION_CODE_INVALID_REQUEST,
+			ShouldContinue:  true,
+		}), nil
+	}
+
+	s.logger.Debug("SubmitRAV buffered receipts",
+		zap.String("session_id", session.ID),
+		zap.Int("receipts", len(receipts)),
+	)
+
+	return connect.NewResponse(&providerv1.SubmitRAVResponse{
+		Accepted:       true,
+		ShouldContinue: true,
+	}), nil
+}
+
+// aggregateReceipts aggregates receipts on top of the session's current RAV
+// and returns the aggregated RAV
+func (s *Sidecar) aggregateReceipts(ctx context.Context, session *sidecar.Session, receipts []*commonv1.SignedReceipt) (*horizon.SignedRAV, error) {
+	if s.aggregator == nil {
+		return nil, fmt.Errorf("no receipt aggregator configured, submit a RAV instead")
+	}
+
+	signedReceipts, err := toHorizonReceipts(receipts)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.aggregator.AggregateReceipts(ctx, signedReceipts, session.GetRAV())
+}
+
+func toHorizonReceipts(receipts []*commonv1.SignedReceipt) ([]*horizon.SignedReceipt, error) {
+	signedReceipts := make([]*horizon.SignedReceipt, 0, len(receipts))
+	for i, receipt := range receipts {
+		signedReceipt := sidecar.ProtoSignedReceiptToHorizon(receipt)
+		if signedReceipt == nil {
+			return nil, fmt.Errorf("invalid or missing receipt at index %d", i)
+		}
+		signedReceipts = append(signedReceipts, signedReceipt)
+	}
+	return signedReceipts, nil
+}
@@ -2,21 +2,30 @@ package sidecar
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
 	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
 	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
 	"github.com/graphprotocol/substreams-data-service/sidecar"
 	"go.uber.org/zap"
 )
 
+// errRAVReplayed rejects a session-initiating RAV re-sent once the session it
+// opened has ended
+var errRAVReplayed = errors.New("RAV already used to open session")
+
 // ValidatePayment validates a RAV received from a client.
 // Called by the provider when a client connects with a payment header.
 func (s *Sidecar) ValidatePayment(
 	ctx context.Context,
 	req *connect.Request[providerv1.ValidatePaymentRequest],
 ) (*connect.Response[providerv1.ValidatePaymentResponse], error) {
+	defer s.latency.observe(ctx, "ValidatePayment", time.Now())
+
 	s.logger.Info("ValidatePayment called")
 
 	// Convert proto RAV to horizon RAV for verification
@@ -25,68 +34,103 @@ func (s *Sidecar) ValidatePayment(
 		return connect.NewResponse(&providerv1.ValidatePaymentResponse{
 			Valid:           false,
 			RejectionReason: "invalid or missing RAV",
+			RejectionCode:   commonv1.RejectionCode_REJECTION_CODE_INVALID_RAV,
 		}), nil
 	}
 
-	// Verify the signature
-	signerAddr, err := s.verifyRAVSignature(signedRAV)
-	if err != nil {
-		s.logger.Warn("failed to verify RAV signature", zap.Error(err))
-		return connect.NewResponse(&providerv1.ValidatePaymentResponse{
-			Valid:           false,
-			RejectionReason: fmt.Sprintf("signature verification failed: %v", err),
-		}), nil
+	payer := signedRAV.Message.Payer
+	dataService := signedRAV.Message.DataService
+
+	// Look for an existing session, a RAV sent for it must not switch its
+	// collection or parties nor decrease its value
+	var session *sidecar.Session
+	if req.Msg.ClientSessionId != "" {
+		session, _ = s.sessions.Get(req.Msg.ClientSessionId)
 	}
 
-	// Check if signer is authorized
-	if !s.isAcceptedSigner(signerAddr) {
-		s.logger.Warn("signer not authorized",
-			zap.Stringer("signer", signerAddr),
-		)
+	// Validate the RAV: signature, signer, parties, escrow cap and, for an
+	// existing session, continuity with its current RAV
+	var previous *horizon.SignedRAV
+	parties := horizon.RAVParties{Payer: payer, ServiceProvider: s.serviceProvider, DataService: dataService}
+	if session != nil {
+		previous, parties = session.GetRAV(), sessionParties(session)
+	}
+	if err := s.validator.Validate(previous, signedRAV, s.ravPolicy(ctx, parties)); err != nil {
+		code := ravRejectionCode(err)
+		s.logger.Warn("rejecting RAV", zap.Stringer("payer", payer), zap.Stringer("code", code), zap.Error(err))
 		return connect.NewResponse(&providerv1.ValidatePaymentResponse{
 			Valid:           false,
-			RejectionReason: fmt.Sprintf("signer %s is not authorized", signerAddr.Pretty()),
+			RejectionReason: err.Error(),
+			RejectionCode:   code,
 		}), nil
 	}
 
-	// Verify RAV is for this service provider
-	if !sidecar.AddressesEqual(signedRAV.Message.ServiceProvider, s.serviceProvider) {
-		s.logger.Warn("RAV is for different service provider",
-			zap.Stringer("expected", s.serviceProvider),
-			zap.Stringer("got", signedRAV.Message.ServiceProvider),
-		)
+	// The signature was verified above, recovering the signer cannot fail
+	signerAddr, err := s.verifyRAVSignature(signedRAV)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+
+	// Apply the acceptance policy on top of the validity of the RAV
+	if err := s.checkPaymentPolicy(ctx, "ValidatePayment", &policyRequest{
+		payer:         payer,
+		session:       session,
+		rav:           signedRAV.Message,
+		pricePerBlock: s.servicePricePerBlock(req.Msg.ServiceParams),
+	}); err != nil {
+		s.logger.Warn("rejecting payment violating the payment policy", zap.Stringer("payer", payer), zap.Error(err))
 		return connect.NewResponse(&providerv1.ValidatePaymentResponse{
 			Valid:           false,
-			RejectionReason: "RAV is for a different service provider",
+			RejectionReason: err.Error(),
+			RejectionCode:   commonv1.RejectionCode_REJECTION_CODE_POLICY_VIOLATION,
 		}), nil
 	}
 
-	// Create or get session
-	payer := signedRAV.Message.Payer
-	dataService := signedRAV.Message.DataService
+	if session == nil {
+		if s.draining.Load() {
+			return connect.NewResponse(&providerv1.ValidatePaymentResponse{
+				Valid:           false,
+				RejectionReason: shutdownStopReason,
+				RejectionCode:   commonv1.RejectionCode_REJECTION_CODE_SHUTTING_DOWN,
+			}), nil
+		}
 
-	// Look for existing session or create new one
-	var session *sidecar.Session
-	if req.Msg.ClientSessionId != "" {
-		var err error
-		session, err = s.sessions.Get(req.Msg.ClientSessionId)
+		if err := s.checkBootstrapRAV(signedRAV.Message, time.Now()); err != nil {
+			s.logger.Warn("rejecting bootstrap RAV", zap.Stringer("payer", payer), zap.Error(err))
+			return connect.NewResponse(&providerv1.ValidatePaymentResponse{
+				Valid:           false,
+				RejectionReason: err.Error(),
+				RejectionCode:   commonv1.RejectionCode_REJECTION_CODE_INVALID_RAV,
+			}), nil
+		}
+
+		session, err = s.openSession(signedRAV)
 		if err != nil {
-			// Create new session if not found
-			session = s.sessions.Create(payer, s.serviceProvider, dataService)
+			s.logger.Warn("rejecting replayed session-initiating RAV", zap.Stringer("payer", payer), zap.Error(err))
+			code := commonv1.RejectionCode_REJECTION_CODE_INTERNAL
+			if errors.Is(err, errRAVReplayed) {
+				code = commonv1.RejectionCode_REJECTION_CODE_RAV_REPLAYED
+			}
+			return connect.NewResponse(&providerv1.ValidatePaymentResponse{
+				Valid:           false,
+				RejectionReason: err.Error(),
+				RejectionCode:   code,
+			}), nil
 		}
-	} else {
-		session = s.sessions.Create(payer, s.serviceProvider, dataService)
 	}
 
 	// Store the RAV
 	session.SetRAV(signedRAV)
+	s.ravTurnaround.received(session.ID, payer, time.Now())
+	s.ravRequests.received(session, time.Now())
 
 	// Set pricing config on session
 	session.SetPricingConfig(s.pricingConfig)
+	s.persistSession(session)
 
 	// Query escrow balance from chain
 	var availableBalance *commonv1.BigInt
-	if escrowBalance, err := s.GetEscrowBalance(ctx, payer); err != nil {
+	if escrowBalance, err := s.startEscrowBalance(ctx, payer); err != nil {
 		s.logger.Warn("failed to query escrow balance", zap.Error(err))
 	} else if escrowBalance != nil {
 		availableBalance = commonv1.BigIntFromNative(escrowBalance)
@@ -101,6 +145,7 @@ func (s *Sidecar) ValidatePayment(
 			Payer:       commonv1.AddressFromEth(payer),
 			Receiver:    commonv1.AddressFromEth(s.serviceProvider),
 			DataService: commonv1.AddressFromEth(dataService),
+			Collector:   commonv1.AddressFromEth(s.domain.VerifyingContract),
 		},
 		AvailableBalance: availableBalance,
 	}
@@ -113,3 +158,31 @@ func (s *Sidecar) ValidatePayment(
 
 	return connect.NewResponse(response), nil
 }
+
+// openSession creates the session initiated by signedRAV. The same RAV re-sent
+// within the replay window is attached to the session it opened while that
+// session is active, and rejected once it has ended.
+func (s *Sidecar) openSession(signedRAV *horizon.SignedRAV) (*sidecar.Session, error) {
+	rav := signedRAV.Message
+
+	digest, err := horizon.HashTypedData(s.domain, rav)
+	if err != nil {
+		return nil, fmt.Errorf("computing RAV digest: %w", err)
+	}
+
+	session := s.sessions.CreateWithCollector(rav.Payer, s.serviceProvider, rav.DataService, s.domain.VerifyingContract)
+
+	existingID, claimed := s.replayGuard.Claim(sidecar.ReplayKey(rav.Payer, rav.CollectionID, digest), session.ID)
+	if claimed {
+		return session, nil
+	}
+	s.sessions.Delete(session.ID)
+
+	existing, err := s.sessions.Get(existingID)
+	if err != nil || !existing.IsActive() {
+		return nil, fmt.Errorf("%w %s", errRAVReplayed, existingID)
+	}
+
+	s.logger.Debug("attaching replayed RAV to existing session", zap.String("session_id", existingID))
+	return existing, nil
+}
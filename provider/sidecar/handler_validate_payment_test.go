@@ -0,0 +1,120 @@
+package sidecar
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestValidatePayment_ReplayProtection(t *testing.T) {
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	domain := horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111"))
+
+	s := New(&Config{
+		ServiceProvider: serviceProvider,
+		Domain:          domain,
+		AcceptedSigners: []eth.Address{signerKey.PublicKey().Address()},
+	}, zap.NewNop())
+
+	signedRAV, err := horizon.SignBootstrapRAV(domain, horizon.RAVParties{
+		Payer:           signerKey.PublicKey().Address(),
+		ServiceProvider: serviceProvider,
+		DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+	}, horizon.CollectionID{}, func() time.Time { return time.Unix(0, 1234567890) }, horizon.NewLocalSigner(signerKey))
+	require.NoError(t, err)
+
+	validate := func() *providerv1.ValidatePaymentResponse {
+		resp, err := s.ValidatePayment(context.Background(), connect.NewRequest(&providerv1.ValidatePaymentRequest{
+			PaymentRav: sidecar.HorizonSignedRAVToProto(signedRAV),
+		}))
+		require.NoError(t, err)
+		return resp.Msg
+	}
+
+	first := validate()
+	require.True(t, first.Valid, first.RejectionReason)
+
+	replayed := validate()
+	require.True(t, replayed.Valid, replayed.RejectionReason)
+	assert.Equal(t, first.SessionId, replayed.SessionId)
+	assert.Equal(t, 1, s.sessions.Count())
+
+	session, err := s.sessions.Get(first.SessionId)
+	require.NoError(t, err)
+	session.End(commonv1.EndReason_END_REASON_COMPLETE)
+
+	rejected := validate()
+	assert.False(t, rejected.Valid)
+	assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_RAV_REPLAYED, rejected.RejectionCode)
+	assert.Equal(t, 1, s.sessions.Count())
+}
+
+func TestValidatePayment_MetadataLimits(t *testing.T) {
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	domain := horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111"))
+
+	validate := func(strict bool, metadata []byte) *providerv1.ValidatePaymentResponse {
+		s := New(&Config{
+			ServiceProvider: serviceProvider,
+			Domain:          domain,
+			AcceptedSigners: []eth.Address{signerKey.PublicKey().Address()},
+			MaxMetadataSize: 64,
+			StrictMetadata:  strict,
+		}, zap.NewNop())
+
+		var collectionID horizon.CollectionID
+		copy(collectionID[:], metadata)
+		signedRAV, err := horizon.Sign(domain, &horizon.RAV{
+			CollectionID:    collectionID,
+			Payer:           signerKey.PublicKey().Address(),
+			DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+			ServiceProvider: serviceProvider,
+			TimestampNs:     1234567890,
+			ValueAggregate:  big.NewInt(0),
+			Metadata:        metadata,
+		}, signerKey)
+		require.NoError(t, err)
+
+		resp, err := s.ValidatePayment(context.Background(), connect.NewRequest(&providerv1.ValidatePaymentRequest{
+			PaymentRav: sidecar.HorizonSignedRAVToProto(signedRAV),
+		}))
+		require.NoError(t, err)
+		return resp.Msg
+	}
+
+	collectionID := bytes.Repeat([]byte{0x01}, 32)
+
+	accepted := validate(true, collectionID)
+	assert.True(t, accepted.Valid, accepted.RejectionReason)
+
+	oversized := validate(false, bytes.Repeat([]byte{0x01}, 65))
+	assert.False(t, oversized.Valid)
+	assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_INVALID_RAV, oversized.RejectionCode)
+	assert.Contains(t, oversized.RejectionReason, horizon.ErrMetadataTooLarge.Error())
+
+	unknown := append(bytes.Clone(collectionID), 0xff)
+	lenient := validate(false, unknown)
+	assert.True(t, lenient.Valid, lenient.RejectionReason)
+
+	strict := validate(true, unknown)
+	assert.False(t, strict.Valid)
+	assert.Contains(t, strict.RejectionReason, horizon.ErrMetadataUnknownType.Error())
+}
@@ -0,0 +1,7 @@
+package sidecar
+
+// DefaultMaxMetadataSize is the RAV metadata size accepted from consumers when
+// Config.MaxMetadataSize is zero, well above horizon.ReceiptsRootMetadataLength.
+// Metadata is kept with every session and sent on-chain on collect, so
+// consumers must not be able to grow it without bound.
+const DefaultMaxMetadataSize = 1024
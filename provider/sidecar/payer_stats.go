@@ -0,0 +1,152 @@
+package sidecar
+
+import (
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+)
+
+// PayerStats aggregates the sessions of a payer as served by the admin API, so
+// operators can decide which payers to prioritize or deny. Values are rendered
+// in the configured amount unit.
+type PayerStats struct {
+	Payer          string `json:"payer"`
+	Sessions       int    `json:"sessions"`
+	ActiveSessions int    `json:"active_sessions"`
+	// ValueSigned totals the current RAV of each session of the payer
+	ValueSigned string `json:"value_signed"`
+	// ValueCollected totals the final RAVs collected on-chain
+	ValueCollected string `json:"value_collected"`
+	// ValueDisputed totals the RAVs held in quarantine for review and the final
+	// RAVs whose collection kept failing (dead-lettered)
+	ValueDisputed string `json:"value_disputed"`
+	// AverageSessionLength is the average duration of the ended sessions, as a
+	// Go duration (e.g. "1h2m3s"), empty when none ended yet
+	AverageSessionLength string    `json:"average_session_length,omitempty"`
+	LastSessionAt        time.Time `json:"last_session_at"`
+	// RAVsReturned counts the RAVs the payer returned while one was due,
+	// RAVTurnaroundAverage and RAVTurnaroundMax are how long it took to return
+	// them as Go durations, empty when none was returned since the last restart
+	RAVsReturned         int    `json:"ravs_returned,omitempty"`
+	RAVTurnaroundAverage string `json:"rav_turnaround_average,omitempty"`
+	RAVTurnaroundMax     string `json:"rav_turnaround_max,omitempty"`
+}
+
+// payerStats accumulates the statistics of a payer
+type payerStats struct {
+	payer          string
+	sessions       int
+	activeSessions int
+	ended          int
+	endedLength    time.Duration
+	valueSigned    *big.Int
+	valueCollected *big.Int
+	valueDisputed  *big.Int
+	lastSessionAt  time.Time
+}
+
+// payerStats returns the statistics of each payer with a session known to the
+// sidecar, highest value signed first. Sessions only live in memory unless a
+// session store is configured, statistics then cover the sessions since the
+// last restart.
+func (s *Sidecar) payerStats() []*payerStats {
+	byPayer := make(map[string]*payerStats)
+	statsOf := func(payer eth.Address) *payerStats {
+		key := payer.Pretty()
+		stats, found := byPayer[key]
+		if !found {
+			stats = &payerStats{payer: key, valueSigned: new(big.Int), valueCollected: new(big.Int), valueDisputed: new(big.Int)}
+			byPayer[key] = stats
+		}
+		return stats
+	}
+
+	for _, session := range s.sessions.List() {
+		snapshot := session.Snapshot()
+		stats := statsOf(session.Payer)
+		stats.sessions++
+		if snapshot.CreatedAt.After(stats.lastSessionAt) {
+			stats.lastSessionAt = snapshot.CreatedAt
+		}
+
+		if snapshot.State == sidecar.SessionStateActive {
+			stats.activeSessions++
+		} else if snapshot.EndedAt != nil {
+			stats.ended++
+			stats.endedLength += snapshot.EndedAt.Sub(snapshot.CreatedAt)
+		}
+
+		value := ravValue(session.GetRAV())
+		stats.valueSigned.Add(stats.valueSigned, value)
+		if s.collections.isCollected(session.ID) {
+			stats.valueCollected.Add(stats.valueCollected, value)
+		}
+	}
+
+	if s.quarantine != nil {
+		for _, entry := range s.quarantine.list() {
+			stats := statsOf(entry.SignedRAV.Message.Payer)
+			stats.valueDisputed.Add(stats.valueDisputed, ravValue(entry.SignedRAV))
+		}
+	}
+	if s.collectRetries != nil {
+		for _, failure := range s.collectRetries.deadLetters() {
+			stats := statsOf(failure.signedRAV.Message.Payer)
+			stats.valueDisputed.Add(stats.valueDisputed, ravValue(failure.signedRAV))
+		}
+	}
+
+	out := make([]*payerStats, 0, len(byPayer))
+	for _, stats := range byPayer {
+		out = append(out, stats)
+	}
+	slices.SortFunc(out, func(a, b *payerStats) int {
+		if c := b.valueSigned.Cmp(a.valueSigned); c != 0 {
+			return c
+		}
+		return strings.Compare(a.payer, b.payer)
+	})
+	return out
+}
+
+// ravValue returns the value aggregate of signedRAV, zero when there is none
+func ravValue(signedRAV *horizon.SignedRAV) *big.Int {
+	if signedRAV == nil || signedRAV.Message == nil || signedRAV.Message.ValueAggregate == nil {
+		return new(big.Int)
+	}
+	return signedRAV.Message.ValueAggregate
+}
+
+func (s *Sidecar) handleAdminPayerStats(w http.ResponseWriter, r *http.Request) {
+	stats := s.payerStats()
+
+	payers := make([]*PayerStats, 0, len(stats))
+	for _, payer := range stats {
+		out := &PayerStats{
+			Payer:          payer.payer,
+			Sessions:       payer.sessions,
+			ActiveSessions: payer.activeSessions,
+			ValueSigned:    s.display.Format(payer.valueSigned),
+			ValueCollected: s.display.Format(payer.valueCollected),
+			ValueDisputed:  s.display.Format(payer.valueDisputed),
+			LastSessionAt:  payer.lastSessionAt,
+		}
+		if payer.ended > 0 {
+			out.AverageSessionLength = (payer.endedLength / time.Duration(payer.ended)).Round(time.Second).String()
+		}
+		if turnaround, found := s.ravTurnaround.stats(eth.MustNewAddress(payer.payer)); found {
+			out.RAVsReturned = turnaround.Count
+			out.RAVTurnaroundAverage = turnaround.Average.Round(time.Millisecond).String()
+			out.RAVTurnaroundMax = turnaround.Max.Round(time.Millisecond).String()
+		}
+		payers = append(payers, out)
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]any{"amount_unit": s.display.UnitName(), "payers": payers})
+}
@@ -0,0 +1,83 @@
+package sidecar
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestAdminPayerStats(t *testing.T) {
+	payerA := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
+	payerB := eth.MustNewAddress("0x5555555555555555555555555555555555555555")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+
+	s := New(&Config{
+		ListenAddr:      ":0",
+		ServiceProvider: serviceProvider,
+		Domain:          horizon.NewDomain(1337, eth.MustNewAddress("0x4444444444444444444444444444444444444444")),
+		AdminListenAddr: ":0",
+		Quarantine:      &QuarantinePolicy{},
+	}, zap.NewNop())
+
+	newRAV := func(payer eth.Address, value int64) *horizon.SignedRAV {
+		return &horizon.SignedRAV{Message: &horizon.RAV{
+			Payer:           payer,
+			DataService:     dataService,
+			ServiceProvider: serviceProvider,
+			ValueAggregate:  big.NewInt(value),
+		}}
+	}
+	newSession := func(payer eth.Address, value int64, length time.Duration) string {
+		session := s.sessions.Create(payer, serviceProvider, dataService)
+		session.SetRAV(newRAV(payer, value))
+		if length > 0 {
+			session.End(commonv1.EndReason_END_REASON_COMPLETE)
+			endedAt := session.CreatedAt.Add(length)
+			session.EndedAt = &endedAt
+		}
+		return session.ID
+	}
+
+	collectedID := newSession(payerA, 1000, time.Minute)
+	newSession(payerA, 2000, 3*time.Minute)
+	newSession(payerA, 500, 0)
+	newSession(payerB, 100, 0)
+	s.collections.done(collectedID, "0xabc", true)
+	require.True(t, s.quarantine.add(&quarantinedRAV{SignedRAV: newRAV(payerB, 700), QuarantinedAt: time.Now()}))
+
+	rec := httptest.NewRecorder()
+	s.admin.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/payers/stats", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var out struct {
+		Payers []*PayerStats `json:"payers"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+	require.Len(t, out.Payers, 2)
+
+	a := out.Payers[0]
+	assert.Equal(t, payerA.Pretty(), a.Payer)
+	assert.Equal(t, 3, a.Sessions)
+	assert.Equal(t, 1, a.ActiveSessions)
+	assert.Equal(t, "3500", a.ValueSigned)
+	assert.Equal(t, "1000", a.ValueCollected)
+	assert.Equal(t, "0", a.ValueDisputed)
+	assert.Equal(t, "2m0s", a.AverageSessionLength)
+
+	b := out.Payers[1]
+	assert.Equal(t, payerB.Pretty(), b.Payer)
+	assert.Equal(t, "100", b.ValueSigned)
+	assert.Equal(t, "700", b.ValueDisputed)
+	assert.Empty(t, b.AverageSessionLength)
+}
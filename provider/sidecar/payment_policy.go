@@ -0,0 +1,207 @@
+package sidecar
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/streamingfast/eth-go"
+	"go.uber.org/zap"
+)
+
+// errPolicyViolation rejects payments and stops sessions the acceptance policy
+// does not allow, on top of the validity of their RAVs
+var errPolicyViolation = errors.New("payment policy violation")
+
+// Rules of the payment policy, as reported in rejection reasons and metrics
+const (
+	policyRuleMinEscrowHeadroom   = "min_escrow_headroom"
+	policyRuleMaxPayerDebt        = "max_payer_debt"
+	policyRuleMinPricePerBlock    = "min_price_per_block"
+	policyRuleAcceptedCollections = "accepted_collections"
+	policyRuleMaxSessionDuration  = "max_session_duration"
+)
+
+// PaymentPolicy bounds the payments the provider accepts beyond the validity
+// of their RAVs, which the signer allowlist and the RAV checks cover. It is
+// evaluated when a payment is validated (ValidatePayment) and on every usage
+// report (ReportUsage), a violation rejecting the payment or stopping the
+// session with REJECTION_CODE_POLICY_VIOLATION. Rules left to their zero value
+// are not enforced.
+type PaymentPolicy struct {
+	// MinEscrowHeadroom is the escrow balance, in GRT wei, the payer must keep
+	// on top of what its active sessions owe (the highest of their usage cost
+	// and RAV value). It is not enforced without RPCEndpoint and EscrowAddr, nor
+	// while no balance could be read.
+	MinEscrowHeadroom *big.Int
+	// MaxPayerDebt bounds, in GRT wei, the usage value the active sessions of a
+	// payer were served beyond their RAVs
+	MaxPayerDebt *big.Int
+	// MinPricePerBlock is the lowest price per block, in GRT wei, payments are
+	// accepted for: the price of the service parameters (the configured pricing
+	// when not set) on ValidatePayment, the cost of the reported blocks on
+	// ReportUsage
+	MinPricePerBlock *big.Int
+	// AcceptedCollections restricts the collections RAVs may be for, any when
+	// empty
+	AcceptedCollections []horizon.CollectionID
+	// MaxSessionDuration bounds how long a session is served from its start
+	MaxSessionDuration time.Duration
+}
+
+// paymentPolicy evaluates a PaymentPolicy, counting rejections per rule in the
+// sds_provider_policy_rejections_total metric
+type paymentPolicy struct {
+	policy              *PaymentPolicy
+	acceptedCollections map[horizon.CollectionID]bool
+
+	// Escrow balance snapshots of the payers, nil when balances cannot be read
+	balances *escrowCaps
+
+	rejections *prometheus.CounterVec
+}
+
+func newPaymentPolicy(policy *PaymentPolicy, fetch func(ctx context.Context, payer eth.Address) (*big.Int, error), registry *prometheus.Registry) *paymentPolicy {
+	p := &paymentPolicy{
+		policy: policy,
+		rejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "sds_provider_policy_rejections_total",
+			Help: "Payments rejected and sessions stopped by the payment policy, by rule and request",
+		}, []string{"rule", "method"}),
+	}
+
+	if len(policy.AcceptedCollections) > 0 {
+		p.acceptedCollections = make(map[horizon.CollectionID]bool, len(policy.AcceptedCollections))
+		for _, collectionID := range policy.AcceptedCollections {
+			p.acceptedCollections[collectionID] = true
+		}
+	}
+	if policy.MinEscrowHeadroom != nil && fetch != nil {
+		p.balances = newEscrowCaps(DefaultEscrowBalanceTTL, fetch)
+	}
+
+	if registry != nil {
+		registry.MustRegister(p.rejections)
+	}
+	return p
+}
+
+// policyRequest is what the payment policy is evaluated on
+type policyRequest struct {
+	payer eth.Address
+	// session is the session paid for, nil for a payment opening a new session
+	session *sidecar.Session
+	// rav is the RAV the session is paid with, nil when it has none yet
+	rav *horizon.RAV
+	// pricePerBlock is the price the blocks are paid at, nil when not known
+	pricePerBlock *big.Int
+}
+
+// evaluate returns the rule req violates with the reason, an empty rule when
+// it complies with the policy
+func (p *paymentPolicy) evaluate(ctx context.Context, sessions []*sidecar.Session, req *policyRequest, now time.Time, logger *zap.Logger) (string, error) {
+	policy := p.policy
+
+	if p.acceptedCollections != nil && req.rav != nil && !p.acceptedCollections[req.rav.CollectionID] {
+		return policyRuleAcceptedCollections, fmt.Errorf("%w: collection %s is not accepted", errPolicyViolation, eth.Hash(req.rav.CollectionID[:]).Pretty())
+	}
+
+	if policy.MaxSessionDuration > 0 && req.session != nil {
+		if duration := now.Sub(req.session.CreatedAt); duration > policy.MaxSessionDuration {
+			return policyRuleMaxSessionDuration, fmt.Errorf("%w: session lasted %s, more than the maximum of %s", errPolicyViolation, duration.Round(time.Second), policy.MaxSessionDuration)
+		}
+	}
+
+	if policy.MinPricePerBlock != nil && req.pricePerBlock != nil && req.pricePerBlock.Cmp(policy.MinPricePerBlock) < 0 {
+		return policyRuleMinPricePerBlock, fmt.Errorf("%w: price per block %s is lower than the minimum of %s", errPolicyViolation, req.pricePerBlock, policy.MinPricePerBlock)
+	}
+
+	if policy.MaxPayerDebt == nil && p.balances == nil {
+		return "", nil
+	}
+
+	// What the payer's active sessions owe, the session paid for with its RAV
+	// as paid in req
+	debt, owed := new(big.Int), new(big.Int)
+	account := func(cost, rav *big.Int) {
+		if cost.Cmp(rav) > 0 {
+			debt.Add(debt, new(big.Int).Sub(cost, rav))
+			owed.Add(owed, cost)
+		} else {
+			owed.Add(owed, rav)
+		}
+	}
+	for _, session := range sessions {
+		if req.session != nil && session.ID == req.session.ID {
+			continue
+		}
+		if bytes.Equal(session.Payer, req.payer) {
+			account(session.GetUsage().Cost.ToNative(), ravValue(session.GetRAV()))
+		}
+	}
+	cost, rav := new(big.Int), new(big.Int)
+	if req.session != nil {
+		cost = req.session.GetUsage().Cost.ToNative()
+	}
+	if req.rav != nil && req.rav.ValueAggregate != nil {
+		rav = req.rav.ValueAggregate
+	}
+	account(cost, rav)
+
+	if policy.MaxPayerDebt != nil && debt.Cmp(policy.MaxPayerDebt) > 0 {
+		return policyRuleMaxPayerDebt, fmt.Errorf("%w: payer owes %s not covered by RAVs, more than the maximum of %s", errPolicyViolation, debt, policy.MaxPayerDebt)
+	}
+
+	if p.balances != nil {
+		balance := p.balances.balance(ctx, req.payer, logger)
+		if balance != nil {
+			if headroom := new(big.Int).Sub(balance, owed); headroom.Cmp(policy.MinEscrowHeadroom) < 0 {
+				return policyRuleMinEscrowHeadroom, fmt.Errorf("%w: escrow balance %s leaves %s over what the payer owes, less than the minimum of %s", errPolicyViolation, balance, headroom, policy.MinEscrowHeadroom)
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// checkPaymentPolicy evaluates the payment policy on req for the method
+// request, it always passes when no policy is configured
+func (s *Sidecar) checkPaymentPolicy(ctx context.Context, method string, req *policyRequest) error {
+	if s.paymentPolicy == nil {
+		return nil
+	}
+
+	rule, err := s.paymentPolicy.evaluate(ctx, s.sessions.GetActive(), req, time.Now(), s.logger)
+	if err != nil {
+		s.paymentPolicy.rejections.WithLabelValues(rule, method).Inc()
+	}
+	return err
+}
+
+// servicePricePerBlock is the price per block of the service parameters of a
+// payment, the configured one when they do not set it
+func (s *Sidecar) servicePricePerBlock(params *commonv1.ServiceParameters) *big.Int {
+	if price := params.GetPricePerBlock(); price != nil {
+		return price.ToNative()
+	}
+	if s.pricingConfig.PricePerBlock != nil {
+		return s.pricingConfig.PricePerBlock.Wei()
+	}
+	return nil
+}
+
+// reportedPricePerBlock is the price per block usage was reported at, nil when
+// it holds no blocks or no cost
+func reportedPricePerBlock(blocks uint64, cost *big.Int) *big.Int {
+	if blocks == 0 || cost == nil {
+		return nil
+	}
+	return new(big.Int).Div(cost, new(big.Int).SetUint64(blocks))
+}
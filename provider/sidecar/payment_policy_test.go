@@ -0,0 +1,162 @@
+package sidecar
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPaymentPolicy_ValidatePayment(t *testing.T) {
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	domain := horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111"))
+
+	s := New(&Config{
+		ListenAddr:      ":0",
+		ServiceProvider: serviceProvider,
+		Domain:          domain,
+		AcceptedSigners: []eth.Address{signerKey.PublicKey().Address()},
+		AdminListenAddr: ":0",
+		PaymentPolicy: &PaymentPolicy{
+			MinPricePerBlock:    big.NewInt(100),
+			AcceptedCollections: []horizon.CollectionID{{0x01}},
+		},
+	}, zap.NewNop())
+
+	// Proto RAVs carry the collection ID as the first 32 bytes of the metadata
+	validate := func(collectionID horizon.CollectionID, pricePerBlock int64) *providerv1.ValidatePaymentResponse {
+		signedRAV, err := horizon.Sign(domain, &horizon.RAV{
+			CollectionID:    collectionID,
+			Payer:           signerKey.PublicKey().Address(),
+			ServiceProvider: serviceProvider,
+			DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+			TimestampNs:     1234567890,
+			ValueAggregate:  big.NewInt(0),
+			Metadata:        collectionID[:],
+		}, signerKey)
+		require.NoError(t, err)
+
+		resp, err := s.ValidatePayment(context.Background(), connect.NewRequest(&providerv1.ValidatePaymentRequest{
+			PaymentRav:    sidecar.HorizonSignedRAVToProto(signedRAV),
+			ServiceParams: &commonv1.ServiceParameters{PricePerBlock: commonv1.BigIntFromNative(big.NewInt(pricePerBlock))},
+		}))
+		require.NoError(t, err)
+		return resp.Msg
+	}
+
+	accepted := validate(horizon.CollectionID{0x01}, 100)
+	assert.True(t, accepted.Valid, accepted.RejectionReason)
+
+	rejected := validate(horizon.CollectionID{0x02}, 100)
+	assert.False(t, rejected.Valid)
+	assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_POLICY_VIOLATION, rejected.RejectionCode)
+	assert.Contains(t, rejected.RejectionReason, "is not accepted")
+
+	rejected = validate(horizon.CollectionID{0x01}, 99)
+	assert.False(t, rejected.Valid)
+	assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_POLICY_VIOLATION, rejected.RejectionCode)
+	assert.Contains(t, rejected.RejectionReason, "lower than the minimum of 100")
+
+	rec := httptest.NewRecorder()
+	s.admin.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, rec.Body.String(), `sds_provider_policy_rejections_total{method="ValidatePayment",rule="accepted_collections"} 1`)
+	assert.Contains(t, rec.Body.String(), `sds_provider_policy_rejections_total{method="ValidatePayment",rule="min_price_per_block"} 1`)
+}
+
+func TestPaymentPolicy_ReportUsage(t *testing.T) {
+	payer := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+
+	newSidecar := func(policy *PaymentPolicy) *Sidecar {
+		return New(&Config{
+			ServiceProvider: serviceProvider,
+			Domain:          horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+			PaymentPolicy:   policy,
+		}, zap.NewNop())
+	}
+	newSession := func(s *Sidecar, ravValue int64) *sidecar.Session {
+		session := s.sessions.Create(payer, serviceProvider, dataService)
+		session.SetRAV(&horizon.SignedRAV{Message: &horizon.RAV{
+			CollectionID:   horizon.CollectionID{0x01},
+			Payer:          payer,
+			ValueAggregate: big.NewInt(ravValue),
+		}})
+		return session
+	}
+	report := func(s *Sidecar, session *sidecar.Session, blocks uint64, cost int64) *providerv1.ReportUsageResponse {
+		resp, err := s.ReportUsage(context.Background(), connect.NewRequest(&providerv1.ReportUsageRequest{
+			SessionId: session.ID,
+			Usage:     &commonv1.Usage{BlocksProcessed: blocks, Cost: commonv1.BigIntFromNative(big.NewInt(cost))},
+		}))
+		require.NoError(t, err)
+		return resp.Msg
+	}
+
+	t.Run("max payer debt", func(t *testing.T) {
+		s := newSidecar(&PaymentPolicy{MaxPayerDebt: big.NewInt(1000)})
+		first, second := newSession(s, 0), newSession(s, 0)
+
+		assert.True(t, report(s, first, 1, 600).ShouldContinue)
+		assert.True(t, report(s, second, 1, 400).ShouldContinue)
+
+		// The debt is the payer's, across its sessions
+		resp := report(s, second, 1, 1)
+		assert.False(t, resp.ShouldContinue)
+		assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_POLICY_VIOLATION, resp.StopCode)
+		assert.Contains(t, resp.StopReason, "payer owes 1001")
+
+		// Usage covered by a RAV is not debt
+		first.SetRAV(&horizon.SignedRAV{Message: &horizon.RAV{CollectionID: horizon.CollectionID{0x01}, Payer: payer, ValueAggregate: big.NewInt(600)}})
+		assert.True(t, report(s, second, 1, 1).ShouldContinue)
+	})
+
+	t.Run("min escrow headroom", func(t *testing.T) {
+		s := newSidecar(&PaymentPolicy{MinEscrowHeadroom: big.NewInt(500)})
+		s.paymentPolicy.balances = newEscrowCaps(time.Minute, func(ctx context.Context, payer eth.Address) (*big.Int, error) {
+			return big.NewInt(2000), nil
+		})
+		session := newSession(s, 1000)
+
+		assert.True(t, report(s, session, 1, 1500).ShouldContinue)
+		resp := report(s, session, 1, 1)
+		assert.False(t, resp.ShouldContinue)
+		assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_POLICY_VIOLATION, resp.StopCode)
+		assert.Contains(t, resp.StopReason, "leaves 499 over what the payer owes")
+	})
+
+	t.Run("min price per block", func(t *testing.T) {
+		s := newSidecar(&PaymentPolicy{MinPricePerBlock: big.NewInt(10)})
+		session := newSession(s, 0)
+
+		assert.True(t, report(s, session, 10, 100).ShouldContinue)
+		assert.True(t, report(s, session, 0, 0).ShouldContinue)
+		assert.False(t, report(s, session, 10, 99).ShouldContinue)
+	})
+
+	t.Run("max session duration", func(t *testing.T) {
+		s := newSidecar(&PaymentPolicy{MaxSessionDuration: time.Hour})
+		session := newSession(s, 0)
+
+		assert.True(t, report(s, session, 1, 1).ShouldContinue)
+		session.CreatedAt = time.Now().Add(-2 * time.Hour)
+		resp := report(s, session, 1, 1)
+		assert.False(t, resp.ShouldContinue)
+		assert.Contains(t, resp.StopReason, "more than the maximum of 1h0m0s")
+	})
+}
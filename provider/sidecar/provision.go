@@ -0,0 +1,59 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"go.uber.org/zap"
+)
+
+// DefaultProvisionCheckInterval is how often the provision is checked for
+// pending parameters when automatic acceptance is enabled
+const DefaultProvisionCheckInterval = 10 * time.Minute
+
+// watchProvision checks the provision for pending parameters every interval
+// and accepts them when within bounds, until the sidecar terminates
+func (s *Sidecar) watchProvision(interval time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.OnTerminating(func(_ error) {
+		cancel()
+	})
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.acceptPendingProvision(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Sidecar) acceptPendingProvision(ctx context.Context) {
+	provision, txHash, err := s.provisionAcceptor.AcceptPending(ctx)
+	switch {
+	case errors.Is(err, sidecar.ErrProvisionOutOfBounds):
+		s.logger.Warn("pending provision parameters not accepted, accept them manually or widen the bounds",
+			zap.Stringer("current", provision.Current),
+			zap.Stringer("pending", provision.Pending),
+			zap.Error(err),
+		)
+	case err != nil:
+		if ctx.Err() == nil {
+			s.logger.Warn("checking pending provision parameters failed", zap.Error(err))
+		}
+	case txHash != "":
+		s.logger.Info("accepted pending provision parameters",
+			zap.String("tx_hash", txHash),
+			zap.Stringer("previous", provision.Current),
+			zap.Stringer("accepted", provision.Pending),
+		)
+	}
+}
@@ -0,0 +1,309 @@
+package sidecar
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"go.uber.org/zap"
+)
+
+// DefaultQuarantineCapacity is the number of RAVs held in quarantine when
+// QuarantinePolicy.Capacity is zero
+const DefaultQuarantineCapacity = 1000
+
+// Errors returned when deciding on a quarantined RAV
+var (
+	ErrQuarantinedRAVNotFound = errors.New("quarantined RAV not found")
+	ErrQuarantinedRAVStale    = errors.New("quarantined RAV is below the session's current RAV")
+	ErrQuarantinedRAVOrphaned = errors.New("session of quarantined RAV not found")
+)
+
+var errQuarantineFull = errors.New("RAV quarantine is full")
+
+// QuarantinePolicy holds the soft checks applied to RAVs submitted during a
+// session on top of the hard ones. A RAV with a valid signature failing a soft
+// check is neither applied nor dropped but quarantined until an operator
+// approves or rejects it through the admin API. RAVs whose timestamp goes
+// backwards from the session's current RAV always fail the soft checks.
+type QuarantinePolicy struct {
+	// MaxValueIncrease is the largest increase of the value aggregate over the
+	// session's current RAV, in GRT wei, unchecked when nil
+	MaxValueIncrease *big.Int
+	// MaxClockSkew is how far in the future a RAV timestamp may be, unchecked
+	// when zero
+	MaxClockSkew time.Duration
+	// Capacity bounds the number of quarantined RAVs, DefaultQuarantineCapacity
+	// when zero. RAVs failing a soft check are rejected once it is reached.
+	Capacity int
+}
+
+// suspicious describes why rav fails the soft checks against the session's
+// current RAV, nil when it passes them
+func (p *QuarantinePolicy) suspicious(current *horizon.SignedRAV, rav *horizon.RAV, now time.Time) []string {
+	var reasons []string
+	if current != nil && current.Message != nil {
+		if p.MaxValueIncrease != nil && current.Message.ValueAggregate != nil {
+			increase := new(big.Int).Sub(rav.ValueAggregate, current.Message.ValueAggregate)
+			if increase.Cmp(p.MaxValueIncrease) > 0 {
+				reasons = append(reasons, fmt.Sprintf("value aggregate increased by %s, more than the maximum of %s", increase, p.MaxValueIncrease))
+			}
+		}
+		if rav.TimestampNs < current.Message.TimestampNs {
+			reasons = append(reasons, fmt.Sprintf("timestamp %d is before the current RAV timestamp %d", rav.TimestampNs, current.Message.TimestampNs))
+		}
+	}
+
+	if p.MaxClockSkew > 0 {
+		if ahead := time.Duration(int64(rav.TimestampNs) - now.UnixNano()); ahead > p.MaxClockSkew {
+			reasons = append(reasons, fmt.Sprintf("timestamp is %s in the future, more than the maximum clock skew of %s", ahead.Round(time.Millisecond), p.MaxClockSkew))
+		}
+	}
+	return reasons
+}
+
+// quarantinedRAV is a RAV held for review along with why it was quarantined
+type quarantinedRAV struct {
+	ID            string
+	SessionID     string
+	QuarantinedAt time.Time
+	Reasons       []string
+	Signer        eth.Address
+	SignedRAV     *horizon.SignedRAV
+}
+
+// ravQuarantine holds the quarantined RAVs until decided on
+type ravQuarantine struct {
+	policy *QuarantinePolicy
+
+	mu      sync.Mutex
+	nextID  uint64
+	entries map[string]*quarantinedRAV
+}
+
+func newRAVQuarantine(policy *QuarantinePolicy) *ravQuarantine {
+	return &ravQuarantine{policy: policy, entries: make(map[string]*quarantinedRAV)}
+}
+
+// add quarantines entry, assigning its ID, false when the quarantine is full
+func (q *ravQuarantine) add(entry *quarantinedRAV) bool {
+	capacity := q.policy.Capacity
+	if capacity <= 0 {
+		capacity = DefaultQuarantineCapacity
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.entries) >= capacity {
+		return false
+	}
+
+	q.nextID++
+	entry.ID = strconv.FormatUint(q.nextID, 10)
+	q.entries[entry.ID] = entry
+	return true
+}
+
+// list returns the quarantined RAVs, oldest first
+func (q *ravQuarantine) list() []*quarantinedRAV {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	out := make([]*quarantinedRAV, 0, len(q.entries))
+	for _, entry := range q.entries {
+		out = append(out, entry)
+	}
+	slices.SortFunc(out, func(a, b *quarantinedRAV) int {
+		return a.QuarantinedAt.Compare(b.QuarantinedAt)
+	})
+	return out
+}
+
+// get returns the quarantined RAV id
+func (q *ravQuarantine) get(id string) (*quarantinedRAV, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	entry, found := q.entries[id]
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrQuarantinedRAVNotFound, id)
+	}
+	return entry, nil
+}
+
+// take removes and returns the quarantined RAV id
+func (q *ravQuarantine) take(id string) (*quarantinedRAV, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	entry, found := q.entries[id]
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrQuarantinedRAVNotFound, id)
+	}
+	delete(q.entries, id)
+	return entry, nil
+}
+
+// quarantineRAV quarantines signedRAV of session when it fails the soft checks,
+// returning why, or nil when it can be applied. RAVs failing the soft checks
+// while the quarantine is full are rejected with errQuarantineFull.
+func (s *Sidecar) quarantineRAV(session *sidecar.Session, signer eth.Address, signedRAV *horizon.SignedRAV) ([]string, error) {
+	if s.quarantine == nil {
+		return nil, nil
+	}
+
+	now := time.Now()
+	reasons := s.quarantine.policy.suspicious(session.GetRAV(), signedRAV.Message, now)
+	if len(reasons) == 0 {
+		return nil, nil
+	}
+
+	entry := &quarantinedRAV{
+		SessionID:     session.ID,
+		QuarantinedAt: now,
+		Reasons:       reasons,
+		Signer:        signer,
+		SignedRAV:     signedRAV,
+	}
+	if !s.quarantine.add(entry) {
+		return reasons, errQuarantineFull
+	}
+
+	s.logger.Warn("RAV quarantined for review",
+		zap.String("quarantine_id", entry.ID),
+		zap.String("session_id", session.ID),
+		zap.Strings("reasons", reasons),
+		s.display.Field("value", signedRAV.Message.ValueAggregate),
+	)
+	return reasons, nil
+}
+
+// approveQuarantinedRAV applies the quarantined RAV id to its session, it is
+// kept in quarantine when its session is gone or it no longer is the latest RAV
+func (s *Sidecar) approveQuarantinedRAV(id string) (*quarantinedRAV, error) {
+	entry, err := s.quarantine.get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.sessions.Get(entry.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrQuarantinedRAVOrphaned, entry.SessionID)
+	}
+	if current := session.GetRAV(); current != nil && current.Message != nil && entry.SignedRAV.Message.ValueAggregate.Cmp(current.Message.ValueAggregate) < 0 {
+		return nil, ErrQuarantinedRAVStale
+	}
+
+	if _, err := s.quarantine.take(id); err != nil {
+		return nil, err
+	}
+
+	session.SetRAV(entry.SignedRAV)
+	s.persistSession(session)
+	s.logger.Info("quarantined RAV approved",
+		zap.String("quarantine_id", entry.ID),
+		zap.String("session_id", entry.SessionID),
+		s.display.Field("value", entry.SignedRAV.Message.ValueAggregate),
+	)
+	return entry, nil
+}
+
+// rejectQuarantinedRAV drops the quarantined RAV id
+func (s *Sidecar) rejectQuarantinedRAV(id string) (*quarantinedRAV, error) {
+	entry, err := s.quarantine.take(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("quarantined RAV rejected",
+		zap.String("quarantine_id", entry.ID),
+		zap.String("session_id", entry.SessionID),
+		s.display.Field("value", entry.SignedRAV.Message.ValueAggregate),
+	)
+	return entry, nil
+}
+
+// QuarantinedRAV is a quarantined RAV as served by the admin API, its values
+// are rendered in the configured amount unit
+type QuarantinedRAV struct {
+	ID             string    `json:"id"`
+	SessionID      string    `json:"session_id"`
+	QuarantinedAt  time.Time `json:"quarantined_at"`
+	Reasons        []string  `json:"reasons"`
+	Signer         string    `json:"signer"`
+	Payer          string    `json:"payer"`
+	CollectionID   string    `json:"collection_id"`
+	TimestampNs    uint64    `json:"timestamp_ns"`
+	ValueAggregate string    `json:"value_aggregate"`
+	CurrentValue   string    `json:"current_value,omitempty"`
+	Signature      string    `json:"signature"`
+}
+
+func (s *Sidecar) newQuarantinedRAV(entry *quarantinedRAV) *QuarantinedRAV {
+	rav := entry.SignedRAV.Message
+	out := &QuarantinedRAV{
+		ID:             entry.ID,
+		SessionID:      entry.SessionID,
+		QuarantinedAt:  entry.QuarantinedAt,
+		Reasons:        entry.Reasons,
+		Signer:         entry.Signer.Pretty(),
+		Payer:          rav.Payer.Pretty(),
+		CollectionID:   eth.Hash(rav.CollectionID[:]).Pretty(),
+		TimestampNs:    rav.TimestampNs,
+		ValueAggregate: s.display.Format(rav.ValueAggregate),
+		Signature:      "0x" + entry.SignedRAV.Signature.String(),
+	}
+
+	if session, err := s.sessions.Get(entry.SessionID); err == nil {
+		if current := session.GetRAV(); current != nil && current.Message != nil {
+			out.CurrentValue = s.display.Format(current.Message.ValueAggregate)
+		}
+	}
+	return out
+}
+
+// quarantineHandlers registers the quarantine review endpoints on the admin server:
+//   - GET /v1/ravs/quarantine: lists the quarantined RAVs, oldest first
+//   - POST /v1/ravs/quarantine/{id}/approve: applies a quarantined RAV to its session
+//   - POST /v1/ravs/quarantine/{id}/reject: drops a quarantined RAV
+func (s *Sidecar) quarantineHandlers(admin *sidecar.AdminServer) {
+	admin.Handle("GET /v1/ravs/quarantine", http.HandlerFunc(s.handleAdminListQuarantine))
+	admin.Handle("POST /v1/ravs/quarantine/{id}/approve", http.HandlerFunc(s.handleAdminDecideQuarantine(s.approveQuarantinedRAV)))
+	admin.Handle("POST /v1/ravs/quarantine/{id}/reject", http.HandlerFunc(s.handleAdminDecideQuarantine(s.rejectQuarantinedRAV)))
+}
+
+func (s *Sidecar) handleAdminListQuarantine(w http.ResponseWriter, r *http.Request) {
+	entries := s.quarantine.list()
+
+	out := make([]*QuarantinedRAV, 0, len(entries))
+	for _, entry := range entries {
+		out = append(out, s.newQuarantinedRAV(entry))
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]any{"amount_unit": s.display.UnitName(), "quarantined": out})
+}
+
+func (s *Sidecar) handleAdminDecideQuarantine(decide func(id string) (*quarantinedRAV, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		entry, err := decide(r.PathValue("id"))
+		switch {
+		case errors.Is(err, ErrQuarantinedRAVNotFound):
+			s.writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		case err != nil:
+			s.writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			return
+		}
+
+		s.writeJSON(w, http.StatusOK, map[string]any{"amount_unit": s.display.UnitName(), "decided": s.newQuarantinedRAV(entry)})
+	}
+}
@@ -0,0 +1,146 @@
+package sidecar
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSubmitRAV_Quarantine(t *testing.T) {
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	payer := signerKey.PublicKey().Address()
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	domain := horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111"))
+
+	s := New(&Config{
+		ListenAddr:      ":0",
+		ServiceProvider: serviceProvider,
+		Domain:          domain,
+		AcceptedSigners: []eth.Address{payer},
+		AdminListenAddr: ":0",
+		Quarantine:      &QuarantinePolicy{MaxValueIncrease: big.NewInt(100), MaxClockSkew: time.Minute},
+	}, zap.NewNop())
+
+	now := time.Now()
+	newRAV := func(value int64, at time.Time) *horizon.SignedRAV {
+		signed, err := horizon.Sign(domain, &horizon.RAV{
+			Payer:           payer,
+			DataService:     dataService,
+			ServiceProvider: serviceProvider,
+			TimestampNs:     uint64(at.UnixNano()),
+			ValueAggregate:  big.NewInt(value),
+		}, signerKey)
+		require.NoError(t, err)
+		return signed
+	}
+
+	session := s.sessions.Create(payer, serviceProvider, dataService)
+	session.SetRAV(newRAV(100, now))
+
+	submit := func(rav *horizon.SignedRAV) *providerv1.SubmitRAVResponse {
+		resp, err := s.SubmitRAV(context.Background(), connect.NewRequest(&providerv1.SubmitRAVRequest{
+			SessionId: session.ID,
+			SignedRav: sidecar.HorizonSignedRAVToProto(rav),
+		}))
+		require.NoError(t, err)
+		return resp.Msg
+	}
+	currentValue := func() int64 {
+		return session.GetRAV().Message.ValueAggregate.Int64()
+	}
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.admin.Handler().ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+	list := func() []*QuarantinedRAV {
+		rec := serve(http.MethodGet, "/v1/ravs/quarantine")
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var out struct {
+			Quarantined []*QuarantinedRAV `json:"quarantined"`
+		}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+		return out.Quarantined
+	}
+
+	// Within the soft checks, applied right away
+	resp := submit(newRAV(200, now.Add(time.Second)))
+	require.True(t, resp.Accepted, resp.RejectionReason)
+	assert.Equal(t, int64(200), currentValue())
+
+	// Value jump, quarantined then approved
+	resp = submit(newRAV(500, now.Add(2*time.Second)))
+	assert.False(t, resp.Accepted)
+	assert.True(t, resp.ShouldContinue)
+	assert.Contains(t, resp.RejectionReason, "RAV quarantined for review: value aggregate increased by 300")
+	assert.Equal(t, int64(200), currentValue())
+
+	quarantined := list()
+	require.Len(t, quarantined, 1)
+	assert.Equal(t, session.ID, quarantined[0].SessionID)
+	assert.Equal(t, "500", quarantined[0].ValueAggregate)
+	assert.Equal(t, "200", quarantined[0].CurrentValue)
+	assert.Equal(t, payer.Pretty(), quarantined[0].Signer)
+
+	require.Equal(t, http.StatusOK, serve(http.MethodPost, "/v1/ravs/quarantine/"+quarantined[0].ID+"/approve").Code)
+	assert.Equal(t, int64(500), currentValue())
+	assert.Empty(t, list())
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/v1/ravs/quarantine/"+quarantined[0].ID+"/approve").Code)
+
+	// Timestamp anomalies, quarantined then rejected
+	resp = submit(newRAV(550, now.Add(time.Hour)))
+	assert.Contains(t, resp.RejectionReason, "in the future")
+	resp = submit(newRAV(560, now))
+	assert.Contains(t, resp.RejectionReason, "before the current RAV timestamp")
+
+	quarantined = list()
+	require.Len(t, quarantined, 2)
+	for _, entry := range quarantined {
+		require.Equal(t, http.StatusOK, serve(http.MethodPost, "/v1/ravs/quarantine/"+entry.ID+"/reject").Code)
+	}
+	assert.Empty(t, list())
+	assert.Equal(t, int64(500), currentValue())
+
+	// A quarantined RAV overtaken by a higher one cannot be approved anymore
+	submit(newRAV(700, now.Add(3*time.Second)))
+	require.True(t, submit(newRAV(600, now.Add(4*time.Second))).Accepted)
+	session.SetRAV(newRAV(800, now.Add(5*time.Second)))
+
+	quarantined = list()
+	require.Len(t, quarantined, 1)
+	assert.Equal(t, http.StatusConflict, serve(http.MethodPost, "/v1/ravs/quarantine/"+quarantined[0].ID+"/approve").Code)
+	assert.Len(t, list(), 1)
+}
+
+func TestRAVQuarantine_Capacity(t *testing.T) {
+	q := newRAVQuarantine(&QuarantinePolicy{Capacity: 1})
+
+	assert.True(t, q.add(&quarantinedRAV{QuarantinedAt: time.Now()}))
+	assert.False(t, q.add(&quarantinedRAV{QuarantinedAt: time.Now()}))
+
+	entry, err := q.take("1")
+	require.NoError(t, err)
+	assert.Equal(t, "1", entry.ID)
+
+	_, err = q.take("1")
+	assert.ErrorIs(t, err, ErrQuarantinedRAVNotFound)
+	assert.True(t, q.add(&quarantinedRAV{QuarantinedAt: time.Now()}))
+}
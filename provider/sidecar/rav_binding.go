@@ -0,0 +1,29 @@
+package sidecar
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+)
+
+// errBootstrapRAVTooOld rejects a zero-value initial RAV older than the
+// configured BootstrapRAVMaxAge
+var errBootstrapRAVTooOld = errors.New("bootstrap RAV is too old")
+
+// checkBootstrapRAV rejects rav when it is a zero-value bootstrap RAV (RAV0)
+// timestamped more than BootstrapRAVMaxAge before now. Such a RAV commits the
+// payer to nothing, a stale one is more likely replayed than sent by a
+// consumer starting a session.
+func (s *Sidecar) checkBootstrapRAV(rav *horizon.RAV, now time.Time) error {
+	if s.bootstrapRAVMaxAge <= 0 || !rav.IsBootstrap() {
+		return nil
+	}
+
+	age := now.Sub(time.Unix(0, int64(rav.TimestampNs)))
+	if age > s.bootstrapRAVMaxAge {
+		return fmt.Errorf("%w: issued %s ago, at most %s accepted", errBootstrapRAVTooOld, age.Truncate(time.Second), s.bootstrapRAVMaxAge)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package sidecar
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRAVBinding(t *testing.T) {
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	parties := horizon.RAVParties{
+		Payer:           signerKey.PublicKey().Address(),
+		ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+	}
+	domain := horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111"))
+
+	s := New(&Config{
+		ServiceProvider:    parties.ServiceProvider,
+		Domain:             domain,
+		AcceptedSigners:    []eth.Address{parties.Payer},
+		BootstrapRAVMaxAge: time.Minute,
+	}, zap.NewNop())
+
+	// The collection ID travels in the metadata of the proto RAVs
+	newRAV := func(collectionID horizon.CollectionID, value int64, at time.Time, modify func(*horizon.RAV)) *commonv1.SignedRAV {
+		rav := horizon.NewBootstrapRAV(parties, collectionID, func() time.Time { return at })
+		rav.ValueAggregate.SetInt64(value)
+		rav.Metadata = collectionID[:]
+		if modify != nil {
+			modify(rav)
+		}
+
+		signed, err := horizon.Sign(domain, rav, signerKey)
+		require.NoError(t, err)
+		return sidecar.HorizonSignedRAVToProto(signed)
+	}
+
+	start := func(rav *commonv1.SignedRAV) *providerv1.StartSessionResponse {
+		resp, err := s.StartSession(context.Background(), connect.NewRequest(&providerv1.StartSessionRequest{
+			EscrowAccount: &commonv1.EscrowAccount{
+				Payer:       commonv1.AddressFromEth(parties.Payer),
+				Receiver:    commonv1.AddressFromEth(parties.ServiceProvider),
+				DataService: commonv1.AddressFromEth(parties.DataService),
+			},
+			InitialRav: rav,
+		}))
+		require.NoError(t, err)
+		return resp.Msg
+	}
+
+	submit := func(sessionID string, rav *commonv1.SignedRAV) *providerv1.SubmitRAVResponse {
+		resp, err := s.SubmitRAV(context.Background(), connect.NewRequest(&providerv1.SubmitRAVRequest{
+			SessionId: sessionID,
+			SignedRav: rav,
+		}))
+		require.NoError(t, err)
+		return resp.Msg
+	}
+
+	collectionID := horizon.CollectionID{0x01}
+	now := time.Now()
+
+	t.Run("stale bootstrap RAV", func(t *testing.T) {
+		resp := start(newRAV(collectionID, 0, now.Add(-time.Hour), nil))
+		assert.False(t, resp.Accepted)
+		assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_INVALID_RAV, resp.RejectionCode)
+		assert.Contains(t, resp.RejectionReason, errBootstrapRAVTooOld.Error())
+
+		// The age limit only applies to zero-value RAVs
+		resp = start(newRAV(collectionID, 100, now.Add(-time.Hour), nil))
+		assert.True(t, resp.Accepted, resp.RejectionReason)
+	})
+
+	t.Run("data service mismatch", func(t *testing.T) {
+		resp := start(newRAV(collectionID, 0, now, func(rav *horizon.RAV) {
+			rav.DataService = eth.MustNewAddress("0x5555555555555555555555555555555555555555")
+		}))
+		assert.False(t, resp.Accepted)
+		assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_PARTY_MISMATCH, resp.RejectionCode)
+	})
+
+	t.Run("session bound to bootstrap RAV", func(t *testing.T) {
+		resp := start(newRAV(collectionID, 0, now, nil))
+		require.True(t, resp.Accepted, resp.RejectionReason)
+
+		for name, rav := range map[string]*commonv1.SignedRAV{
+			"collection": newRAV(horizon.CollectionID{0x02}, 100, now.Add(time.Second), nil),
+			"payer": newRAV(collectionID, 100, now.Add(time.Second), func(rav *horizon.RAV) {
+				rav.Payer = eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+			}),
+			"data service": newRAV(collectionID, 100, now.Add(time.Second), func(rav *horizon.RAV) {
+				rav.DataService = eth.MustNewAddress("0x5555555555555555555555555555555555555555")
+			}),
+		} {
+			submitted := submit(resp.SessionId, rav)
+			assert.False(t, submitted.Accepted, name)
+			assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_PARTY_MISMATCH, submitted.RejectionCode, name)
+			assert.True(t, submitted.ShouldContinue, name)
+		}
+
+		submitted := submit(resp.SessionId, newRAV(collectionID, 100, now.Add(time.Second), nil))
+		assert.True(t, submitted.Accepted, submitted.RejectionReason)
+
+		submitted = submit(resp.SessionId, newRAV(collectionID, 50, now.Add(2*time.Second), nil))
+		assert.False(t, submitted.Accepted)
+		assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_RAV_VALUE_DECREASED, submitted.RejectionCode)
+
+		// Switching collection through the payment header of the session is
+		// rejected too
+		validated, err := s.ValidatePayment(context.Background(), connect.NewRequest(&providerv1.ValidatePaymentRequest{
+			PaymentRav:      newRAV(horizon.CollectionID{0x02}, 200, now.Add(2*time.Second), nil),
+			ClientSessionId: resp.SessionId,
+		}))
+		require.NoError(t, err)
+		assert.False(t, validated.Msg.Valid)
+		assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_PARTY_MISMATCH, validated.Msg.RejectionCode)
+		assert.Contains(t, validated.Msg.RejectionReason, horizon.ErrRAVCollectionChanged.Error())
+
+		session, err := s.sessions.Get(resp.SessionId)
+		require.NoError(t, err)
+		assert.Equal(t, collectionID, session.GetRAV().Message.CollectionID)
+		assert.Equal(t, int64(100), session.GetRAV().Message.ValueAggregate.Int64())
+	})
+}
@@ -0,0 +1,183 @@
+package sidecar
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Triggers of the RAV requests, as reported in the sds_provider_rav_requests_total metric
+const (
+	ravRequestTriggerUsage      = "usage"
+	ravRequestTriggerValue      = "value"
+	ravRequestTriggerInterval   = "interval"
+	ravRequestTriggerBlocks     = "blocks"
+	ravRequestTriggerSessionEnd = "session_end"
+	ravRequestTriggerShutdown   = "shutdown"
+)
+
+// RAVRequestThresholds throttles the RAVs the provider sidecar requests from
+// consumers (rav_requested on ReportUsage): a RAV is requested once the usage
+// not covered by the session's RAV reaches one of the thresholds set, instead
+// of as soon as there is such usage, then not again until a RAV is received.
+// Usage left uncovered when the session ends is always requested
+// (rav_requested on EndSession).
+type RAVRequestThresholds struct {
+	// Value is the usage value, in GRT wei, the session's RAV may leave
+	// uncovered before a RAV is requested
+	Value *big.Int
+	// Interval is how long after the session's last RAV (or its start) a RAV is
+	// requested for the usage it does not cover
+	Interval time.Duration
+	// Blocks is how many blocks streamed after the session's last RAV (or its
+	// start) trigger a RAV request for the usage it does not cover
+	Blocks uint64
+}
+
+func (t *RAVRequestThresholds) isSet() bool {
+	return t != nil && (t.Value != nil || t.Interval > 0 || t.Blocks > 0)
+}
+
+// ravRequests decides when sessions request a RAV from their consumer. Without
+// thresholds, a RAV is requested as soon as usage is reported the current RAV
+// does not cover. A RAV requested is not requested again until one is received.
+type ravRequests struct {
+	thresholds *RAVRequestThresholds
+
+	mu       sync.Mutex
+	sessions map[string]*ravRequestState
+
+	requests *prometheus.CounterVec
+}
+
+type ravRequestState struct {
+	// securedAt and securedBlocks are the time and the session blocks of the
+	// last RAV received
+	securedAt     time.Time
+	securedBlocks uint64
+	// pending is set while a RAV requested was not received yet
+	pending bool
+	// final is set while the final RAV requested at session end was not
+	// received yet
+	final bool
+}
+
+func newRAVRequests(thresholds *RAVRequestThresholds, registry *prometheus.Registry) *ravRequests {
+	r := &ravRequests{
+		sessions: make(map[string]*ravRequestState),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "sds_provider_rav_requests_total",
+			Help: "RAVs requested from consumers, by the trigger of the request",
+		}, []string{"trigger"}),
+	}
+	if thresholds.isSet() {
+		r.thresholds = thresholds
+	}
+
+	if registry != nil {
+		registry.MustRegister(r.requests)
+	}
+	return r
+}
+
+// state returns the request state of session, r.mu must be held
+func (r *ravRequests) state(session *sidecar.Session) *ravRequestState {
+	state, found := r.sessions[session.ID]
+	if !found {
+		state = &ravRequestState{securedAt: session.CreatedAt}
+		r.sessions[session.ID] = state
+	}
+	return state
+}
+
+// check returns the trigger of the RAV session requests after usage was
+// reported at now, empty when no RAV is requested
+func (r *ravRequests) check(session *sidecar.Session, now time.Time) string {
+	if session.PaysWithReceipts() {
+		return ""
+	}
+
+	usage := session.GetUsage()
+	uncovered := new(big.Int).Sub(usage.Cost.ToNative(), ravValue(session.GetRAV()))
+	if uncovered.Sign() <= 0 {
+		return ""
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state := r.state(session)
+	trigger := ""
+	switch {
+	case state.pending:
+		return ""
+	case r.thresholds == nil:
+		trigger = ravRequestTriggerUsage
+	case r.thresholds.Value != nil && uncovered.Cmp(r.thresholds.Value) > 0:
+		trigger = ravRequestTriggerValue
+	case r.thresholds.Interval > 0 && now.Sub(state.securedAt) >= r.thresholds.Interval:
+		trigger = ravRequestTriggerInterval
+	case r.thresholds.Blocks > 0 && usage.BlocksProcessed-min(usage.BlocksProcessed, state.securedBlocks) >= r.thresholds.Blocks:
+		trigger = ravRequestTriggerBlocks
+	default:
+		return ""
+	}
+
+	state.pending = true
+	r.requests.WithLabelValues(trigger).Inc()
+	return trigger
+}
+
+// requestFinal requests the final RAV of session, for trigger, when its current
+// RAV does not cover its usage, returning whether one is requested
+func (r *ravRequests) requestFinal(session *sidecar.Session, trigger string) bool {
+	if session.PaysWithReceipts() {
+		return false
+	}
+	if session.GetUsage().Cost.ToNative().Cmp(ravValue(session.GetRAV())) <= 0 {
+		r.forget(session.ID)
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.state(session).final = true
+	r.requests.WithLabelValues(trigger).Inc()
+	return true
+}
+
+// finalPending returns whether the final RAV of sessionID was requested and
+// not received yet
+func (r *ravRequests) finalPending(sessionID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, found := r.sessions[sessionID]
+	return found && state.final
+}
+
+// received records the RAV received for session at, the session blocks then
+// being covered
+func (r *ravRequests) received(session *sidecar.Session, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state := r.state(session)
+	state.securedAt = at
+	state.securedBlocks = session.GetUsage().BlocksProcessed
+	state.pending = false
+	state.final = false
+}
+
+// forget drops the request state of sessionID, once the session ended and its
+// final RAV is no longer expected
+func (r *ravRequests) forget(sessionID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.sessions, sessionID)
+}
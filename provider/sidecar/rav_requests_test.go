@@ -0,0 +1,156 @@
+package sidecar
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRAVRequests_Thresholds(t *testing.T) {
+	payer := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	now := time.Now()
+
+	newSession := func(ravValue int64) *sidecar.Session {
+		session := sidecar.NewSession(payer, serviceProvider, dataService)
+		session.CreatedAt = now
+		session.SetRAV(&horizon.SignedRAV{Message: &horizon.RAV{ValueAggregate: big.NewInt(ravValue)}})
+		return session
+	}
+
+	t.Run("without thresholds", func(t *testing.T) {
+		requests := newRAVRequests(nil, nil)
+		session := newSession(100)
+
+		session.AddUsage(1, 0, 0, big.NewInt(100))
+		assert.Empty(t, requests.check(session, now), "usage covered by the RAV")
+
+		session.AddUsage(1, 0, 0, big.NewInt(1))
+		assert.Equal(t, ravRequestTriggerUsage, requests.check(session, now))
+		assert.Empty(t, requests.check(session, now), "already requested")
+
+		requests.received(session, now)
+		assert.Equal(t, ravRequestTriggerUsage, requests.check(session, now))
+	})
+
+	t.Run("value", func(t *testing.T) {
+		requests := newRAVRequests(&RAVRequestThresholds{Value: big.NewInt(50)}, nil)
+		session := newSession(100)
+
+		session.AddUsage(1, 0, 0, big.NewInt(150))
+		assert.Empty(t, requests.check(session, now))
+		session.AddUsage(1, 0, 0, big.NewInt(1))
+		assert.Equal(t, ravRequestTriggerValue, requests.check(session, now))
+	})
+
+	t.Run("interval", func(t *testing.T) {
+		requests := newRAVRequests(&RAVRequestThresholds{Interval: time.Minute}, nil)
+		session := newSession(0)
+
+		session.AddUsage(1, 0, 0, big.NewInt(10))
+		assert.Empty(t, requests.check(session, now.Add(59*time.Second)))
+		assert.Equal(t, ravRequestTriggerInterval, requests.check(session, now.Add(time.Minute)))
+
+		// The interval runs from the last RAV received
+		requests.received(session, now.Add(time.Minute))
+		session.AddUsage(1, 0, 0, big.NewInt(10))
+		assert.Empty(t, requests.check(session, now.Add(90*time.Second)))
+	})
+
+	t.Run("blocks", func(t *testing.T) {
+		requests := newRAVRequests(&RAVRequestThresholds{Blocks: 10}, nil)
+		session := newSession(0)
+
+		session.AddUsage(9, 0, 0, big.NewInt(10))
+		assert.Empty(t, requests.check(session, now))
+		session.AddUsage(1, 0, 0, big.NewInt(10))
+		assert.Equal(t, ravRequestTriggerBlocks, requests.check(session, now))
+
+		requests.received(session, now)
+		session.AddUsage(9, 0, 0, big.NewInt(10))
+		assert.Empty(t, requests.check(session, now))
+	})
+}
+
+func TestRAVRequests_FinalRAV(t *testing.T) {
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	payer := signerKey.PublicKey().Address()
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	domain := horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111"))
+
+	s := New(&Config{
+		ServiceProvider:      serviceProvider,
+		Domain:               domain,
+		AcceptedSigners:      []eth.Address{payer},
+		RAVRequestThresholds: &RAVRequestThresholds{Value: big.NewInt(1000)},
+	}, zap.NewNop())
+
+	newRAV := func(value int64) *commonv1.SignedRAV {
+		signed, err := horizon.Sign(domain, &horizon.RAV{
+			Payer:           payer,
+			DataService:     dataService,
+			ServiceProvider: serviceProvider,
+			TimestampNs:     uint64(1000 + value),
+			ValueAggregate:  big.NewInt(value),
+		}, signerKey)
+		require.NoError(t, err)
+		return sidecar.HorizonSignedRAVToProto(signed)
+	}
+	submit := func(sessionID string, value int64) *providerv1.SubmitRAVResponse {
+		resp, err := s.SubmitRAV(context.Background(), connect.NewRequest(&providerv1.SubmitRAVRequest{SessionId: sessionID, SignedRav: newRAV(value)}))
+		require.NoError(t, err)
+		return resp.Msg
+	}
+
+	start, err := s.StartSession(context.Background(), connect.NewRequest(&providerv1.StartSessionRequest{
+		EscrowAccount: &commonv1.EscrowAccount{
+			Payer:       commonv1.AddressFromEth(payer),
+			Receiver:    commonv1.AddressFromEth(serviceProvider),
+			DataService: commonv1.AddressFromEth(dataService),
+		},
+		InitialRav: newRAV(0),
+	}))
+	require.NoError(t, err)
+	require.True(t, start.Msg.Accepted, start.Msg.RejectionReason)
+	sessionID := start.Msg.SessionId
+
+	// Below the value threshold, no RAV is requested while streaming
+	report, err := s.ReportUsage(context.Background(), connect.NewRequest(&providerv1.ReportUsageRequest{
+		SessionId: sessionID,
+		Usage:     &commonv1.Usage{BlocksProcessed: 10, Cost: commonv1.BigIntFromNative(big.NewInt(300))},
+	}))
+	require.NoError(t, err)
+	assert.True(t, report.Msg.ShouldContinue)
+	assert.False(t, report.Msg.RavRequested)
+
+	// The usage left uncovered is requested at session end
+	end, err := s.EndSession(context.Background(), connect.NewRequest(&providerv1.EndSessionRequest{
+		SessionId: sessionID,
+		Reason:    commonv1.EndReason_END_REASON_COMPLETE,
+	}))
+	require.NoError(t, err)
+	assert.True(t, end.Msg.RavRequested)
+
+	// The final RAV is accepted once, although the session ended
+	assert.True(t, submit(sessionID, 300).Accepted)
+	assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_SESSION_NOT_ACTIVE, submit(sessionID, 400).RejectionCode)
+
+	session, err := s.sessions.Get(sessionID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(300), session.GetRAV().Message.ValueAggregate.Int64())
+}
@@ -0,0 +1,163 @@
+package sidecar
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/streamingfast/eth-go"
+)
+
+// RAVTurnaroundStats summarizes how long a payer takes to return a signed RAV
+// once usage it has not paid for yet is reported, so providers can factor slow
+// payers into their continuation and pricing decisions
+type RAVTurnaroundStats struct {
+	// Count is the number of RAVs returned while one was due
+	Count   int
+	Average time.Duration
+	Max     time.Duration
+	Last    time.Duration
+}
+
+// ravTurnaround tracks the RAVs due by consumers: a session owes a RAV from
+// the first RAV requested after its last RAV (see ravRequests), until a new
+// RAV is accepted (SubmitRAV or ValidatePayment). Turnaround times are exported as the
+// sds_provider_rav_turnaround_seconds histogram and summarized per payer.
+type ravTurnaround struct {
+	mu      sync.Mutex
+	due     map[string]time.Time
+	byPayer map[string]*payerTurnaround
+
+	duration prometheus.Histogram
+}
+
+type payerTurnaround struct {
+	count int
+	total time.Duration
+	max   time.Duration
+	last  time.Duration
+}
+
+func newRAVTurnaround(registry *prometheus.Registry) *ravTurnaround {
+	t := &ravTurnaround{
+		due:     make(map[string]time.Time),
+		byPayer: make(map[string]*payerTurnaround),
+		duration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "sds_provider_rav_turnaround_seconds",
+			Help:    "Time consumers take to return a signed RAV once usage not covered by their last RAV is reported",
+			Buckets: prometheus.ExponentialBuckets(0.05, 2, 14),
+		}),
+	}
+
+	if registry != nil {
+		registry.MustRegister(t.duration)
+	}
+	return t
+}
+
+// requested marks a RAV as due for sessionID since at, unless one already is
+func (t *ravTurnaround) requested(sessionID string, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, found := t.due[sessionID]; !found {
+		t.due[sessionID] = at
+	}
+}
+
+// received records the RAV payer returned for sessionID at, accounting for its
+// turnaround when one was due
+func (t *ravTurnaround) received(sessionID string, payer eth.Address, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	since, found := t.due[sessionID]
+	if !found {
+		return
+	}
+	delete(t.due, sessionID)
+
+	elapsed := at.Sub(since)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	t.duration.Observe(elapsed.Seconds())
+
+	key := payer.Pretty()
+	stats, found := t.byPayer[key]
+	if !found {
+		stats = &payerTurnaround{}
+		t.byPayer[key] = stats
+	}
+	stats.count++
+	stats.total += elapsed
+	stats.last = elapsed
+	if elapsed > stats.max {
+		stats.max = elapsed
+	}
+}
+
+// pendingSince returns since when a RAV is due for sessionID, false when none is
+func (t *ravTurnaround) pendingSince(sessionID string) (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	since, found := t.due[sessionID]
+	return since, found
+}
+
+// forget drops the RAV due for sessionID, once the session ended
+func (t *ravTurnaround) forget(sessionID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.due, sessionID)
+}
+
+// stats returns the turnaround statistics of payer, false when it never
+// returned a RAV while one was due
+func (t *ravTurnaround) stats(payer eth.Address) (RAVTurnaroundStats, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats, found := t.byPayer[payer.Pretty()]
+	if !found {
+		return RAVTurnaroundStats{}, false
+	}
+	return RAVTurnaroundStats{
+		Count:   stats.count,
+		Average: stats.total / time.Duration(stats.count),
+		Max:     stats.max,
+		Last:    stats.last,
+	}, true
+}
+
+// PayerRAVTurnaround returns how long payer takes to return signed RAVs, false
+// when it never returned one while it was due since the sidecar started
+func (s *Sidecar) PayerRAVTurnaround(payer eth.Address) (RAVTurnaroundStats, bool) {
+	return s.ravTurnaround.stats(payer)
+}
+
+// checkRAVTurnaround marks a RAV as due for session when one was requested,
+// returning a stop reason once the RAV is overdue by more than
+// maxRAVTurnaround. Sessions in receipts payment mode pay with each report and
+// never owe a RAV.
+func (s *Sidecar) checkRAVTurnaround(session *sidecar.Session, requested bool, now time.Time) string {
+	if session.PaysWithReceipts() {
+		return ""
+	}
+	if requested {
+		s.ravTurnaround.requested(session.ID, now)
+	}
+
+	if s.maxRAVTurnaround <= 0 {
+		return ""
+	}
+	since, due := s.ravTurnaround.pendingSince(session.ID)
+	if !due || now.Sub(since) <= s.maxRAVTurnaround {
+		return ""
+	}
+	return fmt.Sprintf("no signed RAV returned for %s, the maximum turnaround is %s", now.Sub(since).Round(time.Second), s.maxRAVTurnaround)
+}
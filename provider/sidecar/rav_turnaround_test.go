@@ -0,0 +1,97 @@
+package sidecar
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRAVTurnaround(t *testing.T) {
+	payer := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+	turnaround := newRAVTurnaround(nil)
+	start := time.Unix(1700000000, 0)
+
+	// RAVs returned while none is due are not accounted for
+	turnaround.received("session-1", payer, start)
+	_, found := turnaround.stats(payer)
+	assert.False(t, found)
+
+	turnaround.requested("session-1", start)
+	turnaround.requested("session-1", start.Add(time.Second))
+	since, due := turnaround.pendingSince("session-1")
+	require.True(t, due)
+	assert.Equal(t, start, since)
+
+	turnaround.received("session-1", payer, start.Add(2*time.Second))
+	_, due = turnaround.pendingSince("session-1")
+	assert.False(t, due)
+
+	turnaround.requested("session-2", start)
+	turnaround.received("session-2", payer, start.Add(4*time.Second))
+
+	stats, found := turnaround.stats(payer)
+	require.True(t, found)
+	assert.Equal(t, 2, stats.Count)
+	assert.Equal(t, 3*time.Second, stats.Average)
+	assert.Equal(t, 4*time.Second, stats.Max)
+	assert.Equal(t, 4*time.Second, stats.Last)
+
+	turnaround.requested("session-3", start)
+	turnaround.forget("session-3")
+	_, due = turnaround.pendingSince("session-3")
+	assert.False(t, due)
+}
+
+func TestReportUsage_RAVOverdue(t *testing.T) {
+	payer := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	s := New(&Config{
+		ServiceProvider:  serviceProvider,
+		Domain:           horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+		MaxRAVTurnaround: time.Minute,
+	}, zap.NewNop())
+
+	session := s.sessions.Create(payer, serviceProvider, eth.MustNewAddress("0x2222222222222222222222222222222222222222"))
+	report := func(cost int64) *providerv1.ReportUsageResponse {
+		resp, err := s.ReportUsage(context.Background(), connect.NewRequest(&providerv1.ReportUsageRequest{
+			SessionId: session.ID,
+			Usage:     &commonv1.Usage{BlocksProcessed: 1, Cost: commonv1.BigIntFromNative(big.NewInt(cost))},
+		}))
+		require.NoError(t, err)
+		return resp.Msg
+	}
+
+	// Usage free of charge does not make a RAV due
+	assert.True(t, report(0).ShouldContinue)
+	_, due := s.ravTurnaround.pendingSince(session.ID)
+	assert.False(t, due)
+
+	assert.True(t, report(100).ShouldContinue)
+	_, due = s.ravTurnaround.pendingSince(session.ID)
+	require.True(t, due)
+
+	// The RAV has been due for longer than the maximum turnaround
+	s.ravTurnaround.due[session.ID] = time.Now().Add(-2 * time.Minute)
+	resp := report(100)
+	assert.False(t, resp.ShouldContinue)
+	assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_RAV_OVERDUE, resp.StopCode)
+
+	// Returning the RAV lets streaming resume and accounts for the turnaround
+	s.ravTurnaround.received(session.ID, payer, time.Now())
+	assert.True(t, report(100).ShouldContinue)
+
+	stats, found := s.PayerRAVTurnaround(payer)
+	require.True(t, found)
+	assert.Equal(t, 1, stats.Count)
+	assert.GreaterOrEqual(t, stats.Max, 2*time.Minute)
+}
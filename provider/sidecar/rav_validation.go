@@ -0,0 +1,66 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+)
+
+// ravPolicy returns the policy RAVs for parties are validated against: the
+// metadata limits of the sidecar and, when enforced, the escrow cap of the
+// payer, bounding the value not collected yet
+func (s *Sidecar) ravPolicy(ctx context.Context, parties horizon.RAVParties) horizon.Policy {
+	policy := horizon.Policy{
+		Parties:         &parties,
+		MaxMetadataSize: s.maxMetadataSize,
+		StrictMetadata:  s.strictMetadata,
+	}
+	if s.escrowCaps != nil {
+		// RAVs are not bounded while no balance snapshot could be fetched, an
+		// unreachable chain RPC must not stop paid streams
+		policy.EscrowBalance = func(payer eth.Address) *big.Int {
+			return s.escrowCaps.balance(ctx, payer, s.logger)
+		}
+		policy.Collected = s.collectedMidSession
+	}
+	return policy
+}
+
+// sessionParties returns the parties of session, the ones every RAV sent for
+// it must be for
+func sessionParties(session *sidecar.Session) horizon.RAVParties {
+	return horizon.RAVParties{
+		Payer:           session.Payer,
+		ServiceProvider: session.Receiver,
+		DataService:     session.DataService,
+	}
+}
+
+// ravRejectionCode returns the rejection code reported for a RAV failing
+// horizon.Validator.Validate with err
+func ravRejectionCode(err error) commonv1.RejectionCode {
+	switch {
+	case errors.Is(err, horizon.ErrRAVNotCollectable):
+		return commonv1.RejectionCode_REJECTION_CODE_RAV_NOT_COLLECTABLE
+	case errors.Is(err, horizon.ErrRAVSignatureInvalid):
+		return commonv1.RejectionCode_REJECTION_CODE_INVALID_SIGNATURE
+	case errors.Is(err, horizon.ErrRAVUnauthorizedSigner):
+		return commonv1.RejectionCode_REJECTION_CODE_UNAUTHORIZED_SIGNER
+	case errors.Is(err, horizon.ErrRAVPartiesMismatch),
+		errors.Is(err, horizon.ErrRAVPartiesChanged),
+		errors.Is(err, horizon.ErrRAVCollectionMismatch),
+		errors.Is(err, horizon.ErrRAVCollectionChanged):
+		return commonv1.RejectionCode_REJECTION_CODE_PARTY_MISMATCH
+	case errors.Is(err, horizon.ErrRAVValueDecreased):
+		return commonv1.RejectionCode_REJECTION_CODE_RAV_VALUE_DECREASED
+	case errors.Is(err, horizon.ErrRAVExceedsEscrow):
+		return commonv1.RejectionCode_REJECTION_CODE_EXCEEDS_ESCROW
+	default:
+		return commonv1.RejectionCode_REJECTION_CODE_INVALID_RAV
+	}
+}
@@ -0,0 +1,356 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"go.uber.org/zap"
+)
+
+// DefaultReceiptAggregationInterval is how often the receipts of sessions in
+// receipts payment mode are aggregated when Config.ReceiptAggregationInterval
+// is zero
+const DefaultReceiptAggregationInterval = 30 * time.Second
+
+// receiptAggregationMaxAttempts is the number of consecutive aggregations
+// rejecting the receipts buffered for a session after which they are dropped,
+// so a batch that cannot be aggregated does not hold back the receipts after it
+const receiptAggregationMaxAttempts = 5
+
+// receiptFlushTimeout bounds the last aggregation of pending receipts when the
+// sidecar shuts down
+const receiptFlushTimeout = 10 * time.Second
+
+// receiptAggregator turns receipts into a RAV on top of the previous RAV (nil
+// for the first RAV of a collection), see ExternalAggregator and
+// embeddedAggregator
+type receiptAggregator interface {
+	AggregateReceipts(ctx context.Context, receipts []*horizon.SignedReceipt, previousRAV *horizon.SignedRAV) (*horizon.SignedRAV, error)
+}
+
+// embeddedAggregator aggregates receipts in process with a horizon.Aggregator,
+// signing RAVs with the provider's aggregator key. Receipts and previous RAVs
+// are accepted when signed by the sidecar's accepted signers at the time of the
+// aggregation, or by the aggregator key itself. The RAVs it signs are only
+// collectable once the payer authorized the aggregator key as one of its
+// signers, trusting the service provider to aggregate only the receipts it
+// received.
+type embeddedAggregator struct {
+	domain  *horizon.Domain
+	key     *eth.PrivateKey
+	signers func() []eth.Address
+}
+
+func (a *embeddedAggregator) AggregateReceipts(ctx context.Context, receipts []*horizon.SignedReceipt, previousRAV *horizon.SignedRAV) (*horizon.SignedRAV, error) {
+	signers := append(a.signers(), a.key.PublicKey().Address())
+	aggregator := horizon.NewAggregator(a.domain, a.key, signers, horizon.WithDuplicateReceiptTolerance())
+	rav, err := aggregator.AggregateReceipts(receipts, previousRAV)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errReceiptsRejected, err)
+	}
+	return rav, nil
+}
+
+// receiptBuffer holds the receipts submitted for sessions in receipts payment
+// mode until they are aggregated into the session RAV
+type receiptBuffer struct {
+	mu      sync.Mutex
+	pending map[string]*pendingReceipts
+}
+
+type pendingReceipts struct {
+	// mu is held while the receipts are aggregated, receipts submitted meanwhile
+	// wait so none is older than the RAV it is aggregated on top of
+	mu       sync.Mutex
+	receipts []*horizon.SignedReceipt
+	seen     map[[65]byte]bool
+	// failures is the number of consecutive failed aggregations of receipts
+	failures int
+
+	// stored is receipts as of the last change, guarded by receiptBuffer.mu so
+	// sessions are persisted without waiting for an aggregation in progress
+	stored []*horizon.SignedReceipt
+}
+
+// reset drops the buffered receipts
+func (p *pendingReceipts) reset() {
+	p.receipts = nil
+	p.seen = make(map[[65]byte]bool)
+	p.failures = 0
+}
+
+func newReceiptBuffer() *receiptBuffer {
+	return &receiptBuffer{pending: make(map[string]*pendingReceipts)}
+}
+
+// session returns the pending receipts of the session, created when missing
+func (b *receiptBuffer) session(sessionID string) *pendingReceipts {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	pending, found := b.pending[sessionID]
+	if !found {
+		pending = &pendingReceipts{seen: make(map[[65]byte]bool)}
+		b.pending[sessionID] = pending
+	}
+	return pending
+}
+
+// sessionIDs returns the sessions with receipts buffered
+func (b *receiptBuffer) sessionIDs() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ids := make([]string, 0, len(b.pending))
+	for id := range b.pending {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// publish makes the receipts of pending, whose mu is held, those persisted
+// with its session
+func (b *receiptBuffer) publish(pending *pendingReceipts) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	pending.stored = pending.receipts
+}
+
+// stored returns the receipts buffered for sessionID as of their last change
+func (b *receiptBuffer) stored(sessionID string) []*horizon.SignedReceipt {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if pending, found := b.pending[sessionID]; found {
+		return pending.stored
+	}
+	return nil
+}
+
+// restore buffers receipts persisted with session sessionID
+func (b *receiptBuffer) restore(sessionID string, receipts []*horizon.SignedReceipt) {
+	pending := b.session(sessionID)
+	pending.mu.Lock()
+	defer pending.mu.Unlock()
+
+	for _, receipt := range receipts {
+		pending.seen[receipt.UniqueID()] = true
+	}
+	pending.receipts = receipts
+	b.publish(pending)
+}
+
+func (b *receiptBuffer) remove(sessionID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.pending, sessionID)
+}
+
+// bufferReceipts verifies receipts submitted for session and buffers them until
+// the next aggregation. Receipts are checked against those already buffered and
+// the session RAV the way the aggregation checks them, so an invalid receipt is
+// refused instead of failing every later aggregation. Receipts already buffered
+// are ignored, consumers may resend them when retrying.
+func (s *Sidecar) bufferReceipts(session *sidecar.Session, receipts []*horizon.SignedReceipt) error {
+	for i, receipt := range receipts {
+		signer, err := receipt.RecoverSigner(s.domain)
+		if err != nil {
+			return fmt.Errorf("receipt %d: recovering signer: %w", i, err)
+		}
+		if !s.validator.IsAcceptedSigner(signer) {
+			return fmt.Errorf("receipt %d: signer %s is not authorized", i, signer.Pretty())
+		}
+		if !sidecar.AddressesEqual(receipt.Message.Payer, session.Payer) ||
+			!sidecar.AddressesEqual(receipt.Message.ServiceProvider, s.serviceProvider) ||
+			!sidecar.AddressesEqual(receipt.Message.DataService, session.DataService) {
+			return fmt.Errorf("receipt %d: parties do not match the session", i)
+		}
+	}
+
+	pending := s.receipts.session(session.ID)
+	pending.mu.Lock()
+	defer pending.mu.Unlock()
+
+	rav := session.GetRAV()
+	if rav != nil && rav.Message == nil {
+		rav = nil
+	}
+	if err := horizon.ValidateReceipts(receipts, rav); err != nil {
+		return err
+	}
+	if len(pending.receipts) > 0 {
+		if err := horizon.ValidateReceipts([]*horizon.SignedReceipt{pending.receipts[0], receipts[0]}, nil); err != nil {
+			return fmt.Errorf("receipts do not match those buffered: %w", err)
+		}
+	}
+
+	total := new(big.Int)
+	if rav != nil && rav.Message.ValueAggregate != nil {
+		total.Set(rav.Message.ValueAggregate)
+	}
+	for _, receipt := range pending.receipts {
+		total.Add(total, receipt.Message.Value)
+	}
+
+	var accepted []*horizon.SignedReceipt
+	for _, receipt := range receipts {
+		id := receipt.UniqueID()
+		if pending.seen[id] {
+			continue
+		}
+		pending.seen[id] = true
+		accepted = append(accepted, receipt)
+		total.Add(total, receipt.Message.Value)
+	}
+	if total.Cmp(horizon.MaxUint128) > 0 {
+		for _, receipt := range accepted {
+			delete(pending.seen, receipt.UniqueID())
+		}
+		return horizon.ErrAggregateOverflow
+	}
+
+	for _, receipt := range accepted {
+		pending.receipts = append(pending.receipts, receipt)
+		session.AddReceiptValue(receipt.Message.Value)
+	}
+	s.receipts.publish(pending)
+	s.persistSession(session)
+	return nil
+}
+
+// aggregateSessionReceipts aggregates the receipts buffered for session into
+// its RAV. Receipts are kept for the next aggregation when it fails, and dropped
+// once the aggregator rejected them receiptAggregationMaxAttempts times in a
+// row.
+func (s *Sidecar) aggregateSessionReceipts(ctx context.Context, session *sidecar.Session) error {
+	pending := s.receipts.session(session.ID)
+	pending.mu.Lock()
+	defer pending.mu.Unlock()
+
+	if len(pending.receipts) == 0 {
+		if !session.IsActive() {
+			s.receipts.remove(session.ID)
+		}
+		return nil
+	}
+
+	rav, err := s.aggregateSessionRAV(ctx, session, pending.receipts)
+	if err != nil {
+		if ctx.Err() != nil || !errors.Is(err, errReceiptsRejected) {
+			return err
+		}
+
+		pending.failures++
+		if pending.failures < receiptAggregationMaxAttempts {
+			return err
+		}
+
+		value := new(big.Int)
+		for _, receipt := range pending.receipts {
+			value.Add(value, receipt.Message.Value)
+		}
+		s.logger.Error("dropping receipts failing aggregation",
+			zap.String("session_id", session.ID),
+			zap.Int("receipts", len(pending.receipts)),
+			zap.Int("attempts", pending.failures),
+			s.display.Field("value", value),
+			zap.Error(err),
+		)
+
+		pending.reset()
+		s.receipts.publish(pending)
+		s.persistSession(session)
+		if !session.IsActive() {
+			s.receipts.remove(session.ID)
+		}
+		return err
+	}
+
+	s.logger.Info("aggregated session receipts",
+		zap.String("session_id", session.ID),
+		zap.Int("receipts", len(pending.receipts)),
+		s.display.Field("value", rav.Message.ValueAggregate),
+	)
+
+	session.SetRAV(rav)
+	pending.reset()
+	s.receipts.publish(pending)
+	s.persistSession(session)
+	if !session.IsActive() {
+		s.receipts.remove(session.ID)
+	}
+	return nil
+}
+
+// aggregateSessionRAV aggregates receipts on top of the RAV of session and
+// verifies the RAV returned
+func (s *Sidecar) aggregateSessionRAV(ctx context.Context, session *sidecar.Session, receipts []*horizon.SignedReceipt) (*horizon.SignedRAV, error) {
+	currentRAV := session.GetRAV()
+	rav, err := s.aggregator.AggregateReceipts(ctx, receipts, currentRAV)
+	if err != nil {
+		return nil, fmt.Errorf("aggregating %d receipts: %w", len(receipts), err)
+	}
+
+	signer, err := s.verifyRAVSignature(rav)
+	if err != nil {
+		return nil, fmt.Errorf("verifying aggregated RAV signature: %w", err)
+	}
+	if !s.validator.IsAcceptedSigner(signer) {
+		return nil, fmt.Errorf("aggregated RAV signer %s is not authorized", signer.Pretty())
+	}
+	if currentRAV != nil && currentRAV.Message != nil && rav.Message.ValueAggregate.Cmp(currentRAV.Message.ValueAggregate) < 0 {
+		return nil, fmt.Errorf("aggregated RAV value is less than current RAV")
+	}
+	return rav, nil
+}
+
+// aggregatePendingReceipts aggregates the receipts buffered for every session
+func (s *Sidecar) aggregatePendingReceipts(ctx context.Context) {
+	for _, sessionID := range s.receipts.sessionIDs() {
+		session, err := s.sessions.Get(sessionID)
+		if err != nil {
+			s.receipts.remove(sessionID)
+			continue
+		}
+
+		if err := s.aggregateSessionReceipts(ctx, session); err != nil && ctx.Err() == nil {
+			s.logger.Warn("receipts aggregation failed, retrying on next interval", zap.String("session_id", sessionID), zap.Error(err))
+		}
+	}
+}
+
+// watchReceiptAggregation aggregates the buffered receipts every interval, and
+// a last time on shutdown so the final RAVs of the sessions cover them
+func (s *Sidecar) watchReceiptAggregation(interval time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.OnTerminating(func(_ error) {
+		cancel()
+	})
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancelFlush := context.WithTimeout(context.Background(), receiptFlushTimeout)
+			s.aggregatePendingReceipts(flushCtx)
+			cancelFlush()
+			return
+		case <-ticker.C:
+			s.aggregatePendingReceipts(ctx)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package sidecar
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestReceiptsPaymentMode(t *testing.T) {
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	aggregatorKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	payer := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	domain := horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111"))
+
+	s := New(&Config{
+		ServiceProvider: serviceProvider,
+		Domain:          domain,
+		AggregatorKey:   aggregatorKey,
+	}, zap.NewNop())
+	s.AddAcceptedSigner(signerKey.PublicKey().Address())
+
+	initialRAV, err := horizon.Sign(domain, &horizon.RAV{
+		Payer:           payer,
+		DataService:     dataService,
+		ServiceProvider: serviceProvider,
+		TimestampNs:     uint64(time.Now().UnixNano()),
+		ValueAggregate:  big.NewInt(100),
+	}, signerKey)
+	require.NoError(t, err)
+
+	started, err := s.StartSession(context.Background(), connect.NewRequest(&providerv1.StartSessionRequest{
+		EscrowAccount: &commonv1.EscrowAccount{
+			Payer:       commonv1.AddressFromEth(payer),
+			Receiver:    commonv1.AddressFromEth(serviceProvider),
+			DataService: commonv1.AddressFromEth(dataService),
+		},
+		InitialRav:  sidecar.HorizonSignedRAVToProto(initialRAV),
+		PaymentMode: commonv1.PaymentMode_PAYMENT_MODE_RECEIPTS,
+	}))
+	require.NoError(t, err)
+	require.True(t, started.Msg.Accepted, started.Msg.RejectionReason)
+	sessionID := started.Msg.SessionId
+
+	sign := func(key *eth.PrivateKey, value int64) *commonv1.SignedReceipt {
+		receipt, err := horizon.Sign(domain, horizon.NewReceipt(horizon.CollectionID{}, payer, dataService, serviceProvider, big.NewInt(value)), key)
+		require.NoError(t, err)
+		return sidecar.HorizonSignedReceiptToProto(receipt)
+	}
+	submit := func(receipts ...*commonv1.SignedReceipt) *providerv1.SubmitRAVResponse {
+		resp, err := s.SubmitRAV(context.Background(), connect.NewRequest(&providerv1.SubmitRAVRequest{
+			SessionId: sessionID,
+			Receipts:  receipts,
+		}))
+		require.NoError(t, err)
+		return resp.Msg
+	}
+
+	first, second := sign(signerKey, 10), sign(signerKey, 20)
+	resp := submit(first, second)
+	assert.True(t, resp.Accepted, resp.RejectionReason)
+	assert.Nil(t, resp.AggregatedRav)
+
+	// Resent receipts are buffered once
+	resp = submit(first)
+	assert.True(t, resp.Accepted, resp.RejectionReason)
+
+	// Receipts of signers not authorized by the payer are refused
+	intruderKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	resp = submit(sign(intruderKey, 1000))
+	assert.False(t, resp.Accepted)
+	assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_INVALID_REQUEST, resp.RejectionCode)
+
+	session, err := s.sessions.Get(sessionID)
+	require.NoError(t, err)
+	assert.Equal(t, "100", session.GetRAV().Message.ValueAggregate.String())
+	assert.Equal(t, "30", session.GetReceiptsValue().String())
+
+	// Receipts are aggregated on top of the session RAV, signed by the
+	// aggregator key
+	s.aggregatePendingReceipts(context.Background())
+	rav := session.GetRAV()
+	assert.Equal(t, "130", rav.Message.ValueAggregate.String())
+	signer, err := rav.RecoverSigner(domain)
+	require.NoError(t, err)
+	assert.Equal(t, aggregatorKey.PublicKey().Address().Pretty(), signer.Pretty())
+
+	// Receipts older than the aggregated RAV cannot be aggregated anymore
+	resp = submit(first)
+	assert.False(t, resp.Accepted)
+	assert.Contains(t, resp.RejectionReason, horizon.ErrInvalidTimestamp.Error())
+
+	// Receipts still buffered are aggregated into the final RAV
+	resp = submit(sign(signerKey, 5))
+	assert.True(t, resp.Accepted, resp.RejectionReason)
+
+	ended, err := s.EndSession(context.Background(), connect.NewRequest(&providerv1.EndSessionRequest{
+		SessionId: sessionID,
+		Reason:    commonv1.EndReason_END_REASON_COMPLETE,
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "135", ended.Msg.FinalRav.Rav.ValueAggregate.ToNative().String())
+	assert.Empty(t, s.receipts.sessionIDs())
+}
+
+func TestStartSession_ReceiptsRequireAggregator(t *testing.T) {
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	s := New(&Config{
+		ServiceProvider: serviceProvider,
+		Domain:          horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+	}, zap.NewNop())
+
+	resp, err := s.StartSession(context.Background(), connect.NewRequest(&providerv1.StartSessionRequest{
+		EscrowAccount: &commonv1.EscrowAccount{
+			Payer:       commonv1.AddressFromEth(eth.MustNewAddress("0x4444444444444444444444444444444444444444")),
+			Receiver:    commonv1.AddressFromEth(serviceProvider),
+			DataService: commonv1.AddressFromEth(eth.MustNewAddress("0x2222222222222222222222222222222222222222")),
+		},
+		PaymentMode: commonv1.PaymentMode_PAYMENT_MODE_RECEIPTS,
+	}))
+	require.NoError(t, err)
+	assert.False(t, resp.Msg.Accepted)
+	assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_INVALID_REQUEST, resp.Msg.RejectionCode)
+}
+
+// failingAggregator rejects every aggregation while failing is set
+type failingAggregator struct {
+	receiptAggregator
+	failing bool
+}
+
+func (a *failingAggregator) AggregateReceipts(ctx context.Context, receipts []*horizon.SignedReceipt, previousRAV *horizon.SignedRAV) (*horizon.SignedRAV, error) {
+	if a.failing {
+		return nil, fmt.Errorf("%w: receipts flagged", errReceiptsRejected)
+	}
+	return a.receiptAggregator.AggregateReceipts(ctx, receipts, previousRAV)
+}
+
+func TestReceiptsPaymentMode_InvalidReceipts(t *testing.T) {
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	aggregatorKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	payer := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	domain := horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111"))
+
+	s := New(&Config{
+		ServiceProvider: serviceProvider,
+		Domain:          domain,
+		AggregatorKey:   aggregatorKey,
+	}, zap.NewNop())
+	s.AddAcceptedSigner(signerKey.PublicKey().Address())
+	aggregator := &failingAggregator{receiptAggregator: s.aggregator}
+	s.aggregator = aggregator
+
+	var collectionID, otherCollectionID horizon.CollectionID
+	otherCollectionID[0] = 1
+	initialRAV, err := horizon.Sign(domain, &horizon.RAV{
+		CollectionID:    collectionID,
+		Payer:           payer,
+		DataService:     dataService,
+		ServiceProvider: serviceProvider,
+		TimestampNs:     uint64(time.Now().UnixNano()),
+		ValueAggregate:  big.NewInt(100),
+	}, signerKey)
+	require.NoError(t, err)
+
+	started, err := s.StartSession(context.Background(), connect.NewRequest(&providerv1.StartSessionRequest{
+		EscrowAccount: &commonv1.EscrowAccount{
+			Payer:       commonv1.AddressFromEth(payer),
+			Receiver:    commonv1.AddressFromEth(serviceProvider),
+			DataService: commonv1.AddressFromEth(dataService),
+		},
+		InitialRav:  sidecar.HorizonSignedRAVToProto(initialRAV),
+		PaymentMode: commonv1.PaymentMode_PAYMENT_MODE_RECEIPTS,
+	}))
+	require.NoError(t, err)
+	require.True(t, started.Msg.Accepted, started.Msg.RejectionReason)
+	sessionID := started.Msg.SessionId
+
+	sign := func(collectionID horizon.CollectionID, value *big.Int) *commonv1.SignedReceipt {
+		receipt, err := horizon.Sign(domain, horizon.NewReceipt(collectionID, payer, dataService, serviceProvider, value), signerKey)
+		require.NoError(t, err)
+		return sidecar.HorizonSignedReceiptToProto(receipt)
+	}
+	submit := func(receipts ...*commonv1.SignedReceipt) *providerv1.SubmitRAVResponse {
+		resp, err := s.SubmitRAV(context.Background(), connect.NewRequest(&providerv1.SubmitRAVRequest{
+			SessionId: sessionID,
+			Receipts:  receipts,
+		}))
+		require.NoError(t, err)
+		return resp.Msg
+	}
+
+	// Receipts of another collection than the session RAV are refused
+	resp := submit(sign(otherCollectionID, big.NewInt(10)))
+	assert.False(t, resp.Accepted)
+	assert.Contains(t, resp.RejectionReason, horizon.ErrCollectionMismatch.Error())
+
+	// Receipts the aggregated value would overflow with are refused
+	resp = submit(sign(collectionID, horizon.MaxUint128))
+	assert.False(t, resp.Accepted)
+	assert.Contains(t, resp.RejectionReason, horizon.ErrAggregateOverflow.Error())
+	assert.Empty(t, s.receipts.stored(sessionID))
+
+	// Receipts failing aggregation are kept for the next attempts, then dropped
+	resp = submit(sign(collectionID, big.NewInt(10)))
+	require.True(t, resp.Accepted, resp.RejectionReason)
+
+	aggregator.failing = true
+	for range receiptAggregationMaxAttempts - 1 {
+		s.aggregatePendingReceipts(context.Background())
+		assert.Len(t, s.receipts.stored(sessionID), 1)
+	}
+	s.aggregatePendingReceipts(context.Background())
+	assert.Empty(t, s.receipts.stored(sessionID))
+
+	// Later receipts are aggregated once the aggregator recovers
+	aggregator.failing = false
+	resp = submit(sign(collectionID, big.NewInt(20)))
+	require.True(t, resp.Accepted, resp.RejectionReason)
+	s.aggregatePendingReceipts(context.Background())
+
+	session, err := s.sessions.Get(sessionID)
+	require.NoError(t, err)
+	assert.Equal(t, "120", session.GetRAV().Message.ValueAggregate.String())
+}
+
+func TestReceiptsPaymentMode_BufferSurvivesRestart(t *testing.T) {
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	aggregatorKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	payer := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	domain := horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111"))
+	storePath := filepath.Join(t.TempDir(), "sessions")
+
+	var current *Sidecar
+	newSidecar := func() *Sidecar {
+		if current != nil {
+			current.closeSessionStore()
+		}
+		s := New(&Config{
+			ServiceProvider: serviceProvider,
+			Domain:          domain,
+			AggregatorKey:   aggregatorKey,
+			AcceptedSigners: []eth.Address{signerKey.PublicKey().Address()},
+			StorePath:       storePath,
+		}, zap.NewNop())
+		require.NoError(t, s.restoreSessions())
+		t.Cleanup(s.closeSessionStore)
+		current = s
+		return s
+	}
+
+	s := newSidecar()
+	started, err := s.StartSession(context.Background(), connect.NewRequest(&providerv1.StartSessionRequest{
+		EscrowAccount: &commonv1.EscrowAccount{
+			Payer:       commonv1.AddressFromEth(payer),
+			Receiver:    commonv1.AddressFromEth(serviceProvider),
+			DataService: commonv1.AddressFromEth(dataService),
+		},
+		PaymentMode: commonv1.PaymentMode_PAYMENT_MODE_RECEIPTS,
+	}))
+	require.NoError(t, err)
+	require.True(t, started.Msg.Accepted, started.Msg.RejectionReason)
+	sessionID := started.Msg.SessionId
+
+	receipt, err := horizon.Sign(domain, horizon.NewReceipt(horizon.CollectionID{}, payer, dataService, serviceProvider, big.NewInt(25)), signerKey)
+	require.NoError(t, err)
+	resp, err := s.SubmitRAV(context.Background(), connect.NewRequest(&providerv1.SubmitRAVRequest{
+		SessionId: sessionID,
+		Receipts:  []*commonv1.SignedReceipt{sidecar.HorizonSignedReceiptToProto(receipt)},
+	}))
+	require.NoError(t, err)
+	require.True(t, resp.Msg.Accepted, resp.Msg.RejectionReason)
+
+	// Buffered receipts are restored with their session and aggregated after
+	// the restart
+	s = newSidecar()
+	require.Len(t, s.receipts.stored(sessionID), 1)
+	s.aggregatePendingReceipts(context.Background())
+
+	session, err := s.sessions.Get(sessionID)
+	require.NoError(t, err)
+	require.NotNil(t, session.GetRAV())
+	assert.Equal(t, "25", session.GetRAV().Message.ValueAggregate.String())
+}
@@ -0,0 +1,251 @@
+package sidecar
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/streamingfast/eth-go"
+	"go.uber.org/zap"
+)
+
+// DefaultRedeemabilityCheckInterval is how often the current RAV of each active
+// collection is checked for redeemability by simulating its collection
+const DefaultRedeemabilityCheckInterval = time.Minute
+
+// CollectionRedeemability is the outcome of the last simulated collection of a
+// collection's current RAV as served by the admin API, its value is rendered in
+// the configured amount unit
+type CollectionRedeemability struct {
+	CollectionID   string    `json:"collection_id"`
+	Payer          string    `json:"payer"`
+	SessionID      string    `json:"session_id"`
+	ValueAggregate string    `json:"value_aggregate"`
+	Redeemable     bool      `json:"redeemable"`
+	Reason         string    `json:"reason,omitempty"`
+	CheckedAt      time.Time `json:"checked_at"`
+}
+
+type redeemabilityCheck struct {
+	collectionID   horizon.CollectionID
+	payer          string
+	sessionID      string
+	valueAggregate *big.Int
+	redeemable     bool
+	reason         string
+	reasonName     string
+	checkedAt      time.Time
+}
+
+// redeemability holds the last simulated collection of each active collection
+// and exports it as the sds_provider_collection_redeemable gauge, labeled with
+// the decoded revert error name when not redeemable
+type redeemability struct {
+	mu     sync.Mutex
+	checks map[string]*redeemabilityCheck // payer/collection ID -> last check
+
+	redeemable *prometheus.GaugeVec
+}
+
+func newRedeemability(registry *prometheus.Registry) *redeemability {
+	r := &redeemability{
+		checks: make(map[string]*redeemabilityCheck),
+		redeemable: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "sds_provider_collection_redeemable",
+			Help: "Whether the current RAV of an active collection can be collected on-chain (1) or not (0), reason is the revert error when not",
+		}, []string{"collection_id", "payer", "reason"}),
+	}
+	registry.MustRegister(r.redeemable)
+	return r
+}
+
+func redeemabilityKey(payer string, collectionID horizon.CollectionID) string {
+	return payer + "/" + eth.Hash(collectionID[:]).Pretty()
+}
+
+// record stores check and returns the previous check of the collection, nil
+// when it was not checked before
+func (r *redeemability) record(check *redeemabilityCheck) *redeemabilityCheck {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := redeemabilityKey(check.payer, check.collectionID)
+	previous := r.checks[key]
+	if previous != nil {
+		r.redeemable.Delete(previous.labels())
+	}
+	r.checks[key] = check
+
+	value := 0.0
+	if check.redeemable {
+		value = 1
+	}
+	r.redeemable.With(check.labels()).Set(value)
+
+	return previous
+}
+
+// retain forgets the collections not in active, e.g. once their sessions ended
+func (r *redeemability) retain(active map[string]bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, check := range r.checks {
+		if !active[key] {
+			r.redeemable.Delete(check.labels())
+			delete(r.checks, key)
+		}
+	}
+}
+
+func (r *redeemability) list() []*redeemabilityCheck {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]*redeemabilityCheck, 0, len(r.checks))
+	for _, check := range r.checks {
+		out = append(out, check)
+	}
+	slices.SortFunc(out, func(a, b *redeemabilityCheck) int {
+		return a.checkedAt.Compare(b.checkedAt)
+	})
+	return out
+}
+
+func (c *redeemabilityCheck) labels() prometheus.Labels {
+	reason := ""
+	if !c.redeemable {
+		reason = c.reasonName
+		if reason == "" {
+			reason = "unknown"
+		}
+	}
+
+	return prometheus.Labels{
+		"collection_id": eth.Hash(c.collectionID[:]).Pretty(),
+		"payer":         c.payer,
+		"reason":        reason,
+	}
+}
+
+// activeCollections returns, for each (payer, collection) with an active
+// session holding a non-zero RAV, the session holding the highest RAV
+func (s *Sidecar) activeCollections() map[string]*sidecar.Session {
+	out := make(map[string]*sidecar.Session)
+	for _, session := range s.sessions.List() {
+		if !session.IsActive() {
+			continue
+		}
+
+		rav := session.GetRAV()
+		if rav == nil || rav.Message == nil || rav.Message.ValueAggregate == nil || rav.Message.ValueAggregate.Sign() <= 0 {
+			continue
+		}
+
+		key := redeemabilityKey(rav.Message.Payer.Pretty(), rav.Message.CollectionID)
+		if current, found := out[key]; found && current.GetRAV().Message.ValueAggregate.Cmp(rav.Message.ValueAggregate) >= 0 {
+			continue
+		}
+		out[key] = session
+	}
+	return out
+}
+
+// watchRedeemability checks the redeemability of active collections every
+// interval until the sidecar terminates
+func (s *Sidecar) watchRedeemability(interval time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.OnTerminating(func(_ error) {
+		cancel()
+	})
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.checkRedeemability(ctx)
+		}
+	}
+}
+
+// checkRedeemability simulates collecting the current RAV of each active
+// collection. Reverts flag the collection as not redeemable with the decoded
+// revert reason, other failures (e.g. chain RPC unreachable) keep the last
+// outcome.
+func (s *Sidecar) checkRedeemability(ctx context.Context) {
+	active := s.activeCollections()
+
+	keys := make(map[string]bool, len(active))
+	for key, session := range active {
+		keys[key] = true
+		rav := session.GetRAV()
+
+		check := &redeemabilityCheck{
+			collectionID:   rav.Message.CollectionID,
+			payer:          rav.Message.Payer.Pretty(),
+			sessionID:      session.ID,
+			valueAggregate: rav.Message.ValueAggregate,
+			redeemable:     true,
+			checkedAt:      time.Now(),
+		}
+
+		if _, err := s.ravCollector.Estimate(ctx, rav); err != nil {
+			reason, name, reverted := sidecar.RevertReason(err)
+			if !reverted {
+				if ctx.Err() == nil {
+					s.logger.Warn("simulating RAV collection failed", zap.String("session_id", session.ID), zap.Error(err))
+				}
+				continue
+			}
+			check.redeemable = false
+			check.reason = reason
+			check.reasonName = name
+		}
+
+		previous := s.redeemability.record(check)
+		switch {
+		case !check.redeemable && (previous == nil || previous.redeemable || previous.reason != check.reason):
+			s.logger.Warn("RAV not redeemable, collecting it would revert", zap.String("session_id", session.ID), zap.Stringer("rav", rav.Message), zap.String("reason", check.reason))
+		case check.redeemable && previous != nil && !previous.redeemable:
+			s.logger.Info("RAV redeemable again", zap.String("session_id", session.ID), zap.Stringer("rav", rav.Message))
+		}
+	}
+
+	s.redeemability.retain(keys)
+}
+
+func (s *Sidecar) handleAdminRedeemability(w http.ResponseWriter, r *http.Request) {
+	checks := s.redeemability.list()
+
+	out := make([]*CollectionRedeemability, 0, len(checks))
+	for _, check := range checks {
+		out = append(out, &CollectionRedeemability{
+			CollectionID:   eth.Hash(check.collectionID[:]).Pretty(),
+			Payer:          check.payer,
+			SessionID:      check.sessionID,
+			ValueAggregate: s.display.Format(check.valueAggregate),
+			Redeemable:     check.redeemable,
+			Reason:         check.reason,
+			CheckedAt:      check.checkedAt,
+		})
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]any{"amount_unit": s.display.UnitName(), "collections": out})
+}
+
+func (s *Sidecar) metricsHandler() http.Handler {
+	// OpenMetrics is negotiated by scrapers wanting the latency exemplars
+	return promhttp.HandlerFor(s.metrics, promhttp.HandlerOpts{EnableOpenMetrics: true})
+}
@@ -0,0 +1,101 @@
+package sidecar
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCheckRedeemability(t *testing.T) {
+	payer := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	collector := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+
+	// PaymentsEscrowInsufficientBalance(1000, 3000)
+	insufficientBalance := fmt.Sprintf("0x%x%064x%064x", eth.Keccak256([]byte("PaymentsEscrowInsufficientBalance(uint256,uint256)"))[:4], 1000, 3000)
+
+	reverting := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		switch {
+		case req.Method == "eth_estimateGas" && reverting:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"error":{"code":3,"message":"execution reverted","data":"%s"}}`, req.ID, insufficientBalance)
+		case req.Method == "eth_gasPrice" || req.Method == "eth_estimateGas":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x5208"}`, req.ID)
+		default:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x%064x"}`, req.ID, 0)
+		}
+	}))
+	defer server.Close()
+
+	s := New(&Config{
+		ListenAddr:                 ":0",
+		ServiceProvider:            serviceProvider,
+		Domain:                     horizon.NewDomain(1337, collector),
+		CollectorAddr:              collector,
+		RPCEndpoint:                server.URL,
+		AdminListenAddr:            ":0",
+		DataServiceAddr:            dataService,
+		RedeemabilityCheckInterval: DefaultRedeemabilityCheckInterval,
+	}, zap.NewNop())
+	require.NotNil(t, s.redeemability)
+
+	session := s.sessions.Create(payer, serviceProvider, dataService)
+	session.SetRAV(&horizon.SignedRAV{Message: &horizon.RAV{
+		CollectionID:    horizon.CollectionID{0x01},
+		Payer:           payer,
+		DataService:     dataService,
+		ServiceProvider: serviceProvider,
+		ValueAggregate:  big.NewInt(3000),
+	}})
+
+	serve := func(path string) string {
+		rec := httptest.NewRecorder()
+		s.admin.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		return rec.Body.String()
+	}
+
+	s.checkRedeemability(t.Context())
+
+	var out struct {
+		Collections []*CollectionRedeemability `json:"collections"`
+	}
+	require.NoError(t, json.NewDecoder(strings.NewReader(serve("/v1/collections/redeemability"))).Decode(&out))
+	require.Len(t, out.Collections, 1)
+	assert.False(t, out.Collections[0].Redeemable)
+	assert.Equal(t, "PaymentsEscrowInsufficientBalance(1000, 3000)", out.Collections[0].Reason)
+	assert.Equal(t, session.ID, out.Collections[0].SessionID)
+
+	collectionID := horizon.CollectionID{0x01}
+	collectionLabel := eth.Hash(collectionID[:]).Pretty()
+	assert.Contains(t, serve("/metrics"), fmt.Sprintf(`sds_provider_collection_redeemable{collection_id="%s",payer="%s",reason="PaymentsEscrowInsufficientBalance"} 0`, collectionLabel, payer.Pretty()))
+
+	reverting = false
+	s.checkRedeemability(t.Context())
+
+	metrics := serve("/metrics")
+	assert.Contains(t, metrics, fmt.Sprintf(`sds_provider_collection_redeemable{collection_id="%s",payer="%s",reason=""} 1`, collectionLabel, payer.Pretty()))
+	assert.NotContains(t, metrics, "PaymentsEscrowInsufficientBalance")
+
+	session.End(commonv1.EndReason_END_REASON_COMPLETE)
+	s.checkRedeemability(t.Context())
+	assert.NotContains(t, serve("/metrics"), collectionLabel)
+}
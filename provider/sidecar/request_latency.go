@@ -0,0 +1,46 @@
+package sidecar
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestLatency exports the handling time of the data provider calls as the
+// sds_provider_request_duration_seconds histogram. Observations made within a
+// sampled trace carry its trace ID as an OpenMetrics exemplar, linking slow
+// requests to their trace.
+type requestLatency struct {
+	duration *prometheus.HistogramVec
+}
+
+func newRequestLatency(registry *prometheus.Registry) *requestLatency {
+	l := &requestLatency{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "sds_provider_request_duration_seconds",
+			Help:    "Time spent handling data provider calls, method is 'ValidatePayment', 'ReportUsage' or 'StreamUsage' (per report)",
+			Buckets: prometheus.ExponentialBuckets(0.0005, 2, 14),
+		}, []string{"method"}),
+	}
+
+	if registry != nil {
+		registry.MustRegister(l.duration)
+	}
+	return l
+}
+
+// observe records the time elapsed since start handling method, with the trace
+// ID of ctx's span as exemplar when it is sampled
+func (l *requestLatency) observe(ctx context.Context, method string, start time.Time) {
+	observer := l.duration.WithLabelValues(method)
+	elapsed := time.Since(start).Seconds()
+
+	spanContext := trace.SpanContextFromContext(ctx)
+	if exemplarObserver, ok := observer.(prometheus.ExemplarObserver); ok && spanContext.IsSampled() {
+		exemplarObserver.ObserveWithExemplar(elapsed, prometheus.Labels{"trace_id": spanContext.TraceID().String()})
+		return
+	}
+	observer.Observe(elapsed)
+}
@@ -0,0 +1,51 @@
+package sidecar
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRequestLatency_Exemplars(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	latency := newRequestLatency(registry)
+
+	traceID := trace.TraceID{0x01, 0x02, 0x03}
+	sampled := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     trace.SpanID{0x01},
+		TraceFlags: trace.FlagsSampled,
+	}))
+	unsampled := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x04},
+		SpanID:  trace.SpanID{0x02},
+	}))
+
+	latency.observe(sampled, "ValidatePayment", time.Now().Add(-time.Millisecond))
+	latency.observe(unsampled, "ReportUsage", time.Now())
+	latency.observe(context.Background(), "ReportUsage", time.Now())
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	require.Len(t, families, 1)
+
+	exemplars := map[string][]string{}
+	counts := map[string]uint64{}
+	for _, metric := range families[0].GetMetric() {
+		method := metric.GetLabel()[0].GetValue()
+		counts[method] = metric.GetHistogram().GetSampleCount()
+		for _, bucket := range metric.GetHistogram().GetBucket() {
+			if exemplar := bucket.GetExemplar(); exemplar != nil {
+				exemplars[method] = append(exemplars[method], exemplar.GetLabel()[0].GetValue())
+			}
+		}
+	}
+
+	assert.Equal(t, map[string]uint64{"ValidatePayment": 1, "ReportUsage": 2}, counts)
+	assert.Equal(t, map[string][]string{"ValidatePayment": {traceID.String()}}, exemplars)
+}
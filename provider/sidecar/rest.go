@@ -0,0 +1,180 @@
+package sidecar
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/dgrpc/server"
+	"github.com/streamingfast/eth-go"
+	"go.uber.org/zap"
+)
+
+// restSession is the JSON representation of a session served by the read-only
+// REST endpoints. Addresses are hex encoded and GRT values are decimal strings
+// in AmountUnit (exact wei by default, see Config.AmountDisplay) so dashboards
+// can consume them without protobuf tooling.
+type restSession struct {
+	SessionID     string              `json:"session_id"`
+	Active        bool                `json:"active"`
+	State         string              `json:"state"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+	EndedAt       *time.Time          `json:"ended_at,omitempty"`
+	EndReason     string              `json:"end_reason,omitempty"`
+	Payer         string              `json:"payer"`
+	Receiver      string              `json:"receiver"`
+	DataService   string              `json:"data_service"`
+	Collector     string              `json:"collector,omitempty"`
+	AmountUnit    string              `json:"amount_unit"`
+	Usage         restUsage           `json:"usage"`
+	Instances     []restInstanceUsage `json:"instances,omitempty"`
+	CurrentRAV    *restRAV            `json:"current_rav,omitempty"`
+	PaymentStatus *restPaymentStatus  `json:"payment_status,omitempty"`
+	Degraded      bool                `json:"degraded,omitempty"`
+}
+
+type restUsage struct {
+	BlocksProcessed  uint64 `json:"blocks_processed"`
+	BytesTransferred uint64 `json:"bytes_transferred"`
+	Requests         uint64 `json:"requests"`
+	Cost             string `json:"cost"`
+}
+
+type restInstanceUsage struct {
+	InstanceID   string    `json:"instance_id"`
+	Usage        restUsage `json:"usage"`
+	Reports      uint64    `json:"reports"`
+	LastReportAt time.Time `json:"last_report_at"`
+}
+
+type restRAV struct {
+	CollectionID   string `json:"collection_id"`
+	TimestampNs    uint64 `json:"timestamp_ns"`
+	ValueAggregate string `json:"value_aggregate"`
+	Signature      string `json:"signature"`
+}
+
+type restPaymentStatus struct {
+	CurrentRAVValue          string `json:"current_rav_value"`
+	AccumulatedUsageValue    string `json:"accumulated_usage_value"`
+	EscrowBalance            string `json:"escrow_balance"`
+	FundsSufficient          bool   `json:"funds_sufficient"`
+	EstimatedBlocksRemaining uint64 `json:"estimated_blocks_remaining"`
+}
+
+// restHandlers returns the read-only REST endpoints mounted next to the Connect RPCs:
+//   - GET /v1/sessions: lists all known sessions
+//   - GET /v1/sessions/{id}: returns a single session including its payment status
+func (s *Sidecar) restHandlers() []server.HTTPHandlerGetter {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/sessions", s.handleRESTListSessions)
+	mux.HandleFunc("GET /v1/sessions/{id}", s.handleRESTGetSession)
+
+	return []server.HTTPHandlerGetter{
+		func() (string, http.Handler) { return "/v1/sessions", mux },
+		func() (string, http.Handler) { return "/v1/sessions/{id}", mux },
+	}
+}
+
+func (s *Sidecar) handleRESTListSessions(w http.ResponseWriter, r *http.Request) {
+	sessions := s.sessions.List()
+
+	out := make([]*restSession, 0, len(sessions))
+	for _, session := range sessions {
+		out = append(out, newRESTSession(session, s.display))
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]any{"sessions": out})
+}
+
+func (s *Sidecar) handleRESTGetSession(w http.ResponseWriter, r *http.Request) {
+	session, err := s.sessions.Get(r.PathValue("id"))
+	if err != nil {
+		s.writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+
+	out := newRESTSession(session, s.display)
+	out.PaymentStatus = newRESTPaymentStatus(s.paymentStatus(r.Context(), session), s.display)
+	out.Degraded = s.Degraded()
+
+	s.writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Sidecar) writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		s.logger.Warn("failed to write JSON response", zap.Error(err))
+	}
+}
+
+func newRESTSession(session *sidecar.Session, display *sidecar.AmountDisplay) *restSession {
+	usage := session.GetUsage()
+	snapshot := session.Snapshot()
+
+	out := &restSession{
+		SessionID:   session.ID,
+		Active:      snapshot.State == sidecar.SessionStateActive,
+		State:       snapshot.State.String(),
+		CreatedAt:   snapshot.CreatedAt,
+		UpdatedAt:   snapshot.UpdatedAt,
+		EndedAt:     snapshot.EndedAt,
+		Payer:       session.Payer.Pretty(),
+		Receiver:    session.Receiver.Pretty(),
+		DataService: session.DataService.Pretty(),
+		AmountUnit:  display.UnitName(),
+		Usage: restUsage{
+			BlocksProcessed:  usage.BlocksProcessed,
+			BytesTransferred: usage.BytesTransferred,
+			Requests:         usage.Requests,
+			Cost:             display.Format(usage.Cost.ToNative()),
+		},
+	}
+
+	for _, instance := range session.GetInstanceUsage() {
+		out.Instances = append(out.Instances, restInstanceUsage{
+			InstanceID: instance.InstanceID,
+			Usage: restUsage{
+				BlocksProcessed:  instance.BlocksProcessed,
+				BytesTransferred: instance.BytesTransferred,
+				Requests:         instance.Requests,
+				Cost:             display.Format(instance.TotalCost),
+			},
+			Reports:      instance.Reports,
+			LastReportAt: instance.LastReportAt,
+		})
+	}
+
+	if snapshot.Collector != nil {
+		out.Collector = snapshot.Collector.Pretty()
+	}
+
+	if snapshot.EndReason != commonv1.EndReason_END_REASON_UNSPECIFIED {
+		out.EndReason = snapshot.EndReason.String()
+	}
+
+	if rav := session.GetRAV(); rav != nil && rav.Message != nil {
+		out.CurrentRAV = &restRAV{
+			CollectionID:   eth.Hash(rav.Message.CollectionID[:]).Pretty(),
+			TimestampNs:    rav.Message.TimestampNs,
+			ValueAggregate: display.Format(rav.Message.ValueAggregate),
+			Signature:      "0x" + rav.Signature.String(),
+		}
+	}
+
+	return out
+}
+
+func newRESTPaymentStatus(status *commonv1.PaymentStatus, display *sidecar.AmountDisplay) *restPaymentStatus {
+	return &restPaymentStatus{
+		CurrentRAVValue:          display.Format(status.CurrentRavValue.ToNative()),
+		AccumulatedUsageValue:    display.Format(status.AccumulatedUsageValue.ToNative()),
+		EscrowBalance:            display.Format(status.EscrowBalance.ToNative()),
+		FundsSufficient:          status.FundsSufficient,
+		EstimatedBlocksRemaining: status.EstimatedBlocksRemaining,
+	}
+}
@@ -0,0 +1,108 @@
+package sidecar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"go.uber.org/zap"
+)
+
+// ExportSession exports session sessionID to a blob signed with the identity
+// key, to be imported by another sidecar instance of the same service provider
+// (see ImportSession). It requires Config.IdentityKey.
+func (s *Sidecar) ExportSession(sessionID string) (*sidecar.SignedSessionExport, error) {
+	if s.identityKey == nil {
+		return nil, fmt.Errorf("session export requires the service provider identity key")
+	}
+
+	session, err := s.sessions.Get(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := sidecar.NewSessionExport(session)
+	s.collections.mu.Lock()
+	export.CollectTxHash = s.collections.collected[session.ID]
+	s.collections.mu.Unlock()
+
+	return export.Sign(s.identityKey, s.serviceProvider)
+}
+
+// ImportSession imports a session exported by another sidecar instance of the
+// same service provider, its current RAV must be signed by an accepted signer
+func (s *Sidecar) ImportSession(signed *sidecar.SignedSessionExport) (*sidecar.Session, error) {
+	export, err := signed.Open(s.serviceProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := export.Session()
+	if err != nil {
+		return nil, err
+	}
+
+	if rav := session.GetRAV(); rav != nil {
+		signer, err := s.verifyRAVSignature(rav)
+		if err != nil {
+			return nil, fmt.Errorf("verifying current RAV signature: %w", err)
+		}
+		if !s.validator.IsAcceptedSigner(signer) {
+			return nil, fmt.Errorf("current RAV signed by %s, not an accepted signer", signer.Pretty())
+		}
+	}
+
+	if err := s.sessions.Import(session); err != nil {
+		return nil, err
+	}
+	if export.CollectTxHash != "" {
+		s.collections.done(session.ID, export.CollectTxHash, true)
+	}
+	s.persistSession(session)
+
+	return session, nil
+}
+
+func (s *Sidecar) handleAdminExportSession(w http.ResponseWriter, r *http.Request) {
+	if s.identityKey == nil {
+		s.writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "session export is not configured, the identity private key is required"})
+		return
+	}
+
+	signed, err := s.ExportSession(r.PathValue("id"))
+	if err != nil {
+		s.writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.logger.Info("session exported", zap.String("session_id", r.PathValue("id")))
+	s.writeJSON(w, http.StatusOK, signed)
+}
+
+func (s *Sidecar) handleAdminImportSession(w http.ResponseWriter, r *http.Request) {
+	var signed sidecar.SignedSessionExport
+	if err := json.NewDecoder(r.Body).Decode(&signed); err != nil {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body: " + err.Error()})
+		return
+	}
+
+	session, err := s.ImportSession(&signed)
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, sidecar.ErrSessionExists):
+			status = http.StatusConflict
+		case errors.Is(err, sidecar.ErrSessionExportSigner):
+			status = http.StatusForbidden
+		}
+
+		s.logger.Warn("session import refused", zap.Error(err))
+		s.writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.logger.Info("session imported", zap.String("session_id", session.ID), zap.Stringer("state", session.State))
+	s.writeJSON(w, http.StatusOK, newRESTSession(session, s.display))
+}
@@ -0,0 +1,95 @@
+package sidecar
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestAdminSessionMigration(t *testing.T) {
+	providerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	serviceProvider := providerKey.PublicKey().Address()
+	payer := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	domain := horizon.NewDomain(1337, eth.MustNewAddress("0x4444444444444444444444444444444444444444"))
+
+	newSidecar := func(acceptedSigners ...eth.Address) *Sidecar {
+		return New(&Config{
+			ListenAddr:      ":0",
+			ServiceProvider: serviceProvider,
+			Domain:          domain,
+			AcceptedSigners: acceptedSigners,
+			AdminListenAddr: ":0",
+			IdentityKey:     providerKey,
+		}, zap.NewNop())
+	}
+	serve := func(s *Sidecar, method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.admin.Handler().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	source := newSidecar(signerKey.PublicKey().Address())
+	session := source.sessions.Create(payer, serviceProvider, dataService)
+	session.AddUsage(100, 4096, 1, big.NewInt(1000))
+	rav, err := horizon.Sign(domain, &horizon.RAV{
+		Payer:           payer,
+		DataService:     dataService,
+		ServiceProvider: serviceProvider,
+		TimestampNs:     1,
+		ValueAggregate:  big.NewInt(1000),
+	}, signerKey)
+	require.NoError(t, err)
+	session.SetRAV(rav)
+	session.End(commonv1.EndReason_END_REASON_COMPLETE)
+	source.collections.done(session.ID, "0xabc", true)
+
+	rec := serve(source, http.MethodGet, "/v1/sessions/"+session.ID+"/export", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	blob := rec.Body.String()
+
+	assert.Equal(t, http.StatusNotFound, serve(source, http.MethodGet, "/v1/sessions/unknown/export", "").Code)
+	assert.Equal(t, http.StatusConflict, serve(source, http.MethodPost, "/v1/sessions/import", blob).Code)
+
+	// The RAV signer must be accepted by the importing instance too
+	assert.Equal(t, http.StatusBadRequest, serve(newSidecar(), http.MethodPost, "/v1/sessions/import", blob).Code)
+
+	target := newSidecar(signerKey.PublicKey().Address())
+	rec = serve(target, http.MethodPost, "/v1/sessions/import", blob)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var out restSession
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+	assert.Equal(t, session.ID, out.SessionID)
+	assert.Equal(t, "ended", out.State)
+	assert.Equal(t, uint64(100), out.Usage.BlocksProcessed)
+
+	imported, err := target.sessions.Get(session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, rav.Signature, imported.GetRAV().Signature)
+	assert.Empty(t, target.pendingCollections(), "collected RAV must not be collected again")
+
+	// An export of another service provider is refused
+	otherKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	forged, err := sidecar.NewSessionExport(session).Sign(otherKey, serviceProvider)
+	require.NoError(t, err)
+	forgedBlob, err := json.Marshal(forged)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, serve(newSidecar(), http.MethodPost, "/v1/sessions/import", string(forgedBlob)).Code)
+}
@@ -0,0 +1,68 @@
+package sidecar
+
+import (
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+)
+
+// DefaultSessionResumeGrace is how long after its last activity a session can
+// be resumed by a consumer re-initializing with the session's last RAV
+const DefaultSessionResumeGrace = 5 * time.Minute
+
+// resumableEndReasons are the end reasons of interrupted sessions, a consumer
+// crash shows up as a client disconnect or a stream error
+var resumableEndReasons = []commonv1.EndReason{
+	commonv1.EndReason_END_REASON_CLIENT_DISCONNECT,
+	commonv1.EndReason_END_REASON_ERROR,
+}
+
+// resumeSession returns the session a consumer re-initializing with rav after a
+// crash left behind, reactivated, or nil when there is none. The session must
+// hold rav as current RAV, be still active or interrupted (resumableEndReasons)
+// and have been active within the resume grace, its accumulated usage not yet
+// covered by a RAV is carried over instead of being lost with a new session.
+func (s *Sidecar) resumeSession(payer, dataService eth.Address, rav *horizon.SignedRAV) *sidecar.Session {
+	if s.sessionResumeGrace <= 0 || rav == nil || rav.Message == nil {
+		return nil
+	}
+
+	now := time.Now()
+	var candidate *sidecar.Session
+	for _, session := range s.sessions.List() {
+		if !sidecar.AddressesEqual(session.Payer, payer) || !sidecar.AddressesEqual(session.DataService, dataService) {
+			continue
+		}
+
+		current := session.GetRAV()
+		if current == nil || !horizon.SignaturesEqual(current.Signature, rav.Signature) {
+			continue
+		}
+
+		lastActivity := session.LastActivity()
+		if now.Sub(lastActivity) > s.sessionResumeGrace {
+			continue
+		}
+		if candidate == nil || lastActivity.After(candidate.LastActivity()) {
+			candidate = session
+		}
+	}
+	if candidate == nil {
+		return nil
+	}
+
+	// Keep the final RAV from being collected while the session is resumed,
+	// a collected (or being collected) session cannot be resumed
+	if !s.collections.begin(candidate.ID) {
+		return nil
+	}
+	defer s.collections.done(candidate.ID, "", false)
+
+	if !candidate.Resume(resumableEndReasons...) {
+		return nil
+	}
+	return candidate
+}
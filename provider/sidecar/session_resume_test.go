@@ -0,0 +1,113 @@
+package sidecar
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStartSession_ResumeInterruptedSession(t *testing.T) {
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	payer := signerKey.PublicKey().Address()
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	domain := horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111"))
+
+	s := New(&Config{
+		ServiceProvider:    serviceProvider,
+		Domain:             domain,
+		AcceptedSigners:    []eth.Address{payer},
+		SessionResumeGrace: time.Minute,
+	}, zap.NewNop())
+
+	newRAV := func(value int64) *horizon.SignedRAV {
+		signed, err := horizon.Sign(domain, &horizon.RAV{
+			Payer:           payer,
+			DataService:     dataService,
+			ServiceProvider: serviceProvider,
+			TimestampNs:     uint64(1000 + value),
+			ValueAggregate:  big.NewInt(value),
+		}, signerKey)
+		require.NoError(t, err)
+		return signed
+	}
+
+	start := func(rav *horizon.SignedRAV) string {
+		resp, err := s.StartSession(context.Background(), connect.NewRequest(&providerv1.StartSessionRequest{
+			EscrowAccount: &commonv1.EscrowAccount{
+				Payer:       commonv1.AddressFromEth(payer),
+				Receiver:    commonv1.AddressFromEth(serviceProvider),
+				DataService: commonv1.AddressFromEth(dataService),
+			},
+			InitialRav: sidecar.HorizonSignedRAVToProto(rav),
+		}))
+		require.NoError(t, err)
+		require.True(t, resp.Msg.Accepted, resp.Msg.RejectionReason)
+		return resp.Msg.SessionId
+	}
+
+	lastRAV := newRAV(100)
+	sessionID := start(newRAV(50))
+	session, err := s.sessions.Get(sessionID)
+	require.NoError(t, err)
+	session.SetRAV(lastRAV)
+	session.AddUsage(10, 1000, 1, big.NewInt(130))
+
+	t.Run("active session", func(t *testing.T) {
+		assert.Equal(t, sessionID, start(lastRAV))
+		assert.Equal(t, 1, s.sessions.Count())
+	})
+
+	t.Run("interrupted session", func(t *testing.T) {
+		session.End(commonv1.EndReason_END_REASON_CLIENT_DISCONNECT)
+
+		assert.Equal(t, sessionID, start(lastRAV))
+		assert.True(t, session.IsActive())
+		assert.Nil(t, session.EndedAt)
+		assert.Equal(t, uint64(10), session.GetUsage().BlocksProcessed)
+		assert.Equal(t, "130", session.TotalCost.String())
+	})
+
+	t.Run("other RAV", func(t *testing.T) {
+		assert.NotEqual(t, sessionID, start(newRAV(200)))
+	})
+
+	t.Run("completed session", func(t *testing.T) {
+		session.End(commonv1.EndReason_END_REASON_COMPLETE)
+		assert.NotEqual(t, sessionID, start(lastRAV))
+		assert.False(t, session.IsActive())
+	})
+
+	t.Run("collected session", func(t *testing.T) {
+		collected := start(newRAV(300))
+		resumable, err := s.sessions.Get(collected)
+		require.NoError(t, err)
+		resumable.End(commonv1.EndReason_END_REASON_ERROR)
+		s.collections.done(collected, "0xabcd", true)
+
+		assert.NotEqual(t, collected, start(newRAV(300)))
+	})
+
+	t.Run("grace elapsed", func(t *testing.T) {
+		stale := start(newRAV(400))
+		staleSession, err := s.sessions.Get(stale)
+		require.NoError(t, err)
+		staleSession.End(commonv1.EndReason_END_REASON_CLIENT_DISCONNECT)
+		staleSession.UpdatedAt = time.Now().Add(-2 * time.Minute)
+
+		assert.NotEqual(t, stale, start(newRAV(400)))
+	})
+}
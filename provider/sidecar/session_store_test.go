@@ -0,0 +1,170 @@
+package sidecar
+
+import (
+	"context"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSessionStore_SurvivesRestart(t *testing.T) {
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	payer := signerKey.PublicKey().Address()
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	domain := horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111"))
+	storePath := filepath.Join(t.TempDir(), "sessions")
+
+	// Each sidecar closes the store of the previous one, as on a restart
+	var current *Sidecar
+	newSidecar := func() *Sidecar {
+		if current != nil {
+			current.closeSessionStore()
+		}
+		s := New(&Config{
+			ServiceProvider: serviceProvider,
+			Domain:          domain,
+			AcceptedSigners: []eth.Address{payer},
+			StorePath:       storePath,
+		}, zap.NewNop())
+		require.NoError(t, s.restoreSessions())
+		t.Cleanup(s.closeSessionStore)
+		current = s
+		return s
+	}
+
+	newRAV := func(value int64) *horizon.SignedRAV {
+		signed, err := horizon.Sign(domain, &horizon.RAV{
+			Payer:           payer,
+			DataService:     dataService,
+			ServiceProvider: serviceProvider,
+			TimestampNs:     uint64(1000 + value),
+			ValueAggregate:  big.NewInt(value),
+		}, signerKey)
+		require.NoError(t, err)
+		return signed
+	}
+
+	s := newSidecar()
+	assert.Equal(t, 0, s.sessions.Count())
+
+	resp, err := s.StartSession(context.Background(), connect.NewRequest(&providerv1.StartSessionRequest{
+		EscrowAccount: &commonv1.EscrowAccount{
+			Payer:       commonv1.AddressFromEth(payer),
+			Receiver:    commonv1.AddressFromEth(serviceProvider),
+			DataService: commonv1.AddressFromEth(dataService),
+		},
+		InitialRav: sidecar.HorizonSignedRAVToProto(newRAV(50)),
+	}))
+	require.NoError(t, err)
+	require.True(t, resp.Msg.Accepted, resp.Msg.RejectionReason)
+	sessionID := resp.Msg.SessionId
+
+	_, err = s.ReportUsage(context.Background(), connect.NewRequest(&providerv1.ReportUsageRequest{
+		SessionId: sessionID,
+		Usage:     &commonv1.Usage{BlocksProcessed: 10, BytesTransferred: 1000, Requests: 1, Cost: commonv1.BigIntFromNative(big.NewInt(130))},
+	}))
+	require.NoError(t, err)
+
+	lastRAV := newRAV(100)
+	submitted, err := s.SubmitRAV(context.Background(), connect.NewRequest(&providerv1.SubmitRAVRequest{
+		SessionId: sessionID,
+		SignedRav: sidecar.HorizonSignedRAVToProto(lastRAV),
+	}))
+	require.NoError(t, err)
+	require.True(t, submitted.Msg.Accepted, submitted.Msg.RejectionReason)
+
+	// A restarted sidecar serves the session with its usage and current RAV
+	restarted := newSidecar()
+	session, err := restarted.sessions.Get(sessionID)
+	require.NoError(t, err)
+	assert.True(t, session.IsActive())
+	assert.Equal(t, uint64(10), session.GetUsage().BlocksProcessed)
+	assert.Equal(t, "130", session.TotalCost.String())
+	require.NotNil(t, session.GetRAV())
+	assert.Equal(t, lastRAV.Signature, session.GetRAV().Signature)
+	assert.Equal(t, int64(100), session.GetRAV().Message.ValueAggregate.Int64())
+
+	// Ending and collecting are persisted too
+	_, err = restarted.EndSession(context.Background(), connect.NewRequest(&providerv1.EndSessionRequest{
+		SessionId: sessionID,
+		Reason:    commonv1.EndReason_END_REASON_COMPLETE,
+	}))
+	require.NoError(t, err)
+	restarted.collections.done(sessionID, "0xabcd", true)
+	restarted.persistSession(session)
+
+	again := newSidecar()
+	session, err = again.sessions.Get(sessionID)
+	require.NoError(t, err)
+	assert.Equal(t, sidecar.SessionStateEnded, session.State)
+	assert.Equal(t, commonv1.EndReason_END_REASON_COMPLETE, session.EndReason)
+	assert.True(t, again.collections.isCollected(sessionID))
+	assert.Empty(t, again.pendingCollections())
+}
+
+func TestPruneSessions(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "sessions")
+	s := New(&Config{
+		ServiceProvider:       eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		Domain:                horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+		StorePath:             storePath,
+		EndedSessionRetention: time.Hour,
+	}, zap.NewNop())
+
+	newSession := func(value int64, ended bool) *sidecar.Session {
+		session := s.sessions.Create(
+			eth.MustNewAddress("0x4444444444444444444444444444444444444444"),
+			eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+			eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+		)
+		session.SetRAV(&horizon.SignedRAV{Message: &horizon.RAV{ValueAggregate: big.NewInt(value)}})
+		if ended {
+			session.End(commonv1.EndReason_END_REASON_COMPLETE)
+		}
+		s.persistSession(session)
+		return session
+	}
+
+	active := newSession(100, false)
+	unpaid := newSession(0, true)
+	uncollected := newSession(100, true)
+	collected := newSession(100, true)
+	s.collections.done(collected.ID, "0xabcd", true)
+	s.flushSessions()
+
+	// Sessions are kept within the retention
+	assert.Equal(t, 0, s.pruneSessions(time.Now()))
+	assert.Equal(t, 4, s.sessions.Count())
+
+	assert.Equal(t, 2, s.pruneSessions(time.Now().Add(2*time.Hour)))
+	for _, session := range []*sidecar.Session{unpaid, collected} {
+		_, err := s.sessions.Get(session.ID)
+		assert.Error(t, err)
+	}
+	assert.False(t, s.collections.isCollected(collected.ID))
+
+	s.closeSessionStore()
+	store := sidecar.NewBoltSessionStore(storePath)
+	defer store.Close()
+	exports, err := store.List()
+	require.NoError(t, err)
+	stored := make([]string, 0, len(exports))
+	for _, export := range exports {
+		stored = append(stored, export.SessionID)
+	}
+	assert.ElementsMatch(t, []string{active.ID, uncollected.ID}, stored)
+}
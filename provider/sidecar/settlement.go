@@ -0,0 +1,90 @@
+package sidecar
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"go.uber.org/zap"
+)
+
+// settlementQueryTimeout bounds the query of the value already collected
+// on-chain for the collection of a session being settled
+const settlementQueryTimeout = 5 * time.Second
+
+// settlementCuts are the cuts the payout of settled sessions is projected after
+type settlementCuts struct {
+	protocol    horizon.PPM
+	dataService horizon.PPM
+	delegation  horizon.PPM
+}
+
+// settle records the settlement of session, which ended, as of its current
+// RAV. It is left unchanged when the settlement cannot be computed.
+func (s *Sidecar) settle(ctx context.Context, session *sidecar.Session) {
+	rav := session.GetRAV()
+	settlement, err := sidecar.NewSettlement(ravValue(rav), s.collectedValue(ctx, session, rav), s.settlementCuts.protocol, s.settlementCuts.dataService, s.settlementCuts.delegation)
+	if err != nil {
+		s.logger.Warn("settling session failed", zap.String("session_id", session.ID), zap.Error(err))
+		return
+	}
+	session.SetSettlement(settlement)
+
+	s.logger.Info("session settled",
+		zap.String("session_id", session.ID),
+		s.display.Field("signed_value", settlement.SignedValue),
+		s.display.Field("previously_collected", settlement.PreviouslyCollected),
+		s.display.Field("pending_delta", settlement.PendingDelta),
+		s.display.Field("projected_payout", settlement.ProjectedPayout),
+	)
+}
+
+// collectedValue returns the value already collected for the collection of
+// rav: queried on-chain when a collector is configured, what the sidecar
+// collected itself otherwise or when the query fails
+func (s *Sidecar) collectedValue(ctx context.Context, session *sidecar.Session, rav *horizon.SignedRAV) *big.Int {
+	if rav == nil || rav.Message == nil {
+		return new(big.Int)
+	}
+
+	if s.ravCollector != nil {
+		ctx, cancel := context.WithTimeout(ctx, settlementQueryTimeout)
+		defer cancel()
+
+		collected, err := s.ravCollector.TokensCollected(ctx, rav.Message)
+		if err == nil {
+			return collected
+		}
+		s.logger.Warn("querying collected value failed, settling with the value collected by the sidecar", zap.String("session_id", session.ID), zap.Error(err))
+	}
+
+	if s.uncollected != nil {
+		if collected := s.uncollected.collectedValue(rav.Message.CollectionID); collected != nil {
+			return collected
+		}
+	}
+	return new(big.Int)
+}
+
+// settlementToProto returns the settlement of session, nil when it has none
+func settlementToProto(session *sidecar.Session) *providerv1.SessionSettlement {
+	settlement := session.GetSettlement()
+	if settlement == nil {
+		return nil
+	}
+
+	return &providerv1.SessionSettlement{
+		TotalUsage:          session.GetUsage(),
+		SignedValue:         commonv1.BigIntFromNative(settlement.SignedValue),
+		PreviouslyCollected: commonv1.BigIntFromNative(settlement.PreviouslyCollected),
+		PendingDelta:        commonv1.BigIntFromNative(settlement.PendingDelta),
+		ProtocolCut:         commonv1.BigIntFromNative(settlement.ProtocolCut),
+		DataServiceCut:      commonv1.BigIntFromNative(settlement.DataServiceCut),
+		DelegationCut:       commonv1.BigIntFromNative(settlement.DelegationCut),
+		ProjectedPayout:     commonv1.BigIntFromNative(settlement.ProjectedPayout),
+	}
+}
@@ -0,0 +1,113 @@
+package sidecar
+
+import (
+	"context"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestEndSession_Settlement(t *testing.T) {
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	payer := signerKey.PublicKey().Address()
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	domain := horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111"))
+	storePath := filepath.Join(t.TempDir(), "sessions")
+
+	s := New(&Config{
+		ServiceProvider:    serviceProvider,
+		Domain:             domain,
+		AcceptedSigners:    []eth.Address{payer},
+		StorePath:          storePath,
+		ProtocolPaymentCut: 100_000,
+		DataServiceCut:     100_000,
+		DelegationFeeCut:   500_000,
+	}, zap.NewNop())
+
+	// Value of the collection the sidecar collected before, e.g. from an
+	// earlier session
+	s.uncollected = newUncollectedCeiling(big.NewInt(1_000_000))
+	s.uncollected.record(horizon.CollectionID{}, big.NewInt(400))
+
+	newRAV := func(value int64, timestampNs uint64) *commonv1.SignedRAV {
+		signed, err := horizon.Sign(domain, &horizon.RAV{
+			Payer:           payer,
+			DataService:     dataService,
+			ServiceProvider: serviceProvider,
+			TimestampNs:     timestampNs,
+			ValueAggregate:  big.NewInt(value),
+		}, signerKey)
+		require.NoError(t, err)
+		return sidecar.HorizonSignedRAVToProto(signed)
+	}
+	submitRAV := func(sessionID string, value int64, timestampNs uint64) {
+		resp, err := s.SubmitRAV(context.Background(), connect.NewRequest(&providerv1.SubmitRAVRequest{SessionId: sessionID, SignedRav: newRAV(value, timestampNs)}))
+		require.NoError(t, err)
+		require.True(t, resp.Msg.Accepted, resp.Msg.RejectionReason)
+	}
+
+	start, err := s.StartSession(context.Background(), connect.NewRequest(&providerv1.StartSessionRequest{
+		EscrowAccount: &commonv1.EscrowAccount{
+			Payer:       commonv1.AddressFromEth(payer),
+			Receiver:    commonv1.AddressFromEth(serviceProvider),
+			DataService: commonv1.AddressFromEth(dataService),
+		},
+		InitialRav: newRAV(0, 1000),
+	}))
+	require.NoError(t, err)
+	require.True(t, start.Msg.Accepted, start.Msg.RejectionReason)
+	sessionID := start.Msg.SessionId
+
+	submitRAV(sessionID, 1000, 2000)
+	_, err = s.ReportUsage(context.Background(), connect.NewRequest(&providerv1.ReportUsageRequest{
+		SessionId: sessionID,
+		Usage:     &commonv1.Usage{BlocksProcessed: 12, Cost: commonv1.BigIntFromNative(big.NewInt(1200))},
+	}))
+	require.NoError(t, err)
+
+	end, err := s.EndSession(context.Background(), connect.NewRequest(&providerv1.EndSessionRequest{
+		SessionId: sessionID,
+		Reason:    commonv1.EndReason_END_REASON_COMPLETE,
+	}))
+	require.NoError(t, err)
+	assert.True(t, end.Msg.RavRequested)
+
+	settlement := end.Msg.Settlement
+	require.NotNil(t, settlement)
+	assert.Equal(t, uint64(12), settlement.TotalUsage.BlocksProcessed)
+	assert.Equal(t, "1200", settlement.TotalUsage.Cost.ToNative().String())
+	assert.Equal(t, "1000", settlement.SignedValue.ToNative().String())
+	assert.Equal(t, "400", settlement.PreviouslyCollected.ToNative().String())
+	assert.Equal(t, "600", settlement.PendingDelta.ToNative().String())
+	assert.Equal(t, "60", settlement.ProtocolCut.ToNative().String())
+	assert.Equal(t, "54", settlement.DataServiceCut.ToNative().String())
+	assert.Equal(t, "243", settlement.DelegationCut.ToNative().String())
+	assert.Equal(t, "243", settlement.ProjectedPayout.ToNative().String())
+
+	// The final RAV requested settles the session again, which is persisted
+	submitRAV(sessionID, 1200, 3000)
+
+	s.closeSessionStore()
+	store := sidecar.NewBoltSessionStore(storePath)
+	defer store.Close()
+	exports, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, exports, 1)
+	require.NotNil(t, exports[0].Settlement)
+	assert.Equal(t, "1200", exports[0].Settlement.SignedValue.String())
+	assert.Equal(t, "800", exports[0].Settlement.PendingDelta.String())
+	assert.Equal(t, "324", exports[0].Settlement.ProjectedPayout.String())
+}
@@ -0,0 +1,224 @@
+package sidecar
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"go.uber.org/zap"
+)
+
+// DefaultShutdownRAVTimeout is how long shutdown waits for the final RAVs
+// requested from active sessions
+const DefaultShutdownRAVTimeout = 10 * time.Second
+
+// ShutdownCollectTimeout bounds the on-chain collection of the RAVs of active
+// sessions on shutdown, see Config.CollectOnShutdown
+const ShutdownCollectTimeout = 30 * time.Second
+
+// shutdownPollInterval is how often shutdown checks whether the final RAVs
+// requested were received
+const shutdownPollInterval = 50 * time.Millisecond
+
+const shutdownStopReason = "provider sidecar is shutting down"
+
+// shuttingDown is the outcome of usage reported while the sidecar shuts down,
+// ravRequested when the session's final RAV is still awaited
+func shuttingDown(ravRequested bool) *providerv1.ReportUsageResponse {
+	return &providerv1.ReportUsageResponse{
+		ShouldContinue: false,
+		StopReason:     shutdownStopReason,
+		StopCode:       commonv1.RejectionCode_REJECTION_CODE_SHUTTING_DOWN,
+		RavRequested:   ravRequested,
+	}
+}
+
+// usageStreams tracks the open StreamUsage streams along with the sessions
+// reported on each, so outcomes can be pushed to them outside of the reports
+// they answer
+type usageStreams struct {
+	mu      sync.Mutex
+	streams map[*usageStream]struct{}
+}
+
+type usageStream struct {
+	mu       sync.Mutex
+	send     func(*providerv1.StreamUsageResponse) error
+	sessions map[string]bool
+}
+
+func newUsageStreams() *usageStreams {
+	return &usageStreams{streams: make(map[*usageStream]struct{})}
+}
+
+func (u *usageStreams) open(send func(*providerv1.StreamUsageResponse) error) *usageStream {
+	stream := &usageStream{send: send, sessions: make(map[string]bool)}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.streams[stream] = struct{}{}
+	return stream
+}
+
+func (u *usageStreams) close(stream *usageStream) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	delete(u.streams, stream)
+}
+
+// push sends outcome for each of sessionIDs on the streams they are reported
+// on, streams failing to send are left to their handler
+func (u *usageStreams) push(sessionIDs map[string]bool, outcome *providerv1.ReportUsageResponse) {
+	u.mu.Lock()
+	streams := make([]*usageStream, 0, len(u.streams))
+	for stream := range u.streams {
+		streams = append(streams, stream)
+	}
+	u.mu.Unlock()
+
+	for _, stream := range streams {
+		for _, sessionID := range stream.reported() {
+			if !sessionIDs[sessionID] {
+				continue
+			}
+			if err := stream.Send(&providerv1.StreamUsageResponse{SessionId: sessionID, Outcome: outcome}); err != nil {
+				break
+			}
+		}
+	}
+}
+
+// reporting records that sessionID is reported on the stream, until it is told
+// to stop
+func (s *usageStream) reporting(sessionID string, reported bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if reported {
+		s.sessions[sessionID] = true
+	} else {
+		delete(s.sessions, sessionID)
+	}
+}
+
+func (s *usageStream) reported() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]string, 0, len(s.sessions))
+	for sessionID := range s.sessions {
+		out = append(out, sessionID)
+	}
+	return out
+}
+
+// Send sends resp on the stream, serialized with the other sends as the
+// stream does not support concurrent ones
+func (s *usageStream) Send(resp *providerv1.StreamUsageResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.send(resp)
+}
+
+// drain flushes the final RAVs of the active sessions before the sidecar
+// stops serving. New sessions are rejected from then on. A final RAV is
+// requested for each active session whose usage its RAV does not cover, pushed
+// on the StreamUsage streams the session is reported on and answered to its
+// next usage report, then awaited up to shutdownRAVTimeout. Active sessions
+// are persisted and, with collectOnShutdown, their RAVs collected on-chain.
+func (s *Sidecar) drain() {
+	s.draining.Store(true)
+
+	active := s.sessions.GetActive()
+	requested := make(map[string]bool)
+	if s.shutdownRAVTimeout > 0 {
+		for _, session := range active {
+			if s.ravRequests.requestFinal(session, ravRequestTriggerShutdown) {
+				requested[session.ID] = true
+			}
+		}
+	}
+
+	if len(requested) > 0 {
+		s.logger.Info("requesting final RAVs of active sessions before shutting down",
+			zap.Int("sessions", len(requested)),
+			zap.Duration("timeout", s.shutdownRAVTimeout),
+		)
+		s.streams.push(requested, shuttingDown(true))
+
+		if missing := s.awaitFinalRAVs(requested, s.shutdownRAVTimeout); len(missing) > 0 {
+			s.logger.Warn("final RAVs not received before shutting down, the usage they would cover is unpaid until the sessions resume",
+				zap.Strings("session_ids", missing),
+			)
+		} else {
+			s.logger.Info("received final RAVs of active sessions", zap.Int("sessions", len(requested)))
+		}
+	}
+
+	for _, session := range active {
+		s.persistSession(session)
+	}
+	s.flushSessions()
+
+	if s.collectOnShutdown {
+		ctx, cancel := context.WithTimeout(context.Background(), ShutdownCollectTimeout)
+		defer cancel()
+		s.collectActiveRAVs(ctx, active)
+	}
+}
+
+// awaitFinalRAVs waits up to timeout for the final RAVs of sessionIDs and
+// returns the sessions whose final RAV was not received. It does not wait once
+// the server stopped, no RAV can be received anymore.
+func (s *Sidecar) awaitFinalRAVs(sessionIDs map[string]bool, timeout time.Duration) []string {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	for {
+		var missing []string
+		for sessionID := range sessionIDs {
+			if s.ravRequests.finalPending(sessionID) {
+				missing = append(missing, sessionID)
+			}
+		}
+		if len(missing) == 0 || (s.server != nil && s.server.IsTerminating()) {
+			return missing
+		}
+
+		select {
+		case <-deadline.C:
+			return missing
+		case <-ticker.C:
+		}
+	}
+}
+
+// collectActiveRAVs collects the current RAV of each of sessions on-chain,
+// failures are logged and the RAVs left for the next collection
+func (s *Sidecar) collectActiveRAVs(ctx context.Context, sessions []*sidecar.Session) {
+	for _, session := range sessions {
+		rav := session.GetRAV()
+		if rav == nil || rav.Message == nil || rav.Message.ValueAggregate == nil || rav.Message.ValueAggregate.Sign() <= 0 {
+			continue
+		}
+
+		unlock := s.collections.lockCollection(rav.Message.CollectionID)
+		collected, err := s.collectCurrentRAV(ctx, rav)
+		unlock()
+		if err != nil {
+			s.logger.Warn("collecting RAV on shutdown failed", zap.String("session_id", session.ID), zap.Error(err))
+			continue
+		}
+		if s.uncollected != nil {
+			s.uncollected.record(rav.Message.CollectionID, collected)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package sidecar
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1/providerv1connect"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestShutdown_FlushesFinalRAVs(t *testing.T) {
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	payer := signerKey.PublicKey().Address()
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	domain := horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111"))
+	storePath := filepath.Join(t.TempDir(), "sessions")
+
+	s := New(&Config{
+		ServiceProvider:      serviceProvider,
+		Domain:               domain,
+		AcceptedSigners:      []eth.Address{payer},
+		StorePath:            storePath,
+		RAVRequestThresholds: &RAVRequestThresholds{Value: big.NewInt(1000)},
+		ShutdownRAVTimeout:   5 * time.Second,
+	}, zap.NewNop())
+
+	newRAV := func(value int64, timestampNs uint64) *commonv1.SignedRAV {
+		signed, err := horizon.Sign(domain, &horizon.RAV{
+			Payer:           payer,
+			DataService:     dataService,
+			ServiceProvider: serviceProvider,
+			TimestampNs:     timestampNs,
+			ValueAggregate:  big.NewInt(value),
+		}, signerKey)
+		require.NoError(t, err)
+		return sidecar.HorizonSignedRAVToProto(signed)
+	}
+	startSession := func(timestampNs uint64) *providerv1.StartSessionResponse {
+		resp, err := s.StartSession(context.Background(), connect.NewRequest(&providerv1.StartSessionRequest{
+			EscrowAccount: &commonv1.EscrowAccount{
+				Payer:       commonv1.AddressFromEth(payer),
+				Receiver:    commonv1.AddressFromEth(serviceProvider),
+				DataService: commonv1.AddressFromEth(dataService),
+			},
+			InitialRav: newRAV(0, timestampNs),
+		}))
+		require.NoError(t, err)
+		return resp.Msg
+	}
+
+	start := startSession(1000)
+	require.True(t, start.Accepted, start.RejectionReason)
+	streamed := start.SessionId
+	start = startSession(2000)
+	require.True(t, start.Accepted, start.RejectionReason)
+	reported := start.SessionId
+
+	// Bidirectional streams require HTTP/2
+	mux := http.NewServeMux()
+	mux.Handle(providerv1connect.NewProviderSidecarServiceHandler(s))
+	server := httptest.NewUnstartedServer(mux)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	client := providerv1connect.NewProviderSidecarServiceClient(server.Client(), server.URL)
+	stream := client.StreamUsage(context.Background())
+	defer stream.CloseRequest()
+
+	usage := &commonv1.Usage{BlocksProcessed: 10, Cost: commonv1.BigIntFromNative(big.NewInt(300))}
+	require.NoError(t, stream.Send(&providerv1.ReportUsageRequest{SessionId: streamed, Usage: usage}))
+	report, err := s.ReportUsage(context.Background(), connect.NewRequest(&providerv1.ReportUsageRequest{SessionId: reported, Usage: usage}))
+	require.NoError(t, err)
+	require.True(t, report.Msg.ShouldContinue)
+
+	// The first outcome carries the initial RAV
+	resp, err := stream.Receive()
+	require.NoError(t, err)
+	require.True(t, resp.Outcome.ShouldContinue)
+
+	drained := make(chan struct{})
+	go func() {
+		s.drain()
+		close(drained)
+	}()
+
+	// The final RAV request is pushed to the stream
+	resp, err = stream.Receive()
+	require.NoError(t, err)
+	assert.Equal(t, streamed, resp.SessionId)
+	assert.False(t, resp.Outcome.ShouldContinue)
+	assert.True(t, resp.Outcome.RavRequested)
+	assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_SHUTTING_DOWN, resp.Outcome.StopCode)
+
+	// And answered to the next usage report
+	require.Eventually(t, func() bool { return s.ravRequests.finalPending(reported) }, time.Second, 10*time.Millisecond)
+	report, err = s.ReportUsage(context.Background(), connect.NewRequest(&providerv1.ReportUsageRequest{SessionId: reported, Usage: usage}))
+	require.NoError(t, err)
+	assert.False(t, report.Msg.ShouldContinue)
+	assert.True(t, report.Msg.RavRequested)
+	assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_SHUTTING_DOWN, report.Msg.StopCode)
+
+	// New sessions are rejected meanwhile
+	rejected := startSession(3000)
+	assert.False(t, rejected.Accepted)
+	assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_SHUTTING_DOWN, rejected.RejectionCode)
+
+	for sessionID, value := range map[string]int64{streamed: 300, reported: 600} {
+		submitted, err := s.SubmitRAV(context.Background(), connect.NewRequest(&providerv1.SubmitRAVRequest{SessionId: sessionID, SignedRav: newRAV(value, 3000)}))
+		require.NoError(t, err)
+		require.True(t, submitted.Msg.Accepted, submitted.Msg.RejectionReason)
+	}
+
+	select {
+	case <-drained:
+	case <-time.After(time.Second):
+		t.Fatal("shutdown still awaiting final RAVs once received")
+	}
+
+	// The final RAVs are persisted
+	s.closeSessionStore()
+	store := sidecar.NewBoltSessionStore(storePath)
+	defer store.Close()
+	exports, err := store.List()
+	require.NoError(t, err)
+	values := make(map[string]string)
+	for _, export := range exports {
+		values[export.SessionID] = export.CurrentRAV.ValueAggregate.String()
+	}
+	assert.Equal(t, "300", values[streamed])
+	assert.Equal(t, "600", values[reported])
+}
+
+func TestShutdown_FinalRAVTimeout(t *testing.T) {
+	payer := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+
+	newSession := func(s *Sidecar) *sidecar.Session {
+		session := s.sessions.Create(payer, serviceProvider, dataService)
+		session.SetRAV(&horizon.SignedRAV{Message: &horizon.RAV{Payer: payer, ValueAggregate: big.NewInt(100)}})
+		session.AddUsage(1, 0, 0, big.NewInt(150))
+		return session
+	}
+
+	s := New(&Config{
+		ServiceProvider:    serviceProvider,
+		Domain:             horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+		ShutdownRAVTimeout: 100 * time.Millisecond,
+	}, zap.NewNop())
+	session := newSession(s)
+
+	began := time.Now()
+	s.drain()
+	assert.GreaterOrEqual(t, time.Since(began), 100*time.Millisecond)
+	assert.True(t, s.ravRequests.finalPending(session.ID), "final RAV still awaited")
+
+	// No final RAV is requested when disabled
+	s = New(&Config{
+		ServiceProvider:    serviceProvider,
+		Domain:             horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+		ShutdownRAVTimeout: -1,
+	}, zap.NewNop())
+	session = newSession(s)
+
+	s.drain()
+	assert.False(t, s.ravRequests.finalPending(session.ID))
+}
@@ -4,11 +4,14 @@ import (
 	"context"
 	"math/big"
 	"net/http"
+	"sync/atomic"
+	"time"
 
 	"connectrpc.com/connect"
 	"github.com/graphprotocol/substreams-data-service/horizon"
 	"github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1/providerv1connect"
 	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/streamingfast/dgrpc/server"
 	"github.com/streamingfast/dgrpc/server/connectrpc"
 	"github.com/streamingfast/eth-go"
@@ -29,6 +32,14 @@ type Sidecar struct {
 	// Session management
 	sessions *sidecar.SessionManager
 
+	// Persists sessions across restarts, nil when sessions are kept in memory
+	// only, changes being written by storeWriter every sessionFlushInterval
+	store                SessionStore
+	storeWriter          *sidecar.SessionWriter
+	sessionFlushInterval time.Duration
+	// Ended sessions are pruned this long after they ended, never when zero
+	sessionRetention time.Duration
+
 	// Service provider identity
 	serviceProvider eth.Address
 
@@ -42,13 +53,120 @@ type Sidecar struct {
 	// Escrow balance querier
 	escrowQuerier *sidecar.EscrowQuerier
 
+	// Bounds RAV values by the payers' escrow balances, nil when not enforced
+	escrowCaps *escrowCaps
+
+	// Stops sessions the payers' live escrow balances no longer cover, nil when disabled
+	escrowWatch *escrowWatch
+
+	// Collects collections holding too much uncollected value, nil when not bounded
+	uncollected *uncollectedCeiling
+
+	// Holds submitted RAVs failing the soft checks for review, nil when disabled
+	quarantine *ravQuarantine
+
+	// Bounds the payments accepted beyond the validity of their RAVs, nil when
+	// no policy is configured
+	paymentPolicy *paymentPolicy
+
+	// Bounds on the RAV metadata accepted from consumers
+	maxMetadataSize int
+	strictMetadata  bool
+
+	// Escrow balances last read from chain, served while the chain RPC is down
+	escrowBalances *escrowBalances
+
 	// Pricing configuration
 	pricingConfig *sidecar.PricingConfig
 
-	// Accepted signer addresses (authorized by payers)
-	acceptedSigners map[string]bool
+	// How GRT amounts are rendered in admin responses, exports and logs
+	display *sidecar.AmountDisplay
+
+	// Validates RAVs received from consumers, holding the accepted signer
+	// addresses (authorized by payers)
+	validator *horizon.Validator
+
+	// Aggregator turning submitted receipts into RAVs, external or embedded, nil
+	// when not configured
+	aggregator receiptAggregator
+	// Receipts of sessions in receipts payment mode awaiting aggregation, every
+	// receiptAggregationInterval
+	receipts                   *receiptBuffer
+	receiptAggregationInterval time.Duration
+
+	// Admin server exposing /healthz and /readyz, nil when not configured
+	admin *sidecar.AdminServer
+	// Prometheus metrics served by the admin server on /metrics, nil without admin server
+	metrics *prometheus.Registry
+
+	// Replay protection for session-initiating RAVs
+	replayGuard *sidecar.ReplayGuard
+
+	// How long a session interrupted by a consumer crash can be resumed by
+	// re-initializing with its last RAV, disabled when negative
+	sessionResumeGrace time.Duration
+
+	// Sessions started without an initial RAV are rejected when requireInitialRAV
+	// is set and trustWindow nil, bounded by trustWindow until their first RAV otherwise
+	requireInitialRAV bool
+	trustWindow       *TrustWindow
+	// Zero-value initial RAVs older than bootstrapRAVMaxAge are rejected, any
+	// age is accepted when zero
+	bootstrapRAVMaxAge time.Duration
+
+	// On-chain collection of final RAVs, ravCollector is nil when not configured
+	ravCollector *sidecar.RAVCollector
+	paymentTypes *sidecar.PaymentTypes
+	collections  *collections
+	gasSpend     *gasSpend
+
+	// Automatic retry of failed collections and dead-letter queue, nil when disabled
+	collectRetries *collectRetries
+
+	// Ended sessions whose final RAV is collected automatically, nil when disabled
+	autoCollect chan *sidecar.Session
+
+	// Handling time of ValidatePayment and ReportUsage calls
+	latency *requestLatency
+
+	// Time consumers take to return signed RAVs, streaming stops once a RAV is
+	// overdue by more than maxRAVTurnaround (not enforced when zero)
+	ravTurnaround    *ravTurnaround
+	maxRAVTurnaround time.Duration
+
+	// When sessions request a RAV from their consumer
+	ravRequests *ravRequests
+
+	// Open StreamUsage streams, final RAV requests are pushed to them on shutdown
+	streams *usageStreams
+	// Set once shutting down, new sessions are then rejected
+	draining atomic.Bool
+	// How long shutdown waits for the final RAVs of active sessions, none are
+	// requested when zero, and whether their RAVs are then collected on-chain
+	shutdownRAVTimeout time.Duration
+	collectOnShutdown  bool
+
+	// Cuts projected on the payout of ended sessions in their settlement
+	settlementCuts settlementCuts
+
+	// Simulated collection of active collections' RAVs, nil when disabled
+	redeemability              *redeemability
+	redeemabilityCheckInterval time.Duration
+
+	// Automatic acceptance of pending provision parameters, nil when disabled
+	provisionAcceptor      *sidecar.ProvisionAcceptor
+	provisionCheckInterval time.Duration
+
+	// Signs consumer identity challenges, nil when identity proofs are disabled
+	identityKey *eth.PrivateKey
 }
 
+// SessionStore is the pluggable store provider sessions are persisted to, see
+// Config.SessionStore. It is shared with the consumer sidecar, which persists
+// its sessions the same way, sidecar.BoltSessionStore being the default
+// implementation.
+type SessionStore = sidecar.SessionStore
+
 type Config struct {
 	ListenAddr      string
 	ServiceProvider eth.Address
@@ -58,25 +176,258 @@ type Config struct {
 	RPCEndpoint     string
 	PricingConfig   *sidecar.PricingConfig
 	AcceptedSigners []eth.Address
+
+	// AggregatorURL is the JSON-RPC endpoint of an external TAP aggregator, when
+	// set consumers can submit receipts instead of RAVs
+	AggregatorURL string
+	// AggregatorAuthToken is sent as a bearer token to the external aggregator
+	AggregatorAuthToken string
+	// AggregatorKey runs an embedded aggregator signing RAVs with this key
+	// instead of the external aggregator. Its address is added to the accepted
+	// signers, the payer must authorize it as a signer too for the aggregated
+	// RAVs to be collectable, trusting the service provider to aggregate only
+	// the receipts it received.
+	AggregatorKey *eth.PrivateKey
+	// ReceiptAggregationInterval is how often the receipts submitted for
+	// sessions in receipts payment mode (StartSession) are aggregated into the
+	// session RAV, DefaultReceiptAggregationInterval is used when zero. Such
+	// sessions require AggregatorURL or AggregatorKey.
+	ReceiptAggregationInterval time.Duration
+
+	// MaxRAVTurnaround stops streaming (ReportUsage) for sessions whose consumer
+	// has not returned a signed RAV that long after usage not covered by its
+	// last RAV was reported, not enforced when zero. Turnaround times are
+	// tracked per payer regardless, see PayerRAVTurnaround.
+	MaxRAVTurnaround time.Duration
+
+	// RAVRequestThresholds throttles the RAVs requested from consumers
+	// (rav_requested on ReportUsage) to sessions whose usage not covered by
+	// their RAV reaches a threshold, a RAV is requested as soon as there is
+	// such usage when nil
+	RAVRequestThresholds *RAVRequestThresholds
+
+	// ShutdownRAVTimeout is how long shutdown waits for the final RAVs
+	// requested from active sessions before the sidecar stops serving, new
+	// sessions being rejected meanwhile. DefaultShutdownRAVTimeout is used when
+	// zero, no final RAV is requested when negative. Active sessions are
+	// persisted either way.
+	ShutdownRAVTimeout time.Duration
+	// CollectOnShutdown collects the current RAV of each active session
+	// on-chain once the final RAVs were received on shutdown, within
+	// ShutdownCollectTimeout. It requires RPCEndpoint, DataServiceAddr,
+	// CollectorAddr and CollectKey.
+	CollectOnShutdown bool
+
+	// AdminListenAddr is the address of the admin server serving /healthz and
+	// /readyz, disabled when empty
+	AdminListenAddr string
+	// DataServiceAddr is the data service contract the service provider must be
+	// registered with to be ready, registration is not checked when nil
+	DataServiceAddr eth.Address
+
+	// ReplayWindow is how long a session-initiating RAV cannot open another
+	// session, sidecar.DefaultReplayWindow is used when zero
+	ReplayWindow time.Duration
+
+	// SessionResumeGrace is how long after its last activity a session can be
+	// resumed by a consumer re-initializing (StartSession) with the session's
+	// last RAV, e.g. after a crash, carrying over usage not yet covered by a
+	// RAV. Active sessions and sessions ended by a client disconnect or an error
+	// can be resumed. DefaultSessionResumeGrace is used when zero, resumption
+	// is disabled when negative.
+	SessionResumeGrace time.Duration
+
+	// RequireInitialRAV rejects sessions started without an initial RAV (RAV0),
+	// unless TrustWindow is set
+	RequireInitialRAV bool
+	// TrustWindow lets sessions start without an initial RAV, serving them up to
+	// the window before their first signed RAV is required. Such a session is
+	// ended as soon as its usage goes beyond the window without a RAV. Sessions
+	// without an initial RAV are not bounded when nil.
+	TrustWindow *TrustWindow
+	// BootstrapRAVMaxAge rejects zero-value initial RAVs (RAV0) timestamped
+	// longer ago than this, they commit the payer to nothing and a stale one is
+	// more likely replayed than sent by a consumer starting a session. Any age
+	// is accepted when zero. Whatever its age, the first RAV accepted for a
+	// session binds it to its collection and parties.
+	BootstrapRAVMaxAge time.Duration
+
+	// CollectKey signs SubstreamsDataService.collect transactions for final
+	// RAVs collected through the admin API or AutoCollect, it must be the
+	// service provider or one of its authorized operators. Only dry runs are
+	// possible when nil.
+	CollectKey *eth.PrivateKey
+	// DataServiceCut is the share of collected tokens requested for the data service
+	DataServiceCut horizon.PPM
+	// ProtocolPaymentCut and DelegationFeeCut are the GraphPayments protocol
+	// payment cut and the service provider delegation fee cut, only used to
+	// project the payout of ended sessions in their settlement
+	ProtocolPaymentCut horizon.PPM
+	DelegationFeeCut   horizon.PPM
+	// PaymentTypes selects the payment type RAVs are collected under per
+	// collection, query fees for all when nil
+	PaymentTypes *sidecar.PaymentTypes
+	// CollectRetry retries failed collections of final RAVs automatically,
+	// moving those that keep failing to a dead-letter queue listed on the admin
+	// server ('/v1/collections/dead-letter'), disabled when nil. It requires
+	// AdminListenAddr and CollectKey.
+	CollectRetry *CollectRetryPolicy
+	// AutoCollect collects the final RAV of each session on-chain as soon as it
+	// ends, instead of waiting for the admin API to trigger it. Failures are
+	// retried according to CollectRetry when set, left pending otherwise. It
+	// requires RPCEndpoint, DataServiceAddr, CollectorAddr and CollectKey.
+	AutoCollect bool
+
+	// RedeemabilityCheckInterval is how often collecting the current RAV of each
+	// active collection is simulated, the outcome is exported on the admin server
+	// as the sds_provider_collection_redeemable metric ('/metrics') and through
+	// '/v1/collections/redeemability'. Disabled when zero, it requires
+	// AdminListenAddr, RPCEndpoint and DataServiceAddr.
+	RedeemabilityCheckInterval time.Duration
+
+	// ProvisionBounds enables the automatic acceptance of pending provision
+	// parameters staged for the service provider provision, as long as they fall
+	// within these bounds, disabled when nil. Acceptance transactions are signed
+	// by CollectKey, it requires RPCEndpoint, DataServiceAddr and StakingAddr.
+	ProvisionBounds *sidecar.ProvisionBounds
+	// StakingAddr is the HorizonStaking contract holding the provision
+	StakingAddr eth.Address
+	// ProvisionCheckInterval is how often the provision is checked for pending
+	// parameters, DefaultProvisionCheckInterval is used when zero
+	ProvisionCheckInterval time.Duration
+
+	// IdentityKey is the service provider key signing consumer identity
+	// challenges (ProveIdentity), its address must be ServiceProvider. Consumers
+	// verifying provider identities refuse to pay this provider when nil.
+	IdentityKey *eth.PrivateKey
+
+	// EnforceEscrowCap rejects RAVs whose value aggregate exceeds the payer's
+	// escrow balance plus EscrowCapTolerance, balances are snapshotted from chain
+	// every EscrowCapRefresh (DefaultEscrowCapRefresh when zero). It requires
+	// RPCEndpoint and EscrowAddr.
+	EnforceEscrowCap   bool
+	EscrowCapTolerance *big.Int
+	EscrowCapRefresh   time.Duration
+
+	// MaxUncollectedValue bounds, per collection, the value promised by
+	// accepted RAVs but not collected on-chain yet. A collection reaching it has
+	// its current RAV collected before its sessions are allowed more usage, they
+	// are told to stop when the collection fails or no CollectKey is
+	// configured. It bounds what is lost when the payer's escrow is drained
+	// later, not bounded when nil.
+	MaxUncollectedValue *big.Int
+
+	// WatchEscrow reads the payer's escrow balance from chain when a session
+	// starts and again once older than EscrowBalanceTTL (DefaultEscrowBalanceTTL
+	// when zero) while usage is reported, ReportUsage tells the stream to stop
+	// as soon as the session owes more than the balance. It requires
+	// RPCEndpoint and EscrowAddr.
+	WatchEscrow      bool
+	EscrowBalanceTTL time.Duration
+
+	// DegradedGrace is how long cached escrow balances keep existing sessions
+	// served while the chain RPC is unreachable, counted from the last successful
+	// chain query, DefaultDegradedGrace is used when zero
+	DegradedGrace time.Duration
+
+	// AmountDisplay controls how GRT amounts are rendered in REST and admin
+	// responses and in logs, sidecar.DefaultAmountDisplay (exact wei) when nil
+	AmountDisplay *sidecar.AmountDisplay
+
+	// MaxMetadataSize bounds the metadata of RAVs received from consumers, in
+	// bytes, DefaultMaxMetadataSize is used when zero
+	MaxMetadataSize int
+	// StrictMetadata rejects RAVs whose metadata is not of a known
+	// horizon.MetadataType
+	StrictMetadata bool
+
+	// Quarantine holds RAVs submitted during a session (SubmitRAV) that pass
+	// the hard checks but fail its soft checks until an operator approves or
+	// rejects them through the admin API ('/v1/ravs/quarantine'), disabled
+	// when nil. It requires AdminListenAddr.
+	Quarantine *QuarantinePolicy
+
+	// PaymentPolicy bounds the payments accepted beyond the accepted signers
+	// and the validity of their RAVs (escrow headroom, payer debt, price per
+	// block, collections, session duration), evaluated on ValidatePayment and
+	// every ReportUsage. Violations are counted on the admin server as the
+	// sds_provider_policy_rejections_total metric. Not enforced when nil.
+	PaymentPolicy *PaymentPolicy
+
+	// StorePath is the directory where sessions, with their usage and current
+	// RAV, are persisted (sidecar.BoltSessionStore) so they survive restarts
+	// and can be resumed by session ID. Sessions are kept in memory only when
+	// empty.
+	StorePath string
+	// SessionStore persists sessions in a custom store, it takes precedence
+	// over StorePath
+	SessionStore SessionStore
+	// SessionFlushInterval is how often session changes are written to the
+	// session store, sidecar.DefaultSessionFlushInterval when zero. Changes
+	// made since the last flush are lost on a crash.
+	SessionFlushInterval time.Duration
+	// EndedSessionRetention is how long ended sessions are kept, in memory and
+	// in the session store, once their final RAV is collected or has no value.
+	// Ended sessions are kept forever when zero.
+	EndedSessionRetention time.Duration
 }
 
 func New(config *Config, logger *zap.Logger) *Sidecar {
-	signerMap := make(map[string]bool, len(config.AcceptedSigners))
-	for _, addr := range config.AcceptedSigners {
-		signerMap[addr.Pretty()] = true
-	}
-
 	var escrowQuerier *sidecar.EscrowQuerier
 	if config.RPCEndpoint != "" && config.EscrowAddr != nil {
 		escrowQuerier = sidecar.NewEscrowQuerier(config.RPCEndpoint, config.EscrowAddr)
 	}
 
+	var aggregator receiptAggregator
+	if config.AggregatorURL != "" {
+		aggregator = NewExternalAggregator(config.AggregatorURL, config.AggregatorAuthToken)
+	}
+
+	receiptAggregationInterval := config.ReceiptAggregationInterval
+	if receiptAggregationInterval <= 0 {
+		receiptAggregationInterval = DefaultReceiptAggregationInterval
+	}
+
 	pricingConfig := config.PricingConfig
 	if pricingConfig == nil {
 		pricingConfig = sidecar.DefaultPricingConfig()
 	}
 
-	return &Sidecar{
+	display := config.AmountDisplay
+	if display == nil {
+		display = sidecar.DefaultAmountDisplay()
+	}
+
+	var ravCollector *sidecar.RAVCollector
+	if config.RPCEndpoint != "" && config.DataServiceAddr != nil && config.CollectorAddr != nil {
+		ravCollector = sidecar.NewRAVCollector(config.RPCEndpoint, config.Domain.ChainID.Uint64(), config.DataServiceAddr, config.CollectorAddr, config.CollectKey, config.DataServiceCut, config.PaymentTypes, logger)
+	}
+
+	escrowBalances := newEscrowBalances(config.DegradedGrace)
+
+	maxMetadataSize := config.MaxMetadataSize
+	if maxMetadataSize <= 0 {
+		maxMetadataSize = DefaultMaxMetadataSize
+	}
+
+	sessionResumeGrace := config.SessionResumeGrace
+	if sessionResumeGrace == 0 {
+		sessionResumeGrace = DefaultSessionResumeGrace
+	}
+
+	var admin *sidecar.AdminServer
+	if config.AdminListenAddr != "" {
+		checks := []sidecar.ReadinessCheck{sidecar.ListenerReadinessCheck("grpc", config.ListenAddr)}
+		if config.RPCEndpoint != "" {
+			checks = append(checks, escrowBalances.tolerate(sidecar.ChainRPCReadinessCheck(config.RPCEndpoint), true))
+			if config.DataServiceAddr != nil {
+				checks = append(checks, escrowBalances.tolerate(sidecar.RegistrationReadinessCheck(config.RPCEndpoint, config.DataServiceAddr, config.ServiceProvider), false))
+			}
+		}
+		admin = sidecar.NewAdminServer(config.AdminListenAddr, logger, checks...)
+	}
+
+	s := &Sidecar{
 		Shutter:         shutter.New(),
 		listenAddr:      config.ListenAddr,
 		logger:          logger,
@@ -86,25 +437,170 @@ func New(config *Config, logger *zap.Logger) *Sidecar {
 		collectorAddr:   config.CollectorAddr,
 		escrowAddr:      config.EscrowAddr,
 		escrowQuerier:   escrowQuerier,
+		escrowBalances:  escrowBalances,
 		pricingConfig:   pricingConfig,
-		acceptedSigners: signerMap,
+		display:         display,
+		validator:       horizon.NewValidator(config.Domain, config.AcceptedSigners, horizon.WithEscrowCapTolerance(config.EscrowCapTolerance)),
+		aggregator:      aggregator,
+		receipts:        newReceiptBuffer(),
+		admin:           admin,
+		replayGuard:     sidecar.NewReplayGuard(config.ReplayWindow),
+		ravCollector:    ravCollector,
+		paymentTypes:    config.PaymentTypes,
+		collections:     newCollections(),
+		identityKey:     config.IdentityKey,
+
+		maxMetadataSize: maxMetadataSize,
+		strictMetadata:  config.StrictMetadata,
+
+		receiptAggregationInterval: receiptAggregationInterval,
+
+		sessionResumeGrace: sessionResumeGrace,
+		requireInitialRAV:  config.RequireInitialRAV,
+		trustWindow:        config.TrustWindow,
+		bootstrapRAVMaxAge: config.BootstrapRAVMaxAge,
+	}
+
+	// The embedded aggregator takes the place of the external one, the RAVs it
+	// signs being accepted as the payer's
+	if config.AggregatorKey != nil {
+		s.aggregator = &embeddedAggregator{domain: config.Domain, key: config.AggregatorKey, signers: s.validator.AcceptedSigners}
+		s.AddAcceptedSigner(config.AggregatorKey.PublicKey().Address())
+	}
+
+	switch {
+	case config.SessionStore != nil:
+		s.store = config.SessionStore
+	case config.StorePath != "":
+		s.store = sidecar.NewBoltSessionStore(config.StorePath)
+	}
+	if s.store != nil {
+		s.storeWriter = sidecar.NewSessionWriter(s.store)
+	}
+	s.sessionFlushInterval = config.SessionFlushInterval
+	if s.sessionFlushInterval <= 0 {
+		s.sessionFlushInterval = sidecar.DefaultSessionFlushInterval
+	}
+	s.sessionRetention = config.EndedSessionRetention
+
+	if config.EnforceEscrowCap && escrowQuerier != nil {
+		s.escrowCaps = newEscrowCaps(config.EscrowCapRefresh, s.GetEscrowBalance)
+	}
+
+	if config.WatchEscrow && escrowQuerier != nil {
+		s.escrowWatch = newEscrowWatch(config.EscrowBalanceTTL, s.GetEscrowBalance)
+	}
+
+	if config.MaxUncollectedValue != nil && config.MaxUncollectedValue.Sign() > 0 {
+		s.uncollected = newUncollectedCeiling(config.MaxUncollectedValue)
+		if ravCollector != nil && ravCollector.CanSend() {
+			s.uncollected.collect = s.collectCurrentRAV
+		}
+	}
+
+	if admin != nil {
+		s.metrics = prometheus.NewRegistry()
+		if config.Quarantine != nil {
+			s.quarantine = newRAVQuarantine(config.Quarantine)
+		}
+	}
+	s.gasSpend = newGasSpend(s.metrics)
+	if config.CollectRetry != nil && ravCollector != nil && ravCollector.CanSend() && admin != nil {
+		s.collectRetries = newCollectRetries(config.CollectRetry, s.metrics)
+	}
+	if config.AutoCollect && ravCollector != nil && ravCollector.CanSend() {
+		s.autoCollect = make(chan *sidecar.Session, autoCollectQueueSize)
+	}
+	s.latency = newRequestLatency(s.metrics)
+	if config.PaymentPolicy != nil {
+		var fetch func(ctx context.Context, payer eth.Address) (*big.Int, error)
+		if escrowQuerier != nil {
+			fetch = s.GetEscrowBalance
+		}
+		s.paymentPolicy = newPaymentPolicy(config.PaymentPolicy, fetch, s.metrics)
+	}
+	s.ravTurnaround = newRAVTurnaround(s.metrics)
+	s.maxRAVTurnaround = config.MaxRAVTurnaround
+	s.ravRequests = newRAVRequests(config.RAVRequestThresholds, s.metrics)
+	s.streams = newUsageStreams()
+	s.shutdownRAVTimeout = config.ShutdownRAVTimeout
+	if s.shutdownRAVTimeout == 0 {
+		s.shutdownRAVTimeout = DefaultShutdownRAVTimeout
+	}
+	s.collectOnShutdown = config.CollectOnShutdown && ravCollector != nil && ravCollector.CanSend()
+	s.settlementCuts = settlementCuts{
+		protocol:    config.ProtocolPaymentCut,
+		dataService: config.DataServiceCut,
+		delegation:  config.DelegationFeeCut,
 	}
+
+	if config.RedeemabilityCheckInterval > 0 && ravCollector != nil && admin != nil {
+		s.redeemability = newRedeemability(s.metrics)
+		s.redeemabilityCheckInterval = config.RedeemabilityCheckInterval
+	}
+
+	if config.ProvisionBounds != nil && config.CollectKey != nil && config.RPCEndpoint != "" && config.DataServiceAddr != nil && config.StakingAddr != nil {
+		s.provisionAcceptor = sidecar.NewProvisionAcceptor(config.RPCEndpoint, config.Domain.ChainID.Uint64(), config.StakingAddr, config.DataServiceAddr, config.ServiceProvider, config.CollectKey, config.ProvisionBounds, logger)
+		s.provisionCheckInterval = config.ProvisionCheckInterval
+		if s.provisionCheckInterval <= 0 {
+			s.provisionCheckInterval = DefaultProvisionCheckInterval
+		}
+	}
+
+	if admin != nil {
+		s.adminHandlers(admin)
+	}
+
+	return s
 }
 
-// GetEscrowBalance queries the on-chain escrow balance for a payer
+// GetEscrowBalance queries the on-chain escrow balance for a payer. While the
+// chain RPC is unreachable, the last balance read is returned for the degraded
+// grace window (see Config.DegradedGrace).
 func (s *Sidecar) GetEscrowBalance(ctx context.Context, payer eth.Address) (*big.Int, error) {
 	if s.escrowQuerier == nil {
 		return nil, nil // No RPC configured
 	}
-	return s.escrowQuerier.GetBalance(ctx, payer, s.collectorAddr, s.serviceProvider)
+
+	balance, err := s.escrowQuerier.GetBalance(ctx, payer, s.collectorAddr, s.serviceProvider)
+	if err != nil {
+		if s.escrowBalances.unreachable(err) {
+			s.logger.Warn("chain RPC unreachable, entering degraded mode serving cached escrow balances", zap.Error(err))
+		}
+		if cached, found := s.escrowBalances.cached(payer); found {
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	if s.escrowBalances.record(payer, balance) {
+		s.logger.Info("chain RPC reachable again, leaving degraded mode")
+	}
+	return balance, nil
+}
+
+// Degraded reports whether the chain RPC is unreachable and escrow balances
+// are served from cache
+func (s *Sidecar) Degraded() bool {
+	return s.escrowBalances.degraded()
 }
 
 // AddAcceptedSigner adds a signer to the accepted list
 func (s *Sidecar) AddAcceptedSigner(addr eth.Address) {
-	s.acceptedSigners[addr.Pretty()] = true
+	s.validator.AddAcceptedSigner(addr)
 }
 
 func (s *Sidecar) Run() {
+	if err := s.restoreSessions(); err != nil {
+		s.Shutdown(err)
+		return
+	}
+	if s.store != nil {
+		s.OnTerminated(func(_ error) {
+			s.closeSessionStore()
+		})
+	}
+
 	handlerGetters := []connectrpc.HandlerGetter{
 		func(opts ...connect.HandlerOption) (string, http.Handler) {
 			return providerv1connect.NewProviderSidecarServiceHandler(s, opts...)
@@ -122,16 +618,49 @@ func (s *Sidecar) Run() {
 		server.WithConnectPermissiveCORS(),
 		server.WithConnectReflection(providerv1connect.ProviderSidecarServiceName),
 		server.WithConnectReflection(providerv1connect.PaymentGatewayServiceName),
+		server.WithConnectWebHTTPHandlers(s.restHandlers()),
 	)
 
 	s.server.OnTerminated(func(err error) {
 		s.Shutdown(err)
 	})
 
+	// Final RAVs are flushed while the server still serves, so they can be
+	// submitted
 	s.OnTerminating(func(_ error) {
+		s.drain()
 		s.server.Shutdown(nil)
 	})
 
+	if s.admin != nil {
+		s.admin.OnTerminated(func(err error) {
+			s.Shutdown(err)
+		})
+		s.OnTerminating(func(_ error) {
+			s.admin.Shutdown(nil)
+		})
+		go s.admin.Run()
+	}
+
+	if s.redeemability != nil {
+		go s.watchRedeemability(s.redeemabilityCheckInterval)
+	}
+	if s.provisionAcceptor != nil {
+		go s.watchProvision(s.provisionCheckInterval)
+	}
+	if s.collectRetries != nil {
+		go s.watchCollectRetries()
+	}
+	if s.autoCollect != nil {
+		go s.watchAutoCollect()
+	}
+	if s.aggregator != nil {
+		go s.watchReceiptAggregation(s.receiptAggregationInterval)
+	}
+	if s.store != nil || s.sessionRetention > 0 {
+		go s.watchSessionStore(s.sessionFlushInterval)
+	}
+
 	s.logger.Info("starting provider sidecar", zap.String("listen_addr", s.listenAddr))
 	s.server.Launch(s.listenAddr)
 }
@@ -144,8 +673,3 @@ func (s *Sidecar) healthCheck(ctx context.Context) (isReady bool, out interface{
 func (s *Sidecar) verifyRAVSignature(signedRAV *horizon.SignedRAV) (eth.Address, error) {
 	return signedRAV.RecoverSigner(s.domain)
 }
-
-// isAcceptedSigner checks if an address is in the accepted signers list
-func (s *Sidecar) isAcceptedSigner(addr eth.Address) bool {
-	return s.acceptedSigners[addr.Pretty()]
-}
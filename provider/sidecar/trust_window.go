@@ -0,0 +1,52 @@
+package sidecar
+
+import (
+	"fmt"
+	"math/big"
+
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+)
+
+// TrustWindow bounds the usage served to a session started without an initial
+// RAV until its first signed RAV is received. A zero Blocks or nil Value
+// leaves that dimension unbounded.
+type TrustWindow struct {
+	// Blocks is the number of blocks served before a signed RAV is required
+	Blocks uint64
+	// Value is the usage cost, in GRT wei, served before a signed RAV is required
+	Value *big.Int
+}
+
+// exceeded describes how usage goes beyond the window, empty when within it
+func (w *TrustWindow) exceeded(usage *commonv1.Usage) string {
+	if w.Blocks > 0 && usage.BlocksProcessed > w.Blocks {
+		return fmt.Sprintf("%d blocks served exceed the trust window of %d blocks", usage.BlocksProcessed, w.Blocks)
+	}
+	if w.Value != nil {
+		if cost := usage.Cost.ToNative(); cost != nil && cost.Cmp(w.Value) > 0 {
+			return fmt.Sprintf("usage cost %s exceeds the trust window of %s", cost, w.Value)
+		}
+	}
+	return ""
+}
+
+// enforceTrustWindow ends session when it is still without a RAV and its usage
+// went beyond the trust window, returning why, or an empty string when the
+// session may continue. Once ended, a late RAV cannot revive the session.
+func (s *Sidecar) enforceTrustWindow(session *sidecar.Session) string {
+	if s.trustWindow == nil {
+		return ""
+	}
+	if rav := session.GetRAV(); rav != nil && rav.Message != nil {
+		return ""
+	}
+
+	reason := s.trustWindow.exceeded(session.GetUsage())
+	if reason == "" {
+		return ""
+	}
+
+	session.End(commonv1.EndReason_END_REASON_PAYMENT_ISSUE)
+	return reason + " without a signed RAV"
+}
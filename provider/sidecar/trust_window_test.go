@@ -0,0 +1,106 @@
+package sidecar
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTrustWindow(t *testing.T) {
+	payer := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+
+	newSidecar := func(requireInitialRAV bool, window *TrustWindow) *Sidecar {
+		return New(&Config{
+			ServiceProvider:   serviceProvider,
+			Domain:            horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+			RequireInitialRAV: requireInitialRAV,
+			TrustWindow:       window,
+		}, zap.NewNop())
+	}
+
+	start := func(s *Sidecar) *providerv1.StartSessionResponse {
+		resp, err := s.StartSession(context.Background(), connect.NewRequest(&providerv1.StartSessionRequest{
+			EscrowAccount: &commonv1.EscrowAccount{
+				Payer:       commonv1.AddressFromEth(payer),
+				Receiver:    commonv1.AddressFromEth(serviceProvider),
+				DataService: commonv1.AddressFromEth(dataService),
+			},
+		}))
+		require.NoError(t, err)
+		return resp.Msg
+	}
+
+	report := func(s *Sidecar, sessionID string, blocks uint64, cost int64) *providerv1.ReportUsageResponse {
+		resp, err := s.ReportUsage(context.Background(), connect.NewRequest(&providerv1.ReportUsageRequest{
+			SessionId: sessionID,
+			Usage:     &commonv1.Usage{BlocksProcessed: blocks, Cost: commonv1.BigIntFromNative(big.NewInt(cost))},
+		}))
+		require.NoError(t, err)
+		return resp.Msg
+	}
+
+	t.Run("initial RAV required", func(t *testing.T) {
+		resp := start(newSidecar(true, nil))
+		assert.False(t, resp.Accepted)
+		assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_INVALID_RAV, resp.RejectionCode)
+	})
+
+	t.Run("no trust window", func(t *testing.T) {
+		s := newSidecar(false, nil)
+		resp := start(s)
+		require.True(t, resp.Accepted)
+		assert.True(t, report(s, resp.SessionId, 1_000_000, 1_000_000).ShouldContinue)
+	})
+
+	t.Run("blocks window exceeded", func(t *testing.T) {
+		s := newSidecar(true, &TrustWindow{Blocks: 100})
+		resp := start(s)
+		require.True(t, resp.Accepted)
+
+		assert.True(t, report(s, resp.SessionId, 60, 0).ShouldContinue)
+		assert.True(t, report(s, resp.SessionId, 40, 0).ShouldContinue)
+
+		stopped := report(s, resp.SessionId, 1, 0)
+		assert.False(t, stopped.ShouldContinue)
+		assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_INVALID_RAV, stopped.StopCode)
+
+		session, err := s.sessions.Get(resp.SessionId)
+		require.NoError(t, err)
+		assert.False(t, session.IsActive())
+		assert.Equal(t, commonv1.EndReason_END_REASON_PAYMENT_ISSUE, session.EndReason)
+	})
+
+	t.Run("value window exceeded", func(t *testing.T) {
+		s := newSidecar(false, &TrustWindow{Value: big.NewInt(500)})
+		resp := start(s)
+		require.True(t, resp.Accepted)
+
+		assert.True(t, report(s, resp.SessionId, 1_000, 500).ShouldContinue)
+		assert.False(t, report(s, resp.SessionId, 1, 1).ShouldContinue)
+	})
+
+	t.Run("RAV received within window", func(t *testing.T) {
+		s := newSidecar(true, &TrustWindow{Blocks: 100})
+		resp := start(s)
+		require.True(t, resp.Accepted)
+
+		assert.True(t, report(s, resp.SessionId, 50, 0).ShouldContinue)
+
+		session, err := s.sessions.Get(resp.SessionId)
+		require.NoError(t, err)
+		session.SetRAV(&horizon.SignedRAV{Message: &horizon.RAV{Payer: payer, ValueAggregate: big.NewInt(0)}})
+
+		assert.True(t, report(s, resp.SessionId, 1_000, 0).ShouldContinue)
+	})
+}
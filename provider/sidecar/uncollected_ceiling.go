@@ -0,0 +1,154 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"go.uber.org/zap"
+)
+
+// errUncollectedCeiling stops sessions whose collection reached the uncollected
+// value ceiling when it cannot be collected on-chain
+var errUncollectedCeiling = errors.New("uncollected value of collection reached its ceiling")
+
+// uncollectedCeiling bounds the value of each collection promised by accepted
+// RAVs but not collected on-chain yet. A collection reaching the ceiling has
+// its current RAV collected before its sessions are allowed more usage, so a
+// payer draining its escrow later can only cost the provider up to the ceiling
+// per collection.
+type uncollectedCeiling struct {
+	ceiling *big.Int
+
+	mu        sync.Mutex
+	collected map[horizon.CollectionID]*big.Int
+
+	// collect collects rav on-chain and returns the value now collected for its
+	// collection, nil when no collect key is configured
+	collect func(ctx context.Context, rav *horizon.SignedRAV) (*big.Int, error)
+}
+
+func newUncollectedCeiling(ceiling *big.Int) *uncollectedCeiling {
+	return &uncollectedCeiling{
+		ceiling:   ceiling,
+		collected: make(map[horizon.CollectionID]*big.Int),
+	}
+}
+
+// uncollected returns the value of rav not collected yet for its collection
+func (c *uncollectedCeiling) uncollected(rav *horizon.SignedRAV) *big.Int {
+	value := ravValue(rav)
+	if value.Sign() <= 0 {
+		return new(big.Int)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if collected := c.collected[rav.Message.CollectionID]; collected != nil {
+		return new(big.Int).Sub(value, collected)
+	}
+	return new(big.Int).Set(value)
+}
+
+// reached returns true when the uncollected value of rav is at or above the ceiling
+func (c *uncollectedCeiling) reached(rav *horizon.SignedRAV) bool {
+	return c.uncollected(rav).Cmp(c.ceiling) >= 0
+}
+
+// record notes that value was collected for collectionID, RAV values being
+// cumulative only the highest value collected is kept
+func (c *uncollectedCeiling) record(collectionID horizon.CollectionID, value *big.Int) {
+	if value == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if collected := c.collected[collectionID]; collected == nil || value.Cmp(collected) > 0 {
+		c.collected[collectionID] = new(big.Int).Set(value)
+	}
+}
+
+// collectedValue returns the value recorded as collected for collectionID,
+// nil when none
+func (c *uncollectedCeiling) collectedValue(collectionID horizon.CollectionID) *big.Int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if collected := c.collected[collectionID]; collected != nil {
+		return new(big.Int).Set(collected)
+	}
+	return nil
+}
+
+// collectedMidSession returns the value of collectionID collected on-chain while
+// its sessions are served, nil when none or no ceiling is configured. The
+// escrow no longer backs that value, escrow checks only bound the rest.
+func (s *Sidecar) collectedMidSession(collectionID horizon.CollectionID) *big.Int {
+	if s.uncollected == nil {
+		return nil
+	}
+	return s.uncollected.collectedValue(collectionID)
+}
+
+// checkUncollectedValue collects the current RAV of session when the value of
+// its collection not collected yet reached the ceiling. It fails when the
+// collection is not possible or fails, the session must then stop. It always
+// passes when no ceiling is configured.
+func (s *Sidecar) checkUncollectedValue(ctx context.Context, session *sidecar.Session) error {
+	if s.uncollected == nil {
+		return nil
+	}
+
+	rav := session.GetRAV()
+	if !s.uncollected.reached(rav) {
+		return nil
+	}
+	if s.uncollected.collect == nil {
+		return fmt.Errorf("%w: %s uncollected, no collect key configured", errUncollectedCeiling, s.display.FormatWithUnit(s.uncollected.uncollected(rav)))
+	}
+
+	// Sessions of the collection reporting usage meanwhile wait for this
+	// collection, then see what it collected
+	unlock := s.collections.lockCollection(rav.Message.CollectionID)
+	defer unlock()
+	if !s.uncollected.reached(rav) {
+		return nil
+	}
+
+	s.logger.Info("collection reached its uncollected value ceiling, collecting current RAV",
+		zap.String("session_id", session.ID),
+		s.display.Field("uncollected", s.uncollected.uncollected(rav)),
+	)
+	collected, err := s.uncollected.collect(ctx, rav)
+	if err != nil {
+		return fmt.Errorf("%w: collecting current RAV: %w", errUncollectedCeiling, err)
+	}
+	s.uncollected.record(rav.Message.CollectionID, collected)
+	return nil
+}
+
+// collectCurrentRAV collects rav, the current RAV of an active session, and
+// returns the value collected for its collection
+func (s *Sidecar) collectCurrentRAV(ctx context.Context, rav *horizon.SignedRAV) (*big.Int, error) {
+	receipt, estimate, err := s.ravCollector.Collect(ctx, rav)
+	if receipt != nil {
+		s.gasSpend.record(rav.Message, receipt, estimate.TokensDelta, err == nil, time.Now())
+	}
+	if errors.Is(err, sidecar.ErrNothingToCollect) {
+		return estimate.AlreadyCollected, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("collected current RAV", zap.String("tx_hash", receipt.TxHash), s.display.Field("tokens_delta", estimate.TokensDelta))
+	return rav.Message.ValueAggregate, nil
+}
@@ -0,0 +1,180 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	providerv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/provider/v1"
+	"github.com/graphprotocol/substreams-data-service/sidecar"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestUncollectedValueCeiling(t *testing.T) {
+	payer := eth.MustNewAddress("0x4444444444444444444444444444444444444444")
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+
+	s := New(&Config{
+		ServiceProvider:     serviceProvider,
+		Domain:              horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+		MaxUncollectedValue: big.NewInt(1000),
+	}, zap.NewNop())
+
+	session := s.sessions.Create(payer, serviceProvider, dataService)
+	setRAV := func(value int64) {
+		session.SetRAV(&horizon.SignedRAV{Message: &horizon.RAV{
+			CollectionID:    horizon.CollectionID{0x01},
+			Payer:           payer,
+			ServiceProvider: serviceProvider,
+			DataService:     dataService,
+			ValueAggregate:  big.NewInt(value),
+		}})
+	}
+	report := func() *providerv1.ReportUsageResponse {
+		resp, err := s.ReportUsage(context.Background(), connect.NewRequest(&providerv1.ReportUsageRequest{
+			SessionId: session.ID,
+			Usage:     &commonv1.Usage{BlocksProcessed: 1, Cost: commonv1.BigIntFromNative(big.NewInt(1))},
+		}))
+		require.NoError(t, err)
+		return resp.Msg
+	}
+
+	setRAV(999)
+	assert.True(t, report().ShouldContinue)
+
+	// Without a collect key the session stops at the ceiling
+	setRAV(1000)
+	resp := report()
+	assert.False(t, resp.ShouldContinue)
+	assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_INSUFFICIENT_FUNDS, resp.StopCode)
+	assert.Contains(t, resp.StopReason, errUncollectedCeiling.Error())
+
+	var collected []int64
+	var collectErr error
+	s.uncollected.collect = func(ctx context.Context, rav *horizon.SignedRAV) (*big.Int, error) {
+		if collectErr != nil {
+			return nil, collectErr
+		}
+		collected = append(collected, rav.Message.ValueAggregate.Int64())
+		return rav.Message.ValueAggregate, nil
+	}
+
+	// The current RAV is collected before more usage is accepted, only the
+	// value promised since counts towards the ceiling then
+	assert.True(t, report().ShouldContinue)
+	assert.True(t, report().ShouldContinue)
+	setRAV(1999)
+	assert.True(t, report().ShouldContinue)
+	assert.Equal(t, []int64{1000}, collected)
+
+	setRAV(2000)
+	collectErr = errors.New("rpc unavailable")
+	resp = report()
+	assert.False(t, resp.ShouldContinue)
+	assert.Contains(t, resp.StopReason, "rpc unavailable")
+
+	collectErr = nil
+	assert.True(t, report().ShouldContinue)
+	assert.Equal(t, []int64{1000, 2000}, collected)
+
+	// Final RAVs collected on session end count too
+	s.uncollected.record(horizon.CollectionID{0x01}, big.NewInt(2500))
+	setRAV(3400)
+	assert.True(t, report().ShouldContinue)
+	assert.Equal(t, []int64{1000, 2000}, collected)
+}
+
+func TestUncollectedValueCeiling_EscrowChecks(t *testing.T) {
+	signerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	payer := signerKey.PublicKey().Address()
+	serviceProvider := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	domain := horizon.NewDomain(1337, eth.MustNewAddress("0x1111111111111111111111111111111111111111"))
+
+	s := New(&Config{
+		ServiceProvider:     serviceProvider,
+		Domain:              domain,
+		AcceptedSigners:     []eth.Address{payer},
+		MaxUncollectedValue: big.NewInt(1000),
+	}, zap.NewNop())
+
+	// Collecting pays out of the escrow, the balance is read again on every
+	// check
+	balance := big.NewInt(1500)
+	fetch := func(ctx context.Context, p eth.Address) (*big.Int, error) {
+		return new(big.Int).Set(balance), nil
+	}
+	s.escrowCaps = newEscrowCaps(time.Nanosecond, fetch)
+	s.escrowWatch = newEscrowWatch(time.Nanosecond, fetch)
+
+	var collected []int64
+	s.uncollected.collect = func(ctx context.Context, rav *horizon.SignedRAV) (*big.Int, error) {
+		collected = append(collected, rav.Message.ValueAggregate.Int64())
+		balance.Sub(balance, rav.Message.ValueAggregate)
+		return rav.Message.ValueAggregate, nil
+	}
+
+	session := s.sessions.Create(payer, serviceProvider, dataService)
+	collectionID := horizon.CollectionID{0x01}
+	timestamp := uint64(1000)
+	// Proto RAVs carry the collection ID as the first 32 bytes of the metadata
+	submit := func(value int64) *providerv1.SubmitRAVResponse {
+		timestamp++
+		signed, err := horizon.Sign(domain, &horizon.RAV{
+			CollectionID:    collectionID,
+			Payer:           payer,
+			ServiceProvider: serviceProvider,
+			DataService:     dataService,
+			TimestampNs:     timestamp,
+			ValueAggregate:  big.NewInt(value),
+			Metadata:        collectionID[:],
+		}, signerKey)
+		require.NoError(t, err)
+
+		resp, err := s.SubmitRAV(context.Background(), connect.NewRequest(&providerv1.SubmitRAVRequest{
+			SessionId: session.ID,
+			SignedRav: sidecar.HorizonSignedRAVToProto(signed),
+		}))
+		require.NoError(t, err)
+		return resp.Msg
+	}
+	report := func() *providerv1.ReportUsageResponse {
+		resp, err := s.ReportUsage(context.Background(), connect.NewRequest(&providerv1.ReportUsageRequest{
+			SessionId: session.ID,
+			Usage:     &commonv1.Usage{BlocksProcessed: 1, Cost: commonv1.BigIntFromNative(big.NewInt(1))},
+		}))
+		require.NoError(t, err)
+		return resp.Msg
+	}
+
+	resp := submit(1000)
+	require.True(t, resp.Accepted, resp.RejectionReason)
+	require.True(t, report().ShouldContinue)
+	assert.Equal(t, []int64{1000}, collected)
+	assert.Equal(t, "500", balance.String())
+
+	// The collected value is no longer owed, the escrow watch keeps the session
+	// going on what is left in escrow
+	got := report()
+	assert.True(t, got.ShouldContinue, got.StopReason)
+
+	// The escrow cap bounds the value promised since the collection only
+	resp = submit(1400)
+	assert.True(t, resp.Accepted, resp.RejectionReason)
+	assert.True(t, report().ShouldContinue)
+
+	resp = submit(1501)
+	assert.False(t, resp.Accepted)
+	assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_EXCEEDS_ESCROW, resp.RejectionCode)
+}
@@ -0,0 +1,115 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/streamingfast/eth-go"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnknownAddressName is returned when a value is neither an address nor a
+// name known to the address book or, for ENS names, to ENS
+var ErrUnknownAddressName = errors.New("unknown address name")
+
+// AddressBook maps names to addresses so that parties can be referenced by
+// name in CLI arguments and configuration files. Names are case insensitive.
+type AddressBook map[string]eth.Address
+
+// LoadAddressBook loads an address book from a YAML file mapping names to
+// addresses:
+//
+//	payer: "0xe90874856c339d5d3733c92ca5acadb2b2c36a1f"
+//	indexer-1: "0xa6f1845e54b1d6a95319251f1ca775b4ad406cdf"
+func LoadAddressBook(path string) (AddressBook, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading address book: %w", err)
+	}
+
+	return ParseAddressBook(data)
+}
+
+// ParseAddressBook parses an address book from YAML bytes, see LoadAddressBook
+func ParseAddressBook(data []byte) (AddressBook, error) {
+	var raw map[string]string
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing address book: %w", err)
+	}
+
+	out := make(AddressBook, len(raw))
+	for name, addressHex := range raw {
+		key := normalizeAddressName(name)
+		if key == "" {
+			return nil, fmt.Errorf("invalid address book entry %q: empty name", name)
+		}
+		if _, err := eth.NewAddress(key); err == nil {
+			return nil, fmt.Errorf("invalid address book entry %q: names cannot be addresses", name)
+		}
+		if _, found := out[key]; found {
+			return nil, fmt.Errorf("invalid address book entry %q: duplicated name", name)
+		}
+
+		address, err := eth.NewAddress(addressHex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address book entry %q: %w", name, err)
+		}
+		out[key] = address
+	}
+	return out, nil
+}
+
+// Lookup returns the address of name, false when the book does not list it
+func (b AddressBook) Lookup(name string) (eth.Address, bool) {
+	address, found := b[normalizeAddressName(name)]
+	return address, found
+}
+
+func normalizeAddressName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// AddressResolver turns user supplied values into addresses before they are
+// used to build messages or transactions. A value is, in order, a hex
+// address, a name of the address book or, when an ENS resolver is configured,
+// an ENS name ending in ".eth". A nil AddressResolver only accepts hex
+// addresses.
+type AddressResolver struct {
+	book AddressBook
+	ens  *ENSResolver
+}
+
+// NewAddressResolver creates an AddressResolver, book and ens are both optional
+func NewAddressResolver(book AddressBook, ens *ENSResolver) *AddressResolver {
+	return &AddressResolver{book: book, ens: ens}
+}
+
+// Resolve returns the address value refers to
+func (r *AddressResolver) Resolve(ctx context.Context, value string) (eth.Address, error) {
+	value = strings.TrimSpace(value)
+
+	address, err := eth.NewAddress(value)
+	if err == nil {
+		return address, nil
+	}
+	if r == nil || strings.HasPrefix(value, "0x") {
+		return nil, err
+	}
+
+	if address, found := r.book.Lookup(value); found {
+		return address, nil
+	}
+
+	if r.ens != nil && strings.HasSuffix(strings.ToLower(value), ".eth") {
+		address, err := r.ens.Resolve(ctx, value)
+		if err != nil {
+			return nil, fmt.Errorf("resolving ENS name %q: %w", value, err)
+		}
+		return address, nil
+	}
+
+	return nil, fmt.Errorf("%w %q, expected an address or a name of the address book", ErrUnknownAddressName, value)
+}
@@ -0,0 +1,120 @@
+package sidecar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAddressBook(t *testing.T) {
+	book, err := ParseAddressBook([]byte(`
+Payer: "0xE90874856C339D5D3733C92CA5ACADB2B2C36A1F"
+indexer-1: "0xa6f1845e54b1d6a95319251f1ca775b4ad406cdf"
+`))
+	require.NoError(t, err)
+	require.Len(t, book, 2)
+
+	address, found := book.Lookup("payer")
+	require.True(t, found)
+	assert.Equal(t, "0xe90874856c339d5d3733c92ca5acadb2b2c36a1f", address.Pretty())
+
+	_, found = book.Lookup("indexer-2")
+	assert.False(t, found)
+
+	_, err = ParseAddressBook([]byte(`payer: "not-an-address"`))
+	assert.ErrorContains(t, err, `invalid address book entry "payer"`)
+
+	_, err = ParseAddressBook([]byte(`"0xa6f1845e54b1d6a95319251f1ca775b4ad406cdf": "0xa6f1845e54b1d6a95319251f1ca775b4ad406cdf"`))
+	assert.ErrorContains(t, err, "names cannot be addresses")
+
+	_, err = ParseAddressBook([]byte("payer: \"0xa6f1845e54b1d6a95319251f1ca775b4ad406cdf\"\nPAYER: \"0xa6f1845e54b1d6a95319251f1ca775b4ad406cdf\""))
+	assert.ErrorContains(t, err, "duplicated name")
+}
+
+func TestENSNamehash(t *testing.T) {
+	assert.Equal(t, "0000000000000000000000000000000000000000000000000000000000000000", ENSNamehash("").String())
+	assert.Equal(t, "93cdeb708b7545dc668eb9280176169d1c33cfd8ed6f04690a0bcc88a93fc4ae", ENSNamehash("eth").String())
+	assert.Equal(t, "de9b09fd7c5f901e23a3f19fecc54828e9c848539801e86591bd9801b019f84f", ENSNamehash("Foo.eth").String())
+}
+
+func TestAddressResolver(t *testing.T) {
+	resolverAddr := eth.MustNewAddress("0x4976fb03c32e5b8cfe2b6ccb31c09ba78ebaba41")
+	vitalik := eth.MustNewAddress("0xd8da6bf26964af9d7eed9e03e53415d37aa96045")
+	chainID := "0x1"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		result := `"0x"`
+		switch req.Method {
+		case "eth_chainId":
+			result = fmt.Sprintf("%q", chainID)
+		case "eth_call":
+			var call struct {
+				To   string `json:"to"`
+				Data string `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(req.Params[0], &call))
+			node := fmt.Sprintf("%x", []byte(ENSNamehash("vitalik.eth")))
+			switch {
+			case call.To == ENSRegistryAddress.Pretty() && call.Data == fmt.Sprintf("0x%x%s", ENSResolverMethod.MethodID(), node):
+				result = fmt.Sprintf(`"0x%064x"`, []byte(resolverAddr))
+			case call.To == ENSRegistryAddress.Pretty():
+				result = fmt.Sprintf(`"0x%064x"`, 0)
+			case call.To == resolverAddr.Pretty() && call.Data == fmt.Sprintf("0x%x%s", ENSAddrMethod.MethodID(), node):
+				result = fmt.Sprintf(`"0x%064x"`, []byte(vitalik))
+			}
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%s}`, req.ID, result)
+	}))
+	defer server.Close()
+
+	book, err := ParseAddressBook([]byte(`payer: "0xe90874856c339d5d3733c92ca5acadb2b2c36a1f"`))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	resolver := NewAddressResolver(book, NewENSResolver(server.URL))
+
+	address, err := resolver.Resolve(ctx, "0xA6F1845E54B1D6A95319251F1CA775B4AD406CDF")
+	require.NoError(t, err)
+	assert.Equal(t, "0xa6f1845e54b1d6a95319251f1ca775b4ad406cdf", address.Pretty())
+
+	address, err = resolver.Resolve(ctx, " Payer ")
+	require.NoError(t, err)
+	assert.Equal(t, "0xe90874856c339d5d3733c92ca5acadb2b2c36a1f", address.Pretty())
+
+	address, err = resolver.Resolve(ctx, "vitalik.eth")
+	require.NoError(t, err)
+	assert.Equal(t, vitalik.Pretty(), address.Pretty())
+
+	_, err = resolver.Resolve(ctx, "nobody.eth")
+	assert.ErrorIs(t, err, ErrUnknownAddressName)
+
+	_, err = resolver.Resolve(ctx, "indexer-2")
+	assert.ErrorIs(t, err, ErrUnknownAddressName)
+
+	_, err = resolver.Resolve(ctx, "0x1234")
+	assert.Error(t, err)
+
+	// Without resolver, only addresses are accepted
+	var none *AddressResolver
+	_, err = none.Resolve(ctx, "payer")
+	assert.Error(t, err)
+
+	// ENS is refused on chains without an ENS deployment
+	chainID = "0x539"
+	_, err = NewAddressResolver(nil, NewENSResolver(server.URL)).Resolve(ctx, "vitalik.eth")
+	assert.ErrorIs(t, err, ErrENSUnsupportedChain)
+}
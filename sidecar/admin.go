@@ -0,0 +1,236 @@
+package sidecar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/streamingfast/eth-go/rpc"
+	"github.com/streamingfast/shutter"
+	"go.uber.org/zap"
+)
+
+// DefaultReadinessCheckTimeout bounds the time a single readiness check may take
+const DefaultReadinessCheckTimeout = 2 * time.Second
+
+// ErrDegraded is wrapped by readiness check errors reporting a dependency
+// failure the sidecar currently tolerates: the check is reported and the
+// response flagged as degraded, but the sidecar stays ready
+var ErrDegraded = errors.New("degraded")
+
+// ReadinessCheck is a named dependency check run on every /readyz request, the
+// sidecar is ready only when all checks return a nil error
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// AdminServer serves the liveness (/healthz) and readiness (/readyz) endpoints,
+// along with operator endpoints registered through Handle, on a dedicated port
+// so orchestrators can gate rollouts without reaching the public gRPC port
+type AdminServer struct {
+	*shutter.Shutter
+
+	listenAddr string
+	logger     *zap.Logger
+	checks     []ReadinessCheck
+	mux        *http.ServeMux
+	server     *http.Server
+
+	terminating atomic.Bool
+}
+
+// NewAdminServer creates an admin server listening on listenAddr
+func NewAdminServer(listenAddr string, logger *zap.Logger, checks ...ReadinessCheck) *AdminServer {
+	s := &AdminServer{
+		Shutter:    shutter.New(),
+		listenAddr: listenAddr,
+		logger:     logger,
+		checks:     checks,
+		mux:        http.NewServeMux(),
+	}
+	s.mux.HandleFunc("GET /healthz", s.handleHealthz)
+	s.mux.HandleFunc("GET /readyz", s.handleReadyz)
+
+	s.server = &http.Server{
+		Addr:              listenAddr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	s.OnTerminating(func(_ error) {
+		s.terminating.Store(true)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.server.Shutdown(ctx)
+	})
+
+	return s
+}
+
+// Handler returns the HTTP handler serving /healthz, /readyz and the handlers
+// registered through Handle
+func (s *AdminServer) Handler() http.Handler {
+	return s.mux
+}
+
+// Handle registers an additional admin endpoint, pattern follows http.ServeMux
+// syntax. Handlers must be registered before Run is called.
+func (s *AdminServer) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Run starts serving and blocks until the server stops
+func (s *AdminServer) Run() {
+	s.logger.Info("starting admin server", zap.String("listen_addr", s.listenAddr))
+
+	err := s.server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+	s.Shutdown(err)
+}
+
+// handleHealthz reports liveness, the process is alive as long as it answers
+func (s *AdminServer) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok\n"))
+}
+
+type readinessResponse struct {
+	Ready    bool              `json:"ready"`
+	Degraded bool              `json:"degraded,omitempty"`
+	Checks   map[string]string `json:"checks"`
+}
+
+// handleReadyz runs all readiness checks concurrently and reports 503 when any
+// fails or when the sidecar is shutting down. Checks failing with ErrDegraded
+// only flag the response as degraded.
+func (s *AdminServer) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	out := readinessResponse{Ready: true, Checks: make(map[string]string, len(s.checks))}
+	if s.terminating.Load() {
+		out.Ready = false
+		out.Checks["shutdown"] = "sidecar is shutting down"
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range s.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(r.Context(), DefaultReadinessCheckTimeout)
+			defer cancel()
+
+			err := check.Check(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				out.Checks[check.Name] = "ok"
+			case errors.Is(err, ErrDegraded):
+				out.Checks[check.Name] = err.Error()
+				out.Degraded = true
+			default:
+				out.Checks[check.Name] = err.Error()
+				out.Ready = false
+			}
+		}()
+	}
+	wg.Wait()
+
+	statusCode := http.StatusOK
+	if !out.Ready {
+		statusCode = http.StatusServiceUnavailable
+		s.logger.Debug("readiness check failed", zap.Any("checks", out.Checks))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(out)
+}
+
+// ListenerReadinessCheck verifies a TCP listener accepts connections on
+// listenAddr, typically the sidecar's own gRPC address
+func ListenerReadinessCheck(name, listenAddr string) ReadinessCheck {
+	return ReadinessCheck{
+		Name: name,
+		Check: func(ctx context.Context) error {
+			host, port, err := net.SplitHostPort(listenAddr)
+			if err != nil {
+				return fmt.Errorf("invalid listen address %q: %w", listenAddr, err)
+			}
+			if host == "" || host == "0.0.0.0" || host == "::" {
+				host = "localhost"
+			}
+
+			var dialer net.Dialer
+			conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+			if err != nil {
+				return fmt.Errorf("not accepting connections: %w", err)
+			}
+			return conn.Close()
+		},
+	}
+}
+
+// ChainRPCReadinessCheck verifies the chain RPC endpoint answers
+func ChainRPCReadinessCheck(rpcEndpoint string) ReadinessCheck {
+	client := rpc.NewClient(rpcEndpoint)
+
+	return ReadinessCheck{
+		Name: "chain_rpc",
+		Check: func(ctx context.Context) error {
+			if _, err := client.ChainID(ctx); err != nil {
+				return fmt.Errorf("chain RPC unreachable: %w", err)
+			}
+			return nil
+		},
+	}
+}
+
+var isRegisteredMethod = eth.MustNewMethodDef("isRegistered(address)")
+
+// RegistrationReadinessCheck verifies the service provider is registered with
+// the data service contract (SubstreamsDataService.isRegistered)
+func RegistrationReadinessCheck(rpcEndpoint string, dataService, serviceProvider eth.Address) ReadinessCheck {
+	client := rpc.NewClient(rpcEndpoint)
+
+	return ReadinessCheck{
+		Name: "registration",
+		Check: func(ctx context.Context) error {
+			data, err := isRegisteredMethod.NewCall(serviceProvider).Encode()
+			if err != nil {
+				return fmt.Errorf("encoding isRegistered call: %w", err)
+			}
+
+			resultHex, err := client.Call(ctx, rpc.CallParams{To: dataService, Data: data})
+			if err != nil {
+				return fmt.Errorf("calling isRegistered: %w", err)
+			}
+
+			result, err := eth.NewHex(resultHex)
+			if err != nil {
+				return fmt.Errorf("decoding isRegistered result: %w", err)
+			}
+			if len(result) != 32 {
+				return fmt.Errorf("unexpected isRegistered result length: %d", len(result))
+			}
+			if result[31] != 1 {
+				return fmt.Errorf("service provider %s is not registered with data service %s", serviceProvider.Pretty(), dataService.Pretty())
+			}
+			return nil
+		},
+	}
+}
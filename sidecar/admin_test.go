@@ -0,0 +1,81 @@
+package sidecar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestAdminServer_Healthz(t *testing.T) {
+	s := NewAdminServer(":0", zap.NewNop())
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "ok\n", rec.Body.String())
+}
+
+func TestAdminServer_Readyz(t *testing.T) {
+	failing := errors.New("boom")
+	var checkErr error
+
+	s := NewAdminServer(":0", zap.NewNop(),
+		ReadinessCheck{Name: "always", Check: func(ctx context.Context) error { return nil }},
+		ReadinessCheck{Name: "toggled", Check: func(ctx context.Context) error { return checkErr }},
+	)
+
+	readyz := func() (int, readinessResponse) {
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		var out readinessResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+		return rec.Code, out
+	}
+
+	code, out := readyz()
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, out.Ready)
+	assert.Equal(t, map[string]string{"always": "ok", "toggled": "ok"}, out.Checks)
+
+	checkErr = failing
+	code, out = readyz()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.False(t, out.Ready)
+	assert.Equal(t, "boom", out.Checks["toggled"])
+
+	checkErr = fmt.Errorf("%w: %w", ErrDegraded, failing)
+	code, out = readyz()
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, out.Ready)
+	assert.True(t, out.Degraded)
+	assert.Equal(t, "degraded: boom", out.Checks["toggled"])
+
+	checkErr = nil
+	s.Shutdown(nil)
+	code, out = readyz()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Contains(t, out.Checks, "shutdown")
+}
+
+func TestListenerReadinessCheck(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+
+	check := ListenerReadinessCheck("grpc", addr)
+	assert.NoError(t, check.Check(context.Background()))
+
+	require.NoError(t, listener.Close())
+	assert.Error(t, check.Check(context.Background()))
+}
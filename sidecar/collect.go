@@ -0,0 +1,414 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+	"github.com/streamingfast/eth-go/rpc"
+	"github.com/streamingfast/eth-go/signer/native"
+	"go.uber.org/zap"
+)
+
+// DefaultCollectReceiptTimeout bounds how long the sidecar waits for the
+// transactions it sends (collect, provision parameters acceptance) to be mined
+const DefaultCollectReceiptTimeout = 2 * time.Minute
+
+// collectConfirmTimeout bounds how long the RAVCollected event of a mined
+// collect transaction is waited for
+const collectConfirmTimeout = 30 * time.Second
+
+const (
+	// collectRPCAttempts is the number of times a chain RPC call of a collection
+	// failing transiently, e.g. the RPC being unreachable, is attempted
+	collectRPCAttempts = 4
+	// collectRPCBackoff is the delay before retrying such a call, doubled after
+	// each attempt
+	collectRPCBackoff = 500 * time.Millisecond
+)
+
+// gasMarginPercent is added on top of the estimated gas when sending a
+// transaction
+const gasMarginPercent = 20
+
+// ErrNothingToCollect is returned by RAVCollector.Collect when the RAV value
+// aggregate was already entirely collected
+var ErrNothingToCollect = errors.New("nothing to collect")
+
+// CollectEstimate describes the expected outcome of collecting a RAV on-chain
+type CollectEstimate struct {
+	// Gas is the estimated gas used by the collect transaction
+	Gas uint64
+	// GasPrice is the current gas price in wei
+	GasPrice *big.Int
+	// AlreadyCollected is the amount already collected for the RAV's collection
+	AlreadyCollected *big.Int
+	// TokensDelta is the amount the collect transaction is expected to transfer,
+	// the RAV value aggregate minus what was already collected
+	TokensDelta *big.Int
+}
+
+// Fee returns the expected transaction fee in wei
+func (e *CollectEstimate) Fee() *big.Int {
+	return new(big.Int).Mul(new(big.Int).SetUint64(e.Gas), e.GasPrice)
+}
+
+// CollectReceipt is a sent collect transaction along with the gas it was
+// charged once mined, reverted transactions are charged too
+type CollectReceipt struct {
+	TxHash string
+	// Mined is false when the transaction was not mined in time, gas is then unknown
+	Mined bool
+	// GasUsed is the gas used by the mined transaction
+	GasUsed uint64
+	// GasPrice is the effective gas price paid in wei
+	GasPrice *big.Int
+}
+
+// Fee returns the transaction fee paid in wei, zero when not mined
+func (r *CollectReceipt) Fee() *big.Int {
+	if !r.Mined || r.GasPrice == nil {
+		return big.NewInt(0)
+	}
+	return new(big.Int).Mul(new(big.Int).SetUint64(r.GasUsed), r.GasPrice)
+}
+
+// RAVCollector collects signed RAVs on-chain through SubstreamsDataService.collect,
+// the transaction is signed by the service provider or one of its operators.
+type RAVCollector struct {
+	rpcClient      *rpc.Client
+	chainID        uint64
+	dataService    eth.Address
+	collector      eth.Address
+	key            *eth.PrivateKey
+	dataServiceCut horizon.PPM
+	paymentTypes   *PaymentTypes
+	logger         *zap.Logger
+}
+
+// NewRAVCollector creates a RAV collector. key signs collect transactions, it
+// may be nil in which case only Estimate is available. dataServiceCut is the share
+// of collected tokens requested for the data service. paymentTypes selects the
+// payment type of each collection, query fees for all when nil.
+func NewRAVCollector(rpcEndpoint string, chainID uint64, dataService, collector eth.Address, key *eth.PrivateKey, dataServiceCut horizon.PPM, paymentTypes *PaymentTypes, logger *zap.Logger) *RAVCollector {
+	return &RAVCollector{
+		rpcClient:      rpc.NewClient(rpcEndpoint),
+		chainID:        chainID,
+		dataService:    dataService,
+		collector:      collector,
+		key:            key,
+		dataServiceCut: dataServiceCut,
+		paymentTypes:   paymentTypes,
+		logger:         logger,
+	}
+}
+
+// PaymentType returns the payment type rav is collected under
+func (c *RAVCollector) PaymentType(rav *horizon.RAV) uint8 {
+	return c.paymentTypes.Of(rav.CollectionID)
+}
+
+// CanSend reports whether the collector has a key to send collect transactions
+func (c *RAVCollector) CanSend() bool {
+	return c.key != nil
+}
+
+// TokensCollected returns the amount already collected for the RAV's
+// (dataService, collectionId, serviceProvider, payer) tuple
+func (c *RAVCollector) TokensCollected(ctx context.Context, rav *horizon.RAV) (*big.Int, error) {
+	return callTokensCollected(ctx, c.rpcClient, c.collector, rav, nil)
+}
+
+// Estimate simulates collecting signedRAV and returns the expected gas and
+// token delta without sending a transaction
+func (c *RAVCollector) Estimate(ctx context.Context, signedRAV *horizon.SignedRAV) (*CollectEstimate, error) {
+	calldata, err := c.encodeCollect(signedRAV)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.estimate(ctx, signedRAV.Message, calldata)
+}
+
+// encodeCollect encodes the SubstreamsDataService.collect call of signedRAV
+// under the payment type of its collection
+func (c *RAVCollector) encodeCollect(signedRAV *horizon.SignedRAV) ([]byte, error) {
+	if signedRAV == nil || signedRAV.Message == nil {
+		return nil, horizon.ErrRAVMissing
+	}
+	return horizon.EncodeDataServiceCollectWithPaymentType(signedRAV, c.PaymentType(signedRAV.Message), c.dataServiceCut)
+}
+
+// Collect sends the collect transaction for signedRAV, waits for it to be
+// mined and returns its receipt along with the estimate it was sent with. The
+// receipt is nil when no transaction was sent and set, with the gas charged,
+// when the transaction reverted. A mined transaction is only a success once
+// its RAVCollected event for signedRAV is found.
+func (c *RAVCollector) Collect(ctx context.Context, signedRAV *horizon.SignedRAV) (*CollectReceipt, *CollectEstimate, error) {
+	if c.key == nil {
+		return nil, nil, fmt.Errorf("no key configured to send collect transactions")
+	}
+
+	calldata, err := c.encodeCollect(signedRAV)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// What was already collected is checked before estimating gas, the collect
+	// call reverts once the RAV value aggregate was entirely collected, e.g. by
+	// a RAV of the same collection collected in the meantime
+	estimate, err := c.collected(ctx, signedRAV.Message)
+	if err != nil {
+		return nil, nil, err
+	}
+	if estimate.TokensDelta.Sign() <= 0 {
+		return nil, estimate, fmt.Errorf("%w, %s already collected", ErrNothingToCollect, estimate.AlreadyCollected)
+	}
+	if err := c.estimateGas(ctx, signedRAV.Message, calldata, estimate); err != nil {
+		return nil, nil, err
+	}
+
+	txHash, err := sendTransaction(ctx, c.rpcClient, c.chainID, c.key, c.dataService, calldata, estimate.Gas, estimate.GasPrice, "collect", c.logger)
+	if err != nil {
+		return nil, estimate, err
+	}
+	c.logger.Info("collect transaction sent", zap.String("tx_hash", txHash), zap.Stringer("rav", signedRAV.Message))
+
+	receipt := &CollectReceipt{TxHash: txHash}
+	mined, err := waitForReceipt(ctx, c.rpcClient, txHash, "collect")
+	if mined != nil {
+		receipt.Mined = true
+		receipt.GasUsed = uint64(mined.GasUsed)
+		receipt.GasPrice = new(big.Int).SetUint64(uint64(mined.EffectiveGasPrice))
+	}
+	if err != nil {
+		return receipt, estimate, err
+	}
+
+	if err := c.confirmCollected(ctx, signedRAV.Message, txHash, uint64(mined.BlockNumber)); err != nil {
+		return receipt, estimate, err
+	}
+	return receipt, estimate, nil
+}
+
+// confirmCollected waits for the RAVCollected event of rav emitted by the
+// collect transaction txHash, mined in block, so a transaction that succeeded
+// without collecting the RAV is not taken for a collection
+func (c *RAVCollector) confirmCollected(ctx context.Context, rav *horizon.RAV, txHash string, block uint64) error {
+	_, err := WaitForEvent(ctx, c.rpcClient, EventFilter{
+		Event:     horizon.RAVCollectedEvent,
+		Address:   c.collector,
+		Topics:    []any{eth.Hash(rav.CollectionID[:]), rav.Payer, rav.DataService},
+		FromBlock: block,
+		Timeout:   collectConfirmTimeout,
+	}, func(event *Event) bool {
+		value, _ := event.Args["valueAggregate"].(*big.Int)
+		timestampNs, _ := event.Args["timestampNs"].(uint64)
+		return strings.EqualFold(event.Log.TransactionHash.Pretty(), txHash) &&
+			value != nil && value.Cmp(rav.ValueAggregate) == 0 &&
+			timestampNs == rav.TimestampNs
+	})
+	if err != nil {
+		return fmt.Errorf("confirming collect transaction %s: %w", txHash, err)
+	}
+	return nil
+}
+
+func (c *RAVCollector) estimate(ctx context.Context, rav *horizon.RAV, calldata []byte) (*CollectEstimate, error) {
+	estimate, err := c.collected(ctx, rav)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.estimateGas(ctx, rav, calldata, estimate); err != nil {
+		return nil, err
+	}
+	return estimate, nil
+}
+
+// collected returns the estimate of collecting rav without its gas, only what
+// was already collected for its collection and the expected token delta
+func (c *RAVCollector) collected(ctx context.Context, rav *horizon.RAV) (*CollectEstimate, error) {
+	var alreadyCollected *big.Int
+	err := retryTransient(ctx, c.logger, "tokens collected", func() (err error) {
+		alreadyCollected, err = c.TokensCollected(ctx, rav)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &CollectEstimate{
+		GasPrice:         new(big.Int),
+		AlreadyCollected: alreadyCollected,
+		TokensDelta:      new(big.Int).Sub(rav.ValueAggregate, alreadyCollected),
+	}, nil
+}
+
+// estimateGas fills the gas and gas price of estimate for sending calldata
+func (c *RAVCollector) estimateGas(ctx context.Context, rav *horizon.RAV, calldata []byte, estimate *CollectEstimate) error {
+	from := rav.ServiceProvider
+	if c.key != nil {
+		from = c.key.PublicKey().Address()
+	}
+
+	return retryTransient(ctx, c.logger, "collect gas estimate", func() (err error) {
+		estimate.Gas, estimate.GasPrice, err = estimateGas(ctx, c.rpcClient, rpc.CallParams{From: from, To: c.dataService, Data: calldata}, "collect")
+		return err
+	})
+}
+
+// RevertReason describes why the call behind err failed and reports whether it
+// reverted, as opposed to failing for another reason such as the chain RPC
+// being unreachable. Reverts of Horizon contracts are decoded into their custom
+// error, name is then the error name and empty otherwise.
+func RevertReason(err error) (reason string, name string, reverted bool) {
+	var rpcErr *rpc.ErrResponse
+	if !errors.As(err, &rpcErr) {
+		return err.Error(), "", false
+	}
+
+	if dataHex, ok := rpcErr.Data.(string); ok {
+		if data, decodeErr := eth.NewHex(dataHex); decodeErr == nil && len(data) > 0 {
+			return horizon.DecodeRevert(data), horizon.RevertName(data), true
+		}
+	}
+	return rpcErr.Message, "", rpc.IsDeterministicError(rpcErr)
+}
+
+// estimateGas returns the gas estimated for the transaction described by params
+// along with the current gas price, what names the transaction in errors
+func estimateGas(ctx context.Context, client *rpc.Client, params rpc.CallParams, what string) (uint64, *big.Int, error) {
+	gasHex, err := client.EstimateGas(ctx, params)
+	if err != nil {
+		return 0, nil, fmt.Errorf("estimating %s gas: %w", what, err)
+	}
+
+	gas, err := strconv.ParseUint(strings.TrimPrefix(gasHex, "0x"), 16, 64)
+	if err != nil {
+		return 0, nil, fmt.Errorf("decoding gas estimate %q: %w", gasHex, err)
+	}
+
+	gasPrice, err := client.GasPrice(ctx)
+	if err != nil {
+		return 0, nil, fmt.Errorf("getting gas price: %w", err)
+	}
+	return gas, gasPrice, nil
+}
+
+// sendTransaction signs a transaction calling to with calldata using key and
+// sends it, gas is the estimated gas to which a safety margin is added. The
+// nonce lookup and the sending are retried on transient RPC failures, a
+// transaction the node already knows of having been sent by a previous attempt.
+func sendTransaction(ctx context.Context, client *rpc.Client, chainID uint64, key *eth.PrivateKey, to eth.Address, calldata []byte, gas uint64, gasPrice *big.Int, what string, logger *zap.Logger) (string, error) {
+	var nonce uint64
+	err := retryTransient(ctx, logger, "nonce", func() (err error) {
+		nonce, err = client.Nonce(ctx, key.PublicKey().Address(), nil)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("getting nonce: %w", err)
+	}
+
+	signer, err := native.NewPrivateKeySigner(logger, new(big.Int).SetUint64(chainID), key)
+	if err != nil {
+		return "", fmt.Errorf("creating signer: %w", err)
+	}
+
+	gasLimit := gas + gas*gasMarginPercent/100
+	signedTx, err := signer.SignTransaction(nonce, to[:], big.NewInt(0), gasLimit, gasPrice, calldata)
+	if err != nil {
+		return "", fmt.Errorf("signing %s transaction: %w", what, err)
+	}
+
+	var txHash string
+	retried := false
+	err = retryTransient(ctx, logger, what+" transaction", func() (err error) {
+		txHash, err = client.SendRawTransaction(ctx, signedTx)
+		if err != nil && retried && isAlreadyKnown(err) {
+			txHash, err = eth.Hash(eth.Keccak256(signedTx)).Pretty(), nil
+		}
+		retried = true
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("sending %s transaction: %w", what, err)
+	}
+	return txHash, nil
+}
+
+// retryTransient calls fn until it succeeds, fails with an error that is not
+// transient or was attempted collectRPCAttempts times, backing off
+// exponentially between attempts. It returns the last error of fn.
+func retryTransient(ctx context.Context, logger *zap.Logger, what string, fn func() error) error {
+	backoff := collectRPCBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt == collectRPCAttempts || !IsTransientRPCError(err) {
+			return err
+		}
+
+		logger.Debug("chain RPC call failed, retrying", zap.String("call", what), zap.Int("attempt", attempt), zap.Duration("backoff", backoff), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// IsTransientRPCError reports whether err is a failure to reach the chain RPC,
+// such as a refused connection or an HTTP error status, which is worth retrying.
+// Errors answered by the node, e.g. a revert, and context cancellations are not.
+func IsTransientRPCError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var rpcErr *rpc.ErrResponse
+	return !errors.As(err, &rpcErr)
+}
+
+// isAlreadyKnown reports whether err is the node refusing a transaction it
+// already has in its pool
+func isAlreadyKnown(err error) bool {
+	var rpcErr *rpc.ErrResponse
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	msg := strings.ToLower(rpcErr.Message)
+	return strings.Contains(msg, "already known") || strings.Contains(msg, "known transaction")
+}
+
+// waitForReceipt waits up to DefaultCollectReceiptTimeout for txHash to be
+// mined, failing when it reverted. The receipt is returned once mined, even
+// when reverted.
+func waitForReceipt(ctx context.Context, client *rpc.Client, txHash string, what string) (*rpc.TransactionReceipt, error) {
+	ctx, cancel := context.WithTimeout(ctx, DefaultCollectReceiptTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	hash := eth.MustNewHash(txHash)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for %s transaction %s: %w", what, txHash, ctx.Err())
+		case <-ticker.C:
+			receipt, err := client.TransactionReceipt(ctx, hash)
+			if err != nil || receipt == nil {
+				continue // Not mined yet
+			}
+			if receipt.Status != nil && uint64(*receipt.Status) == 0 {
+				return receipt, fmt.Errorf("%s transaction %s reverted", what, txHash)
+			}
+			return receipt, nil
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/streamingfast/eth-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestIsTransientRPCError(t *testing.T) {
+	assert.False(t, IsTransientRPCError(nil))
+	assert.True(t, IsTransientRPCError(errors.New("error in response: 503")))
+	assert.False(t, IsTransientRPCError(&rpc.ErrResponse{Code: -32000, Message: "execution reverted"}))
+	assert.False(t, IsTransientRPCError(context.DeadlineExceeded))
+}
+
+func TestRetryTransient(t *testing.T) {
+	calls := 0
+	err := retryTransient(context.Background(), zap.NewNop(), "test", func() error {
+		calls++
+		if calls == 1 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	calls = 0
+	reverted := &rpc.ErrResponse{Code: -32000, Message: "execution reverted"}
+	err = retryTransient(context.Background(), zap.NewNop(), "test", func() error {
+		calls++
+		return reverted
+	})
+	assert.Equal(t, reverted, err)
+	assert.Equal(t, 1, calls)
+
+	assert.True(t, isAlreadyKnown(&rpc.ErrResponse{Code: -32000, Message: "already known"}))
+	assert.False(t, isAlreadyKnown(errors.New("already known")))
+}
@@ -8,74 +8,50 @@ import (
 	"github.com/streamingfast/eth-go"
 )
 
-// ProtoRAVToHorizon converts a proto RAV to a horizon RAV
+// ProtoRAVToHorizon converts a proto RAV to a horizon RAV, see horizon.RAVFromProto
 func ProtoRAVToHorizon(pr *commonv1.RAV) *horizon.RAV {
-	if pr == nil {
-		return nil
-	}
-
-	var collectionID horizon.CollectionID
-	if len(pr.Metadata) >= 32 {
-		copy(collectionID[:], pr.Metadata[:32])
-	}
-
-	return &horizon.RAV{
-		CollectionID:    collectionID,
-		Payer:           pr.Payer.ToEth(),
-		DataService:     pr.DataService.ToEth(),
-		ServiceProvider: pr.ServiceProvider.ToEth(),
-		TimestampNs:     pr.TimestampNs,
-		ValueAggregate:  pr.ValueAggregate.ToNative(),
-		Metadata:        pr.Metadata,
-	}
+	return horizon.RAVFromProto(pr)
 }
 
-// HorizonRAVToProto converts a horizon RAV to a proto RAV
+// HorizonRAVToProto converts a horizon RAV to a proto RAV, see horizon.RAV.ToProto
 func HorizonRAVToProto(hr *horizon.RAV) *commonv1.RAV {
-	if hr == nil {
-		return nil
-	}
-
-	return &commonv1.RAV{
-		Payer:           commonv1.AddressFromEth(hr.Payer),
-		DataService:     commonv1.AddressFromEth(hr.DataService),
-		ServiceProvider: commonv1.AddressFromEth(hr.ServiceProvider),
-		TimestampNs:     hr.TimestampNs,
-		ValueAggregate:  commonv1.BigIntFromNative(hr.ValueAggregate),
-		Metadata:        hr.Metadata,
-	}
+	return hr.ToProto()
 }
 
-// ProtoSignedRAVToHorizon converts a proto SignedRAV to a horizon SignedRAV
+// ProtoSignedRAVToHorizon converts a proto SignedRAV to a horizon SignedRAV,
+// see horizon.SignedRAVFromProto
 func ProtoSignedRAVToHorizon(psr *commonv1.SignedRAV) *horizon.SignedRAV {
-	if psr == nil {
-		return nil
-	}
+	return horizon.SignedRAVFromProto(psr)
+}
 
-	rav := ProtoRAVToHorizon(psr.Rav)
-	if rav == nil {
-		return nil
-	}
+// HorizonSignedRAVToProto converts a horizon SignedRAV to a proto SignedRAV,
+// see horizon.SignedRAVToProto
+func HorizonSignedRAVToProto(hsr *horizon.SignedRAV) *commonv1.SignedRAV {
+	return horizon.SignedRAVToProto(hsr)
+}
 
-	var sig eth.Signature
-	copy(sig[:], psr.Signature)
+// ProtoReceiptToHorizon converts a proto Receipt to a horizon Receipt, see
+// horizon.ReceiptFromProto
+func ProtoReceiptToHorizon(pr *commonv1.Receipt) *horizon.Receipt {
+	return horizon.ReceiptFromProto(pr)
+}
 
-	return &horizon.SignedRAV{
-		Message:   rav,
-		Signature: sig,
-	}
+// HorizonReceiptToProto converts a horizon Receipt to a proto Receipt, see
+// horizon.Receipt.ToProto
+func HorizonReceiptToProto(hr *horizon.Receipt) *commonv1.Receipt {
+	return hr.ToProto()
 }
 
-// HorizonSignedRAVToProto converts a horizon SignedRAV to a proto SignedRAV
-func HorizonSignedRAVToProto(hsr *horizon.SignedRAV) *commonv1.SignedRAV {
-	if hsr == nil {
-		return nil
-	}
+// ProtoSignedReceiptToHorizon converts a proto SignedReceipt to a horizon
+// SignedReceipt, see horizon.SignedReceiptFromProto
+func ProtoSignedReceiptToHorizon(psr *commonv1.SignedReceipt) *horizon.SignedReceipt {
+	return horizon.SignedReceiptFromProto(psr)
+}
 
-	return &commonv1.SignedRAV{
-		Rav:       HorizonRAVToProto(hsr.Message),
-		Signature: hsr.Signature[:],
-	}
+// HorizonSignedReceiptToProto converts a horizon SignedReceipt to a proto
+// SignedReceipt, see horizon.SignedReceiptToProto
+func HorizonSignedReceiptToProto(hsr *horizon.SignedReceipt) *commonv1.SignedReceipt {
+	return horizon.SignedReceiptToProto(hsr)
 }
 
 // AddressesEqual compares two eth.Address values
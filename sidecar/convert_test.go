@@ -59,6 +59,42 @@ func TestHorizonRAVToProto(t *testing.T) {
 	assert.Equal(t, big.NewInt(1000).Bytes(), result.ValueAggregate.Bytes)
 }
 
+func TestSignedReceiptRoundTrip(t *testing.T) {
+	signed := &horizon.SignedReceipt{
+		Message: &horizon.Receipt{
+			CollectionID:    horizon.CollectionID{0xaa, 0xbb},
+			Payer:           eth.MustNewAddress("0x1111111111111111111111111111111111111111"),
+			DataService:     eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+			ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+			TimestampNs:     1234567890,
+			Nonce:           42,
+			Value:           big.NewInt(1000),
+		},
+		Signature: eth.Signature{27, 0x02, 0x03},
+	}
+
+	result := ProtoSignedReceiptToHorizon(HorizonSignedReceiptToProto(signed))
+
+	assert.NotNil(t, result)
+	assert.Equal(t, signed.Message.CollectionID, result.Message.CollectionID)
+	assert.True(t, bytes.Equal(signed.Message.Payer, result.Message.Payer))
+	assert.True(t, bytes.Equal(signed.Message.DataService, result.Message.DataService))
+	assert.True(t, bytes.Equal(signed.Message.ServiceProvider, result.Message.ServiceProvider))
+	assert.Equal(t, uint64(1234567890), result.Message.TimestampNs)
+	assert.Equal(t, uint64(42), result.Message.Nonce)
+	assert.Equal(t, int64(1000), result.Message.Value.Int64())
+	assert.Equal(t, signed.Signature, result.Signature)
+
+	// EIP-2098 compact signatures are expanded, the recovery ID is the top bit of S
+	compact := make([]byte, horizon.CompactSignatureLength)
+	compact[0], compact[32] = 0x02, 0x80|0x03
+	result = ProtoSignedReceiptToHorizon(&commonv1.SignedReceipt{Receipt: HorizonReceiptToProto(signed.Message), Signature: compact})
+	assert.Equal(t, eth.Signature{0: 28, 1: 0x02, 33: 0x03}, result.Signature)
+
+	assert.Nil(t, ProtoSignedReceiptToHorizon(nil))
+	assert.Nil(t, ProtoSignedReceiptToHorizon(&commonv1.SignedReceipt{}))
+}
+
 func TestAddressesEqual(t *testing.T) {
 	addr1 := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
 	addr2 := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
@@ -67,3 +103,29 @@ func TestAddressesEqual(t *testing.T) {
 	assert.True(t, AddressesEqual(addr1, addr2))
 	assert.False(t, AddressesEqual(addr1, addr3))
 }
+
+func TestPaymentHeader(t *testing.T) {
+	rav := &commonv1.SignedRAV{
+		Rav: &commonv1.RAV{
+			Payer:          commonv1.AddressFromEth(eth.MustNewAddress("0x1111111111111111111111111111111111111111")),
+			TimestampNs:    1234567890,
+			ValueAggregate: commonv1.BigIntFromNative(big.NewInt(1000)),
+		},
+		Signature: bytes.Repeat([]byte{0x01}, 65),
+	}
+
+	header, err := EncodePaymentHeader(rav)
+	assert.NoError(t, err)
+
+	decoded, err := DecodePaymentHeader(header)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1234567890), decoded.Rav.TimestampNs)
+	assert.Equal(t, int64(1000), decoded.Rav.ValueAggregate.ToNative().Int64())
+	assert.Equal(t, rav.Signature, decoded.Signature)
+
+	_, err = DecodePaymentHeader("not base64!")
+	assert.Error(t, err)
+
+	_, err = DecodePaymentHeader("")
+	assert.ErrorContains(t, err, "missing RAV")
+}
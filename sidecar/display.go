@@ -0,0 +1,114 @@
+package sidecar
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// AmountUnit is the unit GRT amounts are displayed in
+type AmountUnit string
+
+const (
+	// AmountUnitWei displays exact integer wei amounts
+	AmountUnitWei AmountUnit = "wei"
+	// AmountUnitGRT displays decimal GRT amounts rounded to a fixed number of decimals
+	AmountUnitGRT AmountUnit = "GRT"
+)
+
+// DefaultDisplayDecimals is the number of decimals GRT amounts are rounded to by default
+const DefaultDisplayDecimals = 6
+
+// ParseAmountUnit parses a display unit, case insensitive
+func ParseAmountUnit(unit string) (AmountUnit, error) {
+	switch strings.ToLower(strings.TrimSpace(unit)) {
+	case "wei":
+		return AmountUnitWei, nil
+	case "grt":
+		return AmountUnitGRT, nil
+	default:
+		return "", fmt.Errorf("unknown amount unit %q, expected \"wei\" or \"grt\"", unit)
+	}
+}
+
+// AmountDisplay controls how GRT amounts are rendered in admin responses,
+// exports and logs so they consistently use a single unit. Wei rendering is
+// exact, GRT rendering rounds half away from zero to Decimals places.
+type AmountDisplay struct {
+	Unit     AmountUnit
+	Decimals int
+}
+
+// DefaultAmountDisplay renders exact wei amounts
+func DefaultAmountDisplay() *AmountDisplay {
+	return &AmountDisplay{Unit: AmountUnitWei, Decimals: DefaultDisplayDecimals}
+}
+
+// NewAmountDisplay creates an amount display, decimals only applies to GRT and
+// must be between 0 and 18
+func NewAmountDisplay(unit AmountUnit, decimals int) (*AmountDisplay, error) {
+	if unit != AmountUnitWei && unit != AmountUnitGRT {
+		return nil, fmt.Errorf("unknown amount unit %q", unit)
+	}
+	if decimals < 0 || decimals > 18 {
+		return nil, fmt.Errorf("decimals must be between 0 and 18, got %d", decimals)
+	}
+
+	return &AmountDisplay{Unit: unit, Decimals: decimals}, nil
+}
+
+// Format renders a wei amount in the display unit, without the unit suffix
+func (d *AmountDisplay) Format(wei *big.Int) string {
+	if wei == nil {
+		wei = big.NewInt(0)
+	}
+	if d == nil || d.Unit != AmountUnitGRT {
+		return wei.String()
+	}
+
+	// Round to Decimals places: scale down to the wanted precision, rounding half away from zero
+	divisor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(18-d.Decimals)), nil)
+	abs := new(big.Int).Abs(wei)
+	scaled, remainder := new(big.Int).QuoRem(abs, divisor, new(big.Int))
+	if remainder.Lsh(remainder, 1).Cmp(divisor) >= 0 {
+		scaled.Add(scaled, big.NewInt(1))
+	}
+
+	sign := ""
+	if wei.Sign() < 0 && scaled.Sign() != 0 {
+		sign = "-"
+	}
+
+	if d.Decimals == 0 {
+		return sign + scaled.String()
+	}
+
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(d.Decimals)), nil)
+	integer, fraction := new(big.Int).QuoRem(scaled, unit, new(big.Int))
+	return fmt.Sprintf("%s%s.%0*d", sign, integer, d.Decimals, fraction)
+}
+
+// FormatWithUnit renders a wei amount in the display unit followed by the unit,
+// e.g. "1.500000 GRT" or "1500000000000000000 wei"
+func (d *AmountDisplay) FormatWithUnit(wei *big.Int) string {
+	return d.Format(wei) + " " + string(d.unit())
+}
+
+// Field returns a log field rendering the wei amount with its unit
+func (d *AmountDisplay) Field(key string, wei *big.Int) zap.Field {
+	return zap.String(key, d.FormatWithUnit(wei))
+}
+
+// UnitName returns the display unit name, reported next to formatted amounts
+func (d *AmountDisplay) UnitName() string {
+	return string(d.unit())
+}
+
+func (d *AmountDisplay) unit() AmountUnit {
+	if d == nil || d.Unit == "" {
+		return AmountUnitWei
+	}
+	return d.Unit
+}
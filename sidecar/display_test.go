@@ -0,0 +1,71 @@
+package sidecar
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAmountDisplay_Format(t *testing.T) {
+	wei := func(s string) *big.Int {
+		v, ok := new(big.Int).SetString(s, 10)
+		require.True(t, ok)
+		return v
+	}
+
+	tests := []struct {
+		name     string
+		unit     AmountUnit
+		decimals int
+		input    *big.Int
+		expected string
+	}{
+		{"wei exact", AmountUnitWei, 6, wei("1500000000000000001"), "1500000000000000001"},
+		{"wei nil", AmountUnitWei, 6, nil, "0"},
+		{"grt padded", AmountUnitGRT, 6, wei("1500000000000000000"), "1.500000"},
+		{"grt rounds down", AmountUnitGRT, 6, wei("1000000499999999999"), "1.000000"},
+		{"grt rounds half up", AmountUnitGRT, 6, wei("1000000500000000000"), "1.000001"},
+		{"grt carries", AmountUnitGRT, 2, wei("9995000000000000000"), "10.00"},
+		{"grt zero decimals", AmountUnitGRT, 0, wei("2500000000000000000"), "3"},
+		{"grt full precision", AmountUnitGRT, 18, wei("1"), "0.000000000000000001"},
+		{"grt negative", AmountUnitGRT, 2, wei("-1005000000000000000"), "-1.01"},
+		{"grt negative rounds to zero", AmountUnitGRT, 2, wei("-1"), "0.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			display, err := NewAmountDisplay(tt.unit, tt.decimals)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, display.Format(tt.input))
+		})
+	}
+}
+
+func TestAmountDisplay_FormatWithUnit(t *testing.T) {
+	assert.Equal(t, "42 wei", DefaultAmountDisplay().FormatWithUnit(big.NewInt(42)))
+
+	display, err := NewAmountDisplay(AmountUnitGRT, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "0.5 GRT", display.FormatWithUnit(big.NewInt(500000000000000000)))
+
+	var unset *AmountDisplay
+	assert.Equal(t, "42 wei", unset.FormatWithUnit(big.NewInt(42)))
+}
+
+func TestParseAmountUnit(t *testing.T) {
+	unit, err := ParseAmountUnit("GRT")
+	require.NoError(t, err)
+	assert.Equal(t, AmountUnitGRT, unit)
+
+	unit, err = ParseAmountUnit("wei")
+	require.NoError(t, err)
+	assert.Equal(t, AmountUnitWei, unit)
+
+	_, err = ParseAmountUnit("eth")
+	assert.Error(t, err)
+
+	_, err = NewAmountDisplay(AmountUnitGRT, 19)
+	assert.Error(t, err)
+}
@@ -0,0 +1,155 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/streamingfast/eth-go/rpc"
+)
+
+var (
+	// ENSRegistryAddress is the address of the ENS registry, the same on every
+	// chain ENS is deployed on
+	ENSRegistryAddress = eth.MustNewAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e")
+
+	// ENSResolverMethod is ENSRegistry.resolver(node)
+	ENSResolverMethod = eth.MustNewMethodDef("resolver(bytes32)")
+	// ENSAddrMethod is Resolver.addr(node)
+	ENSAddrMethod = eth.MustNewMethodDef("addr(bytes32)")
+)
+
+// ErrENSUnsupportedChain is returned when resolving ENS names against a chain
+// without an ENS deployment
+var ErrENSUnsupportedChain = errors.New("ENS is not supported on this chain")
+
+// ensChains are the chains ENS is deployed on, by chain ID
+var ensChains = map[uint64]string{
+	1:        "mainnet",
+	17000:    "holesky",
+	11155111: "sepolia",
+}
+
+// ENSResolver resolves ENS names to addresses through the ENS registry, using
+// the public resolver set for the name
+type ENSResolver struct {
+	rpcClient *rpc.Client
+	registry  eth.Address
+
+	mu          sync.Mutex
+	chainIDSeen bool
+}
+
+// NewENSResolver creates an ENSResolver querying rpcEndpoint, which must be an
+// endpoint of a chain ENS is deployed on (mainnet, Sepolia or Holesky)
+func NewENSResolver(rpcEndpoint string) *ENSResolver {
+	return &ENSResolver{
+		rpcClient: rpc.NewClient(rpcEndpoint),
+		registry:  ENSRegistryAddress,
+	}
+}
+
+// Resolve returns the address name resolves to. Names are only lower cased,
+// not fully normalized (UTS-46), so names with non ASCII characters must be
+// given in their normalized form.
+func (r *ENSResolver) Resolve(ctx context.Context, name string) (eth.Address, error) {
+	if err := r.checkChain(ctx); err != nil {
+		return nil, err
+	}
+
+	node := ENSNamehash(name)
+
+	resolver, err := r.callAddress(ctx, r.registry, ENSResolverMethod, node)
+	if err != nil {
+		return nil, fmt.Errorf("querying resolver: %w", err)
+	}
+	if isZeroAddress(resolver) {
+		return nil, fmt.Errorf("%w %q, no resolver set", ErrUnknownAddressName, name)
+	}
+
+	address, err := r.callAddress(ctx, resolver, ENSAddrMethod, node)
+	if err != nil {
+		return nil, fmt.Errorf("querying address: %w", err)
+	}
+	if isZeroAddress(address) {
+		return nil, fmt.Errorf("%w %q, no address set", ErrUnknownAddressName, name)
+	}
+	return address, nil
+}
+
+// checkChain verifies once that the endpoint serves a chain ENS is deployed on
+func (r *ENSResolver) checkChain(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.chainIDSeen {
+		return nil
+	}
+
+	chainID, err := r.rpcClient.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("querying chain ID: %w", err)
+	}
+	if !chainID.IsUint64() {
+		return fmt.Errorf("%w: chain ID %s", ErrENSUnsupportedChain, chainID)
+	}
+	if _, found := ensChains[chainID.Uint64()]; !found {
+		return fmt.Errorf("%w: chain ID %d", ErrENSUnsupportedChain, chainID.Uint64())
+	}
+
+	r.chainIDSeen = true
+	return nil
+}
+
+func (r *ENSResolver) callAddress(ctx context.Context, to eth.Address, method *eth.MethodDef, node eth.Hash) (eth.Address, error) {
+	data, err := method.NewCall([]byte(node)).Encode()
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s call: %w", method.Name, err)
+	}
+
+	resultHex, err := r.rpcClient.Call(ctx, rpc.CallParams{To: to, Data: data})
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := eth.NewHex(resultHex)
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s result: %w", method.Name, err)
+	}
+	if len(result) == 0 {
+		// No contract at the address, e.g. a resolver that was self-destructed
+		return make(eth.Address, 20), nil
+	}
+	if len(result) != 32 {
+		return nil, fmt.Errorf("unexpected %s result length: %d", method.Name, len(result))
+	}
+	return eth.Address(result[12:]), nil
+}
+
+// ENSNamehash returns the EIP-137 namehash of name, lower cased
+func ENSNamehash(name string) eth.Hash {
+	node := make([]byte, 32)
+
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return node
+	}
+
+	labels := strings.Split(name, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		node = eth.Keccak256(node, eth.Keccak256([]byte(labels[i])))
+	}
+	return node
+}
+
+func isZeroAddress(address eth.Address) bool {
+	for _, b := range address {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,53 @@
+package sidecar
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/streamingfast/eth-go"
+)
+
+// EscrowAccountKey identifies an escrow account in the PaymentsEscrow contract,
+// funds are deposited per (payer, collector, receiver) so the same payer has
+// distinct balances for each collector it pays through.
+type EscrowAccountKey struct {
+	Payer     eth.Address
+	Collector eth.Address
+	Receiver  eth.Address
+}
+
+// String returns a stable textual representation usable as a map key
+func (k EscrowAccountKey) String() string {
+	return fmt.Sprintf("%s/%s/%s", prettyOrUnset(k.Payer), prettyOrUnset(k.Collector), prettyOrUnset(k.Receiver))
+}
+
+func prettyOrUnset(addr eth.Address) string {
+	if addr == nil {
+		return "unset"
+	}
+	return addr.Pretty()
+}
+
+// EscrowAccounts returns the distinct escrow accounts the known sessions are
+// paid from, sorted. Sessions without a collector are paid through
+// defaultCollector.
+func (sm *SessionManager) EscrowAccounts(defaultCollector eth.Address) []EscrowAccountKey {
+	byAccount := make(map[string]EscrowAccountKey)
+	for _, session := range sm.List() {
+		account := session.EscrowAccount()
+		if len(account.Collector) == 0 {
+			account.Collector = defaultCollector
+		}
+		byAccount[account.String()] = account
+	}
+
+	accounts := make([]EscrowAccountKey, 0, len(byAccount))
+	for _, account := range byAccount {
+		accounts = append(accounts, account)
+	}
+	slices.SortFunc(accounts, func(a, b EscrowAccountKey) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	return accounts
+}
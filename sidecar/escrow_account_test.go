@@ -0,0 +1,37 @@
+package sidecar
+
+import (
+	"testing"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionManager_EscrowAccounts(t *testing.T) {
+	payer := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
+	receiver := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	dataService := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	collectorA := eth.MustNewAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	collectorB := eth.MustNewAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+
+	sm := NewSessionManager()
+	sm.CreateWithCollector(payer, receiver, dataService, collectorB)
+	sm.CreateWithCollector(payer, receiver, dataService, collectorA)
+	sm.CreateWithCollector(payer, receiver, dataService, collectorA)
+	// Paid through the default collector
+	sm.Create(payer, receiver, dataService)
+
+	assert.Equal(t, []EscrowAccountKey{
+		{Payer: payer, Collector: collectorA, Receiver: receiver},
+		{Payer: payer, Collector: collectorB, Receiver: receiver},
+	}, sm.EscrowAccounts(collectorB))
+}
+
+func TestEscrowAccountKey_String(t *testing.T) {
+	key := EscrowAccountKey{
+		Payer:    eth.MustNewAddress("0x1111111111111111111111111111111111111111"),
+		Receiver: eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+	}
+
+	assert.Equal(t, "0x1111111111111111111111111111111111111111/unset/0x2222222222222222222222222222222222222222", key.String())
+}
@@ -7,6 +7,7 @@ import (
 	"math/big"
 	"strings"
 
+	"github.com/graphprotocol/substreams-data-service/horizon"
 	"github.com/streamingfast/eth-go"
 	"github.com/streamingfast/eth-go/rpc"
 )
@@ -28,6 +29,12 @@ func NewEscrowQuerier(rpcEndpoint string, escrowAddr eth.Address) *EscrowQuerier
 // GetBalance returns the escrow balance for a payer -> receiver via collector
 // This calls PaymentsEscrow.getBalance(payer, collector, receiver)
 func (q *EscrowQuerier) GetBalance(ctx context.Context, payer, collector, receiver eth.Address) (*big.Int, error) {
+	return q.GetBalanceAt(ctx, payer, collector, receiver, nil)
+}
+
+// GetBalanceAt is GetBalance at a given block, the latest one when at is nil.
+// Blocks older than the node's pruning window require an archive RPC endpoint.
+func (q *EscrowQuerier) GetBalanceAt(ctx context.Context, payer, collector, receiver eth.Address, at *rpc.BlockRef) (*big.Int, error) {
 	// Build the call data for getBalance(address,address,address)
 	// Function selector: keccak256("getBalance(address,address,address)")[:4]
 	// = 0xd6a58fd9
@@ -45,7 +52,7 @@ func (q *EscrowQuerier) GetBalance(ctx context.Context, payer, collector, receiv
 		Data: data,
 	}
 
-	resultHex, err := q.rpcClient.Call(ctx, params)
+	resultHex, err := q.rpcClient.CallAtBlock(ctx, params, blockOrLatest(at))
 	if err != nil {
 		return nil, fmt.Errorf("calling getBalance: %w", err)
 	}
@@ -65,3 +72,46 @@ func (q *EscrowQuerier) GetBalance(ctx context.Context, payer, collector, receiv
 
 	return new(big.Int).SetBytes(resultBytes), nil
 }
+
+// TokensCollectedAt returns the amount collected by the GraphTallyCollector for
+// the (dataService, collectionID, receiver, payer) tuple at a given block, the
+// latest one when at is nil
+func (q *EscrowQuerier) TokensCollectedAt(ctx context.Context, collector, dataService eth.Address, collectionID horizon.CollectionID, receiver, payer eth.Address, at *rpc.BlockRef) (*big.Int, error) {
+	return callTokensCollected(ctx, q.rpcClient, collector, &horizon.RAV{
+		DataService:     dataService,
+		CollectionID:    collectionID,
+		ServiceProvider: receiver,
+		Payer:           payer,
+	}, at)
+}
+
+// callTokensCollected calls GraphTallyCollector.tokensCollected for the RAV's
+// (dataService, collectionId, serviceProvider, payer) tuple
+func callTokensCollected(ctx context.Context, client *rpc.Client, collector eth.Address, rav *horizon.RAV, at *rpc.BlockRef) (*big.Int, error) {
+	data, err := horizon.TokensCollectedMethod.NewCall(rav.DataService, rav.CollectionID[:], rav.ServiceProvider, rav.Payer).Encode()
+	if err != nil {
+		return nil, fmt.Errorf("encoding tokensCollected call: %w", err)
+	}
+
+	resultHex, err := client.CallAtBlock(ctx, rpc.CallParams{To: collector, Data: data}, blockOrLatest(at))
+	if err != nil {
+		return nil, fmt.Errorf("calling tokensCollected: %w", err)
+	}
+
+	result, err := eth.NewHex(resultHex)
+	if err != nil {
+		return nil, fmt.Errorf("decoding tokensCollected result: %w", err)
+	}
+	if len(result) != 32 {
+		return nil, fmt.Errorf("unexpected tokensCollected result length: %d", len(result))
+	}
+
+	return new(big.Int).SetBytes(result), nil
+}
+
+func blockOrLatest(at *rpc.BlockRef) *rpc.BlockRef {
+	if at == nil {
+		return rpc.LatestBlock
+	}
+	return at
+}
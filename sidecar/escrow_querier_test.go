@@ -0,0 +1,49 @@
+package sidecar
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+	"github.com/streamingfast/eth-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEscrowQuerier_AtBlock(t *testing.T) {
+	var blocks []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Params []json.RawMessage `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Len(t, req.Params, 2)
+		blocks = append(blocks, string(req.Params[1]))
+
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x%064x"}`, req.ID, 42)
+	}))
+	defer server.Close()
+
+	address := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
+	querier := NewEscrowQuerier(server.URL, address)
+
+	balance, err := querier.GetBalance(t.Context(), address, address, address)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(42), balance)
+
+	balance, err = querier.GetBalanceAt(t.Context(), address, address, address, rpc.BlockNumber(100))
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(42), balance)
+
+	collected, err := querier.TokensCollectedAt(t.Context(), address, address, horizon.CollectionID{0x01}, address, address, rpc.BlockNumber(100))
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(42), collected)
+
+	assert.Equal(t, []string{`"latest"`, `{"blockNumber":"0x64"}`, `{"blockNumber":"0x64"}`}, blocks)
+}
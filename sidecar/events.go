@@ -0,0 +1,158 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/streamingfast/eth-go/rpc"
+)
+
+// DefaultEventPollInterval is how often WaitForEvent polls the chain for new logs
+const DefaultEventPollInterval = time.Second
+
+// ErrEventTimeout is returned by WaitForEvent when no matching event was
+// emitted before the timeout
+var ErrEventTimeout = errors.New("timed out waiting for event")
+
+// EventFilter selects the logs WaitForEvent decodes and hands to its predicate
+type EventFilter struct {
+	// Event is the event waited for, only logs with its ID as first topic match
+	Event *eth.LogEventDef
+	// Address is the contract emitting the event, any contract when nil
+	Address eth.Address
+	// Topics filters the indexed parameters of the event in order, a nil entry
+	// matching any value, e.g. {collectionID, payer} for RAVCollected
+	Topics []any
+	// FromBlock is the first block searched, the chain head when WaitForEvent
+	// is called when zero
+	FromBlock uint64
+	// Timeout bounds the wait, ErrEventTimeout being returned once elapsed,
+	// the wait is only bounded by the context when zero
+	Timeout time.Duration
+	// PollInterval is how often new blocks are searched,
+	// DefaultEventPollInterval when zero
+	PollInterval time.Duration
+}
+
+// Event is a log decoded against its event definition
+type Event struct {
+	// Name is the name of the event, e.g. "RAVCollected"
+	Name string
+	// Args are the decoded event parameters by name, indexed and not indexed
+	Args map[string]any
+	// Log is the raw log, with the block and transaction that emitted it
+	Log *rpc.LogEntry
+}
+
+// WaitForEvent polls the chain from filter.FromBlock for a log matching
+// filter and returns the first one predicate accepts once decoded, a nil
+// predicate accepting any event. It is used to confirm a transaction had the
+// expected effect (e.g. a RAV collection landed with the right value) rather
+// than only checking its receipt status.
+func WaitForEvent(ctx context.Context, client *rpc.Client, filter EventFilter, predicate func(*Event) bool) (*Event, error) {
+	if filter.Event == nil {
+		return nil, fmt.Errorf("event filter has no event")
+	}
+
+	if filter.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, filter.Timeout)
+		defer cancel()
+	}
+
+	pollInterval := filter.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = DefaultEventPollInterval
+	}
+
+	topics := rpc.NewTopicFilter(append([]any{eth.Hash(filter.Event.LogID())}, filter.Topics...)...)
+	next := filter.FromBlock
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		head, err := client.LatestBlockNum(ctx)
+		if err == nil && next == 0 {
+			next = head
+		}
+
+		if err == nil && head >= next {
+			logs, err := client.Logs(ctx, rpc.LogsParams{
+				FromBlock: rpc.BlockNumber(next),
+				ToBlock:   rpc.BlockNumber(head),
+				Address:   filter.Address,
+				Topics:    topics,
+			})
+			if err == nil {
+				for _, log := range logs {
+					if log.Removed {
+						continue
+					}
+
+					event, err := DecodeEvent(filter.Event, log)
+					if err != nil {
+						continue // Same ID but another layout, e.g. from another contract
+					}
+					if predicate == nil || predicate(event) {
+						return event, nil
+					}
+				}
+				next = head + 1
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("%w %s", ErrEventTimeout, filter.Event.Name)
+			}
+			return nil, fmt.Errorf("waiting for event %s: %w", filter.Event.Name, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// DecodeEvent decodes log against the event definition def
+func DecodeEvent(def *eth.LogEventDef, log *rpc.LogEntry) (*Event, error) {
+	raw := log.ToLog()
+	decoder := eth.NewLogDecoder(&raw)
+	if _, err := decoder.ReadTopic(); err != nil {
+		return nil, fmt.Errorf("reading event ID: %w", err)
+	}
+
+	// Non-indexed parameters are ABI encoded together in the log data, decoded
+	// as a whole so dynamic types are read at their offset
+	var dataParams []*eth.MethodParameter
+	for _, param := range def.Parameters {
+		if !param.Indexed {
+			dataParams = append(dataParams, &eth.MethodParameter{Name: param.Name, TypeName: param.TypeName, Type: param.Type, Components: param.Components})
+		}
+	}
+
+	var data []any
+	if len(dataParams) > 0 {
+		var err error
+		if data, err = eth.NewDecoder(raw.Data).ReadOutput(dataParams); err != nil {
+			return nil, fmt.Errorf("decoding event %s data: %w", def.Name, err)
+		}
+	}
+
+	event := &Event{Name: def.Name, Args: make(map[string]any, len(def.Parameters)), Log: log}
+	for _, param := range def.Parameters {
+		if !param.Indexed {
+			event.Args[param.Name], data = data[0], data[1:]
+			continue
+		}
+
+		value, err := decoder.ReadTypedTopic(param.TypeName)
+		if err != nil {
+			return nil, fmt.Errorf("decoding event %s parameter %s: %w", def.Name, param.Name, err)
+		}
+		event.Args[param.Name] = value
+	}
+	return event, nil
+}
@@ -0,0 +1,131 @@
+package sidecar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+	"github.com/streamingfast/eth-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLogsChain is a JSON-RPC endpoint whose head moves one block forward on
+// each eth_blockNumber call, serving logs by block number on eth_getLogs
+type fakeLogsChain struct {
+	head atomic.Uint64
+	logs map[uint64][]map[string]any
+}
+
+func (c *fakeLogsChain) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var result any
+	switch request.Method {
+	case "eth_blockNumber":
+		result = "0x" + strconv.FormatUint(c.head.Add(1), 16)
+	case "eth_getLogs":
+		var params struct {
+			FromBlock string `json:"fromBlock"`
+			ToBlock   string `json:"toBlock"`
+		}
+		json.Unmarshal(request.Params[0], &params)
+		from, _ := strconv.ParseUint(params.FromBlock[2:], 16, 64)
+		to, _ := strconv.ParseUint(params.ToBlock[2:], 16, 64)
+
+		logs := []map[string]any{}
+		for block := from; block <= to; block++ {
+			logs = append(logs, c.logs[block]...)
+		}
+		result = logs
+	}
+	json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": request.ID, "result": result})
+}
+
+func TestWaitForEvent(t *testing.T) {
+	collector := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
+	rav := &horizon.RAV{
+		CollectionID:    horizon.CollectionID{0x01},
+		Payer:           eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+		ServiceProvider: eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		DataService:     eth.MustNewAddress("0x4444444444444444444444444444444444444444"),
+		TimestampNs:     1234,
+		ValueAggregate:  big.NewInt(5000),
+	}
+
+	ravCollectedLog := func(block uint64, txHash string, value int64) map[string]any {
+		topics, data, err := horizon.RAVCollectedEvent.NewEvent(
+			rav.CollectionID[:], rav.Payer, rav.ServiceProvider, rav.DataService,
+			rav.TimestampNs, big.NewInt(value), []byte{}, []byte{0xaa},
+		).Encode()
+		require.NoError(t, err)
+
+		encodedTopics := make([]string, len(topics))
+		for i, topic := range topics {
+			encodedTopics[i] = eth.Hash(topic[:]).Pretty()
+		}
+		return map[string]any{
+			"address":          collector.Pretty(),
+			"topics":           encodedTopics,
+			"data":             eth.Hex(data).Pretty(),
+			"blockNumber":      "0x" + strconv.FormatUint(block, 16),
+			"transactionHash":  txHash,
+			"transactionIndex": "0x0",
+			"blockHash":        eth.Hash(make([]byte, 32)).Pretty(),
+			"logIndex":         "0x0",
+			"removed":          false,
+		}
+	}
+
+	txHash := eth.Hash(bytes.Repeat([]byte{0xab}, 32)).Pretty()
+	chain := &fakeLogsChain{logs: map[uint64][]map[string]any{
+		3: {ravCollectedLog(3, txHash, 1000)},
+		5: {ravCollectedLog(5, txHash, 5000)},
+	}}
+	server := httptest.NewServer(chain)
+	defer server.Close()
+	client := rpc.NewClient(server.URL)
+
+	filter := EventFilter{
+		Event:        horizon.RAVCollectedEvent,
+		Address:      collector,
+		Topics:       []any{eth.Hash(rav.CollectionID[:]), rav.Payer},
+		FromBlock:    2,
+		Timeout:      5 * time.Second,
+		PollInterval: time.Millisecond,
+	}
+
+	event, err := WaitForEvent(context.Background(), client, filter, func(event *Event) bool {
+		return event.Args["valueAggregate"].(*big.Int).Cmp(rav.ValueAggregate) == 0
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "RAVCollected", event.Name)
+	assert.Equal(t, uint64(5), uint64(event.Log.BlockNumber))
+	assert.Equal(t, rav.TimestampNs, event.Args["timestampNs"])
+	assert.Equal(t, rav.ServiceProvider.Pretty(), event.Args["serviceProvider"].(eth.Address).Pretty())
+	assert.Equal(t, rav.Payer.Pretty(), event.Args["payer"].(eth.Address).Pretty())
+	assert.Equal(t, []byte{0xaa}, event.Args["signature"])
+
+	filter.Timeout = 50 * time.Millisecond
+	_, err = WaitForEvent(context.Background(), client, filter, func(event *Event) bool {
+		return false
+	})
+	assert.ErrorIs(t, err, ErrEventTimeout)
+}
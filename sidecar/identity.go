@@ -0,0 +1,66 @@
+package sidecar
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/streamingfast/eth-go"
+)
+
+// IdentityChallengeLength is the length of provider identity challenges
+const IdentityChallengeLength = 32
+
+// ErrIdentityMismatch is returned when an identity challenge is not signed by
+// the expected service provider
+var ErrIdentityMismatch = errors.New("identity challenge not signed by the expected service provider")
+
+// identityChallengeTag separates identity signatures from any other personal
+// message signed with the service provider key
+var identityChallengeTag = []byte("substreams-data-service/provider-identity/v1")
+
+// NewIdentityChallenge returns a random challenge a provider must sign to prove
+// it holds the service provider key
+func NewIdentityChallenge() ([]byte, error) {
+	challenge := make([]byte, IdentityChallengeLength)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, fmt.Errorf("generating identity challenge: %w", err)
+	}
+	return challenge, nil
+}
+
+// SignIdentityChallenge signs challenge for serviceProvider with key, as an
+// EIP-191 personal message so hardware and remote signers can produce it
+func SignIdentityChallenge(key *eth.PrivateKey, challenge []byte, serviceProvider eth.Address) (eth.Signature, error) {
+	data, err := identityChallengeData(challenge, serviceProvider)
+	if err != nil {
+		return eth.Signature{}, err
+	}
+	return key.SignPersonal(data)
+}
+
+// VerifyIdentityChallenge checks that signature is serviceProvider's signature
+// of challenge
+func VerifyIdentityChallenge(challenge []byte, serviceProvider eth.Address, signature eth.Signature) error {
+	data, err := identityChallengeData(challenge, serviceProvider)
+	if err != nil {
+		return err
+	}
+
+	signer, err := signature.RecoverPersonal(data)
+	if err != nil {
+		return fmt.Errorf("recovering identity signer: %w", err)
+	}
+	if !bytes.Equal(signer, serviceProvider) {
+		return fmt.Errorf("%w: signed by %s, expected %s", ErrIdentityMismatch, signer.Pretty(), serviceProvider.Pretty())
+	}
+	return nil
+}
+
+func identityChallengeData(challenge []byte, serviceProvider eth.Address) (eth.Hex, error) {
+	if len(challenge) != IdentityChallengeLength {
+		return nil, fmt.Errorf("identity challenge must be %d bytes, got %d", IdentityChallengeLength, len(challenge))
+	}
+	return eth.Hex(eth.Keccak256(identityChallengeTag, challenge, serviceProvider)), nil
+}
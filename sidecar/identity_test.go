@@ -0,0 +1,36 @@
+package sidecar
+
+import (
+	"testing"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityChallenge(t *testing.T) {
+	key, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	serviceProvider := key.PublicKey().Address()
+
+	challenge, err := NewIdentityChallenge()
+	require.NoError(t, err)
+	require.Len(t, challenge, IdentityChallengeLength)
+
+	signature, err := SignIdentityChallenge(key, challenge, serviceProvider)
+	require.NoError(t, err)
+	require.NoError(t, VerifyIdentityChallenge(challenge, serviceProvider, signature))
+
+	// Signature for another challenge or claimed service provider does not verify
+	other, err := NewIdentityChallenge()
+	require.NoError(t, err)
+	assert.ErrorIs(t, VerifyIdentityChallenge(other, serviceProvider, signature), ErrIdentityMismatch)
+
+	impostor := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+	signature, err = SignIdentityChallenge(key, challenge, impostor)
+	require.NoError(t, err)
+	assert.ErrorIs(t, VerifyIdentityChallenge(challenge, impostor, signature), ErrIdentityMismatch)
+
+	_, err = SignIdentityChallenge(key, challenge[:16], serviceProvider)
+	assert.Error(t, err)
+}
@@ -0,0 +1,41 @@
+package sidecar
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// PaymentHeader is the request header (gRPC metadata key) carrying the signed
+// RAV paying for a Substreams request, sent by clients on Blocks() and handed
+// to the provider sidecar by the provider (ValidatePayment)
+const PaymentHeader = "payment"
+
+// EncodePaymentHeader encodes rav as a payment header value, the standard
+// base64 encoding of its protobuf serialization
+func EncodePaymentHeader(rav *commonv1.SignedRAV) (string, error) {
+	data, err := proto.Marshal(rav)
+	if err != nil {
+		return "", fmt.Errorf("encoding payment RAV: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// DecodePaymentHeader decodes the signed RAV of a payment header value
+func DecodePaymentHeader(value string) (*commonv1.SignedRAV, error) {
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("decoding payment header: %w", err)
+	}
+
+	rav := &commonv1.SignedRAV{}
+	if err := proto.Unmarshal(data, rav); err != nil {
+		return nil, fmt.Errorf("decoding payment RAV: %w", err)
+	}
+	if rav.Rav == nil {
+		return nil, fmt.Errorf("decoding payment RAV: missing RAV")
+	}
+	return rav, nil
+}
@@ -0,0 +1,70 @@
+package sidecar
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+)
+
+// PaymentTypes selects the IGraphPayments payment type RAVs are collected
+// under: the one of their collection when listed in Collections, Default
+// otherwise. A nil PaymentTypes collects everything as query fees.
+type PaymentTypes struct {
+	Default     uint8
+	Collections map[horizon.CollectionID]uint8
+}
+
+// ParsePaymentTypes parses the default payment type and per collection
+// overrides given as "<collection-id>=<payment-type>", payment types being
+// parsed by horizon.ParsePaymentType. Every payment type must be accepted by
+// the data service (horizon.ValidateDataServicePaymentType).
+func ParsePaymentTypes(defaultType string, collections []string) (*PaymentTypes, error) {
+	paymentType, err := parseDataServicePaymentType(defaultType)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &PaymentTypes{Default: paymentType, Collections: make(map[horizon.CollectionID]uint8, len(collections))}
+	for _, entry := range collections {
+		collection, value, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid collection payment type %q, expected <collection-id>=<payment-type>", entry)
+		}
+
+		id, err := eth.NewHex(strings.TrimSpace(collection))
+		if err != nil || len(id) != len(horizon.CollectionID{}) {
+			return nil, fmt.Errorf("invalid collection ID %q, expected %d bytes in hex", collection, len(horizon.CollectionID{}))
+		}
+		var collectionID horizon.CollectionID
+		copy(collectionID[:], id)
+
+		if out.Collections[collectionID], err = parseDataServicePaymentType(value); err != nil {
+			return nil, fmt.Errorf("collection %s: %w", collection, err)
+		}
+	}
+	return out, nil
+}
+
+func parseDataServicePaymentType(value string) (uint8, error) {
+	paymentType, err := horizon.ParsePaymentType(value)
+	if err != nil {
+		return 0, err
+	}
+	if err := horizon.ValidateDataServicePaymentType(paymentType); err != nil {
+		return 0, err
+	}
+	return paymentType, nil
+}
+
+// Of returns the payment type RAVs of collectionID are collected under
+func (p *PaymentTypes) Of(collectionID horizon.CollectionID) uint8 {
+	if p == nil {
+		return horizon.PaymentTypeQueryFee
+	}
+	if paymentType, found := p.Collections[collectionID]; found {
+		return paymentType
+	}
+	return p.Default
+}
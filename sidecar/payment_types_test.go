@@ -0,0 +1,32 @@
+package sidecar
+
+import (
+	"testing"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePaymentTypes(t *testing.T) {
+	collection := "0xaa00000000000000000000000000000000000000000000000000000000000000"
+
+	paymentTypes, err := ParsePaymentTypes("query-fee", []string{collection + "=0"})
+	require.NoError(t, err)
+	assert.Equal(t, horizon.PaymentTypeQueryFee, paymentTypes.Of(horizon.CollectionID{0xaa}))
+	assert.Equal(t, horizon.PaymentTypeQueryFee, paymentTypes.Of(horizon.CollectionID{0xbb}))
+
+	var unset *PaymentTypes
+	assert.Equal(t, horizon.PaymentTypeQueryFee, unset.Of(horizon.CollectionID{0xaa}))
+
+	// SubstreamsDataService only collects query fees
+	_, err = ParsePaymentTypes("indexing-fee", nil)
+	assert.ErrorIs(t, err, horizon.ErrUnsupportedPaymentType)
+	_, err = ParsePaymentTypes("query-fee", []string{collection + "=indexing-rewards"})
+	assert.ErrorIs(t, err, horizon.ErrUnsupportedPaymentType)
+
+	_, err = ParsePaymentTypes("query-fee", []string{collection})
+	assert.Error(t, err)
+	_, err = ParsePaymentTypes("query-fee", []string{"0xaa=query-fee"})
+	assert.Error(t, err)
+}
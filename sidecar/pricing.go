@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
 	"gopkg.in/yaml.v3"
 )
 
@@ -136,6 +137,22 @@ type PricingConfig struct {
 	// YAML fields (strings for human-readable decimal values)
 	PricePerBlockStr string `yaml:"price_per_block"`
 	PricePerByteStr  string `yaml:"price_per_byte"`
+
+	// Categories prices usage categories (e.g. "cached" or "egress")
+	// differently from the base prices, by category name
+	Categories map[string]*CategoryPricing `yaml:"categories,omitempty"`
+}
+
+// CategoryPricing holds the prices of a usage category, a price left empty is
+// the base one of the PricingConfig
+type CategoryPricing struct {
+	// PricePerBlock is the price per processed block in GRT, nil when not set
+	PricePerBlock *Price `yaml:"-"`
+	// PricePerByte is the price per byte transferred in GRT, nil when not set
+	PricePerByte *Price `yaml:"-"`
+
+	PricePerBlockStr string `yaml:"price_per_block,omitempty"`
+	PricePerByteStr  string `yaml:"price_per_byte,omitempty"`
 }
 
 // LoadPricingConfig loads pricing configuration from a YAML file
@@ -167,6 +184,22 @@ func ParsePricingConfig(data []byte) (*PricingConfig, error) {
 		return nil, fmt.Errorf("invalid price_per_byte: %w", err)
 	}
 
+	for name, category := range config.Categories {
+		if category == nil {
+			return nil, fmt.Errorf("category %q has no prices", name)
+		}
+		if category.PricePerBlockStr != "" {
+			if category.PricePerBlock, err = NewPriceFromDecimal(category.PricePerBlockStr); err != nil {
+				return nil, fmt.Errorf("invalid price_per_block of category %q: %w", name, err)
+			}
+		}
+		if category.PricePerByteStr != "" {
+			if category.PricePerByte, err = NewPriceFromDecimal(category.PricePerByteStr); err != nil {
+				return nil, fmt.Errorf("invalid price_per_byte of category %q: %w", name, err)
+			}
+		}
+	}
+
 	return &config, nil
 }
 
@@ -185,6 +218,48 @@ func (c *PricingConfig) CalculateUsageCost(blocksProcessed, bytesTransferred uin
 	return total
 }
 
+// CalculateCategoryCost calculates the cost of usage in the category named
+// name, at the category prices where set and the base prices otherwise
+func (c *PricingConfig) CalculateCategoryCost(name string, blocksProcessed, bytesTransferred uint64) *big.Int {
+	blockPrice, bytePrice := c.PricePerBlock, c.PricePerByte
+	if category := c.Categories[name]; category != nil {
+		if category.PricePerBlock != nil {
+			blockPrice = category.PricePerBlock
+		}
+		if category.PricePerByte != nil {
+			bytePrice = category.PricePerByte
+		}
+	}
+
+	total := big.NewInt(0)
+	if blockPrice != nil {
+		total.Add(total, blockPrice.CalculateCost(blocksProcessed))
+	}
+	if bytePrice != nil {
+		total.Add(total, bytePrice.CalculateCost(bytesTransferred))
+	}
+	return total
+}
+
+// CalculateCategorizedUsageCost calculates the total cost of usage with its
+// category breakdown: the categories priced in Categories at their prices, the
+// rest of the usage at the base prices. Categories without prices (e.g. a
+// module breakdown overlapping the stage one) are only informative, priced
+// categories must not overlap.
+func (c *PricingConfig) CalculateCategorizedUsageCost(usage *commonv1.Usage) *big.Int {
+	total := big.NewInt(0)
+	blocks, bytes := usage.GetBlocksProcessed(), usage.GetBytesTransferred()
+	for _, category := range usage.GetCategories() {
+		if c.Categories[category.Name] == nil {
+			continue
+		}
+		total.Add(total, c.CalculateCategoryCost(category.Name, category.BlocksProcessed, category.BytesTransferred))
+		blocks -= min(blocks, category.BlocksProcessed)
+		bytes -= min(bytes, category.BytesTransferred)
+	}
+	return total.Add(total, c.CalculateUsageCost(blocks, bytes))
+}
+
 // DefaultPricingConfig returns a default pricing configuration
 func DefaultPricingConfig() *PricingConfig {
 	// Default: 0.000001 GRT per block (1 GRT per million blocks)
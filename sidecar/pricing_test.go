@@ -4,6 +4,7 @@ import (
 	"math/big"
 	"testing"
 
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -162,3 +163,36 @@ func TestPricingConfig_CalculateUsageCost(t *testing.T) {
 	maxDiff, _ := new(big.Int).SetString("100000000000000000", 10)
 	assert.True(t, diff.Cmp(maxDiff) < 0, "cost %s should be close to 2 GRT", cost.String())
 }
+
+func TestPricingConfig_CalculateCategorizedUsageCost(t *testing.T) {
+	config, err := ParsePricingConfig([]byte(`
+price_per_block: "0.000000000000000010"
+price_per_byte: "0.000000000000000001"
+categories:
+  cached:
+    price_per_block: "0.000000000000000002"
+  egress:
+    price_per_byte: "0.000000000000000003"
+`))
+	require.NoError(t, err)
+	require.Nil(t, config.Categories["cached"].PricePerByte)
+
+	// A category without prices is priced at the base ones
+	assert.Equal(t, "20", config.CalculateCategoryCost("live", 2, 0).String())
+	assert.Equal(t, "4", config.CalculateCategoryCost("cached", 2, 0).String())
+
+	cost := config.CalculateCategorizedUsageCost(&commonv1.Usage{
+		BlocksProcessed:  10,
+		BytesTransferred: 100,
+		Categories: []*commonv1.UsageCategory{
+			{Name: "cached", BlocksProcessed: 6},
+			{Name: "egress", BytesTransferred: 100},
+			{Name: "module:map_events", BlocksProcessed: 10},
+		},
+	})
+	// 6 cached blocks at 2, 100 egress bytes at 3, the 4 other blocks at 10
+	assert.Equal(t, "352", cost.String())
+
+	_, err = ParsePricingConfig([]byte("categories:\n  cached:\n    price_per_block: \"1.2.3\"\n"))
+	assert.ErrorContains(t, err, `category "cached"`)
+}
@@ -0,0 +1,176 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+	"github.com/streamingfast/eth-go/rpc"
+	"go.uber.org/zap"
+)
+
+var ErrProvisionOutOfBounds = errors.New("pending provision parameters outside of accepted bounds")
+
+var (
+	getProvisionMethod                     = eth.MustNewMethodDef("getProvision(address,address)")
+	acceptProvisionPendingParametersMethod = eth.MustNewMethodDef("acceptProvisionPendingParameters(address,bytes)")
+)
+
+// ProvisionParameters are the provision parameters a verifier (the data
+// service) requires from the service provider
+type ProvisionParameters struct {
+	// MaxVerifierCut is the share of the provision the verifier may slash
+	MaxVerifierCut horizon.PPM
+	// ThawingPeriod is how long provisioned tokens thaw before being withdrawn
+	ThawingPeriod time.Duration
+}
+
+func (p ProvisionParameters) String() string {
+	return fmt.Sprintf("max_verifier_cut=%s thawing_period=%s", p.MaxVerifierCut, p.ThawingPeriod)
+}
+
+// Provision is the service provider provision to the data service as held by
+// HorizonStaking, Pending differs from Current while parameter changes await
+// acceptance
+type Provision struct {
+	Tokens  *big.Int
+	Current ProvisionParameters
+	Pending ProvisionParameters
+}
+
+// HasPendingParameters reports whether parameter changes await acceptance
+func (p *Provision) HasPendingParameters() bool {
+	return p.Pending != p.Current
+}
+
+// ProvisionBounds are the provision parameters an operator agrees to accept
+// without review
+type ProvisionBounds struct {
+	// MaxVerifierCut is the highest verifier cut accepted
+	MaxVerifierCut horizon.PPM
+	// MaxThawingPeriod is the longest thawing period accepted
+	MaxThawingPeriod time.Duration
+}
+
+// Check returns ErrProvisionOutOfBounds when params fall outside of the bounds
+func (b *ProvisionBounds) Check(params ProvisionParameters) error {
+	if params.MaxVerifierCut > b.MaxVerifierCut {
+		return fmt.Errorf("%w: max verifier cut %s exceeds %s", ErrProvisionOutOfBounds, params.MaxVerifierCut, b.MaxVerifierCut)
+	}
+	if params.ThawingPeriod > b.MaxThawingPeriod {
+		return fmt.Errorf("%w: thawing period %s exceeds %s", ErrProvisionOutOfBounds, params.ThawingPeriod, b.MaxThawingPeriod)
+	}
+	return nil
+}
+
+// ProvisionAcceptor accepts pending provision parameters on behalf of the
+// service provider through SubstreamsDataService.acceptProvisionPendingParameters,
+// as long as they fall within the operator bounds. The transaction is signed by
+// the service provider or one of its operators.
+type ProvisionAcceptor struct {
+	rpcClient       *rpc.Client
+	chainID         uint64
+	staking         eth.Address
+	dataService     eth.Address
+	serviceProvider eth.Address
+	key             *eth.PrivateKey
+	bounds          *ProvisionBounds
+	logger          *zap.Logger
+}
+
+// NewProvisionAcceptor creates a provision acceptor, staking is the
+// HorizonStaking contract holding the provision of serviceProvider to dataService
+func NewProvisionAcceptor(rpcEndpoint string, chainID uint64, staking, dataService, serviceProvider eth.Address, key *eth.PrivateKey, bounds *ProvisionBounds, logger *zap.Logger) *ProvisionAcceptor {
+	return &ProvisionAcceptor{
+		rpcClient:       rpc.NewClient(rpcEndpoint),
+		chainID:         chainID,
+		staking:         staking,
+		dataService:     dataService,
+		serviceProvider: serviceProvider,
+		key:             key,
+		bounds:          bounds,
+		logger:          logger,
+	}
+}
+
+// Provision reads the service provider provision (HorizonStaking.getProvision)
+func (a *ProvisionAcceptor) Provision(ctx context.Context) (*Provision, error) {
+	data, err := getProvisionMethod.NewCall(a.serviceProvider, a.dataService).Encode()
+	if err != nil {
+		return nil, fmt.Errorf("encoding getProvision call: %w", err)
+	}
+
+	resultHex, err := a.rpcClient.Call(ctx, rpc.CallParams{To: a.staking, Data: data})
+	if err != nil {
+		return nil, fmt.Errorf("calling getProvision: %w", err)
+	}
+
+	result, err := eth.NewHex(resultHex)
+	if err != nil {
+		return nil, fmt.Errorf("decoding getProvision result: %w", err)
+	}
+
+	// Provision is a static struct: tokens, tokensThawing, sharesThawing,
+	// maxVerifierCut, thawingPeriod, createdAt, maxVerifierCutPending,
+	// thawingPeriodPending, lastParametersStagedAt, thawingNonce
+	if len(result) != 10*32 {
+		return nil, fmt.Errorf("unexpected getProvision result length: %d", len(result))
+	}
+	word := func(i int) *big.Int {
+		return new(big.Int).SetBytes(result[i*32 : (i+1)*32])
+	}
+
+	return &Provision{
+		Tokens: word(0),
+		Current: ProvisionParameters{
+			MaxVerifierCut: horizon.PPM(word(3).Uint64()),
+			ThawingPeriod:  time.Duration(word(4).Uint64()) * time.Second,
+		},
+		Pending: ProvisionParameters{
+			MaxVerifierCut: horizon.PPM(word(6).Uint64()),
+			ThawingPeriod:  time.Duration(word(7).Uint64()) * time.Second,
+		},
+	}, nil
+}
+
+// AcceptPending accepts the pending provision parameters when there are some
+// and they fall within the bounds, ErrProvisionOutOfBounds is returned when
+// they do not. It returns the provision read before accepting and the hash of
+// the mined acceptance transaction, empty when nothing was pending.
+func (a *ProvisionAcceptor) AcceptPending(ctx context.Context) (*Provision, string, error) {
+	provision, err := a.Provision(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if !provision.HasPendingParameters() {
+		return provision, "", nil
+	}
+	if err := a.bounds.Check(provision.Pending); err != nil {
+		return provision, "", err
+	}
+
+	calldata, err := acceptProvisionPendingParametersMethod.NewCall(a.serviceProvider, []byte{}).Encode()
+	if err != nil {
+		return provision, "", fmt.Errorf("encoding acceptProvisionPendingParameters call: %w", err)
+	}
+
+	gas, gasPrice, err := estimateGas(ctx, a.rpcClient, rpc.CallParams{From: a.key.PublicKey().Address(), To: a.dataService, Data: calldata}, "provision acceptance")
+	if err != nil {
+		return provision, "", err
+	}
+
+	txHash, err := sendTransaction(ctx, a.rpcClient, a.chainID, a.key, a.dataService, calldata, gas, gasPrice, "provision acceptance", a.logger)
+	if err != nil {
+		return provision, "", err
+	}
+	a.logger.Info("provision acceptance transaction sent", zap.String("tx_hash", txHash), zap.Stringer("pending", provision.Pending))
+
+	if _, err := waitForReceipt(ctx, a.rpcClient, txHash, "provision acceptance"); err != nil {
+		return provision, txHash, err
+	}
+	return provision, txHash, nil
+}
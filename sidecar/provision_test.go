@@ -0,0 +1,81 @@
+package sidecar
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestProvisionBounds_Check(t *testing.T) {
+	bounds := &ProvisionBounds{MaxVerifierCut: 100_000, MaxThawingPeriod: 14 * 24 * time.Hour}
+
+	assert.NoError(t, bounds.Check(ProvisionParameters{MaxVerifierCut: 100_000, ThawingPeriod: 14 * 24 * time.Hour}))
+	assert.ErrorIs(t, bounds.Check(ProvisionParameters{MaxVerifierCut: 100_001}), ErrProvisionOutOfBounds)
+	assert.ErrorIs(t, bounds.Check(ProvisionParameters{ThawingPeriod: 15 * 24 * time.Hour}), ErrProvisionOutOfBounds)
+}
+
+func TestProvisionAcceptor_AcceptPending(t *testing.T) {
+	provisionResult := func(cut, thawing, cutPending, thawingPending uint64) string {
+		words := []uint64{1000, 0, 0, cut, thawing, 0, cutPending, thawingPending, 0, 0}
+		var out strings.Builder
+		out.WriteString("0x")
+		for _, word := range words {
+			fmt.Fprintf(&out, "%064x", word)
+		}
+		return out.String()
+	}
+
+	tests := []struct {
+		name        string
+		result      string
+		expectedErr error
+		pending     bool
+	}{
+		{"nothing pending", provisionResult(50_000, 3600, 50_000, 3600), nil, false},
+		{"pending out of bounds", provisionResult(50_000, 3600, 500_000, 3600), ErrProvisionOutOfBounds, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var methods []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req struct {
+					ID     json.RawMessage `json:"id"`
+					Method string          `json:"method"`
+				}
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+				methods = append(methods, req.Method)
+
+				fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"%s"}`, req.ID, tt.result)
+			}))
+			defer server.Close()
+
+			address := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
+			bounds := &ProvisionBounds{MaxVerifierCut: 100_000, MaxThawingPeriod: 24 * time.Hour}
+			acceptor := NewProvisionAcceptor(server.URL, 1337, address, address, address, nil, bounds, zap.NewNop())
+
+			provision, txHash, err := acceptor.AcceptPending(t.Context())
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Empty(t, txHash)
+			assert.Equal(t, big.NewInt(1000), provision.Tokens)
+			assert.Equal(t, ProvisionParameters{MaxVerifierCut: 50_000, ThawingPeriod: time.Hour}, provision.Current)
+			assert.Equal(t, tt.pending, provision.HasPendingParameters())
+			assert.Equal(t, []string{"eth_call"}, methods, "no transaction must be sent")
+		})
+	}
+}
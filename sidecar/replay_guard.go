@@ -0,0 +1,89 @@
+package sidecar
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+)
+
+// DefaultReplayWindow is how long a session-initiating RAV is remembered
+const DefaultReplayWindow = time.Hour
+
+// ReplayGuard remembers which session each session-initiating RAV opened so that
+// re-sending the same RAV does not open unlimited sessions. Entries expire after
+// the configured window.
+type ReplayGuard struct {
+	mu        sync.Mutex
+	window    time.Duration
+	seen      map[string]replayEntry
+	lastPrune time.Time
+
+	now func() time.Time
+}
+
+type replayEntry struct {
+	sessionID string
+	expiresAt time.Time
+}
+
+// NewReplayGuard creates a guard remembering RAVs for window, DefaultReplayWindow
+// is used when window is zero
+func NewReplayGuard(window time.Duration) *ReplayGuard {
+	if window <= 0 {
+		window = DefaultReplayWindow
+	}
+
+	return &ReplayGuard{
+		window: window,
+		seen:   make(map[string]replayEntry),
+		now:    time.Now,
+	}
+}
+
+// ReplayKey identifies a session-initiating RAV by (payer, collection, RAV digest)
+func ReplayKey(payer eth.Address, collectionID horizon.CollectionID, ravDigest eth.Hash) string {
+	return fmt.Sprintf("%s/%x/%x", payer.Pretty(), collectionID[:], ravDigest[:])
+}
+
+// Claim records that key opened sessionID. If key was already claimed within
+// the window, the original session ID is returned with claimed false and the
+// guard is left untouched.
+func (g *ReplayGuard) Claim(key, sessionID string) (existingSessionID string, claimed bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	g.pruneLocked(now)
+
+	if entry, found := g.seen[key]; found && now.Before(entry.expiresAt) {
+		return entry.sessionID, false
+	}
+
+	g.seen[key] = replayEntry{sessionID: sessionID, expiresAt: now.Add(g.window)}
+	return sessionID, true
+}
+
+// Len returns the number of remembered RAVs, expired ones included until pruned
+func (g *ReplayGuard) Len() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return len(g.seen)
+}
+
+// pruneLocked drops expired entries, at most once per minute
+func (g *ReplayGuard) pruneLocked(now time.Time) {
+	if now.Sub(g.lastPrune) < time.Minute {
+		return
+	}
+	g.lastPrune = now
+
+	for key, entry := range g.seen {
+		if !now.Before(entry.expiresAt) {
+			delete(g.seen, key)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package sidecar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplayGuard_Claim(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	g := NewReplayGuard(time.Hour)
+	g.now = func() time.Time { return now }
+
+	payer := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
+	key := ReplayKey(payer, horizon.CollectionID{0x01}, eth.Hash(make([]byte, 32)))
+
+	sessionID, claimed := g.Claim(key, "session-1")
+	assert.True(t, claimed)
+	assert.Equal(t, "session-1", sessionID)
+
+	sessionID, claimed = g.Claim(key, "session-2")
+	assert.False(t, claimed)
+	assert.Equal(t, "session-1", sessionID)
+
+	otherKey := ReplayKey(payer, horizon.CollectionID{0x02}, eth.Hash(make([]byte, 32)))
+	_, claimed = g.Claim(otherKey, "session-3")
+	assert.True(t, claimed)
+
+	now = now.Add(time.Hour)
+	sessionID, claimed = g.Claim(key, "session-4")
+	assert.True(t, claimed)
+	assert.Equal(t, "session-4", sessionID)
+}
+
+func TestReplayGuard_Prune(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	g := NewReplayGuard(time.Minute)
+	g.now = func() time.Time { return now }
+
+	g.Claim("a", "session-a")
+	g.Claim("b", "session-b")
+	assert.Equal(t, 2, g.Len())
+
+	now = now.Add(2 * time.Minute)
+	g.Claim("c", "session-c")
+	assert.Equal(t, 1, g.Len())
+}
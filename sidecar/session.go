@@ -3,6 +3,8 @@ package sidecar
 import (
 	"fmt"
 	"math/big"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,6 +23,20 @@ const (
 	SessionStateEnded
 )
 
+// String returns the lower-case name of the session state
+func (s SessionState) String() string {
+	switch s {
+	case SessionStateActive:
+		return "active"
+	case SessionStatePaused:
+		return "paused"
+	case SessionStateEnded:
+		return "ended"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
 // Session represents an active payment session
 type Session struct {
 	mu sync.RWMutex
@@ -36,6 +52,19 @@ type Session struct {
 	Payer       eth.Address
 	Receiver    eth.Address // Service provider
 	DataService eth.Address
+	Collector   eth.Address // Collector contract, nil when not known
+
+	// ProviderEndpoint is the provider endpoint the consumer streams from,
+	// empty when not known
+	ProviderEndpoint string
+
+	// PaymentMode is how usage is paid for, with a RAV per usage report when
+	// unspecified
+	PaymentMode commonv1.PaymentMode
+	// ReceiptsValue is the total value of the receipts signed (consumer) or
+	// accepted (provider) for the session in PAYMENT_MODE_RECEIPTS, nil when
+	// none
+	ReceiptsValue *big.Int
 
 	// Current RAV state
 	CurrentRAV *horizon.SignedRAV
@@ -46,12 +75,35 @@ type Session struct {
 	Requests         uint64
 	TotalCost        *big.Int
 
+	// Per instance usage breakdown, for usage reported by identified instances
+	Instances map[string]*InstanceUsage
+	// Per category usage breakdown, for usage reported with categories
+	Categories map[string]*CategoryUsage
+
+	// Settlement of the session, set by the provider once it ended
+	Settlement *Settlement
+	// ConsumerSettlement of the session, set by the consumer once it ended
+	ConsumerSettlement *ConsumerSettlement
+
 	// Price configuration (set by provider)
 	PricePerBlock *big.Int
 	PricePerByte  *big.Int
 	PricingConfig *PricingConfig
 }
 
+// InstanceUsage is the usage reported for a session by a single provider
+// instance, when several instances (e.g. tier2 pods behind a load balancer)
+// serve the same session through one sidecar
+type InstanceUsage struct {
+	InstanceID       string
+	BlocksProcessed  uint64
+	BytesTransferred uint64
+	Requests         uint64
+	TotalCost        *big.Int
+	Reports          uint64
+	LastReportAt     time.Time
+}
+
 // NewSession creates a new session with a generated ID
 func NewSession(payer, receiver, dataService eth.Address) *Session {
 	return &Session{
@@ -69,16 +121,62 @@ func NewSession(payer, receiver, dataService eth.Address) *Session {
 
 // AddUsage adds usage to the session and returns the updated total cost
 func (s *Session) AddUsage(blocks, bytes, requests uint64, cost *big.Int) {
+	s.AddInstanceUsage("", blocks, bytes, requests, cost)
+}
+
+// AddInstanceUsage adds usage reported by the given provider instance to the
+// session totals and to the instance breakdown. An empty instanceID only
+// updates the session totals.
+func (s *Session) AddInstanceUsage(instanceID string, blocks, bytes, requests uint64, cost *big.Int) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	now := time.Now()
 	s.BlocksProcessed += blocks
 	s.BytesTransferred += bytes
 	s.Requests += requests
 	if cost != nil {
 		s.TotalCost = new(big.Int).Add(s.TotalCost, cost)
 	}
-	s.UpdatedAt = time.Now()
+	s.UpdatedAt = now
+
+	if instanceID == "" {
+		return
+	}
+
+	if s.Instances == nil {
+		s.Instances = make(map[string]*InstanceUsage)
+	}
+	instance, ok := s.Instances[instanceID]
+	if !ok {
+		instance = &InstanceUsage{InstanceID: instanceID, TotalCost: big.NewInt(0)}
+		s.Instances[instanceID] = instance
+	}
+
+	instance.BlocksProcessed += blocks
+	instance.BytesTransferred += bytes
+	instance.Requests += requests
+	if cost != nil {
+		instance.TotalCost = new(big.Int).Add(instance.TotalCost, cost)
+	}
+	instance.Reports++
+	instance.LastReportAt = now
+}
+
+// GetInstanceUsage returns a copy of the per instance usage breakdown, ordered
+// by instance ID
+func (s *Session) GetInstanceUsage() []InstanceUsage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	instances := make([]InstanceUsage, 0, len(s.Instances))
+	for _, instance := range s.Instances {
+		instances = append(instances, *instance)
+	}
+	slices.SortFunc(instances, func(a, b InstanceUsage) int {
+		return strings.Compare(a.InstanceID, b.InstanceID)
+	})
+	return instances
 }
 
 // GetUsage returns a copy of the current usage
@@ -86,12 +184,16 @@ func (s *Session) GetUsage() *commonv1.Usage {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	return &commonv1.Usage{
+	usage := &commonv1.Usage{
 		BlocksProcessed:  s.BlocksProcessed,
 		BytesTransferred: s.BytesTransferred,
 		Requests:         s.Requests,
 		Cost:             commonv1.BigIntFromNative(s.TotalCost),
 	}
+	for _, category := range sortedCategories(s.Categories) {
+		usage.Categories = append(usage.Categories, category.toProto())
+	}
+	return usage
 }
 
 // SetRAV updates the current RAV
@@ -111,6 +213,39 @@ func (s *Session) GetRAV() *horizon.SignedRAV {
 	return s.CurrentRAV
 }
 
+// PaysWithReceipts returns true if usage of the session is paid for with
+// receipts aggregated by the provider rather than with RAVs
+func (s *Session) PaysWithReceipts() bool {
+	return s.PaymentMode == commonv1.PaymentMode_PAYMENT_MODE_RECEIPTS
+}
+
+// AddReceiptValue adds the value of a receipt to the session and returns the
+// new receipts total
+func (s *Session) AddReceiptValue(value *big.Int) *big.Int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := new(big.Int).Set(value)
+	if s.ReceiptsValue != nil {
+		total.Add(total, s.ReceiptsValue)
+	}
+	s.ReceiptsValue = total
+	s.UpdatedAt = time.Now()
+	return new(big.Int).Set(total)
+}
+
+// GetReceiptsValue returns the total value of the session's receipts, zero
+// when none
+func (s *Session) GetReceiptsValue() *big.Int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.ReceiptsValue == nil {
+		return big.NewInt(0)
+	}
+	return new(big.Int).Set(s.ReceiptsValue)
+}
+
 // End marks the session as ended
 func (s *Session) End(reason commonv1.EndReason) {
 	s.mu.Lock()
@@ -123,6 +258,65 @@ func (s *Session) End(reason commonv1.EndReason) {
 	s.UpdatedAt = now
 }
 
+// Resume reactivates a session ended with one of reasons, e.g. interrupted by
+// a consumer crash, keeping its usage and current RAV. Active sessions are left
+// as is. Returns false when the session ended for another reason.
+func (s *Session) Resume(reasons ...commonv1.EndReason) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.State == SessionStateActive {
+		return true
+	}
+	if s.State != SessionStateEnded || !slices.Contains(reasons, s.EndReason) {
+		return false
+	}
+
+	s.State = SessionStateActive
+	s.EndedAt = nil
+	s.EndReason = commonv1.EndReason_END_REASON_UNSPECIFIED
+	s.UpdatedAt = time.Now()
+	return true
+}
+
+// SessionSnapshot is a consistent copy of the lifecycle of a session
+type SessionSnapshot struct {
+	State     SessionState
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	EndedAt   *time.Time
+	EndReason commonv1.EndReason
+	Collector eth.Address
+}
+
+// Snapshot returns a copy of the lifecycle of the session, read under the
+// session lock so it does not race with End, Resume or usage updates
+func (s *Session) Snapshot() SessionSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := SessionSnapshot{
+		State:     s.State,
+		CreatedAt: s.CreatedAt,
+		UpdatedAt: s.UpdatedAt,
+		EndReason: s.EndReason,
+		Collector: s.Collector,
+	}
+	if s.EndedAt != nil {
+		endedAt := *s.EndedAt
+		snapshot.EndedAt = &endedAt
+	}
+	return snapshot
+}
+
+// LastActivity returns when the session was last updated (usage, RAV or state)
+func (s *Session) LastActivity() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.UpdatedAt
+}
+
 // IsActive returns true if the session is active
 func (s *Session) IsActive() bool {
 	s.mu.RLock()
@@ -180,12 +374,25 @@ func (s *Session) ToSessionInfo() *commonv1.SessionInfo {
 			Payer:       commonv1.AddressFromEth(s.Payer),
 			Receiver:    commonv1.AddressFromEth(s.Receiver),
 			DataService: commonv1.AddressFromEth(s.DataService),
+			Collector:   collectorToProto(s.Collector),
 		},
 		CurrentRav:       HorizonSignedRAVToProto(s.CurrentRAV),
 		AccumulatedUsage: s.GetUsage(),
 	}
 }
 
+// EscrowAccount returns the escrow account the session is paid from
+func (s *Session) EscrowAccount() EscrowAccountKey {
+	return EscrowAccountKey{Payer: s.Payer, Collector: s.Collector, Receiver: s.Receiver}
+}
+
+func collectorToProto(collector eth.Address) *commonv1.Address {
+	if collector == nil {
+		return nil
+	}
+	return commonv1.AddressFromEth(collector)
+}
+
 // SessionManager manages active sessions
 type SessionManager struct {
 	mu       sync.RWMutex
@@ -201,7 +408,14 @@ func NewSessionManager() *SessionManager {
 
 // Create creates and stores a new session
 func (sm *SessionManager) Create(payer, receiver, dataService eth.Address) *Session {
+	return sm.CreateWithCollector(payer, receiver, dataService, nil)
+}
+
+// CreateWithCollector creates and stores a new session paid from the escrow
+// account of the given collector
+func (sm *SessionManager) CreateWithCollector(payer, receiver, dataService, collector eth.Address) *Session {
 	session := NewSession(payer, receiver, dataService)
+	session.Collector = collector
 
 	sm.mu.Lock()
 	sm.sessions[session.ID] = session
@@ -229,6 +443,23 @@ func (sm *SessionManager) Delete(id string) {
 	sm.mu.Unlock()
 }
 
+// EndedBefore returns the sessions that ended before cutoff, in no particular
+// order
+func (sm *SessionManager) EndedBefore(cutoff time.Time) []*Session {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	var ended []*Session
+	for _, s := range sm.sessions {
+		s.mu.RLock()
+		if s.State == SessionStateEnded && s.EndedAt != nil && s.EndedAt.Before(cutoff) {
+			ended = append(ended, s)
+		}
+		s.mu.RUnlock()
+	}
+	return ended
+}
+
 // GetActive returns all active sessions
 func (sm *SessionManager) GetActive() []*Session {
 	sm.mu.RLock()
@@ -243,6 +474,21 @@ func (sm *SessionManager) GetActive() []*Session {
 	return active
 }
 
+// List returns all sessions (active and ended) ordered by creation time
+func (sm *SessionManager) List() []*Session {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	sessions := make([]*Session, 0, len(sm.sessions))
+	for _, s := range sm.sessions {
+		sessions = append(sessions, s)
+	}
+	slices.SortFunc(sessions, func(a, b *Session) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return sessions
+}
+
 // Count returns the number of sessions
 func (sm *SessionManager) Count() int {
 	sm.mu.RLock()
@@ -0,0 +1,329 @@
+package sidecar
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	"github.com/streamingfast/eth-go"
+)
+
+// SessionExportVersion is the version of the session export format
+const SessionExportVersion = 1
+
+// Schemas versioning the JSON encoding of signed session exports, the blob
+// exchanged between instances, and of the session state they carry, whose
+// current RAV is encoded canonically since version 2
+var (
+	SignedSessionExportSchema = horizon.NewSchema("session export", "version", SessionExportVersion)
+	SessionExportSchema       = horizon.NewSchema("exported session", "schema_version", 2)
+)
+
+func init() {
+	SessionExportSchema.RegisterMigration(1, horizon.CanonicalJSONMigration)
+}
+
+var (
+	// ErrSessionExportSigner is returned when a session export is not signed by
+	// the service provider importing it
+	ErrSessionExportSigner = errors.New("session export not signed by the expected service provider")
+	// ErrSessionExists is returned when importing a session already known
+	ErrSessionExists = errors.New("session already exists")
+)
+
+// sessionExportTag separates session export signatures from any other personal
+// message signed with the service provider key
+var sessionExportTag = []byte("substreams-data-service/session-export/v1")
+
+// SessionExport is the state of a session moved from one sidecar instance to
+// another: escrow account, usage, pricing and the current RAV, which aggregates
+// every RAV of the session. GRT values are in wei.
+type SessionExport struct {
+	// SchemaVersion is the SessionExportSchema version of the export
+	SchemaVersion int `json:"schema_version"`
+
+	SessionID string             `json:"session_id"`
+	State     SessionState       `json:"state"`
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+	EndedAt   *time.Time         `json:"ended_at,omitempty"`
+	EndReason commonv1.EndReason `json:"end_reason,omitempty"`
+
+	Payer       eth.Address `json:"payer"`
+	Receiver    eth.Address `json:"receiver"`
+	DataService eth.Address `json:"data_service"`
+	Collector   eth.Address `json:"collector,omitempty"`
+
+	// ProviderEndpoint is the provider gateway a consumer session streams from
+	ProviderEndpoint string `json:"provider_endpoint,omitempty"`
+
+	PaymentMode   commonv1.PaymentMode `json:"payment_mode,omitempty"`
+	ReceiptsValue *big.Int             `json:"receipts_value,omitempty"`
+
+	BlocksProcessed  uint64                 `json:"blocks_processed"`
+	BytesTransferred uint64                 `json:"bytes_transferred"`
+	Requests         uint64                 `json:"requests"`
+	TotalCost        *big.Int               `json:"total_cost"`
+	Instances        []*InstanceUsageExport `json:"instances,omitempty"`
+
+	PricePerBlock *big.Int `json:"price_per_block,omitempty"`
+	PricePerByte  *big.Int `json:"price_per_byte,omitempty"`
+
+	CurrentRAV          *horizon.RAV `json:"current_rav,omitempty"`
+	CurrentRAVSignature eth.Hex      `json:"current_rav_signature,omitempty"`
+
+	// Settlement is the settlement of the session once it ended
+	Settlement *Settlement `json:"settlement,omitempty"`
+	// ConsumerSettlement is the consumer settlement of the session once it
+	// ended
+	ConsumerSettlement *ConsumerSettlement `json:"consumer_settlement,omitempty"`
+
+	// CollectTxHash is the transaction that collected the final RAV on-chain,
+	// empty when it was not collected yet
+	CollectTxHash string `json:"collect_tx_hash,omitempty"`
+
+	// PendingReceipts are the receipts of a session in receipts payment mode
+	// not aggregated into the current RAV yet
+	PendingReceipts []*horizon.SignedReceipt `json:"pending_receipts,omitempty"`
+}
+
+// InstanceUsageExport is the usage reported by one provider instance in a
+// session export
+type InstanceUsageExport struct {
+	InstanceID       string    `json:"instance_id"`
+	BlocksProcessed  uint64    `json:"blocks_processed"`
+	BytesTransferred uint64    `json:"bytes_transferred"`
+	Requests         uint64    `json:"requests"`
+	TotalCost        *big.Int  `json:"total_cost"`
+	Reports          uint64    `json:"reports"`
+	LastReportAt     time.Time `json:"last_report_at"`
+}
+
+// SignedSessionExport is a session export signed with the service provider
+// key, the blob exchanged between sidecar instances. The payload is the JSON
+// encoded SessionExport, kept as bytes so the signature survives reformatting.
+type SignedSessionExport struct {
+	Version         int         `json:"version"`
+	ServiceProvider eth.Address `json:"service_provider"`
+	Payload         []byte      `json:"payload"`
+	Signature       eth.Hex     `json:"signature"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, exports of older schema versions
+// are migrated to the current one
+func (s *SignedSessionExport) UnmarshalJSON(data []byte) error {
+	type plain SignedSessionExport
+	return SignedSessionExportSchema.Unmarshal(data, (*plain)(s))
+}
+
+// UnmarshalJSON implements json.Unmarshaler, exported sessions of older schema
+// versions are migrated to the current one
+func (e *SessionExport) UnmarshalJSON(data []byte) error {
+	type plain SessionExport
+	return SessionExportSchema.Unmarshal(data, (*plain)(e))
+}
+
+// NewSessionExport snapshots the state of session
+func NewSessionExport(session *Session) *SessionExport {
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+
+	export := &SessionExport{
+		SchemaVersion:      SessionExportSchema.Version(),
+		SessionID:          session.ID,
+		State:              session.State,
+		CreatedAt:          session.CreatedAt,
+		UpdatedAt:          session.UpdatedAt,
+		EndedAt:            session.EndedAt,
+		EndReason:          session.EndReason,
+		Payer:              session.Payer,
+		Receiver:           session.Receiver,
+		DataService:        session.DataService,
+		Collector:          session.Collector,
+		ProviderEndpoint:   session.ProviderEndpoint,
+		PaymentMode:        session.PaymentMode,
+		ReceiptsValue:      session.ReceiptsValue,
+		BlocksProcessed:    session.BlocksProcessed,
+		BytesTransferred:   session.BytesTransferred,
+		Requests:           session.Requests,
+		TotalCost:          session.TotalCost,
+		PricePerBlock:      session.PricePerBlock,
+		PricePerByte:       session.PricePerByte,
+		Settlement:         session.Settlement,
+		ConsumerSettlement: session.ConsumerSettlement,
+	}
+
+	for _, instance := range session.Instances {
+		export.Instances = append(export.Instances, &InstanceUsageExport{
+			InstanceID:       instance.InstanceID,
+			BlocksProcessed:  instance.BlocksProcessed,
+			BytesTransferred: instance.BytesTransferred,
+			Requests:         instance.Requests,
+			TotalCost:        instance.TotalCost,
+			Reports:          instance.Reports,
+			LastReportAt:     instance.LastReportAt,
+		})
+	}
+	slices.SortFunc(export.Instances, func(a, b *InstanceUsageExport) int {
+		return strings.Compare(a.InstanceID, b.InstanceID)
+	})
+
+	if session.CurrentRAV != nil {
+		export.CurrentRAV = session.CurrentRAV.Message
+		export.CurrentRAVSignature = eth.Hex(session.CurrentRAV.Signature[:])
+	}
+
+	return export
+}
+
+// Sign signs the export with the key of serviceProvider, as an EIP-191
+// personal message so hardware and remote signers can produce it
+func (e *SessionExport) Sign(key *eth.PrivateKey, serviceProvider eth.Address) (*SignedSessionExport, error) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("encoding session export: %w", err)
+	}
+
+	signature, err := key.SignPersonal(sessionExportData(serviceProvider, payload))
+	if err != nil {
+		return nil, fmt.Errorf("signing session export: %w", err)
+	}
+
+	return &SignedSessionExport{
+		Version:         SessionExportVersion,
+		ServiceProvider: serviceProvider,
+		Payload:         payload,
+		Signature:       eth.Hex(signature[:]),
+	}, nil
+}
+
+// Open checks the export is signed by serviceProvider and returns its content
+func (s *SignedSessionExport) Open(serviceProvider eth.Address) (*SessionExport, error) {
+	if s.Version != SessionExportVersion {
+		return nil, fmt.Errorf("unsupported session export version %d, expected %d", s.Version, SessionExportVersion)
+	}
+	if len(s.Signature) != len(eth.Signature{}) {
+		return nil, fmt.Errorf("session export signature must be %d bytes, got %d", len(eth.Signature{}), len(s.Signature))
+	}
+
+	var signature eth.Signature
+	copy(signature[:], s.Signature)
+
+	signer, err := signature.RecoverPersonal(sessionExportData(serviceProvider, s.Payload))
+	if err != nil {
+		return nil, fmt.Errorf("recovering session export signer: %w", err)
+	}
+	if !bytes.Equal(signer, serviceProvider) {
+		return nil, fmt.Errorf("%w: signed by %s, expected %s", ErrSessionExportSigner, signer.Pretty(), serviceProvider.Pretty())
+	}
+
+	var export SessionExport
+	if err := json.Unmarshal(s.Payload, &export); err != nil {
+		return nil, fmt.Errorf("decoding session export: %w", err)
+	}
+	if !bytes.Equal(export.Receiver, serviceProvider) {
+		return nil, fmt.Errorf("session %s is paid to %s, not %s", export.SessionID, export.Receiver.Pretty(), serviceProvider.Pretty())
+	}
+
+	return &export, nil
+}
+
+// Session rebuilds the exported session
+func (e *SessionExport) Session() (*Session, error) {
+	if e.SessionID == "" {
+		return nil, fmt.Errorf("session export has no session ID")
+	}
+
+	session := &Session{
+		ID:                 e.SessionID,
+		State:              e.State,
+		CreatedAt:          e.CreatedAt,
+		UpdatedAt:          e.UpdatedAt,
+		EndedAt:            e.EndedAt,
+		EndReason:          e.EndReason,
+		Payer:              e.Payer,
+		Receiver:           e.Receiver,
+		DataService:        e.DataService,
+		Collector:          e.Collector,
+		ProviderEndpoint:   e.ProviderEndpoint,
+		PaymentMode:        e.PaymentMode,
+		ReceiptsValue:      e.ReceiptsValue,
+		BlocksProcessed:    e.BlocksProcessed,
+		BytesTransferred:   e.BytesTransferred,
+		Requests:           e.Requests,
+		TotalCost:          e.TotalCost,
+		PricePerBlock:      e.PricePerBlock,
+		PricePerByte:       e.PricePerByte,
+		Settlement:         e.Settlement,
+		ConsumerSettlement: e.ConsumerSettlement,
+	}
+	if session.TotalCost == nil {
+		session.TotalCost = big.NewInt(0)
+	}
+	if len(session.Collector) == 0 {
+		session.Collector = nil
+	}
+
+	if e.PricePerBlock != nil || e.PricePerByte != nil {
+		blockPrice, bytePrice := NewPriceFromWei(e.PricePerBlock), NewPriceFromWei(e.PricePerByte)
+		session.PricingConfig = &PricingConfig{
+			PricePerBlock:    blockPrice,
+			PricePerByte:     bytePrice,
+			PricePerBlockStr: blockPrice.ToDecimalString(),
+			PricePerByteStr:  bytePrice.ToDecimalString(),
+		}
+	}
+
+	for _, instance := range e.Instances {
+		if session.Instances == nil {
+			session.Instances = make(map[string]*InstanceUsage, len(e.Instances))
+		}
+		session.Instances[instance.InstanceID] = &InstanceUsage{
+			InstanceID:       instance.InstanceID,
+			BlocksProcessed:  instance.BlocksProcessed,
+			BytesTransferred: instance.BytesTransferred,
+			Requests:         instance.Requests,
+			TotalCost:        instance.TotalCost,
+			Reports:          instance.Reports,
+			LastReportAt:     instance.LastReportAt,
+		}
+	}
+
+	if e.CurrentRAV != nil {
+		if len(e.CurrentRAVSignature) != len(eth.Signature{}) {
+			return nil, fmt.Errorf("current RAV signature must be %d bytes, got %d", len(eth.Signature{}), len(e.CurrentRAVSignature))
+		}
+		if !bytes.Equal(e.CurrentRAV.Payer, e.Payer) || !bytes.Equal(e.CurrentRAV.ServiceProvider, e.Receiver) {
+			return nil, fmt.Errorf("current RAV does not belong to the session escrow account")
+		}
+
+		session.CurrentRAV = &horizon.SignedRAV{Message: e.CurrentRAV}
+		copy(session.CurrentRAV.Signature[:], e.CurrentRAVSignature)
+	}
+
+	return session, nil
+}
+
+// Import stores a session created elsewhere, e.g. rebuilt from a SessionExport,
+// failing with ErrSessionExists when its ID is already known
+func (sm *SessionManager) Import(session *Session) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if _, found := sm.sessions[session.ID]; found {
+		return fmt.Errorf("%w: %s", ErrSessionExists, session.ID)
+	}
+	sm.sessions[session.ID] = session
+	return nil
+}
+
+func sessionExportData(serviceProvider eth.Address, payload []byte) eth.Hex {
+	return eth.Hex(eth.Keccak256(sessionExportTag, serviceProvider, payload))
+}
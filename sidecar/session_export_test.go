@@ -0,0 +1,103 @@
+package sidecar
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionExport_RoundTrip(t *testing.T) {
+	providerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	serviceProvider := providerKey.PublicKey().Address()
+	payer := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
+	dataService := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+
+	session := NewSession(payer, serviceProvider, dataService)
+	session.PaymentMode = commonv1.PaymentMode_PAYMENT_MODE_RECEIPTS
+	session.SetPricingConfig(DefaultPricingConfig())
+	session.AddInstanceUsage("tier2-0", 10, 2048, 1, big.NewInt(500))
+	session.AddReceiptValue(big.NewInt(500))
+	session.SetRAV(&horizon.SignedRAV{
+		Message: &horizon.RAV{
+			CollectionID:    horizon.CollectionID{0x01},
+			Payer:           payer,
+			DataService:     dataService,
+			ServiceProvider: serviceProvider,
+			TimestampNs:     42,
+			ValueAggregate:  big.NewInt(500),
+		},
+		Signature: eth.Signature{0x0a, 0x0b},
+	})
+	session.End(commonv1.EndReason_END_REASON_COMPLETE)
+	settlement, err := NewSettlement(big.NewInt(500), big.NewInt(100), 0, 0, 0)
+	require.NoError(t, err)
+	session.SetSettlement(settlement)
+
+	signed, err := NewSessionExport(session).Sign(providerKey, serviceProvider)
+	require.NoError(t, err)
+
+	// The blob survives a JSON round trip, e.g. saved to a file
+	blob, err := json.MarshalIndent(signed, "", "  ")
+	require.NoError(t, err)
+	var decoded SignedSessionExport
+	require.NoError(t, json.Unmarshal(blob, &decoded))
+
+	export, err := decoded.Open(serviceProvider)
+	require.NoError(t, err)
+
+	imported, err := export.Session()
+	require.NoError(t, err)
+	assert.Equal(t, session.ID, imported.ID)
+	assert.Equal(t, SessionStateEnded, imported.State)
+	assert.Equal(t, commonv1.EndReason_END_REASON_COMPLETE, imported.EndReason)
+	assert.Nil(t, imported.Collector)
+	assert.True(t, imported.PaysWithReceipts())
+	assert.Equal(t, "500", imported.GetReceiptsValue().String())
+	assert.Equal(t, session.GetUsage().String(), imported.GetUsage().String())
+	assert.Equal(t, session.GetInstanceUsage()[0].TotalCost, imported.GetInstanceUsage()[0].TotalCost)
+	assert.Equal(t, session.CalculateUsageCost(1000, 1000), imported.CalculateUsageCost(1000, 1000))
+	assert.Equal(t, session.GetRAV().Signature, imported.GetRAV().Signature)
+	assert.Equal(t, "500", imported.GetRAV().Message.ValueAggregate.String())
+	assert.Equal(t, "400", imported.GetSettlement().PendingDelta.String())
+
+	manager := NewSessionManager()
+	require.NoError(t, manager.Import(imported))
+	assert.ErrorIs(t, manager.Import(imported), ErrSessionExists)
+}
+
+func TestSessionExport_Open(t *testing.T) {
+	providerKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	serviceProvider := providerKey.PublicKey().Address()
+
+	session := NewSession(eth.MustNewAddress("0x1111111111111111111111111111111111111111"), serviceProvider, eth.MustNewAddress("0x2222222222222222222222222222222222222222"))
+	signed, err := NewSessionExport(session).Sign(providerKey, serviceProvider)
+	require.NoError(t, err)
+
+	_, err = signed.Open(eth.MustNewAddress("0x3333333333333333333333333333333333333333"))
+	assert.ErrorIs(t, err, ErrSessionExportSigner)
+
+	tampered := *signed
+	tampered.Payload = []byte(string(signed.Payload[:len(signed.Payload)-1]) + ` `)
+	_, err = tampered.Open(serviceProvider)
+	assert.ErrorIs(t, err, ErrSessionExportSigner)
+
+	otherKey, err := eth.NewRandomPrivateKey()
+	require.NoError(t, err)
+	forged, err := NewSessionExport(session).Sign(otherKey, serviceProvider)
+	require.NoError(t, err)
+	_, err = forged.Open(serviceProvider)
+	assert.ErrorIs(t, err, ErrSessionExportSigner)
+
+	unsupported := *signed
+	unsupported.Version = 2
+	_, err = unsupported.Open(serviceProvider)
+	assert.ErrorContains(t, err, "unsupported session export version")
+}
@@ -0,0 +1,188 @@
+package sidecar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// SessionStore persists payment sessions, with their usage and current RAV,
+// so they survive sidecar restarts. Sessions are stored as session exports,
+// the format sessions are moved between sidecar instances with.
+type SessionStore interface {
+	// Save stores the state of sessions, each one replacing the one stored
+	// under the same session ID, all or none of them being saved
+	Save(sessions ...*SessionExport) error
+	// Delete removes the sessions stored under sessionIDs, unknown ones are
+	// ignored
+	Delete(sessionIDs ...string) error
+	// List returns every stored session
+	List() ([]*SessionExport, error)
+	// Close releases the resources held by the store
+	Close() error
+}
+
+// ErrSessionStoreClosed is returned by the methods of a closed BoltSessionStore
+var ErrSessionStoreClosed = errors.New("session store is closed")
+
+// boltSessionStoreFile is the database file of a BoltSessionStore directory
+const boltSessionStoreFile = "sessions.db"
+
+// boltOpenTimeout bounds how long opening the database waits for the file
+// lock, held by another process using the same store
+const boltOpenTimeout = 5 * time.Second
+
+var sessionsBucket = []byte("sessions")
+
+// BoltSessionStore is a SessionStore keeping sessions in a BoltDB database,
+// keyed by session ID. Every Save or Delete is a single transaction, a crash
+// leaves either the previous or the new state of all the sessions it touches.
+type BoltSessionStore struct {
+	dir string
+
+	mu     sync.Mutex
+	db     *bolt.DB
+	closed bool
+}
+
+// NewBoltSessionStore returns a store keeping sessions in a database of dir,
+// both created on first use when missing
+func NewBoltSessionStore(dir string) *BoltSessionStore {
+	return &BoltSessionStore{dir: dir}
+}
+
+// open returns the database, opening it on first use
+func (s *BoltSessionStore) open() (*bolt.DB, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, ErrSessionStoreClosed
+	}
+	if s.db != nil {
+		return s.db, nil
+	}
+
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating session store directory: %w", err)
+	}
+
+	db, err := bolt.Open(filepath.Join(s.dir, boltSessionStoreFile), 0o600, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("opening session store: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(sessionsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("initializing session store: %w", err)
+	}
+
+	s.db = db
+	return db, nil
+}
+
+func (s *BoltSessionStore) Save(sessions ...*SessionExport) error {
+	if len(sessions) == 0 {
+		return nil
+	}
+
+	values := make([][]byte, len(sessions))
+	for i, session := range sessions {
+		if session.SessionID == "" {
+			return errors.New("session ID is empty")
+		}
+
+		data, err := json.Marshal(session)
+		if err != nil {
+			return fmt.Errorf("encoding session %s: %w", session.SessionID, err)
+		}
+		values[i] = data
+	}
+
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(sessionsBucket)
+		for i, session := range sessions {
+			if err := bucket.Put([]byte(session.SessionID), values[i]); err != nil {
+				return fmt.Errorf("saving session %s: %w", session.SessionID, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BoltSessionStore) Delete(sessionIDs ...string) error {
+	if len(sessionIDs) == 0 {
+		return nil
+	}
+
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(sessionsBucket)
+		for _, sessionID := range sessionIDs {
+			if err := bucket.Delete([]byte(sessionID)); err != nil {
+				return fmt.Errorf("deleting session %s: %w", sessionID, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BoltSessionStore) List() ([]*SessionExport, error) {
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []*SessionExport
+	err = db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(sessionsBucket).ForEach(func(key, value []byte) error {
+			var session SessionExport
+			if err := json.Unmarshal(value, &session); err != nil {
+				return fmt.Errorf("decoding session %s: %w", key, err)
+			}
+			sessions = append(sessions, &session)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(sessions, func(a, b *SessionExport) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return sessions, nil
+}
+
+func (s *BoltSessionStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	if s.db == nil {
+		return nil
+	}
+
+	err := s.db.Close()
+	s.db = nil
+	return err
+}
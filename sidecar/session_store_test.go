@@ -0,0 +1,126 @@
+package sidecar
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStoreTestSession() *Session {
+	return NewSession(
+		eth.MustNewAddress("0x1111111111111111111111111111111111111111"),
+		eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+		eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+	)
+}
+
+func TestBoltSessionStore(t *testing.T) {
+	dir := t.TempDir()
+	store := NewBoltSessionStore(dir)
+
+	sessions, err := store.List()
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	session := newStoreTestSession()
+	other := newStoreTestSession()
+	require.NoError(t, store.Save(NewSessionExport(session), NewSessionExport(other)))
+
+	session.AddUsage(5, 500, 1, big.NewInt(42))
+	require.NoError(t, store.Save(NewSessionExport(session)))
+
+	sessions, err = store.List()
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, session.ID, sessions[0].SessionID)
+	assert.Equal(t, uint64(5), sessions[0].BlocksProcessed)
+	assert.Equal(t, "42", sessions[0].TotalCost.String())
+
+	require.NoError(t, store.Delete(other.ID, "unknown"))
+	require.NoError(t, store.Close())
+	assert.ErrorIs(t, store.Save(NewSessionExport(session)), ErrSessionStoreClosed)
+
+	// Sessions survive reopening the store
+	reopened := NewBoltSessionStore(dir)
+	defer reopened.Close()
+
+	sessions, err = reopened.List()
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, session.ID, sessions[0].SessionID)
+
+	invalid := NewSessionExport(session)
+	invalid.SessionID = ""
+	assert.ErrorContains(t, reopened.Save(invalid), "session ID is empty")
+}
+
+// failingSessionStore fails every Save while failing is set
+type failingSessionStore struct {
+	SessionStore
+	failing bool
+}
+
+func (s *failingSessionStore) Save(sessions ...*SessionExport) error {
+	if s.failing {
+		return errors.New("disk full")
+	}
+	return s.SessionStore.Save(sessions...)
+}
+
+func TestSessionWriter(t *testing.T) {
+	store := &failingSessionStore{SessionStore: NewBoltSessionStore(t.TempDir())}
+	defer store.Close()
+	writer := NewSessionWriter(store)
+
+	session := newStoreTestSession()
+	exports := 0
+	markDirty := func() {
+		writer.MarkDirty(session.ID, func() *SessionExport {
+			exports++
+			return NewSessionExport(session)
+		})
+	}
+
+	// A session changing many times between flushes is written once, in its
+	// latest state
+	markDirty()
+	session.AddUsage(1, 100, 1, big.NewInt(10))
+	markDirty()
+	assert.Equal(t, 1, writer.Pending())
+	require.NoError(t, writer.Flush())
+	assert.Equal(t, 1, exports)
+	assert.Equal(t, 0, writer.Pending())
+
+	stored, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	assert.Equal(t, "10", stored[0].TotalCost.String())
+
+	// Failed writes are retried on the next flush
+	store.failing = true
+	session.AddUsage(1, 100, 1, big.NewInt(5))
+	markDirty()
+	assert.Error(t, writer.Flush())
+	assert.Equal(t, 1, writer.Pending())
+
+	store.failing = false
+	require.NoError(t, writer.Flush())
+	stored, err = store.List()
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	assert.Equal(t, "15", stored[0].TotalCost.String())
+
+	// Deleting drops pending writes
+	markDirty()
+	require.NoError(t, writer.Delete(session.ID))
+	assert.Equal(t, 0, writer.Pending())
+	require.NoError(t, writer.Flush())
+
+	stored, err = store.List()
+	require.NoError(t, err)
+	assert.Empty(t, stored)
+}
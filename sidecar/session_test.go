@@ -51,6 +51,31 @@ func TestSession_AddUsage(t *testing.T) {
 	assert.Equal(t, int64(1500), session.TotalCost.Int64())
 }
 
+func TestSession_AddInstanceUsage(t *testing.T) {
+	payer := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
+	receiver := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	dataService := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+
+	session := NewSession(payer, receiver, dataService)
+	session.AddInstanceUsage("tier2-b", 10, 100, 1, big.NewInt(100))
+	session.AddInstanceUsage("tier2-a", 5, 50, 1, big.NewInt(50))
+	session.AddInstanceUsage("tier2-b", 1, 10, 1, nil)
+	session.AddUsage(2, 20, 1, big.NewInt(20))
+
+	assert.Equal(t, uint64(18), session.BlocksProcessed)
+	assert.Equal(t, int64(170), session.TotalCost.Int64())
+
+	instances := session.GetInstanceUsage()
+	require.Len(t, instances, 2)
+	assert.Equal(t, "tier2-a", instances[0].InstanceID)
+	assert.Equal(t, uint64(5), instances[0].BlocksProcessed)
+	assert.Equal(t, "tier2-b", instances[1].InstanceID)
+	assert.Equal(t, uint64(11), instances[1].BlocksProcessed)
+	assert.Equal(t, uint64(110), instances[1].BytesTransferred)
+	assert.Equal(t, int64(100), instances[1].TotalCost.Int64())
+	assert.Equal(t, uint64(2), instances[1].Reports)
+}
+
 func TestSession_GetUsage(t *testing.T) {
 	payer := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
 	receiver := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
@@ -84,6 +109,50 @@ func TestSession_End(t *testing.T) {
 	assert.Equal(t, commonv1.EndReason_END_REASON_COMPLETE, session.EndReason)
 }
 
+func TestSession_Snapshot(t *testing.T) {
+	payer := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
+	receiver := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	dataService := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+
+	session := NewSession(payer, receiver, dataService)
+
+	snapshot := session.Snapshot()
+	assert.Equal(t, SessionStateActive, snapshot.State)
+	assert.Equal(t, session.CreatedAt, snapshot.CreatedAt)
+	assert.Nil(t, snapshot.EndedAt)
+
+	session.End(commonv1.EndReason_END_REASON_COMPLETE)
+	assert.Equal(t, SessionStateActive, snapshot.State)
+
+	snapshot = session.Snapshot()
+	assert.Equal(t, SessionStateEnded, snapshot.State)
+	require.NotNil(t, snapshot.EndedAt)
+	assert.Equal(t, *session.EndedAt, *snapshot.EndedAt)
+	assert.Equal(t, commonv1.EndReason_END_REASON_COMPLETE, snapshot.EndReason)
+
+	// The snapshot keeps its end time when the session is resumed
+	require.True(t, session.Resume(commonv1.EndReason_END_REASON_COMPLETE))
+	assert.Nil(t, session.EndedAt)
+	assert.NotNil(t, snapshot.EndedAt)
+}
+
+func TestSession_AddReceiptValue(t *testing.T) {
+	payer := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
+	receiver := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	dataService := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+
+	session := NewSession(payer, receiver, dataService)
+	assert.False(t, session.PaysWithReceipts())
+	assert.Equal(t, big.NewInt(0), session.GetReceiptsValue())
+
+	session.PaymentMode = commonv1.PaymentMode_PAYMENT_MODE_RECEIPTS
+	assert.True(t, session.PaysWithReceipts())
+
+	assert.Equal(t, big.NewInt(100), session.AddReceiptValue(big.NewInt(100)))
+	assert.Equal(t, big.NewInt(250), session.AddReceiptValue(big.NewInt(150)))
+	assert.Equal(t, big.NewInt(250), session.GetReceiptsValue())
+}
+
 func TestSessionManager_Create(t *testing.T) {
 	sm := NewSessionManager()
 
@@ -155,3 +224,27 @@ func TestSessionManager_GetActive(t *testing.T) {
 	assert.Len(t, active, 1)
 	assert.Equal(t, session2.ID, active[0].ID)
 }
+
+func TestSessionManager_List(t *testing.T) {
+	sm := NewSessionManager()
+
+	payer := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
+	receiver := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
+	dataService := eth.MustNewAddress("0x3333333333333333333333333333333333333333")
+
+	session1 := sm.Create(payer, receiver, dataService)
+	session2 := sm.Create(payer, receiver, dataService)
+	session1.End(commonv1.EndReason_END_REASON_COMPLETE)
+
+	// Ended sessions are listed too, oldest first
+	sessions := sm.List()
+	require.Len(t, sessions, 2)
+	assert.Equal(t, session1.ID, sessions[0].ID)
+	assert.Equal(t, session2.ID, sessions[1].ID)
+}
+
+func TestSessionState_String(t *testing.T) {
+	assert.Equal(t, "active", SessionStateActive.String())
+	assert.Equal(t, "paused", SessionStatePaused.String())
+	assert.Equal(t, "ended", SessionStateEnded.String())
+}
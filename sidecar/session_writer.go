@@ -0,0 +1,119 @@
+package sidecar
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultSessionFlushInterval is how often the sessions changed since the last
+// flush are written to the session store
+const DefaultSessionFlushInterval = time.Second
+
+// SessionWriter writes sessions to a SessionStore off the request path:
+// sessions are marked dirty as they change and the state each one has at the
+// next flush is written, all the dirty sessions in a single Save. A session
+// changing many times between two flushes is written once.
+type SessionWriter struct {
+	store SessionStore
+
+	mu    sync.Mutex
+	dirty map[string]func() *SessionExport // by session ID
+
+	// flushMu serializes writes so an older snapshot of a session never
+	// replaces a newer one, nor revives a deleted one
+	flushMu sync.Mutex
+}
+
+// NewSessionWriter returns a writer of the sessions of store
+func NewSessionWriter(store SessionStore) *SessionWriter {
+	return &SessionWriter{
+		store: store,
+		dirty: make(map[string]func() *SessionExport),
+	}
+}
+
+// MarkDirty schedules writing session sessionID on the next flush, export
+// returning its state at that time
+func (w *SessionWriter) MarkDirty(sessionID string, export func() *SessionExport) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.dirty[sessionID] = export
+}
+
+// Pending returns the number of sessions awaiting the next flush
+func (w *SessionWriter) Pending() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return len(w.dirty)
+}
+
+// Flush writes the dirty sessions. They are kept dirty, for the next flush to
+// retry, when writing fails.
+func (w *SessionWriter) Flush() error {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	dirty := w.dirty
+	w.dirty = make(map[string]func() *SessionExport)
+	w.mu.Unlock()
+
+	if len(dirty) == 0 {
+		return nil
+	}
+
+	exports := make([]*SessionExport, 0, len(dirty))
+	for _, export := range dirty {
+		exports = append(exports, export())
+	}
+
+	if err := w.store.Save(exports...); err != nil {
+		w.mu.Lock()
+		for sessionID, export := range dirty {
+			if _, found := w.dirty[sessionID]; !found {
+				w.dirty[sessionID] = export
+			}
+		}
+		w.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Delete removes sessionIDs from the store, dropping their pending writes
+func (w *SessionWriter) Delete(sessionIDs ...string) error {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	for _, sessionID := range sessionIDs {
+		delete(w.dirty, sessionID)
+	}
+	w.mu.Unlock()
+
+	return w.store.Delete(sessionIDs...)
+}
+
+// Run flushes every interval until ctx is done, then a last time. Flush
+// failures are passed to onError.
+func (w *SessionWriter) Run(ctx context.Context, interval time.Duration, onError func(err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := w.Flush(); err != nil {
+				onError(err)
+			}
+			return
+		case <-ticker.C:
+			if err := w.Flush(); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package sidecar
+
+import (
+	"math/big"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+)
+
+// Settlement summarizes what an ended session is paid: the value its consumer
+// signed for in the final RAV, the value already collected on-chain for its
+// collection and the pending delta collecting the final RAV pays, along with
+// how GraphPayments is projected to distribute it. GRT values are in wei.
+type Settlement struct {
+	SignedValue         *big.Int `json:"signed_value"`
+	PreviouslyCollected *big.Int `json:"previously_collected"`
+	PendingDelta        *big.Int `json:"pending_delta"`
+
+	// Projected distribution of the pending delta, ProjectedPayout being the
+	// service provider's share
+	ProtocolCut     *big.Int `json:"protocol_cut"`
+	DataServiceCut  *big.Int `json:"data_service_cut"`
+	DelegationCut   *big.Int `json:"delegation_cut"`
+	ProjectedPayout *big.Int `json:"projected_payout"`
+}
+
+// NewSettlement settles signedValue, the value of a session's final RAV, of
+// which previouslyCollected was collected already. The pending delta is
+// projected after the protocol payment cut, the data service cut and the
+// delegation fee cut, see horizon.SplitPayment.
+func NewSettlement(signedValue, previouslyCollected *big.Int, protocolCut, dataServiceCut, delegationFeeCut horizon.PPM) (*Settlement, error) {
+	if signedValue == nil {
+		signedValue = new(big.Int)
+	}
+	if previouslyCollected == nil {
+		previouslyCollected = new(big.Int)
+	}
+
+	pendingDelta := new(big.Int).Sub(signedValue, previouslyCollected)
+	if pendingDelta.Sign() < 0 {
+		pendingDelta.SetInt64(0)
+	}
+
+	split, err := horizon.SplitPayment(pendingDelta, protocolCut, dataServiceCut, delegationFeeCut)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Settlement{
+		SignedValue:         new(big.Int).Set(signedValue),
+		PreviouslyCollected: new(big.Int).Set(previouslyCollected),
+		PendingDelta:        pendingDelta,
+		ProtocolCut:         split.Protocol,
+		DataServiceCut:      split.DataService,
+		DelegationCut:       split.Delegation,
+		ProjectedPayout:     split.ServiceProvider,
+	}, nil
+}
+
+// SetSettlement records the settlement of the session once it ended
+func (s *Session) SetSettlement(settlement *Settlement) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Settlement = settlement
+}
+
+// GetSettlement returns the settlement of the session, nil until it ended
+func (s *Session) GetSettlement() *Settlement {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.Settlement
+}
+
+// ConsumerSettlement acknowledges, on the consumer side, what an ended session
+// is paid: the value signed for it, the usage the consumer observed against
+// the usage its provider claimed and the budgets left once it ended. GRT
+// values are in wei.
+type ConsumerSettlement struct {
+	SignedValue *big.Int `json:"signed_value"`
+
+	// Observed is the usage the consumer observed, nil when it was not
+	// reported. Divergence is the largest relative difference between the
+	// claimed and observed blocks or bytes, Disputed being set when it exceeds
+	// the tolerance.
+	Observed   *ObservedUsage `json:"observed,omitempty"`
+	Divergence float64        `json:"divergence,omitempty"`
+	Disputed   bool           `json:"disputed,omitempty"`
+
+	// Budgets left once the session ended, nil when unlimited
+	GlobalBudgetRemaining   *big.Int `json:"global_budget_remaining,omitempty"`
+	ProviderBudgetRemaining *big.Int `json:"provider_budget_remaining,omitempty"`
+}
+
+// ObservedUsage is the usage of a session as observed by its consumer
+type ObservedUsage struct {
+	BlocksProcessed  uint64 `json:"blocks_processed"`
+	BytesTransferred uint64 `json:"bytes_transferred"`
+}
+
+// SetConsumerSettlement records the consumer settlement of the session once it
+// ended
+func (s *Session) SetConsumerSettlement(settlement *ConsumerSettlement) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ConsumerSettlement = settlement
+}
+
+// GetConsumerSettlement returns the consumer settlement of the session, nil
+// until it ended
+func (s *Session) GetConsumerSettlement() *ConsumerSettlement {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ConsumerSettlement
+}
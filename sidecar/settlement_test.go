@@ -0,0 +1,36 @@
+package sidecar
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSettlement(t *testing.T) {
+	settlement, err := NewSettlement(big.NewInt(1000), big.NewInt(400), 100_000, 100_000, 500_000)
+	require.NoError(t, err)
+	assert.Equal(t, "1000", settlement.SignedValue.String())
+	assert.Equal(t, "400", settlement.PreviouslyCollected.String())
+	assert.Equal(t, "600", settlement.PendingDelta.String())
+	assert.Equal(t, "60", settlement.ProtocolCut.String())
+	assert.Equal(t, "54", settlement.DataServiceCut.String())
+	assert.Equal(t, "243", settlement.DelegationCut.String())
+	assert.Equal(t, "243", settlement.ProjectedPayout.String())
+
+	// Another RAV of the collection may have been collected for more
+	settlement, err = NewSettlement(big.NewInt(1000), big.NewInt(1500), 0, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "0", settlement.PendingDelta.String())
+	assert.Equal(t, "0", settlement.ProjectedPayout.String())
+
+	settlement, err = NewSettlement(nil, nil, 0, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "0", settlement.SignedValue.String())
+	assert.Equal(t, "0", settlement.PendingDelta.String())
+
+	_, err = NewSettlement(big.NewInt(1000), nil, horizon.MaxPPM+1, 0, 0)
+	assert.ErrorIs(t, err, horizon.ErrInvalidPPM)
+}
@@ -0,0 +1,128 @@
+package sidecar
+
+import (
+	"math/big"
+	"slices"
+	"strings"
+
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+)
+
+// Well-known usage categories, usage may also be broken down per module with
+// ModuleUsageCategory
+const (
+	// UsageCategoryPreprocessed is blocks computed ahead of the chain head
+	UsageCategoryPreprocessed = "preprocessed"
+	// UsageCategoryLive is blocks computed at the chain head
+	UsageCategoryLive = "live"
+	// UsageCategoryCached is blocks served from cache instead of computed
+	UsageCategoryCached = "cached"
+	// UsageCategoryEgress is bytes sent to the consumer
+	UsageCategoryEgress = "egress"
+
+	usageCategoryModulePrefix = "module:"
+)
+
+// ModuleUsageCategory returns the usage category of the module named module
+func ModuleUsageCategory(module string) string {
+	return usageCategoryModulePrefix + module
+}
+
+// CategoryUsage is the usage of a session in one category
+type CategoryUsage struct {
+	Name             string
+	BlocksProcessed  uint64
+	BytesTransferred uint64
+	Requests         uint64
+	TotalCost        *big.Int
+}
+
+func (c *CategoryUsage) add(category *commonv1.UsageCategory) {
+	c.BlocksProcessed += category.BlocksProcessed
+	c.BytesTransferred += category.BytesTransferred
+	c.Requests += category.Requests
+	if category.Cost != nil {
+		c.TotalCost = new(big.Int).Add(c.TotalCost, category.Cost.ToNative())
+	}
+}
+
+func (c *CategoryUsage) toProto() *commonv1.UsageCategory {
+	return &commonv1.UsageCategory{
+		Name:             c.Name,
+		BlocksProcessed:  c.BlocksProcessed,
+		BytesTransferred: c.BytesTransferred,
+		Requests:         c.Requests,
+		Cost:             commonv1.BigIntFromNative(c.TotalCost),
+	}
+}
+
+// AddUsageCategories adds a usage category breakdown to the session one.
+// Categories without a name are ignored.
+func (s *Session) AddUsageCategories(categories []*commonv1.UsageCategory) {
+	if len(categories) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, category := range categories {
+		if category.GetName() == "" {
+			continue
+		}
+		if s.Categories == nil {
+			s.Categories = make(map[string]*CategoryUsage)
+		}
+		usage, ok := s.Categories[category.Name]
+		if !ok {
+			usage = &CategoryUsage{Name: category.Name, TotalCost: big.NewInt(0)}
+			s.Categories[category.Name] = usage
+		}
+		usage.add(category)
+	}
+}
+
+// GetCategoryUsage returns a copy of the usage category breakdown, ordered by
+// category name
+func (s *Session) GetCategoryUsage() []CategoryUsage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedCategories(s.Categories)
+}
+
+// MergeUsageCategories sums category breakdowns per category name, ordered by
+// name. Categories without a name are ignored.
+func MergeUsageCategories(breakdowns ...[]*commonv1.UsageCategory) []*commonv1.UsageCategory {
+	merged := make(map[string]*CategoryUsage)
+	for _, categories := range breakdowns {
+		for _, category := range categories {
+			if category.GetName() == "" {
+				continue
+			}
+			usage, ok := merged[category.Name]
+			if !ok {
+				usage = &CategoryUsage{Name: category.Name, TotalCost: big.NewInt(0)}
+				merged[category.Name] = usage
+			}
+			usage.add(category)
+		}
+	}
+
+	var out []*commonv1.UsageCategory
+	for _, usage := range sortedCategories(merged) {
+		out = append(out, usage.toProto())
+	}
+	return out
+}
+
+func sortedCategories(categories map[string]*CategoryUsage) []CategoryUsage {
+	out := make([]CategoryUsage, 0, len(categories))
+	for _, category := range categories {
+		out = append(out, *category)
+	}
+	slices.SortFunc(out, func(a, b CategoryUsage) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return out
+}
@@ -0,0 +1,60 @@
+package sidecar
+
+import (
+	"math/big"
+	"testing"
+
+	commonv1 "github.com/graphprotocol/substreams-data-service/pb/graph/substreams/data_service/common/v1"
+	"github.com/streamingfast/eth-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession_AddUsageCategories(t *testing.T) {
+	session := NewSession(
+		eth.MustNewAddress("0x1111111111111111111111111111111111111111"),
+		eth.MustNewAddress("0x2222222222222222222222222222222222222222"),
+		eth.MustNewAddress("0x3333333333333333333333333333333333333333"),
+	)
+
+	session.AddUsageCategories([]*commonv1.UsageCategory{
+		{Name: UsageCategoryLive, BlocksProcessed: 2, Cost: commonv1.BigIntFromNative(big.NewInt(20))},
+		{Name: UsageCategoryCached, BlocksProcessed: 8, Cost: commonv1.BigIntFromNative(big.NewInt(8))},
+		{BlocksProcessed: 100},
+	})
+	session.AddUsageCategories([]*commonv1.UsageCategory{
+		{Name: UsageCategoryLive, BlocksProcessed: 1, Cost: commonv1.BigIntFromNative(big.NewInt(10))},
+		{Name: ModuleUsageCategory("map_events"), BytesTransferred: 500},
+	})
+
+	categories := session.GetCategoryUsage()
+	require.Len(t, categories, 3)
+	assert.Equal(t, "cached", categories[0].Name)
+	assert.Equal(t, "live", categories[1].Name)
+	assert.Equal(t, uint64(3), categories[1].BlocksProcessed)
+	assert.Equal(t, "30", categories[1].TotalCost.String())
+	assert.Equal(t, "module:map_events", categories[2].Name)
+	assert.Equal(t, uint64(500), categories[2].BytesTransferred)
+
+	usage := session.GetUsage()
+	require.Len(t, usage.Categories, 3)
+	assert.Equal(t, "live", usage.Categories[1].Name)
+	assert.Equal(t, "30", usage.Categories[1].Cost.ToNative().String())
+}
+
+func TestMergeUsageCategories(t *testing.T) {
+	merged := MergeUsageCategories(
+		[]*commonv1.UsageCategory{{Name: "live", BlocksProcessed: 1}, {Name: "egress", BytesTransferred: 10}},
+		nil,
+		[]*commonv1.UsageCategory{{Name: "live", BlocksProcessed: 2, Cost: commonv1.BigIntFromNative(big.NewInt(5))}},
+	)
+
+	require.Len(t, merged, 2)
+	assert.Equal(t, "egress", merged[0].Name)
+	assert.Equal(t, uint64(10), merged[0].BytesTransferred)
+	assert.Equal(t, "live", merged[1].Name)
+	assert.Equal(t, uint64(3), merged[1].BlocksProcessed)
+	assert.Equal(t, "5", merged[1].Cost.ToNative().String())
+
+	assert.Empty(t, MergeUsageCategories())
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/graphprotocol/substreams-data-service/horizon/devenv"
 	"github.com/streamingfast/eth-go"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -54,7 +55,7 @@ func TestAuthorizeSignerFlow(t *testing.T) {
 
 	// Create and sign RAV with the authorized signer
 	domain := horizon.NewDomain(env.ChainID, env.Collector.Address)
-	collectionID := mustNewCollectionID("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	collectionID := devenv.EthereumExplorerPackage.ScenarioCollectionID(t.Name())
 
 	rav := &horizon.RAV{
 		CollectionID:    collectionID,
@@ -79,7 +80,7 @@ func TestAuthorizeSignerFlow(t *testing.T) {
 	zlog.Debug("verified signature recovery", zap.Stringer("recovered", recoveredSigner), zap.Stringer("expected", signerAddr))
 
 	// Call collect() via SubstreamsDataService - should succeed because signer is authorized
-	dataServiceCut := uint64(100000) // 10% in PPM
+	dataServiceCut := testDataServiceCut
 	zlog.Info("calling SubstreamsDataService.collect() with authorized signer", zap.Uint64("chain_id", env.ChainID))
 	tokensCollected, err := callDataServiceCollect(env, signedRAV, dataServiceCut)
 	require.NoError(t, err)
@@ -119,7 +120,7 @@ func TestUnauthorizedSignerFails(t *testing.T) {
 
 	// Create and sign RAV with unauthorized signer
 	domain := horizon.NewDomain(env.ChainID, env.Collector.Address)
-	collectionID := mustNewCollectionID("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	collectionID := devenv.EthereumExplorerPackage.ScenarioCollectionID(t.Name())
 
 	rav := &horizon.RAV{
 		CollectionID:    collectionID,
@@ -136,7 +137,7 @@ func TestUnauthorizedSignerFails(t *testing.T) {
 	require.NoError(t, err)
 
 	// Call collect() via SubstreamsDataService - should fail
-	dataServiceCut := uint64(100000) // 10% in PPM
+	dataServiceCut := testDataServiceCut
 	zlog.Info("calling SubstreamsDataService.collect() with unauthorized signer (expecting failure)", zap.Uint64("chain_id", env.ChainID))
 	_, err = callDataServiceCollect(env, signedRAV, dataServiceCut)
 	require.Error(t, err, "Collection should fail with unauthorized signer")
@@ -175,7 +176,7 @@ func TestRevokeSignerFlow(t *testing.T) {
 
 	// Try to collect with revoked signer - should fail
 	domain := horizon.NewDomain(env.ChainID, env.Collector.Address)
-	collectionID := mustNewCollectionID("0xcccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc")
+	collectionID := devenv.EthereumExplorerPackage.ScenarioCollectionID(t.Name())
 
 	rav := &horizon.RAV{
 		CollectionID:    collectionID,
@@ -190,7 +191,7 @@ func TestRevokeSignerFlow(t *testing.T) {
 	signedRAV, err := horizon.Sign(domain, rav, signerKey)
 	require.NoError(t, err)
 
-	dataServiceCut := uint64(100000)
+	dataServiceCut := testDataServiceCut
 	zlog.Info("calling SubstreamsDataService.collect() with revoked signer (expecting failure)", zap.Uint64("chain_id", env.ChainID))
 	_, err = callDataServiceCollect(env, signedRAV, dataServiceCut)
 	require.Error(t, err, "Collection should fail with revoked signer")
@@ -1,11 +1,13 @@
 package integration
 
 import (
+	"context"
 	"math/big"
 	"testing"
 	"time"
 
 	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/graphprotocol/substreams-data-service/horizon/devenv"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
@@ -22,7 +24,7 @@ func TestCollectRAV(t *testing.T) {
 
 	// Create domain and RAV
 	domain := horizon.NewDomain(env.ChainID, env.Collector.Address)
-	collectionID := mustNewCollectionID("0x1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef")
+	collectionID := devenv.UniswapV3Package.ScenarioCollectionID(t.Name())
 	valueAggregate := big.NewInt(1000000000000000000) // 1 GRT
 
 	rav := &horizon.RAV{
@@ -46,7 +48,7 @@ func TestCollectRAV(t *testing.T) {
 	zlog.Debug("signature verified locally", zap.Stringer("recovered_signer", recoveredSigner))
 
 	// Call collect() via SubstreamsDataService
-	dataServiceCut := uint64(100000) // 10% in PPM
+	dataServiceCut := testDataServiceCut
 	zlog.Info("calling SubstreamsDataService.collect() on chain", zap.String("data_service", env.DataService.Address.Pretty()), zap.Uint64("chain_id", env.ChainID))
 	tokensCollected, err := callDataServiceCollect(env, signedRAV, dataServiceCut)
 	require.NoError(t, err)
@@ -70,7 +72,7 @@ func TestCollectRAVIncremental(t *testing.T) {
 	signerKey := setup.SignerKey
 
 	domain := horizon.NewDomain(env.ChainID, env.Collector.Address)
-	collectionID := mustNewCollectionID("0xfedcba0987654321fedcba0987654321fedcba0987654321fedcba0987654321")
+	collectionID := devenv.UniswapV3Package.ScenarioCollectionID(t.Name())
 
 	// First RAV: 1 GRT
 	rav1 := &horizon.RAV{
@@ -86,7 +88,7 @@ func TestCollectRAVIncremental(t *testing.T) {
 	signedRAV1, err := horizon.Sign(domain, rav1, signerKey)
 	require.NoError(t, err)
 
-	dataServiceCut := uint64(100000) // 10% in PPM
+	dataServiceCut := testDataServiceCut
 	collected1, err := callDataServiceCollect(env, signedRAV1, dataServiceCut)
 	require.NoError(t, err)
 	require.Equal(t, uint64(1000000000000000000), collected1)
@@ -117,3 +119,74 @@ func TestCollectRAVIncremental(t *testing.T) {
 	t.Logf("Successfully collected incrementally: first=%d, second=%d, total=%d",
 		collected1, collected2, totalCollected)
 }
+
+// TestCollectRAVPaymentDistribution tests how GraphPayments distributes a
+// collected RAV between the protocol, the data service, the delegation pool
+// and the service provider
+func TestCollectRAVPaymentDistribution(t *testing.T) {
+	env := SetupEnv(t)
+	ctx := context.Background()
+
+	setup := SetupTestWithSigner(t, env, nil)
+
+	protocolCut, err := env.ProtocolPaymentCut()
+	require.NoError(t, err)
+	require.Equal(t, devenv.ProtocolPaymentCut, protocolCut)
+
+	// 20% of query fees go to delegators
+	delegationFeeCut := horizon.PPM(200_000)
+	require.NoError(t, env.SetDelegationFeeCut(horizon.PaymentTypeQueryFee, delegationFeeCut))
+	t.Cleanup(func() {
+		require.NoError(t, env.SetDelegationFeeCut(horizon.PaymentTypeQueryFee, 0))
+	})
+	require.NoError(t, env.AddToDelegationPool(big.NewInt(100000000000000000))) // 0.1 GRT
+
+	cut, err := env.DelegationFeeCut(horizon.PaymentTypeQueryFee)
+	require.NoError(t, err)
+	require.Equal(t, delegationFeeCut, cut)
+
+	valueAggregate := big.NewInt(1000000000000000000) // 1 GRT
+	expected, err := env.ExpectedPaymentSplit(valueAggregate, horizon.PaymentTypeQueryFee, testDataServiceCut)
+	require.NoError(t, err)
+	require.Positive(t, expected.Delegation.Sign())
+
+	poolBefore, err := env.DelegationPool()
+	require.NoError(t, err)
+	providerBefore, err := env.GetGRTBalance(env.ServiceProvider.Address)
+	require.NoError(t, err)
+	dataServiceBefore, err := env.GetGRTBalance(env.DataService.Address)
+	require.NoError(t, err)
+	supplyBefore, err := env.GRT().TotalSupply(ctx)
+	require.NoError(t, err)
+
+	signedRAV, err := horizon.Sign(env.Domain(), &horizon.RAV{
+		CollectionID:    devenv.UniswapV3Package.ScenarioCollectionID(t.Name()),
+		Payer:           env.Payer.Address,
+		ServiceProvider: env.ServiceProvider.Address,
+		DataService:     env.DataService.Address,
+		TimestampNs:     uint64(time.Now().UnixNano()),
+		ValueAggregate:  valueAggregate,
+		Metadata:        []byte{},
+	}, setup.SignerKey)
+	require.NoError(t, err)
+
+	tokensCollected, err := callDataServiceCollect(env, signedRAV, testDataServiceCut)
+	require.NoError(t, err)
+	require.Equal(t, valueAggregate.Uint64(), tokensCollected)
+
+	poolAfter, err := env.DelegationPool()
+	require.NoError(t, err)
+	providerAfter, err := env.GetGRTBalance(env.ServiceProvider.Address)
+	require.NoError(t, err)
+	dataServiceAfter, err := env.GetGRTBalance(env.DataService.Address)
+	require.NoError(t, err)
+	supplyAfter, err := env.GRT().TotalSupply(ctx)
+	require.NoError(t, err)
+
+	// The protocol cut is burned, the provider is paid to itself as payments
+	// destination
+	require.Equal(t, expected.Protocol, new(big.Int).Sub(supplyBefore, supplyAfter), "protocol cut")
+	require.Equal(t, expected.DataService, new(big.Int).Sub(dataServiceAfter, dataServiceBefore), "data service cut")
+	require.Equal(t, expected.Delegation, new(big.Int).Sub(poolAfter.Tokens, poolBefore.Tokens), "delegator cut")
+	require.Equal(t, expected.ServiceProvider, new(big.Int).Sub(providerAfter, providerBefore), "operator take-home")
+}
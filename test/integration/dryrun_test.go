@@ -0,0 +1,35 @@
+package integration
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestDryRunDoesNotBroadcast tests that dry-run mode simulates transactions without changing chain state
+func TestDryRunDoesNotBroadcast(t *testing.T) {
+	env := SetupEnv(t)
+
+	balanceBefore, err := env.GetGRTBalance(env.User3.Address)
+	require.NoError(t, err)
+
+	env.SetDryRun(true)
+	defer env.SetDryRun(false)
+
+	amount := big.NewInt(1000000000000000000) // 1 GRT
+	require.NoError(t, callMintGRT(env, env.User3.Address, amount))
+
+	simulated := env.SimulatedTransactions()
+	require.NotEmpty(t, simulated)
+
+	tx := simulated[len(simulated)-1]
+	require.Equal(t, "mint(address,uint256)", tx.Method)
+	require.Greater(t, tx.Gas, uint64(0))
+	require.Equal(t, env.GRTToken.Address, *tx.To)
+	require.NotEmpty(t, tx.Data)
+
+	balanceAfter, err := env.GetGRTBalance(env.User3.Address)
+	require.NoError(t, err)
+	require.Equal(t, balanceBefore, balanceAfter)
+}
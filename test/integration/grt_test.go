@@ -0,0 +1,42 @@
+package integration
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/graphprotocol/substreams-data-service/horizon/devenv"
+	"github.com/streamingfast/eth-go/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGRTClient tests the typed GRT client against MockGRTToken
+func TestGRTClient(t *testing.T) {
+	env := SetupEnv(t)
+	ctx := context.Background()
+
+	grt := devenv.NewGRTClient(rpc.NewClient(env.RPCURL), env.ChainID, env.GRTToken.Address)
+
+	amount, err := devenv.ParseGRT("2.5")
+	require.NoError(t, err)
+	require.NoError(t, callMintGRT(env, env.User1.Address, amount))
+
+	user2Before, err := grt.BalanceOf(ctx, env.User2.Address)
+	require.NoError(t, err)
+
+	oneGRT := big.NewInt(1000000000000000000)
+	require.NoError(t, grt.Transfer(ctx, env.User1.PrivateKey, env.User2.Address, oneGRT))
+
+	user2After, err := grt.BalanceOf(ctx, env.User2.Address)
+	require.NoError(t, err)
+	require.Equal(t, new(big.Int).Add(user2Before, oneGRT), user2After)
+
+	require.NoError(t, grt.Approve(ctx, env.User1.PrivateKey, env.User3.Address, oneGRT))
+	allowance, err := grt.Allowance(ctx, env.User1.Address, env.User3.Address)
+	require.NoError(t, err)
+	require.Equal(t, oneGRT, allowance)
+
+	supply, err := grt.TotalSupply(ctx)
+	require.NoError(t, err)
+	require.True(t, supply.Cmp(amount) >= 0)
+}
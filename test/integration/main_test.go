@@ -17,7 +17,7 @@ func init() {
 
 func TestMain(m *testing.M) {
 	ctx := context.Background()
-	_, err := devenv.Start(ctx)
+	_, err := devenv.Start(ctx, devenv.WithReadCache())
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to start development environment: %v\n", err)
 		os.Exit(1)
@@ -1,12 +1,15 @@
 package integration
 
 import (
+	"context"
 	"encoding/hex"
 	"math/big"
+	"math/rand"
 	"testing"
 	"time"
 
 	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/graphprotocol/substreams-data-service/horizon/devenv"
 	"github.com/streamingfast/eth-go"
 	"github.com/stretchr/testify/require"
 )
@@ -17,7 +20,7 @@ func TestEIP712HashCompatibility(t *testing.T) {
 	env := SetupEnv(t)
 
 	domain := horizon.NewDomain(env.ChainID, env.Collector.Address)
-	collectionID := mustNewCollectionID("0xabababababababababababababababababababababababababababababababab")
+	collectionID := devenv.EthereumExplorerPackage.CollectionID()
 
 	rav := &horizon.RAV{
 		CollectionID:    collectionID,
@@ -48,7 +51,7 @@ func TestEIP712HashWithMetadata(t *testing.T) {
 	env := SetupEnv(t)
 
 	domain := horizon.NewDomain(env.ChainID, env.Collector.Address)
-	collectionID := mustNewCollectionID("0xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef")
+	collectionID := devenv.UniswapV3Package.CollectionID()
 
 	rav := &horizon.RAV{
 		CollectionID:    collectionID,
@@ -78,7 +81,7 @@ func TestSignatureRecoveryCompatibility(t *testing.T) {
 	expectedSigner := key.PublicKey().Address()
 
 	domain := horizon.NewDomain(env.ChainID, env.Collector.Address)
-	collectionID := mustNewCollectionID("0xcafebabecafebabecafebabecafebabecafebabecafebabecafebabecafebabe")
+	collectionID := devenv.ERC20BalancesPackage.CollectionID()
 
 	rav := &horizon.RAV{
 		CollectionID:    collectionID,
@@ -117,6 +120,21 @@ func TestSignatureRecoveryCompatibility(t *testing.T) {
 		expectedSigner.Pretty(), contractRecovered.Pretty())
 }
 
+// TestRandomizedEIP712Compatibility compares Go hashing and signer recovery
+// with the collector on randomized RAVs, see package compat
+func TestRandomizedEIP712Compatibility(t *testing.T) {
+	env := SetupEnv(t)
+
+	seed := time.Now().UnixNano()
+	t.Logf("Seed: %d", seed)
+
+	report, err := compatChecker(env).Run(context.Background(), 25, rand.New(rand.NewSource(seed)))
+	require.NoError(t, err)
+	for _, mismatch := range report.Mismatches {
+		t.Errorf("RAV %+v signed by %s: %s", mismatch.RAV, mismatch.Signer.Pretty(), mismatch.Err)
+	}
+}
+
 // TestSignatureEncodingComparison compares the SignedRAV encoding from recoverRAVSigner vs collect
 func TestSignatureEncodingComparison(t *testing.T) {
 	env := SetupEnv(t)
@@ -125,7 +143,7 @@ func TestSignatureEncodingComparison(t *testing.T) {
 	require.NoError(t, err)
 
 	domain := horizon.NewDomain(env.ChainID, env.Collector.Address)
-	collectionID := mustNewCollectionID("0xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef")
+	collectionID := devenv.UniswapV3Package.CollectionID()
 
 	rav := &horizon.RAV{
 		CollectionID:    collectionID,
@@ -169,7 +187,7 @@ func TestSignatureEncodingComparison(t *testing.T) {
 	require.NoError(t, err)
 
 	// Get the encoding from collectDataEncoder (synthetic ABI)
-	collectData := encodeCollectData(signedRAV, 100000, eth.Address{})
+	collectData := encodeCollectData(signedRAV, testDataServiceCut, eth.Address{})
 
 	t.Logf("\n=== Encoding Comparison ===")
 	t.Logf("recoverRAVSigner calldata length: %d", len(recoverData))
@@ -221,7 +239,7 @@ func TestReceiptSigningAndRecovery(t *testing.T) {
 	expectedSigner := key.PublicKey().Address()
 
 	domain := horizon.NewDomain(env.ChainID, env.Collector.Address)
-	collectionID := mustNewCollectionID("0xabababababababababababababababababababababababababababababababab")
+	collectionID := devenv.EthereumExplorerPackage.CollectionID()
 
 	receipt := horizon.NewReceipt(
 		collectionID,
@@ -249,7 +267,7 @@ func TestRAVAggregation(t *testing.T) {
 
 	senderAddr := senderKey.PublicKey().Address()
 	domain := horizon.NewDomain(env.ChainID, env.Collector.Address)
-	collectionID := mustNewCollectionID("0xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef")
+	collectionID := devenv.UniswapV3Package.CollectionID()
 
 	payer := senderAddr
 	dataService := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
@@ -487,8 +505,8 @@ func TestCollectionIDMismatch(t *testing.T) {
 	dataService := eth.MustNewAddress("0x1111111111111111111111111111111111111111")
 	serviceProvider := eth.MustNewAddress("0x2222222222222222222222222222222222222222")
 
-	collectionID1 := mustNewCollectionID("0x1111111111111111111111111111111111111111111111111111111111111111")
-	collectionID2 := mustNewCollectionID("0x2222222222222222222222222222222222222222222222222222222222222222")
+	collectionID1 := devenv.EthereumExplorerPackage.CollectionID()
+	collectionID2 := devenv.UniswapV3Package.CollectionID()
 
 	receipt1 := &horizon.Receipt{
 		CollectionID:    collectionID1,
@@ -0,0 +1,33 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/graphprotocol/substreams-data-service/horizon/devenv"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSeedFundedSessionTwice tests that a seed scenario can be applied again to
+// a running environment, topping up escrow and skipping what is in place
+func TestSeedFundedSessionTwice(t *testing.T) {
+	env := SetupEnv(t)
+
+	config := &devenv.SeedConfig{Payer: env.User2, ServiceProvider: env.ServiceProvider}
+	first, err := env.Seed("funded-session", config)
+	require.NoError(t, err)
+	require.NotNil(t, first.SignerKey)
+	require.Contains(t, first.Applied, devenv.SeedEscrowDeposit)
+
+	balanceBefore, err := env.GetEscrowBalance(env.User2.Address, env.ServiceProvider.Address)
+	require.NoError(t, err)
+
+	config.SignerKey = first.SignerKey
+	second, err := env.Seed("funded-session", config)
+	require.NoError(t, err)
+	require.Equal(t, []devenv.SeedStep{devenv.SeedEscrowDeposit, devenv.SeedProvision}, second.Applied)
+	require.Equal(t, []devenv.SeedStep{devenv.SeedRegistration, devenv.SeedSignerAuthorization}, second.Skipped)
+
+	balanceAfter, err := env.GetEscrowBalance(env.User2.Address, env.ServiceProvider.Address)
+	require.NoError(t, err)
+	require.Equal(t, 1, balanceAfter.Cmp(balanceBefore))
+}
@@ -16,6 +16,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/graphprotocol/substreams-data-service/horizon/compat"
 	"github.com/graphprotocol/substreams-data-service/horizon/devenv"
 )
 
@@ -61,11 +62,6 @@ func SetupTestWithSigner(t *testing.T, env *TestEnv, config *TestSetupConfig) *T
 	return result
 }
 
-// mustNewCollectionID creates a CollectionID from a hex string or panics
-func mustNewCollectionID(hexStr string) horizon.CollectionID {
-	return devenv.MustNewCollectionID(hexStr)
-}
-
 // ========== Contract Call Helpers (delegates to devenv) ==========
 
 // callMintGRT mints GRT tokens to an address
@@ -84,7 +80,7 @@ func callDepositEscrow(env *TestEnv, amount *big.Int) error {
 }
 
 // callSetProvision sets provision tokens for service provider
-func callSetProvision(env *TestEnv, tokens *big.Int, maxVerifierCut uint32, thawingPeriod uint64) error {
+func callSetProvision(env *TestEnv, tokens *big.Int, maxVerifierCut horizon.PPM, thawingPeriod uint64) error {
 	return env.SetProvision(tokens, maxVerifierCut, thawingPeriod)
 }
 
@@ -125,8 +121,11 @@ func sendTransaction(ctx context.Context, rpcClient *rpc.Client, key *eth.Privat
 
 // ========== RAV/Collection Helpers ==========
 
-// callTokensCollected queries tokensCollected mapping
+// callTokensCollected queries tokensCollected mapping, refreshing the read
+// cache first as RAVs are collected by sidecars and clients outside of env
 func callTokensCollected(env *TestEnv, dataService eth.Address, collectionID horizon.CollectionID, receiver eth.Address, payer eth.Address) (uint64, error) {
+	env.RefreshReadCache()
+
 	// eth-go expects []byte for bytes32 parameters
 	data, err := env.Collector.CallData("tokensCollected", dataService, collectionID[:], receiver, payer)
 	if err != nil {
@@ -144,86 +143,24 @@ func callTokensCollected(env *TestEnv, dataService eth.Address, collectionID hor
 
 // callEncodeRAV calls encodeRAV to get the EIP-712 hash
 func callEncodeRAV(env *TestEnv, rav *horizon.RAV) (eth.Hash, error) {
-	encodeRAVFn := env.Collector.ABI.FindFunctionByName("encodeRAV")
-	if encodeRAVFn == nil {
-		return nil, fmt.Errorf("encodeRAV function not found in ABI")
-	}
-
-	ravTuple := map[string]interface{}{
-		"collectionId":    rav.CollectionID[:],
-		"payer":           rav.Payer,
-		"serviceProvider": rav.ServiceProvider,
-		"dataService":     rav.DataService,
-		"timestampNs":     rav.TimestampNs,
-		"valueAggregate":  rav.ValueAggregate,
-		"metadata":        rav.Metadata,
-	}
-
-	data, err := encodeRAVFn.NewCall(ravTuple).Encode()
-	if err != nil {
-		return nil, fmt.Errorf("encoding encodeRAV call: %w", err)
-	}
-
-	result, err := env.CallContract(env.Collector.Address, data)
-	if err != nil {
-		return nil, err
-	}
-
-	return eth.Hash(result), nil
+	return compatChecker(env).EncodeRAV(context.Background(), rav)
 }
 
 // callRecoverRAVSigner calls recoverRAVSigner to recover the signer address
 func callRecoverRAVSigner(env *TestEnv, signedRAV *horizon.SignedRAV) (eth.Address, error) {
-	recoverRAVSignerFn := env.Collector.ABI.FindFunctionByName("recoverRAVSigner")
-	if recoverRAVSignerFn == nil {
-		return nil, fmt.Errorf("recoverRAVSigner function not found in ABI")
-	}
-
-	rav := signedRAV.Message
-
-	ravTuple := map[string]interface{}{
-		"collectionId":    rav.CollectionID[:],
-		"payer":           rav.Payer,
-		"serviceProvider": rav.ServiceProvider,
-		"dataService":     rav.DataService,
-		"timestampNs":     rav.TimestampNs,
-		"valueAggregate":  rav.ValueAggregate,
-		"metadata":        rav.Metadata,
-	}
-
-	// Convert signature from V+R+S (eth-go format) to R+S+V (Solidity ECDSA format)
-	sig := signedRAV.Signature
-	rsv := make([]byte, 65)
-	copy(rsv[0:32], sig[1:33])   // R (32 bytes)
-	copy(rsv[32:64], sig[33:65]) // S (32 bytes)
-	rsv[64] = sig[0]             // V (1 byte)
-
-	signedRAVTuple := map[string]interface{}{
-		"rav":       ravTuple,
-		"signature": rsv,
-	}
-
-	data, err := recoverRAVSignerFn.NewCall(signedRAVTuple).Encode()
-	if err != nil {
-		return nil, fmt.Errorf("encoding recoverRAVSigner call: %w", err)
-	}
-
-	result, err := env.CallContract(env.Collector.Address, data)
-	if err != nil {
-		return nil, err
-	}
+	return compatChecker(env).RecoverRAVSigner(context.Background(), signedRAV)
+}
 
-	if len(result) >= 32 {
-		return eth.Address(result[12:32]), nil
-	}
-	return nil, fmt.Errorf("unexpected result length: %d", len(result))
+// compatChecker returns a checker comparing Go EIP-712 hashing and signing
+// with the collector deployed in env
+func compatChecker(env *TestEnv) *compat.Checker {
+	return compat.NewChecker(env.RPCURL, env.ChainID, env.Collector.Address)
 }
 
 // ========== Data Service Collect Helpers ==========
 
 // collectDataEncoderABI is a synthetic ABI used to encode the collect() data parameter.
 var collectDataEncoderABI *eth.ABI
-var dataServiceCollectEncoderABI *eth.ABI
 
 func init() {
 	var err error
@@ -260,81 +197,23 @@ func init() {
 	if err != nil {
 		panic(fmt.Sprintf("failed to parse collectDataEncoderABI: %v", err))
 	}
-
-	dataServiceCollectEncoderABI, err = eth.ParseABIFromBytes([]byte(`{
-		"abi": [{
-			"type": "function",
-			"name": "encode",
-			"inputs": [
-				{
-					"name": "signedRAV",
-					"type": "tuple",
-					"components": [
-						{
-							"name": "rav",
-							"type": "tuple",
-							"components": [
-								{"name": "collectionId", "type": "bytes32"},
-								{"name": "payer", "type": "address"},
-								{"name": "serviceProvider", "type": "address"},
-								{"name": "dataService", "type": "address"},
-								{"name": "timestampNs", "type": "uint64"},
-								{"name": "valueAggregate", "type": "uint128"},
-								{"name": "metadata", "type": "bytes"}
-							]
-						},
-						{"name": "signature", "type": "bytes"}
-					]
-				},
-				{"name": "dataServiceCut", "type": "uint256"}
-			]
-		}]
-	}`))
-	if err != nil {
-		panic(fmt.Sprintf("failed to parse dataServiceCollectEncoderABI: %v", err))
-	}
 }
 
-// encodeDataServiceCollectData encodes (SignedRAV, uint256 dataServiceCut) for SubstreamsDataService.collect()
-func encodeDataServiceCollectData(signedRAV *horizon.SignedRAV, dataServiceCut uint64) []byte {
-	encodeFn := dataServiceCollectEncoderABI.FindFunctionByName("encode")
-	if encodeFn == nil {
-		panic("encode function not found in dataServiceCollectEncoderABI")
-	}
-
-	rav := signedRAV.Message
-
-	ravTuple := map[string]interface{}{
-		"collectionId":    rav.CollectionID[:],
-		"payer":           rav.Payer,
-		"serviceProvider": rav.ServiceProvider,
-		"dataService":     rav.DataService,
-		"timestampNs":     rav.TimestampNs,
-		"valueAggregate":  rav.ValueAggregate,
-		"metadata":        rav.Metadata,
-	}
-
-	sig := signedRAV.Signature
-	rsv := make([]byte, 65)
-	copy(rsv[0:32], sig[1:33])   // R (32 bytes)
-	copy(rsv[32:64], sig[33:65]) // S (32 bytes)
-	rsv[64] = sig[0]             // V (1 byte)
+// testDataServiceCut is the data service cut requested when collecting in tests
+const testDataServiceCut = horizon.PPM(100_000) // 10%
 
-	signedRAVTuple := map[string]interface{}{
-		"rav":       ravTuple,
-		"signature": rsv,
-	}
-
-	data, err := encodeFn.NewCall(signedRAVTuple, big.NewInt(int64(dataServiceCut))).Encode()
+// encodeDataServiceCollectData encodes (SignedRAV, uint256 dataServiceCut) for SubstreamsDataService.collect()
+func encodeDataServiceCollectData(signedRAV *horizon.SignedRAV, dataServiceCut horizon.PPM) []byte {
+	data, err := horizon.EncodeCollectData(signedRAV, dataServiceCut)
 	if err != nil {
 		panic(fmt.Sprintf("encoding SubstreamsDataService collect data: %v", err))
 	}
 
-	return data[4:]
+	return data
 }
 
 // encodeCollectData encodes (SignedRAV, uint256 dataServiceCut, address receiverDestination) for collect()
-func encodeCollectData(signedRAV *horizon.SignedRAV, dataServiceCut uint64, receiverDestination eth.Address) []byte {
+func encodeCollectData(signedRAV *horizon.SignedRAV, dataServiceCut horizon.PPM, receiverDestination eth.Address) []byte {
 	encodeFn := collectDataEncoderABI.FindFunctionByName("encode")
 	if encodeFn == nil {
 		panic("encode function not found in collectDataEncoderABI")
@@ -363,7 +242,7 @@ func encodeCollectData(signedRAV *horizon.SignedRAV, dataServiceCut uint64, rece
 		"signature": rsv,
 	}
 
-	data, err := encodeFn.NewCall(signedRAVTuple, big.NewInt(int64(dataServiceCut)), receiverDestination).Encode()
+	data, err := encodeFn.NewCall(signedRAVTuple, dataServiceCut.BigInt(), receiverDestination).Encode()
 	if err != nil {
 		panic(fmt.Sprintf("encoding collect data: %v", err))
 	}
@@ -377,7 +256,7 @@ func encodeCollectData(signedRAV *horizon.SignedRAV, dataServiceCut uint64, rece
 }
 
 // callDataServiceCollect calls SubstreamsDataService.collect()
-func callDataServiceCollect(env *TestEnv, signedRAV *horizon.SignedRAV, dataServiceCut uint64) (uint64, error) {
+func callDataServiceCollect(env *TestEnv, signedRAV *horizon.SignedRAV, dataServiceCut horizon.PPM) (uint64, error) {
 	rav := signedRAV.Message
 	zlog.Debug("preparing SubstreamsDataService.collect() call",
 		zap.Uint64("chain_id", env.ChainID),
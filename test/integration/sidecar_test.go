@@ -194,14 +194,11 @@ func TestRAVSignatureVerification(t *testing.T) {
 
 	// Create a RAV signed by the authorized signer
 	t.Log("Testing valid RAV signature")
-	rav := &horizon.RAV{
+	rav := horizon.NewBootstrapRAV(horizon.RAVParties{
 		Payer:           env.Payer.Address,
-		DataService:     env.DataService.Address,
 		ServiceProvider: env.ServiceProvider.Address,
-		TimestampNs:     uint64(time.Now().UnixNano()),
-		ValueAggregate:  big.NewInt(0),
-		Metadata:        nil,
-	}
+		DataService:     env.DataService.Address,
+	}, horizon.CollectionID{}, nil)
 	signedRAV, err := horizon.Sign(domain, rav, setup.SignerKey)
 	require.NoError(t, err, "failed to sign RAV")
 
@@ -228,7 +225,7 @@ func TestRAVSignatureVerification(t *testing.T) {
 	}))
 	require.NoError(t, err)
 	assert.False(t, validateResp2.Msg.Valid, "invalid RAV should be rejected")
-	assert.Contains(t, validateResp2.Msg.RejectionReason, "not authorized")
+	assert.Equal(t, commonv1.RejectionCode_REJECTION_CODE_UNAUTHORIZED_SIGNER, validateResp2.Msg.RejectionCode)
 
 	t.Log("Signature verification test completed successfully!")
 }
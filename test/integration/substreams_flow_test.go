@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/graphprotocol/substreams-data-service/horizon"
+	"github.com/graphprotocol/substreams-data-service/horizon/devenv"
 	"github.com/streamingfast/eth-go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -82,7 +83,7 @@ type Provider struct {
 	blocksSent         uint64
 	requiredPreproc    uint64
 	collectorAddress   eth.Address
-	dataServiceCut     uint64
+	dataServiceCut     horizon.PPM
 	serviceProvider    eth.Address
 }
 
@@ -184,7 +185,7 @@ func NewProvider(
 		serviceProviderKey: serviceProviderKey,
 		requiredPreproc:    1000, // Default blocks to preprocess
 		collectorAddress:   collectorAddress,
-		dataServiceCut:     100000, // 10% in PPM
+		dataServiceCut:     testDataServiceCut,
 		serviceProvider:    serviceProvider,
 	}
 }
@@ -203,17 +204,11 @@ func (sc *ConsumerSidecar) StartSession(psc *ProviderSidecar, escrowAccount eth.
 
 	// Create initial RAV (RAV0) with zero value
 	// Note: The flow diagram asks "can we emit a 0-value RAV?" - yes, we can
-	rav0 := &horizon.RAV{
-		CollectionID:    sc.collectionID,
+	signedRAV0, err := horizon.SignBootstrapRAV(sc.domain, horizon.RAVParties{
 		Payer:           sc.payerAddr,
 		ServiceProvider: sc.serviceProvider,
 		DataService:     sc.dataService,
-		TimestampNs:     uint64(time.Now().UnixNano()),
-		ValueAggregate:  big.NewInt(0),
-		Metadata:        []byte{},
-	}
-
-	signedRAV0, err := horizon.Sign(sc.domain, rav0, sc.signerKey)
+	}, sc.collectionID, nil, horizon.NewLocalSigner(sc.signerKey))
 	if err != nil {
 		return nil, err
 	}
@@ -491,7 +486,7 @@ func (p *Provider) EndSession() {
 }
 
 // CollectFinalRAV collects the final RAV on-chain via SubstreamsDataService
-func (psc *ProviderSidecar) CollectFinalRAV(env *TestEnv, dataServiceCut uint64) (uint64, error) {
+func (psc *ProviderSidecar) CollectFinalRAV(env *TestEnv, dataServiceCut horizon.PPM) (uint64, error) {
 	if psc.currentRAV == nil {
 		return 0, nil
 	}
@@ -524,7 +519,7 @@ func TestSubstreamsNetworkPaymentsFlow(t *testing.T) {
 
 	// Create flow participants
 	domain := horizon.NewDomain(env.ChainID, env.Collector.Address)
-	collectionID := mustNewCollectionID("0x5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b01")
+	collectionID := devenv.ERC20BalancesPackage.ScenarioCollectionID(t.Name())
 
 	// Consumer Sidecar (sc)
 	consumerSidecar := NewConsumerSidecar(
@@ -712,7 +707,7 @@ func TestSubstreamsFlowWithInsufficientEscrow(t *testing.T) {
 	signerAddr := setup.SignerAddr
 
 	domain := horizon.NewDomain(env.ChainID, env.Collector.Address)
-	collectionID := mustNewCollectionID("0x5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b02")
+	collectionID := devenv.ERC20BalancesPackage.ScenarioCollectionID(t.Name())
 
 	// Create participants
 	consumerSidecar := NewConsumerSidecar(
@@ -808,7 +803,7 @@ func TestSubstreamsFlowMultipleRAVRequests(t *testing.T) {
 	signerAddr := setup.SignerAddr
 
 	domain := horizon.NewDomain(env.ChainID, env.Collector.Address)
-	collectionID := mustNewCollectionID("0x5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b5b03")
+	collectionID := devenv.ERC20BalancesPackage.ScenarioCollectionID(t.Name())
 
 	// Create participants
 	consumerSidecar := NewConsumerSidecar(